@@ -0,0 +1,208 @@
+// Package client is the Go SDK for the SoHoLINK consumer API (/api/v1 on the
+// portal; docs/openapi.yaml describes it). It submits jobs, follows them to a
+// terminal state, reads their logs and downloads their output, authenticated
+// by an API key a consumer creates on the portal's API keys page.
+//
+//	c := client.New("https://portal.example.org", os.Getenv("SOHOLINK_API_KEY"))
+//	sub, err := c.SubmitJob(ctx, client.SubmitRequest{ContainerImage: image})
+//	...
+//	job, err := c.WatchJob(ctx, sub.JobID, nil)
+//
+// Errors the API returns are *Error values; branch on their Code.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPollInterval is how often WatchJob and StreamLogs poll.
+const defaultPollInterval = 2 * time.Second
+
+// Client calls the consumer API with one API key. It is safe for concurrent
+// use.
+type Client struct {
+	baseURL      string
+	apiKey       string
+	httpClient   *http.Client
+	pollInterval time.Duration
+}
+
+// New constructs a Client for the portal at baseURL (scheme, host and port,
+// e.g. "https://portal.example.org") authenticating with apiKey. Requests
+// have no timeout of their own, since an artifact download may take long;
+// bound them with the context.
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		apiKey:       apiKey,
+		httpClient:   &http.Client{},
+		pollInterval: defaultPollInterval,
+	}
+}
+
+// Error codes the API returns, in Error.Code.
+const (
+	CodeUnauthorized        = "unauthorized"
+	CodeInsufficientScope   = "insufficient_scope"
+	CodeRateLimited         = "rate_limited"
+	CodeInvalidRequest      = "invalid_request"
+	CodeJobNotFound         = "job_not_found"
+	CodeJobNotCancellable   = "job_not_cancellable"
+	CodeInputNotFound       = "input_not_found"
+	CodeInvalidQuote        = "invalid_quote"
+	CodeImageNotAllowlisted = "image_not_allowlisted"
+	CodeTypeMismatch        = "workload_type_mismatch"
+	CodeNoCapacity          = "no_capacity"
+	CodeNoEstimate          = "estimate_unavailable"
+	CodePaymentFailed       = "payment_failed"
+	CodeArtifactNotFound    = "artifact_not_found"
+	CodeArtifactExpired     = "artifact_expired"
+	CodeUnavailable         = "unavailable"
+	CodeInternal            = "internal_error"
+)
+
+// Error is an error response from the API. Message is for people; Code is
+// one of the Code* constants and is what callers should branch on. The
+// remaining fields are set only for the responses that carry them.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+
+	// Reason and WantedTier explain a CodeNoCapacity rejection: Reason is
+	// no_capacity (retry later or ask for less), too_big or no_matching_tier
+	// (ask for less).
+	Reason     string
+	WantedTier string
+
+	// CurrentStatus is the job's status with CodeJobNotCancellable.
+	CurrentStatus string
+
+	// RetryAfter is how long to wait with CodeRateLimited.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("soholink: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// ErrorCode returns the API error code in err's chain, or "" when err is not
+// an API error.
+func ErrorCode(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ""
+}
+
+// errorBody is every error body the API sends; fields a response does not
+// carry stay empty.
+type errorBody struct {
+	Error         string `json:"error"`
+	Code          string `json:"code"`
+	Reason        string `json:"reason"`
+	WantedTier    string `json:"wanted_tier"`
+	CurrentStatus string `json:"current_status"`
+}
+
+// responseError builds the *Error for a non-2xx response. A body that is not
+// an API error (a proxy's error page) keeps the status text as its message.
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var b errorBody
+	if err := json.Unmarshal(body, &b); err != nil || b.Code == "" {
+		b = errorBody{Error: http.StatusText(resp.StatusCode), Code: CodeUnavailable}
+		if resp.StatusCode < 500 {
+			b.Code = ""
+		}
+	}
+	e := &Error{
+		StatusCode:    resp.StatusCode,
+		Code:          b.Code,
+		Message:       b.Error,
+		Reason:        b.Reason,
+		WantedTier:    b.WantedTier,
+		CurrentStatus: b.CurrentStatus,
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		e.RetryAfter = time.Duration(s) * time.Second
+	}
+	return e
+}
+
+// send makes an authenticated request to path with query and, when in is not
+// nil, in as the JSON body. The caller closes the body of a 2xx response;
+// any other status is returned as an *Error.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, in any) (*http.Response, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, fmt.Errorf("soholink: marshal request: %w", err)
+		}
+		body = bytes.NewReader(b)
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("soholink: build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("soholink: %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// do is send for a JSON response, decoded into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	resp, err := c.send(ctx, method, path, query, in)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("soholink: decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+// pause waits d, or until ctx is done. A rate-limited poll waits as long as
+// the API asks instead.
+func pause(ctx context.Context, d time.Duration, err error) error {
+	var e *Error
+	if errors.As(err, &e) && e.Code == CodeRateLimited && e.RetryAfter > d {
+		d = e.RetryAfter
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
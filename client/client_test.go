@@ -0,0 +1,236 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testKey = "shk_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// newTestClient returns a Client for handler that polls without waiting.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := New(srv.URL+"/", testKey)
+	c.pollInterval = time.Millisecond
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func TestSubmitJob(t *testing.T) {
+	var got map[string]any
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/jobs" {
+			t.Errorf("request: %s %s", r.Method, r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer "+testKey {
+			t.Errorf("Authorization = %q", auth)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		writeJSON(w, http.StatusCreated, SubmitResponse{JobID: "job-1", NodeID: "node-1"})
+	})
+
+	resp, err := c.SubmitJob(context.Background(), SubmitRequest{
+		JobShape:       JobShape{WorkloadType: "batch_compute", CPUCores: 4},
+		ContainerImage: "soholink/compute-worker@sha256:00",
+		Inputs:         []JobInput{{Name: "data.csv", SHA256: "ab"}},
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if resp.JobID != "job-1" || resp.NodeID != "node-1" {
+		t.Errorf("response = %+v", resp)
+	}
+	// The API rejects unknown fields; zero values are left to its defaults.
+	want := map[string]any{
+		"workload_type":   "batch_compute",
+		"cpu_cores":       float64(4),
+		"container_image": "soholink/compute-worker@sha256:00",
+		"inputs":          []any{map[string]any{"name": "data.csv", "sha256": "ab"}},
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("request body = %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    Error
+	}{
+		{
+			name: "no capacity",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{
+					"error": "no node has room", "code": "no_capacity", "reason": "too_big", "wanted_tier": "storm",
+				})
+			},
+			want: Error{StatusCode: 503, Code: CodeNoCapacity, Message: "no node has room", Reason: "too_big", WantedTier: "storm"},
+		},
+		{
+			name: "not cancellable",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusConflict, map[string]string{
+					"error": "job can no longer be cancelled", "code": "job_not_cancellable", "current_status": "completed",
+				})
+			},
+			want: Error{StatusCode: 409, Code: CodeJobNotCancellable, Message: "job can no longer be cancelled", CurrentStatus: "completed"},
+		},
+		{
+			name: "rate limited",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "3")
+				writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded", "code": "rate_limited"})
+			},
+			want: Error{StatusCode: 429, Code: CodeRateLimited, Message: "rate limit exceeded", RetryAfter: 3 * time.Second},
+		},
+		{
+			name: "proxy error page",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+				w.Write([]byte("<html>bad gateway</html>")) //nolint:errcheck
+			},
+			want: Error{StatusCode: 502, Code: CodeUnavailable, Message: "Bad Gateway"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, tt.handler)
+			_, err := c.CancelJob(context.Background(), "job-1")
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if *e != tt.want {
+				t.Errorf("error = %+v, want %+v", *e, tt.want)
+			}
+			if ErrorCode(err) != tt.want.Code {
+				t.Errorf("ErrorCode = %q", ErrorCode(err))
+			}
+		})
+	}
+}
+
+func TestWatchJob(t *testing.T) {
+	var mu sync.Mutex
+	statuses := []string{"scheduled", "scheduled", "running", "running", "completed"}
+	polls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if polls == 2 {
+			polls++
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded", "code": "rate_limited"})
+			return
+		}
+		s := statuses[min(polls, len(statuses)-1)]
+		polls++
+		writeJSON(w, http.StatusOK, Job{ID: "job-1", Status: s})
+	})
+
+	var seen []string
+	job, err := c.WatchJob(context.Background(), "job-1", func(j Job) error {
+		seen = append(seen, j.Status)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WatchJob: %v", err)
+	}
+	if !job.Terminal() || !job.Succeeded() {
+		t.Errorf("final job = %+v", job)
+	}
+	if got := strings.Join(seen, ","); got != "scheduled,running,completed" {
+		t.Errorf("status changes = %s", got)
+	}
+}
+
+func TestStreamLogs(t *testing.T) {
+	var mu sync.Mutex
+	emptyPolls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Query().Get("replica") != "1" {
+			t.Errorf("replica = %q", r.URL.Query().Get("replica"))
+		}
+		var page LogPage
+		switch after := r.URL.Query().Get("after"); after {
+		case "-1":
+			page = LogPage{Live: true, Chunks: []LogChunk{{Seq: 0, Data: "a"}, {Seq: 1, Data: "b"}}, Next: 1}
+		case "1":
+			// Two empty polls while the job runs, then it finishes with one
+			// more chunk.
+			emptyPolls++
+			page = LogPage{Live: true, Chunks: []LogChunk{}, Next: 1}
+			if emptyPolls == 3 {
+				page = LogPage{Live: false, Chunks: []LogChunk{{Seq: 2, Data: "c"}}, Next: 2}
+			}
+		case "2":
+			page = LogPage{Live: false, Chunks: []LogChunk{}, Next: 2}
+		default:
+			t.Errorf("unexpected after=%s", after)
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+
+	var out strings.Builder
+	err := c.StreamLogs(context.Background(), "job-1", 1, func(ch LogChunk) error {
+		out.WriteString(ch.Data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamLogs: %v", err)
+	}
+	if out.String() != "abc" {
+		t.Errorf("streamed %q, want %q", out.String(), "abc")
+	}
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	content := []byte("tar bytes")
+	sum := sha256.Sum256(content)
+	recorded := hex.EncodeToString(sum[:])
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/jobs/job-1/artifacts" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Header().Set("X-Artifact-SHA256", recorded)
+		w.Write(content) //nolint:errcheck
+	})
+
+	var buf bytes.Buffer
+	a, err := c.DownloadArtifact(context.Background(), "job-1", &buf)
+	if err != nil {
+		t.Fatalf("DownloadArtifact: %v", err)
+	}
+	if a.SHA256 != recorded || a.SizeBytes != int64(len(content)) || !bytes.Equal(buf.Bytes(), content) {
+		t.Errorf("artifact = %+v, body %q", a, buf.String())
+	}
+
+	recorded = strings.Repeat("0", 64)
+	if _, err := c.DownloadArtifact(context.Background(), "job-1", &bytes.Buffer{}); !errors.Is(err, ErrArtifactChecksum) {
+		t.Errorf("corrupt download: err = %v, want ErrArtifactChecksum", err)
+	}
+}
@@ -0,0 +1,376 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// JobShape is what a job asks for and is priced on. Zero fields take the
+// portal's defaults: app_hosting, 2 cores, 4096 MB, no storage or GPU, the
+// workload type's runtime ceiling, and one replica. SLATier above 1 runs that
+// many replicas on nodes of distinct owners, each billed.
+type JobShape struct {
+	WorkloadType      string `json:"workload_type,omitempty"`
+	CPUCores          int    `json:"cpu_cores,omitempty"`
+	RAMMB             int    `json:"ram_mb,omitempty"`
+	StorageGB         int    `json:"storage_gb,omitempty"`
+	GPURequired       bool   `json:"gpu_required,omitempty"`
+	GPUVRAMGB         int    `json:"gpu_vram_gb,omitempty"`
+	MaxRuntimeSeconds int    `json:"max_runtime_seconds,omitempty"`
+	SLATier           int    `json:"sla_tier,omitempty"`
+}
+
+// JobInput is a file staged read-only for the job: an upload named by its
+// SHA-256, or a URL the node fetches and checks against it.
+type JobInput struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Placement says where a job may run and which nodes to try first.
+// RequiredNodeID, ExcludedOwnerIDs and NodeSelector are hard constraints, as
+// is AntiAffinity with AntiAffinityRequired; the preferences only rank the
+// nodes that can take the job. AntiAffinity "owner" keeps the job away from
+// the owners of the nodes running the caller's other active jobs, "region"
+// from their regions too. NodeSelector lists the node labels the job needs,
+// and a node with taints gets the job only if Tolerations cover each one; an
+// empty value in either matches any value of the key.
+type Placement struct {
+	RequiredNodeID       string   `json:"required_node_id,omitempty"`
+	PreferredNodeIDs     []string `json:"preferred_node_ids,omitempty"`
+	PreferredOwnerIDs    []string `json:"preferred_owner_ids,omitempty"`
+	ExcludedOwnerIDs     []string `json:"excluded_owner_ids,omitempty"`
+	AntiAffinity         string   `json:"anti_affinity,omitempty"`
+	AntiAffinityRequired bool     `json:"anti_affinity_required,omitempty"`
+
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+	Tolerations  []Toleration      `json:"tolerations,omitempty"`
+}
+
+// Toleration is one node taint a job accepts: Key with any value when Value
+// is empty.
+type Toleration struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// SubmitRequest is a job to submit. QuoteToken, from Estimate, holds the job
+// to the quoted price while it is valid. Quorum is how many replicas must
+// succeed (zero: 1, or 2 with Verify); Verify has them agree on the SHA-256
+// of the OutputPath file, for batch_compute jobs of SLATier 2 or more.
+type SubmitRequest struct {
+	JobShape
+	Quorum         int        `json:"quorum,omitempty"`
+	Verify         bool       `json:"verify,omitempty"`
+	ContainerImage string     `json:"container_image"`
+	OutputPath     string     `json:"output_path,omitempty"`
+	Inputs         []JobInput `json:"inputs,omitempty"`
+	QuoteToken     string     `json:"quote_token,omitempty"`
+	Placement      *Placement `json:"placement,omitempty"`
+}
+
+// SubmitResponse is a submitted job. An escrowed job is not dispatched until
+// its payment is authorized at PaymentURL.
+type SubmitResponse struct {
+	JobID      string `json:"job_id"`
+	NodeID     string `json:"node_id,omitempty"`
+	QuoteCents int64  `json:"quote_cents,omitempty"`
+	PaymentURL string `json:"payment_url,omitempty"`
+}
+
+// Job is one of the caller's jobs. Bill is set once the job is metered.
+type Job struct {
+	ID                string     `json:"id"`
+	WorkloadType      string     `json:"workload_type"`
+	Status            string     `json:"status"`
+	NodeID            string     `json:"node_id,omitempty"`
+	ContainerImage    string     `json:"container_image,omitempty"`
+	CPUCores          int        `json:"cpu_cores"`
+	RAMMB             int        `json:"ram_mb"`
+	MaxRuntimeSeconds int        `json:"max_runtime_seconds,omitempty"`
+	FailureCause      string     `json:"failure_cause,omitempty"`
+	PaymentStatus     string     `json:"payment_status,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	StartedAt         *time.Time `json:"started_at,omitempty"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	Bill              *Bill      `json:"bill,omitempty"`
+}
+
+// Terminal reports whether the job has reached a status it never leaves:
+// completed, failed, disputed, or a print job's delivered.
+func (j Job) Terminal() bool {
+	switch j.Status {
+	case "completed", "failed", "disputed", "delivered":
+		return true
+	}
+	return false
+}
+
+// Succeeded reports whether the job completed (or, for a print job, was
+// delivered).
+func (j Job) Succeeded() bool {
+	return j.Status == "completed" || j.Status == "delivered"
+}
+
+// Bill is what a metered job cost, line by line.
+type Bill struct {
+	UsageSource            string         `json:"usage_source"`
+	ConsumerPaidCents      int64          `json:"consumer_paid_cents"`
+	ContributorEarnedCents int64          `json:"contributor_earned_cents"`
+	PlatformFeeCents       int64          `json:"platform_fee_cents"`
+	FeeSeq                 *uint64        `json:"fee_seq"`
+	Lines                  []MeteringLine `json:"lines"`
+}
+
+// MeteringLine is one resource's charge on a Bill.
+type MeteringLine struct {
+	ResourceType string  `json:"resource_type"`
+	Quantity     float64 `json:"quantity"`
+	Rate         float64 `json:"rate"`
+	Multiplier   float64 `json:"multiplier"`
+	Cents        int64   `json:"cents"`
+}
+
+// ListOptions filters and pages ListJobs. Zero values list the newest 50 jobs
+// of any status.
+type ListOptions struct {
+	Status string
+	Limit  int
+	Offset int
+}
+
+// CancelResponse is a cancelled job: what it was charged for the time it ran,
+// and the refund of the rest (RefundStatus none, escrow, refunded or pending).
+type CancelResponse struct {
+	Status       string `json:"status"`
+	PriorStatus  string `json:"prior_status"`
+	ChargedCents int64  `json:"charged_cents"`
+	RefundCents  int64  `json:"refund_cents"`
+	RefundStatus string `json:"refund_status"`
+}
+
+// LogChunk is one piece of a job's container output.
+type LogChunk struct {
+	Seq    int64  `json:"seq"`
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+}
+
+// LogPage is a page of a job's output. Pass Next to Logs for the following
+// page; once a page is empty and Live is false there is no more.
+type LogPage struct {
+	JobID     string     `json:"job_id"`
+	Status    string     `json:"status"`
+	Live      bool       `json:"live"`
+	Truncated bool       `json:"truncated"`
+	Chunks    []LogChunk `json:"chunks"`
+	Next      int64      `json:"next"`
+}
+
+// LogOptions pages Logs. Limit zero takes the API default; Replica picks the
+// replica of a replicated job.
+type LogOptions struct {
+	Limit   int
+	Replica int
+}
+
+// EstimateRequest is a job shape to price, with how long it is expected to
+// run (zero: its max runtime). Placement's hard constraints narrow the
+// candidates priced.
+type EstimateRequest struct {
+	JobShape
+	ExpectedRuntimeSeconds int        `json:"expected_runtime_seconds,omitempty"`
+	Placement              *Placement `json:"placement,omitempty"`
+}
+
+// NodeEstimate is the price range on one candidate node.
+type NodeEstimate struct {
+	NodeID          string  `json:"node_id"`
+	CountryCode     string  `json:"country_code"`
+	PriceMultiplier float64 `json:"price_multiplier"`
+	LowCents        int64   `json:"low_cents"`
+	HighCents       int64   `json:"high_cents"`
+}
+
+// Estimate prices a job shape. QuoteToken, passed in SubmitRequest, holds the
+// job to these prices until ExpiresAt.
+type Estimate struct {
+	Candidates          []NodeEstimate `json:"candidates"`
+	Replicas            int            `json:"replicas"`
+	LowCents            int64          `json:"low_cents"`
+	HighCents           int64          `json:"high_cents"`
+	ContributorShareBps int            `json:"contributor_share_bps"`
+	PlatformFeeBps      int            `json:"platform_fee_bps"`
+	FeeSeq              *uint64        `json:"fee_seq"`
+	QuoteToken          string         `json:"quote_token"`
+	ExpiresAt           time.Time      `json:"expires_at"`
+}
+
+// Artifact describes a downloaded job output archive (a tar of /output).
+type Artifact struct {
+	SHA256    string
+	SizeBytes int64
+}
+
+// ErrArtifactChecksum is returned by DownloadArtifact when the bytes received
+// do not hash to the SHA-256 the portal recorded for the artifact.
+var ErrArtifactChecksum = errors.New("soholink: artifact checksum mismatch")
+
+// SubmitJob submits a job. Placement failures come back as an *Error with
+// CodeNoCapacity, CodeImageNotAllowlisted, CodeTypeMismatch or
+// CodeInvalidQuote, whose Message says what to change.
+func (c *Client) SubmitJob(ctx context.Context, req SubmitRequest) (SubmitResponse, error) {
+	var out SubmitResponse
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs", nil, req, &out)
+	return out, err
+}
+
+// GetJob returns one of the caller's jobs.
+func (c *Client) GetJob(ctx context.Context, jobID string) (Job, error) {
+	var out Job
+	err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(jobID), nil, nil, &out)
+	return out, err
+}
+
+// ListJobs lists the caller's jobs, newest first.
+func (c *Client) ListJobs(ctx context.Context, opts ListOptions) ([]Job, error) {
+	q := url.Values{}
+	if opts.Status != "" {
+		q.Set("status", opts.Status)
+	}
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	var out struct {
+		Jobs []Job `json:"jobs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/api/v1/jobs", q, nil, &out)
+	return out.Jobs, err
+}
+
+// CancelJob cancels a job. A job that can no longer be cancelled is an *Error
+// with CodeJobNotCancellable and its CurrentStatus.
+func (c *Client) CancelJob(ctx context.Context, jobID string) (CancelResponse, error) {
+	var out CancelResponse
+	err := c.do(ctx, http.MethodPost, "/api/v1/jobs/"+url.PathEscape(jobID)+"/cancel", nil, nil, &out)
+	return out, err
+}
+
+// Estimate prices a job shape on the nodes that could run it now.
+func (c *Client) Estimate(ctx context.Context, req EstimateRequest) (Estimate, error) {
+	var out Estimate
+	err := c.do(ctx, http.MethodPost, "/api/v1/estimate", nil, req, &out)
+	return out, err
+}
+
+// Logs returns the page of a job's output after chunk seq after: -1 for the
+// beginning, then the previous page's Next.
+func (c *Client) Logs(ctx context.Context, jobID string, after int64, opts LogOptions) (LogPage, error) {
+	q := url.Values{}
+	q.Set("after", strconv.FormatInt(after, 10))
+	if opts.Limit > 0 {
+		q.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Replica > 0 {
+		q.Set("replica", strconv.Itoa(opts.Replica))
+	}
+	var out LogPage
+	err := c.do(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(jobID)+"/logs", q, nil, &out)
+	return out, err
+}
+
+// StreamLogs calls fn with each chunk of a replica's output, from the
+// beginning, polling until the job has finished and all of it is read. An
+// error from fn stops the stream and is returned.
+func (c *Client) StreamLogs(ctx context.Context, jobID string, replica int, fn func(LogChunk) error) error {
+	after := int64(-1)
+	for {
+		page, err := c.Logs(ctx, jobID, after, LogOptions{Replica: replica})
+		if err != nil {
+			if ErrorCode(err) != CodeRateLimited {
+				return err
+			}
+			if err := pause(ctx, c.pollInterval, err); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, ch := range page.Chunks {
+			if err := fn(ch); err != nil {
+				return err
+			}
+		}
+		after = page.Next
+		if len(page.Chunks) > 0 {
+			continue
+		}
+		if !page.Live {
+			return nil
+		}
+		if err := pause(ctx, c.pollInterval, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// WatchJob polls a job until it is Terminal and returns it. fn, when not nil,
+// is called with the job each time its status changes, the first time
+// included; an error from fn stops the watch and is returned.
+func (c *Client) WatchJob(ctx context.Context, jobID string, fn func(Job) error) (Job, error) {
+	var last string
+	for {
+		job, err := c.GetJob(ctx, jobID)
+		if err != nil {
+			if ErrorCode(err) != CodeRateLimited {
+				return Job{}, err
+			}
+		} else {
+			if fn != nil && job.Status != last {
+				if err := fn(job); err != nil {
+					return job, err
+				}
+			}
+			last = job.Status
+			if job.Terminal() {
+				return job, nil
+			}
+		}
+		if err := pause(ctx, c.pollInterval, err); err != nil {
+			return Job{}, err
+		}
+	}
+}
+
+// DownloadArtifact writes a job's output archive to w, checking it against
+// the SHA-256 the portal recorded. On ErrArtifactChecksum w has received the
+// corrupt bytes; the caller discards them.
+func (c *Client) DownloadArtifact(ctx context.Context, jobID string, w io.Writer) (Artifact, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/api/v1/jobs/"+url.PathEscape(jobID)+"/artifacts", nil, nil)
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer resp.Body.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), resp.Body)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("soholink: download artifact of %s: %w", jobID, err)
+	}
+	a := Artifact{SHA256: resp.Header.Get("X-Artifact-SHA256"), SizeBytes: n}
+	if got := hex.EncodeToString(h.Sum(nil)); a.SHA256 != "" && got != a.SHA256 {
+		return a, fmt.Errorf("%w: got %s, want %s", ErrArtifactChecksum, got, a.SHA256)
+	}
+	return a, nil
+}
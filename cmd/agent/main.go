@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"log/slog"
@@ -12,6 +13,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -21,6 +23,7 @@ import (
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/agent"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
 )
 
 func mustEnv(key string) string {
@@ -184,6 +187,15 @@ plugins {
 		Region:           os.Getenv("AGENT_REGION"),
 		TokenSecret:      tokenSecret,
 	}
+	// Optional placement labels and taints, e.g. AGENT_LABELS="ssd,ups-backed"
+	// and AGENT_TAINTS="office-hours-only". Fail fast on a typo rather than
+	// let the control plane drop the report on every heartbeat.
+	if cfg.Labels, err = types.ParseNodeLabels(os.Getenv("AGENT_LABELS")); err != nil {
+		log.Fatalf("AGENT_LABELS: %v", err)
+	}
+	if cfg.Taints, err = types.ParseNodeLabels(os.Getenv("AGENT_TAINTS")); err != nil {
+		log.Fatalf("AGENT_TAINTS: %v", err)
+	}
 
 	hw := detectHW(ctx)
 	slog.Info("hardware detected",
@@ -208,7 +220,9 @@ plugins {
 	}
 	optOutStore := agent.NewOptOutStore(oo)
 
-	heartbeatAgent, err := agent.NewHeartbeatAgent(ctx, cfg, hw, optOutStore)
+	admission := agent.NewAdmission(maxConcurrentJobs(hw))
+
+	heartbeatAgent, err := agent.NewHeartbeatAgent(ctx, cfg, hw, optOutStore, admission)
 	if err != nil {
 		slog.Error("heartbeat agent init failed", "error", err)
 		os.Exit(1)
@@ -237,13 +251,42 @@ plugins {
 			slog.Info("shutting down")
 			return
 		case <-ticker.C:
-			jobs, err := heartbeatAgent.PollJobs(ctx)
+			polled, err := heartbeatAgent.PollJobs(ctx)
 			if err != nil {
 				slog.Warn("poll jobs failed", "error", err)
+			}
+			// Jobs deferred on an earlier tick are retried ahead of new
+			// assignments, longest-deferred first.
+			jobs := append(admission.Deferred(), polled...)
+			if len(jobs) == 0 {
 				continue
 			}
+			// Re-fetched each round so contributor edits take effect on the
+			// next job; on failure the last fetched set is still enforced.
+			// Until one fetch succeeds there is none, and jobs wait.
+			profiles, err := heartbeatAgent.FetchProfiles(ctx)
+			if errors.Is(err, agent.ErrProfilesNotFetched) {
+				slog.Warn("resource profiles not yet fetched — deferring jobs", "error", err)
+				deferJobs(ctx, heartbeatAgent, admission, time.Now(), jobs)
+				continue
+			}
+			if err != nil {
+				slog.Warn("fetch resource profiles failed, using cached", "error", err)
+			}
+			now := time.Now()
+			admission.SetBudget(agent.BudgetFor(agent.ResolveProfile(profiles, now), hw))
+			seen := make(map[string]bool, len(jobs))
 			for _, job := range jobs {
-				go runJob(ctx, executor, telemetryClient, cfg.ControlPlaneAddr, cfg.NodeID, tokenSecret, hw, job)
+				// A deferred job can reappear in polled if the orchestrator's
+				// dispatched expiry returned it to scheduled meanwhile.
+				if seen[job.JobID] {
+					continue
+				}
+				seen[job.JobID] = true
+				admitJob(ctx, heartbeatAgent, admission, profiles, now, hw, job,
+					func(job agent.JobAssignment, caps agent.CapProfile) {
+						runJob(ctx, executor, heartbeatAgent, telemetryClient, cfg.ControlPlaneAddr, cfg.NodeID, tokenSecret, hw, caps, job)
+					})
 			}
 		}
 	}
@@ -306,15 +349,111 @@ func detectHW(ctx context.Context) agent.HardwareProfile {
 	}
 }
 
-// runJob executes a single job assignment. It runs the container and
-// concurrently emits signed telemetry every 30 seconds until the container exits.
+// maxConcurrentJobs is the local job slot budget: AGENT_MAX_CONCURRENT_JOBS
+// when set to a positive integer, otherwise one slot per detected core.
+func maxConcurrentJobs(hw agent.HardwareProfile) int {
+	if v := os.Getenv("AGENT_MAX_CONCURRENT_JOBS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+		slog.Warn("ignoring invalid AGENT_MAX_CONCURRENT_JOBS", "value", v)
+	}
+	return max(hw.CPUCores, 1)
+}
+
+// admitJob runs job through admission control. A job the active profile can
+// never hold is declined at once; one that only lacks free capacity right now
+// is deferred and retried on later ticks, then declined once DeferWindow
+// passes. An admitted job starts on its own goroutine and releases its
+// reservation when run returns.
+func admitJob(
+	ctx context.Context,
+	heartbeatAgent *agent.HeartbeatAgent,
+	admission *agent.Admission,
+	profiles []agent.ResourceProfile,
+	now time.Time,
+	hw agent.HardwareProfile,
+	job agent.JobAssignment,
+	run func(agent.JobAssignment, agent.CapProfile),
+) {
+	caps, err := agent.JobCaps(profiles, now, hw, job)
+	if err != nil {
+		slog.Warn("job does not fit active resource profile — declining",
+			"job_id", job.JobID, "error", err)
+		if err := heartbeatAgent.DeclineJob(ctx, job.JobID, "exceeds_profile"); err != nil {
+			slog.Warn("decline failed", "job_id", job.JobID, "error", err)
+		}
+		return
+	}
+
+	if err := admission.Reserve(job.JobID, caps); err != nil {
+		if errors.Is(err, agent.ErrAlreadyAdmitted) {
+			return
+		}
+		if admission.Defer(job, now) {
+			slog.Info("job deferred — no free capacity", "job_id", job.JobID)
+			return
+		}
+		slog.Warn("job deferred too long — declining", "job_id", job.JobID)
+		if err := heartbeatAgent.DeclineJob(ctx, job.JobID, "no_capacity"); err != nil {
+			slog.Warn("decline failed", "job_id", job.JobID, "error", err)
+		}
+		return
+	}
+
+	go func() {
+		defer admission.Release(job.JobID)
+		run(job, caps)
+	}()
+}
+
+// deferJobs holds jobs for a later round without starting them — no caps
+// are known to enforce — and declines each one held past DeferWindow.
+func deferJobs(ctx context.Context, heartbeatAgent *agent.HeartbeatAgent, admission *agent.Admission, now time.Time, jobs []agent.JobAssignment) {
+	seen := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		if seen[job.JobID] {
+			continue
+		}
+		seen[job.JobID] = true
+		if admission.Defer(job, now) {
+			continue
+		}
+		slog.Warn("job deferred too long without resource profiles — declining", "job_id", job.JobID)
+		if err := heartbeatAgent.DeclineJob(ctx, job.JobID, "profiles_unavailable"); err != nil {
+			slog.Warn("decline failed", "job_id", job.JobID, "error", err)
+		}
+	}
+}
+
+// failureCause returns the failure_cause /complete reports for result: a
+// timeout when the executor stopped the job at its max runtime, else empty
+// (the control plane derives tmpfs exhaustion itself). C6 adds print-side
+// detection (filament runout, thermal runaway, print detachment).
+func failureCause(result agent.ExecutionResult) string {
+	if result.TimedOut {
+		return agent.FailureCauseTimeout
+	}
+	return ""
+}
+
+// logShipInterval is how often a running job's container output is shipped.
+const logShipInterval = 5 * time.Second
+
+// runJob executes a single job assignment under the caps admitJob reserved
+// for it. It stages the job's inputs, runs the container and concurrently
+// emits signed telemetry every 30 seconds and ships container output every
+// logShipInterval until the container exits, reaches the job's max runtime,
+// or is stopped by the control plane through the heartbeat.
 func runJob(
 	ctx context.Context,
 	executor *agent.Executor,
+	heartbeatAgent *agent.HeartbeatAgent,
 	telemetryClient *http.Client,
 	controlPlaneAddr, nodeID string,
 	tokenSecret []byte,
 	hw agent.HardwareProfile,
+	caps agent.CapProfile,
 	job agent.JobAssignment,
 ) {
 	// Validate inputs before starting the telemetry goroutine so early returns
@@ -331,7 +470,28 @@ func runJob(
 		return
 	}
 
-	slog.Info("starting job", "job_id", job.JobID)
+	// Stage inputs before starting anything: a job whose inputs cannot be
+	// fetched or do not match their digests is declined so the orchestrator
+	// can re-place it, rather than run without them.
+	var inputDir string
+	if len(job.Inputs) > 0 {
+		inputDir, err = agent.StageInputs(ctx, telemetryClient, controlPlaneAddr, job.JobID, job.Inputs, caps.StorageBytes)
+		if err != nil {
+			slog.Warn("input staging failed — declining", "job_id", job.JobID, "error", err)
+			if err := heartbeatAgent.DeclineJob(ctx, job.JobID, "input_staging_failed"); err != nil {
+				slog.Warn("decline failed", "job_id", job.JobID, "error", err)
+			}
+			return
+		}
+		defer func() {
+			if err := os.RemoveAll(inputDir); err != nil {
+				slog.Warn("input cleanup failed", "job_id", job.JobID, "error", err)
+			}
+		}()
+	}
+
+	slog.Info("starting job", "job_id", job.JobID,
+		"cpu_cores", caps.CPUCores, "ram_bytes", caps.RAMBytes, "storage_bytes", caps.StorageBytes)
 
 	done := make(chan struct{})
 	go func() {
@@ -354,16 +514,21 @@ func runJob(
 		}
 	}()
 
+	// Container output is shipped to the control plane for the consumer's
+	// log tail, with the job token and likely credentials redacted.
+	logs := agent.NewLogShipper(telemetryClient, controlPlaneAddr, job.JobID, agent.NewRedactor(job.JobToken))
+
 	spec := agent.ContainerSpec{
 		Image:          job.Image,
 		JobID:          job.JobID,
 		JobToken:       job.JobToken,
 		ConnectionPath: connectionPath,
-		Caps: agent.CapProfile{
-			CPUEnabled: true,
-			CPUCores:   hw.CPUCores,
-			RAMBytes:   hw.RAMMB * 1024 * 1024,
-		},
+		Caps:           caps,
+		OutputPath:     job.OutputPath,
+		InputDir:       inputDir,
+		Stdout:         logs.Stdout(),
+		Stderr:         logs.Stderr(),
+		MaxRuntime:     time.Duration(job.MaxRuntimeSeconds) * time.Second,
 	}
 
 	// Start the container. On error, stop the telemetry goroutine and bail.
@@ -403,13 +568,46 @@ func runJob(
 	}
 	startedResp.Body.Close()
 
+	logCtx, stopLogs := context.WithCancel(ctx)
+	go logs.Run(logCtx, logShipInterval)
+
+	// A heartbeat naming this job in stop_jobs (the consumer cancelled it)
+	// stops the container; Wait then returns with result.Stopped.
+	untrack := heartbeatAgent.TrackJob(job.JobID, func() {
+		_ = executor.Stop(context.Background(), ec)
+	})
 	result, err := executor.Wait(ctx, ec)
+	untrack()
 	close(done)
+	stopLogs()
+	// Final flush before /complete, so the log tail is whole when the
+	// consumer sees the job finish.
+	if err := logs.Close(ctx); err != nil {
+		slog.Warn("log shipping failed", "job_id", job.JobID, "error", err)
+	}
 
 	if err != nil {
 		slog.Error("job execution error", "job_id", job.JobID, "error", err)
 		return
 	}
+	// The control plane already settled a stopped job; there is nothing to
+	// upload or complete.
+	if result.Stopped {
+		slog.Info("job stopped by control plane", "job_id", job.JobID)
+		return
+	}
+
+	// Upload the output artifact while the job is still running — the
+	// control plane only accepts it before /complete. A failed upload is
+	// logged; the job still completes.
+	if result.Artifact != nil {
+		if err := agent.UploadArtifact(ctx, telemetryClient, controlPlaneAddr, job.JobID, result.Artifact); err != nil {
+			slog.Warn("artifact upload failed", "job_id", job.JobID, "error", err)
+		}
+		if err := os.Remove(result.Artifact.Path); err != nil {
+			slog.Warn("artifact spool cleanup failed", "job_id", job.JobID, "error", err)
+		}
+	}
 
 	// Signal job completion to the control plane so it can set completed_at
 	// and trigger metering. Only called on successful execution.
@@ -418,11 +616,12 @@ func runJob(
 		ExitCode       int    `json:"exit_code"`
 		FailureCause   string `json:"failure_cause,omitempty"`
 		TmpfsExhausted bool   `json:"tmpfs_exhausted,omitempty"`
+		ResultHash     string `json:"result_hash,omitempty"`
 	}{
 		ExitCode:       result.ExitCode,
+		FailureCause:   failureCause(result),
 		TmpfsExhausted: result.TmpfsExhausted,
-		// FailureCause stays empty for C3; C6 adds agent-side detection
-		// (filament runout, thermal runaway, print detachment).
+		ResultHash:     result.ResultHash,
 	})
 	req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, completeURL, bytes.NewReader(completeBody))
 	if reqErr == nil {
@@ -130,6 +130,11 @@ func main() {
 	// Demand-sounding dashboard read model over the migration-025 hypertables.
 	// LOCAL-ONLY, like the rest of the :8090 surface.
 	gov.ConfigureSounding(sounding.NewReader(db))
+	// Stripe reconciliation report, read from the runs the portal's reconciler
+	// stores. This process never holds the Stripe key.
+	gov.ConfigureReconciliation(api.NewReconciliationReader(db))
+	// Effective-dated resource_pricing rates and their audit trail.
+	gov.ConfigurePricing(api.NewPricingStore(db))
 
 	go func() {
 		slog.Info("governance server listening (loopback only)", "addr", addr)
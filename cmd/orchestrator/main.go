@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/api"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/operator"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
@@ -129,8 +130,16 @@ func main() {
 
 	printConfirmEnabled, _ := strconv.ParseBool(os.Getenv("PRINT_CONFIRMATION_ENABLED"))
 
+	// SCHEDULER_STRATEGY: "spread" (default) or "binpack".
+	strategy, err := scheduler.ParseStrategy(os.Getenv("SCHEDULER_STRATEGY"))
+	if err != nil {
+		slog.Error("invalid SCHEDULER_STRATEGY", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("scheduler strategy", "strategy", strategy)
+
 	registry := orchestrator.NewNodeRegistry()
-	orch := orchestrator.New(db, registry, tokenSecret, scheduler.Schedule, allowlistPath, printConfirmEnabled, 4*time.Hour)
+	orch := orchestrator.New(db, registry, tokenSecret, scheduler.ScheduleWith(strategy), allowlistPath, printConfirmEnabled, 4*time.Hour)
 
 	// Demand-sounding telemetry (step 2): async, fire-and-forget, fail-open.
 	// The sink drains until ctx is cancelled; the rung ladder is loaded once
@@ -147,6 +156,11 @@ func main() {
 	sounding.StartCapacitySampler(ctx, registry.CapacityInputs, demandSink, time.Minute)
 
 	orchestrator.StartEvictionLoop(ctx, registry, 5*time.Minute)
+	if err := orch.RestoreReservations(ctx); err != nil {
+		// Degrades to under-counted reservations until jobs turn over;
+		// placement keeps working.
+		slog.Warn("restore node reservations failed", "error", err)
+	}
 	orch.StartDeclineRerouteLoop(ctx)
 
 	// sohocloud-protocol /v0 surface (B4 milestone): the adapter delegates to
@@ -164,7 +178,25 @@ func main() {
 	}
 	protocolV0 := protocoladapter.NewHandler(adapter, idSource, idSource == nil, v0Gate)
 
-	srv := api.New(db, registry, idSource, apiAddr, metricsAddr, allowlistPath, protocolV0, repo, coordinatorID)
+	// Job output artifacts: ARTIFACT_STORE selects the backend (see
+	// artifact.FromEnv); the portal must be configured with the same one.
+	artifacts, err := artifact.FromEnv()
+	if err != nil {
+		slog.Error("artifact store init failed", "error", err)
+		os.Exit(1)
+	}
+	artifactLimits, err := artifact.LimitsFromEnv()
+	if err != nil {
+		slog.Error("invalid artifact limits", "error", err)
+		os.Exit(1)
+	}
+	go func() {
+		if err := store.RunArtifactReaper(ctx, db, artifacts, time.Hour); err != nil {
+			slog.Error("artifact reaper exited", "error", err)
+		}
+	}()
+
+	srv := api.New(db, registry, idSource, apiAddr, metricsAddr, allowlistPath, protocolV0, repo, coordinatorID, artifacts, artifactLimits)
 	internalSrv := api.NewInternal(orch, internalAddr)
 
 	go func() {
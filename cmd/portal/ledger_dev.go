@@ -0,0 +1,10 @@
+//go:build devledger
+
+package main
+
+import "github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+
+// devLedger returns the in-memory ledger provider. It is compiled in only
+// with -tags devledger: its balances live in process memory and are lost on
+// restart, so a production portal must never settle through it.
+func devLedger() (payment.PaymentProvider, bool) { return payment.NewLedger(), true }
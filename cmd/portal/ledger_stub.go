@@ -0,0 +1,9 @@
+//go:build !devledger
+
+package main
+
+import "github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+
+// devLedger reports that this build has no ledger provider. Build with
+// -tags devledger for dev and CI instances that settle without Stripe.
+func devLedger() (payment.PaymentProvider, bool) { return nil, false }
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/api"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/notify"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/operator"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchclient"
@@ -41,6 +42,30 @@ func buildNotifier() notify.Notifier {
 	})
 }
 
+// buildPaymentProvider constructs the PaymentProvider selected by
+// PAYMENT_PROVIDER: "stripe" (the default) reads STRIPE_SECRET_KEY and
+// STRIPE_WEBHOOK_SECRET; "ledger" settles in an in-memory ledger with no
+// Stripe account, for dev and CI only — it is refused unless the portal was
+// built with -tags devledger, since its balances are lost on restart.
+// Returns the webhook secret alongside, empty for the ledger — the Stripe
+// webhook then rejects every request.
+func buildPaymentProvider() (payment.PaymentProvider, string) {
+	switch p := os.Getenv("PAYMENT_PROVIDER"); p {
+	case "", "stripe":
+		return payment.New(mustEnv("STRIPE_SECRET_KEY")), mustEnv("STRIPE_WEBHOOK_SECRET")
+	case "ledger":
+		pc, ok := devLedger()
+		if !ok {
+			log.Fatalf("PAYMENT_PROVIDER=ledger is for dev and CI only; build the portal with -tags devledger to use it")
+		}
+		slog.Warn("PAYMENT_PROVIDER=ledger; money moves in an in-memory ledger only (no Stripe calls, lost on restart)")
+		return pc, ""
+	default:
+		log.Fatalf("PAYMENT_PROVIDER: unknown provider %q (want stripe or ledger)", p)
+		return nil, ""
+	}
+}
+
 func mustEnv(key string) string {
 	v := os.Getenv(key)
 	if v == "" {
@@ -77,12 +102,10 @@ func main() {
 
 	dbURL := mustEnv("DATABASE_URL")
 	sessionPrivKey := mustEd25519Key("SESSION_PRIVATE_KEY")
-	stripeKey := mustEnv("STRIPE_SECRET_KEY")
 	addr := mustEnv("PORTAL_ADDR")
 	baseURL := mustEnv("PORTAL_BASE_URL")
 	templatesDir := mustEnv("PORTAL_TEMPLATES_DIR")
 	metricsAddr := mustEnv("METRICS_ADDR")
-	webhookSecret := mustEnv("STRIPE_WEBHOOK_SECRET")
 	internalOrchURL := mustEnv("ORCHESTRATOR_INTERNAL_URL")
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
@@ -100,7 +123,7 @@ func main() {
 	}
 	slog.Info("migrations applied")
 
-	paymentClient := payment.New(stripeKey)
+	paymentClient, webhookSecret := buildPaymentProvider()
 
 	orch := orchclient.New(internalOrchURL)
 
@@ -122,8 +145,37 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Job output artifacts are read from the store the orchestrator uploads
+	// into; ARTIFACT_STORE and friends must match its configuration. Job
+	// inputs are uploaded into the same store.
+	artifacts, err := artifact.FromEnv()
+	if err != nil {
+		slog.Error("artifact store init failed", "error", err)
+		os.Exit(1)
+	}
+	artifactLimits, err := artifact.LimitsFromEnv()
+	if err != nil {
+		slog.Error("artifact limits invalid", "error", err)
+		os.Exit(1)
+	}
+
+	portalOpts := []portal.Option{
+		portal.WithOperatorConsole(onboarding.RegisterRoutes),
+		portal.WithArtifactStore(artifacts, artifactLimits),
+	}
+	// Escrow-in: with a publishable key the consumer authorizes a payment
+	// hold for each job on a Stripe.js page before it is dispatched, and the
+	// settler below captures the metered cost. Without one, jobs run unpaid
+	// as before. The ledger provider has no Stripe.js page to authorize on.
+	_, isLedger := paymentClient.(*payment.Ledger)
+	if publishableKey := os.Getenv("STRIPE_PUBLISHABLE_KEY"); publishableKey != "" && !isLedger {
+		portalOpts = append(portalOpts, portal.WithEscrow(publishableKey))
+	} else {
+		slog.Warn("escrow disabled (STRIPE_PUBLISHABLE_KEY not set, or ledger provider); jobs are submitted without escrow")
+	}
+
 	ps, err := portal.New(db, addr, sessionPrivKey, templatesDir, paymentClient, baseURL, orch, metricsAddr, webhookSecret,
-		portal.WithOperatorConsole(onboarding.RegisterRoutes))
+		portalOpts...)
 	if err != nil {
 		slog.Error("portal init failed", "error", err)
 		os.Exit(1)
@@ -148,6 +200,30 @@ func main() {
 		}
 	}()
 
+	go func() {
+		if err := store.RunLedgerChecker(ctx, db, time.Hour); err != nil {
+			slog.Error("ledger checker exited", "error", err)
+		}
+	}()
+
+	go func() {
+		if err := store.RunReconciler(ctx, db, paymentClient, 6*time.Hour); err != nil {
+			slog.Error("reconciler exited", "error", err)
+		}
+	}()
+
+	go func() {
+		if err := store.RunEscrowSettler(ctx, db, paymentClient, time.Minute); err != nil {
+			slog.Error("escrow settler exited", "error", err)
+		}
+	}()
+
+	go func() {
+		if err := store.RunCancelRefunder(ctx, db, paymentClient, 10*time.Minute); err != nil {
+			slog.Error("cancel refunder exited", "error", err)
+		}
+	}()
+
 	go func() {
 		if err := ps.StartMetrics(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("metrics server error", "error", err)
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/client"
+)
+
+// runConfigure saves --api-url and --api-key as the selected profile. With no
+// --api-key the key is read from stdin, so it stays out of shell history.
+func runConfigure(_ context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("configure", "--api-url URL [--api-key KEY]")
+	apiURL := fs.String("api-url", "", "portal base URL, e.g. https://portal.example.org")
+	apiKey := fs.String("api-key", "", "API key from the portal's API keys page (default: read from stdin)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *apiURL == "" {
+		fs.Usage()
+		return errUsage
+	}
+	if *apiKey == "" {
+		fmt.Fprint(c.stderr, "API key: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("read API key: %w", err)
+		}
+		*apiKey = strings.TrimSpace(line)
+		if *apiKey == "" {
+			return errors.New("no API key given")
+		}
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+	cfg.Profiles[c.profile] = profile{APIURL: strings.TrimSuffix(*apiURL, "/"), APIKey: *apiKey}
+	if err := saveConfig(path, cfg); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.stderr, "saved profile %q to %s\n", c.profile, path)
+	return nil
+}
+
+// shapeFlags are the job shape flags submit and estimate share.
+type shapeFlags struct {
+	workloadType string
+	cpuCores     int
+	ramMB        int
+	storageGB    int
+	gpu          bool
+	gpuVRAMGB    int
+	maxRuntime   time.Duration
+	slaTier      int
+}
+
+func (s *shapeFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&s.workloadType, "type", "", "workload type (default app_hosting)")
+	fs.IntVar(&s.cpuCores, "cpu", 0, "CPU cores (default 2)")
+	fs.IntVar(&s.ramMB, "ram-mb", 0, "memory in MB (default 4096)")
+	fs.IntVar(&s.storageGB, "storage-gb", 0, "disk to reserve in GB")
+	fs.BoolVar(&s.gpu, "gpu", false, "run only on a node with a GPU")
+	fs.IntVar(&s.gpuVRAMGB, "gpu-vram-gb", 0, "GPU memory to reserve in GB (requires --gpu)")
+	fs.DurationVar(&s.maxRuntime, "max-runtime", 0, "runtime limit, e.g. 2h (default: the workload type's ceiling)")
+	fs.IntVar(&s.slaTier, "sla-tier", 0, "replicas on distinct owners' nodes: 1 standard, 2 reliable, 3 premium (default 1)")
+}
+
+func (s *shapeFlags) shape() client.JobShape {
+	return client.JobShape{
+		WorkloadType:      s.workloadType,
+		CPUCores:          s.cpuCores,
+		RAMMB:             s.ramMB,
+		StorageGB:         s.storageGB,
+		GPURequired:       s.gpu,
+		GPUVRAMGB:         s.gpuVRAMGB,
+		MaxRuntimeSeconds: int(s.maxRuntime / time.Second),
+		SLATier:           s.slaTier,
+	}
+}
+
+// placementFlags are the placement constraint flags submit and estimate
+// share.
+type placementFlags struct {
+	node           string
+	preferNodes    listFlag
+	preferOwners   listFlag
+	excludeOwners  listFlag
+	spread         string
+	spreadRequired bool
+	selectors      listFlag
+	tolerations    listFlag
+}
+
+func (p *placementFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&p.node, "node", "", "run only on this node")
+	fs.Var(&p.preferNodes, "prefer-node", "try this node first; repeatable")
+	fs.Var(&p.preferOwners, "prefer-owner", "try this contributor's nodes first; repeatable")
+	fs.Var(&p.excludeOwners, "exclude-owner", "never run on this contributor's nodes; repeatable")
+	fs.StringVar(&p.spread, "spread", "", "keep away from the owners (owner) or regions (region) running your other jobs")
+	fs.BoolVar(&p.spreadRequired, "spread-required", false, "fail rather than break --spread")
+	fs.Var(&p.selectors, "selector", "run only on nodes labelled KEY or KEY=VALUE; repeatable")
+	fs.Var(&p.tolerations, "toleration", "accept nodes tainted KEY (any value) or KEY=VALUE; repeatable")
+}
+
+// placement returns the flags as a client.Placement, or nil when none is set.
+func (p *placementFlags) placement() *client.Placement {
+	pl := client.Placement{
+		RequiredNodeID:       p.node,
+		PreferredNodeIDs:     p.preferNodes,
+		PreferredOwnerIDs:    p.preferOwners,
+		ExcludedOwnerIDs:     p.excludeOwners,
+		AntiAffinity:         p.spread,
+		AntiAffinityRequired: p.spreadRequired,
+	}
+	for _, s := range p.selectors {
+		if pl.NodeSelector == nil {
+			pl.NodeSelector = map[string]string{}
+		}
+		k, v, _ := strings.Cut(s, "=")
+		pl.NodeSelector[k] = v
+	}
+	for _, t := range p.tolerations {
+		k, v, _ := strings.Cut(t, "=")
+		pl.Tolerations = append(pl.Tolerations, client.Toleration{Key: k, Value: v})
+	}
+	if pl.RequiredNodeID == "" && len(pl.PreferredNodeIDs) == 0 && len(pl.PreferredOwnerIDs) == 0 &&
+		len(pl.ExcludedOwnerIDs) == 0 && pl.AntiAffinity == "" && !pl.AntiAffinityRequired &&
+		len(pl.NodeSelector) == 0 && len(pl.Tolerations) == 0 {
+		return nil
+	}
+	return &pl
+}
+
+// listFlag collects a repeated string flag.
+type listFlag []string
+
+func (f *listFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *listFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// inputFlag collects repeated --input NAME=SHA256[@URL] flags.
+type inputFlag []client.JobInput
+
+func (f *inputFlag) String() string { return "" }
+
+func (f *inputFlag) Set(v string) error {
+	name, rest, ok := strings.Cut(v, "=")
+	if !ok || name == "" || rest == "" {
+		return errors.New("want NAME=SHA256 or NAME=SHA256@URL")
+	}
+	sum, url, _ := strings.Cut(rest, "@")
+	*f = append(*f, client.JobInput{Name: name, SHA256: strings.ToLower(sum), URL: url})
+	return nil
+}
+
+// runSubmit submits a job and, with --wait, follows it to a terminal state.
+func runSubmit(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("submit", "--image IMAGE [flags]")
+	var shape shapeFlags
+	shape.register(fs)
+	var placement placementFlags
+	placement.register(fs)
+	image := fs.String("image", "", "allowlisted container image (required)")
+	output := fs.String("output", "", "output path under /output to keep as the job's artifact")
+	quote := fs.String("quote", "", "quote token from soholink estimate, to hold the quoted price")
+	quorum := fs.Int("quorum", 0, "replicas that must succeed (default 1, or 2 with --verify)")
+	verify := fs.Bool("verify", false, "have the replicas agree on the hash of the --output file (batch_compute, --sla-tier 2 or more)")
+	wait := fs.Bool("wait", false, "follow the job until it finishes; exit 1 unless it succeeds")
+	var inputs inputFlag
+	fs.Var(&inputs, "input", "input file NAME=SHA256 (an upload) or NAME=SHA256@URL; repeatable")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *image == "" {
+		fs.Usage()
+		return errUsage
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	resp, err := api.SubmitJob(ctx, client.SubmitRequest{
+		JobShape:       shape.shape(),
+		ContainerImage: *image,
+		OutputPath:     *output,
+		Quorum:         *quorum,
+		Verify:         *verify,
+		Inputs:         inputs,
+		QuoteToken:     *quote,
+		Placement:      placement.placement(),
+	})
+	if err != nil {
+		return err
+	}
+	if resp.PaymentURL != "" && *wait {
+		// An escrowed job waits for its payment, which only a person in a
+		// browser can authorize; say so before waiting on it.
+		fmt.Fprintf(c.stderr, "job %s needs payment (%s) before it runs: %s\n",
+			resp.JobID, cents(resp.QuoteCents), resp.PaymentURL)
+	}
+	if *wait {
+		return c.waitJob(ctx, api, resp.JobID)
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+	fmt.Fprintf(c.stdout, "%s\n", resp.JobID)
+	if resp.NodeID != "" {
+		fmt.Fprintf(c.stderr, "submitted to node %s\n", resp.NodeID)
+	}
+	if resp.PaymentURL != "" {
+		fmt.Fprintf(c.stderr, "authorize payment of up to %s to run it: %s\n", cents(resp.QuoteCents), resp.PaymentURL)
+	}
+	return nil
+}
+
+// waitJob follows jobID to a terminal state, reporting each status change on
+// stderr, then prints the job. A job that did not succeed is errJobFailed.
+func (c *cli) waitJob(ctx context.Context, api *client.Client, jobID string) error {
+	job, err := api.WatchJob(ctx, jobID, func(j client.Job) error {
+		if !c.json {
+			fmt.Fprintf(c.stderr, "%s  %s\n", time.Now().Format(time.TimeOnly), j.Status)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.printJob(job); err != nil {
+		return err
+	}
+	if !job.Succeeded() {
+		return errJobFailed
+	}
+	return nil
+}
+
+// printJob prints a job as JSON or as aligned fields.
+func (c *cli) printJob(j client.Job) error {
+	if c.json {
+		return c.printJSON(j)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "id\t%s\n", j.ID)
+	fmt.Fprintf(tw, "status\t%s\n", j.Status)
+	if j.FailureCause != "" {
+		fmt.Fprintf(tw, "failure cause\t%s\n", j.FailureCause)
+	}
+	fmt.Fprintf(tw, "workload type\t%s\n", j.WorkloadType)
+	if j.ContainerImage != "" {
+		fmt.Fprintf(tw, "image\t%s\n", j.ContainerImage)
+	}
+	fmt.Fprintf(tw, "resources\t%d cores, %d MB\n", j.CPUCores, j.RAMMB)
+	if j.NodeID != "" {
+		fmt.Fprintf(tw, "node\t%s\n", j.NodeID)
+	}
+	if j.PaymentStatus != "" {
+		fmt.Fprintf(tw, "payment\t%s\n", j.PaymentStatus)
+	}
+	fmt.Fprintf(tw, "created\t%s\n", j.CreatedAt.Format(time.RFC3339))
+	if j.StartedAt != nil {
+		fmt.Fprintf(tw, "started\t%s\n", j.StartedAt.Format(time.RFC3339))
+	}
+	if j.CompletedAt != nil {
+		fmt.Fprintf(tw, "completed\t%s\n", j.CompletedAt.Format(time.RFC3339))
+	}
+	if j.Bill != nil {
+		fmt.Fprintf(tw, "cost\t%s\n", cents(j.Bill.ConsumerPaidCents))
+	}
+	return tw.Flush()
+}
+
+// runEstimate prices a job shape.
+func runEstimate(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("estimate", "[flags]")
+	var shape shapeFlags
+	shape.register(fs)
+	var placement placementFlags
+	placement.register(fs)
+	expected := fs.Duration("expected-runtime", 0, "how long the job is expected to run (default: its max runtime)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	est, err := api.Estimate(ctx, client.EstimateRequest{
+		JobShape:               shape.shape(),
+		ExpectedRuntimeSeconds: int(*expected / time.Second),
+		Placement:              placement.placement(),
+	})
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(est)
+	}
+	fmt.Fprintf(c.stdout, "estimate: %s – %s on %d candidate node(s)\n",
+		cents(est.LowCents), cents(est.HighCents), len(est.Candidates))
+	fmt.Fprintf(c.stdout, "quote token (valid until %s):\n%s\n", est.ExpiresAt.Format(time.RFC3339), est.QuoteToken)
+	return nil
+}
+
+// runList lists the caller's jobs.
+func runList(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("list", "[flags]")
+	status := fs.String("status", "", "only jobs in this status")
+	limit := fs.Int("limit", 0, "at most this many jobs (default 50, at most 200)")
+	offset := fs.Int("offset", 0, "skip this many jobs")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := api.ListJobs(ctx, client.ListOptions{Status: *status, Limit: *limit, Offset: *offset})
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(jobs)
+	}
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSTATUS\tTYPE\tCREATED")
+	for _, j := range jobs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", j.ID, j.Status, j.WorkloadType, j.CreatedAt.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
+
+// runStatus shows a job and, with --wait, follows it to a terminal state.
+func runStatus(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("status", "JOB_ID [--wait]")
+	wait := fs.Bool("wait", false, "follow the job until it finishes; exit 1 unless it succeeds")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	jobID, err := oneJobID(fs, positional)
+	if err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	if *wait {
+		return c.waitJob(ctx, api, jobID)
+	}
+	job, err := api.GetJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	return c.printJob(job)
+}
+
+// runLogs prints a job's output: stdout chunks to stdout and stderr chunks to
+// stderr, or in JSON mode one chunk object per line. --follow keeps polling
+// until the job finishes.
+func runLogs(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("logs", "JOB_ID [--follow] [--replica N]")
+	follow := fs.Bool("follow", false, "keep printing output until the job finishes")
+	replica := fs.Int("replica", 0, "replica of a replicated job")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	jobID, err := oneJobID(fs, positional)
+	if err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	emit := func(ch client.LogChunk) error {
+		if c.json {
+			return c.printJSONLine(ch)
+		}
+		w := c.stdout
+		if ch.Stream == "stderr" {
+			w = c.stderr
+		}
+		_, err := io.WriteString(w, ch.Data)
+		return err
+	}
+	if *follow {
+		return api.StreamLogs(ctx, jobID, *replica, emit)
+	}
+	after := int64(-1)
+	for {
+		page, err := api.Logs(ctx, jobID, after, client.LogOptions{Replica: *replica})
+		if err != nil {
+			return err
+		}
+		for _, ch := range page.Chunks {
+			if err := emit(ch); err != nil {
+				return err
+			}
+		}
+		if len(page.Chunks) == 0 {
+			return nil
+		}
+		after = page.Next
+	}
+}
+
+// printJSONLine writes v to stdout as one line of JSON.
+func (c *cli) printJSONLine(v any) error {
+	return json.NewEncoder(c.stdout).Encode(v)
+}
+
+// runCancel cancels a job.
+func runCancel(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("cancel", "JOB_ID")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	jobID, err := oneJobID(fs, positional)
+	if err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	resp, err := api.CancelJob(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(resp)
+	}
+	fmt.Fprintf(c.stdout, "cancelled %s (was %s); charged %s, refund %s (%s)\n",
+		jobID, resp.PriorStatus, cents(resp.ChargedCents), cents(resp.RefundCents), resp.RefundStatus)
+	return nil
+}
+
+// runDownload writes a job's output archive to --out (default
+// JOB_ID-output.tar), or to stdout with --out -. The file appears only once
+// the download is complete and its checksum verified.
+func runDownload(ctx context.Context, c *cli, args []string) error {
+	fs := c.newFlagSet("download", "JOB_ID [--out FILE]")
+	out := fs.String("out", "", "file to write, or - for stdout (default JOB_ID-output.tar)")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	jobID, err := oneJobID(fs, positional)
+	if err != nil {
+		return err
+	}
+	api, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	if *out == "-" {
+		_, err := api.DownloadArtifact(ctx, jobID, c.stdout)
+		return err
+	}
+	path := *out
+	if path == "" {
+		path = jobID + "-output.tar"
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".soholink-download-*")
+	if err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op once renamed
+	a, err := api.DownloadArtifact(ctx, jobID, tmp)
+	if cerr := tmp.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("download: %w", cerr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	if c.json {
+		return c.printJSON(map[string]any{"path": path, "sha256": a.SHA256, "size_bytes": a.SizeBytes})
+	}
+	fmt.Fprintf(c.stderr, "wrote %s (%d bytes, sha256 %s)\n", path, a.SizeBytes, a.SHA256)
+	return nil
+}
+
+// cents formats an amount in US cents as dollars.
+func cents(c int64) string {
+	return fmt.Sprintf("$%d.%02d", c/100, c%100)
+}
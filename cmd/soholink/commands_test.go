@@ -0,0 +1,339 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/client"
+)
+
+const testKey = "shk_0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+// serveAPI points the CLI at an httptest server running handler, checking
+// every request carries the API key.
+func serveAPI(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer "+testKey {
+			t.Errorf("Authorization = %q", auth)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("SOHOLINK_API_URL", srv.URL)
+	t.Setenv("SOHOLINK_API_KEY", testKey)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func TestSubmit(t *testing.T) {
+	var got client.SubmitRequest
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/jobs" {
+			t.Errorf("request: %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		writeJSON(w, http.StatusCreated, client.SubmitResponse{JobID: "job-1", NodeID: "node-1"})
+	})
+
+	code, stdout, stderr := runCLI(t, "submit", "--image", "soholink/compute-worker@sha256:00",
+		"--cpu", "4", "--input", "data.csv=AB", "--selector", "ssd")
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, stderr)
+	}
+	if stdout != "job-1\n" {
+		t.Errorf("stdout = %q, want the job ID", stdout)
+	}
+	if !strings.Contains(stderr, "submitted to node node-1") {
+		t.Errorf("stderr = %q", stderr)
+	}
+	if got.ContainerImage != "soholink/compute-worker@sha256:00" || got.CPUCores != 4 ||
+		len(got.Inputs) != 1 || got.Inputs[0] != (client.JobInput{Name: "data.csv", SHA256: "ab"}) ||
+		got.Placement == nil || len(got.Placement.NodeSelector) != 1 {
+		t.Errorf("request = %+v", got)
+	}
+}
+
+func TestSubmit_Verified(t *testing.T) {
+	var got map[string]any
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		writeJSON(w, http.StatusCreated, client.SubmitResponse{JobID: "job-1"})
+	})
+
+	code, _, stderr := runCLI(t, "submit", "--image", "img", "--type", "batch_compute",
+		"--sla-tier", "2", "--verify", "--output", "/output/result.bin", "--storage-gb", "20", "--gpu", "--gpu-vram-gb", "8")
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, stderr)
+	}
+	want := map[string]any{
+		"sla_tier": float64(2), "verify": true, "output_path": "/output/result.bin",
+		"storage_gb": float64(20), "gpu_required": true, "gpu_vram_gb": float64(8),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("request %s = %v, want %v", k, got[k], v)
+		}
+	}
+	if _, ok := got["quorum"]; ok {
+		t.Errorf("request carries quorum %v; omitted, the portal's default applies", got["quorum"])
+	}
+}
+
+func TestSubmit_JSON(t *testing.T) {
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusCreated, client.SubmitResponse{
+			JobID: "job-1", QuoteCents: 725, PaymentURL: "https://pay.example.org/1",
+		})
+	})
+
+	code, stdout, stderr := runCLI(t, "--json", "submit", "--image", "img")
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, stderr)
+	}
+	var resp client.SubmitResponse
+	if err := json.Unmarshal([]byte(stdout), &resp); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, stdout)
+	}
+	if resp.JobID != "job-1" || resp.PaymentURL != "https://pay.example.org/1" {
+		t.Errorf("printed %+v", resp)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		job        client.Job
+		want       int
+		wantStdout string
+	}{
+		{
+			name:       "running",
+			args:       []string{"status", "job-1"},
+			job:        client.Job{ID: "job-1", Status: "running", WorkloadType: "batch_compute", CPUCores: 2, RAMMB: 4096},
+			want:       exitOK,
+			wantStdout: "status         running",
+		},
+		{
+			name:       "wait for success",
+			args:       []string{"status", "job-1", "--wait"},
+			job:        client.Job{ID: "job-1", Status: "completed", Bill: &client.Bill{ConsumerPaidCents: 725}},
+			want:       exitOK,
+			wantStdout: "cost           $7.25",
+		},
+		{
+			name:       "wait for failure",
+			args:       []string{"status", "--wait", "job-1"},
+			job:        client.Job{ID: "job-1", Status: "failed", FailureCause: "oom"},
+			want:       exitFailure,
+			wantStdout: "failure cause  oom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/v1/jobs/job-1" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				writeJSON(w, http.StatusOK, tt.job)
+			})
+			code, stdout, stderr := runCLI(t, tt.args...)
+			if code != tt.want {
+				t.Errorf("exit = %d, want %d; stderr:\n%s", code, tt.want, stderr)
+			}
+			if !strings.Contains(stdout, tt.wantStdout) {
+				t.Errorf("stdout = %q, want it to contain %q", stdout, tt.wantStdout)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query(); q.Get("status") != "running" || q.Get("limit") != "5" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"jobs": []client.Job{
+			{ID: "job-1", Status: "running", WorkloadType: "batch_compute"},
+			{ID: "job-2", Status: "running", WorkloadType: "app_hosting"},
+		}})
+	})
+
+	code, stdout, stderr := runCLI(t, "list", "--status", "running", "--limit", "5")
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") ||
+		!strings.HasPrefix(lines[1], "job-1") || !strings.HasPrefix(lines[2], "job-2") {
+		t.Errorf("stdout =\n%s", stdout)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		var page client.LogPage
+		switch after := r.URL.Query().Get("after"); after {
+		case "-1":
+			page = client.LogPage{Chunks: []client.LogChunk{
+				{Seq: 0, Stream: "stdout", Data: "out\n"},
+				{Seq: 1, Stream: "stderr", Data: "err\n"},
+			}, Next: 1}
+		case "1":
+			page = client.LogPage{Chunks: []client.LogChunk{}, Next: 1}
+		default:
+			t.Errorf("unexpected after=%s", after)
+		}
+		writeJSON(w, http.StatusOK, page)
+	})
+
+	code, stdout, stderr := runCLI(t, "logs", "job-1")
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, stderr)
+	}
+	if stdout != "out\n" || stderr != "err\n" {
+		t.Errorf("stdout = %q, stderr = %q", stdout, stderr)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/jobs/job-1/cancel" {
+			t.Errorf("request: %s %s", r.Method, r.URL.Path)
+		}
+		writeJSON(w, http.StatusOK, client.CancelResponse{
+			Status: "cancelled", PriorStatus: "running", ChargedCents: 125, RefundCents: 600, RefundStatus: "refunded",
+		})
+	})
+
+	code, stdout, stderr := runCLI(t, "cancel", "job-1")
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, stderr)
+	}
+	if want := "cancelled job-1 (was running); charged $1.25, refund $6.00 (refunded)\n"; stdout != want {
+		t.Errorf("stdout = %q, want %q", stdout, want)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStderr string
+	}{
+		{"text", []string{"cancel", "job-1"}, "soholink: job can no longer be cancelled (job_not_cancellable)\n"},
+		{
+			"json", []string{"--json", "cancel", "job-1"},
+			`{"code":"job_not_cancellable","current_status":"completed","error":"job can no longer be cancelled","status":409}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusConflict, map[string]string{
+					"error": "job can no longer be cancelled", "code": "job_not_cancellable", "current_status": "completed",
+				})
+			})
+			code, stdout, stderr := runCLI(t, tt.args...)
+			if code != exitFailure {
+				t.Errorf("exit = %d, want %d", code, exitFailure)
+			}
+			if stdout != "" || stderr != tt.wantStderr {
+				t.Errorf("stdout = %q, stderr = %q; want stderr %q", stdout, stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	content := []byte("tar bytes")
+	sum := sha256.Sum256(content)
+	recorded := hex.EncodeToString(sum[:])
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/jobs/job-1/artifacts" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Header().Set("X-Artifact-SHA256", recorded)
+		w.Write(content) //nolint:errcheck
+	})
+
+	code, stdout, stderr := runCLI(t, "download", "job-1", "--out", "-")
+	if code != exitOK || stdout != string(content) {
+		t.Errorf("--out -: exit = %d, stdout = %q; stderr:\n%s", code, stdout, stderr)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.tar")
+	code, _, stderr = runCLI(t, "download", "job-1", "--out", path)
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, stderr)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != string(content) {
+		t.Errorf("%s = %q, %v", path, got, err)
+	}
+
+	// A corrupt download leaves no file behind.
+	recorded = strings.Repeat("0", 64)
+	corrupt := filepath.Join(filepath.Dir(path), "corrupt.tar")
+	if code, _, _ := runCLI(t, "download", "job-1", "--out", corrupt); code != exitFailure {
+		t.Errorf("corrupt download: exit = %d, want %d", code, exitFailure)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files after a corrupt download, want only out.tar", len(entries))
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "soholink", "config.json")
+	t.Setenv("SOHOLINK_CONFIG", path)
+	t.Setenv("SOHOLINK_PROFILE", "")
+	t.Setenv("SOHOLINK_API_URL", "")
+	t.Setenv("SOHOLINK_API_KEY", "")
+
+	var out, errOut strings.Builder
+	code := run(t.Context(), []string{"--profile", "ci", "configure", "--api-url", "https://portal.example.org/", "--api-key", testKey}, &out, &errOut)
+	if code != exitOK {
+		t.Fatalf("exit = %d; stderr:\n%s", code, errOut.String())
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat config: %v", err)
+	}
+	if fi.Mode().Perm() != 0o600 {
+		t.Errorf("config mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	p, err := resolveProfile("ci")
+	if err != nil {
+		t.Fatalf("resolveProfile: %v", err)
+	}
+	if p != (profile{APIURL: "https://portal.example.org", APIKey: testKey}) {
+		t.Errorf("profile = %+v", p)
+	}
+	if _, err := resolveProfile(defaultProfile); err == nil {
+		t.Error("resolveProfile of an unconfigured profile succeeded")
+	}
+
+	// The environment overrides the saved profile field by field.
+	t.Setenv("SOHOLINK_API_URL", "http://localhost:8080")
+	if p, err := resolveProfile("ci"); err != nil || p != (profile{APIURL: "http://localhost:8080", APIKey: testKey}) {
+		t.Errorf("resolveProfile with SOHOLINK_API_URL = %+v, %v", p, err)
+	}
+}
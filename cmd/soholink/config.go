@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// defaultProfile is the profile used when neither --profile nor
+// SOHOLINK_PROFILE names one.
+const defaultProfile = "default"
+
+// profile is where one portal account's API lives and the key to call it.
+type profile struct {
+	APIURL string `json:"api_url"`
+	APIKey string `json:"api_key"`
+}
+
+// cliConfig is the config file: named profiles.
+type cliConfig struct {
+	Profiles map[string]profile `json:"profiles"`
+}
+
+// configPath is $SOHOLINK_CONFIG, or soholink/config.json under the user's
+// config directory.
+func configPath() (string, error) {
+	if p := os.Getenv("SOHOLINK_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locate config: %w", err)
+	}
+	return filepath.Join(dir, "soholink", "config.json"), nil
+}
+
+// loadConfig reads the config file at path. A missing file is an empty
+// config.
+func loadConfig(path string) (cliConfig, error) {
+	cfg := cliConfig{Profiles: map[string]profile{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cliConfig{}, fmt.Errorf("load config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cliConfig{}, fmt.Errorf("load config %s: parse: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]profile{}
+	}
+	return cfg, nil
+}
+
+// saveConfig writes cfg to path, readable only by the user: it holds API
+// keys.
+func saveConfig(path string, cfg cliConfig) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("save config: mkdir: %w", err)
+	}
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("save config: marshal: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("save config: write: %w", err)
+	}
+	return nil
+}
+
+// resolveProfile returns the named profile from the config file, with
+// SOHOLINK_API_URL and SOHOLINK_API_KEY overriding its fields, so a CI job
+// can run with no config file at all.
+func resolveProfile(name string) (profile, error) {
+	path, err := configPath()
+	if err != nil {
+		return profile{}, err
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return profile{}, err
+	}
+	p := cfg.Profiles[name]
+	if v := os.Getenv("SOHOLINK_API_URL"); v != "" {
+		p.APIURL = v
+	}
+	if v := os.Getenv("SOHOLINK_API_KEY"); v != "" {
+		p.APIKey = v
+	}
+	if p.APIURL == "" || p.APIKey == "" {
+		return profile{}, fmt.Errorf("profile %q has no API URL and key; run `soholink --profile %s configure` or set SOHOLINK_API_URL and SOHOLINK_API_KEY", name, name)
+	}
+	return p, nil
+}
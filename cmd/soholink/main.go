@@ -0,0 +1,214 @@
+// Command soholink is the consumer CLI for the SoHoLINK marketplace: submit
+// jobs, follow them, read their output and download their artifacts from a
+// terminal or a CI pipeline. It calls the portal's /api/v1 API through the
+// client package, authenticated by an API key from the portal's API keys
+// page.
+//
+//	soholink --profile ci configure --api-url https://portal.example.org
+//	soholink submit --image soholink/compute-worker@sha256:... --cpu 4 --wait
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/client"
+)
+
+// Exit statuses. A --wait whose job did not succeed exits exitFailure, as
+// does any error, so a pipeline step fails with its job.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// errUsage is returned by a command whose arguments are wrong; its flag set
+// has already printed why.
+var errUsage = errors.New("usage")
+
+// errJobFailed is returned by --wait when the job ended without succeeding;
+// the job itself has already been printed.
+var errJobFailed = errors.New("job did not succeed")
+
+// cli is one invocation: the global flags, and where output goes.
+type cli struct {
+	profile string
+	json    bool
+	stdout  io.Writer
+	stderr  io.Writer
+}
+
+// command is one subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = []command{
+	{"configure", "save an API URL and key as a profile", runConfigure},
+	{"submit", "submit a job (--wait follows it to the end)", runSubmit},
+	{"estimate", "price a job shape and get a quote token", runEstimate},
+	{"list", "list your jobs", runList},
+	{"status", "show a job (--wait follows it to the end)", runStatus},
+	{"logs", "print a job's output (--follow tails it)", runLogs},
+	{"cancel", "cancel a job", runCancel},
+	{"download", "download a job's output archive", runDownload},
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run parses the global flags, runs the command, and returns the exit
+// status.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	fs := flag.NewFlagSet("soholink", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	profileDefault := os.Getenv("SOHOLINK_PROFILE")
+	if profileDefault == "" {
+		profileDefault = defaultProfile
+	}
+	fs.StringVar(&c.profile, "profile", profileDefault, "config profile to use (env SOHOLINK_PROFILE)")
+	fs.BoolVar(&c.json, "json", false, "print JSON instead of text")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: soholink [--profile NAME] [--json] <command> [flags] [args]\n\ncommands:\n")
+		for _, cmd := range commands {
+			fmt.Fprintf(stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintf(stderr, "\nglobal flags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\nSOHOLINK_API_URL and SOHOLINK_API_KEY override the profile; SOHOLINK_CONFIG moves the config file.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	name := fs.Arg(0)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(ctx, c, fs.Args()[1:])
+		switch {
+		case err == nil:
+			return exitOK
+		case errors.Is(err, flag.ErrHelp):
+			return exitOK
+		case errors.Is(err, errUsage):
+			return exitUsage
+		case errors.Is(err, errJobFailed):
+			return exitFailure
+		}
+		c.printError(err)
+		return exitFailure
+	}
+	fmt.Fprintf(stderr, "soholink: unknown command %q\n", name)
+	fs.Usage()
+	return exitUsage
+}
+
+// client returns an API client for the selected profile.
+func (c *cli) client() (*client.Client, error) {
+	p, err := resolveProfile(c.profile)
+	if err != nil {
+		return nil, err
+	}
+	return client.New(p.APIURL, p.APIKey), nil
+}
+
+// printJSON writes v to stdout as indented JSON.
+func (c *cli) printJSON(v any) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printError reports err on stderr: the API's message and what to change,
+// or in JSON mode the API error as a JSON object.
+func (c *cli) printError(err error) {
+	var apiErr *client.Error
+	if c.json {
+		out := map[string]any{"error": err.Error()}
+		if errors.As(err, &apiErr) {
+			out = map[string]any{"error": apiErr.Message, "code": apiErr.Code, "status": apiErr.StatusCode}
+			if apiErr.Reason != "" {
+				out["reason"] = apiErr.Reason
+			}
+			if apiErr.WantedTier != "" {
+				out["wanted_tier"] = apiErr.WantedTier
+			}
+			if apiErr.CurrentStatus != "" {
+				out["current_status"] = apiErr.CurrentStatus
+			}
+		}
+		enc := json.NewEncoder(c.stderr)
+		_ = enc.Encode(out)
+		return
+	}
+	if errors.As(err, &apiErr) {
+		fmt.Fprintf(c.stderr, "soholink: %s (%s)\n", apiErr.Message, apiErr.Code)
+		return
+	}
+	fmt.Fprintf(c.stderr, "soholink: %v\n", err)
+}
+
+// parseArgs parses args with fs, allowing flags after positional arguments
+// (`status JOB --wait` as well as `status --wait JOB`), and returns the
+// positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// newFlagSet returns the flag set for a command taking usage as its
+// arguments synopsis.
+func (c *cli) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: soholink %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// oneJobID returns the single job ID a command takes, or errUsage.
+func oneJobID(fs *flag.FlagSet, positional []string) (string, error) {
+	if len(positional) != 1 {
+		fs.Usage()
+		return "", errUsage
+	}
+	return positional[0], nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/client"
+)
+
+// runCLI runs the CLI with args against an empty config, and returns its
+// exit status and output.
+func runCLI(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	t.Setenv("SOHOLINK_CONFIG", filepath.Join(t.TempDir(), "config.json"))
+	t.Setenv("SOHOLINK_PROFILE", "")
+	var out, errOut strings.Builder
+	code = run(context.Background(), args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func TestRun_Usage(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		want       int
+		wantStderr string
+	}{
+		{"no command", nil, exitUsage, "usage: soholink"},
+		{"help", []string{"--help"}, exitOK, "commands:"},
+		{"unknown command", []string{"frobnicate"}, exitUsage, `unknown command "frobnicate"`},
+		{"unknown global flag", []string{"--verbose", "list"}, exitUsage, "flag provided but not defined"},
+		{"command help", []string{"submit", "--help"}, exitOK, "usage: soholink submit"},
+		{"submit without image", []string{"submit", "--cpu", "4"}, exitUsage, "usage: soholink submit"},
+		{"status without job", []string{"status", "--wait"}, exitUsage, "usage: soholink status"},
+		{"cancel with two jobs", []string{"cancel", "job-1", "job-2"}, exitUsage, "usage: soholink cancel"},
+		{"bad input flag", []string{"submit", "--image", "img", "--input", "data.csv"}, exitUsage, "want NAME=SHA256"},
+		{"configure without URL", []string{"configure", "--api-key", "k"}, exitUsage, "usage: soholink configure"},
+		{"no profile", []string{"list"}, exitFailure, `profile "default" has no API URL and key`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCLI(t, tt.args...)
+			if code != tt.want {
+				t.Errorf("exit = %d, want %d; stderr:\n%s", code, tt.want, stderr)
+			}
+			if !strings.Contains(stderr, tt.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		wantPositional []string
+		wantWait       bool
+		wantErr        error
+	}{
+		{"flag first", []string{"--wait", "job-1"}, []string{"job-1"}, true, nil},
+		{"flag after", []string{"job-1", "--wait"}, []string{"job-1"}, true, nil},
+		{"interleaved", []string{"a", "--wait", "b"}, []string{"a", "b"}, true, nil},
+		{"no flags", []string{"job-1"}, []string{"job-1"}, false, nil},
+		{"nothing", nil, nil, false, nil},
+		{"unknown flag", []string{"job-1", "--frob"}, nil, false, errUsage},
+		{"help", []string{"-h"}, nil, false, flag.ErrHelp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			wait := fs.Bool("wait", false, "")
+			positional, err := parseArgs(fs, tt.args)
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(positional, tt.wantPositional) || *wait != tt.wantWait {
+				t.Errorf("positional = %q, wait = %v; want %q, %v", positional, *wait, tt.wantPositional, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestInputFlag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    client.JobInput
+		wantErr bool
+	}{
+		{in: "data.csv=ABCD", want: client.JobInput{Name: "data.csv", SHA256: "abcd"}},
+		{in: "data.csv=ab@https://example.org/d.csv", want: client.JobInput{Name: "data.csv", SHA256: "ab", URL: "https://example.org/d.csv"}},
+		{in: "data.csv", wantErr: true},
+		{in: "=ab", wantErr: true},
+		{in: "data.csv=", wantErr: true},
+	}
+	for _, tt := range tests {
+		var f inputFlag
+		err := f.Set(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Set(%q) = %+v, want an error", tt.in, f)
+			}
+			continue
+		}
+		if err != nil || len(f) != 1 || f[0] != tt.want {
+			t.Errorf("Set(%q) = %+v, %v; want %+v", tt.in, f, err, tt.want)
+		}
+	}
+}
+
+func TestPlacementFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want *client.Placement
+	}{
+		{"none", nil, nil},
+		{"required node", []string{"--node", "node-1"}, &client.Placement{RequiredNodeID: "node-1"}},
+		{
+			name: "repeated lists",
+			args: []string{"--prefer-node", "n1", "--prefer-node", "n2", "--exclude-owner", "o1"},
+			want: &client.Placement{PreferredNodeIDs: []string{"n1", "n2"}, ExcludedOwnerIDs: []string{"o1"}},
+		},
+		{
+			name: "spread required alone",
+			args: []string{"--spread-required"},
+			want: &client.Placement{AntiAffinityRequired: true},
+		},
+		{
+			name: "selectors and tolerations",
+			args: []string{"--selector", "ssd", "--selector", "zone=attic", "--toleration", "gpu", "--toleration", "owner=me"},
+			want: &client.Placement{
+				NodeSelector: map[string]string{"ssd": "", "zone": "attic"},
+				Tolerations:  []client.Toleration{{Key: "gpu"}, {Key: "owner", Value: "me"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			var p placementFlags
+			p.register(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := p.placement(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("placement = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCents(t *testing.T) {
+	for in, want := range map[int64]string{0: "$0.00", 5: "$0.05", 725: "$7.25", 120000: "$1200.00"} {
+		if got := cents(in); got != want {
+			t.Errorf("cents(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNoCapacity is returned by Admission.Reserve when a job's caps do not fit
+// the unreserved budget or every job slot is taken. The job may fit later;
+// see Admission.Defer.
+var ErrNoCapacity = errors.New("agent: insufficient free capacity")
+
+// ErrAlreadyAdmitted is returned by Admission.Reserve for a job that already
+// holds a reservation, so a re-polled assignment never starts twice.
+var ErrAlreadyAdmitted = errors.New("agent: job already admitted")
+
+// DeferWindow is how long a job that does not fit is held locally and retried
+// before the agent declines it. It stays under the orchestrator's 2-minute
+// dispatched expiry so the decline, not the reaper, returns the job.
+const DeferWindow = 90 * time.Second
+
+// AdmissionBudget is the node-wide capacity the admission controller may
+// reserve from, derived from the active profile by BudgetFor. A negative
+// value means the dimension is uncapped and is not checked.
+type AdmissionBudget struct {
+	CPUCores     int
+	RAMBytes     int64
+	StorageBytes int64
+}
+
+// FreeCapacity is the unreserved share of the budget, reported to the control
+// plane in the heartbeat. Negative resource values mirror an uncapped budget
+// dimension.
+type FreeCapacity struct {
+	CPUCores  int `json:"cpu_cores"`
+	RAMMB     int `json:"ram_mb"`
+	StorageGB int `json:"storage_gb"`
+	Slots     int `json:"slots"`
+}
+
+// BudgetFor computes the admission budget for profile on hw. Unlike
+// ApplyCaps' CapProfile, a zero here is a real limit: CPU disabled gives a
+// zero CPU budget, while unknown hardware gives -1 (uncapped). Storage takes
+// the profile cap when set, else the detected disk.
+func BudgetFor(profile ResourceProfile, hw HardwareProfile) AdmissionBudget {
+	caps := ApplyCaps(profile, hw)
+	b := AdmissionBudget{CPUCores: -1, RAMBytes: -1, StorageBytes: -1}
+	switch {
+	case !profile.CPUEnabled:
+		b.CPUCores = 0
+	case hw.CPUCores > 0:
+		b.CPUCores = caps.CPUCores
+	}
+	if hw.RAMMB > 0 {
+		b.RAMBytes = caps.RAMBytes
+	}
+	switch {
+	case caps.StorageBytes > 0:
+		b.StorageBytes = caps.StorageBytes
+	case hw.StorageGB > 0:
+		b.StorageBytes = hw.StorageGB * 1024 * 1024 * 1024
+	}
+	return b
+}
+
+// deferredJob is a job held by Defer, with the time it was first deferred.
+type deferredJob struct {
+	job   JobAssignment
+	since time.Time
+}
+
+// Admission is the agent's local admission controller. It tracks the caps
+// reserved by running containers against the active profile's budget and a
+// fixed job slot budget, so a poll that returns more work than the node can
+// hold starts only what fits. Safe for concurrent use: runJob goroutines
+// Release while the poll loop Reserves and the heartbeat reads Free.
+type Admission struct {
+	mu       sync.Mutex
+	maxSlots int
+	budget   AdmissionBudget
+	running  map[string]CapProfile
+	deferred map[string]deferredJob
+}
+
+// NewAdmission returns a controller allowing at most maxSlots concurrent jobs
+// (minimum 1). The budget starts uncapped until the first SetBudget.
+func NewAdmission(maxSlots int) *Admission {
+	if maxSlots < 1 {
+		maxSlots = 1
+	}
+	return &Admission{
+		maxSlots: maxSlots,
+		budget:   AdmissionBudget{CPUCores: -1, RAMBytes: -1, StorageBytes: -1},
+		running:  map[string]CapProfile{},
+		deferred: map[string]deferredJob{},
+	}
+}
+
+// SetBudget replaces the budget, typically once per poll after resolving the
+// active profile. Running reservations are kept even if the new budget is
+// smaller; the overrun only blocks new admissions until they drain.
+func (a *Admission) SetBudget(b AdmissionBudget) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.budget = b
+}
+
+// Reserve admits jobID with caps, or returns ErrNoCapacity (or
+// ErrAlreadyAdmitted). A successful Reserve clears any deferral.
+func (a *Admission) Reserve(jobID string, caps CapProfile) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.running[jobID]; ok {
+		return ErrAlreadyAdmitted
+	}
+	if len(a.running) >= a.maxSlots {
+		return ErrNoCapacity
+	}
+	used := a.usedLocked()
+	if a.budget.CPUCores >= 0 && used.CPUCores+caps.CPUCores > a.budget.CPUCores {
+		return ErrNoCapacity
+	}
+	if a.budget.RAMBytes >= 0 && used.RAMBytes+caps.RAMBytes > a.budget.RAMBytes {
+		return ErrNoCapacity
+	}
+	if a.budget.StorageBytes >= 0 && used.StorageBytes+caps.StorageBytes > a.budget.StorageBytes {
+		return ErrNoCapacity
+	}
+	a.running[jobID] = caps
+	delete(a.deferred, jobID)
+	return nil
+}
+
+// Release frees jobID's reservation. Unknown IDs are a no-op.
+func (a *Admission) Release(jobID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.running, jobID)
+}
+
+// Defer holds job for a later retry. It reports true while the job is within
+// DeferWindow of its first deferral; once the window has passed the job is
+// dropped from the queue and false is returned — the caller should decline.
+func (a *Admission) Defer(job JobAssignment, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	d, ok := a.deferred[job.JobID]
+	if !ok {
+		a.deferred[job.JobID] = deferredJob{job: job, since: now}
+		return true
+	}
+	if now.Sub(d.since) >= DeferWindow {
+		delete(a.deferred, job.JobID)
+		return false
+	}
+	return true
+}
+
+// Deferred returns the jobs currently held by Defer, longest-deferred first,
+// for retry ahead of the next poll's assignments.
+func (a *Admission) Deferred() []JobAssignment {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	held := make([]deferredJob, 0, len(a.deferred))
+	for _, d := range a.deferred {
+		held = append(held, d)
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].since.Before(held[j].since) })
+	jobs := make([]JobAssignment, len(held))
+	for i, d := range held {
+		jobs[i] = d.job
+	}
+	return jobs
+}
+
+// Free returns the unreserved budget and remaining slots.
+func (a *Admission) Free() FreeCapacity {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	used := a.usedLocked()
+	const mb = 1024 * 1024
+	free := FreeCapacity{CPUCores: -1, RAMMB: -1, StorageGB: -1, Slots: a.maxSlots - len(a.running)}
+	if a.budget.CPUCores >= 0 {
+		free.CPUCores = max(a.budget.CPUCores-used.CPUCores, 0)
+	}
+	if a.budget.RAMBytes >= 0 {
+		free.RAMMB = int(max(a.budget.RAMBytes-used.RAMBytes, 0) / mb)
+	}
+	if a.budget.StorageBytes >= 0 {
+		free.StorageGB = int(max(a.budget.StorageBytes-used.StorageBytes, 0) / (1024 * mb))
+	}
+	free.Slots = max(free.Slots, 0)
+	return free
+}
+
+// usedLocked sums the running reservations. Caller holds a.mu.
+func (a *Admission) usedLocked() CapProfile {
+	var used CapProfile
+	for _, c := range a.running {
+		used.CPUCores += c.CPUCores
+		used.RAMBytes += c.RAMBytes
+		used.StorageBytes += c.StorageBytes
+	}
+	return used
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBudgetFor(t *testing.T) {
+	hw := HardwareProfile{CPUCores: 8, RAMMB: 16384, StorageGB: 500}
+
+	b := BudgetFor(ResourceProfile{CPUEnabled: true, RAMPct: 50, StorageGB: 100}, hw)
+	if b.CPUCores != 8 || b.RAMBytes != 8192*1024*1024 || b.StorageBytes != 100*1024*1024*1024 {
+		t.Errorf("budget = %+v, want 8 cores / 8 GiB / 100 GiB", b)
+	}
+
+	b = BudgetFor(ResourceProfile{CPUEnabled: false, RAMPct: 100}, hw)
+	if b.CPUCores != 0 {
+		t.Errorf("CPUCores = %d, want 0 when CPU disabled", b.CPUCores)
+	}
+	if b.StorageBytes != 500*1024*1024*1024 {
+		t.Errorf("StorageBytes = %d, want detected disk without a profile cap", b.StorageBytes)
+	}
+
+	b = BudgetFor(ResourceProfile{CPUEnabled: true, RAMPct: 100}, HardwareProfile{})
+	if b.CPUCores != -1 || b.RAMBytes != -1 || b.StorageBytes != -1 {
+		t.Errorf("budget = %+v, want all uncapped on unknown hardware", b)
+	}
+}
+
+func TestAdmission_ReserveAndRelease(t *testing.T) {
+	const gb = int64(1024 * 1024 * 1024)
+	a := NewAdmission(4)
+	a.SetBudget(AdmissionBudget{CPUCores: 4, RAMBytes: 8 * gb, StorageBytes: -1})
+
+	if err := a.Reserve("job-1", CapProfile{CPUCores: 3, RAMBytes: 2 * gb}); err != nil {
+		t.Fatalf("Reserve job-1: %v", err)
+	}
+	if err := a.Reserve("job-1", CapProfile{CPUCores: 1}); !errors.Is(err, ErrAlreadyAdmitted) {
+		t.Fatalf("re-Reserve job-1: got %v, want ErrAlreadyAdmitted", err)
+	}
+	if err := a.Reserve("job-2", CapProfile{CPUCores: 2, RAMBytes: gb}); !errors.Is(err, ErrNoCapacity) {
+		t.Fatalf("Reserve job-2 over CPU budget: got %v, want ErrNoCapacity", err)
+	}
+	if err := a.Reserve("job-3", CapProfile{CPUCores: 1, RAMBytes: 7 * gb}); !errors.Is(err, ErrNoCapacity) {
+		t.Fatalf("Reserve job-3 over RAM budget: got %v, want ErrNoCapacity", err)
+	}
+
+	free := a.Free()
+	want := FreeCapacity{CPUCores: 1, RAMMB: 6 * 1024, StorageGB: -1, Slots: 3}
+	if free != want {
+		t.Errorf("Free = %+v, want %+v", free, want)
+	}
+
+	a.Release("job-1")
+	if err := a.Reserve("job-2", CapProfile{CPUCores: 2, RAMBytes: gb}); err != nil {
+		t.Fatalf("Reserve job-2 after release: %v", err)
+	}
+}
+
+func TestAdmission_SlotBudget(t *testing.T) {
+	a := NewAdmission(2)
+	for _, id := range []string{"job-1", "job-2"} {
+		if err := a.Reserve(id, CapProfile{CPUCores: 1}); err != nil {
+			t.Fatalf("Reserve %s: %v", id, err)
+		}
+	}
+	if err := a.Reserve("job-3", CapProfile{CPUCores: 1}); !errors.Is(err, ErrNoCapacity) {
+		t.Fatalf("third Reserve: got %v, want ErrNoCapacity", err)
+	}
+	if got := a.Free().Slots; got != 0 {
+		t.Errorf("Free().Slots = %d, want 0", got)
+	}
+}
+
+func TestAdmission_DeferWindow(t *testing.T) {
+	a := NewAdmission(1)
+	t0 := time.Date(2026, 4, 7, 12, 0, 0, 0, time.UTC)
+	older := JobAssignment{JobID: "job-old"}
+	newer := JobAssignment{JobID: "job-new"}
+
+	if !a.Defer(older, t0) || !a.Defer(newer, t0.Add(30*time.Second)) {
+		t.Fatal("first Defer should hold the job")
+	}
+	if got := a.Deferred(); len(got) != 2 || got[0].JobID != "job-old" {
+		t.Fatalf("Deferred = %v, want job-old first", got)
+	}
+	if !a.Defer(older, t0.Add(DeferWindow-time.Second)) {
+		t.Error("Defer inside the window should keep holding the job")
+	}
+	if a.Defer(older, t0.Add(DeferWindow)) {
+		t.Error("Defer at the window edge should give up")
+	}
+	if got := a.Deferred(); len(got) != 1 || got[0].JobID != "job-new" {
+		t.Fatalf("Deferred after give-up = %v, want only job-new", got)
+	}
+
+	// Admission clears the deferral.
+	if err := a.Reserve("job-new", CapProfile{}); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if got := a.Deferred(); len(got) != 0 {
+		t.Errorf("Deferred after Reserve = %v, want empty", got)
+	}
+}
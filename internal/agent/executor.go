@@ -8,12 +8,16 @@ import (
 	"io"
 	"log/slog"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 var (
@@ -27,6 +31,14 @@ const (
 	jobNetworkPrefix = "soholink-job-"
 )
 
+// FailureCauseTimeout is the failure_cause the agent reports in /complete for
+// a job Wait stopped at its ContainerSpec.MaxRuntime.
+const FailureCauseTimeout = "timeout"
+
+// timeoutExitCode is reported for a timed-out container that exited zero on
+// SIGTERM, so the job still completes as failed — timeout(1)'s convention.
+const timeoutExitCode = 124
+
 // ContainerSpec describes the workload container to run.
 type ContainerSpec struct {
 	Image    string
@@ -41,6 +53,28 @@ type ContainerSpec struct {
 	// specific printer. Empty for compute/storage workloads and for
 	// print workloads that route via CUPS rather than direct USB.
 	ConnectionPath string
+
+	// OutputPath is the job's declared output: OutputDir, or a file under
+	// it. When set, OutputDir is mounted writable and Wait collects it into
+	// ExecutionResult.Artifact; for a file, Wait also reports its SHA-256 in
+	// ExecutionResult.ResultHash.
+	OutputPath string
+
+	// InputDir is the host directory of the job's staged inputs
+	// (StageInputs), bind-mounted read-only at InputDir. Empty when the job
+	// has no inputs.
+	InputDir string
+
+	// Stdout and Stderr, when either is set, receive the container's output
+	// as it runs (typically a LogShipper's writers); a nil one discards its
+	// stream. Wait returns only after both have seen the end of the output.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// MaxRuntime, when positive, limits the container's run from Start:
+	// Wait stops a container still running at the limit and returns with
+	// ExecutionResult.TimedOut.
+	MaxRuntime time.Duration
 }
 
 // ExecutionResult carries the outcome of a completed container run.
@@ -49,15 +83,46 @@ type ExecutionResult struct {
 	ExitCode       int
 	Error          string
 	TmpfsExhausted bool
+
+	// ResultHash is the hex SHA-256 of the declared output file, set only
+	// for a zero exit with ContainerSpec.OutputPath naming a readable file.
+	ResultHash string
+
+	// Artifact is the collected OutputDir, set only for a zero exit with
+	// ContainerSpec.OutputPath. The caller uploads it (UploadArtifact) and
+	// removes Artifact.Path.
+	Artifact *Artifact
+
+	// Stopped is set when Stop ended the container while Wait was waiting
+	// on it (the control plane cancelled the job). No output is collected.
+	Stopped bool
+
+	// TimedOut is set when Wait stopped the container at
+	// ContainerSpec.MaxRuntime. ExitCode is then non-zero and no output is
+	// collected; the caller completes the job with FailureCauseTimeout.
+	TimedOut bool
 }
 
 // ExecutionContext is the handle returned by Start. It carries the resources
 // Wait needs to clean up, and lets Stop tear down a partially or fully
-// started container in the agent's /started 409 path.
+// started container — in the agent's /started 409 path, or while Wait is
+// waiting on it when the control plane stops the job.
 type ExecutionContext struct {
 	JobID       string
 	ContainerID string
 	NetworkID   string
+	OutputPath  string
+
+	// logsDone is closed when followLogs has copied the container's last
+	// output; nil when the spec asked for no output.
+	logsDone chan struct{}
+
+	// deadline is when Wait stops the container; zero for no limit.
+	deadline time.Time
+
+	stopped     atomic.Bool
+	timedOut    atomic.Bool
+	cleanupOnce sync.Once
 }
 
 // imageInspector is the subset of the Docker client used for image
@@ -207,18 +272,37 @@ func (e *Executor) Start(ctx context.Context, spec ContainerSpec) (*ExecutionCon
 		return nil, fmt.Errorf("start: container start: %w", err)
 	}
 
-	return &ExecutionContext{
+	ec := &ExecutionContext{
 		JobID:       spec.JobID,
 		ContainerID: containerID,
 		NetworkID:   networkID,
-	}, nil
+		OutputPath:  spec.OutputPath,
+	}
+	if spec.MaxRuntime > 0 {
+		ec.deadline = time.Now().Add(spec.MaxRuntime)
+	}
+	if spec.Stdout != nil || spec.Stderr != nil {
+		ec.logsDone = make(chan struct{})
+		go e.followLogs(ctx, ec, spec.Stdout, spec.Stderr)
+	}
+	return ec, nil
 }
 
-// Wait blocks until the container exits, then cleans up the container and
+// Wait blocks until the container exits — or, with a ContainerSpec.MaxRuntime,
+// until it is stopped at the limit — then cleans up the container and
 // network. It must be called exactly once per successful Start.
 func (e *Executor) Wait(ctx context.Context, ec *ExecutionContext) (ExecutionResult, error) {
 	defer e.cleanup(context.Background(), ec)
 
+	if !ec.deadline.IsZero() {
+		timer := time.AfterFunc(time.Until(ec.deadline), func() {
+			slog.Warn("job reached its max runtime — stopping container", "job_id", ec.JobID)
+			ec.timedOut.Store(true)
+			e.stopContainer(context.Background(), ec)
+		})
+		defer timer.Stop()
+	}
+
 	statusCh, errCh := e.client.ContainerWait(ctx, ec.ContainerID, container.WaitConditionNotRunning)
 	select {
 	case err := <-errCh:
@@ -227,6 +311,7 @@ func (e *Executor) Wait(ctx context.Context, ec *ExecutionContext) (ExecutionRes
 		}
 		return ExecutionResult{JobID: ec.JobID}, nil
 	case waitResp := <-statusCh:
+		e.drainLogs(ec)
 		result := ExecutionResult{
 			JobID:    ec.JobID,
 			ExitCode: int(waitResp.StatusCode),
@@ -234,8 +319,35 @@ func (e *Executor) Wait(ctx context.Context, ec *ExecutionContext) (ExecutionRes
 		if waitResp.Error != nil {
 			result.Error = waitResp.Error.Message
 		}
+		if ec.stopped.Load() {
+			result.Stopped = true
+			return result, nil
+		}
+		if ec.timedOut.Load() {
+			result.TimedOut = true
+			if result.ExitCode == 0 {
+				result.ExitCode = timeoutExitCode
+			}
+			return result, nil
+		}
 		if waitResp.StatusCode != 0 {
 			result.TmpfsExhausted = e.scanStderrForENOSPC(ctx, ec.ContainerID)
+		} else if ec.OutputPath != "" {
+			// Both best-effort: the container has already exited, so the
+			// job completes either way. A missing file reports no hash,
+			// which result verification treats as a non-matching result.
+			if ec.OutputPath != OutputDir {
+				hash, err := e.hashOutput(ctx, ec)
+				if err != nil {
+					slog.Warn("output artifact hash failed", "job_id", ec.JobID, "path", ec.OutputPath, "error", err)
+				}
+				result.ResultHash = hash
+			}
+			art, err := e.collectOutput(ctx, ec)
+			if err != nil {
+				slog.Warn("output collection failed", "job_id", ec.JobID, "error", err)
+			}
+			result.Artifact = art
 		}
 		return result, nil
 	}
@@ -251,9 +363,15 @@ func (e *Executor) Run(ctx context.Context, spec ContainerSpec) (ExecutionResult
 	return e.Wait(ctx, ec)
 }
 
-// cleanup removes the container and network. Errors are logged, not returned —
+// cleanup removes the container and network, once: Stop and Wait may both
+// reach it for the same container. Errors are logged, not returned —
 // cleanup must never mask the original error that triggered the teardown.
 func (e *Executor) cleanup(ctx context.Context, ec *ExecutionContext) {
+	ec.cleanupOnce.Do(func() { e.removeResources(ctx, ec) })
+}
+
+// removeResources does cleanup's work.
+func (e *Executor) removeResources(ctx context.Context, ec *ExecutionContext) {
 	if err := e.client.ContainerRemove(ctx, ec.ContainerID,
 		container.RemoveOptions{Force: true}); err != nil {
 		slog.Warn("container remove failed",
@@ -263,22 +381,36 @@ func (e *Executor) cleanup(ctx context.Context, ec *ExecutionContext) {
 		slog.Warn("network remove failed",
 			"network_id", ec.NetworkID, "job_id", ec.JobID, "error", err)
 	}
+	if ec.OutputPath != "" {
+		if err := e.client.VolumeRemove(ctx, outputVolumeName(ec.JobID), true); err != nil {
+			slog.Warn("output volume remove failed", "job_id", ec.JobID, "error", err)
+		}
+	}
 }
 
 // Stop terminates and cleans up a container started via Start. Used by runJob
 // when the orchestrator rejects /jobs/{id}/started (e.g. 409 — job already
-// reaped or claimed elsewhere). Internal failures are logged but never
+// reaped or claimed elsewhere), and when a heartbeat tells the agent to stop
+// a running job (cancelled by its consumer); a Wait in progress then returns
+// with ExecutionResult.Stopped. Internal failures are logged but never
 // returned — the caller is already in an error path and cleanup must not
 // mask the original 409 context.
 func (e *Executor) Stop(ctx context.Context, ec *ExecutionContext) error {
+	ec.stopped.Store(true)
+	e.stopContainer(ctx, ec)
+	e.cleanup(ctx, ec)
+	return nil
+}
+
+// stopContainer sends the container SIGTERM, then SIGKILL after a grace
+// period. Failures are logged; the container is force-removed by cleanup.
+func (e *Executor) stopContainer(ctx context.Context, ec *ExecutionContext) {
 	timeout := 10 // seconds — SIGTERM-to-SIGKILL grace period
 	if err := e.client.ContainerStop(ctx, ec.ContainerID,
 		container.StopOptions{Timeout: &timeout}); err != nil {
 		slog.Warn("container stop failed",
 			"container_id", ec.ContainerID, "job_id", ec.JobID, "error", err)
 	}
-	e.cleanup(ctx, ec)
-	return nil
 }
 
 // createJobNetwork creates a dedicated Docker network for a single job.
@@ -302,6 +434,49 @@ func (e *Executor) createJobNetwork(ctx context.Context, jobID string, tier Egre
 	return resp.ID, nil
 }
 
+// logDrainTimeout bounds how long Wait waits for followLogs to deliver the
+// output of an exited container before removing it.
+const logDrainTimeout = 10 * time.Second
+
+// followLogs copies the container's output to stdout and stderr until the
+// container exits, then closes ec.logsDone. Best-effort: a failure is logged
+// and ends the copy.
+func (e *Executor) followLogs(ctx context.Context, ec *ExecutionContext, stdout, stderr io.Writer) {
+	defer close(ec.logsDone)
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	rc, err := e.client.ContainerLogs(ctx, ec.ContainerID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		e.log.Warn("follow logs: container logs failed", "job_id", ec.JobID, "error", err)
+		return
+	}
+	defer rc.Close()
+	// Containers run without a TTY, so the stream is multiplexed.
+	if _, err := stdcopy.StdCopy(stdout, stderr, rc); err != nil && ctx.Err() == nil {
+		e.log.Warn("follow logs: copy failed", "job_id", ec.JobID, "error", err)
+	}
+}
+
+// drainLogs waits, up to logDrainTimeout, for followLogs to finish.
+func (e *Executor) drainLogs(ec *ExecutionContext) {
+	if ec.logsDone == nil {
+		return
+	}
+	select {
+	case <-ec.logsDone:
+	case <-time.After(logDrainTimeout):
+		e.log.Warn("container output not drained before cleanup", "job_id", ec.JobID)
+	}
+}
+
 // scanStderrForENOSPC reads the container's recent stderr and returns true
 // if a "no space left on device" or ENOSPC marker is present. Best-effort
 // diagnostic — read failures are logged at debug and treated as "not exhausted".
@@ -355,6 +530,23 @@ func buildHostConfig(spec ContainerSpec, entry *AllowlistEntry) *container.HostC
 		},
 	}
 
+	if spec.OutputPath != "" {
+		mounts = append(mounts, mount.Mount{
+			Type:   mount.TypeVolume,
+			Source: outputVolumeName(spec.JobID),
+			Target: OutputDir,
+		})
+	}
+
+	if spec.InputDir != "" {
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   spec.InputDir,
+			Target:   InputDir,
+			ReadOnly: true,
+		})
+	}
+
 	devices := deviceMountsFor(entry.DeviceAccess, spec.ConnectionPath)
 	mounts = append(mounts, devices.mounts...)
 
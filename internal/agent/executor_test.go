@@ -226,6 +226,54 @@ func TestBuildHostConfig_TmpfsScratchPresent(t *testing.T) {
 	}
 }
 
+func TestBuildHostConfig_OutputVolume(t *testing.T) {
+	hc := buildHostConfig(ContainerSpec{Image: allowedImage, JobID: "job-1"}, entryWith())
+	for _, m := range hc.Mounts {
+		if m.Target == OutputDir {
+			t.Fatalf("unexpected %s mount without OutputPath", OutputDir)
+		}
+	}
+
+	spec := ContainerSpec{Image: allowedImage, JobID: "job-1", OutputPath: OutputDir + "/result.bin"}
+	hc = buildHostConfig(spec, entryWith())
+	var found bool
+	for _, m := range hc.Mounts {
+		if m.Target == OutputDir {
+			found = true
+			if m.Type != mount.TypeVolume || m.Source != outputVolumeName("job-1") || m.ReadOnly {
+				t.Errorf("output mount = %+v, want writable volume %s", m, outputVolumeName("job-1"))
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no mount at %s found in HostConfig.Mounts", OutputDir)
+	}
+}
+
+func TestBuildHostConfig_InputBind(t *testing.T) {
+	hc := buildHostConfig(ContainerSpec{Image: allowedImage, JobID: "job-1"}, entryWith())
+	for _, m := range hc.Mounts {
+		if m.Target == InputDir {
+			t.Fatalf("unexpected %s mount without InputDir", InputDir)
+		}
+	}
+
+	spec := ContainerSpec{Image: allowedImage, JobID: "job-1", InputDir: "/tmp/soholink-input-1"}
+	hc = buildHostConfig(spec, entryWith())
+	var found bool
+	for _, m := range hc.Mounts {
+		if m.Target == InputDir {
+			found = true
+			if m.Type != mount.TypeBind || m.Source != spec.InputDir || !m.ReadOnly {
+				t.Errorf("input mount = %+v, want read-only bind of %s", m, spec.InputDir)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("no mount at %s found in HostConfig.Mounts", InputDir)
+	}
+}
+
 func TestBuildHostConfig_PreservesResourceCaps(t *testing.T) {
 	spec := ContainerSpec{
 		Image: allowedImage,
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
@@ -24,6 +26,12 @@ type AgentConfig struct {
 	ControlPlaneAddr string // e.g. "https://control.soholink.org:8443"
 	SPIFFESocketPath string // path to the SPIRE agent Unix socket
 	TokenSecret      []byte
+
+	// Labels and Taints describe and reserve this node for placement
+	// (AGENT_LABELS / AGENT_TAINTS), key to value. Reported on every
+	// heartbeat; labels the owner sets in the portal take precedence.
+	Labels map[string]string
+	Taints map[string]string
 }
 
 // JobAssignment is a single entry returned by PollJobs.
@@ -32,6 +40,17 @@ type JobAssignment struct {
 	JobToken  string `json:"job_token"`
 	Image     string `json:"container_image"`
 	PrinterID string `json:"printer_id,omitempty"`
+	// Requested resources; 0 means the job did not specify one and the
+	// active profile's cap applies (see JobCaps).
+	CPUCores  int `json:"cpu_cores,omitempty"`
+	RAMMB     int `json:"ram_mb,omitempty"`
+	StorageGB int `json:"storage_gb,omitempty"`
+	// OutputPath is the declared output artifact (under OutputDir), or empty.
+	OutputPath string `json:"output_path,omitempty"`
+	// Inputs are staged under InputDir before the container starts.
+	Inputs []InputFile `json:"inputs,omitempty"`
+	// MaxRuntimeSeconds is the job's runtime limit; 0 means none.
+	MaxRuntimeSeconds int `json:"max_runtime_seconds,omitempty"`
 }
 
 // HeartbeatAgent manages registration, heartbeating, and job polling
@@ -42,6 +61,14 @@ type HeartbeatAgent struct {
 	client      *http.Client
 	idSource    *identity.Source
 	optOutStore *OptOutStore
+	admission   *Admission // nil: no free_capacity in heartbeats
+
+	profilesMu      sync.Mutex
+	profiles        []ResourceProfile // last successful FetchProfiles result
+	profilesFetched bool              // a FetchProfiles has succeeded
+
+	runningMu sync.Mutex
+	running   map[string]func() // job ID → stop, for heartbeat stop_jobs
 }
 
 // NewHeartbeatAgent connects to the SPIRE agent socket, obtains an X.509 SVID,
@@ -50,7 +77,7 @@ type HeartbeatAgent struct {
 //
 // Note: the spec references spiffeid.RequireIDFromString — the actual function
 // in go-spiffe/v2 is spiffeid.RequireFromString.
-func NewHeartbeatAgent(ctx context.Context, cfg AgentConfig, hw HardwareProfile, optOutStore *OptOutStore) (*HeartbeatAgent, error) {
+func NewHeartbeatAgent(ctx context.Context, cfg AgentConfig, hw HardwareProfile, optOutStore *OptOutStore, admission *Admission) (*HeartbeatAgent, error) {
 	idSource, err := identity.NewSource(ctx, cfg.SPIFFESocketPath)
 	if err != nil {
 		return nil, fmt.Errorf("new heartbeat agent: identity source: %w", err)
@@ -70,9 +97,54 @@ func NewHeartbeatAgent(ctx context.Context, cfg AgentConfig, hw HardwareProfile,
 		client:      client,
 		idSource:    idSource,
 		optOutStore: optOutStore,
+		admission:   admission,
+		running:     map[string]func(){},
 	}, nil
 }
 
+// TrackJob registers a running job's container with the heartbeat: its ID is
+// reported in running_jobs, and stop is called (in its own goroutine) if the
+// control plane answers with the job in stop_jobs. Call the returned untrack
+// once the container has exited.
+func (a *HeartbeatAgent) TrackJob(jobID string, stop func()) (untrack func()) {
+	a.runningMu.Lock()
+	a.running[jobID] = stop
+	a.runningMu.Unlock()
+	return func() {
+		a.runningMu.Lock()
+		delete(a.running, jobID)
+		a.runningMu.Unlock()
+	}
+}
+
+// runningJobs returns the tracked job IDs, sorted.
+func (a *HeartbeatAgent) runningJobs() []string {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	ids := make([]string, 0, len(a.running))
+	for id := range a.running {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// stopJobs stops the tracked jobs among ids. Each is untracked first, so a
+// later heartbeat does not stop it again while its container winds down.
+func (a *HeartbeatAgent) stopJobs(ids []string) {
+	a.runningMu.Lock()
+	defer a.runningMu.Unlock()
+	for _, id := range ids {
+		stop, ok := a.running[id]
+		if !ok {
+			continue
+		}
+		delete(a.running, id)
+		log.Printf("heartbeat: control plane stopped job %s", id)
+		go stop()
+	}
+}
+
 // NewTelemetryClient returns an mTLS HTTP client that presents this node's
 // SPIRE-issued SVID to the control plane. Use this for telemetry and job
 // completion calls in runJob — the plain http.Client will be rejected by
@@ -121,7 +193,8 @@ type heartbeatResp struct {
 		PrintingEnabled bool            `json:"printing_enabled"`
 		EnabledPrinters map[string]bool `json:"enabled_printers"`
 	} `json:"opt_out"`
-	RequestPrinterReport bool `json:"request_printer_report"`
+	RequestPrinterReport bool     `json:"request_printer_report"`
+	StopJobs             []string `json:"stop_jobs"`
 }
 
 // Register sends the node's identity and current hardware profile to the
@@ -161,6 +234,11 @@ func (a *HeartbeatAgent) Register(ctx context.Context) error {
 // liveness.Heartbeat) is untouched, so no float ever enters the canonical
 // byte format. A sampling failure sends cpu_pct=100 (conservatively busy)
 // rather than a false idle claim.
+//
+// free_capacity, by contrast, is the admission controller's unreserved
+// budget and slot count; the orchestrator stops matching jobs to the node
+// that would not fit it. labels and taints are always sent, empty when
+// unset, so removing one from the config clears it on the control plane.
 func (a *HeartbeatAgent) Heartbeat(ctx context.Context) error {
 	var version int
 	if a.optOutStore != nil {
@@ -179,6 +257,14 @@ func (a *HeartbeatAgent) Heartbeat(ctx context.Context) error {
 		"printer_hash":    PrinterHash(a.hw.Printers),
 		"owner_active":    DetectOwnerActive(),
 		"cpu_pct":         cpuPct,
+		"labels":          orEmptyLabels(a.cfg.Labels),
+		"taints":          orEmptyLabels(a.cfg.Taints),
+	}
+	if a.admission != nil {
+		payload["free_capacity"] = a.admission.Free()
+	}
+	if running := a.runningJobs(); len(running) > 0 {
+		payload["running_jobs"] = running
 	}
 
 	data, err := json.Marshal(payload)
@@ -225,6 +311,8 @@ func (a *HeartbeatAgent) Heartbeat(ctx context.Context) error {
 		}
 	}
 
+	a.stopJobs(hbResp.StopJobs)
+
 	if hbResp.RequestPrinterReport {
 		if err := a.ReportPrinters(ctx); err != nil {
 			log.Printf("heartbeat: report printers: %v", err)
@@ -234,6 +322,15 @@ func (a *HeartbeatAgent) Heartbeat(ctx context.Context) error {
 	return nil
 }
 
+// orEmptyLabels returns m, or an empty map for nil, which would marshal as
+// JSON null and leave the reported labels untouched.
+func orEmptyLabels(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
 // ReportPrinters sends the full current printer list to the control plane.
 // Called when the server signals a hash mismatch via RequestPrinterReport.
 func (a *HeartbeatAgent) ReportPrinters(ctx context.Context) error {
@@ -277,6 +374,117 @@ func (a *HeartbeatAgent) PollJobs(ctx context.Context) ([]JobAssignment, error)
 	return jobs, nil
 }
 
+// DeclineJob turns down a job assigned to this node via POST
+// /jobs/{id}/decline; the orchestrator re-places it elsewhere. reason is
+// advisory (logged server-side).
+func (a *HeartbeatAgent) DeclineJob(ctx context.Context, jobID, reason string) error {
+	return a.postJSON(ctx, "/jobs/"+jobID+"/decline", map[string]string{"reason": reason})
+}
+
+// profilePayload is one entry of the GET /nodes/profiles response.
+type profilePayload struct {
+	IsDefault         bool     `json:"is_default"`
+	CPUEnabled        bool     `json:"cpu_enabled"`
+	GPUPct            int      `json:"gpu_pct"`
+	RAMPct            int      `json:"ram_pct"`
+	StorageGB         int      `json:"storage_gb"`
+	BandwidthMbps     int      `json:"bandwidth_mbps"`
+	ScheduleStart     string   `json:"schedule_start"`
+	ScheduleEnd       string   `json:"schedule_end"`
+	ScheduleDays      []string `json:"schedule_days"`
+	OverrideStartDate string   `json:"override_start_date"`
+	OverrideEndDate   string   `json:"override_end_date"`
+}
+
+// FetchProfiles retrieves the node's resource profiles (default plus scheduled
+// overrides) from the control plane. On any error the last successfully
+// fetched set is returned alongside the error, so a control-plane blip does
+// not lift a contributor's caps mid-session; callers may log and carry on.
+// Before any fetch has succeeded there is no such set, and the error wraps
+// ErrProfilesNotFetched: callers must hold their jobs.
+func (a *HeartbeatAgent) FetchProfiles(ctx context.Context) ([]ResourceProfile, error) {
+	profiles, err := a.fetchProfiles(ctx)
+
+	a.profilesMu.Lock()
+	defer a.profilesMu.Unlock()
+	if err != nil {
+		if !a.profilesFetched {
+			return nil, fmt.Errorf("%w: %w", ErrProfilesNotFetched, err)
+		}
+		return a.profiles, err
+	}
+	a.profiles = profiles
+	a.profilesFetched = true
+	return profiles, nil
+}
+
+func (a *HeartbeatAgent) fetchProfiles(ctx context.Context) ([]ResourceProfile, error) {
+	url := a.cfg.ControlPlaneAddr + "/nodes/profiles?node_id=" + a.cfg.NodeID
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch profiles: build request: %w", err)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch profiles: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch profiles: unexpected status %d", resp.StatusCode)
+	}
+
+	var payload []profilePayload
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("fetch profiles: decode: %w", err)
+	}
+	profiles := make([]ResourceProfile, 0, len(payload))
+	for _, p := range payload {
+		rp, err := p.toProfile()
+		if err != nil {
+			return nil, fmt.Errorf("fetch profiles: %w", err)
+		}
+		profiles = append(profiles, rp)
+	}
+	return profiles, nil
+}
+
+// toProfile converts the wire form ("HH:MM" times, "YYYY-MM-DD" dates, empty
+// string = unset) into a ResourceProfile.
+func (p profilePayload) toProfile() (ResourceProfile, error) {
+	rp := ResourceProfile{
+		IsDefault:     p.IsDefault,
+		CPUEnabled:    p.CPUEnabled,
+		GPUPct:        p.GPUPct,
+		RAMPct:        p.RAMPct,
+		StorageGB:     p.StorageGB,
+		BandwidthMbps: p.BandwidthMbps,
+		ScheduleDays:  p.ScheduleDays,
+	}
+	fields := []struct {
+		dst    **time.Time
+		value  string
+		layout string
+	}{
+		{&rp.ScheduleStart, p.ScheduleStart, "15:04"},
+		{&rp.ScheduleEnd, p.ScheduleEnd, "15:04"},
+		{&rp.OverrideStartDate, p.OverrideStartDate, "2006-01-02"},
+		{&rp.OverrideEndDate, p.OverrideEndDate, "2006-01-02"},
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		t, err := time.Parse(f.layout, f.value)
+		if err != nil {
+			return ResourceProfile{}, fmt.Errorf("parse %q: %w", f.value, err)
+		}
+		*f.dst = &t
+	}
+	return rp, nil
+}
+
 // StartHeartbeatLoop registers the node on startup, then on every interval:
 //   - detects current hardware and re-registers if anything has changed
 //   - sends a heartbeat regardless
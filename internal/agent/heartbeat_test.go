@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestHeartbeatAgent_StopJobs verifies tracked jobs are reported sorted, and
+// that a stop request runs only the named, tracked jobs' stop funcs — once —
+// and untracks them.
+func TestHeartbeatAgent_StopJobs(t *testing.T) {
+	a := &HeartbeatAgent{running: map[string]func(){}}
+	stopped := make(chan string, 4)
+	for _, id := range []string{"job-b", "job-a", "job-c"} {
+		a.TrackJob(id, func() { stopped <- id })
+	}
+	untrackC := a.TrackJob("job-c", func() { stopped <- "job-c" })
+	untrackC()
+
+	if got, want := a.runningJobs(), []string{"job-a", "job-b"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("runningJobs = %v, want %v", got, want)
+	}
+
+	a.stopJobs([]string{"job-b", "job-unknown", "job-c"})
+	a.stopJobs([]string{"job-b"})
+
+	select {
+	case id := <-stopped:
+		if id != "job-b" {
+			t.Errorf("stopped %q, want job-b", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job-b was not stopped")
+	}
+	select {
+	case id := <-stopped:
+		t.Errorf("unexpected second stop of %q", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got, want := a.runningJobs(), []string{"job-a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("runningJobs after stop = %v, want %v", got, want)
+	}
+}
+
+// TestHeartbeatAgent_FetchProfilesBeforeFirstSuccess verifies a failed fetch
+// is ErrProfilesNotFetched — never an empty, unrestricted set — until one
+// succeeds, and returns the cached set after that.
+func TestHeartbeatAgent_FetchProfilesBeforeFirstSuccess(t *testing.T) {
+	var up atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, `[{"is_default":true,"cpu_enabled":true,"ram_pct":50}]`) //nolint:errcheck
+	}))
+	defer srv.Close()
+	a := &HeartbeatAgent{cfg: AgentConfig{ControlPlaneAddr: srv.URL, NodeID: "n1"}, client: srv.Client()}
+	ctx := context.Background()
+
+	profiles, err := a.FetchProfiles(ctx)
+	if !errors.Is(err, ErrProfilesNotFetched) || profiles != nil {
+		t.Fatalf("first fetch failing: FetchProfiles = %v, %v; want nil, ErrProfilesNotFetched", profiles, err)
+	}
+
+	up.Store(true)
+	if profiles, err = a.FetchProfiles(ctx); err != nil || len(profiles) != 1 || profiles[0].RAMPct != 50 {
+		t.Fatalf("FetchProfiles = %+v, %v; want the default profile", profiles, err)
+	}
+
+	up.Store(false)
+	profiles, err = a.FetchProfiles(ctx)
+	if err == nil || errors.Is(err, ErrProfilesNotFetched) {
+		t.Errorf("later failure: err = %v, want a plain fetch error", err)
+	}
+	if len(profiles) != 1 || profiles[0].RAMPct != 50 {
+		t.Errorf("later failure: profiles = %+v, want the cached set", profiles)
+	}
+}
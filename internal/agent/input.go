@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// InputDir is the read-only mount holding a job's staged inputs. The agent
+// downloads every input into a host directory before ContainerStart and
+// bind-mounts it here; the caller removes the directory once Wait returns.
+const InputDir = "/input"
+
+// ErrInputDigestMismatch is returned by StageInputs when a fetched input's
+// SHA-256 or size differs from what the assignment pinned.
+var ErrInputDigestMismatch = errors.New("agent: input digest mismatch")
+
+// ErrInputAddressBlocked is returned when a URL input resolves to a loopback,
+// private or link-local address — the agent will not fetch from the
+// contributor's own network on a consumer's behalf.
+var ErrInputAddressBlocked = errors.New("agent: input URL resolves to a non-public address")
+
+// InputFile is one input of a JobAssignment. With URL empty the file is
+// fetched from the control plane (GET /jobs/{id}/inputs/{name}) and
+// SizeBytes is its exact size; otherwise it is downloaded from URL. Either
+// way the bytes must hash to SHA256.
+type InputFile struct {
+	Name      string `json:"name"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// inputFetchTimeout bounds the download of one input; the telemetry client's
+// own 15s timeout is lifted for it.
+const inputFetchTimeout = 30 * time.Minute
+
+// publicInputClient downloads URL inputs. A package variable so tests can
+// substitute a client that reaches httptest servers on loopback.
+var publicInputClient = newPublicInputClient()
+
+// newPublicInputClient returns a client that only connects to public
+// addresses, checked after DNS resolution so a hostname cannot smuggle in a
+// LAN address, and only follows redirects to https.
+func newPublicInputClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrInputAddressBlocked, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		Timeout:   inputFetchTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("input redirect to non-https URL %s", req.URL.Redacted())
+			}
+			if len(via) >= 5 {
+				return errors.New("input URL: too many redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// sharedAddressSpace is RFC 6598 carrier-grade NAT space, which net.IP does
+// not classify as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// publicIP reports whether ip is a globally routable unicast address.
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// StageInputs downloads inputs into a new temporary directory, verifying each
+// against its pinned digest, and returns the directory for
+// ContainerSpec.InputDir. Uploaded inputs come from the control plane via
+// client (the agent's mTLS client); URL inputs via a client restricted to
+// public addresses. maxBytes caps the staged total (zero or negative:
+// uncapped), so a job cannot stage more than its storage cap. On error the
+// directory is removed; otherwise the caller removes it after the container
+// exits.
+func StageInputs(ctx context.Context, client *http.Client, controlPlaneAddr, jobID string, inputs []InputFile, maxBytes int64) (string, error) {
+	dir, err := os.MkdirTemp("", "soholink-input-*")
+	if err != nil {
+		return "", fmt.Errorf("stage inputs: %w", err)
+	}
+	// The container runs as a non-root user that does not own the directory.
+	if err := os.Chmod(dir, 0o755); err != nil {
+		os.RemoveAll(dir) //nolint:errcheck
+		return "", fmt.Errorf("stage inputs: %w", err)
+	}
+
+	cp := *client
+	cp.Timeout = inputFetchTimeout
+	remaining := maxBytes
+	for _, in := range inputs {
+		// The control plane validates both; re-checked since they name a
+		// host path and a fetch on the contributor's machine.
+		if in.Name == "." || in.Name == ".." || filepath.Base(in.Name) != in.Name ||
+			(in.URL != "" && !strings.HasPrefix(in.URL, "https://")) {
+			os.RemoveAll(dir) //nolint:errcheck
+			return "", fmt.Errorf("stage inputs: invalid input %q", in.Name)
+		}
+		src, fetch := publicInputClient, in.URL
+		if in.URL == "" {
+			src, fetch = &cp, controlPlaneAddr+"/jobs/"+jobID+"/inputs/"+in.Name
+		}
+		n, err := stageInput(ctx, src, fetch, filepath.Join(dir, in.Name), in, remaining, maxBytes > 0)
+		if err != nil {
+			os.RemoveAll(dir) //nolint:errcheck
+			return "", fmt.Errorf("stage input %s: %w", in.Name, err)
+		}
+		remaining -= n
+	}
+	return dir, nil
+}
+
+// stageInput downloads one input to dst and verifies it, returning its size.
+// When capped, more than limit bytes is an error.
+func stageInput(ctx context.Context, client *http.Client, url, dst string, in InputFile, limit int64, capped bool) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	body := io.Reader(resp.Body)
+	if capped {
+		// One byte over the limit is enough to detect the overrun.
+		body = io.LimitReader(body, limit+1)
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if capped && n > limit {
+		return 0, errors.New("inputs exceed the job's storage cap")
+	}
+	if hex.EncodeToString(h.Sum(nil)) != in.SHA256 || (in.URL == "" && n != in.SizeBytes) {
+		return 0, ErrInputDigestMismatch
+	}
+	return n, nil
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sumHex(b string) string {
+	s := sha256.Sum256([]byte(b))
+	return hex.EncodeToString(s[:])
+}
+
+func TestStageInputs(t *testing.T) {
+	const gcode, csv = "G28\nG1 X10\n", "a,b\n1,2\n"
+	var paths []string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/jobs/j1/inputs/part.gcode":
+			w.Write([]byte(gcode)) //nolint:errcheck
+		case "/datasets/data.csv":
+			w.Write([]byte(csv)) //nolint:errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	// URL inputs normally go through the public-only client, which refuses
+	// the loopback test server.
+	saved := publicInputClient
+	publicInputClient = srv.Client()
+	t.Cleanup(func() { publicInputClient = saved })
+
+	inputs := []InputFile{
+		{Name: "part.gcode", SHA256: sumHex(gcode), SizeBytes: int64(len(gcode))},
+		{Name: "data.csv", SHA256: sumHex(csv), URL: srv.URL + "/datasets/data.csv"},
+	}
+	dir, err := StageInputs(context.Background(), srv.Client(), srv.URL, "j1", inputs, 0)
+	if err != nil {
+		t.Fatalf("StageInputs: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, want := range map[string]string{"part.gcode": gcode, "data.csv": csv} {
+		if got, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(got) != want {
+			t.Errorf("%s = %q (%v), want %q", name, got, err, want)
+		}
+	}
+	if len(paths) != 2 {
+		t.Errorf("fetched %v, want both inputs", paths)
+	}
+}
+
+func TestStageInputs_Rejects(t *testing.T) {
+	const body = "payload"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body)) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name     string
+		in       InputFile
+		maxBytes int64
+		wantErr  error
+	}{
+		{"digest mismatch", InputFile{Name: "a.bin", SHA256: sumHex("other"), SizeBytes: int64(len(body))}, 0, ErrInputDigestMismatch},
+		{"size mismatch", InputFile{Name: "a.bin", SHA256: sumHex(body), SizeBytes: 3}, 0, ErrInputDigestMismatch},
+		{"over storage cap", InputFile{Name: "a.bin", SHA256: sumHex(body), SizeBytes: int64(len(body))}, 4, nil},
+		{"path name", InputFile{Name: "../a.bin", SHA256: sumHex(body), SizeBytes: int64(len(body))}, 0, nil},
+		{"plain http URL", InputFile{Name: "a.bin", SHA256: sumHex(body), URL: srv.URL + "/a.bin"}, 0, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := StageInputs(context.Background(), srv.Client(), srv.URL, "j1", []InputFile{tc.in}, tc.maxBytes)
+			if err == nil {
+				os.RemoveAll(dir)
+				t.Fatal("expected error, got nil")
+			}
+			if tc.wantErr != nil && !errors.Is(err, tc.wantErr) {
+				t.Errorf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublicInputClient_BlocksLoopback(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := newPublicInputClient().Get(srv.URL)
+	if !errors.Is(err, ErrInputAddressBlocked) {
+		t.Fatalf("err = %v, want ErrInputAddressBlocked", err)
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1":    true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"192.168.1.10":    false,
+		"172.16.0.1":      false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+	} {
+		if got := publicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("publicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxLogBytes caps the container output the agent ships per job; output past
+// it is dropped. It matches the control plane's per-job cap
+// (store.JobLogCapBytes), so shipping more would only be discarded there.
+const MaxLogBytes = 1 << 20
+
+// maxLogChunkBytes bounds one shipped chunk. A line longer than this is split.
+const maxLogChunkBytes = 16 << 10
+
+// maxLogBatchChunks bounds the chunks sent in one POST /jobs/{id}/logs.
+const maxLogBatchChunks = 64
+
+// redactedText replaces redacted secrets in shipped logs.
+const redactedText = "[REDACTED]"
+
+// minSecretLen is the shortest literal secret Redactor replaces; shorter
+// strings would redact ordinary output.
+const minSecretLen = 8
+
+// credentialPatterns match likely credentials in container output, with the
+// replacement keeping enough context to read the line.
+var credentialPatterns = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	// KEY=value, key: value, "api_key": "value" with a credential-like key.
+	{regexp.MustCompile(`(?i)([a-z0-9_.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credential)[a-z0-9_.-]*"?)(\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,;]+)`), "${1}${2}" + redactedText},
+	// Authorization header values.
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[a-z0-9._~+/=-]{8,}`), "${1} " + redactedText},
+	// AWS access key IDs.
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), redactedText},
+	// Passwords in URL userinfo.
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`), "${1}" + redactedText + "@"},
+}
+
+// pemKeyBegin matches the BEGIN marker of a PEM private key, capturing its
+// label ("RSA PRIVATE KEY") so the block can be followed to the END marker
+// with the same label. The base64 lines between are indistinguishable from
+// other output on their own.
+var pemKeyBegin = regexp.MustCompile(`-----BEGIN ([A-Z ]*PRIVATE KEY)-----`)
+
+// Redactor scrubs secrets from container output before it leaves the node:
+// the literal secrets it was built with (the job token, for one), anything
+// matching credentialPatterns, and PEM private keys from the BEGIN marker
+// through the matching END marker. Redaction is line-based and best-effort —
+// a secret split across a forced line break can slip through.
+type Redactor struct {
+	secrets []string
+}
+
+// NewRedactor returns a Redactor for the given literal secrets. Empty and
+// very short values are ignored.
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, s := range secrets {
+		if len(s) >= minSecretLen {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	return r
+}
+
+// Redact returns line with secrets replaced by [REDACTED]. A PEM private key
+// is redacted from its BEGIN marker to its END marker, or to the end of the
+// line when the key goes on; LogShipper follows such a key onto the lines
+// after it with redactKey.
+func (r *Redactor) Redact(line string) string {
+	line, _ = r.redactKey(line, "")
+	return line
+}
+
+// redactKey redacts line, which comes after a line that left the PEM private
+// key labelled openKey unterminated ("" for none), and returns the label of
+// the key still unterminated after it. Every line of a key, from the BEGIN
+// line through the END line, is replaced; output around the markers on those
+// lines is redacted as usual.
+func (r *Redactor) redactKey(line, openKey string) (string, string) {
+	rest, newline := strings.CutSuffix(line, "\n")
+	var out strings.Builder
+	for {
+		if openKey != "" {
+			out.WriteString(redactedText)
+			end := "-----END " + openKey + "-----"
+			i := strings.Index(rest, end)
+			if i < 0 {
+				break
+			}
+			rest, openKey = rest[i+len(end):], ""
+		}
+		m := pemKeyBegin.FindStringSubmatchIndex(rest)
+		if m == nil {
+			out.WriteString(r.redactLine(rest))
+			break
+		}
+		out.WriteString(r.redactLine(rest[:m[0]]))
+		rest, openKey = rest[m[1]:], rest[m[2]:m[3]]
+	}
+	if newline {
+		out.WriteString("\n")
+	}
+	return out.String(), openKey
+}
+
+// redactLine replaces the literal secrets and credentialPatterns in line.
+func (r *Redactor) redactLine(line string) string {
+	for _, s := range r.secrets {
+		line = strings.ReplaceAll(line, s, redactedText)
+	}
+	for _, p := range credentialPatterns {
+		line = p.re.ReplaceAllString(line, p.repl)
+	}
+	return line
+}
+
+// LogChunk is a run of redacted output from one container stream, as shipped
+// to POST /jobs/{id}/logs. Seq orders chunks within a job and lets the
+// control plane drop a retried batch.
+type LogChunk struct {
+	Seq    int64  `json:"seq"`
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+}
+
+// errLogsRejected is returned by LogShipper.Flush when the control plane no
+// longer accepts the job's logs (the job is not running); pending output is
+// discarded.
+var errLogsRejected = errors.New("agent: job logs rejected")
+
+// LogShipper ships a job's container output to the control plane. The
+// executor writes demultiplexed stdout/stderr to Stdout and Stderr; complete
+// lines are redacted and queued, and Run flushes the queue periodically.
+// Close flushes what is left once the container has exited. Output past
+// MaxLogBytes is dropped. Safe for concurrent use.
+type LogShipper struct {
+	client   *http.Client
+	url      string
+	redactor *Redactor
+
+	mu        sync.Mutex
+	partial   map[string]*bytes.Buffer
+	openKey   map[string]string // per stream: the PEM private key still being redacted
+	pending   []LogChunk
+	nextSeq   int64
+	sealed    int64 // chunks below this seq have been sent once; never extend them
+	queued    int64
+	truncated bool
+	rejected  bool
+}
+
+// NewLogShipper returns a shipper for jobID posting through client (the
+// agent's mTLS client).
+func NewLogShipper(client *http.Client, controlPlaneAddr, jobID string, redactor *Redactor) *LogShipper {
+	return &LogShipper{
+		client:   client,
+		url:      controlPlaneAddr + "/jobs/" + jobID + "/logs",
+		redactor: redactor,
+		partial:  map[string]*bytes.Buffer{"stdout": {}, "stderr": {}},
+		openKey:  make(map[string]string),
+	}
+}
+
+// Stdout returns the writer for the container's stdout.
+func (s *LogShipper) Stdout() io.Writer { return logStream{s, "stdout"} }
+
+// Stderr returns the writer for the container's stderr.
+func (s *LogShipper) Stderr() io.Writer { return logStream{s, "stderr"} }
+
+// logStream is one stream's writer. Writes never fail: a log problem must
+// not stall the container's output.
+type logStream struct {
+	s      *LogShipper
+	stream string
+}
+
+func (w logStream) Write(p []byte) (int, error) {
+	w.s.write(w.stream, p)
+	return len(p), nil
+}
+
+// write buffers p and queues every complete line, splitting lines longer
+// than maxLogChunkBytes.
+func (s *LogShipper) write(stream string, p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	buf := s.partial[stream]
+	buf.Write(p)
+	for {
+		b := buf.Bytes()
+		n := bytes.IndexByte(b, '\n') + 1
+		if n == 0 && len(b) < maxLogChunkBytes {
+			return
+		}
+		if n == 0 || n > maxLogChunkBytes {
+			n = maxLogChunkBytes
+		}
+		s.queueLocked(stream, string(buf.Next(n)))
+	}
+}
+
+// queueLocked redacts line and appends it to the pending chunks, extending
+// the last chunk when it is from the same stream, has room and has not been
+// sent (the control plane may have stored a batch whose response was lost,
+// and would drop a retried chunk's additions). Caller holds s.mu.
+func (s *LogShipper) queueLocked(stream, line string) {
+	if s.truncated || s.rejected {
+		return
+	}
+	line, s.openKey[stream] = s.redactor.redactKey(line, s.openKey[stream])
+	if room := MaxLogBytes - s.queued; int64(len(line)) > room {
+		line = line[:room]
+		s.truncated = true
+	}
+	s.queued += int64(len(line))
+	if n := len(s.pending); n > 0 && s.pending[n-1].Seq >= s.sealed &&
+		s.pending[n-1].Stream == stream && len(s.pending[n-1].Data)+len(line) <= maxLogChunkBytes {
+		s.pending[n-1].Data += line
+		return
+	}
+	s.pending = append(s.pending, LogChunk{Seq: s.nextSeq, Stream: stream, Data: line})
+	s.nextSeq++
+}
+
+// Run flushes every interval until ctx is done. Flush errors are left for
+// the next tick; Close makes the final attempt.
+func (s *LogShipper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = s.Flush(ctx)
+		}
+	}
+}
+
+// Close queues any unterminated final lines and flushes. Call once the
+// container has exited and before the job's /complete, so the consumer's
+// log tail is whole by the time the job finishes.
+func (s *LogShipper) Close(ctx context.Context) error {
+	s.mu.Lock()
+	for _, stream := range []string{"stdout", "stderr"} {
+		if buf := s.partial[stream]; buf.Len() > 0 {
+			s.queueLocked(stream, buf.String())
+			buf.Reset()
+		}
+	}
+	s.mu.Unlock()
+	return s.Flush(ctx)
+}
+
+// Flush ships the pending chunks in batches. A failed batch is requeued for
+// the next attempt, except when the control plane rejects the job's logs
+// outright (errLogsRejected), which stops shipping for good.
+func (s *LogShipper) Flush(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+		n := min(len(s.pending), maxLogBatchChunks)
+		batch := s.pending[:n:n]
+		s.pending = s.pending[n:]
+		if n > 0 {
+			s.sealed = max(s.sealed, batch[n-1].Seq+1)
+		}
+		s.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+
+		err := s.post(ctx, batch)
+		if err != nil {
+			s.mu.Lock()
+			if errors.Is(err, errLogsRejected) {
+				s.rejected = true
+				s.pending = nil
+			} else {
+				s.pending = append(batch, s.pending...)
+			}
+			s.mu.Unlock()
+			return err
+		}
+	}
+}
+
+// post sends one batch.
+func (s *LogShipper) post(ctx context.Context, batch []LogChunk) error {
+	body, err := json.Marshal(struct {
+		Chunks []LogChunk `json:"chunks"`
+	}{batch})
+	if err != nil {
+		return fmt.Errorf("ship logs: marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("ship logs: build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("ship logs: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: status %d", errLogsRejected, resp.StatusCode)
+	default:
+		return fmt.Errorf("ship logs: unexpected status %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// OutputDir is the writable mount a job with a declared output artifact gets,
+// alongside the /tmp tmpfs. It is backed by a per-job Docker volume rather
+// than tmpfs so the artifact survives container exit until the agent has read
+// it; Executor.cleanup removes the volume with the container.
+const OutputDir = "/output"
+
+// ErrNoOutputArtifact is returned when the declared output artifact is missing
+// or is not a regular file.
+var ErrNoOutputArtifact = errors.New("agent: declared output artifact not found")
+
+// outputVolumeName is the per-job Docker volume mounted at OutputDir.
+func outputVolumeName(jobID string) string {
+	return jobNetworkPrefix + jobID + "-output"
+}
+
+// hashOutput returns the lowercase hex SHA-256 of the container's declared
+// output artifact, copied out of the (exited) container.
+func (e *Executor) hashOutput(ctx context.Context, ec *ExecutionContext) (string, error) {
+	rc, _, err := e.client.CopyFromContainer(ctx, ec.ContainerID, ec.OutputPath)
+	if err != nil {
+		return "", fmt.Errorf("hash output %s: %w", ec.OutputPath, err)
+	}
+	defer rc.Close()
+	return hashTarFile(rc)
+}
+
+// hashTarFile hashes the contents of the first entry of a tar stream, which
+// must be a regular file — Docker's copy API wraps a single path in a tar
+// archive. A directory or anything else is ErrNoOutputArtifact.
+func hashTarFile(r io.Reader) (string, error) {
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", ErrNoOutputArtifact
+		}
+		return "", fmt.Errorf("read output archive: %w", err)
+	}
+	if hdr.Typeflag != tar.TypeReg {
+		return "", fmt.Errorf("%w: %s is not a regular file", ErrNoOutputArtifact, hdr.Name)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, tr); err != nil {
+		return "", fmt.Errorf("read output artifact: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Artifact is a job's collected OutputDir: a tar spooled to a local temporary
+// file, with its SHA-256 and size.
+type Artifact struct {
+	Path      string
+	SHA256    string
+	SizeBytes int64
+}
+
+// collectOutput copies OutputDir out of the (exited) container into a
+// temporary tar file, hashing it on the way. The caller owns the file.
+func (e *Executor) collectOutput(ctx context.Context, ec *ExecutionContext) (*Artifact, error) {
+	rc, _, err := e.client.CopyFromContainer(ctx, ec.ContainerID, OutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("collect output: %w", err)
+	}
+	defer rc.Close()
+	return spoolArtifact(rc)
+}
+
+// spoolArtifact writes r to a temporary file and returns it as an Artifact.
+// The file is removed on error.
+func spoolArtifact(r io.Reader) (*Artifact, error) {
+	f, err := os.CreateTemp("", "soholink-artifact-*.tar")
+	if err != nil {
+		return nil, fmt.Errorf("spool artifact: %w", err)
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name()) //nolint:errcheck
+		return nil, fmt.Errorf("spool artifact: %w", err)
+	}
+	return &Artifact{Path: f.Name(), SHA256: hex.EncodeToString(h.Sum(nil)), SizeBytes: n}, nil
+}
+
+// artifactUploadTimeout bounds an artifact upload; the telemetry client's own
+// 15s timeout is lifted for it.
+const artifactUploadTimeout = 30 * time.Minute
+
+// UploadArtifact PUTs a to the control plane as jobID's output artifact. It
+// must run before the job's /complete call, while the job is still running.
+// client is the agent's mTLS client; its timeout is replaced by
+// artifactUploadTimeout.
+func UploadArtifact(ctx context.Context, client *http.Client, controlPlaneAddr, jobID string, a *Artifact) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return fmt.Errorf("upload artifact: %w", err)
+	}
+	defer f.Close()
+
+	url := controlPlaneAddr + "/jobs/" + jobID + "/artifacts"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, f)
+	if err != nil {
+		return fmt.Errorf("upload artifact: build request: %w", err)
+	}
+	req.ContentLength = a.SizeBytes
+	req.Header.Set("Content-Type", "application/x-tar")
+	req.Header.Set("X-Artifact-SHA256", a.SHA256)
+
+	uploader := *client
+	uploader.Timeout = artifactUploadTimeout
+	resp, err := uploader.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload artifact: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("upload artifact: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
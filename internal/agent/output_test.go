@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHashTarFile(t *testing.T) {
+	archive := func(hdr *tar.Header, body string) *bytes.Buffer {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		hdr.Size = int64(len(body))
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(body)) //nolint:errcheck
+		tw.Close()             //nolint:errcheck
+		return &buf
+	}
+
+	got, err := hashTarFile(archive(&tar.Header{Name: "result.bin", Typeflag: tar.TypeReg, Mode: 0o644}, "hello"))
+	if err != nil {
+		t.Fatalf("hashTarFile: %v", err)
+	}
+	// sha256("hello")
+	if want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"; got != want {
+		t.Errorf("hash = %s, want %s", got, want)
+	}
+
+	if _, err := hashTarFile(archive(&tar.Header{Name: "out/", Typeflag: tar.TypeDir, Mode: 0o755}, "")); !errors.Is(err, ErrNoOutputArtifact) {
+		t.Errorf("directory: err = %v, want ErrNoOutputArtifact", err)
+	}
+	if _, err := hashTarFile(&bytes.Buffer{}); !errors.Is(err, ErrNoOutputArtifact) {
+		t.Errorf("empty archive: err = %v, want ErrNoOutputArtifact", err)
+	}
+}
+
+func TestSpoolArtifact(t *testing.T) {
+	a, err := spoolArtifact(strings.NewReader("tar bytes"))
+	if err != nil {
+		t.Fatalf("spoolArtifact: %v", err)
+	}
+	defer os.Remove(a.Path)
+
+	sum := sha256.Sum256([]byte("tar bytes"))
+	if a.SHA256 != hex.EncodeToString(sum[:]) || a.SizeBytes != 9 {
+		t.Errorf("artifact = %+v", a)
+	}
+	if got, _ := os.ReadFile(a.Path); string(got) != "tar bytes" {
+		t.Errorf("spooled %q, want tar bytes", got)
+	}
+}
+
+func TestUploadArtifact(t *testing.T) {
+	a, err := spoolArtifact(strings.NewReader("tar bytes"))
+	if err != nil {
+		t.Fatalf("spoolArtifact: %v", err)
+	}
+	defer os.Remove(a.Path)
+
+	var gotPath, gotSum string
+	var gotBody []byte
+	status := http.StatusCreated
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotSum = r.Method+" "+r.URL.Path, r.Header.Get("X-Artifact-SHA256")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	if err := UploadArtifact(context.Background(), srv.Client(), srv.URL, "job-1", a); err != nil {
+		t.Fatalf("UploadArtifact: %v", err)
+	}
+	if gotPath != "PUT /jobs/job-1/artifacts" || gotSum != a.SHA256 || string(gotBody) != "tar bytes" {
+		t.Errorf("server got %s sha=%s body=%q", gotPath, gotSum, gotBody)
+	}
+
+	status = http.StatusRequestEntityTooLarge
+	if err := UploadArtifact(context.Background(), srv.Client(), srv.URL, "job-1", a); err == nil {
+		t.Error("expected error on 413")
+	}
+}
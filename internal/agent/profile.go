@@ -1,10 +1,28 @@
 package agent
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
 
+// ErrJobExceedsProfile is returned by JobCaps when the active profile cannot
+// accommodate the job's request — CPU disabled, or a requested resource above
+// the profile's cap. The job must not be started.
+var ErrJobExceedsProfile = errors.New("agent: job exceeds active resource profile")
+
+// ErrProfilesNotFetched is returned by FetchProfiles until one fetch has
+// succeeded. Until then the agent cannot tell a node without profiles, which
+// runs unrestricted, from one whose caps it has not learned: it must start
+// no job.
+var ErrProfilesNotFetched = errors.New("agent: resource profiles not yet fetched")
+
+// unrestrictedProfile applies when the node has no resource_profiles rows at
+// all (nodes claimed before a contributor saved one): the whole machine, as
+// before profiles were enforced.
+var unrestrictedProfile = ResourceProfile{IsDefault: true, CPUEnabled: true, RAMPct: 100}
+
 // ResourceProfile mirrors the resource_profiles database row. All time fields
 // use *time.Time — there is no time.Date type in Go's standard library.
 // ScheduleStart/ScheduleEnd carry only the time-of-day component (hour/minute).
@@ -86,6 +104,72 @@ func ApplyCaps(profile ResourceProfile, hw HardwareProfile) CapProfile {
 	return cap
 }
 
+// ResolveProfile is ActiveProfile with the no-profiles fallback: a node whose
+// contributor never saved a profile runs unrestricted. profiles must come
+// from a successful fetch (see ErrProfilesNotFetched).
+func ResolveProfile(profiles []ResourceProfile, now time.Time) ResourceProfile {
+	if len(profiles) == 0 {
+		return unrestrictedProfile
+	}
+	return ActiveProfile(profiles, now)
+}
+
+// JobCaps resolves the profile active at now and intersects its caps with the
+// job's requested resources, yielding the limits handed to Executor.Start.
+//
+// A requested resource becomes the cap when it fits within the profile; an
+// unset request (0) takes the profile's cap. A request above the profile's cap
+// returns ErrJobExceedsProfile rather than silently shrinking the container.
+// Where the hardware figure is unknown (0) the request is used as-is.
+//
+// The returned CPUCores and RAMBytes are always non-zero: Docker treats a zero
+// NanoCPUs or Memory as "unlimited", so a zero cap would lift the limit
+// instead of enforcing it.
+func JobCaps(profiles []ResourceProfile, now time.Time, hw HardwareProfile, job JobAssignment) (CapProfile, error) {
+	profile := ResolveProfile(profiles, now)
+	if !profile.CPUEnabled {
+		return CapProfile{}, fmt.Errorf("%w: cpu disabled", ErrJobExceedsProfile)
+	}
+	limit := ApplyCaps(profile, hw)
+
+	caps := limit
+	cores, err := intersect("cpu_cores", int64(job.CPUCores), int64(limit.CPUCores), hw.CPUCores > 0)
+	if err != nil {
+		return CapProfile{}, err
+	}
+	caps.CPUCores = int(cores)
+	const mb = 1024 * 1024
+	if caps.RAMBytes, err = intersect("ram_mb", int64(job.RAMMB)*mb, limit.RAMBytes, hw.RAMMB > 0); err != nil {
+		return CapProfile{}, err
+	}
+	// StorageBytes == 0 already means "no cap", so an absent profile cap
+	// leaves only the request.
+	if caps.StorageBytes, err = intersect("storage_gb", int64(job.StorageGB)*1024*mb, limit.StorageBytes, limit.StorageBytes > 0); err != nil {
+		return CapProfile{}, err
+	}
+
+	if caps.CPUCores <= 0 || caps.RAMBytes <= 0 {
+		return CapProfile{}, fmt.Errorf("%w: no cpu or ram available (cpu_cores=%d ram_bytes=%d)",
+			ErrJobExceedsProfile, caps.CPUCores, caps.RAMBytes)
+	}
+	return caps, nil
+}
+
+// intersect returns the effective cap for one resource. known reports whether
+// limit is meaningful; when it is not, the request stands alone.
+func intersect(resource string, request, limit int64, known bool) (int64, error) {
+	switch {
+	case !known:
+		return request, nil
+	case request == 0:
+		return limit, nil
+	case request > limit:
+		return 0, fmt.Errorf("%w: %s requested %d, profile allows %d",
+			ErrJobExceedsProfile, resource, request, limit)
+	}
+	return request, nil
+}
+
 // inDateWindow reports whether now falls within [start, end] (date component
 // only, inclusive on both ends). A nil bound means that side is unbounded.
 func inDateWindow(now time.Time, start, end *time.Time) bool {
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"errors"
 	"testing"
 	"time"
 )
@@ -179,3 +180,115 @@ func TestApplyCaps(t *testing.T) {
 		}
 	})
 }
+
+func TestJobCaps(t *testing.T) {
+	hw := HardwareProfile{CPUCores: 8, RAMMB: 16384}
+	now := time.Date(2026, 4, 7, 14, 0, 0, 0, time.UTC) // Tuesday 14:00
+	const mb = int64(1024 * 1024)
+
+	def := ResourceProfile{IsDefault: true, CPUEnabled: true, RAMPct: 50, StorageGB: 100}
+	night := ResourceProfile{
+		CPUEnabled:    true,
+		RAMPct:        25,
+		ScheduleStart: tod(13, 0),
+		ScheduleEnd:   tod(15, 0),
+	}
+
+	t.Run("request within profile becomes the cap", func(t *testing.T) {
+		caps, err := JobCaps([]ResourceProfile{def}, now, hw, JobAssignment{CPUCores: 2, RAMMB: 4096, StorageGB: 10})
+		if err != nil {
+			t.Fatalf("JobCaps: %v", err)
+		}
+		if caps.CPUCores != 2 || caps.RAMBytes != 4096*mb || caps.StorageBytes != 10*1024*mb {
+			t.Errorf("caps = %+v, want 2 cores / 4096 MiB / 10 GiB", caps)
+		}
+	})
+
+	t.Run("unset request takes the profile cap", func(t *testing.T) {
+		caps, err := JobCaps([]ResourceProfile{def}, now, hw, JobAssignment{})
+		if err != nil {
+			t.Fatalf("JobCaps: %v", err)
+		}
+		if caps.CPUCores != 8 || caps.RAMBytes != 8192*mb || caps.StorageBytes != 100*1024*mb {
+			t.Errorf("caps = %+v, want 8 cores / 8192 MiB / 100 GiB", caps)
+		}
+	})
+
+	t.Run("active override narrows the default", func(t *testing.T) {
+		_, err := JobCaps([]ResourceProfile{def, night}, now, hw, JobAssignment{RAMMB: 8192})
+		if !errors.Is(err, ErrJobExceedsProfile) {
+			t.Fatalf("expected ErrJobExceedsProfile under 25%% override, got %v", err)
+		}
+		if _, err := JobCaps([]ResourceProfile{def, night}, now.Add(2*time.Hour), hw, JobAssignment{RAMMB: 8192}); err != nil {
+			t.Fatalf("outside the override window the default should admit the job: %v", err)
+		}
+	})
+
+	t.Run("cpu disabled rejects", func(t *testing.T) {
+		off := ResourceProfile{IsDefault: true, CPUEnabled: false, RAMPct: 100}
+		if _, err := JobCaps([]ResourceProfile{off}, now, hw, JobAssignment{}); !errors.Is(err, ErrJobExceedsProfile) {
+			t.Fatalf("expected ErrJobExceedsProfile, got %v", err)
+		}
+	})
+
+	t.Run("zero ram percentage rejects instead of unlimited", func(t *testing.T) {
+		noRAM := ResourceProfile{IsDefault: true, CPUEnabled: true, RAMPct: 0}
+		if _, err := JobCaps([]ResourceProfile{noRAM}, now, hw, JobAssignment{}); !errors.Is(err, ErrJobExceedsProfile) {
+			t.Fatalf("expected ErrJobExceedsProfile, got %v", err)
+		}
+	})
+
+	t.Run("no profiles falls back to whole machine", func(t *testing.T) {
+		caps, err := JobCaps(nil, now, hw, JobAssignment{})
+		if err != nil {
+			t.Fatalf("JobCaps: %v", err)
+		}
+		if caps.CPUCores != 8 || caps.RAMBytes != 16384*mb {
+			t.Errorf("caps = %+v, want whole machine", caps)
+		}
+	})
+
+	t.Run("unknown hardware uses the request", func(t *testing.T) {
+		caps, err := JobCaps([]ResourceProfile{def}, now, HardwareProfile{}, JobAssignment{CPUCores: 1, RAMMB: 512})
+		if err != nil {
+			t.Fatalf("JobCaps: %v", err)
+		}
+		if caps.CPUCores != 1 || caps.RAMBytes != 512*mb {
+			t.Errorf("caps = %+v, want 1 core / 512 MiB", caps)
+		}
+		if _, err := JobCaps([]ResourceProfile{def}, now, HardwareProfile{}, JobAssignment{}); !errors.Is(err, ErrJobExceedsProfile) {
+			t.Fatalf("unknown hardware and no request must not run uncapped, got %v", err)
+		}
+	})
+}
+
+func TestProfilePayload_ToProfile(t *testing.T) {
+	p := profilePayload{
+		CPUEnabled:        true,
+		RAMPct:            40,
+		ScheduleStart:     "22:00",
+		ScheduleEnd:       "06:30",
+		ScheduleDays:      []string{"sat", "sun"},
+		OverrideStartDate: "2026-04-01",
+	}
+	rp, err := p.toProfile()
+	if err != nil {
+		t.Fatalf("toProfile: %v", err)
+	}
+	if rp.ScheduleStart == nil || todSeconds(*rp.ScheduleStart) != 22*3600 {
+		t.Errorf("ScheduleStart = %v, want 22:00", rp.ScheduleStart)
+	}
+	if rp.ScheduleEnd == nil || todSeconds(*rp.ScheduleEnd) != 6*3600+30*60 {
+		t.Errorf("ScheduleEnd = %v, want 06:30", rp.ScheduleEnd)
+	}
+	if rp.OverrideStartDate == nil || !rp.OverrideStartDate.Equal(*date(2026, 4, 1)) {
+		t.Errorf("OverrideStartDate = %v, want 2026-04-01", rp.OverrideStartDate)
+	}
+	if rp.OverrideEndDate != nil {
+		t.Errorf("OverrideEndDate = %v, want nil", rp.OverrideEndDate)
+	}
+
+	if _, err := (profilePayload{ScheduleStart: "10pm"}).toProfile(); err == nil {
+		t.Error("expected error for malformed schedule_start")
+	}
+}
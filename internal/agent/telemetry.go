@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -17,7 +18,7 @@ import (
 
 // TelemetryPayload is the signed telemetry record emitted by the agent
 // during job execution. The Signature field is an HMAC-SHA256 over the
-// base64url-encoded canonical message string.
+// base64url-encoded canonical message string of version SigVersion.
 type TelemetryPayload struct {
 	NodeID        string    `json:"node_id"`
 	JobID         string    `json:"job_id"`
@@ -25,29 +26,89 @@ type TelemetryPayload struct {
 	RAMPct        float64   `json:"ram_pct"`
 	BandwidthMbps int       `json:"bandwidth_mbps"`
 	Timestamp     time.Time `json:"timestamp"`
+	SigVersion    int       `json:"sig_version,omitempty"`
 	Signature     string    `json:"signature"`
 }
 
-// SignTelemetry attaches an HMAC-SHA256 signature to payload and returns
-// the updated payload. The canonical message is:
+// Telemetry signature versions. Version 1, which agents predating
+// sig_version send as 0, leaves BandwidthMbps out of the canonical message;
+// version 2 covers it, since metering bills egress from it.
+const (
+	TelemetrySigV1 = 1
+	TelemetrySigV2 = 2
+)
+
+// ErrTelemetrySignature is returned by VerifyTelemetry when the payload's
+// signature does not match the HMAC computed with the node's secret.
+var ErrTelemetrySignature = errors.New("telemetry signature mismatch")
+
+// SignTelemetry attaches a version 2 HMAC-SHA256 signature to payload and
+// returns the updated payload. The canonical message is:
 //
-//	base64RawURL( nodeID|jobID|cpu_pct|ram_pct|timestamp_RFC3339 )
+//	base64RawURL( 2|nodeID|jobID|cpu_pct|ram_pct|bandwidth_mbps|timestamp_RFC3339 )
 //
 // The signature is base64RawURL( HMAC-SHA256( canonical, secret ) ).
 func SignTelemetry(payload TelemetryPayload, secret []byte) (TelemetryPayload, error) {
 	if payload.NodeID == "" || payload.JobID == "" {
 		return TelemetryPayload{}, fmt.Errorf("sign telemetry: NodeID and JobID must not be empty")
 	}
+	payload.SigVersion = TelemetrySigV2
+	payload.Signature = base64.RawURLEncoding.EncodeToString(telemetryMAC(payload, secret))
+	return payload, nil
+}
+
+// VerifyTelemetry checks payload.Signature against the HMAC the control plane
+// recomputes with the node's stored secret, using the canonical form of
+// payload.SigVersion. The comparison is constant-time. Sub-second timestamp
+// precision is not authenticated. A version 1 signature does not cover
+// BandwidthMbps, so a version 1 payload reporting any bandwidth is rejected
+// rather than let an unauthenticated value bill egress.
+func VerifyTelemetry(payload TelemetryPayload, secret []byte) error {
+	if payload.NodeID == "" || payload.JobID == "" {
+		return fmt.Errorf("verify telemetry: NodeID and JobID must not be empty")
+	}
+	if len(secret) == 0 {
+		return fmt.Errorf("verify telemetry: empty secret")
+	}
+	switch payload.SigVersion {
+	case 0, TelemetrySigV1:
+		if payload.BandwidthMbps != 0 {
+			return fmt.Errorf("verify telemetry: bandwidth_mbps is not covered by a version 1 signature: %w", ErrTelemetrySignature)
+		}
+	case TelemetrySigV2:
+	default:
+		return fmt.Errorf("verify telemetry: unknown signature version %d: %w", payload.SigVersion, ErrTelemetrySignature)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(payload.Signature)
+	if err != nil {
+		return fmt.Errorf("verify telemetry: decode signature: %w", ErrTelemetrySignature)
+	}
+	if !hmac.Equal(sig, telemetryMAC(payload, secret)) {
+		return fmt.Errorf("verify telemetry: %w", ErrTelemetrySignature)
+	}
+	return nil
+}
+
+// telemetryMAC computes the raw HMAC-SHA256 over the base64url-encoded
+// canonical message shared by SignTelemetry and VerifyTelemetry.
+func telemetryMAC(payload TelemetryPayload, secret []byte) []byte {
 	raw := payload.NodeID + "|" +
 		payload.JobID + "|" +
 		fmt.Sprintf("%.2f", payload.CPUPct) + "|" +
 		fmt.Sprintf("%.2f", payload.RAMPct) + "|" +
 		payload.Timestamp.UTC().Format(time.RFC3339)
+	if payload.SigVersion == TelemetrySigV2 {
+		raw = "2|" + payload.NodeID + "|" +
+			payload.JobID + "|" +
+			fmt.Sprintf("%.2f", payload.CPUPct) + "|" +
+			fmt.Sprintf("%.2f", payload.RAMPct) + "|" +
+			fmt.Sprintf("%d", payload.BandwidthMbps) + "|" +
+			payload.Timestamp.UTC().Format(time.RFC3339)
+	}
 	encoded := base64.RawURLEncoding.EncodeToString([]byte(raw))
 	mac := hmac.New(sha256.New, secret)
 	mac.Write([]byte(encoded))
-	payload.Signature = base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
-	return payload, nil
+	return mac.Sum(nil)
 }
 
 // CollectTelemetry samples current CPU and RAM utilisation, assembles a
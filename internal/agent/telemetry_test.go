@@ -1,6 +1,8 @@
 package agent
 
 import (
+	"encoding/base64"
+	"errors"
 	"testing"
 	"time"
 )
@@ -86,3 +88,117 @@ func TestSignTelemetry_TamperedPayloadDifferentSignature(t *testing.T) {
 		t.Error("expected different signatures for different CPUPct, got identical")
 	}
 }
+
+func testTelemetryPayload() TelemetryPayload {
+	return TelemetryPayload{
+		NodeID:        "node-1",
+		JobID:         "job-1",
+		CPUPct:        42.5,
+		RAMPct:        61.25,
+		BandwidthMbps: 10,
+		Timestamp:     time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestVerifyTelemetry_RoundTrip(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	signed, err := SignTelemetry(testTelemetryPayload(), secret)
+	if err != nil {
+		t.Fatalf("SignTelemetry: %v", err)
+	}
+	if err := VerifyTelemetry(signed, secret); err != nil {
+		t.Fatalf("VerifyTelemetry: %v", err)
+	}
+}
+
+func TestVerifyTelemetry_SubSecondTimestampIgnored(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	signed, err := SignTelemetry(testTelemetryPayload(), secret)
+	if err != nil {
+		t.Fatalf("SignTelemetry: %v", err)
+	}
+	// The canonical form is RFC 3339 at second precision; a JSON round trip
+	// that adds nanoseconds must not break verification.
+	signed.Timestamp = signed.Timestamp.Add(500 * time.Millisecond)
+	if err := VerifyTelemetry(signed, secret); err != nil {
+		t.Fatalf("VerifyTelemetry: %v", err)
+	}
+}
+
+func TestVerifyTelemetry_Rejects(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	signed, err := SignTelemetry(testTelemetryPayload(), secret)
+	if err != nil {
+		t.Fatalf("SignTelemetry: %v", err)
+	}
+
+	cases := map[string]func(p TelemetryPayload) (TelemetryPayload, []byte){
+		"wrong secret": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			return p, []byte("another-secret-another-secret-xx")
+		},
+		"tampered cpu": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			p.CPUPct = 12.5
+			return p, secret
+		},
+		"tampered bandwidth": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			p.BandwidthMbps = 1000
+			return p, secret
+		},
+		"downgraded version": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			p.SigVersion = TelemetrySigV1
+			return p, secret
+		},
+		"unknown version": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			p.SigVersion = 3
+			return p, secret
+		},
+		"other job": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			p.JobID = "job-2"
+			return p, secret
+		},
+		"shifted timestamp": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			p.Timestamp = p.Timestamp.Add(time.Second)
+			return p, secret
+		},
+		"malformed signature": func(p TelemetryPayload) (TelemetryPayload, []byte) {
+			p.Signature = "not base64url!"
+			return p, secret
+		},
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, key := mutate(signed)
+			if err := VerifyTelemetry(p, key); !errors.Is(err, ErrTelemetrySignature) {
+				t.Fatalf("expected ErrTelemetrySignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyTelemetry_Version1(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	// An agent predating sig_version signs without bandwidth and sends no
+	// version.
+	legacy := testTelemetryPayload()
+	legacy.BandwidthMbps = 0
+	legacy.Signature = base64.RawURLEncoding.EncodeToString(telemetryMAC(legacy, secret))
+	if err := VerifyTelemetry(legacy, secret); err != nil {
+		t.Fatalf("VerifyTelemetry(version 1): %v", err)
+	}
+
+	// The same signature cannot vouch for a bandwidth it does not cover.
+	legacy.BandwidthMbps = 10
+	if err := VerifyTelemetry(legacy, secret); !errors.Is(err, ErrTelemetrySignature) {
+		t.Fatalf("version 1 with bandwidth: expected ErrTelemetrySignature, got %v", err)
+	}
+}
+
+func TestVerifyTelemetry_EmptySecret(t *testing.T) {
+	signed, err := SignTelemetry(testTelemetryPayload(), []byte("k"))
+	if err != nil {
+		t.Fatalf("SignTelemetry: %v", err)
+	}
+	if err := VerifyTelemetry(signed, nil); err == nil {
+		t.Fatal("expected error for empty secret")
+	}
+}
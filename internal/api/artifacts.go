@@ -0,0 +1,167 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// artifactUploadTimeout replaces the server's 15s read deadline for the
+// duration of an artifact upload.
+const artifactUploadTimeout = 30 * time.Minute
+
+// hexSHA256 matches a lowercase hex SHA-256 digest.
+var hexSHA256 = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// artifactUploadResponse is the JSON body returned by PUT /jobs/{id}/artifacts.
+type artifactUploadResponse struct {
+	SHA256    string    `json:"sha256"`
+	SizeBytes int64     `json:"size_bytes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleUploadArtifact accepts the tar of a running job's output directory
+// from the node it runs on. The body is the raw tar with an exact
+// Content-Length and the agent's SHA-256 in X-Artifact-SHA256; the handler
+// hashes the bytes as they stream into the artifact store and discards the
+// object on a mismatch. Size is bounded by limits.MaxBytes (413) and the
+// consumer's limits.QuotaBytes (413, checked before and after the upload).
+// The agent uploads before /complete, so the artifact is recorded by the time
+// the consumer sees the job finish.
+func handleUploadArtifact(db *store.DB, artifacts artifact.Store, limits artifact.Limits) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if artifacts == nil {
+			writeError(w, http.StatusServiceUnavailable, "artifact store not configured")
+			return
+		}
+		jobID := r.PathValue("id")
+		if jobID == "" {
+			writeError(w, http.StatusBadRequest, "job ID required")
+			return
+		}
+
+		var nodeID, consumerID, status, outputPath string
+		err := db.Pool.QueryRow(r.Context(), `
+			SELECT COALESCE(node_id::text, ''), participant_id::text, status::text,
+			       COALESCE(output_path, '')
+			FROM jobs
+			WHERE id = $1`,
+			jobID,
+		).Scan(&nodeID, &consumerID, &status, &outputPath)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		// Same SPIFFE binding as /jobs/{id}/complete.
+		spiffeID, ok := identity.SPIFFEIDFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "no SPIFFE identity in context")
+			return
+		}
+		if spiffeID.Path() != "/node/"+nodeID {
+			writeError(w, http.StatusForbidden, "SPIFFE identity does not match job owner")
+			return
+		}
+		if outputPath == "" {
+			writeError(w, http.StatusBadRequest, "job declares no output")
+			return
+		}
+		if status != "running" {
+			writeError(w, http.StatusConflict, "job is not in running state")
+			return
+		}
+
+		wantSum := r.Header.Get("X-Artifact-SHA256")
+		if !hexSHA256.MatchString(wantSum) {
+			writeError(w, http.StatusBadRequest, "X-Artifact-SHA256 must be a hex SHA-256 digest")
+			return
+		}
+		size := r.ContentLength
+		if size < 0 {
+			writeError(w, http.StatusLengthRequired, "Content-Length required")
+			return
+		}
+		if size > limits.MaxBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, "artifact exceeds maximum size")
+			return
+		}
+		used, err := store.ArtifactUsage(r.Context(), db, consumerID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if used+size > limits.QuotaBytes {
+			writeError(w, http.StatusRequestEntityTooLarge, "artifact quota exceeded")
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		_ = rc.SetReadDeadline(time.Now().Add(artifactUploadTimeout))
+
+		// A fresh key per upload: the job's recorded artifact is untouched
+		// until this one verifies and replaces it, so a retry that arrives
+		// corrupt cannot destroy a good upload.
+		key := artifact.UploadKey(jobID)
+		h := sha256.New()
+		body := io.TeeReader(http.MaxBytesReader(w, r.Body, size), h)
+		if err := artifacts.Put(r.Context(), key, body, size); err != nil {
+			slog.Error("artifact upload failed", "job_id", jobID, "error", err)
+			writeError(w, http.StatusBadGateway, "artifact store write failed")
+			return
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != wantSum {
+			discardArtifact(r, artifacts, jobID, key)
+			writeError(w, http.StatusBadRequest, "artifact digest mismatch")
+			return
+		}
+
+		a := store.JobArtifact{
+			JobID:         jobID,
+			ParticipantID: consumerID,
+			ObjectKey:     key,
+			SizeBytes:     size,
+			SHA256:        wantSum,
+			ExpiresAt:     time.Now().Add(limits.TTL).UTC(),
+		}
+		replaced, err := store.RecordArtifact(r.Context(), db, a, limits.QuotaBytes)
+		if err != nil {
+			discardArtifact(r, artifacts, jobID, key)
+			if errors.Is(err, store.ErrArtifactQuotaExceeded) {
+				writeError(w, http.StatusRequestEntityTooLarge, "artifact quota exceeded")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if replaced != "" {
+			discardArtifact(r, artifacts, jobID, replaced)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(artifactUploadResponse{ //nolint:errcheck
+			SHA256:    a.SHA256,
+			SizeBytes: a.SizeBytes,
+			ExpiresAt: a.ExpiresAt,
+		})
+	}
+}
+
+// discardArtifact removes an object no artifact row points at: an upload
+// rejected after it was stored, or the earlier upload a newer one replaced.
+// A failure leaves an unreferenced object; it is logged, not returned.
+func discardArtifact(r *http.Request, artifacts artifact.Store, jobID, key string) {
+	if err := artifacts.Delete(r.Context(), key); err != nil {
+		slog.Warn("discard rejected artifact failed", "job_id", jobID, "error", err)
+	}
+}
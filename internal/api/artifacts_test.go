@@ -0,0 +1,194 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// seedOutputJob inserts a node and a running job declaring /output, returning
+// the consumer, node and job IDs.
+func seedOutputJob(t *testing.T, db *store.DB, email string) (consumerID, nodeID, jobID string) {
+	t.Helper()
+	consumerID = seedAPIParticipant(t, db, email)
+	nodeID = seedPubkeyNode(t, db, consumerID)
+	if err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO jobs (participant_id, node_id, workload_type, status,
+		  amount_cents, cpu_cores, ram_mb, started_at, output_path)
+		 VALUES ($1, $2, 'batch_compute', 'running', 0, 2, 4096, NOW(), '/output')
+		 RETURNING id`,
+		consumerID, nodeID,
+	).Scan(&jobID); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	return consumerID, nodeID, jobID
+}
+
+func uploadRequest(t *testing.T, jobID, nodeID string, body []byte, sum string) *http.Request {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPut, "/jobs/"+jobID+"/artifacts", bytes.NewReader(body))
+	r.Header.Set("X-Artifact-SHA256", sum)
+	r.SetPathValue("id", jobID)
+	return withNodeSPIFFE(r, nodeID)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// ── handleUploadArtifact ─────────────────────────────────────────────────────
+
+func TestHandleUploadArtifact_NoStore(t *testing.T) {
+	s := &APIServer{}
+	r := httptest.NewRequest(http.MethodPut, "/jobs/j/artifacts", nil)
+	r.SetPathValue("id", "j")
+	w := httptest.NewRecorder()
+	s.handleUploadArtifact(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+}
+
+func TestHandleUploadArtifact_Stores(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	ps.artifacts, _ = artifact.NewFSStore(t.TempDir())
+	ps.limits = artifact.DefaultLimits
+	consumerID, nodeID, jobID := seedOutputJob(t, db, "artifact_ok@test.com")
+
+	body := []byte("tar bytes")
+	w := httptest.NewRecorder()
+	ps.handleUploadArtifact(w, uploadRequest(t, jobID, nodeID, body, sha256Hex(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	a, err := store.ConsumerArtifact(context.Background(), db, jobID, consumerID)
+	if err != nil {
+		t.Fatalf("ConsumerArtifact: %v", err)
+	}
+	if a.SizeBytes != int64(len(body)) || a.SHA256 != sha256Hex(body) {
+		t.Errorf("recorded size=%d sha=%s", a.SizeBytes, a.SHA256)
+	}
+	rc, err := ps.artifacts.Open(context.Background(), a.ObjectKey)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer rc.Close()
+	if got, _ := io.ReadAll(rc); !bytes.Equal(got, body) {
+		t.Errorf("stored %q, want %q", got, body)
+	}
+}
+
+func TestHandleUploadArtifact_DigestMismatch(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	dir := t.TempDir()
+	ps.artifacts, _ = artifact.NewFSStore(dir)
+	ps.limits = artifact.DefaultLimits
+	_, nodeID, jobID := seedOutputJob(t, db, "artifact_digest@test.com")
+
+	w := httptest.NewRecorder()
+	ps.handleUploadArtifact(w, uploadRequest(t, jobID, nodeID, []byte("tar bytes"), sha256Hex([]byte("other"))))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	if objects := storedObjects(t, dir); len(objects) != 0 {
+		t.Errorf("rejected upload left objects: %v", objects)
+	}
+}
+
+// TestHandleUploadArtifact_RetryKeepsOrReplaces verifies a corrupt retry
+// leaves the recorded artifact intact, and a good one replaces it and
+// deletes the object it replaced.
+func TestHandleUploadArtifact_RetryKeepsOrReplaces(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	dir := t.TempDir()
+	ps.artifacts, _ = artifact.NewFSStore(dir)
+	ps.limits = artifact.DefaultLimits
+	consumerID, nodeID, jobID := seedOutputJob(t, db, "artifact_retry@test.com")
+	ctx := context.Background()
+
+	good := []byte("good tar")
+	w := httptest.NewRecorder()
+	ps.handleUploadArtifact(w, uploadRequest(t, jobID, nodeID, good, sha256Hex(good)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("first upload: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	ps.handleUploadArtifact(w, uploadRequest(t, jobID, nodeID, []byte("truncat"), sha256Hex(good)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("corrupt retry: expected 400, got %d", w.Code)
+	}
+	a, err := store.ConsumerArtifact(ctx, db, jobID, consumerID)
+	if err != nil {
+		t.Fatalf("ConsumerArtifact: %v", err)
+	}
+	rc, err := ps.artifacts.Open(ctx, a.ObjectKey)
+	if err != nil {
+		t.Fatalf("recorded artifact gone after a corrupt retry: %v", err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(got, good) {
+		t.Errorf("recorded artifact = %q, want %q", got, good)
+	}
+
+	newer := []byte("newer tar")
+	w = httptest.NewRecorder()
+	ps.handleUploadArtifact(w, uploadRequest(t, jobID, nodeID, newer, sha256Hex(newer)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("replacing upload: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := ps.artifacts.Open(ctx, a.ObjectKey); !errors.Is(err, artifact.ErrNotFound) {
+		t.Errorf("replaced object not deleted: err = %v", err)
+	}
+	if objects := storedObjects(t, dir); len(objects) != 1 {
+		t.Errorf("stored objects = %v, want only the newer upload", objects)
+	}
+}
+
+// storedObjects lists the files under an FSStore root.
+func storedObjects(t *testing.T, dir string) []string {
+	t.Helper()
+	var out []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			out = append(out, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("walk store: %v", err)
+	}
+	return out
+}
+
+func TestHandleUploadArtifact_QuotaExceeded(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	ps.artifacts, _ = artifact.NewFSStore(t.TempDir())
+	ps.limits = artifact.Limits{MaxBytes: 1 << 20, QuotaBytes: 4, TTL: artifact.DefaultLimits.TTL}
+	_, nodeID, jobID := seedOutputJob(t, db, "artifact_quota@test.com")
+
+	body := []byte("tar bytes")
+	w := httptest.NewRecorder()
+	ps.handleUploadArtifact(w, uploadRequest(t, jobID, nodeID, body, sha256Hex(body)))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+}
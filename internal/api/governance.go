@@ -75,6 +75,15 @@ type GovernanceServer struct {
 	// without that call: the route renders a 500, the same failure mode as an
 	// unconfigured console. Stays on the LOCAL-ONLY :8090 mux.
 	sounding soundingReadModel
+
+	// reconciliation is the Stripe reconciliation read model behind GET
+	// /admin/reconciliation, populated by ConfigureReconciliation
+	// (governance_reconciliation.go). Nil renders a 500, like sounding.
+	reconciliation reconciliationReadModel
+
+	// pricing is the resource-pricing repository behind /admin/pricing,
+	// populated by ConfigurePricing (governance_pricing.go). Nil answers 500.
+	pricing pricingRepo
 }
 
 // GovernanceConfig configures the :8090 server. CoordinatorKey and CoordinatorID
@@ -174,6 +183,18 @@ func (g *GovernanceServer) registerRoutes(mux *http.ServeMux) {
 	// Demand-sounding dashboard (governance_sounding.go). Server-rendered SVG
 	// charts over the migration-025 hypertables; LOCAL-ONLY like the rest.
 	mux.HandleFunc("GET /admin/sounding", g.handleAdminSoundingPage)
+
+	// Stripe reconciliation report (governance_reconciliation.go). Reads the
+	// reconciler's stored runs; LOCAL-ONLY like the rest.
+	mux.HandleFunc("GET /admin/reconciliation", g.handleAdminReconciliationPage)
+
+	// Effective-dated resource pricing (governance_pricing.go): the console
+	// page, a dry-run preview against recent jobs, schedule, and retire.
+	// LOCAL-ONLY like the rest.
+	mux.HandleFunc("GET /admin/pricing", g.handleAdminPricingPage)
+	mux.HandleFunc("POST /admin/pricing/preview", g.handlePreviewPricing)
+	mux.HandleFunc("POST /admin/pricing", g.handleSchedulePricing)
+	mux.HandleFunc("POST /admin/pricing/{id}/retire", g.handleRetirePricing)
 }
 
 // Start begins serving the :8090 governance surface on the (loopback) listener.
@@ -0,0 +1,364 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// This file adds LOCAL-ONLY :8090 RESOURCE PRICING governance: the
+// effective-dated resource_pricing rates metering and quotes price jobs at.
+// An admin previews a proposed rate against recent jobs, schedules it from now
+// or a future instant (closing the rate it replaces there), and retires rates.
+// Every change is recorded with who made it (migration 044). Rates are never
+// changed retroactively: a job is priced at the rates in effect when it was
+// metered, or when it was quoted.
+
+// pricingHistoryLimit bounds the audit trail shown on GET /admin/pricing.
+const pricingHistoryLimit = 50
+
+// pricingRepo is the resource-pricing surface the handlers use. An interface
+// so a test GovernanceServer can use a fake; nil renders a 500, like an
+// unconfigured sounding dashboard.
+type pricingRepo interface {
+	ListPricing(ctx context.Context) ([]store.PricingRate, error)
+	PricingHistory(ctx context.Context, limit int) ([]store.PricingChange, error)
+	PreviewPricing(ctx context.Context, resourceType string, rate float64) (store.PricingImpact, error)
+	SchedulePricing(ctx context.Context, r store.NewPricingRate) (store.PricingRate, error)
+	RetirePricing(ctx context.Context, id string, at time.Time, changedBy, reason string) (store.PricingRate, error)
+}
+
+// PricingStore is the pricing repository over the coordinator DB. Construct
+// with NewPricingStore.
+type PricingStore struct {
+	db *store.DB
+}
+
+// compile-time assertion that *PricingStore satisfies pricingRepo.
+var _ pricingRepo = (*PricingStore)(nil)
+
+// NewPricingStore returns a PricingStore over db.
+func NewPricingStore(db *store.DB) *PricingStore {
+	return &PricingStore{db: db}
+}
+
+// ListPricing returns every rate.
+func (p *PricingStore) ListPricing(ctx context.Context) ([]store.PricingRate, error) {
+	return store.ListPricing(ctx, p.db)
+}
+
+// PricingHistory returns the most recent pricing changes.
+func (p *PricingStore) PricingHistory(ctx context.Context, limit int) ([]store.PricingChange, error) {
+	return store.PricingHistory(ctx, p.db, limit)
+}
+
+// PreviewPricing reprices recent jobs at a proposed rate.
+func (p *PricingStore) PreviewPricing(ctx context.Context, resourceType string, rate float64) (store.PricingImpact, error) {
+	return store.PreviewPricing(ctx, p.db, resourceType, rate)
+}
+
+// SchedulePricing schedules a rate.
+func (p *PricingStore) SchedulePricing(ctx context.Context, r store.NewPricingRate) (store.PricingRate, error) {
+	return store.SchedulePricing(ctx, p.db, r)
+}
+
+// RetirePricing retires or cancels a rate.
+func (p *PricingStore) RetirePricing(ctx context.Context, id string, at time.Time, changedBy, reason string) (store.PricingRate, error) {
+	return store.RetirePricing(ctx, p.db, id, at, changedBy, reason)
+}
+
+// ConfigurePricing attaches the pricing repository behind GET /admin/pricing
+// and the pricing POST routes. Separate from the constructor like
+// ConfigureSounding; without it those routes answer 500.
+func (g *GovernanceServer) ConfigurePricing(repo pricingRepo) {
+	g.pricing = repo
+}
+
+type previewPricingRequest struct {
+	ResourceType string  `json:"resource_type"`
+	BaseRate     float64 `json:"base_rate"` // 0 previews retiring the type's rate
+}
+
+type pricingImpactResponse struct {
+	ResourceType  string  `json:"resource_type"`
+	Since         string  `json:"since"` // RFC3339
+	CurrentRate   float64 `json:"current_rate"`
+	ProposedRate  float64 `json:"proposed_rate"`
+	Jobs          int     `json:"jobs"`
+	CurrentCents  int64   `json:"current_cents"`
+	ProposedCents int64   `json:"proposed_cents"`
+	DeltaCents    int64   `json:"delta_cents"`
+}
+
+type schedulePricingRequest struct {
+	ResourceType     string   `json:"resource_type"`
+	BaseRate         float64  `json:"base_rate"`
+	ContributorShare *float64 `json:"contributor_share,omitempty"` // omitted: carried forward
+	EffectiveFrom    string   `json:"effective_from,omitempty"`    // RFC3339; omitted: now
+	ChangedBy        string   `json:"changed_by"`
+	Reason           string   `json:"reason"`
+}
+
+type retirePricingRequest struct {
+	EffectiveUntil string `json:"effective_until,omitempty"` // RFC3339; omitted: now
+	ChangedBy      string `json:"changed_by"`
+	Reason         string `json:"reason"`
+}
+
+type pricingRateResponse struct {
+	ID               string  `json:"id"`
+	ResourceType     string  `json:"resource_type"`
+	BaseRate         float64 `json:"base_rate"`
+	ContributorShare float64 `json:"contributor_share"`
+	EffectiveFrom    string  `json:"effective_from"`            // RFC3339
+	EffectiveUntil   string  `json:"effective_until,omitempty"` // RFC3339; "" while open-ended
+	Status           string  `json:"status"`
+}
+
+func pricingToResponse(p store.PricingRate, now time.Time) pricingRateResponse {
+	resp := pricingRateResponse{
+		ID:               p.ID,
+		ResourceType:     p.ResourceType,
+		BaseRate:         p.BaseRate,
+		ContributorShare: p.ContributorShare,
+		EffectiveFrom:    p.EffectiveFrom.UTC().Format(time.RFC3339),
+		Status:           p.Status(now),
+	}
+	if p.EffectiveUntil != nil {
+		resp.EffectiveUntil = p.EffectiveUntil.UTC().Format(time.RFC3339)
+	}
+	return resp
+}
+
+// parseOptionalTime parses an RFC3339 instant; "" is the zero time (now).
+func parseOptionalTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// writePricingError maps a store pricing error to a status.
+func writePricingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrUnknownResourceType):
+		writeError(w, http.StatusBadRequest, "unknown resource_type")
+	case errors.Is(err, store.ErrPricingNotFound):
+		writeError(w, http.StatusNotFound, "pricing rate not found")
+	case errors.Is(err, store.ErrPricingRetroactive):
+		writeError(w, http.StatusConflict, "pricing changes cannot take effect in the past (non-retroactive)")
+	case errors.Is(err, store.ErrPricingOverlap):
+		writeError(w, http.StatusConflict, "a rate is already scheduled at or after effective_from; retire it first")
+	case errors.Is(err, store.ErrPricingHasSuccessor):
+		writeError(w, http.StatusConflict, "a later rate is scheduled; retire that one first")
+	case errors.Is(err, store.ErrPricingRetired):
+		writeError(w, http.StatusConflict, "rate is already retired")
+	default:
+		writeError(w, http.StatusInternalServerError, "could not change pricing")
+	}
+}
+
+// handlePreviewPricing reports what a proposed rate would have charged the
+// last 30 days of metered jobs against what they were charged. Pure read:
+// nothing is scheduled.
+func (g *GovernanceServer) handlePreviewPricing(w http.ResponseWriter, r *http.Request) {
+	if g.pricing == nil {
+		writeError(w, http.StatusInternalServerError, "pricing unavailable")
+		return
+	}
+	var req previewPricingRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return
+	}
+	if req.BaseRate < 0 {
+		writeError(w, http.StatusBadRequest, "base_rate must be non-negative")
+		return
+	}
+	impact, err := g.pricing.PreviewPricing(r.Context(), req.ResourceType, req.BaseRate)
+	if err != nil {
+		writePricingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pricingImpactResponse{
+		ResourceType:  impact.ResourceType,
+		Since:         impact.Since.UTC().Format(time.RFC3339),
+		CurrentRate:   impact.CurrentRate,
+		ProposedRate:  impact.ProposedRate,
+		Jobs:          impact.Jobs,
+		CurrentCents:  impact.CurrentCents,
+		ProposedCents: impact.ProposedCents,
+		DeltaCents:    impact.ProposedCents - impact.CurrentCents,
+	})
+}
+
+// handleSchedulePricing schedules a rate for a resource type from
+// effective_from (default now), closing the rate it replaces at that instant.
+func (g *GovernanceServer) handleSchedulePricing(w http.ResponseWriter, r *http.Request) {
+	if g.pricing == nil {
+		writeError(w, http.StatusInternalServerError, "pricing unavailable")
+		return
+	}
+	var req schedulePricingRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return
+	}
+	req.ChangedBy = strings.TrimSpace(req.ChangedBy)
+	if req.ChangedBy == "" {
+		writeError(w, http.StatusBadRequest, "changed_by is required")
+		return
+	}
+	if req.BaseRate < 0 || req.BaseRate >= 10000 {
+		writeError(w, http.StatusBadRequest, "base_rate must be at least 0 and below 10000")
+		return
+	}
+	if s := req.ContributorShare; s != nil && (*s < 0 || *s > 1) {
+		writeError(w, http.StatusBadRequest, "contributor_share must be between 0 and 1")
+		return
+	}
+	from, err := parseOptionalTime(req.EffectiveFrom)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "effective_from must be RFC3339")
+		return
+	}
+
+	p, err := g.pricing.SchedulePricing(r.Context(), store.NewPricingRate{
+		ResourceType:     req.ResourceType,
+		BaseRate:         req.BaseRate,
+		ContributorShare: req.ContributorShare,
+		EffectiveFrom:    from,
+		ChangedBy:        req.ChangedBy,
+		Reason:           strings.TrimSpace(req.Reason),
+	})
+	if err != nil {
+		writePricingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, pricingToResponse(p, time.Now()))
+}
+
+// handleRetirePricing ends a rate at effective_until (default now), or
+// cancels a rate that has not yet taken effect.
+func (g *GovernanceServer) handleRetirePricing(w http.ResponseWriter, r *http.Request) {
+	if g.pricing == nil {
+		writeError(w, http.StatusInternalServerError, "pricing unavailable")
+		return
+	}
+	var req retirePricingRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return
+	}
+	req.ChangedBy = strings.TrimSpace(req.ChangedBy)
+	if req.ChangedBy == "" {
+		writeError(w, http.StatusBadRequest, "changed_by is required")
+		return
+	}
+	until, err := parseOptionalTime(req.EffectiveUntil)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "effective_until must be RFC3339")
+		return
+	}
+
+	p, err := g.pricing.RetirePricing(r.Context(), r.PathValue("id"), until, req.ChangedBy, strings.TrimSpace(req.Reason))
+	if err != nil {
+		writePricingError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pricingToResponse(p, time.Now()))
+}
+
+type adminPricingRateRow struct {
+	ID               string
+	BaseRate         float64
+	ContributorShare float64
+	EffectiveFrom    string
+	EffectiveUntil   string // "" while open-ended
+	Status           string
+}
+
+type adminPricingType struct {
+	ResourceType string
+	Rates        []adminPricingRateRow
+}
+
+type adminPricingChangeRow struct {
+	At             string
+	Action         string
+	ResourceType   string
+	BaseRate       float64
+	EffectiveFrom  string
+	EffectiveUntil string
+	ChangedBy      string
+	Reason         string
+}
+
+type adminPricingData struct {
+	Types         []adminPricingType
+	ResourceTypes []string
+	History       []adminPricingChangeRow
+}
+
+// handleAdminPricingPage renders gov_pricing.html: each resource type's
+// rates — active, scheduled, retired — the audit trail, and the
+// preview/schedule form, which POSTs to the JSON routes above. Pure read.
+func (g *GovernanceServer) handleAdminPricingPage(w http.ResponseWriter, r *http.Request) {
+	if g.pricing == nil {
+		http.Error(w, "pricing unavailable", http.StatusInternalServerError)
+		return
+	}
+	ctx := r.Context()
+	rates, err := g.pricing.ListPricing(ctx)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	history, err := g.pricing.PricingHistory(ctx, pricingHistoryLimit)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	const stamp = "2006-01-02 15:04 UTC"
+	now := time.Now()
+	data := adminPricingData{ResourceTypes: store.PricingResourceTypes}
+	for _, rt := range store.PricingResourceTypes {
+		t := adminPricingType{ResourceType: rt}
+		for _, p := range rates {
+			if p.ResourceType != rt {
+				continue
+			}
+			row := adminPricingRateRow{
+				ID:               p.ID,
+				BaseRate:         p.BaseRate,
+				ContributorShare: p.ContributorShare,
+				EffectiveFrom:    p.EffectiveFrom.UTC().Format(stamp),
+				Status:           p.Status(now),
+			}
+			if p.EffectiveUntil != nil {
+				row.EffectiveUntil = p.EffectiveUntil.UTC().Format(stamp)
+			}
+			t.Rates = append(t.Rates, row)
+		}
+		data.Types = append(data.Types, t)
+	}
+	for _, c := range history {
+		row := adminPricingChangeRow{
+			At:            c.CreatedAt.UTC().Format(stamp),
+			Action:        c.Action,
+			ResourceType:  c.ResourceType,
+			BaseRate:      c.BaseRate,
+			EffectiveFrom: c.EffectiveFrom.UTC().Format(stamp),
+			ChangedBy:     c.ChangedBy,
+			Reason:        c.Reason,
+		}
+		if c.EffectiveUntil != nil {
+			row.EffectiveUntil = c.EffectiveUntil.UTC().Format(stamp)
+		}
+		data.History = append(data.History, row)
+	}
+	g.renderAdmin(w, "gov_pricing.html", data)
+}
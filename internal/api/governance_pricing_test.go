@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/notify"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// fakePricing is an in-memory pricingRepo: no DB.
+type fakePricing struct {
+	rates     []store.PricingRate
+	history   []store.PricingChange
+	impact    store.PricingImpact
+	scheduled []store.NewPricingRate
+	retired   []string
+	err       error
+}
+
+func (f *fakePricing) ListPricing(_ context.Context) ([]store.PricingRate, error) {
+	return f.rates, nil
+}
+
+func (f *fakePricing) PricingHistory(_ context.Context, _ int) ([]store.PricingChange, error) {
+	return f.history, nil
+}
+
+func (f *fakePricing) PreviewPricing(_ context.Context, resourceType string, rate float64) (store.PricingImpact, error) {
+	impact := f.impact
+	impact.ResourceType, impact.ProposedRate = resourceType, rate
+	return impact, f.err
+}
+
+func (f *fakePricing) SchedulePricing(_ context.Context, r store.NewPricingRate) (store.PricingRate, error) {
+	if f.err != nil {
+		return store.PricingRate{}, f.err
+	}
+	f.scheduled = append(f.scheduled, r)
+	return store.PricingRate{ID: "rate-new", ResourceType: r.ResourceType, BaseRate: r.BaseRate, EffectiveFrom: r.EffectiveFrom}, nil
+}
+
+func (f *fakePricing) RetirePricing(_ context.Context, id string, at time.Time, changedBy, _ string) (store.PricingRate, error) {
+	if f.err != nil {
+		return store.PricingRate{}, f.err
+	}
+	f.retired = append(f.retired, id+" by "+changedBy)
+	return store.PricingRate{ID: id, EffectiveFrom: at.Add(-time.Hour), EffectiveUntil: &at}, nil
+}
+
+func newPricingGovServer(t *testing.T, repo pricingRepo) http.Handler {
+	t.Helper()
+	g := newTestGovServer(t, &fakeGovRepo{}, notify.NewLogNotifier())
+	if err := g.ConfigureConsole(sampleGovRead(), "../../web/templates"); err != nil {
+		t.Fatalf("ConfigureConsole: %v", err)
+	}
+	if repo != nil {
+		g.ConfigurePricing(repo)
+	}
+	return govMux(g)
+}
+
+func TestAdminPricing_RendersRatesAndHistory(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(0, 6, 0)
+	h := newPricingGovServer(t, &fakePricing{
+		rates: []store.PricingRate{
+			{ID: "rate-old", ResourceType: "cpu_core_hr", BaseRate: 0.025, ContributorShare: 0.65, EffectiveFrom: from, EffectiveUntil: &until},
+			{ID: "rate-cur", ResourceType: "cpu_core_hr", BaseRate: 0.031, ContributorShare: 0.65, EffectiveFrom: until},
+		},
+		history: []store.PricingChange{{
+			Action: "schedule", ResourceType: "cpu_core_hr", BaseRate: 0.031,
+			EffectiveFrom: until, ChangedBy: "alice", Reason: "hardware costs", CreatedAt: from,
+		}},
+	})
+
+	rec := getGov(h, "/admin/pricing")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"$0.025000", "$0.031000", "retired", "active", "alice", "hardware costs", `data-id="rate-cur"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+	if !strings.Contains(body, "not billed") {
+		t.Error("a resource type without rates should say it is not billed")
+	}
+}
+
+func TestAdminPricing_Unconfigured500(t *testing.T) {
+	h := newPricingGovServer(t, nil)
+	if rec := getGov(h, "/admin/pricing"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("GET status = %d, want 500", rec.Code)
+	}
+	if rec := postGov(h, "/admin/pricing", `{}`); rec.Code != http.StatusInternalServerError {
+		t.Errorf("POST status = %d, want 500", rec.Code)
+	}
+}
+
+func TestPreviewPricing(t *testing.T) {
+	h := newPricingGovServer(t, &fakePricing{impact: store.PricingImpact{
+		CurrentRate: 0.025, Jobs: 3, CurrentCents: 120, ProposedCents: 150,
+	}})
+	rec := postGov(h, "/admin/pricing/preview", `{"resource_type":"cpu_core_hr","base_rate":0.03}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	var got pricingImpactResponse
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Jobs != 3 || got.DeltaCents != 30 || got.ProposedRate != 0.03 {
+		t.Errorf("impact = %+v, want 3 jobs, +30 cents at 0.03", got)
+	}
+}
+
+func TestSchedulePricing(t *testing.T) {
+	repo := &fakePricing{}
+	h := newPricingGovServer(t, repo)
+
+	rec := postGov(h, "/admin/pricing",
+		`{"resource_type":"ram_gb_hr","base_rate":0.004,"effective_from":"2030-01-01T00:00:00Z","changed_by":" alice ","reason":"q1"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201; body=%s", rec.Code, rec.Body.String())
+	}
+	if len(repo.scheduled) != 1 {
+		t.Fatalf("scheduled %d rates, want 1", len(repo.scheduled))
+	}
+	s := repo.scheduled[0]
+	if s.ChangedBy != "alice" || s.BaseRate != 0.004 || !s.EffectiveFrom.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("scheduled %+v", s)
+	}
+
+	for _, body := range []string{
+		`{"resource_type":"ram_gb_hr","base_rate":0.004}`,
+		`{"resource_type":"ram_gb_hr","base_rate":-1,"changed_by":"alice"}`,
+		`{"resource_type":"ram_gb_hr","base_rate":0.004,"contributor_share":1.5,"changed_by":"alice"}`,
+		`{"resource_type":"ram_gb_hr","base_rate":0.004,"effective_from":"tomorrow","changed_by":"alice"}`,
+	} {
+		if rec := postGov(h, "/admin/pricing", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+func TestSchedulePricing_Conflicts(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{store.ErrPricingOverlap, http.StatusConflict},
+		{store.ErrPricingRetroactive, http.StatusConflict},
+		{store.ErrUnknownResourceType, http.StatusBadRequest},
+	} {
+		h := newPricingGovServer(t, &fakePricing{err: tc.err})
+		rec := postGov(h, "/admin/pricing", `{"resource_type":"cpu_core_hr","base_rate":0.03,"changed_by":"alice"}`)
+		if rec.Code != tc.code {
+			t.Errorf("%v: status = %d, want %d", tc.err, rec.Code, tc.code)
+		}
+	}
+}
+
+func TestRetirePricing(t *testing.T) {
+	repo := &fakePricing{}
+	h := newPricingGovServer(t, repo)
+
+	rec := postGov(h, "/admin/pricing/rate-cur/retire", `{"changed_by":"bob"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	if len(repo.retired) != 1 || repo.retired[0] != "rate-cur by bob" {
+		t.Errorf("retired = %v", repo.retired)
+	}
+	if rec := postGov(h, "/admin/pricing/rate-cur/retire", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("without changed_by: status = %d, want 400", rec.Code)
+	}
+
+	h = newPricingGovServer(t, &fakePricing{err: store.ErrPricingNotFound})
+	if rec := postGov(h, "/admin/pricing/nope/retire", `{"changed_by":"bob"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown rate: status = %d, want 404", rec.Code)
+	}
+}
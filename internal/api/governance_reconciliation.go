@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// This file adds the LOCAL-ONLY :8090 STRIPE RECONCILIATION report (GET
+// /admin/reconciliation). The portal process holds the Stripe key and runs the
+// reconciler (store.RunReconciler), which compares Stripe's payment intents,
+// transfers and payouts with our job, metering and payout-batch rows and stores
+// each run's discrepancies. This page only reads the latest stored run, the
+// escrow holds the settler gave up on and the cancellation refunds still
+// owed: the governance process never holds the Stripe key and never calls
+// Stripe.
+
+// reconciliationReadModel is the read surface the report consumes. An interface
+// so a test GovernanceServer can use a fake; nil renders a 500, like an
+// unconfigured sounding dashboard.
+type reconciliationReadModel interface {
+	LatestReconciliation(ctx context.Context) (store.ReconciliationReport, error)
+	LastStripeEvent(ctx context.Context) (time.Time, bool, error)
+	FailedEscrowSettlements(ctx context.Context) ([]store.FailedEscrowSettlement, error)
+	OwedCancelRefunds(ctx context.Context) ([]store.OwedRefund, error)
+}
+
+// ReconciliationReader is the reconciliation read model over the coordinator
+// DB. Construct with NewReconciliationReader.
+type ReconciliationReader struct {
+	db *store.DB
+}
+
+// compile-time assertion that *ReconciliationReader satisfies reconciliationReadModel.
+var _ reconciliationReadModel = (*ReconciliationReader)(nil)
+
+// NewReconciliationReader returns a ReconciliationReader over db.
+func NewReconciliationReader(db *store.DB) *ReconciliationReader {
+	return &ReconciliationReader{db: db}
+}
+
+// LatestReconciliation returns the most recent reconciliation run.
+func (r *ReconciliationReader) LatestReconciliation(ctx context.Context) (store.ReconciliationReport, error) {
+	return store.LatestReconciliation(ctx, r.db)
+}
+
+// LastStripeEvent returns when the last Stripe webhook was received.
+func (r *ReconciliationReader) LastStripeEvent(ctx context.Context) (time.Time, bool, error) {
+	return store.LastStripeEvent(ctx, r.db)
+}
+
+// FailedEscrowSettlements returns the escrow holds the settler gave up on.
+func (r *ReconciliationReader) FailedEscrowSettlements(ctx context.Context) ([]store.FailedEscrowSettlement, error) {
+	return store.FailedEscrowSettlements(ctx, r.db)
+}
+
+// OwedCancelRefunds returns the cancellation refunds still owed.
+func (r *ReconciliationReader) OwedCancelRefunds(ctx context.Context) ([]store.OwedRefund, error) {
+	return store.OwedCancelRefunds(ctx, r.db)
+}
+
+// ConfigureReconciliation attaches the reconciliation read model so GET
+// /admin/reconciliation renders the latest run. Separate from the constructor
+// like ConfigureSounding; without it the route renders a 500.
+func (g *GovernanceServer) ConfigureReconciliation(reader reconciliationReadModel) {
+	g.reconciliation = reader
+}
+
+type reconciliationPageData struct {
+	HasRun         bool
+	StartedAt      string
+	Duration       string
+	ObjectsChecked int
+	Discrepancies  []store.Discrepancy
+	LastEvent      string // "" when no webhook has been received
+	FailedEscrows  []failedEscrowRow
+	OwedRefunds    []owedRefundRow
+}
+
+// failedEscrowRow is one escrow hold the settler gave up on, formatted.
+type failedEscrowRow struct {
+	JobID           string
+	PaymentIntentID string
+	Held            string
+	Metered         string
+	Attempts        int
+	LastError       string
+	FailedAt        string
+}
+
+// owedRefundRow is one cancellation refund still owed, formatted.
+type owedRefundRow struct {
+	JobID           string
+	PaymentIntentID string
+	Refund          string
+	Attempts        int
+	GaveUp          bool // the cancel refunder stopped retrying
+	LastError       string
+	CancelledAt     string
+}
+
+// handleAdminReconciliationPage renders the latest reconciliation run's
+// discrepancy report, when the last Stripe webhook arrived — a stalled
+// webhook endpoint is the usual cause of payout and dispute discrepancies —
+// the escrow holds that could not be settled, and the cancellation refunds
+// still owed. Pure read.
+func (g *GovernanceServer) handleAdminReconciliationPage(w http.ResponseWriter, r *http.Request) {
+	if g.reconciliation == nil {
+		http.Error(w, "reconciliation read model unavailable", http.StatusInternalServerError)
+		return
+	}
+	ctx := r.Context()
+
+	var data reconciliationPageData
+	report, err := g.reconciliation.LatestReconciliation(ctx)
+	switch {
+	case errors.Is(err, store.ErrNoReconciliation):
+	case err != nil:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	default:
+		data.HasRun = true
+		data.StartedAt = report.StartedAt.UTC().Format("2006-01-02 15:04 UTC")
+		data.Duration = report.FinishedAt.Sub(report.StartedAt).Round(time.Second).String()
+		data.ObjectsChecked = report.ObjectsChecked
+		data.Discrepancies = report.Discrepancies
+	}
+
+	last, ok, err := g.reconciliation.LastStripeEvent(ctx)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if ok {
+		data.LastEvent = last.UTC().Format("2006-01-02 15:04 UTC")
+	}
+
+	failed, err := g.reconciliation.FailedEscrowSettlements(ctx)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	for _, f := range failed {
+		data.FailedEscrows = append(data.FailedEscrows, failedEscrowRow{
+			JobID:           f.JobID,
+			PaymentIntentID: f.PaymentIntentID,
+			Held:            fmt.Sprintf("$%.2f", float64(f.AmountCents)/100),
+			Metered:         fmt.Sprintf("$%.2f", float64(f.MeteredCents)/100),
+			Attempts:        f.Attempts,
+			LastError:       f.LastError,
+			FailedAt:        f.FailedAt.UTC().Format("2006-01-02 15:04 UTC"),
+		})
+	}
+
+	owed, err := g.reconciliation.OwedCancelRefunds(ctx)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	for _, o := range owed {
+		data.OwedRefunds = append(data.OwedRefunds, owedRefundRow{
+			JobID:           o.JobID,
+			PaymentIntentID: o.PaymentIntentID,
+			Refund:          fmt.Sprintf("$%.2f", float64(o.RefundCents)/100),
+			Attempts:        o.Attempts,
+			GaveUp:          o.GaveUp(),
+			LastError:       o.LastError,
+			CancelledAt:     o.CancelledAt.UTC().Format("2006-01-02 15:04 UTC"),
+		})
+	}
+
+	g.renderAdmin(w, "gov_reconciliation.html", data)
+}
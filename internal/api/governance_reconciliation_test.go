@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/notify"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// fakeReconciliation is an in-memory reconciliationReadModel: no DB.
+type fakeReconciliation struct {
+	report    store.ReconciliationReport
+	reportErr error
+	lastEvent time.Time
+	failed    []store.FailedEscrowSettlement
+	owed      []store.OwedRefund
+}
+
+func (f *fakeReconciliation) LatestReconciliation(_ context.Context) (store.ReconciliationReport, error) {
+	return f.report, f.reportErr
+}
+
+func (f *fakeReconciliation) LastStripeEvent(_ context.Context) (time.Time, bool, error) {
+	return f.lastEvent, !f.lastEvent.IsZero(), nil
+}
+
+func (f *fakeReconciliation) FailedEscrowSettlements(_ context.Context) ([]store.FailedEscrowSettlement, error) {
+	return f.failed, nil
+}
+
+func (f *fakeReconciliation) OwedCancelRefunds(_ context.Context) ([]store.OwedRefund, error) {
+	return f.owed, nil
+}
+
+func newReconciliationGovServer(t *testing.T, read reconciliationReadModel) http.Handler {
+	t.Helper()
+	g := newTestGovServer(t, &fakeGovRepo{}, notify.NewLogNotifier())
+	if err := g.ConfigureConsole(sampleGovRead(), "../../web/templates"); err != nil {
+		t.Fatalf("ConfigureConsole: %v", err)
+	}
+	if read != nil {
+		g.ConfigureReconciliation(read)
+	}
+	return govMux(g)
+}
+
+func TestAdminReconciliation_RendersDiscrepancies(t *testing.T) {
+	started := time.Date(2026, 10, 1, 6, 0, 0, 0, time.UTC)
+	h := newReconciliationGovServer(t, &fakeReconciliation{
+		report: store.ReconciliationReport{
+			StartedAt:      started,
+			FinishedAt:     started.Add(42 * time.Second),
+			ObjectsChecked: 17,
+			Discrepancies: []store.Discrepancy{{
+				ObjectType: "payment_intent", ObjectID: "pi_123", Ref: "job-1",
+				Check: "refunded", Ours: "$0.00", Provider: "$4.00",
+			}},
+		},
+		lastEvent: started.Add(-time.Hour),
+	})
+
+	rec := getGov(h, "/admin/reconciliation")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"pi_123", "job-1", "refunded", "$4.00", "2026-10-01 06:00 UTC", "2026-10-01 05:00 UTC"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+}
+
+func TestAdminReconciliation_RendersFailedEscrows(t *testing.T) {
+	h := newReconciliationGovServer(t, &fakeReconciliation{
+		reportErr: store.ErrNoReconciliation,
+		failed: []store.FailedEscrowSettlement{{
+			JobID: "job-7", PaymentIntentID: "pi_777", AmountCents: 5000, MeteredCents: 1234,
+			Attempts: 5, LastError: "authorization expired",
+			FailedAt: time.Date(2026, 10, 2, 9, 30, 0, 0, time.UTC),
+		}},
+	})
+
+	rec := getGov(h, "/admin/reconciliation")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"job-7", "pi_777", "$50.00", "$12.34", "authorization expired", "2026-10-02 09:30 UTC"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+}
+
+func TestAdminReconciliation_RendersOwedRefunds(t *testing.T) {
+	h := newReconciliationGovServer(t, &fakeReconciliation{
+		reportErr: store.ErrNoReconciliation,
+		owed: []store.OwedRefund{{
+			JobID: "job-9", PaymentIntentID: "pi_999", RefundCents: 725,
+			Attempts: 5, LastError: "charge disputed",
+			CancelledAt: time.Date(2026, 10, 3, 14, 5, 0, 0, time.UTC),
+		}},
+	})
+
+	rec := getGov(h, "/admin/reconciliation")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body=%s", rec.Code, rec.Body.String())
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"job-9", "pi_999", "$7.25", "given up", "charge disputed", "2026-10-03 14:05 UTC"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q", want)
+		}
+	}
+}
+
+func TestAdminReconciliation_EmptyState(t *testing.T) {
+	h := newReconciliationGovServer(t, &fakeReconciliation{reportErr: store.ErrNoReconciliation})
+
+	rec := getGov(h, "/admin/reconciliation")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "No reconciliation has run yet.") || !strings.Contains(body, "none received") {
+		t.Errorf("empty state not rendered: %s", body)
+	}
+}
+
+func TestAdminReconciliation_Unconfigured500(t *testing.T) {
+	h := newReconciliationGovServer(t, nil)
+
+	if rec := getGov(h, "/admin/reconciliation"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500 without a read model", rec.Code)
+	}
+}
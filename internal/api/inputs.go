@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// inputDownloadTimeout replaces the server's 15s write deadline for the
+// duration of an input download.
+const inputDownloadTimeout = 30 * time.Minute
+
+// handleGetJobInput streams one of a job's uploaded inputs (migration 034) to
+// the node the job is placed on, which stages it before starting the
+// container. The node verifies the bytes against the digest it received with
+// the assignment; X-Input-SHA256 repeats it. Inputs with a source URL are
+// fetched by the node directly and are 404 here. Only a dispatched or running
+// job's inputs are served (409 otherwise).
+func handleGetJobInput(db *store.DB, artifacts artifact.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if artifacts == nil {
+			writeError(w, http.StatusServiceUnavailable, "artifact store not configured")
+			return
+		}
+		jobID, name := r.PathValue("id"), r.PathValue("name")
+		if jobID == "" || name == "" {
+			writeError(w, http.StatusBadRequest, "job ID and input name required")
+			return
+		}
+
+		var nodeID, status string
+		err := db.Pool.QueryRow(r.Context(),
+			`SELECT COALESCE(node_id::text, ''), status::text FROM jobs WHERE id = $1`,
+			jobID,
+		).Scan(&nodeID, &status)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		// Same SPIFFE binding as /jobs/{id}/complete.
+		spiffeID, ok := identity.SPIFFEIDFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "no SPIFFE identity in context")
+			return
+		}
+		if spiffeID.Path() != "/node/"+nodeID {
+			writeError(w, http.StatusForbidden, "SPIFFE identity does not match job owner")
+			return
+		}
+		if status != "dispatched" && status != "running" {
+			writeError(w, http.StatusConflict, "job is not dispatched or running")
+			return
+		}
+
+		u, err := store.JobInputUpload(r.Context(), db, jobID, name)
+		if err != nil {
+			if errors.Is(err, store.ErrInputNotFound) {
+				writeError(w, http.StatusNotFound, "input not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		rc, err := artifacts.Open(r.Context(), u.ObjectKey)
+		if err != nil {
+			if errors.Is(err, artifact.ErrNotFound) {
+				writeError(w, http.StatusNotFound, "input not found")
+				return
+			}
+			slog.Error("open job input failed", "job_id", jobID, "input", name, "error", err)
+			writeError(w, http.StatusBadGateway, "artifact store read failed")
+			return
+		}
+		defer rc.Close()
+
+		rctl := http.NewResponseController(w)
+		_ = rctl.SetWriteDeadline(time.Now().Add(inputDownloadTimeout))
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.FormatInt(u.SizeBytes, 10))
+		w.Header().Set("X-Input-SHA256", u.SHA256)
+		if _, err := io.Copy(w, rc); err != nil {
+			slog.Warn("job input download interrupted", "job_id", jobID, "input", name, "error", err)
+		}
+	}
+}
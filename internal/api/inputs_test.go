@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// seedInputJob stores body as the consumer's upload and attaches it to the
+// seeded job as input name, leaving the job in status.
+func seedInputJob(t *testing.T, db *store.DB, st artifact.Store, email, name, status string, body []byte) (nodeID, jobID string) {
+	t.Helper()
+	ctx := context.Background()
+	consumerID, nodeID, jobID := seedOutputJob(t, db, email)
+	sum := sha256Hex(body)
+	key := artifact.InputKey(consumerID, sum)
+	if err := st.Put(ctx, key, bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Fatalf("put input: %v", err)
+	}
+	if err := store.RecordInputUpload(ctx, db, store.InputUpload{
+		ParticipantID: consumerID, SHA256: sum, ObjectKey: key,
+		SizeBytes: int64(len(body)), ExpiresAt: time.Now().Add(time.Hour),
+	}, artifact.DefaultLimits.QuotaBytes); err != nil {
+		t.Fatalf("RecordInputUpload: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`INSERT INTO job_inputs (job_id, name, sha256, size_bytes) VALUES ($1, $2, $3, $4)`,
+		jobID, name, sum, len(body),
+	); err != nil {
+		t.Fatalf("seed job input: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE jobs SET status = $2::job_status WHERE id = $1`, jobID, status,
+	); err != nil {
+		t.Fatalf("set job status: %v", err)
+	}
+	return nodeID, jobID
+}
+
+func inputRequest(jobID, nodeID, name string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/jobs/"+jobID+"/inputs/"+name, nil)
+	r.SetPathValue("id", jobID)
+	r.SetPathValue("name", name)
+	return withNodeSPIFFE(r, nodeID)
+}
+
+// ── handleGetJobInput ────────────────────────────────────────────────────────
+
+func TestHandleGetJobInput_Serves(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	ps.artifacts, _ = artifact.NewFSStore(t.TempDir())
+	body := []byte("G28\nG1 X10 Y10\n")
+	nodeID, jobID := seedInputJob(t, db, ps.artifacts, "input_ok@test.com", "part.gcode", "dispatched", body)
+
+	w := httptest.NewRecorder()
+	ps.handleGetJobInput(w, inputRequest(jobID, nodeID, "part.gcode"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if !bytes.Equal(w.Body.Bytes(), body) {
+		t.Errorf("body = %q, want %q", w.Body.Bytes(), body)
+	}
+	if got := w.Header().Get("X-Input-SHA256"); got != sha256Hex(body) {
+		t.Errorf("X-Input-SHA256 = %s", got)
+	}
+
+	w = httptest.NewRecorder()
+	ps.handleGetJobInput(w, inputRequest(jobID, nodeID, "other.gcode"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown input: expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleGetJobInput_WrongNode(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	ps.artifacts, _ = artifact.NewFSStore(t.TempDir())
+	_, jobID := seedInputJob(t, db, ps.artifacts, "input_node@test.com", "data.csv", "dispatched", []byte("a,b\n"))
+
+	w := httptest.NewRecorder()
+	ps.handleGetJobInput(w, inputRequest(jobID, "00000000-0000-0000-0000-000000000000", "data.csv"))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", w.Code)
+	}
+}
+
+func TestHandleGetJobInput_NotYetDispatched(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	ps.artifacts, _ = artifact.NewFSStore(t.TempDir())
+	nodeID, jobID := seedInputJob(t, db, ps.artifacts, "input_done@test.com", "data.csv", "scheduled", []byte("a,b\n"))
+
+	w := httptest.NewRecorder()
+	ps.handleGetJobInput(w, inputRequest(jobID, nodeID, "data.csv"))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+}
@@ -9,9 +9,10 @@ import (
 // InternalAPIServer is the SoHoLINK orchestrator's internal HTTP server.
 // Unlike APIServer, this listener is bound to a Docker-network-only address
 // and serves plain HTTP. It is not exposed via the Cloudflare tunnel.
-// The single endpoint POST /internal/jobs/submit is called by the portal
-// process to submit consumer jobs to the orchestrator's NodeRegistry —
-// the one that actually receives agent heartbeats.
+// Its endpoints — POST /internal/jobs/submit, POST /internal/jobs/estimate and
+// POST /internal/jobs/{id}/cancel — are called by the portal process to
+// submit, price and cancel consumer jobs against the orchestrator's
+// NodeRegistry — the one that actually receives agent heartbeats.
 //
 // Trust model: the listener address binding is the security boundary. The
 // /internal/ path prefix is documentation, not a control.
@@ -19,12 +20,15 @@ type InternalAPIServer struct {
 	srv *http.Server
 }
 
-// NewInternal constructs an InternalAPIServer that wraps orch.SubmitJob.
+// NewInternal constructs an InternalAPIServer that wraps orch.SubmitJob,
+// orch.EstimateJob and orch.CancelJob.
 // addr is the internal-only listen address (e.g. ":8083"); network isolation
 // is enforced by Docker — port 8083 is not published externally.
 func NewInternal(orch jobSubmitter, addr string) *InternalAPIServer {
 	mux := http.NewServeMux()
 	mux.HandleFunc("POST /internal/jobs/submit", handleInternalSubmitJob(orch))
+	mux.HandleFunc("POST /internal/jobs/estimate", handleInternalEstimateJob(orch))
+	mux.HandleFunc("POST /internal/jobs/{id}/cancel", handleInternalCancelJob(orch))
 
 	return &InternalAPIServer{
 		srv: &http.Server{
@@ -3,9 +3,12 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
 )
 
 // jobSubmitter is the subset of *orchestrator.Orchestrator the internal API
@@ -14,13 +17,43 @@ import (
 // both packages need it independently; see Chat audit note.
 type jobSubmitter interface {
 	SubmitJob(ctx context.Context, req orchestrator.SubmitJobRequest) (orchestrator.SubmitJobResponse, error)
+	CancelJob(ctx context.Context, jobID, consumerID string) (orchestrator.CancelJobResponse, error)
+	EstimateJob(ctx context.Context, req orchestrator.EstimateJobRequest) (orchestrator.EstimateJobResponse, error)
+}
+
+// internalSubmitErrorBody is the JSON error body of POST /internal/jobs/submit:
+// an *orchestrator.SubmitError, which orchclient reconstructs. Error is the
+// full error, for logs; Message is the consumer-facing advice.
+type internalSubmitErrorBody struct {
+	Error      string                        `json:"error"`
+	Class      orchestrator.SubmitErrorClass `json:"class"`
+	Reason     string                        `json:"reason,omitempty"`
+	WantedRung string                        `json:"wanted_rung,omitempty"`
+	Message    string                        `json:"message"`
+}
+
+// submitErrorStatus is the HTTP status for a SubmitJob rejection of class c.
+func submitErrorStatus(c orchestrator.SubmitErrorClass) int {
+	switch c {
+	case orchestrator.SubmitErrValidation:
+		return http.StatusBadRequest
+	case orchestrator.SubmitErrImageNotAllowlisted, orchestrator.SubmitErrTypeMismatch:
+		return http.StatusUnprocessableEntity
+	case orchestrator.SubmitErrNoCapacity:
+		return http.StatusServiceUnavailable
+	case orchestrator.SubmitErrPaymentRequired:
+		return http.StatusPaymentRequired
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 // handleInternalSubmitJob decodes a SubmitJobRequest from the request body,
 // invokes orch.SubmitJob, and returns the SubmitJobResponse as JSON.
 // Relies on writeError from internal/api/server.go (same package, no import).
-// Decode failures return 400; SubmitJob errors return 500. Error-class
-// discrimination (validation vs no-nodes vs internal) is a future refinement.
+// Decode failures return 400. SubmitJob errors return an
+// internalSubmitErrorBody with the status for their class (see
+// submitErrorStatus); unclassified errors are class internal, status 500.
 func handleInternalSubmitJob(orch jobSubmitter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req orchestrator.SubmitJobRequest
@@ -31,6 +64,81 @@ func handleInternalSubmitJob(orch jobSubmitter) http.HandlerFunc {
 
 		resp, err := orch.SubmitJob(r.Context(), req)
 		if err != nil {
+			se := orchestrator.SubmitErrorOf(err)
+			if se.Class == orchestrator.SubmitErrInternal {
+				slog.Error("internal submit job failed", "consumer_id", req.ConsumerID, "error", err)
+			}
+			writeJSON(w, submitErrorStatus(se.Class), internalSubmitErrorBody{
+				Error:      err.Error(),
+				Class:      se.Class,
+				Reason:     se.Reason,
+				WantedRung: se.WantedRung,
+				Message:    se.Message,
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// handleInternalEstimateJob decodes an EstimateJobRequest, invokes
+// orch.EstimateJob, and returns the EstimateJobResponse — candidate prices and
+// a signed quote token — as JSON. Decode failures return 400; EstimateJob
+// errors return 500, as for submit.
+func handleInternalEstimateJob(orch jobSubmitter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req orchestrator.EstimateJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "decode estimate job request: "+err.Error())
+			return
+		}
+
+		resp, err := orch.EstimateJob(r.Context(), req)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// internalCancelJobRequest is the JSON body of POST /internal/jobs/{id}/cancel.
+// The portal has already authenticated the consumer; the orchestrator only
+// checks that the job is theirs.
+type internalCancelJobRequest struct {
+	ConsumerID string `json:"consumer_id"`
+}
+
+// handleInternalCancelJob cancels a consumer's job via orch.CancelJob and
+// returns the CancelJobResponse as JSON. 404 when the consumer has no such
+// job; 409 with the job's current_status when it can no longer be cancelled.
+func handleInternalCancelJob(orch jobSubmitter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req internalCancelJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ConsumerID == "" {
+			writeError(w, http.StatusBadRequest, "consumer_id is required")
+			return
+		}
+
+		resp, err := orch.CancelJob(r.Context(), r.PathValue("id"), req.ConsumerID)
+		switch {
+		case errors.Is(err, store.ErrJobNotFound):
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		case errors.Is(err, store.ErrJobNotCancellable):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"error":          "job is not cancellable",
+				"current_status": resp.PriorStatus,
+			})
+			return
+		case err != nil:
+			slog.Error("internal cancel job failed", "job_id", r.PathValue("id"), "error", err)
 			writeError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
 )
 
@@ -19,6 +20,17 @@ type stubSubmitter struct {
 	resp   orchestrator.SubmitJobResponse
 	err    error
 	gotReq orchestrator.SubmitJobRequest
+
+	gotCancel  [2]string // job ID, consumer ID
+	cancelResp orchestrator.CancelJobResponse
+
+	gotEstimate  orchestrator.EstimateJobRequest
+	estimateResp orchestrator.EstimateJobResponse
+}
+
+func (s *stubSubmitter) CancelJob(_ context.Context, jobID, consumerID string) (orchestrator.CancelJobResponse, error) {
+	s.gotCancel = [2]string{jobID, consumerID}
+	return s.cancelResp, s.err
 }
 
 func (s *stubSubmitter) SubmitJob(_ context.Context, req orchestrator.SubmitJobRequest) (orchestrator.SubmitJobResponse, error) {
@@ -26,6 +38,11 @@ func (s *stubSubmitter) SubmitJob(_ context.Context, req orchestrator.SubmitJobR
 	return s.resp, s.err
 }
 
+func (s *stubSubmitter) EstimateJob(_ context.Context, req orchestrator.EstimateJobRequest) (orchestrator.EstimateJobResponse, error) {
+	s.gotEstimate = req
+	return s.estimateResp, s.err
+}
+
 func TestHandleInternalSubmitJob_HappyPath(t *testing.T) {
 	stub := &stubSubmitter{
 		resp: orchestrator.SubmitJobResponse{JobID: "job-abc", NodeID: "node-1"},
@@ -94,3 +111,127 @@ func TestHandleInternalSubmitJob_SubmitJobErrorReturns500(t *testing.T) {
 		t.Errorf("expected error string in body, got: %s", w.Body.String())
 	}
 }
+
+func TestHandleInternalSubmitJob_ClassifiedErrors(t *testing.T) {
+	tests := []struct {
+		err    *orchestrator.SubmitError
+		status int
+	}{
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrValidation, Message: "ConsumerID is required"}, http.StatusBadRequest},
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrImageNotAllowlisted, Message: "choose an allowlisted image"}, http.StatusUnprocessableEntity},
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrTypeMismatch, Message: "choose a matching workload type"}, http.StatusUnprocessableEntity},
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrPaymentRequired, Reason: orchestrator.ReasonInvalidQuote, Message: "request a new estimate"}, http.StatusPaymentRequired},
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrNoCapacity, Reason: "too_big", WantedRung: "storm",
+			Message: "request fewer resources", Err: errors.New("find nodes: no available nodes match request")}, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.err.Class), func(t *testing.T) {
+			handler := handleInternalSubmitJob(&stubSubmitter{err: tt.err})
+			w := postJSON(t, handler, "/internal/jobs/submit", orchestrator.SubmitJobRequest{ConsumerID: "participant-1"})
+			if w.Code != tt.status {
+				t.Fatalf("expected %d, got %d; body: %s", tt.status, w.Code, w.Body.String())
+			}
+			var body internalSubmitErrorBody
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("decode error body: %v", err)
+			}
+			if body.Class != tt.err.Class || body.Reason != tt.err.Reason ||
+				body.WantedRung != tt.err.WantedRung || body.Message != tt.err.Message {
+				t.Errorf("body = %+v, want the fields of %+v", body, tt.err)
+			}
+			if body.Error != tt.err.Error() {
+				t.Errorf("error = %q, want %q", body.Error, tt.err.Error())
+			}
+		})
+	}
+}
+
+func TestHandleInternalEstimateJob(t *testing.T) {
+	stub := &stubSubmitter{estimateResp: orchestrator.EstimateJobResponse{
+		Candidates: []orchestrator.NodeEstimate{{NodeID: "node-1", PriceMultiplier: 1.2, LowCents: 10, HighCents: 40}},
+		Replicas:   1, LowCents: 10, HighCents: 40, QuoteToken: "tok",
+	}}
+	req := orchestrator.EstimateJobRequest{
+		SubmitJobRequest: orchestrator.SubmitJobRequest{
+			ConsumerID: "participant-1", WorkloadType: types.MarketplaceBatchCompute, CPUCores: 2, RAMMB: 2048,
+		},
+		ExpectedRuntimeSeconds: 600,
+	}
+	w := postJSON(t, handleInternalEstimateJob(stub), "/internal/jobs/estimate", req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body: %s", w.Code, w.Body.String())
+	}
+	var got orchestrator.EstimateJobResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.QuoteToken != "tok" || len(got.Candidates) != 1 || got.Candidates[0].HighCents != 40 {
+		t.Errorf("response = %+v", got)
+	}
+	if stub.gotEstimate.ExpectedRuntimeSeconds != 600 || stub.gotEstimate.ConsumerID != "participant-1" {
+		t.Errorf("EstimateJob called with %+v", stub.gotEstimate)
+	}
+
+	stub = &stubSubmitter{err: errors.New("no available nodes match request")}
+	if w := postJSON(t, handleInternalEstimateJob(stub), "/internal/jobs/estimate", req); w.Code != http.StatusInternalServerError {
+		t.Errorf("estimate error: expected 500, got %d", w.Code)
+	}
+}
+
+func cancelRequest(jobID, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/internal/jobs/"+jobID+"/cancel", strings.NewReader(body))
+	r.SetPathValue("id", jobID)
+	return r
+}
+
+func TestHandleInternalCancelJob_HappyPath(t *testing.T) {
+	stub := &stubSubmitter{cancelResp: orchestrator.CancelJobResponse{
+		JobID: "job-abc", PriorStatus: "running", ChargedCents: 40,
+		PaymentIntentID: "pi_123", RefundCents: 60,
+	}}
+	w := httptest.NewRecorder()
+	handleInternalCancelJob(stub)(w, cancelRequest("job-abc", `{"consumer_id":"participant-1"}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body: %s", w.Code, w.Body.String())
+	}
+	if stub.gotCancel != [2]string{"job-abc", "participant-1"} {
+		t.Errorf("CancelJob called with %v", stub.gotCancel)
+	}
+	var got orchestrator.CancelJobResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got != stub.cancelResp {
+		t.Errorf("response = %+v, want %+v", got, stub.cancelResp)
+	}
+}
+
+func TestHandleInternalCancelJob_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		body   string
+		stub   *stubSubmitter
+		status int
+		want   string
+	}{
+		{"missing consumer", `{}`, &stubSubmitter{}, http.StatusBadRequest, "consumer_id is required"},
+		{"not found", `{"consumer_id":"p"}`, &stubSubmitter{err: store.ErrJobNotFound}, http.StatusNotFound, "job not found"},
+		{"not cancellable", `{"consumer_id":"p"}`, &stubSubmitter{
+			err:        store.ErrJobNotCancellable,
+			cancelResp: orchestrator.CancelJobResponse{PriorStatus: "completed"},
+		}, http.StatusConflict, `"current_status":"completed"`},
+		{"internal", `{"consumer_id":"p"}`, &stubSubmitter{err: errors.New("boom")}, http.StatusInternalServerError, "boom"},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handleInternalCancelJob(c.stub)(w, cancelRequest("job-abc", c.body))
+		if w.Code != c.status {
+			t.Errorf("%s: expected %d, got %d; body: %s", c.name, c.status, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), c.want) {
+			t.Errorf("%s: expected %q in body, got: %s", c.name, c.want, w.Body.String())
+		}
+	}
+}
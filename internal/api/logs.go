@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// maxLogBatchBytes bounds a POST /jobs/{id}/logs body: the agent's largest
+// batch (64 chunks of 16 KiB) plus JSON overhead.
+const maxLogBatchBytes = 2 << 20
+
+// logBatchRequest is the JSON body of POST /jobs/{id}/logs.
+type logBatchRequest struct {
+	Chunks []struct {
+		Seq    int64  `json:"seq"`
+		Stream string `json:"stream"`
+		Data   string `json:"data"`
+	} `json:"chunks"`
+}
+
+// handleAppendJobLogs accepts a batch of a running job's container output
+// from the node it runs on. The agent has already redacted it; the handler
+// stores it, up to store.JobLogCapBytes per job, for the consumer's log tail.
+// Only a running job accepts logs (409 otherwise), which also tells the
+// agent to stop shipping.
+func handleAppendJobLogs(db *store.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := r.PathValue("id")
+		if jobID == "" {
+			writeError(w, http.StatusBadRequest, "job ID required")
+			return
+		}
+
+		var nodeID, status string
+		err := db.Pool.QueryRow(r.Context(),
+			`SELECT COALESCE(node_id::text, ''), status::text FROM jobs WHERE id = $1`,
+			jobID,
+		).Scan(&nodeID, &status)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		// See handleCompleteJob for the binding rationale.
+		spiffeID, ok := identity.SPIFFEIDFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "no SPIFFE identity in context")
+			return
+		}
+		if spiffeID.Path() != "/node/"+nodeID {
+			writeError(w, http.StatusForbidden, "SPIFFE identity does not match job owner")
+			return
+		}
+		if status != "running" {
+			writeError(w, http.StatusConflict, "job is not in running state")
+			return
+		}
+
+		var req logBatchRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxLogBatchBytes)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		chunks := make([]store.LogChunk, 0, len(req.Chunks))
+		for _, c := range req.Chunks {
+			if c.Seq < 0 || (c.Stream != "stdout" && c.Stream != "stderr") {
+				writeError(w, http.StatusBadRequest, "chunk seq must be non-negative and stream stdout or stderr")
+				return
+			}
+			chunks = append(chunks, store.LogChunk{Seq: c.Seq, Stream: c.Stream, Data: c.Data})
+		}
+
+		truncated, err := store.AppendJobLogs(r.Context(), db, jobID, chunks)
+		if err != nil {
+			slog.Error("append job logs failed", "job_id", jobID, "error", err)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"truncated": truncated}) //nolint:errcheck
+	}
+}
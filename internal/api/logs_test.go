@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// seedLogJob seeds a job bound to a node and leaves it in status.
+func seedLogJob(t *testing.T, db *store.DB, email, status string) (nodeID, jobID string) {
+	t.Helper()
+	_, nodeID, jobID = seedOutputJob(t, db, email)
+	if _, err := db.Pool.Exec(context.Background(),
+		`UPDATE jobs SET status = $2::job_status WHERE id = $1`, jobID, status,
+	); err != nil {
+		t.Fatalf("set job status: %v", err)
+	}
+	return nodeID, jobID
+}
+
+func logsRequest(jobID, nodeID, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/jobs/"+jobID+"/logs", strings.NewReader(body))
+	r.SetPathValue("id", jobID)
+	return withNodeSPIFFE(r, nodeID)
+}
+
+// ── handleAppendJobLogs ──────────────────────────────────────────────────────
+
+func TestHandleAppendJobLogs_StoresAndDedupes(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	nodeID, jobID := seedLogJob(t, db, "logs_ok@test.com", "running")
+
+	const batch = `{"chunks":[{"seq":0,"stream":"stdout","data":"hello\n"},{"seq":1,"stream":"stderr","data":"warn\n"}]}`
+	for i := 0; i < 2; i++ { // the retry of an acknowledged batch is a no-op
+		w := httptest.NewRecorder()
+		ps.handleAppendJobLogs(w, logsRequest(jobID, nodeID, batch))
+		if w.Code != http.StatusOK {
+			t.Fatalf("attempt %d: expected 200, got %d: %s", i, w.Code, w.Body.String())
+		}
+		var resp struct{ Truncated bool }
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Truncated {
+			t.Fatalf("attempt %d: response %s", i, w.Body.String())
+		}
+	}
+
+	chunks, err := store.JobLogs(context.Background(), db, jobID, -1, 10)
+	if err != nil {
+		t.Fatalf("JobLogs: %v", err)
+	}
+	if len(chunks) != 2 || chunks[0].Data != "hello\n" || chunks[1].Stream != "stderr" {
+		t.Fatalf("stored chunks = %+v", chunks)
+	}
+}
+
+func TestHandleAppendJobLogs_Cap(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	nodeID, jobID := seedLogJob(t, db, "logs_cap@test.com", "running")
+
+	var chunks []store.LogChunk
+	for i := range store.JobLogCapBytes/(16<<10) + 1 {
+		chunks = append(chunks, store.LogChunk{Seq: int64(i), Stream: "stdout", Data: strings.Repeat("x", 16<<10)})
+	}
+	truncated, err := store.AppendJobLogs(context.Background(), db, jobID, chunks)
+	if err != nil {
+		t.Fatalf("AppendJobLogs: %v", err)
+	}
+	if !truncated {
+		t.Fatal("expected the log to be truncated past the cap")
+	}
+
+	w := httptest.NewRecorder()
+	ps.handleAppendJobLogs(w, logsRequest(jobID, nodeID, `{"chunks":[{"seq":999,"stream":"stdout","data":"more\n"}]}`))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"truncated":true`) {
+		t.Fatalf("expected 200 truncated, got %d: %s", w.Code, w.Body.String())
+	}
+	var stored int64
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT COALESCE(SUM(octet_length(data)), 0) FROM job_log_chunks WHERE job_id = $1`, jobID,
+	).Scan(&stored); err != nil {
+		t.Fatalf("sum log bytes: %v", err)
+	}
+	if stored != store.JobLogCapBytes {
+		t.Errorf("stored %d bytes, want the %d cap", stored, store.JobLogCapBytes)
+	}
+}
+
+func TestHandleAppendJobLogs_Rejects(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	nodeID, jobID := seedLogJob(t, db, "logs_reject@test.com", "running")
+	idleNodeID, idleJobID := seedLogJob(t, db, "logs_idle@test.com", "scheduled")
+
+	cases := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"wrong node", logsRequest(jobID, "00000000-0000-0000-0000-000000000000", `{"chunks":[]}`), http.StatusForbidden},
+		{"bad stream", logsRequest(jobID, nodeID, `{"chunks":[{"seq":0,"stream":"stdin","data":"x"}]}`), http.StatusBadRequest},
+		{"negative seq", logsRequest(jobID, nodeID, `{"chunks":[{"seq":-1,"stream":"stdout","data":"x"}]}`), http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		ps.handleAppendJobLogs(w, c.req)
+		if w.Code != c.status {
+			t.Errorf("%s: expected %d, got %d", c.name, c.status, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	ps.handleAppendJobLogs(w, logsRequest(idleJobID, idleNodeID, `{"chunks":[]}`))
+	if w.Code != http.StatusConflict {
+		t.Errorf("not running: expected 409, got %d", w.Code)
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"log/slog"
 	"net/http"
 	"os"
@@ -23,10 +22,12 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/agent"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/metrics"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
 )
 
 type nodePrinterInfo struct {
@@ -75,6 +76,31 @@ type heartbeatRequest struct {
 	// signed protocol Heartbeat carries neither (no float in canon).
 	OwnerActive bool    `json:"owner_active"`
 	CPUPct      float64 `json:"cpu_pct"`
+
+	// FreeCapacity is the agent admission controller's unreserved headroom
+	// (absent from older agents). Unlike the advisory fields above it gates
+	// FindMatch while fresh; see orchestrator.NodeEntry.FreeCapacity.
+	FreeCapacity *heartbeatFreeCapacity `json:"free_capacity,omitempty"`
+
+	// RunningJobs lists the jobs whose containers the agent is running
+	// (absent from older agents). Any the control plane no longer has
+	// running on this node come back in heartbeatResponse.StopJobs.
+	RunningJobs []string `json:"running_jobs,omitempty"`
+
+	// Labels and Taints are the node's self-described labels and taints
+	// (AGENT_LABELS / AGENT_TAINTS), key to value. Older agents send
+	// neither, leaving the reported set as it was; an empty object clears it.
+	Labels map[string]string `json:"labels,omitempty"`
+	Taints map[string]string `json:"taints,omitempty"`
+}
+
+// heartbeatFreeCapacity mirrors agent.FreeCapacity. -1 marks an uncapped
+// dimension.
+type heartbeatFreeCapacity struct {
+	CPUCores  int `json:"cpu_cores"`
+	RAMMB     int `json:"ram_mb"`
+	StorageGB int `json:"storage_gb"`
+	Slots     int `json:"slots"`
 }
 
 type heartbeatOptOut struct {
@@ -89,6 +115,9 @@ type heartbeatResponse struct {
 	OK                   bool             `json:"ok"`
 	OptOut               *heartbeatOptOut `json:"opt_out,omitempty"`
 	RequestPrinterReport bool             `json:"request_printer_report,omitempty"`
+	// StopJobs are running_jobs the agent must stop: cancelled by the
+	// consumer, failed by a reaper, or re-placed elsewhere.
+	StopJobs []string `json:"stop_jobs,omitempty"`
 }
 
 type telemetryRequest struct {
@@ -97,13 +126,65 @@ type telemetryRequest struct {
 	RAMPct        float64   `json:"ram_pct"`
 	BandwidthMbps int       `json:"bandwidth_mbps"`
 	Timestamp     time.Time `json:"timestamp"`
+	SigVersion    int       `json:"sig_version"`
+	Signature     string    `json:"signature"`
+}
+
+// telemetrySampleDTO is one point of the per-job series returned by
+// GET /jobs/{id}/telemetry.
+type telemetrySampleDTO struct {
+	SampledAt     time.Time `json:"sampled_at"`
+	CPUPct        float64   `json:"cpu_pct"`
+	RAMPct        float64   `json:"ram_pct"`
+	BandwidthMbps int       `json:"bandwidth_mbps"`
 }
 
+// telemetryMaxClockSkew bounds how far ahead of the control plane's clock a
+// signed sample timestamp may be. Without it a node could pre-sign a far-future
+// sample and thereby lock out every later (honest) sample for the job via the
+// strictly-increasing replay guard.
+const telemetryMaxClockSkew = 2 * time.Minute
+
 type jobEntry struct {
-	JobID     string `json:"job_id"`
-	JobToken  string `json:"job_token"`
-	Image     string `json:"container_image"`
-	PrinterID string `json:"printer_id,omitempty"`
+	JobID      string `json:"job_id"`
+	JobToken   string `json:"job_token"`
+	Image      string `json:"container_image"`
+	PrinterID  string `json:"printer_id,omitempty"`
+	CPUCores   int    `json:"cpu_cores,omitempty"`
+	RAMMB      int    `json:"ram_mb,omitempty"`
+	StorageGB  int    `json:"storage_gb,omitempty"`
+	OutputPath string `json:"output_path,omitempty"`
+
+	MaxRuntimeSeconds int `json:"max_runtime_seconds,omitempty"`
+
+	Inputs []jobInputEntry `json:"inputs,omitempty"`
+}
+
+// jobInputEntry is one of a jobEntry's inputs. An upload (no URL) is fetched
+// from GET /jobs/{id}/inputs/{name}; a URL input is fetched from URL.
+type jobInputEntry struct {
+	Name      string `json:"name"`
+	SHA256    string `json:"sha256"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// resourceProfileEntry is one resource_profiles row as served to the agent by
+// GET /nodes/profiles. Times are "HH:MM" and dates "YYYY-MM-DD"; both are
+// interpreted in the node's local time zone by agent.ActiveProfile.
+type resourceProfileEntry struct {
+	Name              string   `json:"name"`
+	IsDefault         bool     `json:"is_default"`
+	CPUEnabled        bool     `json:"cpu_enabled"`
+	GPUPct            int      `json:"gpu_pct"`
+	RAMPct            int      `json:"ram_pct"`
+	StorageGB         int      `json:"storage_gb"`
+	BandwidthMbps     int      `json:"bandwidth_mbps"`
+	ScheduleStart     *string  `json:"schedule_start,omitempty"`
+	ScheduleEnd       *string  `json:"schedule_end,omitempty"`
+	ScheduleDays      []string `json:"schedule_days,omitempty"`
+	OverrideStartDate *string  `json:"override_start_date,omitempty"`
+	OverrideEndDate   *string  `json:"override_end_date,omitempty"`
 }
 
 func registerNodeRoutes(mux *http.ServeMux, db *store.DB, registry *orchestrator.NodeRegistry) {
@@ -112,8 +193,12 @@ func registerNodeRoutes(mux *http.ServeMux, db *store.DB, registry *orchestrator
 	mux.HandleFunc("POST /nodes/printers", handleReportPrinters(db))
 	mux.HandleFunc("POST /nodes/pubkey", handleRegisterNodePubkey(db))
 	mux.HandleFunc("GET /nodes/jobs", handleGetJobs(db))
+	mux.HandleFunc("GET /nodes/profiles", handleGetProfiles(db))
 	mux.HandleFunc("POST /jobs/{id}/started", handleStartedJob(db))
+	mux.HandleFunc("POST /jobs/{id}/decline", handleDeclineJob(db, registry))
 	mux.HandleFunc("POST /jobs/{id}/telemetry", handleTelemetry(db))
+	mux.HandleFunc("GET /jobs/{id}/telemetry", handleGetTelemetry(db))
+	mux.HandleFunc("POST /jobs/{id}/logs", handleAppendJobLogs(db))
 	mux.HandleFunc("POST /jobs/{id}/complete", handleCompleteJob(db, registry))
 }
 
@@ -159,6 +244,14 @@ func handleRegisterNode(db *store.DB, registry *orchestrator.NodeRegistry) http.
 				BandwidthMbps: req.HardwareProfile.BandwidthMbps,
 			},
 		})
+		// A first-time node has no row yet and so no labels; a returning one
+		// must come back with its taints, or FindMatch would place untolerating
+		// jobs on it until its next heartbeat.
+		if err := loadRegistryLabels(r.Context(), db, registry, req.NodeID); err != nil && !errors.Is(err, store.ErrNodeNotFound) {
+			registry.Evict(req.NodeID)
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
 
 		hwJSON, err := json.Marshal(req.HardwareProfile)
 		if err != nil {
@@ -227,6 +320,23 @@ func handleRegisterNode(db *store.DB, registry *orchestrator.NodeRegistry) http.
 	}
 }
 
+// loadRegistryLabels mirrors nodeID's stored labels and taints into its
+// registry entry. Register replaces the entry wholesale, so every call to it
+// is followed by this one; a node must never be matchable without its taints.
+func loadRegistryLabels(ctx context.Context, db *store.DB, registry *orchestrator.NodeRegistry, nodeID string) error {
+	labels, err := store.GetNodeLabels(ctx, db, nodeID)
+	if err != nil {
+		return err
+	}
+	if err := registry.UpdateLabels(nodeID, orchestrator.NodeLabelState{
+		Labels: labels.Labels,
+		Taints: labels.Taints,
+	}); err != nil {
+		slog.Warn("registry update labels failed", "node_id", nodeID, "err", err)
+	}
+	return nil
+}
+
 // handleClaimNode is the installer-facing registration endpoint.
 // The agent presents a single-use token generated by the participant on their
 // dashboard. On success a new node record is created and the agent receives its
@@ -273,13 +383,22 @@ func handleClaimNode(db *store.DB, registry *orchestrator.NodeRegistry) http.Han
 			region = &req.Region
 		}
 
+		// Generate the per-node HMAC secret for telemetry signing. Returned
+		// once as token_secret (the agent persists it to agent.conf) and stored
+		// on the node row so handleTelemetry can verify every sample.
+		secretBytes := make([]byte, 32)
+		if _, err := rand.Read(secretBytes); err != nil {
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+
 		// Create the node record; DB generates the UUID.
 		var nodeID string
 		err = db.Pool.QueryRow(r.Context(), `
-			INSERT INTO nodes (id, participant_id, node_class, hostname, country_code, region, status, hardware_profile)
-			VALUES (gen_random_uuid(), $1, 'C'::node_class, $2, $3, $4, 'online'::node_status, $5)
+			INSERT INTO nodes (id, participant_id, node_class, hostname, country_code, region, status, hardware_profile, telemetry_secret)
+			VALUES (gen_random_uuid(), $1, 'C'::node_class, $2, $3, $4, 'online'::node_status, $5, $6)
 			RETURNING id`,
-			participantID, hostname, req.CountryCode, region, string(hwJSON),
+			participantID, hostname, req.CountryCode, region, string(hwJSON), secretBytes,
 		).Scan(&nodeID)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "database error")
@@ -348,13 +467,9 @@ func handleClaimNode(db *store.DB, registry *orchestrator.NodeRegistry) http.Han
 				BandwidthMbps: req.HardwareProfile.BandwidthMbps,
 			},
 		})
-
-		// Generate a per-node HMAC secret for telemetry signing. Returned once;
-		// the agent persists it to agent.conf. Not stored server-side until the
-		// telemetry verification layer is added.
-		secretBytes := make([]byte, 32)
-		if _, err := rand.Read(secretBytes); err != nil {
-			writeError(w, http.StatusInternalServerError, "internal error")
+		if err := loadRegistryLabels(r.Context(), db, registry, nodeID); err != nil {
+			registry.Evict(nodeID)
+			writeError(w, http.StatusInternalServerError, "database error")
 			return
 		}
 
@@ -469,14 +584,41 @@ func handleHeartbeat(db *store.DB, registry *orchestrator.NodeRegistry) http.Han
 			slog.Warn("registry update opt-out failed", "node_id", req.NodeID, "err", err)
 		}
 
+		// Record the agent's labels and taints, then mirror the merged set
+		// (owner's over agent's) into the registry for FindMatch. An invalid
+		// report is dropped rather than failing the heartbeat: liveness must
+		// not hinge on a typo in AGENT_LABELS.
+		if req.Labels != nil || req.Taints != nil {
+			if err := errors.Join(types.ValidateNodeLabels(req.Labels), types.ValidateNodeLabels(req.Taints)); err != nil {
+				slog.Warn("heartbeat labels rejected", "node_id", req.NodeID, "err", err)
+			} else if err := store.ReportNodeLabels(r.Context(), db, req.NodeID,
+				store.NodeLabels{Labels: req.Labels, Taints: req.Taints}); err != nil {
+				writeError(w, http.StatusInternalServerError, "database error")
+				return
+			}
+		}
+		if err := loadRegistryLabels(r.Context(), db, registry, req.NodeID); err != nil {
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
 		// Refresh the advisory load sample (B2) for the scheduler's soft
 		// idle-first scoring. Same warn-and-continue posture as UpdateOptOut:
 		// a lost sample must never fail a heartbeat.
-		if err := registry.UpdateLoad(req.NodeID, orchestrator.NodeLoadState{
+		load := orchestrator.NodeLoadState{
 			OwnerActive: req.OwnerActive,
 			CPUUtilPct:  req.CPUPct,
 			SampledAt:   time.Now(),
-		}); err != nil {
+		}
+		if fc := req.FreeCapacity; fc != nil {
+			load.Free = &orchestrator.NodeFreeCapacity{
+				CPUCores:  fc.CPUCores,
+				RAMMB:     fc.RAMMB,
+				StorageGB: fc.StorageGB,
+				Slots:     fc.Slots,
+			}
+		}
+		if err := registry.UpdateLoad(req.NodeID, load); err != nil {
 			slog.Warn("registry update load failed", "node_id", req.NodeID, "err", err)
 		}
 
@@ -528,6 +670,13 @@ func handleHeartbeat(db *store.DB, registry *orchestrator.NodeRegistry) http.Han
 			resp.RequestPrinterReport = true
 		}
 
+		stop, err := store.JobsToStop(r.Context(), db, req.NodeID, req.RunningJobs)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		resp.StopJobs = stop
+
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp) //nolint:errcheck
 	}
@@ -633,10 +782,17 @@ func handleGetJobs(db *store.DB) http.HandlerFunc {
 		jobs := make([]jobEntry, 0, len(dispatched))
 		for _, d := range dispatched {
 			jobs = append(jobs, jobEntry{
-				JobID:     d.JobID,
-				JobToken:  d.JobToken,
-				Image:     d.Image,
-				PrinterID: d.PrinterID,
+				JobID:      d.JobID,
+				JobToken:   d.JobToken,
+				Image:      d.Image,
+				PrinterID:  d.PrinterID,
+				CPUCores:   d.CPUCores,
+				RAMMB:      d.RAMMB,
+				StorageGB:  d.StorageGB,
+				OutputPath: d.OutputPath,
+				Inputs:     jobInputEntries(d.Inputs),
+
+				MaxRuntimeSeconds: d.MaxRuntimeSeconds,
 			})
 		}
 
@@ -645,14 +801,90 @@ func handleGetJobs(db *store.DB) http.HandlerFunc {
 	}
 }
 
+// jobInputEntries converts a dispatched job's inputs to their wire form.
+func jobInputEntries(inputs []store.JobInput) []jobInputEntry {
+	if len(inputs) == 0 {
+		return nil
+	}
+	entries := make([]jobInputEntry, len(inputs))
+	for i, in := range inputs {
+		entries[i] = jobInputEntry{Name: in.Name, SHA256: in.SHA256, SizeBytes: in.SizeBytes, URL: in.URL}
+	}
+	return entries
+}
+
+// handleGetProfiles returns the node's resource profiles (default plus any
+// scheduled overrides) so the agent can resolve the active profile at job
+// start. Overrides are ordered oldest-first: agent.ActiveProfile takes the
+// first matching override, so the earliest-created one wins ties.
+func handleGetProfiles(db *store.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		nodeID := r.URL.Query().Get("node_id")
+		if nodeID == "" {
+			writeError(w, http.StatusBadRequest, "node_id query parameter is required")
+			return
+		}
+
+		// See handleCompleteJob for the binding rationale.
+		spiffeID, ok := identity.SPIFFEIDFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "no SPIFFE identity in context")
+			return
+		}
+		if spiffeID.Path() != "/node/"+nodeID {
+			writeError(w, http.StatusForbidden, "SPIFFE identity does not match node")
+			return
+		}
+
+		rows, err := db.Pool.Query(r.Context(), `
+			SELECT name, is_default, cpu_enabled, gpu_pct, ram_pct, storage_gb, bandwidth_mbps,
+			       to_char(schedule_start, 'HH24:MI'), to_char(schedule_end, 'HH24:MI'),
+			       schedule_days,
+			       to_char(override_start_date, 'YYYY-MM-DD'), to_char(override_end_date, 'YYYY-MM-DD')
+			FROM resource_profiles
+			WHERE node_id = $1
+			ORDER BY is_default DESC, created_at ASC`,
+			nodeID,
+		)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		defer rows.Close()
+
+		profiles := []resourceProfileEntry{}
+		for rows.Next() {
+			var p resourceProfileEntry
+			if err := rows.Scan(&p.Name, &p.IsDefault, &p.CPUEnabled, &p.GPUPct, &p.RAMPct,
+				&p.StorageGB, &p.BandwidthMbps,
+				&p.ScheduleStart, &p.ScheduleEnd, &p.ScheduleDays,
+				&p.OverrideStartDate, &p.OverrideEndDate); err != nil {
+				writeError(w, http.StatusInternalServerError, "database error")
+				return
+			}
+			profiles = append(profiles, p)
+		}
+		if err := rows.Err(); err != nil {
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(profiles) //nolint:errcheck
+	}
+}
+
 // completeJobRequest is the JSON body the agent POSTs to /jobs/{id}/complete.
 // ExitCode is a pointer so the handler distinguishes "not sent" (old agent —
 // persisted as NULL) from "sent zero" (success — persisted as 0). C4 uses this
-// distinction to decide whether to fire metering.
+// distinction to decide whether to fire metering. ResultHash is the SHA-256 of
+// the job's declared output artifact, compared across replicas of a verified
+// job (migration 032).
 type completeJobRequest struct {
 	ExitCode       *int   `json:"exit_code,omitempty"`
 	FailureCause   string `json:"failure_cause,omitempty"`
 	TmpfsExhausted bool   `json:"tmpfs_exhausted,omitempty"`
+	ResultHash     string `json:"result_hash,omitempty"`
 }
 
 func handleCompleteJob(db *store.DB, registry *orchestrator.NodeRegistry) http.HandlerFunc {
@@ -696,6 +928,22 @@ func handleCompleteJob(db *store.DB, registry *orchestrator.NodeRegistry) http.H
 			return
 		}
 
+		// Recorded before CompleteJob, which settles the replica group and
+		// may compare this hash against the siblings'.
+		if req.ResultHash != "" {
+			if err := store.RecordResultHash(r.Context(), db, jobID, req.ResultHash); err != nil {
+				switch {
+				case errors.Is(err, store.ErrInvalidResultHash):
+					writeError(w, http.StatusBadRequest, "result_hash must be a hex SHA-256 digest")
+				case errors.Is(err, store.ErrJobNotRunning):
+					writeError(w, http.StatusConflict, "job is not in running state")
+				default:
+					writeError(w, http.StatusInternalServerError, "database error")
+				}
+				return
+			}
+		}
+
 		newStatus, err := store.CompleteJob(r.Context(), db, jobID, req.ExitCode, req.FailureCause, req.TmpfsExhausted)
 		if err != nil {
 			if errors.Is(err, store.ErrJobNotRunning) {
@@ -706,17 +954,81 @@ func handleCompleteJob(db *store.DB, registry *orchestrator.NodeRegistry) http.H
 			return
 		}
 
-		// Advisory in-flight accounting (B4): all three statuses CompleteJob
-		// can produce are terminal FOR PLACEMENT — completed, failed, and
+		// Placement accounting (B4): all three statuses CompleteJob can
+		// produce are terminal FOR PLACEMENT — completed, failed, and
 		// awaiting_pickup (container work is done; only physical handoff
-		// remains) — so the node's slot is released.
-		registry.AddInFlight(nodeID, -1)
+		// remains) — so the node's reservation is released.
+		registry.Release(nodeID, jobID)
+		orchestrator.SettleReplicaGroup(r.Context(), db, registry, jobID)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{"status": newStatus}) //nolint:errcheck
 	}
 }
 
+// declineJobRequest is the optional JSON body for POST /jobs/{id}/decline.
+// Reason is advisory and only logged (e.g. "no_capacity", "exceeds_profile",
+// "input_staging_failed").
+type declineJobRequest struct {
+	Reason string `json:"reason"`
+}
+
+// handleDeclineJob lets an agent turn down a job placed on it — the bespoke
+// counterpart of the protocol Decline message, sharing store.DeclineJob. The
+// reroute worker then re-places the job with this node excluded.
+func handleDeclineJob(db *store.DB, registry *orchestrator.NodeRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := r.PathValue("id")
+		if jobID == "" {
+			writeError(w, http.StatusBadRequest, "job ID required")
+			return
+		}
+
+		var nodeID string
+		err := db.Pool.QueryRow(r.Context(), `
+			SELECT j.node_id::text
+			FROM jobs j
+			WHERE j.id = $1`,
+			jobID,
+		).Scan(&nodeID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		// See handleCompleteJob for the binding rationale.
+		spiffeID, ok := identity.SPIFFEIDFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "no SPIFFE identity in context")
+			return
+		}
+		if spiffeID.Path() != "/node/"+nodeID {
+			writeError(w, http.StatusForbidden, "SPIFFE identity does not match job owner")
+			return
+		}
+
+		var req declineJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+
+		declined, err := store.DeclineJob(r.Context(), db, jobID, nodeID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		if !declined {
+			writeError(w, http.StatusConflict, "job is not scheduled or dispatched")
+			return
+		}
+		registry.Release(nodeID, jobID)
+		slog.Info("job declined by node", "job_id", jobID, "node_id", nodeID, "reason", req.Reason)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"declined": true}) //nolint:errcheck
+	}
+}
+
 // nodePubkeyRequest is the JSON body for POST /nodes/pubkey (A1): out-of-band
 // enrollment of a node's sohocloud-protocol Ed25519 verification key.
 type nodePubkeyRequest struct {
@@ -856,6 +1168,7 @@ func handleStartedJob(db *store.DB) http.HandlerFunc {
 			writeError(w, http.StatusConflict, "job is not in dispatched state")
 			return
 		}
+		orchestrator.SettleReplicaGroup(r.Context(), db, nil, jobID)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]bool{"started": true}) //nolint:errcheck
@@ -871,12 +1184,16 @@ func handleTelemetry(db *store.DB) http.HandlerFunc {
 		}
 
 		var nodeID string
+		var nodeBandwidthMbps int
 		err := db.Pool.QueryRow(r.Context(), `
-			SELECT j.node_id::text
+			SELECT j.node_id::text,
+			       COALESCE((n.hardware_profile->>'bandwidth_mbps')::int,
+			                (n.hardware_profile->>'BandwidthMbps')::int, 0)
 			FROM jobs j
+			JOIN nodes n ON n.id = j.node_id
 			WHERE j.id = $1`,
 			jobID,
-		).Scan(&nodeID)
+		).Scan(&nodeID, &nodeBandwidthMbps)
 		if err != nil {
 			writeError(w, http.StatusNotFound, "job not found")
 			return
@@ -897,17 +1214,144 @@ func handleTelemetry(db *store.DB) http.HandlerFunc {
 			writeError(w, http.StatusBadRequest, "invalid JSON body")
 			return
 		}
+		if req.Timestamp.IsZero() {
+			writeError(w, http.StatusBadRequest, "timestamp is required")
+			return
+		}
+		if req.CPUPct < 0 || req.CPUPct > 100 || req.RAMPct < 0 || req.RAMPct > 100 {
+			writeError(w, http.StatusBadRequest, "cpu_pct and ram_pct must be within 0-100")
+			return
+		}
+		// bandwidth_mbps bills egress, so beyond being signed (version 2; see
+		// agent.VerifyTelemetry) it may not exceed the link speed the node
+		// registered. Metering clamps to the same range (and bills no egress
+		// for a node that registered none).
+		if req.BandwidthMbps < 0 || (nodeBandwidthMbps > 0 && req.BandwidthMbps > nodeBandwidthMbps) {
+			writeError(w, http.StatusBadRequest,
+				fmt.Sprintf("bandwidth_mbps must be within 0-%d, the node's registered bandwidth", nodeBandwidthMbps))
+			return
+		}
+		if req.Timestamp.After(time.Now().Add(telemetryMaxClockSkew)) {
+			writeError(w, http.StatusBadRequest, "timestamp is in the future")
+			return
+		}
+
+		// Verify the HMAC against the secret issued at claim time. The node ID
+		// in the canonical message is the job's bound node (already matched to
+		// the SPIFFE peer above), never the body's self-reported node_id.
+		secret, err := store.NodeTelemetrySecret(r.Context(), db, nodeID)
+		if err != nil {
+			if errors.Is(err, store.ErrNoTelemetrySecret) {
+				writeError(w, http.StatusForbidden, "no telemetry secret enrolled for node; re-claim the node")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		payload := agent.TelemetryPayload{
+			NodeID:        nodeID,
+			JobID:         jobID,
+			CPUPct:        req.CPUPct,
+			RAMPct:        req.RAMPct,
+			BandwidthMbps: req.BandwidthMbps,
+			Timestamp:     req.Timestamp,
+			SigVersion:    req.SigVersion,
+			Signature:     req.Signature,
+		}
+		if err := agent.VerifyTelemetry(payload, secret); err != nil {
+			slog.Warn("telemetry signature rejected", "job_id", jobID, "node_id", nodeID, "error", err)
+			writeError(w, http.StatusUnauthorized, "invalid telemetry signature")
+			return
+		}
 
-		// Metering table is added in Phase 2 Step 4 — log for now.
-		log.Printf("telemetry job=%s node=%s cpu=%.1f%% ram=%.1f%% bw=%dMbps ts=%s spiffe=%s",
-			jobID, req.NodeID, req.CPUPct, req.RAMPct, req.BandwidthMbps,
-			req.Timestamp.Format(time.RFC3339), spiffeID)
+		err = store.InsertTelemetrySample(r.Context(), db, jobID, store.TelemetrySample{
+			SampledAt:     req.Timestamp,
+			CPUPct:        req.CPUPct,
+			RAMPct:        req.RAMPct,
+			BandwidthMbps: req.BandwidthMbps,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrTelemetryReplay):
+				writeError(w, http.StatusConflict, "telemetry sample is not newer than the last accepted sample")
+			case errors.Is(err, store.ErrJobNotRunning):
+				writeError(w, http.StatusConflict, "job is not in running state")
+			default:
+				writeError(w, http.StatusInternalServerError, "database error")
+			}
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]bool{"ok": true}) //nolint:errcheck
 	}
 }
 
+// handleGetTelemetry returns the job's verified CPU/RAM series to the node
+// that ran it. SPIFFE-bound like the write path; consumers read the same
+// series through the portal.
+func handleGetTelemetry(db *store.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jobID := r.PathValue("id")
+		if jobID == "" {
+			writeError(w, http.StatusBadRequest, "job ID required")
+			return
+		}
+
+		var nodeID string
+		err := db.Pool.QueryRow(r.Context(), `
+			SELECT j.node_id::text
+			FROM jobs j
+			WHERE j.id = $1`,
+			jobID,
+		).Scan(&nodeID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "job not found")
+			return
+		}
+		// See handleCompleteJob for the binding rationale.
+		spiffeID, ok := identity.SPIFFEIDFromContext(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "no SPIFFE identity in context")
+			return
+		}
+		if spiffeID.Path() != "/node/"+nodeID {
+			writeError(w, http.StatusForbidden, "SPIFFE identity does not match job owner")
+			return
+		}
+
+		var since time.Time
+		if v := r.URL.Query().Get("since"); v != "" {
+			since, err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp")
+				return
+			}
+		}
+
+		samples, err := store.JobTelemetry(r.Context(), db, jobID, since)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "database error")
+			return
+		}
+		out := make([]telemetrySampleDTO, 0, len(samples))
+		for _, s := range samples {
+			out = append(out, telemetrySampleDTO{
+				SampledAt:     s.SampledAt,
+				CPUPct:        s.CPUPct,
+				RAMPct:        s.RAMPct,
+				BandwidthMbps: s.BandwidthMbps,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+			"job_id":  jobID,
+			"samples": out,
+		})
+	}
+}
+
 // ── APIServer method wrappers (used by tests and future handler composition) ─
 
 func (s *APIServer) handleRegisterNode(w http.ResponseWriter, r *http.Request) {
@@ -937,3 +1381,27 @@ func (s *APIServer) handleReportPrinters(w http.ResponseWriter, r *http.Request)
 func (s *APIServer) handleTelemetry(w http.ResponseWriter, r *http.Request) {
 	handleTelemetry(s.db)(w, r)
 }
+
+func (s *APIServer) handleGetTelemetry(w http.ResponseWriter, r *http.Request) {
+	handleGetTelemetry(s.db)(w, r)
+}
+
+func (s *APIServer) handleGetProfiles(w http.ResponseWriter, r *http.Request) {
+	handleGetProfiles(s.db)(w, r)
+}
+
+func (s *APIServer) handleDeclineJob(w http.ResponseWriter, r *http.Request) {
+	handleDeclineJob(s.db, s.registry)(w, r)
+}
+
+func (s *APIServer) handleUploadArtifact(w http.ResponseWriter, r *http.Request) {
+	handleUploadArtifact(s.db, s.artifacts, s.limits)(w, r)
+}
+
+func (s *APIServer) handleGetJobInput(w http.ResponseWriter, r *http.Request) {
+	handleGetJobInput(s.db, s.artifacts)(w, r)
+}
+
+func (s *APIServer) handleAppendJobLogs(w http.ResponseWriter, r *http.Request) {
+	handleAppendJobLogs(s.db)(w, r)
+}
@@ -18,9 +18,11 @@ import (
 
 	"github.com/spiffe/go-spiffe/v2/spiffeid"
 
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/agent"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
 )
 
 // ── test helpers ─────────────────────────────────────────────────────────────
@@ -446,6 +448,57 @@ func TestHandleCompleteJob_NotRunning(t *testing.T) {
 	}
 }
 
+// TestHandleCompleteJob_InvalidResultHash verifies a malformed result_hash is
+// rejected with 400 and the job is left running.
+func TestHandleCompleteJob_InvalidResultHash(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	participantID := seedAPIParticipant(t, db, "complete_badhash@test.com")
+
+	var nodeID string
+	if err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO nodes (participant_id, hostname, status, node_class, country_code,
+		  hardware_profile, uptime_pct)
+		 VALUES ($1, 'complete-badhash-host', 'online', 'A', 'US', '{"CPUCores":2,"RAMMB":4096}', 100.0)
+		 RETURNING id`,
+		participantID,
+	).Scan(&nodeID); err != nil {
+		t.Fatalf("seed node: %v", err)
+	}
+
+	var jobID string
+	if err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO jobs (participant_id, node_id, workload_type, status,
+		  amount_cents, cpu_cores, ram_mb, started_at)
+		 VALUES ($1, $2, 'batch_compute', 'running', 0, 2, 4096, NOW())
+		 RETURNING id`,
+		participantID, nodeID,
+	).Scan(&jobID); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+
+	b, _ := json.Marshal(map[string]any{"exit_code": 0, "result_hash": "not-a-digest"})
+	r := httptest.NewRequest(http.MethodPost, "/jobs/"+jobID+"/complete", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", jobID)
+	r = withNodeSPIFFE(r, nodeID)
+	w := httptest.NewRecorder()
+	ps.handleCompleteJob(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	var status string
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT status FROM jobs WHERE id = $1`, jobID,
+	).Scan(&status); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if status != "running" {
+		t.Errorf("status = %q, want running", status)
+	}
+}
+
 func TestHandleCompleteJob_PersistsExitCodeNonzero(t *testing.T) {
 	db := connectAPITestDB(t)
 	ps := newAPIServer(t, db)
@@ -1169,6 +1222,83 @@ func TestHandleHeartbeat_PrinterHashMismatch_RequestsReport(t *testing.T) {
 
 // ── handleRegisterNode (printer upsert) ──────────────────────────────────────
 
+func TestHandleHeartbeat_Labels_OwnerOverAgentInRegistry(t *testing.T) {
+	t.Setenv("CONTROL_PLANE_REGISTER_SECRET", "test-secret")
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	pid := seedAPIParticipant(t, db, "hb_labels@test.com")
+	nodeID := "40000000-0000-0000-0000-000000000005"
+	registerTestNode(t, ps, pid, nodeID, nil)
+
+	if err := store.SetNodeLabels(context.Background(), db, nodeID, pid, store.NodeLabels{
+		Labels: map[string]string{"zone": "attic"},
+		Taints: map[string]string{"office-hours-only": ""},
+	}); err != nil {
+		t.Fatalf("SetNodeLabels: %v", err)
+	}
+
+	beat := func(labels map[string]string) {
+		t.Helper()
+		w := postJSONAs(t, ps.handleHeartbeat, "/nodes/heartbeat", map[string]any{
+			"node_id":         nodeID,
+			"opt_out_version": 0,
+			"labels":          labels,
+			"taints":          map[string]string{},
+		}, nodeID)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	beat(map[string]string{"ssd": "", "zone": "cellar"})
+	entry, ok := ps.registry.Get(nodeID)
+	if !ok {
+		t.Fatal("node missing from registry")
+	}
+	if got := types.FormatNodeLabels(entry.Labels); got != "ssd, zone=attic" {
+		t.Errorf("registry labels = %q, want the owner's zone over the agent's", got)
+	}
+	if got := types.FormatNodeLabels(entry.Taints); got != "office-hours-only" {
+		t.Errorf("registry taints = %q", got)
+	}
+
+	// An invalid report is dropped, keeping the last good one.
+	beat(map[string]string{"SSD": ""})
+	entry, _ = ps.registry.Get(nodeID)
+	if got := types.FormatNodeLabels(entry.Labels); got != "ssd, zone=attic" {
+		t.Errorf("after invalid report: registry labels = %q", got)
+	}
+}
+
+func TestHandleRegisterNode_ReRegisterKeepsTaints(t *testing.T) {
+	t.Setenv("CONTROL_PLANE_REGISTER_SECRET", "test-secret")
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	pid := seedAPIParticipant(t, db, "rereg_taints@test.com")
+	nodeID := "40000000-0000-0000-0000-000000000006"
+	registerTestNode(t, ps, pid, nodeID, nil)
+
+	if err := store.SetNodeLabels(context.Background(), db, nodeID, pid, store.NodeLabels{
+		Taints: map[string]string{"office-hours-only": ""},
+	}); err != nil {
+		t.Fatalf("SetNodeLabels: %v", err)
+	}
+
+	// A control-plane restart or agent reconnect re-registers the node;
+	// no heartbeat follows before the scheduler looks at it.
+	registerTestNode(t, ps, pid, nodeID, nil)
+
+	match := orchestrator.MatchRequest{CPUCores: 1, RAMMB: 1024}
+	if got, _ := ps.registry.FindMatch(match); len(got) != 0 {
+		t.Fatalf("untolerating job matched tainted node: %+v", got)
+	}
+	match.Tolerations = []orchestrator.Toleration{{Key: "office-hours-only"}}
+	got, err := ps.registry.FindMatch(match)
+	if err != nil || len(got) != 1 || got[0].NodeID != nodeID {
+		t.Fatalf("tolerating job: got %+v, %v; want %s", got, err, nodeID)
+	}
+}
+
 func TestHandleRegisterNode_WithPrinters_CreatesRows(t *testing.T) {
 	t.Setenv("CONTROL_PLANE_REGISTER_SECRET", "test-secret")
 	db := connectAPITestDB(t)
@@ -1900,6 +2030,387 @@ func TestHandleTelemetry_SPIFFEMismatch_403(t *testing.T) {
 	}
 }
 
+// ── handleTelemetry verification & persistence ─────────────────────────────
+
+// seedTelemetryJob inserts a node carrying secret as its telemetry_secret
+// (nil leaves the column NULL, as for pre-029 nodes) and a running job on it.
+func seedTelemetryJob(t *testing.T, db *store.DB, email string, secret []byte) (nodeID, jobID string) {
+	t.Helper()
+	participantID := seedAPIParticipant(t, db, email)
+	nodeID = seedPubkeyNode(t, db, participantID)
+	if _, err := db.Pool.Exec(context.Background(),
+		`UPDATE nodes SET telemetry_secret = $2 WHERE id = $1`, nodeID, secret,
+	); err != nil {
+		t.Fatalf("set telemetry secret: %v", err)
+	}
+	if err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO jobs (participant_id, node_id, workload_type, status,
+		  amount_cents, cpu_cores, ram_mb, started_at)
+		 VALUES ($1, $2, 'app_hosting', 'running', 0, 2, 4096, NOW())
+		 RETURNING id`, participantID, nodeID,
+	).Scan(&jobID); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	return nodeID, jobID
+}
+
+// postSignedTelemetry signs a sample at ts with secret and POSTs it as nodeID.
+func postSignedTelemetry(t *testing.T, ps *APIServer, nodeID, jobID string, secret []byte, ts time.Time) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, err := agent.SignTelemetry(agent.TelemetryPayload{
+		NodeID:    nodeID,
+		JobID:     jobID,
+		CPUPct:    37.5,
+		RAMPct:    52.25,
+		Timestamp: ts,
+	}, secret)
+	if err != nil {
+		t.Fatalf("SignTelemetry: %v", err)
+	}
+	b, _ := json.Marshal(payload)
+	r := httptest.NewRequest(http.MethodPost, "/jobs/"+jobID+"/telemetry", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", jobID)
+	r = withNodeSPIFFE(r, nodeID)
+	w := httptest.NewRecorder()
+	ps.handleTelemetry(w, r)
+	return w
+}
+
+func TestHandleTelemetry_ValidSignature_Persists(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_valid@test.com", secret)
+
+	ts := time.Now().UTC().Truncate(time.Second)
+	w := postSignedTelemetry(t, ps, nodeID, jobID, secret, ts)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var cpu, ram float64
+	var sampledAt time.Time
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT cpu_pct, ram_pct, sampled_at FROM job_telemetry WHERE job_id = $1`, jobID,
+	).Scan(&cpu, &ram, &sampledAt); err != nil {
+		t.Fatalf("read job_telemetry: %v", err)
+	}
+	if cpu != 37.5 || ram != 52.25 || !sampledAt.Equal(ts) {
+		t.Errorf("unexpected row: cpu=%v ram=%v sampled_at=%v", cpu, ram, sampledAt)
+	}
+}
+
+func TestHandleTelemetry_BandwidthOutOfRange_400(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_bandwidth@test.com", secret)
+	if _, err := db.Pool.Exec(context.Background(),
+		`UPDATE nodes SET hardware_profile = hardware_profile || '{"BandwidthMbps":100}' WHERE id = $1`, nodeID,
+	); err != nil {
+		t.Fatalf("set node bandwidth: %v", err)
+	}
+
+	ts := time.Now().UTC().Truncate(time.Second)
+	for _, mbps := range []int{-1, 101} {
+		payload, err := agent.SignTelemetry(agent.TelemetryPayload{
+			NodeID: nodeID, JobID: jobID, CPUPct: 10, RAMPct: 10, BandwidthMbps: mbps, Timestamp: ts,
+		}, secret)
+		if err != nil {
+			t.Fatalf("SignTelemetry: %v", err)
+		}
+		b, _ := json.Marshal(payload)
+		r := httptest.NewRequest(http.MethodPost, "/jobs/"+jobID+"/telemetry", bytes.NewReader(b))
+		r.Header.Set("Content-Type", "application/json")
+		r.SetPathValue("id", jobID)
+		r = withNodeSPIFFE(r, nodeID)
+		w := httptest.NewRecorder()
+		ps.handleTelemetry(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("bandwidth_mbps %d: expected 400, got %d: %s", mbps, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestHandleTelemetry_UnsignedBandwidth_401(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_v1_bandwidth@test.com", secret)
+	if _, err := db.Pool.Exec(context.Background(),
+		`UPDATE nodes SET hardware_profile = hardware_profile || '{"BandwidthMbps":100}' WHERE id = $1`, nodeID,
+	); err != nil {
+		t.Fatalf("set node bandwidth: %v", err)
+	}
+
+	payload, err := agent.SignTelemetry(agent.TelemetryPayload{
+		NodeID: nodeID, JobID: jobID, CPUPct: 10, RAMPct: 10, BandwidthMbps: 50,
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+	}, secret)
+	if err != nil {
+		t.Fatalf("SignTelemetry: %v", err)
+	}
+	// Claiming a version 1 signature, which does not cover bandwidth_mbps.
+	payload.SigVersion = agent.TelemetrySigV1
+	b, _ := json.Marshal(payload)
+	r := httptest.NewRequest(http.MethodPost, "/jobs/"+jobID+"/telemetry", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	r.SetPathValue("id", jobID)
+	r = withNodeSPIFFE(r, nodeID)
+	w := httptest.NewRecorder()
+	ps.handleTelemetry(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleTelemetry_ReplayAndOutOfOrder_409(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_replay@test.com", secret)
+
+	ts := time.Now().UTC().Truncate(time.Second)
+	if w := postSignedTelemetry(t, ps, nodeID, jobID, secret, ts); w.Code != http.StatusOK {
+		t.Fatalf("first sample: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := postSignedTelemetry(t, ps, nodeID, jobID, secret, ts); w.Code != http.StatusConflict {
+		t.Fatalf("replay: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := postSignedTelemetry(t, ps, nodeID, jobID, secret, ts.Add(-30*time.Second)); w.Code != http.StatusConflict {
+		t.Fatalf("out-of-order: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var n int
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM job_telemetry WHERE job_id = $1`, jobID,
+	).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected exactly 1 stored sample, got %d", n)
+	}
+}
+
+func TestHandleTelemetry_BadSignature_401(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_badsig@test.com",
+		[]byte("0123456789abcdef0123456789abcdef"))
+
+	w := postSignedTelemetry(t, ps, nodeID, jobID, []byte("wrong-secret-wrong-secret-wrong!"), time.Now().UTC())
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleTelemetry_FutureTimestamp_400(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_future@test.com", secret)
+
+	w := postSignedTelemetry(t, ps, nodeID, jobID, secret, time.Now().UTC().Add(time.Hour))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleTelemetry_NoSecretEnrolled_403(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_nosecret@test.com", nil)
+
+	w := postSignedTelemetry(t, ps, nodeID, jobID, []byte("anything"), time.Now().UTC())
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleGetTelemetry_ReturnsSeries(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	nodeID, jobID := seedTelemetryJob(t, db, "telemetry_series@test.com", secret)
+
+	base := time.Now().UTC().Truncate(time.Second).Add(-time.Minute)
+	for i := 0; i < 3; i++ {
+		if w := postSignedTelemetry(t, ps, nodeID, jobID, secret, base.Add(time.Duration(i)*20*time.Second)); w.Code != http.StatusOK {
+			t.Fatalf("sample %d: expected 200, got %d: %s", i, w.Code, w.Body.String())
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/jobs/"+jobID+"/telemetry?since="+base.Format(time.RFC3339), nil)
+	r.SetPathValue("id", jobID)
+	r = withNodeSPIFFE(r, nodeID)
+	w := httptest.NewRecorder()
+	ps.handleGetTelemetry(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Samples []telemetrySampleDTO `json:"samples"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// since is exclusive: the first sample (at base) is omitted.
+	if len(resp.Samples) != 2 {
+		t.Fatalf("expected 2 samples after since, got %d", len(resp.Samples))
+	}
+	if !resp.Samples[0].SampledAt.Before(resp.Samples[1].SampledAt) {
+		t.Errorf("samples not in ascending order: %v", resp.Samples)
+	}
+}
+
+// ── handleDeclineJob ─────────────────────────────────────────────────────────
+
+// seedDeclineJob inserts a node and a job bound to it in the given status.
+func seedDeclineJob(t *testing.T, db *store.DB, email, status string) (nodeID, jobID string) {
+	t.Helper()
+	participantID := seedAPIParticipant(t, db, email)
+	nodeID = seedPubkeyNode(t, db, participantID)
+	if err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO jobs (participant_id, node_id, workload_type, status,
+		  amount_cents, cpu_cores, ram_mb)
+		 VALUES ($1, $2, 'app_hosting', $3::job_status, 0, 2, 4096)
+		 RETURNING id`,
+		participantID, nodeID, status,
+	).Scan(&jobID); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	return nodeID, jobID
+}
+
+func postDecline(t *testing.T, ps *APIServer, jobID, spiffeNodeID string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/jobs/"+jobID+"/decline",
+		strings.NewReader(`{"reason":"no_capacity"}`))
+	r.SetPathValue("id", jobID)
+	r = withNodeSPIFFE(r, spiffeNodeID)
+	w := httptest.NewRecorder()
+	ps.handleDeclineJob(w, r)
+	return w
+}
+
+func TestHandleDeclineJob_DispatchedRecordsDecline(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	nodeID, jobID := seedDeclineJob(t, db, "decline_ok@test.com", "dispatched")
+
+	if w := postDecline(t, ps, jobID, nodeID); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var status string
+	var declines int
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT status::text, (SELECT COUNT(*) FROM job_node_declines WHERE job_id = $1 AND node_id = $2)
+		 FROM jobs WHERE id = $1`, jobID, nodeID,
+	).Scan(&status, &declines); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if status != "declined" || declines != 1 {
+		t.Errorf("status=%q declines=%d, want declined/1", status, declines)
+	}
+
+	// A second decline finds nothing to flip.
+	if w := postDecline(t, ps, jobID, nodeID); w.Code != http.StatusConflict {
+		t.Fatalf("repeat decline: expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestHandleDeclineJob_RunningJob_409(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	nodeID, jobID := seedDeclineJob(t, db, "decline_running@test.com", "running")
+
+	if w := postDecline(t, ps, jobID, nodeID); w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	var declines int
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM job_node_declines WHERE job_id = $1`, jobID,
+	).Scan(&declines); err != nil {
+		t.Fatalf("count declines: %v", err)
+	}
+	if declines != 0 {
+		t.Errorf("declines = %d, want 0 for a job that was not declinable", declines)
+	}
+}
+
+func TestHandleDeclineJob_SPIFFEMismatch_403(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	_, jobID := seedDeclineJob(t, db, "decline_mismatch@test.com", "scheduled")
+
+	if w := postDecline(t, ps, jobID, "00000000-0000-0000-0000-000000000000"); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// ── handleGetProfiles ────────────────────────────────────────────────────────
+
+func TestHandleGetProfiles_ReturnsDefaultAndOverrides(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	participantID := seedAPIParticipant(t, db, "get_profiles@test.com")
+	nodeID := seedPubkeyNode(t, db, participantID)
+
+	if _, err := db.Pool.Exec(context.Background(),
+		`INSERT INTO resource_profiles (node_id, name, is_default, cpu_enabled, ram_pct, storage_gb, bandwidth_mbps,
+		   schedule_start, schedule_end, schedule_days, override_start_date)
+		 VALUES ($1, 'nights', FALSE, FALSE, 25, 0, 0, '22:00', '06:00', ARRAY['sat','sun'], '2026-04-01'),
+		        ($1, 'default', TRUE, TRUE, 50, 100, 0, NULL, NULL, NULL, NULL)`,
+		nodeID,
+	); err != nil {
+		t.Fatalf("seed profiles: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/nodes/profiles?node_id="+nodeID, nil)
+	r = withNodeSPIFFE(r, nodeID)
+	w := httptest.NewRecorder()
+	ps.handleGetProfiles(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var profiles []resourceProfileEntry
+	if err := json.NewDecoder(w.Body).Decode(&profiles); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(profiles) != 2 {
+		t.Fatalf("expected 2 profiles, got %d", len(profiles))
+	}
+	if !profiles[0].IsDefault || profiles[0].RAMPct != 50 || profiles[0].ScheduleStart != nil {
+		t.Errorf("first profile should be the default: %+v", profiles[0])
+	}
+	o := profiles[1]
+	if o.ScheduleStart == nil || *o.ScheduleStart != "22:00" || o.ScheduleEnd == nil || *o.ScheduleEnd != "06:00" {
+		t.Errorf("override schedule = %v-%v, want 22:00-06:00", o.ScheduleStart, o.ScheduleEnd)
+	}
+	if o.OverrideStartDate == nil || *o.OverrideStartDate != "2026-04-01" {
+		t.Errorf("override_start_date = %v, want 2026-04-01", o.OverrideStartDate)
+	}
+}
+
+func TestHandleGetProfiles_SPIFFEMismatch_403(t *testing.T) {
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	participantID := seedAPIParticipant(t, db, "get_profiles_mismatch@test.com")
+	nodeID := seedPubkeyNode(t, db, participantID)
+
+	r := httptest.NewRequest(http.MethodGet, "/nodes/profiles?node_id="+nodeID, nil)
+	r = withNodeSPIFFE(r, "00000000-0000-0000-0000-000000000000")
+	w := httptest.NewRecorder()
+	ps.handleGetProfiles(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
 // ── handleHeartbeat SPIFFE binding ───────────────────────────────────────────
 
 func TestHandleHeartbeat_SPIFFEMissing_401(t *testing.T) {
@@ -2344,4 +2855,35 @@ func TestHandleHeartbeat_AbsentLoadFieldsDefaultZero(t *testing.T) {
 	if entry.CPUUtilPct != 0 {
 		t.Errorf("CPUUtilPct should default 0 for old agents, got %v", entry.CPUUtilPct)
 	}
+	if entry.FreeCapacityKnown {
+		t.Error("FreeCapacityKnown should be false for old agents")
+	}
+}
+
+func TestHandleHeartbeat_RecordsFreeCapacity(t *testing.T) {
+	t.Setenv("CONTROL_PLANE_REGISTER_SECRET", "test-secret")
+	db := connectAPITestDB(t)
+	ps := newAPIServer(t, db)
+	pid := seedAPIParticipant(t, db, "hb_free_capacity@test.com")
+	nodeID := "40000000-0000-0000-0000-000000000012"
+	registerTestNode(t, ps, pid, nodeID, nil)
+
+	w := postJSONAs(t, ps.handleHeartbeat, "/nodes/heartbeat", map[string]any{
+		"node_id": nodeID,
+		"free_capacity": map[string]int{
+			"cpu_cores": 2, "ram_mb": 3072, "storage_gb": -1, "slots": 1,
+		},
+	}, nodeID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	entry, ok := ps.registry.Get(nodeID)
+	if !ok {
+		t.Fatal("node missing from registry after heartbeat")
+	}
+	want := orchestrator.NodeFreeCapacity{CPUCores: 2, RAMMB: 3072, StorageGB: -1, Slots: 1}
+	if !entry.FreeCapacityKnown || entry.FreeCapacity != want {
+		t.Errorf("FreeCapacity = %+v (known=%v), want %+v", entry.FreeCapacity, entry.FreeCapacityKnown, want)
+	}
 }
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/identity"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/metrics"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
@@ -22,6 +23,8 @@ type APIServer struct {
 	db         *store.DB
 	registry   *orchestrator.NodeRegistry
 	idSource   *identity.Source
+	artifacts  artifact.Store
+	limits     artifact.Limits
 }
 
 // New constructs an APIServer. Node/job routes are registered on an inner mux
@@ -45,10 +48,16 @@ type APIServer struct {
 // that also accept SPIFFE (currently node-pubkey enrollment). Both nil (or a
 // degraded idSource) leaves those routes SPIFFE-only — the pre-operator
 // behavior — so the parameters are additive and backward compatible.
-func New(db *store.DB, registry *orchestrator.NodeRegistry, idSource *identity.Source, addr string, metricsAddr string, allowlistPath string, protocolV0 http.Handler, opVerifier operatorVerifier, coordinatorID string) *APIServer {
+//
+// artifacts receives job output uploads (PUT /jobs/{id}/artifacts) within
+// artifactLimits and serves consumer-uploaded job inputs to nodes (GET
+// /jobs/{id}/inputs/{name}); nil answers both 503.
+func New(db *store.DB, registry *orchestrator.NodeRegistry, idSource *identity.Source, addr string, metricsAddr string, allowlistPath string, protocolV0 http.Handler, opVerifier operatorVerifier, coordinatorID string, artifacts artifact.Store, artifactLimits artifact.Limits) *APIServer {
 	// authMux: all routes that require a valid SPIFFE SVID.
 	authMux := http.NewServeMux()
 	registerNodeRoutes(authMux, db, registry)
+	authMux.HandleFunc("PUT /jobs/{id}/artifacts", handleUploadArtifact(db, artifacts, artifactLimits))
+	authMux.HandleFunc("GET /jobs/{id}/inputs/{name}", handleGetJobInput(db, artifacts))
 
 	// top: plain routes + SPIFFE-protected subtree.
 	top := http.NewServeMux()
@@ -78,9 +87,11 @@ func New(db *store.DB, registry *orchestrator.NodeRegistry, idSource *identity.S
 	}
 
 	s := &APIServer{
-		db:       db,
-		registry: registry,
-		idSource: idSource,
+		db:        db,
+		registry:  registry,
+		idSource:  idSource,
+		artifacts: artifacts,
+		limits:    artifactLimits,
 	}
 	s.srv = &http.Server{
 		Addr:         addr,
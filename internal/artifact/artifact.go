@@ -0,0 +1,141 @@
+// Package artifact stores the files that move in and out of jobs. Output
+// artifacts — the tar of a job's declared output directory — are uploaded by
+// the agent to the orchestrator after the container exits and downloaded by
+// the consumer through the portal. Input files go the other way: consumers
+// upload them through the portal, and the orchestrator serves them to the
+// agent for staging into the job's container. Each process reads what the
+// other writes, so both must be configured with the same backend — a shared
+// directory (FSStore) or an S3-compatible bucket (S3Store, for which MinIO
+// stands in on a self-hosted coordinator).
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned by Store.Open for a key with no stored object.
+var ErrNotFound = errors.New("artifact: not found")
+
+// Store is an artifact object store. Keys are slash-separated and generated
+// by UploadKey or InputKey; implementations need not accept arbitrary keys.
+type Store interface {
+	// Put stores size bytes read from r under key, replacing any existing
+	// object. A reader that yields fewer or more than size bytes is an error.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open returns the object stored under key, or ErrNotFound.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object under key. Deleting a missing key is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Key returns the object key jobID's output artifact was stored under before
+// UploadKey; artifacts recorded then still point at it.
+func Key(jobID string) string {
+	return "jobs/" + jobID + "/output.tar"
+}
+
+// UploadKey returns a fresh object key for one upload of jobID's output
+// artifact. The job's artifact row points at the latest upload that verified,
+// so an upload rejected after it was stored never touches the recorded one.
+func UploadKey(jobID string) string {
+	return "jobs/" + jobID + "/output-" + uuid.NewString() + ".tar"
+}
+
+// InputKey returns the object key for a consumer's uploaded job input with
+// digest sha256 (see migration 034). Inputs are content-addressed per
+// consumer, so one upload serves every job that references it.
+func InputKey(participantID, sha256 string) string {
+	return "inputs/" + participantID + "/" + sha256
+}
+
+// Limits bounds what the control plane accepts and how long it keeps it.
+type Limits struct {
+	// MaxBytes caps a single artifact or input upload.
+	MaxBytes int64
+	// QuotaBytes caps the total size of one consumer's unexpired artifacts
+	// and input uploads.
+	QuotaBytes int64
+	// TTL is how long an artifact is downloadable after upload. Input
+	// uploads expire after TTL too, but are kept while an unfinished job
+	// still references them.
+	TTL time.Duration
+}
+
+// DefaultLimits applies when the ARTIFACT_* limit variables are unset.
+var DefaultLimits = Limits{
+	MaxBytes:   1 << 30,
+	QuotaBytes: 10 << 30,
+	TTL:        7 * 24 * time.Hour,
+}
+
+// defaultDir is the FSStore root when ARTIFACT_DIR is unset.
+const defaultDir = "/var/lib/soholink/artifacts"
+
+// FromEnv builds the Store selected by ARTIFACT_STORE:
+//
+//   - "fs" (default): FSStore rooted at ARTIFACT_DIR
+//     (default /var/lib/soholink/artifacts)
+//   - "s3": S3Store on ARTIFACT_S3_ENDPOINT / ARTIFACT_S3_BUCKET, signed with
+//     ARTIFACT_S3_ACCESS_KEY / ARTIFACT_S3_SECRET_KEY in ARTIFACT_S3_REGION
+//     (default us-east-1)
+func FromEnv() (Store, error) {
+	switch backend := os.Getenv("ARTIFACT_STORE"); backend {
+	case "", "fs":
+		dir := os.Getenv("ARTIFACT_DIR")
+		if dir == "" {
+			dir = defaultDir
+		}
+		return NewFSStore(dir)
+	case "s3":
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("ARTIFACT_S3_ENDPOINT"),
+			Region:    os.Getenv("ARTIFACT_S3_REGION"),
+			Bucket:    os.Getenv("ARTIFACT_S3_BUCKET"),
+			AccessKey: os.Getenv("ARTIFACT_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("ARTIFACT_S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("artifact: unknown ARTIFACT_STORE %q (want fs or s3)", backend)
+	}
+}
+
+// LimitsFromEnv reads ARTIFACT_MAX_BYTES, ARTIFACT_QUOTA_BYTES and
+// ARTIFACT_TTL (a Go duration), falling back to DefaultLimits for each one
+// unset.
+func LimitsFromEnv() (Limits, error) {
+	l := DefaultLimits
+	for _, v := range []struct {
+		key string
+		dst *int64
+	}{
+		{"ARTIFACT_MAX_BYTES", &l.MaxBytes},
+		{"ARTIFACT_QUOTA_BYTES", &l.QuotaBytes},
+	} {
+		raw := os.Getenv(v.key)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n <= 0 {
+			return Limits{}, fmt.Errorf("artifact: %s must be a positive byte count, got %q", v.key, raw)
+		}
+		*v.dst = n
+	}
+	if raw := os.Getenv("ARTIFACT_TTL"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return Limits{}, fmt.Errorf("artifact: ARTIFACT_TTL must be a positive duration, got %q", raw)
+		}
+		l.TTL = d
+	}
+	return l, nil
+}
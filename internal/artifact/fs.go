@@ -0,0 +1,95 @@
+package artifact
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FSStore keeps artifacts as files under a root directory. Writes go to a
+// temporary file in the destination directory and are renamed into place, so
+// a reader never sees a partial artifact.
+type FSStore struct {
+	root string
+}
+
+// NewFSStore returns an FSStore rooted at dir, creating it if needed.
+func NewFSStore(dir string) (*FSStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("artifact: create store dir %s: %w", dir, err)
+	}
+	return &FSStore{root: dir}, nil
+}
+
+// path maps key to a file under root, rejecting keys that would escape it.
+func (s *FSStore) path(key string) (string, error) {
+	p := filepath.Join(s.root, filepath.FromSlash(key))
+	if !strings.HasPrefix(p, filepath.Clean(s.root)+string(filepath.Separator)) {
+		return "", fmt.Errorf("artifact: invalid key %q", key)
+	}
+	return p, nil
+}
+
+// Put implements Store.
+func (s *FSStore) Put(_ context.Context, key string, r io.Reader, size int64) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return fmt.Errorf("artifact: put %s: mkdir: %w", key, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("artifact: put %s: create: %w", key, err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op once renamed
+
+	n, err := io.Copy(tmp, io.LimitReader(r, size+1))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("artifact: put %s: write: %w", key, err)
+	}
+	if n != size {
+		return fmt.Errorf("artifact: put %s: got %d bytes, want %d", key, n, size)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("artifact: put %s: rename: %w", key, err)
+	}
+	return nil
+}
+
+// Open implements Store.
+func (s *FSStore) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("artifact: open %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// Delete implements Store.
+func (s *FSStore) Delete(_ context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("artifact: delete %s: %w", key, err)
+	}
+	return nil
+}
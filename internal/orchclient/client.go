@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
 )
 
 const defaultTimeout = 10 * time.Second
@@ -35,10 +38,9 @@ func New(baseURL string) *Client {
 
 // SubmitJob encodes req as JSON, POSTs it to POST {baseURL}/internal/jobs/submit,
 // and decodes the orchestrator.SubmitJobResponse from a 2xx response body.
-// Any non-2xx response is returned as an error containing the HTTP status
-// code and the response body verbatim. Error-class discrimination (validation
-// vs no-nodes vs internal) is deferred — callers treat all errors uniformly
-// for now; see Chat audit note.
+// A non-2xx response is returned as the *orchestrator.SubmitError the
+// listener encoded (see submitError), so callers classify it with
+// orchestrator.SubmitErrorOf exactly as with an in-process Orchestrator.
 func (c *Client) SubmitJob(ctx context.Context, req orchestrator.SubmitJobRequest) (orchestrator.SubmitJobResponse, error) {
 	b, err := json.Marshal(req)
 	if err != nil {
@@ -60,8 +62,7 @@ func (c *Client) SubmitJob(ctx context.Context, req orchestrator.SubmitJobReques
 		return orchestrator.SubmitJobResponse{}, fmt.Errorf("orchclient: read response body: %w", err)
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return orchestrator.SubmitJobResponse{}, fmt.Errorf("orchclient: submit job: status %d: %s",
-			resp.StatusCode, string(body))
+		return orchestrator.SubmitJobResponse{}, submitError(resp.StatusCode, body)
 	}
 	var result orchestrator.SubmitJobResponse
 	if err := json.Unmarshal(body, &result); err != nil {
@@ -69,3 +70,114 @@ func (c *Client) SubmitJob(ctx context.Context, req orchestrator.SubmitJobReques
 	}
 	return result, nil
 }
+
+// submitError reconstructs the *orchestrator.SubmitError in a non-2xx submit
+// response body. A quote rejection wraps orchestrator.ErrInvalidQuote again. A
+// body without a class (a request the listener could not decode) is returned
+// as an error containing the status code and the body verbatim.
+func submitError(status int, body []byte) error {
+	var e struct {
+		Error      string                        `json:"error"`
+		Class      orchestrator.SubmitErrorClass `json:"class"`
+		Reason     string                        `json:"reason"`
+		WantedRung string                        `json:"wanted_rung"`
+		Message    string                        `json:"message"`
+	}
+	if err := json.Unmarshal(body, &e); err != nil || e.Class == "" {
+		return fmt.Errorf("orchclient: submit job: status %d: %s", status, string(body))
+	}
+	detail := strings.TrimPrefix(e.Error, "submit job: ")
+	cause := fmt.Errorf("orchclient: status %d: %s", status, detail)
+	if e.Class == orchestrator.SubmitErrPaymentRequired && e.Reason == orchestrator.ReasonInvalidQuote {
+		cause = fmt.Errorf("orchclient: status %d: %s: %w", status,
+			strings.TrimSuffix(detail, ": "+orchestrator.ErrInvalidQuote.Error()), orchestrator.ErrInvalidQuote)
+	}
+	return &orchestrator.SubmitError{
+		Class:      e.Class,
+		Reason:     e.Reason,
+		WantedRung: e.WantedRung,
+		Message:    e.Message,
+		Err:        cause,
+	}
+}
+
+// EstimateJob encodes req as JSON, POSTs it to POST
+// {baseURL}/internal/jobs/estimate, and decodes the
+// orchestrator.EstimateJobResponse from a 2xx response body. Non-2xx
+// responses are returned as errors, as for SubmitJob.
+func (c *Client) EstimateJob(ctx context.Context, req orchestrator.EstimateJobRequest) (orchestrator.EstimateJobResponse, error) {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return orchestrator.EstimateJobResponse{}, fmt.Errorf("orchclient: marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.baseURL+"/internal/jobs/estimate", bytes.NewReader(b))
+	if err != nil {
+		return orchestrator.EstimateJobResponse{}, fmt.Errorf("orchclient: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return orchestrator.EstimateJobResponse{}, fmt.Errorf("orchclient: do request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return orchestrator.EstimateJobResponse{}, fmt.Errorf("orchclient: read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return orchestrator.EstimateJobResponse{}, fmt.Errorf("orchclient: estimate job: status %d: %s",
+			resp.StatusCode, string(body))
+	}
+	var result orchestrator.EstimateJobResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return orchestrator.EstimateJobResponse{}, fmt.Errorf("orchclient: decode response: %w", err)
+	}
+	return result, nil
+}
+
+// CancelJob POSTs to {baseURL}/internal/jobs/{jobID}/cancel and decodes the
+// orchestrator.CancelJobResponse. A 404 is returned wrapping
+// store.ErrJobNotFound and a 409 wrapping store.ErrJobNotCancellable, with the
+// job's current status in the response's PriorStatus, so callers can tell
+// them apart exactly as with an in-process Orchestrator.
+func (c *Client) CancelJob(ctx context.Context, jobID, consumerID string) (orchestrator.CancelJobResponse, error) {
+	b, err := json.Marshal(map[string]string{"consumer_id": consumerID})
+	if err != nil {
+		return orchestrator.CancelJobResponse{}, fmt.Errorf("orchclient: marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.baseURL+"/internal/jobs/"+url.PathEscape(jobID)+"/cancel", bytes.NewReader(b))
+	if err != nil {
+		return orchestrator.CancelJobResponse{}, fmt.Errorf("orchclient: build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return orchestrator.CancelJobResponse{}, fmt.Errorf("orchclient: do request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return orchestrator.CancelJobResponse{}, fmt.Errorf("orchclient: read response body: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return orchestrator.CancelJobResponse{}, fmt.Errorf("orchclient: cancel job: %w", store.ErrJobNotFound)
+	case resp.StatusCode == http.StatusConflict:
+		var conflict struct {
+			CurrentStatus string `json:"current_status"`
+		}
+		_ = json.Unmarshal(body, &conflict)
+		return orchestrator.CancelJobResponse{JobID: jobID, PriorStatus: conflict.CurrentStatus},
+			fmt.Errorf("orchclient: cancel job: %w", store.ErrJobNotCancellable)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return orchestrator.CancelJobResponse{}, fmt.Errorf("orchclient: cancel job: status %d: %s",
+			resp.StatusCode, string(body))
+	}
+	var result orchestrator.CancelJobResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return orchestrator.CancelJobResponse{}, fmt.Errorf("orchclient: decode response: %w", err)
+	}
+	return result, nil
+}
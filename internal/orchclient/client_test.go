@@ -3,12 +3,14 @@ package orchclient
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
 )
 
@@ -89,6 +91,48 @@ func TestSubmitJob_ServerReturns400(t *testing.T) {
 	}
 }
 
+func TestSubmitJob_ClassifiedError(t *testing.T) {
+	respond := func(status int, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(body)) //nolint:errcheck
+		}))
+	}
+
+	srv := respond(http.StatusServiceUnavailable,
+		`{"error":"submit job: find nodes: no available nodes match request","class":"no_capacity",`+
+			`"reason":"no_capacity","wanted_rung":"congestus","message":"no node has room for the job right now"}`)
+	defer srv.Close()
+	_, err := New(srv.URL).SubmitJob(context.Background(), orchestrator.SubmitJobRequest{ConsumerID: "participant-1"})
+	var se *orchestrator.SubmitError
+	if !errors.As(err, &se) {
+		t.Fatalf("err = %v, want a *orchestrator.SubmitError", err)
+	}
+	if se.Class != orchestrator.SubmitErrNoCapacity || se.Reason != "no_capacity" || se.WantedRung != "congestus" ||
+		se.Message != "no node has room for the job right now" {
+		t.Errorf("reconstructed %+v", se)
+	}
+	if want := "submit job: orchclient: status 503: find nodes: no available nodes match request"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	quoteSrv := respond(http.StatusPaymentRequired,
+		`{"error":"submit job: verify quote token: quote expired: invalid price quote","class":"payment_required",`+
+			`"reason":"invalid_quote","message":"request a new estimate and resubmit"}`)
+	defer quoteSrv.Close()
+	_, err = New(quoteSrv.URL).SubmitJob(context.Background(), orchestrator.SubmitJobRequest{ConsumerID: "participant-1"})
+	if !errors.Is(err, orchestrator.ErrInvalidQuote) {
+		t.Errorf("quote rejection %v does not wrap ErrInvalidQuote", err)
+	}
+	if se := orchestrator.SubmitErrorOf(err); se.Class != orchestrator.SubmitErrPaymentRequired {
+		t.Errorf("class = %s, want payment_required", se.Class)
+	}
+	if strings.Count(err.Error(), "invalid price quote") != 1 {
+		t.Errorf("Error() = %q repeats the sentinel", err.Error())
+	}
+}
+
 func TestSubmitJob_NetworkError(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }))
 	url := srv.URL
@@ -111,3 +155,88 @@ func TestSubmitJob_ContextCancelled(t *testing.T) {
 		t.Fatal("expected error on cancelled context, got nil")
 	}
 }
+
+func TestCancelJob_HappyPath(t *testing.T) {
+	want := orchestrator.CancelJobResponse{JobID: "job-xyz", PriorStatus: "running", ChargedCents: 25, PaymentIntentID: "pi_1", RefundCents: 75}
+	var gotBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/internal/jobs/job-xyz/cancel" {
+			t.Errorf("request: want POST /internal/jobs/job-xyz/cancel, got %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotBody); err != nil {
+			t.Fatalf("decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(want) //nolint:errcheck
+	}))
+	defer srv.Close()
+	got, err := New(srv.URL).CancelJob(context.Background(), "job-xyz", "participant-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("response = %+v, want %+v", got, want)
+	}
+	if gotBody["consumer_id"] != "participant-1" {
+		t.Errorf("consumer_id forwarded: got %q", gotBody["consumer_id"])
+	}
+}
+
+func TestCancelJob_ErrorClasses(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   error
+		prior  string
+	}{
+		{http.StatusNotFound, `{"error":"job not found"}`, store.ErrJobNotFound, ""},
+		{http.StatusConflict, `{"error":"job is not cancellable","current_status":"completed"}`, store.ErrJobNotCancellable, "completed"},
+	}
+	for _, c := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(c.status)
+			w.Write([]byte(c.body)) //nolint:errcheck
+		}))
+		got, err := New(srv.URL).CancelJob(context.Background(), "job-xyz", "participant-1")
+		srv.Close()
+		if !errors.Is(err, c.want) {
+			t.Errorf("status %d: error = %v, want %v", c.status, err, c.want)
+		}
+		if got.PriorStatus != c.prior {
+			t.Errorf("status %d: PriorStatus = %q, want %q", c.status, got.PriorStatus, c.prior)
+		}
+	}
+}
+
+func TestEstimateJob_HappyPath(t *testing.T) {
+	want := orchestrator.EstimateJobResponse{
+		Candidates: []orchestrator.NodeEstimate{{NodeID: "node-42", PriceMultiplier: 1.1, LowCents: 20, HighCents: 80}},
+		Replicas:   1, LowCents: 20, HighCents: 80, QuoteToken: "quote-token",
+	}
+	var gotReq orchestrator.EstimateJobRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/internal/jobs/estimate" {
+			t.Errorf("request: want POST /internal/jobs/estimate, got %s %s", r.Method, r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			t.Fatalf("decode request body: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(want) //nolint:errcheck
+	}))
+	defer srv.Close()
+	req := orchestrator.EstimateJobRequest{
+		SubmitJobRequest:       orchestrator.SubmitJobRequest{ConsumerID: "participant-1", WorkloadType: types.MarketplaceBatchCompute, CPUCores: 2, RAMMB: 1024},
+		ExpectedRuntimeSeconds: 300,
+	}
+	got, err := New(srv.URL).EstimateJob(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.QuoteToken != want.QuoteToken || len(got.Candidates) != 1 || got.Candidates[0].NodeID != "node-42" {
+		t.Errorf("response = %+v, want %+v", got, want)
+	}
+	if gotReq.ConsumerID != "participant-1" || gotReq.ExpectedRuntimeSeconds != 300 {
+		t.Errorf("request forwarded = %+v", gotReq)
+	}
+}
@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// CancelJobResponse reports a consumer cancellation. RefundCents is owed back
+// against PaymentIntentID; the portal, which holds the payment client, issues
+// it and records it with store.MarkRefunded. An empty PaymentIntentID means
+// the job carried no charge and nothing is owed. For an escrowed job
+// (Escrowed) the portal issues nothing: RefundCents is the share of the hold
+// the escrow settler will release.
+type CancelJobResponse struct {
+	JobID           string
+	PriorStatus     string
+	ChargedCents    int64
+	PaymentIntentID string
+	RefundCents     int64
+	Escrowed        bool
+}
+
+// CancelJob cancels consumerID's job jobID from any status before its work is
+// done (see store.CancelJob). Node placements the job (or its replicas) held
+// are released; runs that had started are metered for the time they ran, and
+// their agents stop the containers on the next heartbeat. The refund owed is
+// the job's charge less that metered cost.
+//
+// Returns store.ErrJobNotFound, or store.ErrJobNotCancellable with
+// PriorStatus set to the job's current status. Once the cancellation is
+// committed, metering and refund bookkeeping failures are logged rather than
+// returned: the job is cancelled either way.
+func (o *Orchestrator) CancelJob(ctx context.Context, jobID, consumerID string) (CancelJobResponse, error) {
+	c, err := store.CancelJob(ctx, o.db, jobID, consumerID)
+	if err != nil {
+		return CancelJobResponse{JobID: jobID, PriorStatus: c.PriorStatus}, err
+	}
+	resp := CancelJobResponse{JobID: jobID, PriorStatus: c.PriorStatus}
+
+	for _, j := range c.Jobs {
+		if j.HoldsNode() {
+			o.registry.Release(j.NodeID, j.JobID)
+		}
+		if j.Started {
+			if err := store.ComputeMetering(ctx, o.db, j.JobID); err != nil {
+				slog.Error("cancel: meter partial run", "job_id", j.JobID, "error", err)
+			}
+		}
+	}
+
+	refund, err := store.SettleCancelRefund(ctx, o.db, jobID)
+	if err != nil {
+		slog.Error("cancel: settle refund", "job_id", jobID, "error", err)
+		return resp, nil
+	}
+	resp.ChargedCents = refund.ChargedCents
+	resp.PaymentIntentID = refund.PaymentIntentID
+	resp.RefundCents = refund.RefundCents
+	resp.Escrowed = refund.Escrowed
+	slog.Info("job cancelled by consumer", "job_id", jobID, "prior_status", c.PriorStatus,
+		"charged_cents", resp.ChargedCents, "refund_cents", resp.RefundCents)
+	return resp, nil
+}
@@ -0,0 +1,143 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// paymentWindow is how long an escrowed job may wait for its consumer to
+// authorize payment before it is failed and its node released.
+const paymentWindow = time.Hour
+
+// EscrowMaxRuntime is the longest an escrowed job may run. A card
+// authorization lapses about 7 days after it is made and can then no longer
+// be captured; 5 days of runtime leaves the rest for the payment window,
+// dispatch, metering and the escrow settler. A hold that still cannot be
+// settled is left to staff (store.RecordEscrowSettleFailure).
+const EscrowMaxRuntime = 5 * 24 * time.Hour
+
+// quoteEscrow returns an escrowed job's maximum cost across the nodes it was
+// placed on — one quote per replica, honoring the job's price quote if it has
+// one — or zero for a job without escrow.
+func (o *Orchestrator) quoteEscrow(ctx context.Context, req SubmitJobRequest, nodes []NodeEntry, pq *PriceQuote) (int64, error) {
+	if !req.Escrow {
+		return 0, nil
+	}
+	maxRuntime := time.Duration(req.maxRuntimeSeconds()) * time.Second
+	var total int64
+	for _, n := range nodes {
+		var pin store.PricePin
+		if pq != nil {
+			pin = pq.pin(n.NodeID)
+		}
+		q, err := store.QuoteJob(ctx, o.db, n.NodeID, req.resources(), maxRuntime, pin)
+		if err != nil {
+			return 0, fmt.Errorf("quote escrow: %w", err)
+		}
+		total += q.TotalCents
+	}
+	return total, nil
+}
+
+// ExpireUnpaid fails an escrowed job — with its replicas — whose payment was
+// not authorized within paymentWindow of submission, with failure_cause
+// "payment_not_authorized", and releases the nodes it held. The escrow
+// settler then cancels the hold, unless Stripe already has
+// (store.ReleaseEscrowByIntent). Returns expired=true when the job was
+// actually changed; expired=false with err=nil is the lost-race case (the
+// payment was authorized between the caller's SELECT and this UPDATE).
+func (o *Orchestrator) ExpireUnpaid(ctx context.Context, jobID string) (bool, error) {
+	rows, err := o.db.Pool.Query(ctx,
+		`WITH unpaid AS (
+		     SELECT id FROM jobs
+		     WHERE id = $1
+		       AND parent_job_id IS NULL
+		       AND payment_status IN ($3, $5)
+		       AND status = 'scheduled'::job_status
+		       AND created_at < NOW() - $4 * INTERVAL '1 second'
+		     FOR UPDATE
+		 )
+		 UPDATE jobs j
+		 SET status        = 'failed'::job_status,
+		     failure_cause = $2,
+		     completed_at  = NOW(),
+		     updated_at    = NOW()
+		 FROM unpaid
+		 WHERE (j.id = unpaid.id OR j.parent_job_id = unpaid.id)
+		   AND j.status = 'scheduled'::job_status
+		 RETURNING j.id::text, COALESCE(j.node_id::text, '')`,
+		jobID, store.FailureCausePaymentNotAuthorized, store.PaymentAwaiting,
+		int(paymentWindow/time.Second), store.PaymentReleased,
+	)
+	if err != nil {
+		return false, fmt.Errorf("expire unpaid: update job %s: %w", jobID, err)
+	}
+	var expired bool
+	for rows.Next() {
+		var id, nodeID string
+		if err := rows.Scan(&id, &nodeID); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("expire unpaid: scan job %s: %w", jobID, err)
+		}
+		expired = true
+		if nodeID != "" {
+			o.registry.Release(nodeID, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("expire unpaid: rows for job %s: %w", jobID, err)
+	}
+	return expired, nil
+}
+
+// expireUnpaid finds escrowed jobs still awaiting payment after
+// paymentWindow and fails each via ExpireUnpaid. Called on every tick of
+// StartDeclineRerouteLoop, after reapTimedOut and before rerouteDeclined.
+func (o *Orchestrator) expireUnpaid(ctx context.Context) {
+	rows, err := o.db.Pool.Query(ctx,
+		`SELECT id FROM jobs
+		 WHERE parent_job_id IS NULL
+		   AND payment_status IN ($1, $3)
+		   AND status = 'scheduled'::job_status
+		   AND created_at < NOW() - $2 * INTERVAL '1 second'
+		 LIMIT 100`,
+		store.PaymentAwaiting, int(paymentWindow/time.Second), store.PaymentReleased,
+	)
+	if err != nil {
+		slog.Error("expire unpaid: query awaiting jobs", "error", err)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			slog.Error("expire unpaid: scan job id", "error", err)
+			rows.Close()
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		slog.Error("expire unpaid: rows", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		expired, err := o.ExpireUnpaid(ctx, id)
+		if err != nil {
+			slog.Error("expire unpaid: update job", "job_id", id, "error", err)
+			continue
+		}
+		if expired {
+			slog.Info("escrowed job never paid — failed as payment_not_authorized", "job_id", id)
+		} else {
+			slog.Debug("expire unpaid lost race", "job_id", id)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// This file holds job input staging (migration 034). Inputs are recorded in
+// job_inputs alongside the job row; the node receives them with the job's
+// assignment and stages them under agent.InputDir before ContainerStart.
+
+// MaxJobInputs caps the number of inputs one job may declare.
+const MaxJobInputs = 16
+
+// JobInput is a file staged read-only into the job's container as
+// agent.InputDir/Name. With URL empty, SHA256 names a file the consumer
+// already uploaded (store.InputUpload); otherwise the node downloads URL
+// itself and SHA256 is the digest the download must match.
+type JobInput struct {
+	Name   string
+	SHA256 string
+	URL    string
+}
+
+var (
+	// inputNamePattern matches a bare file name, mirroring the job_inputs
+	// CHECK constraint.
+	inputNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+	// inputSHA256Pattern matches a lowercase hex SHA-256 digest.
+	inputSHA256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+// validateInputs checks the shape of a request's inputs; whether each upload
+// exists is checked when the rows are inserted.
+func validateInputs(inputs []JobInput) error {
+	if len(inputs) > MaxJobInputs {
+		return fmt.Errorf("at most %d Inputs are allowed", MaxJobInputs)
+	}
+	seen := make(map[string]bool, len(inputs))
+	for _, in := range inputs {
+		if !inputNamePattern.MatchString(in.Name) || in.Name == "." || in.Name == ".." {
+			return fmt.Errorf("input name %q must be a file name of letters, digits, '.', '_' or '-'", in.Name)
+		}
+		if seen[in.Name] {
+			return fmt.Errorf("duplicate input name %q", in.Name)
+		}
+		seen[in.Name] = true
+		if !inputSHA256Pattern.MatchString(in.SHA256) {
+			return fmt.Errorf("input %q: SHA256 must be a lowercase hex SHA-256 digest", in.Name)
+		}
+		if in.URL != "" {
+			u, err := url.Parse(in.URL)
+			if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+				return fmt.Errorf("input %q: URL must be an https URL without credentials", in.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// insertJobInputs records jobID's inputs within tx. An upload input is
+// resolved against consumerID's live input_uploads; one that is missing or
+// expired fails the insert with store.ErrInputNotFound.
+func insertJobInputs(ctx context.Context, tx pgx.Tx, jobID, consumerID string, inputs []JobInput) error {
+	for _, in := range inputs {
+		if in.URL != "" {
+			if _, err := tx.Exec(ctx,
+				`INSERT INTO job_inputs (job_id, name, sha256, source_url) VALUES ($1, $2, $3, $4)`,
+				jobID, in.Name, in.SHA256, in.URL,
+			); err != nil {
+				return fmt.Errorf("insert input %q: %w", in.Name, err)
+			}
+			continue
+		}
+		ct, err := tx.Exec(ctx,
+			`INSERT INTO job_inputs (job_id, name, sha256, size_bytes)
+			 SELECT $1, $2, sha256, size_bytes
+			 FROM input_uploads
+			 WHERE participant_id = $3 AND sha256 = $4
+			   AND deleted_at IS NULL AND expires_at > NOW()`,
+			jobID, in.Name, consumerID, in.SHA256,
+		)
+		if err != nil {
+			return fmt.Errorf("insert input %q: %w", in.Name, err)
+		}
+		if ct.RowsAffected() == 0 {
+			return fmt.Errorf("input %q: %w", in.Name, store.ErrInputNotFound)
+		}
+	}
+	return nil
+}
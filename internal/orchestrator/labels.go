@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
+)
+
+// This file matches jobs to node labels and taints (migration 047). A node's
+// labels describe it and a job's Placement.NodeSelector requires them; a
+// node's taints reserve it, and only a job whose Placement.Tolerations cover
+// every one of them is placed there. In a selector term or a toleration an
+// empty value matches any value of the key.
+
+// Toleration lets a job run on nodes carrying the taint Key, with any value
+// when Value is empty or with exactly Value otherwise.
+type Toleration struct {
+	Key   string
+	Value string
+}
+
+// String renders t as stored in jobs.tolerations: "key" or "key=value".
+func (t Toleration) String() string {
+	if t.Value == "" {
+		return t.Key
+	}
+	return t.Key + "=" + t.Value
+}
+
+// parseToleration is the inverse of Toleration.String.
+func parseToleration(s string) Toleration {
+	k, v, _ := strings.Cut(s, "=")
+	return Toleration{Key: k, Value: v}
+}
+
+// tolerates reports whether t covers the taint key=value.
+func (t Toleration) tolerates(key, value string) bool {
+	return t.Key == key && (t.Value == "" || t.Value == value)
+}
+
+// selectedBy reports whether n carries every label of selector.
+func (n NodeEntry) selectedBy(selector map[string]string) bool {
+	for k, want := range selector {
+		got, ok := n.Labels[k]
+		if !ok || (want != "" && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
+// toleratedBy reports whether tolerations cover every taint on n.
+func (n NodeEntry) toleratedBy(tolerations []Toleration) bool {
+	for k, v := range n.Taints {
+		if !slices.ContainsFunc(tolerations, func(t Toleration) bool { return t.tolerates(k, v) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSelectorAndTolerations checks the label terms of a Placement.
+func validateSelectorAndTolerations(selector map[string]string, tolerations []Toleration) error {
+	if err := types.ValidateNodeLabels(selector); err != nil {
+		return fmt.Errorf("Placement.NodeSelector: %w", err)
+	}
+	if len(tolerations) > types.MaxNodeLabels {
+		return fmt.Errorf("Placement.Tolerations may list at most %d taints", types.MaxNodeLabels)
+	}
+	for _, t := range tolerations {
+		if err := types.ValidateNodeLabel(t.Key, t.Value); err != nil {
+			return fmt.Errorf("Placement.Tolerations: %w", err)
+		}
+	}
+	return nil
+}
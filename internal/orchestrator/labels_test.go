@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindMatch_NodeSelectorAndTaints(t *testing.T) {
+	r := NewNodeRegistry()
+	plain := newOnlineNode("node-plain", "US", 8, 16384, 100, false)
+	r.Register(plain)
+	ssd := newOnlineNode("node-ssd", "US", 8, 16384, 100, false)
+	ssd.Labels = map[string]string{"ssd": "", "zone": "attic"}
+	r.Register(ssd)
+	reserved := newOnlineNode("node-reserved", "US", 8, 16384, 100, false)
+	reserved.Labels = map[string]string{"ssd": "", "zone": "cellar"}
+	reserved.Taints = map[string]string{"office-hours-only": "", "tenant": "acme"}
+	r.Register(reserved)
+
+	cases := []struct {
+		name string
+		req  MatchRequest
+		want string
+	}{
+		{"untainted nodes only", MatchRequest{}, "node-plain,node-ssd"},
+		{"key selector", MatchRequest{NodeSelector: map[string]string{"ssd": ""}}, "node-ssd"},
+		{"value selector", MatchRequest{NodeSelector: map[string]string{"zone": "attic"}}, "node-ssd"},
+		{
+			"every taint tolerated",
+			MatchRequest{Tolerations: []Toleration{{Key: "office-hours-only"}, {Key: "tenant", Value: "acme"}}},
+			"node-plain,node-reserved,node-ssd",
+		},
+		{
+			"selector and tolerations together",
+			MatchRequest{NodeSelector: map[string]string{"zone": "cellar"}, Tolerations: []Toleration{{Key: "office-hours-only"}, {Key: "tenant"}}},
+			"node-reserved",
+		},
+		{"one taint untolerated", MatchRequest{Tolerations: []Toleration{{Key: "office-hours-only"}}}, "node-plain,node-ssd"},
+		{"wrong taint value", MatchRequest{Tolerations: []Toleration{{Key: "office-hours-only"}, {Key: "tenant", Value: "other"}}}, "node-plain,node-ssd"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := r.FindMatch(tc.req)
+			if err != nil {
+				t.Fatalf("FindMatch: %v", err)
+			}
+			var ids []string
+			for _, m := range matches {
+				ids = append(ids, m.NodeID)
+			}
+			slices.Sort(ids)
+			if got := strings.Join(ids, ","); got != tc.want {
+				t.Errorf("matches = %s, want %s", got, tc.want)
+			}
+		})
+	}
+
+	if _, err := r.FindMatch(MatchRequest{NodeSelector: map[string]string{"gpu": ""}}); err == nil {
+		t.Error("selector no node carries: expected no match")
+	}
+}
+
+func TestNodeRegistry_UpdateLabels(t *testing.T) {
+	r := NewNodeRegistry()
+	if err := r.UpdateLabels("node-missing", NodeLabelState{}); err == nil {
+		t.Error("UpdateLabels on an unregistered node: expected error")
+	}
+	r.Register(newOnlineNode("node-1", "US", 8, 16384, 100, false))
+	if err := r.UpdateLabels("node-1", NodeLabelState{Taints: map[string]string{"ups-backed": ""}}); err != nil {
+		t.Fatalf("UpdateLabels: %v", err)
+	}
+	if _, err := r.FindMatch(MatchRequest{}); err == nil {
+		t.Error("tainted node matched a job without tolerations")
+	}
+}
+
+func TestToleration_StringRoundTrips(t *testing.T) {
+	for _, tol := range []Toleration{{Key: "office-hours-only"}, {Key: "tenant", Value: "acme"}} {
+		if got := parseToleration(tol.String()); got != tol {
+			t.Errorf("parseToleration(%q) = %+v, want %+v", tol.String(), got, tol)
+		}
+	}
+}
+
+func TestPlacement_HardTerms(t *testing.T) {
+	p := Placement{NodeSelector: map[string]string{"ssd": ""}}
+	if got := p.hardTerms(); got != "node selector" {
+		t.Errorf("hardTerms = %q", got)
+	}
+	p.ExcludedOwnerIDs = []string{testOwnerA}
+	p.AntiAffinity, p.AntiAffinityRequired = AntiAffinityOwner, true
+	if got := p.hardTerms(); got != "node selector, excluded owners and anti-affinity" {
+		t.Errorf("hardTerms = %q", got)
+	}
+	if (Placement{Tolerations: []Toleration{{Key: "ssd"}}}).hard() {
+		t.Error("hard() = true for tolerations alone, which only widen placement")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -38,10 +40,19 @@ const (
 // locality score. RequesterCountry is NEVER copied into
 // MatchRequest.CountryConstraint — the hard residency filter stays a
 // consumer-stated constraint, not an inferred one.
+//
+// The remaining fields are the consumer's soft placement constraints (see
+// Placement): nodes and owners to rank first, and the owners and regions of
+// the consumer's other active jobs, to rank last.
 type PlacementContext struct {
 	RequesterParticipantID string
 	RequesterRegion        string
 	RequesterCountry       string
+
+	PreferredNodeIDs        []string
+	PreferredParticipantIDs []string
+	AvoidParticipantIDs     []string
+	AvoidRegions            []string
 }
 
 // ScheduleFunc scores and ranks a candidate list, returning the top N nodes
@@ -60,6 +71,116 @@ type SubmitJobRequest struct {
 	RAMMB             int
 	GPURequired       bool
 	StorageGB         int
+
+	// GPUVRAMGB is the GPU memory the job reserves — the billing quantity for
+	// gpu_vram_gb_hr. Only meaningful with GPURequired; zero bills no GPU time.
+	GPUVRAMGB int
+
+	// SLATier is the number of replicas to place, each on a distinct node
+	// with a distinct owner (zero means SLAStandard). Above Standard the job
+	// is a replica group — see submitReplicated.
+	SLATier SLATier
+
+	// Quorum is how many replicas must succeed for the job to complete.
+	// Zero means 1 (2 with Verify): the first successful replica completes
+	// the job.
+	Quorum int
+
+	// OutputPath declares that the job produces output: agent.OutputDir
+	// itself, or a file under it naming the job's result. Either way the
+	// node mounts agent.OutputDir writable and uploads its contents as the
+	// job's artifact; for a file, the agent also reports the file's SHA-256
+	// with the job's completion.
+	OutputPath string
+
+	// Verify requests redundant execution with result-hash verification:
+	// the quorum of replicas must report the same OutputPath hash, or the
+	// job is disputed and the odd replicas are flagged suspected fraud.
+	// batch_compute only; requires SLATier of at least SLAReliable.
+	Verify bool
+
+	// Inputs are files staged read-only under agent.InputDir before the
+	// container starts — a print job's document or G-code, a batch job's
+	// dataset. See JobInput.
+	Inputs []JobInput
+
+	// MaxRuntimeSeconds limits the job's run, from its start: the agent
+	// stops the container when it is reached and the job fails with
+	// failure_cause "timeout". Zero means the workload type's ceiling
+	// (MaxRuntimeCeiling), which is also the most that may be declared.
+	MaxRuntimeSeconds int
+
+	// Escrow holds the consumer's payment from submission to settlement
+	// (migration 038): the job is quoted at its maximum cost (QuoteCents in
+	// the response) and is not dispatched until a hold for it is authorized
+	// (store.AuthorizeJobPayment). The hold must still be capturable when
+	// the job settles, so an escrowed job runs for at most EscrowMaxRuntime.
+	// Not supported for print workloads, whose metering is deferred to C9.
+	Escrow bool
+
+	// QuoteToken is a price quote from EstimateJob for this request: while
+	// it is valid the job is priced as quoted (see quote.go). A token that
+	// has expired, or was issued for another consumer or request, fails the
+	// submission with ErrInvalidQuote.
+	QuoteToken string
+
+	// Placement constrains which nodes may take the job and which are ranked
+	// first; see placement.go.
+	Placement Placement
+}
+
+// tier returns the effective SLA tier (zero value → SLAStandard).
+func (r SubmitJobRequest) tier() SLATier {
+	if r.SLATier == 0 {
+		return SLAStandard
+	}
+	return r.SLATier
+}
+
+// quorum returns the effective replica quorum (zero value → 1, or 2 for a
+// verified job so there are hashes to compare).
+func (r SubmitJobRequest) quorum() int {
+	switch {
+	case r.Quorum != 0:
+		return r.Quorum
+	case r.Verify:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// maxRuntimeSeconds returns the effective runtime limit (zero value → the
+// workload type's ceiling, or EscrowMaxRuntime if lower and escrowed).
+func (r SubmitJobRequest) maxRuntimeSeconds() int {
+	if r.MaxRuntimeSeconds != 0 {
+		return r.MaxRuntimeSeconds
+	}
+	ceiling := MaxRuntimeCeiling(r.WorkloadType)
+	if r.Escrow {
+		ceiling = min(ceiling, EscrowMaxRuntime)
+	}
+	return int(ceiling / time.Second)
+}
+
+// resources returns the resources the job requests, for pricing.
+func (r SubmitJobRequest) resources() store.ResourceRequest {
+	return store.ResourceRequest{
+		CPUCores:    r.CPUCores,
+		RAMMB:       r.RAMMB,
+		StorageGB:   r.StorageGB,
+		GPURequired: r.GPURequired,
+		GPUVRAMGB:   r.GPUVRAMGB,
+	}
+}
+
+// paymentStatus returns the job's initial jobs.payment_status: awaiting
+// payment when escrowed, otherwise none ("").
+func (r SubmitJobRequest) paymentStatus() string {
+	if r.Escrow {
+		return store.PaymentAwaiting
+	}
+	return ""
 }
 
 // Validate checks all required fields and returns the first error found.
@@ -73,15 +194,91 @@ func (r SubmitJobRequest) Validate() error {
 	if !r.WorkloadType.IsValid() {
 		return fmt.Errorf("unknown WorkloadType %q", r.WorkloadType)
 	}
-	return nil
+	if r.StorageGB < 0 {
+		return fmt.Errorf("StorageGB must not be negative")
+	}
+	if r.GPUVRAMGB < 0 {
+		return fmt.Errorf("GPUVRAMGB must not be negative")
+	}
+	if r.GPUVRAMGB > 0 && !r.GPURequired {
+		return fmt.Errorf("GPUVRAMGB requires GPURequired")
+	}
+	if r.MaxRuntimeSeconds < 0 {
+		return fmt.Errorf("MaxRuntimeSeconds must not be negative")
+	}
+	if ceiling := MaxRuntimeCeiling(r.WorkloadType); time.Duration(r.MaxRuntimeSeconds)*time.Second > ceiling {
+		return fmt.Errorf("MaxRuntimeSeconds exceeds the %s ceiling of %d", r.WorkloadType, int(ceiling/time.Second))
+	}
+	if r.SLATier < 0 || r.SLATier > SLAPremium {
+		return fmt.Errorf("SLATier must be between %d and %d", SLAStandard, SLAPremium)
+	}
+	if r.Quorum < 0 || r.Quorum > int(r.tier()) {
+		return fmt.Errorf("Quorum must be between 1 and SLATier (%d)", r.tier())
+	}
+	if r.tier() > SLAStandard &&
+		(r.WorkloadType == types.MarketplacePrintTraditional || r.WorkloadType == types.MarketplacePrint3D) {
+		return fmt.Errorf("SLATier above Standard is not supported for print workloads")
+	}
+	if r.Escrow &&
+		(r.WorkloadType == types.MarketplacePrintTraditional || r.WorkloadType == types.MarketplacePrint3D) {
+		return fmt.Errorf("Escrow is not supported for print workloads")
+	}
+	if r.Escrow && time.Duration(r.MaxRuntimeSeconds)*time.Second > EscrowMaxRuntime {
+		return fmt.Errorf("MaxRuntimeSeconds exceeds the escrow limit of %d: a payment hold lapses after about 7 days", int(EscrowMaxRuntime/time.Second))
+	}
+	if r.OutputPath != "" {
+		if p := path.Clean(r.OutputPath); p != r.OutputPath ||
+			(p != agent.OutputDir && !strings.HasPrefix(p, agent.OutputDir+"/")) {
+			return fmt.Errorf("OutputPath must be %s or a clean path under it", agent.OutputDir)
+		}
+	}
+	if r.Verify {
+		if r.WorkloadType != types.MarketplaceBatchCompute {
+			return fmt.Errorf("Verify is only supported for %s", types.MarketplaceBatchCompute)
+		}
+		if r.tier() < SLAReliable {
+			return fmt.Errorf("Verify requires SLATier of at least %d", SLAReliable)
+		}
+		if r.quorum() < 2 {
+			return fmt.Errorf("Verify requires a Quorum of at least 2")
+		}
+		if r.OutputPath == "" || r.OutputPath == agent.OutputDir {
+			return fmt.Errorf("Verify requires OutputPath to name a file under %s", agent.OutputDir)
+		}
+	}
+	if err := validateInputs(r.Inputs); err != nil {
+		return err
+	}
+	return r.Placement.validate(r.tier())
+}
+
+// matchRequest returns the MatchRequest for the job's shape, before its
+// placement constraints are applied.
+func (r SubmitJobRequest) matchRequest() MatchRequest {
+	return MatchRequest{
+		WorkloadType:                 r.WorkloadType,
+		CountryConstraint:            r.CountryConstraint,
+		CPUCores:                     r.CPUCores,
+		RAMMB:                        r.RAMMB,
+		GPURequired:                  r.GPURequired,
+		StorageGB:                    r.StorageGB,
+		ExcludeConsumerParticipantID: r.ConsumerID,
+	}
 }
 
 // SubmitJobResponse carries the placement result returned to the consumer.
+// For a replicated job (SLATier above Standard) JobID is the replica group's
+// parent job, NodeID, JobToken and ProviderStripeAccountID are empty, and
+// Replicas lists each replica's placement. QuoteCents is an escrowed job's
+// maximum cost, every replica included; the caller creates the payment hold
+// for it and attaches it with store.AttachJobPayment.
 type SubmitJobResponse struct {
 	JobID                   string
 	NodeID                  string
 	JobToken                string
 	ProviderStripeAccountID string
+	Replicas                []ReplicaPlacement
+	QuoteCents              int64
 }
 
 // Orchestrator coordinates job placement across the node registry and database.
@@ -160,10 +357,21 @@ func loadAllowlist(path string) (*agent.Allowlist, error) {
 
 // SubmitJob validates the request, finds matching nodes, runs them through
 // the scheduler, writes the job to PostgreSQL, generates a signed job token,
-// and returns the placement result.
+// and returns the placement result. A request with a QuoteToken is priced as
+// the quote commits to. A rejection the consumer can act on is returned as a
+// *SubmitError; see SubmitErrorOf.
 func (o *Orchestrator) SubmitJob(ctx context.Context, req SubmitJobRequest) (SubmitJobResponse, error) {
 	if err := req.Validate(); err != nil {
-		return SubmitJobResponse{}, fmt.Errorf("submit job: %w", err)
+		return SubmitJobResponse{}, &SubmitError{Class: SubmitErrValidation, Message: err.Error(), Err: err}
+	}
+	pq, err := o.verifyQuote(req)
+	if err != nil {
+		return SubmitJobResponse{}, &SubmitError{
+			Class:   SubmitErrPaymentRequired,
+			Reason:  ReasonInvalidQuote,
+			Message: "the price quote is invalid or has expired; request a new estimate and resubmit",
+			Err:     err,
+		}
 	}
 
 	// Defense 3 (B7 commit 5): verify marketplace workload type, mapping,
@@ -175,7 +383,11 @@ func (o *Orchestrator) SubmitJob(ctx context.Context, req SubmitJobRequest) (Sub
 	}
 	entry, err := al.Lookup(req.ContainerImage)
 	if err != nil {
-		return SubmitJobResponse{}, fmt.Errorf("submit job: image not in allowlist: %w", err)
+		return SubmitJobResponse{}, &SubmitError{
+			Class:   SubmitErrImageNotAllowlisted,
+			Message: fmt.Sprintf("container image %s is not on the workload allowlist; choose an allowlisted image", req.ContainerImage),
+			Err:     fmt.Errorf("image not in allowlist: %w", err),
+		}
 	}
 	expectedAgentType, ok := marketplaceToAgent[req.WorkloadType]
 	if !ok {
@@ -185,32 +397,54 @@ func (o *Orchestrator) SubmitJob(ctx context.Context, req SubmitJobRequest) (Sub
 		return SubmitJobResponse{}, fmt.Errorf("submit job: no mapping for workload type %q", req.WorkloadType)
 	}
 	if entry.Type != expectedAgentType {
-		return SubmitJobResponse{}, fmt.Errorf("submit job: workload type mismatch: marketplace=%s maps to agent=%s, but allowlist entry for %s declares agent=%s",
-			req.WorkloadType, expectedAgentType, req.ContainerImage, entry.Type)
-	}
-
-	candidates, err := o.registry.FindMatch(MatchRequest{
-		WorkloadType:                 req.WorkloadType,
-		CountryConstraint:            req.CountryConstraint,
-		CPUCores:                     req.CPUCores,
-		RAMMB:                        req.RAMMB,
-		GPURequired:                  req.GPURequired,
-		StorageGB:                    req.StorageGB,
-		ExcludeConsumerParticipantID: req.ConsumerID,
-	})
+		return SubmitJobResponse{}, &SubmitError{
+			Class: SubmitErrTypeMismatch,
+			Message: fmt.Sprintf("container image %s is allowlisted for %s workloads, not %s; choose a matching workload type or image",
+				req.ContainerImage, entry.Type, req.WorkloadType),
+			Err: fmt.Errorf("workload type mismatch: marketplace=%s maps to agent=%s, but allowlist entry for %s declares agent=%s",
+				req.WorkloadType, expectedAgentType, req.ContainerImage, entry.Type),
+		}
+	}
+
+	match, pctx, err := o.placementFor(ctx, req.matchRequest(), req.ConsumerID, "", req.Placement)
+	if err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("submit job: %w", err)
+	}
+	if pq != nil {
+		// A quote prices only the nodes it lists; placing elsewhere would
+		// bill a node at a multiplier nobody quoted for it.
+		match.QuotedNodeIDs = pq.nodeIDs()
+	}
+	candidates, err := o.registry.FindMatch(match)
 	if err != nil {
 		// Placement rejection — the purest unmet-demand signal. Record it
-		// fire-and-forget AFTER the decision, then return the placement error
-		// unchanged. Telemetry never alters the error or blocks the return.
+		// fire-and-forget AFTER the decision, then return the placement error,
+		// classified by the same ladder. Telemetry never alters the error or
+		// blocks the return.
 		o.recordRejection(ctx, req)
-		return SubmitJobResponse{}, fmt.Errorf("find nodes: %w", err)
+		return SubmitJobResponse{}, o.noCapacityError(req, err)
 	}
 
-	scheduled, err := o.schedule(candidates, SLAStandard, o.requesterPlacementContext(ctx, req.ConsumerID))
+	scheduled, err := o.schedule(candidates, req.tier(), pctx)
 	if err != nil {
-		return SubmitJobResponse{}, fmt.Errorf("schedule: %w", err)
+		// The scheduler fails only when too few candidates (with distinct
+		// owners) remain for the SLA tier.
+		return SubmitJobResponse{}, &SubmitError{
+			Class:   SubmitErrNoCapacity,
+			Reason:  sounding.ReasonNoCapacity,
+			Message: fmt.Sprintf("too few nodes with distinct owners are free for SLA tier %d right now; retry later or lower the SLA tier", req.tier()),
+			Err:     fmt.Errorf("schedule: %w", err),
+		}
+	}
+	quote, err := o.quoteEscrow(ctx, req, scheduled, pq)
+	if err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("submit job: %w", err)
+	}
+	if req.tier() > SLAStandard {
+		return o.submitReplicated(ctx, req, match, scheduled, quote, pq)
 	}
 	node := scheduled[0]
+	quotedAt, quotedMultiplier := quotedColumns(pq, node.NodeID)
 
 	isPrintJob := req.WorkloadType == types.MarketplacePrintTraditional ||
 		req.WorkloadType == types.MarketplacePrint3D
@@ -232,18 +466,27 @@ func (o *Orchestrator) SubmitJob(ctx context.Context, req SubmitJobRequest) (Sub
 		INSERT INTO jobs (
 			id, participant_id, node_id, workload_type, status,
 			country_constraint, cpu_cores, ram_mb, storage_gb, gpu_required,
-			container_image
+			container_image, gpu_vram_gb, output_path, max_runtime_seconds,
+			amount_cents, payment_status, quoted_at, quoted_multiplier
 		) VALUES (
 			$1, $2, $3, $4::workload_type, 'pending'::job_status,
-			$5, $6, $7, $8, $9, $10
+			$5, $6, $7, $8, $9, $10, NULLIF($11, 0), NULLIF($12, ''), $13,
+			$14, NULLIF($15, ''), $16, $17
 		)`,
 		jobID, req.ConsumerID, node.NodeID, req.WorkloadType,
 		countryConstraint, req.CPUCores, req.RAMMB, req.StorageGB, req.GPURequired,
-		req.ContainerImage,
+		req.ContainerImage, req.GPUVRAMGB, req.OutputPath, req.maxRuntimeSeconds(),
+		quote, req.paymentStatus(), quotedAt, quotedMultiplier,
 	)
 	if err != nil {
 		return SubmitJobResponse{}, fmt.Errorf("insert job: %w", err)
 	}
+	if err := insertJobInputs(ctx, tx, jobID, req.ConsumerID, req.Inputs); err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("submit job: %w", err)
+	}
+	if err := storeJobPlacement(ctx, tx, jobID, req.Placement); err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("submit job: %w", err)
+	}
 
 	token, err := GenerateJobToken(jobID, node.NodeID, jobTokenTTL, o.tokenSecret)
 	if err != nil {
@@ -317,11 +560,12 @@ func (o *Orchestrator) SubmitJob(ctx context.Context, req SubmitJobRequest) (Sub
 		return SubmitJobResponse{}, fmt.Errorf("commit transaction: %w", err)
 	}
 
-	// Advisory in-flight counter (B4): count the committed placement against
-	// the chosen node so the scheduler's per-in-flight penalty spreads load.
-	// Both scheduled and awaiting_confirmation placements count — the node is
-	// spoken for until the job reaches a terminal-for-placement state.
-	o.registry.AddInFlight(node.NodeID, +1)
+	// Reserve the committed placement's resources on the chosen node (B4
+	// in-flight counter included) so FindMatch stops offering capacity that
+	// is already spoken for. Both scheduled and awaiting_confirmation
+	// placements count — the node is held until the job reaches a
+	// terminal-for-placement state.
+	o.registry.Reserve(node.NodeID, jobID, match.Resources())
 
 	// Placement succeeded and is durably committed — record the placed job's
 	// shape fire-and-forget. Runs only on the committed path so the record
@@ -333,22 +577,33 @@ func (o *Orchestrator) SubmitJob(ctx context.Context, req SubmitJobRequest) (Sub
 		NodeID:                  node.NodeID,
 		JobToken:                token,
 		ProviderStripeAccountID: stripeAccountID,
+		QuoteCents:              quote,
 	}, nil
 }
 
 // canonicalJobSpecHash returns a SHA-256 digest of the job spec fields that
 // the contributor sees and acknowledges at confirmation time. Field declaration
 // order is load-bearing — encoding/json marshals struct fields in declaration
-// order, so reordering this struct silently changes all hashes.
+// order, so reordering this struct silently changes all hashes. Inputs are
+// omitted when empty, so jobs without inputs hash as they did before 034.
 func canonicalJobSpecHash(req SubmitJobRequest) ([]byte, error) {
+	type specInput struct {
+		Name   string `json:"name"`
+		SHA256 string `json:"sha256"`
+	}
 	type spec struct {
-		WorkloadType      string `json:"workload_type"`
-		ContainerImage    string `json:"container_image"`
-		CPUCores          int    `json:"cpu_cores"`
-		RAMMB             int    `json:"ram_mb"`
-		StorageGB         int    `json:"storage_gb"`
-		GPURequired       bool   `json:"gpu_required"`
-		CountryConstraint string `json:"country_constraint"`
+		WorkloadType      string      `json:"workload_type"`
+		ContainerImage    string      `json:"container_image"`
+		CPUCores          int         `json:"cpu_cores"`
+		RAMMB             int         `json:"ram_mb"`
+		StorageGB         int         `json:"storage_gb"`
+		GPURequired       bool        `json:"gpu_required"`
+		CountryConstraint string      `json:"country_constraint"`
+		Inputs            []specInput `json:"inputs,omitempty"`
+	}
+	var inputs []specInput
+	for _, in := range req.Inputs {
+		inputs = append(inputs, specInput{Name: in.Name, SHA256: in.SHA256})
 	}
 	b, err := json.Marshal(spec{
 		WorkloadType:      string(req.WorkloadType),
@@ -358,6 +613,7 @@ func canonicalJobSpecHash(req SubmitJobRequest) ([]byte, error) {
 		StorageGB:         req.StorageGB,
 		GPURequired:       req.GPURequired,
 		CountryConstraint: req.CountryConstraint,
+		Inputs:            inputs,
 	})
 	if err != nil {
 		return nil, err
@@ -441,20 +697,36 @@ func (o *Orchestrator) RerouteDeclinedJob(ctx context.Context, jobID string) err
 		return fmt.Errorf("reroute: declines rows: %w", err)
 	}
 
-	candidates, findErr := o.registry.FindMatch(MatchRequest{
+	siblingNodes, siblingOwners, err := o.replicaSiblings(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("reroute: %w", err)
+	}
+	placement, err := o.jobPlacement(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("reroute: %w", err)
+	}
+
+	match := MatchRequest{
 		WorkloadType:                 types.MarketplaceWorkloadType(workloadType),
 		CountryConstraint:            countryConstraint,
 		CPUCores:                     cpuCores,
 		RAMMB:                        ramMB,
 		StorageGB:                    storageGB,
 		GPURequired:                  gpuRequired,
-		ExcludedNodeIDs:              excludedIDs,
+		ExcludedNodeIDs:              append(excludedIDs, siblingNodes...),
+		ExcludedParticipantIDs:       siblingOwners,
 		ExcludeConsumerParticipantID: consumerParticipantID,
-	})
+	}
+	match, pctx, err := o.placementFor(ctx, match, consumerParticipantID, jobID, placement)
+	if err != nil {
+		return fmt.Errorf("reroute: %w", err)
+	}
+	candidates, findErr := o.registry.FindMatch(match)
 	if findErr != nil {
-		// No eligible nodes remain — fail the job. The guarded UPDATE means a
+		// No eligible nodes remain — fail the job. A job pinned to the node
+		// that declined it always ends here. The guarded UPDATE means a
 		// concurrent worker that already moved the row forward makes this a
-		// no-op; the in-flight decrement fires only when we actually flipped it.
+		// no-op; the reservation is released only when we actually flipped it.
 		ct, err := o.db.Pool.Exec(ctx,
 			`UPDATE jobs SET status = 'failed'::job_status, updated_at = NOW()
 			 WHERE id = $1 AND status = 'declined'::job_status`,
@@ -463,13 +735,16 @@ func (o *Orchestrator) RerouteDeclinedJob(ctx context.Context, jobID string) err
 		if err != nil {
 			return fmt.Errorf("reroute: fail job %s: %w", jobID, err)
 		}
-		if ct.RowsAffected() == 1 && previousNodeID != "" {
-			o.registry.AddInFlight(previousNodeID, -1)
+		if ct.RowsAffected() == 1 {
+			if previousNodeID != "" {
+				o.registry.Release(previousNodeID, jobID)
+			}
+			SettleReplicaGroup(ctx, o.db, o.registry, jobID)
 		}
 		return nil
 	}
 
-	scheduled, err := o.schedule(candidates, SLAStandard, o.requesterPlacementContext(ctx, consumerParticipantID))
+	scheduled, err := o.schedule(candidates, SLAStandard, pctx)
 	if err != nil {
 		return fmt.Errorf("reroute: schedule %s: %w", jobID, err)
 	}
@@ -531,15 +806,14 @@ func (o *Orchestrator) RerouteDeclinedJob(ctx context.Context, jobID string) err
 		}
 	}
 
-	// Advisory in-flight rebind accounting (B4): only when the guarded UPDATE
-	// actually moved the row. The previous node may already have been
-	// decremented by a protocol Decline — AddInFlight clamps at zero, and the
-	// counter is a soft scheduling signal, so the occasional double decrement
-	// is accepted rather than tracked.
+	// Rebind accounting (B4): only when the guarded UPDATE actually moved the
+	// row. The previous node may already have been released by a Decline —
+	// the reservation is keyed by job so resources are never freed twice;
+	// only the soft InFlight counter may double-decrement (clamped at zero).
 	if ct.RowsAffected() == 1 {
-		o.registry.AddInFlight(node.NodeID, +1)
+		o.registry.Reserve(node.NodeID, jobID, match.Resources())
 		if previousNodeID != "" {
-			o.registry.AddInFlight(previousNodeID, -1)
+			o.registry.Release(previousNodeID, jobID)
 		}
 	}
 	return nil
@@ -600,25 +874,41 @@ func (o *Orchestrator) RescheduleStaleJob(ctx context.Context, jobID, oldNodeID
 		return fmt.Errorf("reschedule stale: declines rows: %w", err)
 	}
 
-	candidates, findErr := o.registry.FindMatch(MatchRequest{
+	siblingNodes, siblingOwners, err := o.replicaSiblings(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("reschedule stale: %w", err)
+	}
+	placement, err := o.jobPlacement(ctx, jobID)
+	if err != nil {
+		return fmt.Errorf("reschedule stale: %w", err)
+	}
+
+	match := MatchRequest{
 		WorkloadType:                 types.MarketplaceWorkloadType(workloadType),
 		CountryConstraint:            countryConstraint,
 		CPUCores:                     cpuCores,
 		RAMMB:                        ramMB,
 		StorageGB:                    storageGB,
 		GPURequired:                  gpuRequired,
-		ExcludedNodeIDs:              excludedIDs,
+		ExcludedNodeIDs:              append(excludedIDs, siblingNodes...),
+		ExcludedParticipantIDs:       siblingOwners,
 		ExcludeConsumerParticipantID: consumerParticipantID,
-	})
+	}
+	match, pctx, err := o.placementFor(ctx, match, consumerParticipantID, jobID, placement)
+	if err != nil {
+		return fmt.Errorf("reschedule stale: %w", err)
+	}
+	candidates, findErr := o.registry.FindMatch(match)
 	if findErr != nil {
 		// Deliberate divergence from RerouteDeclinedJob: leave the job
-		// scheduled and retry next tick — the bound node may wake.
+		// scheduled and retry next tick — the bound node may wake. A job
+		// pinned to that node always waits for it here.
 		slog.Warn("reschedule stale: no candidates; leaving job scheduled",
 			"job_id", jobID, "node_id", oldNodeID, "error", findErr)
 		return nil
 	}
 
-	scheduled, err := o.schedule(candidates, SLAStandard, o.requesterPlacementContext(ctx, consumerParticipantID))
+	scheduled, err := o.schedule(candidates, SLAStandard, pctx)
 	if err != nil {
 		return fmt.Errorf("reschedule stale: schedule %s: %w", jobID, err)
 	}
@@ -641,8 +931,8 @@ func (o *Orchestrator) RescheduleStaleJob(ctx context.Context, jobID, oldNodeID
 		return fmt.Errorf("reschedule stale: update job %s: %w", jobID, err)
 	}
 	if ct.RowsAffected() == 1 {
-		o.registry.AddInFlight(node.NodeID, +1)
-		o.registry.AddInFlight(oldNodeID, -1)
+		o.registry.Reserve(node.NodeID, jobID, match.Resources())
+		o.registry.Release(oldNodeID, jobID)
 	}
 	return nil
 }
@@ -921,12 +1211,135 @@ func (o *Orchestrator) expirePickedUp(ctx context.Context) {
 	}
 }
 
+// timeoutGrace is how long past a running job's deadline (started_at +
+// max_runtime_seconds) the reaper waits before failing it. The agent stops
+// the container at the deadline itself; the grace covers the stop and its
+// /complete report, so the reaper only fires for a node that went quiet.
+const timeoutGrace = 5 * time.Minute
+
+// ReapTimedOut fails a single running job whose deadline plus timeoutGrace has
+// passed without a /complete, with failure_cause "timeout", and releases its
+// node reservation. The node, if still up, is told to stop the container on
+// its next heartbeat (store.JobsToStop). Returns reaped=true when the row was
+// actually changed; reaped=false with err=nil is the lost-race case (the
+// agent's /complete landed between the caller's SELECT and this UPDATE).
+// Race-safe: the UPDATE re-checks status and the deadline.
+func (o *Orchestrator) ReapTimedOut(ctx context.Context, jobID string) (bool, error) {
+	var nodeID string
+	err := o.db.Pool.QueryRow(ctx,
+		`UPDATE jobs
+		 SET status        = 'failed'::job_status,
+		     failure_cause = $2,
+		     completed_at  = NOW(),
+		     updated_at    = NOW()
+		 WHERE id = $1
+		   AND status = 'running'::job_status
+		   AND node_id IS NOT NULL
+		   AND started_at + (max_runtime_seconds + $3) * INTERVAL '1 second' < NOW()
+		 RETURNING node_id::text`,
+		jobID, store.FailureCauseTimeout, int(timeoutGrace/time.Second),
+	).Scan(&nodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reap timed out: update job %s: %w", jobID, err)
+	}
+	o.registry.Release(nodeID, jobID)
+	SettleReplicaGroup(ctx, o.db, o.registry, jobID)
+	return true, nil
+}
+
+// reapTimedOut finds running jobs past their deadline plus timeoutGrace and
+// fails each via ReapTimedOut. Replica group parents carry no node and are
+// settled through their replicas instead. Called on every tick of
+// StartDeclineRerouteLoop, after expirePickedUp and before rerouteDeclined.
+func (o *Orchestrator) reapTimedOut(ctx context.Context) {
+	rows, err := o.db.Pool.Query(ctx,
+		`SELECT id FROM jobs
+		 WHERE status = 'running'::job_status
+		   AND node_id IS NOT NULL
+		   AND started_at + (max_runtime_seconds + $1) * INTERVAL '1 second' < NOW()
+		 LIMIT 100`,
+		int(timeoutGrace/time.Second),
+	)
+	if err != nil {
+		slog.Error("reap timed out: query running jobs", "error", err)
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			slog.Error("reap timed out: scan job id", "error", err)
+			rows.Close()
+			return
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		slog.Error("reap timed out: rows", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		reaped, err := o.ReapTimedOut(ctx, id)
+		if err != nil {
+			slog.Error("reap timed out: update job", "job_id", id, "error", err)
+			continue
+		}
+		if reaped {
+			slog.Info("running job past its deadline — failed as timeout", "job_id", id)
+		} else {
+			slog.Debug("reap timed out lost race", "job_id", id)
+		}
+	}
+}
+
+// RestoreReservations rebuilds the registry's resource reservations from the
+// jobs currently holding a node (awaiting_confirmation, scheduled, dispatched,
+// running). The registry is in-memory, so without this an orchestrator
+// restart would forget every placement and FindMatch would over-place onto
+// busy nodes. Call once at startup, before the reroute loop.
+func (o *Orchestrator) RestoreReservations(ctx context.Context) error {
+	rows, err := o.db.Pool.Query(ctx,
+		`SELECT id, node_id::text, COALESCE(cpu_cores, 0), COALESCE(ram_mb, 0),
+		        COALESCE(storage_gb, 0), gpu_required
+		 FROM jobs
+		 WHERE node_id IS NOT NULL
+		   AND status IN ('awaiting_confirmation'::job_status, 'scheduled'::job_status,
+		                  'dispatched'::job_status, 'running'::job_status)`)
+	if err != nil {
+		return fmt.Errorf("restore reservations: query: %w", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var jobID, nodeID string
+		var m MatchRequest
+		if err := rows.Scan(&jobID, &nodeID, &m.CPUCores, &m.RAMMB, &m.StorageGB, &m.GPURequired); err != nil {
+			return fmt.Errorf("restore reservations: scan: %w", err)
+		}
+		o.registry.Reserve(nodeID, jobID, m.Resources())
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("restore reservations: rows: %w", err)
+	}
+	slog.Info("restored node reservations", "jobs", n)
+	return nil
+}
+
 // StartDeclineRerouteLoop runs a background goroutine that periodically (a)
 // auto-declines awaiting_confirmation jobs whose deadline has passed, (b)
 // reverts stale dispatched jobs back to scheduled, (c) rebinds scheduled jobs
 // whose node is no longer online, (d) auto-advances stale picked_up print
-// jobs to delivered, and (e) re-dispatches declined jobs to a different node.
-// All five passes run on the same 30s ticker in expire-then-reroute order.
+// jobs to delivered, (e) fails running jobs long past their max runtime, (f)
+// fails escrowed jobs whose payment was never authorized, and (g)
+// re-dispatches declined jobs to a different node. All seven passes run on
+// the same 30s ticker in expire-then-reroute order.
 // Stops when ctx is cancelled. Run only from cmd/orchestrator — not from
 // cmd/portal, which has a separate registry instance that never receives
 // agent heartbeats.
@@ -943,6 +1356,8 @@ func (o *Orchestrator) StartDeclineRerouteLoop(ctx context.Context) {
 				o.expireDispatched(ctx)
 				o.rescheduleStale(ctx)
 				o.expirePickedUp(ctx)
+				o.reapTimedOut(ctx)
+				o.expireUnpaid(ctx)
 				o.rerouteDeclined(ctx)
 			}
 		}
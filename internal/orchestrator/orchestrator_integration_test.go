@@ -5,6 +5,7 @@ package orchestrator_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -926,3 +927,616 @@ func TestRerouteDeclinedJob_Compute_RebindsToScheduled(t *testing.T) {
 		t.Errorf("printer_id: got %q, want NULL for compute", *printerID)
 	}
 }
+
+// ── SLA tiers: replica groups ────────────────────────────────────────────────
+
+// registerOtherOwnerNode registers an online node owned by a fresh provider,
+// so a replicated placement can pair it with the fixture's node.
+func registerOtherOwnerNode(t *testing.T, f *orchFixture, hostname string) string {
+	t.Helper()
+	ctx := context.Background()
+	var ownerID, nodeID string
+	if err := f.db.Pool.QueryRow(ctx,
+		`INSERT INTO participants (email, display_name) VALUES ($1, 'Other Provider') RETURNING id`,
+		hostname+"@test.internal",
+	).Scan(&ownerID); err != nil {
+		t.Fatalf("insert provider: %v", err)
+	}
+	if err := f.db.Pool.QueryRow(ctx,
+		`INSERT INTO nodes (participant_id, node_class, hostname, country_code, status)
+		 VALUES ($1, 'A', $2, 'US', 'online')
+		 RETURNING id`,
+		ownerID, hostname,
+	).Scan(&nodeID); err != nil {
+		t.Fatalf("insert node: %v", err)
+	}
+	f.registry.Register(orchestrator.NodeEntry{
+		NodeID:        nodeID,
+		ParticipantID: ownerID,
+		NodeClass:     "A",
+		CountryCode:   "US",
+		Status:        "online",
+		LastHeartbeat: time.Now(),
+		HardwareProfile: orchestrator.HardwareProfile{
+			CPUCores:  8,
+			RAMMB:     16384,
+			StorageGB: 200,
+		},
+	})
+	return nodeID
+}
+
+// TestSubmitJob_ReliableTier_PlacesReplicaGroup verifies a tier-2 submit
+// writes a node-less parent plus one scheduled child per distinct-owner node,
+// and skips a second node belonging to an owner already holding a replica.
+func TestSubmitJob_ReliableTier_PlacesReplicaGroup(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+	registerSecondNode(t, f) // same owner as the fixture node
+	otherNodeID := registerOtherOwnerNode(t, f, "orch-test-node-other")
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+		SLATier:        orchestrator.SLAReliable,
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if len(resp.Replicas) != 2 {
+		t.Fatalf("Replicas: got %d, want 2", len(resp.Replicas))
+	}
+
+	var parentStatus string
+	var parentNode *string
+	var tier, quorum int
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT status, node_id::text, sla_tier, replica_quorum FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&parentStatus, &parentNode, &tier, &quorum); err != nil {
+		t.Fatalf("query parent: %v", err)
+	}
+	if parentStatus != "scheduled" || parentNode != nil || tier != 2 || quorum != 1 {
+		t.Errorf("parent: status=%q node=%v tier=%d quorum=%d, want scheduled/NULL/2/1", parentStatus, parentNode, tier, quorum)
+	}
+
+	var owners, nodes int
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT COUNT(DISTINCT n.participant_id), COUNT(DISTINCT j.node_id)
+		 FROM jobs j JOIN nodes n ON n.id = j.node_id
+		 WHERE j.parent_job_id = $1 AND j.status = 'scheduled'::job_status`, resp.JobID,
+	).Scan(&owners, &nodes); err != nil {
+		t.Fatalf("query replicas: %v", err)
+	}
+	if owners != 2 || nodes != 2 {
+		t.Errorf("replicas span %d owner(s) on %d node(s), want 2 and 2", owners, nodes)
+	}
+	placedOther := false
+	for _, rp := range resp.Replicas {
+		placedOther = placedOther || rp.NodeID == otherNodeID
+	}
+	if !placedOther {
+		t.Error("no replica placed on the only other-owner node")
+	}
+}
+
+// TestSettleReplicaGroup_FirstSuccessCompletesParent verifies the default
+// quorum of 1: the first replica to complete completes the parent, and the
+// unstarted sibling is withdrawn and its node reservation released.
+func TestSettleReplicaGroup_FirstSuccessCompletesParent(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+	registerOtherOwnerNode(t, f, "orch-test-node-other")
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+		SLATier:        orchestrator.SLAReliable,
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	winner, loser := resp.Replicas[0], resp.Replicas[1]
+
+	if _, err := f.db.Pool.Exec(ctx,
+		`UPDATE jobs SET status = 'running'::job_status, started_at = NOW() - INTERVAL '1 minute'
+		 WHERE id = $1`, winner.JobID,
+	); err != nil {
+		t.Fatalf("mark replica running: %v", err)
+	}
+	exitCode := 0
+	if _, err := store.CompleteJob(ctx, f.db, winner.JobID, &exitCode, "", false); err != nil {
+		t.Fatalf("CompleteJob: %v", err)
+	}
+	f.registry.Release(winner.NodeID, winner.JobID)
+	orchestrator.SettleReplicaGroup(ctx, f.db, f.registry, winner.JobID)
+
+	var parentStatus string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT status FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&parentStatus); err != nil {
+		t.Fatalf("query parent: %v", err)
+	}
+	if parentStatus != "completed" {
+		t.Errorf("parent status: got %q, want completed", parentStatus)
+	}
+
+	var loserStatus, cause string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT status, COALESCE(failure_cause, '') FROM jobs WHERE id = $1`, loser.JobID,
+	).Scan(&loserStatus, &cause); err != nil {
+		t.Fatalf("query sibling: %v", err)
+	}
+	if loserStatus != "failed" || cause != store.FailureCauseReplicaSuperseded {
+		t.Errorf("sibling: status=%q cause=%q, want failed/%s", loserStatus, cause, store.FailureCauseReplicaSuperseded)
+	}
+	if entry, _ := f.registry.Get(loser.NodeID); entry.InFlight != 0 || entry.Reserved != (orchestrator.Resources{}) {
+		t.Errorf("withdrawn replica's node still reserved: InFlight=%d Reserved=%+v", entry.InFlight, entry.Reserved)
+	}
+}
+
+// TestSettleReplicaGroup_VerifiedMismatchDisputesParent verifies a verified
+// group whose two replicas report different result hashes settles 'disputed',
+// flags both replicas suspected_fraud and opens a dispute on each.
+func TestSettleReplicaGroup_VerifiedMismatchDisputesParent(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+	registerOtherOwnerNode(t, f, "orch-test-node-other")
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+		SLATier:        orchestrator.SLAReliable,
+		Verify:         true,
+		OutputPath:     "/output/result.bin",
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if len(resp.Replicas) != 2 {
+		t.Fatalf("Replicas: got %d, want 2", len(resp.Replicas))
+	}
+
+	hashes := []string{strings.Repeat("a", 64), strings.Repeat("b", 64)}
+	exitCode := 0
+	for i, rp := range resp.Replicas {
+		if _, err := f.db.Pool.Exec(ctx,
+			`UPDATE jobs SET status = 'running'::job_status, started_at = NOW() - INTERVAL '1 minute'
+			 WHERE id = $1`, rp.JobID,
+		); err != nil {
+			t.Fatalf("mark replica running: %v", err)
+		}
+		if err := store.RecordResultHash(ctx, f.db, rp.JobID, hashes[i]); err != nil {
+			t.Fatalf("RecordResultHash: %v", err)
+		}
+		if _, err := store.CompleteJob(ctx, f.db, rp.JobID, &exitCode, "", false); err != nil {
+			t.Fatalf("CompleteJob: %v", err)
+		}
+		f.registry.Release(rp.NodeID, rp.JobID)
+		orchestrator.SettleReplicaGroup(ctx, f.db, f.registry, rp.JobID)
+	}
+	t.Cleanup(func() {
+		f.db.Pool.Exec(context.Background(), `DELETE FROM disputes WHERE job_id IN ($1, $2)`, //nolint:errcheck
+			resp.Replicas[0].JobID, resp.Replicas[1].JobID)
+	})
+
+	var parentStatus, verification string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT status, COALESCE(verification, '') FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&parentStatus, &verification); err != nil {
+		t.Fatalf("query parent: %v", err)
+	}
+	if parentStatus != "disputed" || verification != store.VerificationMismatched {
+		t.Errorf("parent: status=%q verification=%q, want disputed/%s", parentStatus, verification, store.VerificationMismatched)
+	}
+
+	var suspects, disputes int
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FILTER (WHERE suspected_fraud),
+		        (SELECT COUNT(*) FROM disputes d JOIN jobs c ON c.id = d.job_id
+		         WHERE c.parent_job_id = $1 AND d.status = 'open')
+		 FROM jobs WHERE parent_job_id = $1`, resp.JobID,
+	).Scan(&suspects, &disputes); err != nil {
+		t.Fatalf("query replicas: %v", err)
+	}
+	if suspects != 2 || disputes != 2 {
+		t.Errorf("suspects=%d disputes=%d, want 2 and 2", suspects, disputes)
+	}
+}
+
+// TestCancelJob_RunningJob verifies cancelling a running job fails it with
+// the consumer-cancelled cause, releases its node reservation, meters the
+// partial run, asks the node to stop it, and computes the refund owed as the
+// charge less the metered cost.
+func TestCancelJob_RunningJob(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if _, err := f.db.Pool.Exec(ctx,
+		`UPDATE jobs SET status = 'running'::job_status, started_at = NOW() - INTERVAL '10 minutes',
+		        payment_intent_id = 'pi_cancel_test', amount_cents = 100000
+		 WHERE id = $1`, resp.JobID,
+	); err != nil {
+		t.Fatalf("mark job running: %v", err)
+	}
+
+	stop, err := store.JobsToStop(ctx, f.db, f.nodeID, []string{resp.JobID})
+	if err != nil {
+		t.Fatalf("JobsToStop before cancel: %v", err)
+	}
+	if len(stop) != 0 {
+		t.Errorf("JobsToStop before cancel: got %v, want none", stop)
+	}
+
+	cr, err := f.orch.CancelJob(ctx, resp.JobID, f.consumerID)
+	if err != nil {
+		t.Fatalf("CancelJob: %v", err)
+	}
+	if cr.PriorStatus != "running" {
+		t.Errorf("PriorStatus: got %q, want running", cr.PriorStatus)
+	}
+
+	var status, cause string
+	var cancelled bool
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT status, COALESCE(failure_cause, ''), cancelled_at IS NOT NULL FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&status, &cause, &cancelled); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if status != "failed" || cause != store.FailureCauseConsumerCancelled || !cancelled {
+		t.Errorf("job: status=%q cause=%q cancelled=%v, want failed/%s/true", status, cause, cancelled, store.FailureCauseConsumerCancelled)
+	}
+
+	var paid int64
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT consumer_paid_cents FROM job_metering WHERE job_id = $1`, resp.JobID,
+	).Scan(&paid); err != nil {
+		t.Fatalf("query metering: %v", err)
+	}
+	if cr.ChargedCents != paid || cr.RefundCents != 100000-paid || cr.PaymentIntentID != "pi_cancel_test" {
+		t.Errorf("refund: charged=%d refund=%d pi=%q, want %d/%d/pi_cancel_test", cr.ChargedCents, cr.RefundCents, cr.PaymentIntentID, paid, 100000-paid)
+	}
+
+	if entry, _ := f.registry.Get(f.nodeID); entry.InFlight != 0 || entry.Reserved != (orchestrator.Resources{}) {
+		t.Errorf("node still reserved: InFlight=%d Reserved=%+v", entry.InFlight, entry.Reserved)
+	}
+
+	stop, err = store.JobsToStop(ctx, f.db, f.nodeID, []string{resp.JobID})
+	if err != nil {
+		t.Fatalf("JobsToStop after cancel: %v", err)
+	}
+	if len(stop) != 1 || stop[0] != resp.JobID {
+		t.Errorf("JobsToStop after cancel: got %v, want [%s]", stop, resp.JobID)
+	}
+}
+
+// TestCancelJob_NotCancellable verifies a finished job, and another
+// consumer's job, cannot be cancelled.
+func TestCancelJob_NotCancellable(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+
+	if _, err := f.orch.CancelJob(ctx, resp.JobID, f.providerID); !errors.Is(err, store.ErrJobNotFound) {
+		t.Errorf("other consumer: got %v, want ErrJobNotFound", err)
+	}
+
+	if _, err := f.db.Pool.Exec(ctx,
+		`UPDATE jobs SET status = 'completed'::job_status, completed_at = NOW() WHERE id = $1`, resp.JobID,
+	); err != nil {
+		t.Fatalf("mark job completed: %v", err)
+	}
+	cr, err := f.orch.CancelJob(ctx, resp.JobID, f.consumerID)
+	if !errors.Is(err, store.ErrJobNotCancellable) {
+		t.Fatalf("completed job: got %v, want ErrJobNotCancellable", err)
+	}
+	if cr.PriorStatus != "completed" {
+		t.Errorf("PriorStatus: got %q, want completed", cr.PriorStatus)
+	}
+}
+
+// TestCancelJob_ReplicaGroup verifies cancelling a replica group's parent
+// fails every unfinished replica and releases each replica's node.
+func TestCancelJob_ReplicaGroup(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+	registerOtherOwnerNode(t, f, "orch-test-node-other")
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+		SLATier:        orchestrator.SLAReliable,
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+
+	if _, err := f.orch.CancelJob(ctx, resp.JobID, f.consumerID); err != nil {
+		t.Fatalf("CancelJob: %v", err)
+	}
+
+	var cancelled int
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM jobs
+		 WHERE (id = $1 OR parent_job_id = $1)
+		   AND status = 'failed'::job_status AND failure_cause = $2`,
+		resp.JobID, store.FailureCauseConsumerCancelled,
+	).Scan(&cancelled); err != nil {
+		t.Fatalf("query group: %v", err)
+	}
+	if cancelled != 3 {
+		t.Errorf("cancelled rows: got %d, want 3 (parent + 2 replicas)", cancelled)
+	}
+	for _, rp := range resp.Replicas {
+		if entry, _ := f.registry.Get(rp.NodeID); entry.InFlight != 0 {
+			t.Errorf("replica node %s still has InFlight=%d", rp.NodeID, entry.InFlight)
+		}
+	}
+}
+
+// TestReapTimedOut verifies a running job is failed as a timeout only once
+// its deadline plus the grace period has passed, and that reaping releases
+// its node reservation.
+func TestReapTimedOut(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:        f.consumerID,
+		WorkloadType:      types.MarketplaceBatchCompute,
+		ContainerImage:    orchComputeImage,
+		CPUCores:          2,
+		RAMMB:             4096,
+		MaxRuntimeSeconds: 60,
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+
+	// One minute past the deadline: still within the grace period.
+	if _, err := f.db.Pool.Exec(ctx,
+		`UPDATE jobs SET status = 'running'::job_status, started_at = NOW() - INTERVAL '2 minutes'
+		 WHERE id = $1`, resp.JobID,
+	); err != nil {
+		t.Fatalf("mark job running: %v", err)
+	}
+	reaped, err := f.orch.ReapTimedOut(ctx, resp.JobID)
+	if err != nil || reaped {
+		t.Fatalf("within grace: reaped=%v err=%v, want false/nil", reaped, err)
+	}
+
+	if _, err := f.db.Pool.Exec(ctx,
+		`UPDATE jobs SET started_at = NOW() - INTERVAL '1 hour' WHERE id = $1`, resp.JobID,
+	); err != nil {
+		t.Fatalf("backdate start: %v", err)
+	}
+	reaped, err = f.orch.ReapTimedOut(ctx, resp.JobID)
+	if err != nil || !reaped {
+		t.Fatalf("past grace: reaped=%v err=%v, want true/nil", reaped, err)
+	}
+
+	var status, cause string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT status, COALESCE(failure_cause, '') FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&status, &cause); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if status != "failed" || cause != store.FailureCauseTimeout {
+		t.Errorf("job: status=%q cause=%q, want failed/%s", status, cause, store.FailureCauseTimeout)
+	}
+	if entry, _ := f.registry.Get(f.nodeID); entry.InFlight != 0 {
+		t.Errorf("node still has InFlight=%d after reap", entry.InFlight)
+	}
+
+	reaped, err = f.orch.ReapTimedOut(ctx, resp.JobID)
+	if err != nil || reaped {
+		t.Errorf("second reap: reaped=%v err=%v, want false/nil", reaped, err)
+	}
+}
+
+// ── Placement constraints ────────────────────────────────────────────────────
+
+// TestSubmitJob_RequiredNode_PinnedThroughReroute verifies a pinned job lands
+// on its node although another fits, keeps the pin on the row, and is failed
+// rather than moved when that node declines it.
+func TestSubmitJob_RequiredNode_PinnedThroughReroute(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+	nodeBID := registerSecondNode(t, f)
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+		Placement:      orchestrator.Placement{RequiredNodeID: nodeBID},
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if resp.NodeID != nodeBID {
+		t.Fatalf("NodeID: got %q, want the pinned node %q", resp.NodeID, nodeBID)
+	}
+	var pinned string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(required_node_id::text, '') FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&pinned); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if pinned != nodeBID {
+		t.Errorf("required_node_id: got %q, want %q", pinned, nodeBID)
+	}
+
+	if _, err := f.db.Pool.Exec(ctx,
+		`UPDATE jobs SET status = 'declined'::job_status WHERE id = $1`, resp.JobID); err != nil {
+		t.Fatalf("decline job: %v", err)
+	}
+	if _, err := f.db.Pool.Exec(ctx,
+		`INSERT INTO job_node_declines (job_id, node_id) VALUES ($1, $2)`, resp.JobID, nodeBID); err != nil {
+		t.Fatalf("insert job_node_declines: %v", err)
+	}
+	if err := f.orch.RerouteDeclinedJob(ctx, resp.JobID); err != nil {
+		t.Fatalf("RerouteDeclinedJob: %v", err)
+	}
+	var status, nodeID string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT status, node_id::text FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&status, &nodeID); err != nil {
+		t.Fatalf("query rerouted job: %v", err)
+	}
+	if status != "failed" || nodeID != nodeBID {
+		t.Errorf("after decline: status %q on %s, want failed on the pinned node", status, nodeID)
+	}
+}
+
+// TestSubmitJob_AntiAffinityRequired_AvoidsOwnerOfOtherJob verifies required
+// owner anti-affinity keeps a new job off every node of an owner already
+// running one of the consumer's jobs, and rejects it when no other owner can
+// take it.
+func TestSubmitJob_AntiAffinityRequired_AvoidsOwnerOfOtherJob(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+	registerSecondNode(t, f) // same owner as the fixture node
+
+	if _, err := f.db.Pool.Exec(ctx,
+		`INSERT INTO jobs (participant_id, node_id, workload_type, status,
+		                   cpu_cores, ram_mb, storage_gb, gpu_required, container_image)
+		 VALUES ($1, $2, 'batch_compute'::workload_type, 'running'::job_status,
+		         1, 1024, 0, FALSE, $3)`,
+		f.consumerID, f.nodeID, orchComputeImage,
+	); err != nil {
+		t.Fatalf("insert running job: %v", err)
+	}
+	req := orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+		Placement: orchestrator.Placement{
+			AntiAffinity:         orchestrator.AntiAffinityOwner,
+			AntiAffinityRequired: true,
+		},
+	}
+
+	_, err := f.orch.SubmitJob(ctx, req)
+	if se := orchestrator.SubmitErrorOf(err); se.Class != orchestrator.SubmitErrNoCapacity {
+		t.Fatalf("only the busy owner's nodes: got %v, want a no_capacity rejection", err)
+	}
+
+	otherNodeID := registerOtherOwnerNode(t, f, "orch-test-node-spread")
+	resp, err := f.orch.SubmitJob(ctx, req)
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if resp.NodeID != otherNodeID {
+		t.Errorf("NodeID: got %q, want the other owner's node %q", resp.NodeID, otherNodeID)
+	}
+}
+
+// TestSubmitJob_NodeSelector_KeptThroughReschedule verifies a job with a node
+// selector and a toleration lands on the tainted node carrying the label, has
+// both stored, and is only rebound to another node that carries the label.
+func TestSubmitJob_NodeSelector_KeptThroughReschedule(t *testing.T) {
+	ctx := context.Background()
+	f := setupOrchFixture(t, writeOrchAllowlist(t), false)
+	nodeBID := registerSecondNode(t, f)
+	if err := f.registry.UpdateLabels(nodeBID, orchestrator.NodeLabelState{
+		Labels: map[string]string{"ssd": ""},
+		Taints: map[string]string{"office-hours-only": ""},
+	}); err != nil {
+		t.Fatalf("UpdateLabels: %v", err)
+	}
+
+	resp, err := f.orch.SubmitJob(ctx, orchestrator.SubmitJobRequest{
+		ConsumerID:     f.consumerID,
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: orchComputeImage,
+		CPUCores:       2,
+		RAMMB:          4096,
+		Placement: orchestrator.Placement{
+			NodeSelector: map[string]string{"ssd": ""},
+			Tolerations:  []orchestrator.Toleration{{Key: "office-hours-only"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+	if resp.NodeID != nodeBID {
+		t.Fatalf("NodeID: got %q, want the labelled node %q", resp.NodeID, nodeBID)
+	}
+	var selector map[string]string
+	var tolerations []string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT node_selector, tolerations FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&selector, &tolerations); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if _, ok := selector["ssd"]; !ok || len(tolerations) != 1 || tolerations[0] != "office-hours-only" {
+		t.Errorf("stored node_selector %v, tolerations %v", selector, tolerations)
+	}
+
+	// Node B goes away; the unlabelled fixture node cannot take the job.
+	f.registry.Evict(nodeBID)
+	if err := f.orch.RescheduleStaleJob(ctx, resp.JobID, nodeBID); err != nil {
+		t.Fatalf("RescheduleStaleJob: %v", err)
+	}
+	var nodeID string
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT node_id::text FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&nodeID); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if nodeID != nodeBID {
+		t.Fatalf("rebound to %s without the ssd label", nodeID)
+	}
+
+	if err := f.registry.UpdateLabels(f.nodeID, orchestrator.NodeLabelState{
+		Labels: map[string]string{"ssd": ""},
+	}); err != nil {
+		t.Fatalf("UpdateLabels: %v", err)
+	}
+	if err := f.orch.RescheduleStaleJob(ctx, resp.JobID, nodeBID); err != nil {
+		t.Fatalf("RescheduleStaleJob: %v", err)
+	}
+	if err := f.db.Pool.QueryRow(ctx,
+		`SELECT node_id::text FROM jobs WHERE id = $1`, resp.JobID,
+	).Scan(&nodeID); err != nil {
+		t.Fatalf("query job: %v", err)
+	}
+	if nodeID != f.nodeID {
+		t.Errorf("node_id: got %s, want the newly labelled node %s", nodeID, f.nodeID)
+	}
+}
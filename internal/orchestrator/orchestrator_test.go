@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -213,6 +214,155 @@ func TestSubmitJobRequest_Validate(t *testing.T) {
 			wantErr:     true,
 			errContains: "banana",
 		},
+		{
+			name:    "premium tier with quorum 2",
+			req:     SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceAppHosting, SLATier: SLAPremium, Quorum: 2},
+			wantErr: false,
+		},
+		{
+			name:        "tier out of range",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceAppHosting, SLATier: 4},
+			wantErr:     true,
+			errContains: "SLATier",
+		},
+		{
+			name:        "quorum above tier",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceAppHosting, SLATier: SLAReliable, Quorum: 3},
+			wantErr:     true,
+			errContains: "Quorum",
+		},
+		{
+			name:        "replicated print job",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplacePrint3D, SLATier: SLAReliable},
+			wantErr:     true,
+			errContains: "print",
+		},
+		{
+			name:    "verified batch job",
+			req:     SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, SLATier: SLAReliable, Verify: true, OutputPath: "/output/result.bin"},
+			wantErr: false,
+		},
+		{
+			name:    "output dir without verify",
+			req:     SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, OutputPath: "/output"},
+			wantErr: false,
+		},
+		{
+			name:        "verify on output dir",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, SLATier: SLAReliable, Verify: true, OutputPath: "/output"},
+			wantErr:     true,
+			errContains: "OutputPath",
+		},
+		{
+			name:        "output path outside output dir",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, OutputPath: "/output/../etc/passwd"},
+			wantErr:     true,
+			errContains: "OutputPath",
+		},
+		{
+			name:        "verify without output path",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, SLATier: SLAReliable, Verify: true},
+			wantErr:     true,
+			errContains: "OutputPath",
+		},
+		{
+			name:        "verify on standard tier",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, Verify: true, OutputPath: "/output/result.bin"},
+			wantErr:     true,
+			errContains: "SLATier",
+		},
+		{
+			name:        "verify with quorum 1",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, SLATier: SLAPremium, Quorum: 1, Verify: true, OutputPath: "/output/result.bin"},
+			wantErr:     true,
+			errContains: "Quorum",
+		},
+		{
+			name:    "max runtime at ceiling",
+			req:     SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, MaxRuntimeSeconds: 24 * 60 * 60},
+			wantErr: false,
+		},
+		{
+			name:        "max runtime above ceiling",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, MaxRuntimeSeconds: 24*60*60 + 1},
+			wantErr:     true,
+			errContains: "MaxRuntimeSeconds",
+		},
+		{
+			name:        "negative max runtime",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, MaxRuntimeSeconds: -1},
+			wantErr:     true,
+			errContains: "MaxRuntimeSeconds",
+		},
+		{
+			name:        "negative storage",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, StorageGB: -1},
+			wantErr:     true,
+			errContains: "StorageGB",
+		},
+		{
+			name:    "escrowed batch job",
+			req:     SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, SLATier: SLAReliable, Escrow: true},
+			wantErr: false,
+		},
+		{
+			name:        "escrowed job beyond the escrow limit",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceAppHosting, Escrow: true, MaxRuntimeSeconds: int(EscrowMaxRuntime/time.Second) + 1},
+			wantErr:     true,
+			errContains: "escrow limit",
+		},
+		{
+			name:        "escrowed print job",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplacePrintTraditional, Escrow: true},
+			wantErr:     true,
+			errContains: "Escrow",
+		},
+		{
+			name: "valid inputs",
+			req: SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplacePrint3D, Inputs: []JobInput{
+				{Name: "part.gcode", SHA256: inputSum},
+				{Name: "data.csv", SHA256: inputSum, URL: "https://example.com/data.csv"},
+			}},
+			wantErr: false,
+		},
+		{
+			name:        "input name with path",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, Inputs: []JobInput{{Name: "../etc/passwd", SHA256: inputSum}}},
+			wantErr:     true,
+			errContains: "input name",
+		},
+		{
+			name:        "input name dot-dot",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, Inputs: []JobInput{{Name: "..", SHA256: inputSum}}},
+			wantErr:     true,
+			errContains: "input name",
+		},
+		{
+			name: "duplicate input name",
+			req: SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, Inputs: []JobInput{
+				{Name: "a.bin", SHA256: inputSum}, {Name: "a.bin", SHA256: inputSum},
+			}},
+			wantErr:     true,
+			errContains: "duplicate",
+		},
+		{
+			name:        "input bad digest",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, Inputs: []JobInput{{Name: "a.bin", SHA256: "ABC"}}},
+			wantErr:     true,
+			errContains: "SHA256",
+		},
+		{
+			name:        "input plain http URL",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, Inputs: []JobInput{{Name: "a.bin", SHA256: inputSum, URL: "http://example.com/a.bin"}}},
+			wantErr:     true,
+			errContains: "https",
+		},
+		{
+			name:        "too many inputs",
+			req:         SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute, Inputs: make([]JobInput, MaxJobInputs+1)},
+			wantErr:     true,
+			errContains: "Inputs",
+		},
 	}
 	for _, tc := range cases {
 		tc := tc
@@ -234,6 +384,9 @@ func TestSubmitJobRequest_Validate(t *testing.T) {
 	}
 }
 
+// inputSum is a well-formed input digest for Validate cases.
+var inputSum = strings.Repeat("a", 64)
+
 // writeTempAllowlist marshals al to a temp file and returns its path.
 func writeTempAllowlist(t *testing.T, al agent.Allowlist) string {
 	t.Helper()
@@ -522,6 +675,41 @@ func TestCanonicalJobSpecHash_ContainerImageAffectsHash(t *testing.T) {
 	}
 }
 
+func TestCanonicalJobSpecHash_Inputs(t *testing.T) {
+	base := SubmitJobRequest{
+		WorkloadType:   types.MarketplacePrint3D,
+		ContainerImage: "soholink/print-worker@sha256:aaaa",
+		CPUCores:       4,
+		RAMMB:          8192,
+	}
+	// A job without inputs must keep its pre-034 hash.
+	legacy := []byte(`{"workload_type":"print_3d","container_image":"soholink/print-worker@sha256:aaaa",` +
+		`"cpu_cores":4,"ram_mb":8192,"storage_gb":0,"gpu_required":false,"country_constraint":""}`)
+	h0, err := canonicalJobSpecHash(base)
+	if err != nil {
+		t.Fatalf("canonicalJobSpecHash: %v", err)
+	}
+	if want := sha256.Sum256(legacy); string(h0) != string(want[:]) {
+		t.Error("a job without inputs should hash as before inputs existed")
+	}
+
+	withInput := base
+	withInput.Inputs = []JobInput{{Name: "part.gcode", SHA256: inputSum}}
+	h1, err := canonicalJobSpecHash(withInput)
+	if err != nil {
+		t.Fatalf("canonicalJobSpecHash with input: %v", err)
+	}
+	swapped := base
+	swapped.Inputs = []JobInput{{Name: "part.gcode", SHA256: strings.Repeat("b", 64)}}
+	h2, err := canonicalJobSpecHash(swapped)
+	if err != nil {
+		t.Fatalf("canonicalJobSpecHash swapped input: %v", err)
+	}
+	if string(h0) == string(h1) || string(h1) == string(h2) {
+		t.Error("expected the inputs' digests to affect the hash")
+	}
+}
+
 func TestCanonicalJobSpecHash_ConsumerIDExcluded(t *testing.T) {
 	// ConsumerID is an orchestrator-internal identity, not part of the spec
 	// a contributor acknowledges. Changing it must not change the hash.
@@ -767,6 +955,73 @@ func TestNodeRegistry_UpdateLoad_PersistsState(t *testing.T) {
 	}
 }
 
+func TestNodeRegistry_FindMatch_FreeCapacityFilter(t *testing.T) {
+	r := NewNodeRegistry()
+	r.Register(newOnlineNode("full", "US", 8, 16384, 100, false))
+	r.Register(newOnlineNode("roomy", "US", 8, 16384, 100, false))
+	r.Register(newOnlineNode("legacy", "US", 8, 16384, 100, false))
+
+	now := time.Now()
+	if err := r.UpdateLoad("full", NodeLoadState{SampledAt: now,
+		Free: &NodeFreeCapacity{CPUCores: 1, RAMMB: 8192, StorageGB: -1, Slots: 3}}); err != nil {
+		t.Fatalf("UpdateLoad full: %v", err)
+	}
+	if err := r.UpdateLoad("roomy", NodeLoadState{SampledAt: now,
+		Free: &NodeFreeCapacity{CPUCores: 6, RAMMB: -1, StorageGB: -1, Slots: 1}}); err != nil {
+		t.Fatalf("UpdateLoad roomy: %v", err)
+	}
+
+	candidates, err := r.FindMatch(MatchRequest{CPUCores: 2, RAMMB: 4096})
+	if err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	got := map[string]bool{}
+	for _, c := range candidates {
+		got[c.NodeID] = true
+	}
+	if got["full"] || !got["roomy"] || !got["legacy"] {
+		t.Errorf("candidates = %v, want roomy and legacy (no report) only", got)
+	}
+
+	// No slots left excludes the node regardless of resources.
+	if err := r.UpdateLoad("roomy", NodeLoadState{SampledAt: now,
+		Free: &NodeFreeCapacity{CPUCores: 6, RAMMB: -1, StorageGB: -1, Slots: 0}}); err != nil {
+		t.Fatalf("UpdateLoad roomy: %v", err)
+	}
+	// A stale report no longer gates matching.
+	if err := r.UpdateLoad("full", NodeLoadState{SampledAt: now.Add(-2 * freeCapacityTTL),
+		Free: &NodeFreeCapacity{CPUCores: 0, RAMMB: 0, StorageGB: 0, Slots: 0}}); err != nil {
+		t.Fatalf("UpdateLoad full: %v", err)
+	}
+	candidates, err = r.FindMatch(MatchRequest{CPUCores: 2, RAMMB: 4096})
+	if err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	got = map[string]bool{}
+	for _, c := range candidates {
+		got[c.NodeID] = true
+	}
+	if !got["full"] || got["roomy"] || !got["legacy"] {
+		t.Errorf("candidates = %v, want full (stale report) and legacy", got)
+	}
+}
+
+func TestFindMatch_ExcludedParticipantIDs(t *testing.T) {
+	r := NewNodeRegistry()
+	held := newOnlineNode("node-held", "US", 8, 16384, 100, false)
+	held.ParticipantID = "owner-with-replica"
+	r.Register(held)
+	r.Register(newOnlineNode("node-free", "US", 8, 16384, 100, false))
+
+	matches, err := r.FindMatch(MatchRequest{ExcludedParticipantIDs: []string{"owner-with-replica"}})
+	if err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	if len(matches) != 1 || matches[0].NodeID != "node-free" {
+		t.Errorf("got %d match(es), want only node-free", len(matches))
+	}
+}
+
 // ── B4: AddInFlight / IsOnline / Get ─────────────────────────────────────────
 
 func TestNodeRegistry_AddInFlight_ClampsAtZero(t *testing.T) {
@@ -834,3 +1089,78 @@ func TestNodeRegistry_IsOnline(t *testing.T) {
 		t.Error("IsOnline(node-missing) = true, want false")
 	}
 }
+
+// ── Reserve / Release: resource-aware placement ──────────────────────────────
+
+func TestNodeRegistry_Reserve_ShrinksRemainingCapacity(t *testing.T) {
+	r := NewNodeRegistry()
+	r.Register(newOnlineNode("node-1", "US", 8, 16384, 100, false))
+
+	r.Reserve("node-1", "job-1", Resources{CPUCores: 6, RAMMB: 4096})
+
+	// 2 cores left: a 4-core job no longer fits, a 2-core job still does.
+	if matches, _ := r.FindMatch(MatchRequest{CPUCores: 4}); len(matches) != 0 {
+		t.Errorf("4-core request matched %d node(s), want 0 after reserving 6 of 8 cores", len(matches))
+	}
+	matches, _ := r.FindMatch(MatchRequest{CPUCores: 2})
+	if len(matches) != 1 {
+		t.Fatalf("2-core request matched %d node(s), want 1", len(matches))
+	}
+	if got := matches[0].Reserved; got != (Resources{CPUCores: 6, RAMMB: 4096}) {
+		t.Errorf("FindMatch Reserved = %+v, want 6 cores / 4096 MB", got)
+	}
+
+	r.Release("node-1", "job-1")
+	if matches, _ := r.FindMatch(MatchRequest{CPUCores: 8}); len(matches) != 1 {
+		t.Errorf("after Release, full-node request matched %d node(s), want 1", len(matches))
+	}
+}
+
+func TestNodeRegistry_Reserve_GPUIsExclusive(t *testing.T) {
+	r := NewNodeRegistry()
+	r.Register(newOnlineNode("gpu-node", "US", 16, 65536, 500, true))
+
+	r.Reserve("gpu-node", "job-1", MatchRequest{CPUCores: 2, GPURequired: true}.Resources())
+
+	if matches, _ := r.FindMatch(MatchRequest{GPURequired: true}); len(matches) != 0 {
+		t.Errorf("second GPU job matched %d node(s), want 0 while the GPU is held", len(matches))
+	}
+	if matches, _ := r.FindMatch(MatchRequest{CPUCores: 4}); len(matches) != 1 {
+		t.Errorf("CPU-only job matched %d node(s), want 1 alongside the GPU job", len(matches))
+	}
+}
+
+func TestNodeRegistry_Reserve_IdempotentPerJob(t *testing.T) {
+	r := NewNodeRegistry()
+	r.Register(newOnlineNode("node-1", "US", 8, 16384, 100, false))
+
+	r.Reserve("node-1", "job-1", Resources{CPUCores: 2})
+	r.Reserve("node-1", "job-1", Resources{CPUCores: 3})
+
+	entry, _ := r.Get("node-1")
+	if entry.InFlight != 1 {
+		t.Errorf("InFlight = %d, want 1 (re-reserve must not double count)", entry.InFlight)
+	}
+	if entry.Reserved.CPUCores != 3 {
+		t.Errorf("Reserved.CPUCores = %d, want 3 (latest reservation wins)", entry.Reserved.CPUCores)
+	}
+
+	r.Release("node-1", "job-1")
+	entry, _ = r.Get("node-1")
+	if entry.InFlight != 0 || entry.Reserved != (Resources{}) {
+		t.Errorf("after Release: InFlight=%d Reserved=%+v, want 0 and zero", entry.InFlight, entry.Reserved)
+	}
+}
+
+func TestNodeRegistry_Reserve_SurvivesReRegister(t *testing.T) {
+	r := NewNodeRegistry()
+	r.Register(newOnlineNode("node-1", "US", 8, 16384, 100, false))
+	r.Reserve("node-1", "job-1", Resources{CPUCores: 8})
+
+	// A listing refresh replaces the entry; the placement is still bound.
+	r.Register(newOnlineNode("node-1", "US", 8, 16384, 100, false))
+
+	if matches, _ := r.FindMatch(MatchRequest{CPUCores: 1}); len(matches) != 0 {
+		t.Errorf("re-registered node matched %d time(s), want 0 while fully reserved", len(matches))
+	}
+}
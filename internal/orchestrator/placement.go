@@ -0,0 +1,296 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// This file holds consumer placement constraints (migration 046): pinning a
+// job to one node, preferring nodes or owners, excluding owners, and keeping
+// a consumer's own jobs apart, plus the node selector and tolerations of
+// migration 047 (see labels.go). Hard constraints narrow FindMatch through
+// MatchRequest; soft ones travel in PlacementContext and only move the
+// scheduler's ranking. Both are stored on the job so a reroute or a stale
+// reschedule re-places it under the same terms.
+
+// AntiAffinity keeps a job away from the nodes already running the
+// consumer's other active jobs.
+type AntiAffinity string
+
+const (
+	// AntiAffinityNone places the job without regard to the consumer's
+	// other jobs.
+	AntiAffinityNone AntiAffinity = ""
+	// AntiAffinityOwner avoids the owners of those nodes: a contributor
+	// going offline takes out at most one of the consumer's jobs.
+	AntiAffinityOwner AntiAffinity = "owner"
+	// AntiAffinityRegion avoids their regions as well as their owners.
+	AntiAffinityRegion AntiAffinity = "region"
+)
+
+// maxPlacementIDs bounds each ID list in a Placement.
+const maxPlacementIDs = 32
+
+// Placement is a consumer's say in where a job runs. The zero value leaves
+// placement entirely to the scheduler.
+type Placement struct {
+	// RequiredNodeID pins the job to one node (hard): if that node cannot
+	// take it, the job is not placed anywhere else. Standard SLA tier only —
+	// replicas need distinct owners.
+	RequiredNodeID string
+
+	// PreferredNodeIDs and PreferredOwnerIDs are ranked first among the nodes
+	// that can take the job (soft).
+	PreferredNodeIDs  []string
+	PreferredOwnerIDs []string
+
+	// ExcludedOwnerIDs are contributors whose nodes never get the job (hard).
+	ExcludedOwnerIDs []string
+
+	// AntiAffinity spreads the job away from the consumer's other active
+	// jobs: soft by default, hard with AntiAffinityRequired, in which case a
+	// job with nowhere else to go is not placed.
+	AntiAffinity         AntiAffinity
+	AntiAffinityRequired bool
+
+	// NodeSelector lists labels the node must carry (hard); an empty value
+	// requires only the key.
+	NodeSelector map[string]string
+
+	// Tolerations are the node taints the job accepts. A node with any taint
+	// not tolerated here never gets the job.
+	Tolerations []Toleration
+}
+
+// validate checks p for SubmitJobRequest.Validate.
+func (p Placement) validate(tier SLATier) error {
+	if p.RequiredNodeID != "" {
+		if tier > SLAStandard {
+			return fmt.Errorf("Placement.RequiredNodeID requires SLATier %d: replicas need distinct owners", SLAStandard)
+		}
+		if _, err := uuid.Parse(p.RequiredNodeID); err != nil {
+			return fmt.Errorf("Placement.RequiredNodeID is not a node ID")
+		}
+	}
+	for _, l := range []struct {
+		name string
+		ids  []string
+	}{
+		{"PreferredNodeIDs", p.PreferredNodeIDs},
+		{"PreferredOwnerIDs", p.PreferredOwnerIDs},
+		{"ExcludedOwnerIDs", p.ExcludedOwnerIDs},
+	} {
+		if len(l.ids) > maxPlacementIDs {
+			return fmt.Errorf("Placement.%s may list at most %d IDs", l.name, maxPlacementIDs)
+		}
+		for _, id := range l.ids {
+			if _, err := uuid.Parse(id); err != nil {
+				return fmt.Errorf("Placement.%s: %q is not an ID", l.name, id)
+			}
+		}
+	}
+	for _, id := range p.PreferredOwnerIDs {
+		if slices.Contains(p.ExcludedOwnerIDs, id) {
+			return fmt.Errorf("Placement: owner %s is both preferred and excluded", id)
+		}
+	}
+	switch p.AntiAffinity {
+	case AntiAffinityNone, AntiAffinityOwner, AntiAffinityRegion:
+	default:
+		return fmt.Errorf("unknown Placement.AntiAffinity %q", p.AntiAffinity)
+	}
+	if p.AntiAffinityRequired && p.AntiAffinity == AntiAffinityNone {
+		return fmt.Errorf("Placement.AntiAffinityRequired requires an AntiAffinity")
+	}
+	return validateSelectorAndTolerations(p.NodeSelector, p.Tolerations)
+}
+
+// hard reports whether p narrows the nodes a job may run on, rather than
+// only ranking them.
+func (p Placement) hard() bool {
+	return p.RequiredNodeID != "" || len(p.ExcludedOwnerIDs) > 0 || p.AntiAffinityRequired ||
+		len(p.NodeSelector) > 0
+}
+
+// hardTerms names p's hard constraints other than RequiredNodeID, for
+// advice on a job no node can take: "node selector and excluded owners".
+func (p Placement) hardTerms() string {
+	var terms []string
+	if len(p.NodeSelector) > 0 {
+		terms = append(terms, "node selector")
+	}
+	if len(p.ExcludedOwnerIDs) > 0 {
+		terms = append(terms, "excluded owners")
+	}
+	if p.AntiAffinityRequired {
+		terms = append(terms, "anti-affinity")
+	}
+	if len(terms) < 2 {
+		return strings.Join(terms, "")
+	}
+	return strings.Join(terms[:len(terms)-1], ", ") + " and " + terms[len(terms)-1]
+}
+
+// storeJobPlacement records p on jobID within tx, so the job is re-placed
+// under the same constraints. A zero Placement writes nothing: the columns
+// default to none.
+func storeJobPlacement(ctx context.Context, tx pgx.Tx, jobID string, p Placement) error {
+	if p.RequiredNodeID == "" && len(p.PreferredNodeIDs) == 0 && len(p.PreferredOwnerIDs) == 0 &&
+		len(p.ExcludedOwnerIDs) == 0 && p.AntiAffinity == AntiAffinityNone &&
+		len(p.NodeSelector) == 0 && len(p.Tolerations) == 0 {
+		return nil
+	}
+	tolerations := make([]string, len(p.Tolerations))
+	for i, t := range p.Tolerations {
+		tolerations[i] = t.String()
+	}
+	selector := p.NodeSelector
+	if selector == nil {
+		selector = map[string]string{}
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE jobs
+		SET required_node_id       = NULLIF($2, '')::uuid,
+		    preferred_node_ids     = $3::text[]::uuid[],
+		    preferred_owner_ids    = $4::text[]::uuid[],
+		    excluded_owner_ids     = $5::text[]::uuid[],
+		    anti_affinity          = NULLIF($6, ''),
+		    anti_affinity_required = $7,
+		    node_selector          = $8::jsonb,
+		    tolerations            = $9
+		WHERE id = $1`,
+		jobID, p.RequiredNodeID, orEmpty(p.PreferredNodeIDs), orEmpty(p.PreferredOwnerIDs),
+		orEmpty(p.ExcludedOwnerIDs), string(p.AntiAffinity), p.AntiAffinityRequired,
+		selector, tolerations,
+	); err != nil {
+		return fmt.Errorf("store placement: %w", err)
+	}
+	return nil
+}
+
+// orEmpty returns ids, or an empty slice for nil, which pgx would send as
+// NULL.
+func orEmpty(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+// jobPlacement reads the placement constraints stored on jobID.
+func (o *Orchestrator) jobPlacement(ctx context.Context, jobID string) (Placement, error) {
+	var p Placement
+	var antiAffinity string
+	var tolerations []string
+	if err := o.db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(required_node_id::text, ''), preferred_node_ids::text[],
+		        preferred_owner_ids::text[], excluded_owner_ids::text[],
+		        COALESCE(anti_affinity, ''), anti_affinity_required,
+		        node_selector, tolerations
+		 FROM jobs WHERE id = $1`,
+		jobID,
+	).Scan(&p.RequiredNodeID, &p.PreferredNodeIDs, &p.PreferredOwnerIDs, &p.ExcludedOwnerIDs,
+		&antiAffinity, &p.AntiAffinityRequired, &p.NodeSelector, &tolerations); err != nil {
+		return Placement{}, fmt.Errorf("read placement of %s: %w", jobID, err)
+	}
+	p.AntiAffinity = AntiAffinity(antiAffinity)
+	for _, t := range tolerations {
+		p.Tolerations = append(p.Tolerations, parseToleration(t))
+	}
+	return p, nil
+}
+
+// consumerSpread is where a consumer's other active jobs run: the owners and
+// regions an anti-affine job keeps away from.
+type consumerSpread struct {
+	owners  []string
+	regions []string
+}
+
+// apply adds p's hard constraints to m, given where the consumer's other jobs
+// run.
+func (p Placement) apply(m *MatchRequest, spread consumerSpread) {
+	m.RequiredNodeID = p.RequiredNodeID
+	m.NodeSelector = p.NodeSelector
+	m.Tolerations = p.Tolerations
+	m.ExcludedParticipantIDs = append(m.ExcludedParticipantIDs, p.ExcludedOwnerIDs...)
+	if p.AntiAffinityRequired {
+		m.ExcludedParticipantIDs = append(m.ExcludedParticipantIDs, spread.owners...)
+		if p.AntiAffinity == AntiAffinityRegion {
+			m.ExcludedRegions = append(m.ExcludedRegions, spread.regions...)
+		}
+	}
+}
+
+// context adds p's soft constraints to pctx. Hard anti-affinity has already
+// removed the nodes it would score down.
+func (p Placement) context(pctx PlacementContext, spread consumerSpread) PlacementContext {
+	pctx.PreferredNodeIDs = p.PreferredNodeIDs
+	pctx.PreferredParticipantIDs = p.PreferredOwnerIDs
+	if p.AntiAffinity != AntiAffinityNone && !p.AntiAffinityRequired {
+		pctx.AvoidParticipantIDs = spread.owners
+		if p.AntiAffinity == AntiAffinityRegion {
+			pctx.AvoidRegions = spread.regions
+		}
+	}
+	return pctx
+}
+
+// placementFor returns the MatchRequest and PlacementContext that place a
+// job of consumerID under p. excludeJobID, when set, is the job being
+// re-placed: its own current node does not count against it.
+func (o *Orchestrator) placementFor(ctx context.Context, match MatchRequest, consumerID, excludeJobID string, p Placement) (MatchRequest, PlacementContext, error) {
+	var spread consumerSpread
+	if p.AntiAffinity != AntiAffinityNone {
+		var err error
+		spread, err = o.consumerSpread(ctx, consumerID, excludeJobID)
+		if err != nil {
+			return MatchRequest{}, PlacementContext{}, err
+		}
+	}
+	p.apply(&match, spread)
+	return match, p.context(o.requesterPlacementContext(ctx, consumerID), spread), nil
+}
+
+// consumerSpread returns the owners and regions of the nodes running
+// consumerID's active jobs other than excludeJobID and its replica group.
+// Replica-group parents hold no node and drop out of the join.
+func (o *Orchestrator) consumerSpread(ctx context.Context, consumerID, excludeJobID string) (consumerSpread, error) {
+	rows, err := o.db.Pool.Query(ctx,
+		`SELECT DISTINCT n.participant_id::text, COALESCE(n.region, '')
+		 FROM jobs j
+		 JOIN nodes n ON n.id = j.node_id
+		 WHERE j.participant_id = $1
+		   AND j.status IN ('scheduled'::job_status, 'dispatched'::job_status,
+		                    'running'::job_status, 'awaiting_confirmation'::job_status)
+		   AND j.id::text <> $2
+		   AND NOT COALESCE(j.parent_job_id = (SELECT parent_job_id FROM jobs WHERE id::text = $2), FALSE)`,
+		consumerID, excludeJobID,
+	)
+	if err != nil {
+		return consumerSpread{}, fmt.Errorf("read consumer spread: %w", err)
+	}
+	defer rows.Close()
+	var s consumerSpread
+	for rows.Next() {
+		var owner, region string
+		if err := rows.Scan(&owner, &region); err != nil {
+			return consumerSpread{}, fmt.Errorf("scan consumer spread: %w", err)
+		}
+		if !slices.Contains(s.owners, owner) {
+			s.owners = append(s.owners, owner)
+		}
+		if region != "" && !slices.Contains(s.regions, region) {
+			s.regions = append(s.regions, region)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return consumerSpread{}, fmt.Errorf("consumer spread rows: %w", err)
+	}
+	return s, nil
+}
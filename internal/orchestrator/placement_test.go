@@ -0,0 +1,163 @@
+package orchestrator
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
+)
+
+const (
+	testNodeA  = "11111111-1111-1111-1111-111111111111"
+	testNodeB  = "22222222-2222-2222-2222-222222222222"
+	testOwnerA = "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"
+	testOwnerB = "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"
+)
+
+func TestFindMatch_RequiredNodeID(t *testing.T) {
+	r := NewNodeRegistry()
+	r.Register(newOnlineNode("node-pinned", "US", 8, 16384, 100, false))
+	r.Register(newOnlineNode("node-other", "US", 8, 16384, 100, false))
+
+	matches, err := r.FindMatch(MatchRequest{RequiredNodeID: "node-pinned"})
+	if err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	if len(matches) != 1 || matches[0].NodeID != "node-pinned" {
+		t.Errorf("got %d match(es), want only node-pinned", len(matches))
+	}
+
+	// A pinned node that cannot take the job is a miss, not a fallback.
+	if _, err := r.FindMatch(MatchRequest{RequiredNodeID: "node-pinned", CPUCores: 16}); err == nil {
+		t.Error("pinned node too small: expected no match")
+	}
+	if _, err := r.FindMatch(MatchRequest{RequiredNodeID: "node-pinned", ExcludedNodeIDs: []string{"node-pinned"}}); err == nil {
+		t.Error("pinned node declined: expected no match")
+	}
+}
+
+func TestFindMatch_ExcludedRegions(t *testing.T) {
+	r := NewNodeRegistry()
+	east := newOnlineNode("node-east", "US", 8, 16384, 100, false)
+	east.Region = "us-east"
+	r.Register(east)
+	west := newOnlineNode("node-west", "US", 8, 16384, 100, false)
+	west.Region = "us-west"
+	r.Register(west)
+	r.Register(newOnlineNode("node-unknown", "US", 8, 16384, 100, false))
+
+	matches, err := r.FindMatch(MatchRequest{ExcludedRegions: []string{"us-east"}})
+	if err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	var ids []string
+	for _, m := range matches {
+		ids = append(ids, m.NodeID)
+	}
+	slices.Sort(ids)
+	if got := strings.Join(ids, ","); got != "node-unknown,node-west" {
+		t.Errorf("matches = %s, want node-unknown,node-west", got)
+	}
+}
+
+func TestPlacement_Validate(t *testing.T) {
+	base := SubmitJobRequest{ConsumerID: "c", WorkloadType: types.MarketplaceBatchCompute}
+	cases := []struct {
+		name        string
+		placement   Placement
+		tier        SLATier
+		errContains string
+	}{
+		{name: "zero", placement: Placement{}},
+		{
+			name: "all set",
+			placement: Placement{
+				RequiredNodeID:    testNodeA,
+				PreferredNodeIDs:  []string{testNodeB},
+				PreferredOwnerIDs: []string{testOwnerA},
+				ExcludedOwnerIDs:  []string{testOwnerB},
+				AntiAffinity:      AntiAffinityRegion, AntiAffinityRequired: true,
+			},
+		},
+		{name: "pinned replicas", placement: Placement{RequiredNodeID: testNodeA}, tier: SLAReliable, errContains: "RequiredNodeID requires SLATier"},
+		{name: "pinned to garbage", placement: Placement{RequiredNodeID: "node-1"}, errContains: "not a node ID"},
+		{name: "bad preferred ID", placement: Placement{PreferredNodeIDs: []string{""}}, errContains: "PreferredNodeIDs"},
+		{name: "too many IDs", placement: Placement{ExcludedOwnerIDs: slices.Repeat([]string{testOwnerA}, maxPlacementIDs+1)}, errContains: "at most"},
+		{
+			name:        "preferred and excluded",
+			placement:   Placement{PreferredOwnerIDs: []string{testOwnerA}, ExcludedOwnerIDs: []string{testOwnerA}},
+			errContains: "both preferred and excluded",
+		},
+		{name: "unknown anti-affinity", placement: Placement{AntiAffinity: "rack"}, errContains: "rack"},
+		{name: "required without anti-affinity", placement: Placement{AntiAffinityRequired: true}, errContains: "requires an AntiAffinity"},
+		{
+			name:      "selector and tolerations",
+			placement: Placement{NodeSelector: map[string]string{"ssd": "", "zone": "attic"}, Tolerations: []Toleration{{Key: "office-hours-only"}}},
+		},
+		{name: "bad selector", placement: Placement{NodeSelector: map[string]string{"SSD": ""}}, errContains: "Placement.NodeSelector"},
+		{name: "bad toleration", placement: Placement{Tolerations: []Toleration{{Key: "gpu", Value: "a/b"}}}, errContains: "Placement.Tolerations"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := base
+			req.Placement = tc.placement
+			req.SLATier = tc.tier
+			err := req.Validate()
+			if tc.errContains == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+				t.Errorf("error = %v, want one containing %q", err, tc.errContains)
+			}
+		})
+	}
+}
+
+func TestPlacement_HardAndSoftSplit(t *testing.T) {
+	spread := consumerSpread{owners: []string{testOwnerA}, regions: []string{"us-east"}}
+
+	// Soft anti-affinity only scores: nothing is excluded.
+	soft := Placement{
+		RequiredNodeID:   testNodeA,
+		PreferredNodeIDs: []string{testNodeB},
+		ExcludedOwnerIDs: []string{testOwnerB},
+		AntiAffinity:     AntiAffinityRegion,
+	}
+	m := MatchRequest{ExcludedParticipantIDs: []string{"sibling-owner"}}
+	soft.apply(&m, spread)
+	if m.RequiredNodeID != testNodeA {
+		t.Errorf("RequiredNodeID = %q", m.RequiredNodeID)
+	}
+	if got := strings.Join(m.ExcludedParticipantIDs, ","); got != "sibling-owner,"+testOwnerB {
+		t.Errorf("soft: ExcludedParticipantIDs = %s", got)
+	}
+	if len(m.ExcludedRegions) != 0 {
+		t.Errorf("soft: ExcludedRegions = %v", m.ExcludedRegions)
+	}
+	pctx := soft.context(PlacementContext{RequesterRegion: "us-west"}, spread)
+	if pctx.RequesterRegion != "us-west" || !slices.Equal(pctx.PreferredNodeIDs, []string{testNodeB}) ||
+		!slices.Equal(pctx.AvoidParticipantIDs, spread.owners) || !slices.Equal(pctx.AvoidRegions, spread.regions) {
+		t.Errorf("soft: context = %+v", pctx)
+	}
+
+	// Required anti-affinity filters instead of scoring.
+	hard := Placement{AntiAffinity: AntiAffinityOwner, AntiAffinityRequired: true}
+	m = MatchRequest{}
+	hard.apply(&m, spread)
+	if !slices.Equal(m.ExcludedParticipantIDs, spread.owners) || len(m.ExcludedRegions) != 0 {
+		t.Errorf("hard owner: match = %+v", m)
+	}
+	if pctx := hard.context(PlacementContext{}, spread); len(pctx.AvoidParticipantIDs) != 0 {
+		t.Errorf("hard owner: context still avoids %v", pctx.AvoidParticipantIDs)
+	}
+	hard.AntiAffinity = AntiAffinityRegion
+	m = MatchRequest{}
+	hard.apply(&m, spread)
+	if !slices.Equal(m.ExcludedRegions, spread.regions) {
+		t.Errorf("hard region: ExcludedRegions = %v", m.ExcludedRegions)
+	}
+}
@@ -0,0 +1,339 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// This file holds price estimates and quotes (migration 042). EstimateJob
+// prices a request on the nodes it could be placed on and signs a quote token
+// committing to those prices; SubmitJob honors the token until it expires:
+// the job is placed only on a quoted node and priced at the rates in effect
+// when it was quoted, at no more than that node's quoted multiplier.
+
+const (
+	// quoteTTL is how long a quote token can be submitted with.
+	quoteTTL = 15 * time.Minute
+
+	// maxQuoteCandidates bounds the nodes an estimate lists and its token
+	// carries multipliers for, cheapest first.
+	maxQuoteCandidates = 20
+
+	// quoteMACPrefix separates quote-token MACs from job-token MACs, which
+	// share the orchestrator's token secret.
+	quoteMACPrefix = "quote:"
+)
+
+// ErrInvalidQuote is returned by SubmitJob for a quote token that is
+// malformed, forged, expired, or issued for another consumer or request.
+var ErrInvalidQuote = errors.New("invalid price quote")
+
+// EstimateJobRequest is a SubmitJobRequest to be priced, with how long the
+// consumer expects it to run.
+type EstimateJobRequest struct {
+	SubmitJobRequest
+
+	// ExpectedRuntimeSeconds prices the low end of the estimate; zero means
+	// the max runtime. It may not exceed the max runtime.
+	ExpectedRuntimeSeconds int
+}
+
+// NodeEstimate is the price range of a job — one replica of it — on one
+// candidate node: LowCents at the expected runtime, HighCents at the max
+// runtime, the most it can be metered (egress aside).
+type NodeEstimate struct {
+	NodeID          string
+	CountryCode     string
+	PriceMultiplier float64
+	LowCents        int64
+	HighCents       int64
+}
+
+// EstimateJobResponse prices a job before submission. LowCents..HighCents
+// spans the whole job — every replica — from the cheapest candidates at the
+// expected runtime to the dearest at the max runtime; which nodes the
+// scheduler picks decides where in the range it lands. The split is the
+// active fee declaration's (FeeSeq nil: none yet, the platform default).
+// QuoteToken, passed back as SubmitJobRequest.QuoteToken before ExpiresAt,
+// holds the submission to these prices.
+type EstimateJobResponse struct {
+	Candidates          []NodeEstimate
+	Replicas            int
+	LowCents            int64
+	HighCents           int64
+	ContributorShareBps int
+	PlatformFeeBps      int
+	FeeSeq              *uint64
+	QuoteToken          string
+	ExpiresAt           time.Time
+}
+
+// EstimateJob validates req and prices it on the nodes FindMatch offers for
+// it now, under its hard placement constraints, at current rates, node
+// multipliers and fee terms. It places and reserves nothing.
+func (o *Orchestrator) EstimateJob(ctx context.Context, req EstimateJobRequest) (EstimateJobResponse, error) {
+	if err := req.Validate(); err != nil {
+		return EstimateJobResponse{}, fmt.Errorf("estimate job: %w", err)
+	}
+	if req.ExpectedRuntimeSeconds < 0 || req.ExpectedRuntimeSeconds > req.maxRuntimeSeconds() {
+		return EstimateJobResponse{}, fmt.Errorf("estimate job: ExpectedRuntimeSeconds must be between 0 and the max runtime (%d)", req.maxRuntimeSeconds())
+	}
+	expected := req.ExpectedRuntimeSeconds
+	if expected == 0 {
+		expected = req.maxRuntimeSeconds()
+	}
+
+	match, _, err := o.placementFor(ctx, req.matchRequest(), req.ConsumerID, "", req.Placement)
+	if err != nil {
+		return EstimateJobResponse{}, fmt.Errorf("estimate job: %w", err)
+	}
+	candidates, err := o.registry.FindMatch(match)
+	if err != nil {
+		return EstimateJobResponse{}, fmt.Errorf("find nodes: %w", err)
+	}
+	replicas := int(req.tier())
+	if len(candidates) < replicas {
+		return EstimateJobResponse{}, fmt.Errorf("find nodes: %d candidates for %d replicas", len(candidates), replicas)
+	}
+	country := make(map[string]string, len(candidates))
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.NodeID
+		country[c.NodeID] = c.CountryCode
+	}
+
+	quotedAt := time.Now().Truncate(time.Second)
+	estimates, err := store.EstimateJob(ctx, o.db, ids, req.resources(),
+		time.Duration(expected)*time.Second, time.Duration(req.maxRuntimeSeconds())*time.Second, quotedAt)
+	if err != nil {
+		return EstimateJobResponse{}, fmt.Errorf("estimate job: %w", err)
+	}
+	if len(estimates) < replicas {
+		return EstimateJobResponse{}, fmt.Errorf("find nodes: %d candidates for %d replicas", len(estimates), replicas)
+	}
+	terms, err := store.ActiveFeeTerms(ctx, o.db, quotedAt)
+	if err != nil {
+		return EstimateJobResponse{}, fmt.Errorf("estimate job: %w", err)
+	}
+
+	resp := EstimateJobResponse{
+		Replicas:            replicas,
+		ContributorShareBps: terms.ContributorShareBps,
+		PlatformFeeBps:      terms.PlatformFeeBps,
+		FeeSeq:              terms.Seq,
+		ExpiresAt:           quotedAt.Add(quoteTTL),
+	}
+	// The token quotes, and SubmitJob then places on, only the cheapest
+	// candidates, so the range spans those alone.
+	if len(estimates) > maxQuoteCandidates {
+		estimates = estimates[:maxQuoteCandidates]
+	}
+	// The cheapest replicas at the expected runtime to the dearest at the
+	// max. estimates are sorted by HighCents, so sort the low ends apart.
+	lows := make([]int64, len(estimates))
+	for i, e := range estimates {
+		lows[i] = e.LowCents
+	}
+	slices.Sort(lows)
+	for i := 0; i < replicas; i++ {
+		resp.LowCents += lows[i]
+		resp.HighCents += estimates[len(estimates)-1-i].HighCents
+	}
+
+	q := PriceQuote{
+		ConsumerID:  req.ConsumerID,
+		SpecHash:    pricingSpecHash(req.SubmitJobRequest),
+		QuotedAt:    quotedAt.Unix(),
+		ExpiresAt:   resp.ExpiresAt.Unix(),
+		Multipliers: make(map[string]float64, len(estimates)),
+	}
+	for _, e := range estimates {
+		resp.Candidates = append(resp.Candidates, NodeEstimate{
+			NodeID:          e.NodeID,
+			CountryCode:     country[e.NodeID],
+			PriceMultiplier: e.PriceMultiplier,
+			LowCents:        e.LowCents,
+			HighCents:       e.HighCents,
+		})
+		q.Multipliers[e.NodeID] = e.PriceMultiplier
+		q.MaxMultiplier = max(q.MaxMultiplier, e.PriceMultiplier)
+	}
+	resp.QuoteToken, err = GenerateQuoteToken(q, o.tokenSecret)
+	if err != nil {
+		return EstimateJobResponse{}, fmt.Errorf("estimate job: %w", err)
+	}
+	return resp, nil
+}
+
+// PriceQuote is what a quote token commits to: prices for one consumer's
+// request (SpecHash) at the rates of QuotedAt, on the nodes in Multipliers,
+// each with its quoted multiplier as a ceiling. SubmitJob places the job on
+// none other; MaxMultiplier, the dearest quoted, is only a fallback.
+type PriceQuote struct {
+	ConsumerID    string             `json:"consumer_id"`
+	SpecHash      string             `json:"spec_hash"`
+	QuotedAt      int64              `json:"quoted_at"`
+	ExpiresAt     int64              `json:"expires_at"`
+	Multipliers   map[string]float64 `json:"multipliers"`
+	MaxMultiplier float64            `json:"max_multiplier"`
+}
+
+// pin returns the pricing the quote holds a job placed on nodeID to.
+func (q PriceQuote) pin(nodeID string) store.PricePin {
+	m, ok := q.Multipliers[nodeID]
+	if !ok {
+		m = q.MaxMultiplier
+	}
+	return store.PricePin{QuotedAt: time.Unix(q.QuotedAt, 0), Multiplier: m}
+}
+
+// nodeIDs returns the nodes q quotes, the only ones a job submitted with it
+// may be placed on.
+func (q PriceQuote) nodeIDs() []string {
+	ids := make([]string, 0, len(q.Multipliers))
+	for id := range q.Multipliers {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// GenerateQuoteToken signs q into a URL-safe token in the form:
+//
+//	base64(json(q)) + "." + base64(hmac-sha256("quote:" + payload))
+func GenerateQuoteToken(q PriceQuote, secret []byte) (string, error) {
+	raw, err := json.Marshal(q)
+	if err != nil {
+		return "", fmt.Errorf("generate quote token: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	return payload + "." + quoteMAC(payload, secret), nil
+}
+
+// VerifyQuoteToken validates the HMAC signature and expiry, then returns the
+// quote. Errors wrap ErrInvalidQuote.
+func VerifyQuoteToken(token string, secret []byte) (PriceQuote, error) {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return PriceQuote{}, fmt.Errorf("verify quote token: invalid format: %w", ErrInvalidQuote)
+	}
+	if !hmac.Equal([]byte(sig), []byte(quoteMAC(payload, secret))) {
+		return PriceQuote{}, fmt.Errorf("verify quote token: invalid signature: %w", ErrInvalidQuote)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return PriceQuote{}, fmt.Errorf("verify quote token: decode payload: %w", ErrInvalidQuote)
+	}
+	var q PriceQuote
+	if err := json.Unmarshal(raw, &q); err != nil {
+		return PriceQuote{}, fmt.Errorf("verify quote token: malformed quote: %w", ErrInvalidQuote)
+	}
+	if time.Now().Unix() > q.ExpiresAt {
+		return PriceQuote{}, fmt.Errorf("verify quote token: quote expired: %w", ErrInvalidQuote)
+	}
+	return q, nil
+}
+
+// quotedColumns returns jobs.quoted_at and jobs.quoted_multiplier for a job
+// placed on nodeID under pq; both nil without a quote.
+func quotedColumns(pq *PriceQuote, nodeID string) (*time.Time, *float64) {
+	if pq == nil {
+		return nil, nil
+	}
+	pin := pq.pin(nodeID)
+	if pin.Multiplier <= 0 {
+		return &pin.QuotedAt, nil
+	}
+	return &pin.QuotedAt, &pin.Multiplier
+}
+
+func quoteMAC(payload string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(quoteMACPrefix + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyQuote returns the quote req was submitted with, checked against req,
+// or nil for a request without one.
+func (o *Orchestrator) verifyQuote(req SubmitJobRequest) (*PriceQuote, error) {
+	if req.QuoteToken == "" {
+		return nil, nil
+	}
+	q, err := VerifyQuoteToken(req.QuoteToken, o.tokenSecret)
+	if err != nil {
+		return nil, err
+	}
+	if q.ConsumerID != req.ConsumerID {
+		return nil, fmt.Errorf("quote was issued to another consumer: %w", ErrInvalidQuote)
+	}
+	if q.SpecHash != pricingSpecHash(req) {
+		return nil, fmt.Errorf("quote does not match the request: %w", ErrInvalidQuote)
+	}
+	return &q, nil
+}
+
+// pricingSpecHash is the hex SHA-256 of the request fields that decide its
+// price and its candidate nodes — among them the hard placement terms, so a
+// quote for a pinned or constrained request cannot price an unconstrained
+// one. Soft preferences only rank the same candidates and are left out. As
+// with canonicalJobSpecHash, field order is load-bearing; the placement
+// fields are omitted when empty so an unconstrained request hashes as it did
+// before they were added.
+func pricingSpecHash(req SubmitJobRequest) string {
+	type spec struct {
+		WorkloadType         string            `json:"workload_type"`
+		CountryConstraint    string            `json:"country_constraint"`
+		CPUCores             int               `json:"cpu_cores"`
+		RAMMB                int               `json:"ram_mb"`
+		StorageGB            int               `json:"storage_gb"`
+		GPURequired          bool              `json:"gpu_required"`
+		GPUVRAMGB            int               `json:"gpu_vram_gb"`
+		MaxRuntimeSeconds    int               `json:"max_runtime_seconds"`
+		SLATier              int               `json:"sla_tier"`
+		RequiredNodeID       string            `json:"required_node_id,omitempty"`
+		ExcludedOwnerIDs     []string          `json:"excluded_owner_ids,omitempty"`
+		AntiAffinity         string            `json:"anti_affinity,omitempty"`
+		AntiAffinityRequired bool              `json:"anti_affinity_required,omitempty"`
+		NodeSelector         map[string]string `json:"node_selector,omitempty"`
+		Tolerations          []string          `json:"tolerations,omitempty"`
+	}
+	p := req.Placement
+	// Order within a list does not change the candidates; normalise it.
+	excluded := slices.Sorted(slices.Values(p.ExcludedOwnerIDs))
+	var tolerations []string
+	for _, t := range p.Tolerations {
+		tolerations = append(tolerations, t.String())
+	}
+	slices.Sort(tolerations)
+	b, _ := json.Marshal(spec{
+		WorkloadType:      string(req.WorkloadType),
+		CountryConstraint: req.CountryConstraint,
+		CPUCores:          req.CPUCores,
+		RAMMB:             req.RAMMB,
+		StorageGB:         req.StorageGB,
+		GPURequired:       req.GPURequired,
+		GPUVRAMGB:         req.GPUVRAMGB,
+		MaxRuntimeSeconds: req.maxRuntimeSeconds(),
+		SLATier:           int(req.tier()),
+
+		RequiredNodeID:       p.RequiredNodeID,
+		ExcludedOwnerIDs:     excluded,
+		AntiAffinity:         string(p.AntiAffinity),
+		AntiAffinityRequired: p.AntiAffinityRequired,
+		NodeSelector:         p.NodeSelector,
+		Tolerations:          tolerations,
+	})
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,189 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
+)
+
+func testQuoteRequest() SubmitJobRequest {
+	return SubmitJobRequest{
+		ConsumerID:   "consumer-1",
+		WorkloadType: types.MarketplaceBatchCompute,
+		CPUCores:     2,
+		RAMMB:        4096,
+	}
+}
+
+func testQuote(req SubmitJobRequest, expiresAt time.Time) PriceQuote {
+	return PriceQuote{
+		ConsumerID:    req.ConsumerID,
+		SpecHash:      pricingSpecHash(req),
+		QuotedAt:      time.Now().Unix(),
+		ExpiresAt:     expiresAt.Unix(),
+		Multipliers:   map[string]float64{"node-a": 0.9, "node-b": 1.4},
+		MaxMultiplier: 1.4,
+	}
+}
+
+func TestGenerateAndVerifyQuoteToken(t *testing.T) {
+	secret := []byte("test-secret")
+	q := testQuote(testQuoteRequest(), time.Now().Add(quoteTTL))
+
+	token, err := GenerateQuoteToken(q, secret)
+	if err != nil {
+		t.Fatalf("GenerateQuoteToken: %v", err)
+	}
+	got, err := VerifyQuoteToken(token, secret)
+	if err != nil {
+		t.Fatalf("VerifyQuoteToken: %v", err)
+	}
+	if got.SpecHash != q.SpecHash || got.Multipliers["node-b"] != 1.4 {
+		t.Errorf("round trip = %+v, want %+v", got, q)
+	}
+
+	if pin := got.pin("node-a"); pin.Multiplier != 0.9 || pin.QuotedAt.Unix() != q.QuotedAt {
+		t.Errorf("pin(node-a) = %+v, want multiplier 0.9 at the quote time", pin)
+	}
+	if pin := got.pin("node-unquoted"); pin.Multiplier != 1.4 {
+		t.Errorf("pin(unquoted) multiplier = %v, want the dearest quoted 1.4", pin.Multiplier)
+	}
+}
+
+func TestVerifyQuoteToken_Rejects(t *testing.T) {
+	secret := []byte("test-secret")
+	req := testQuoteRequest()
+	valid, _ := GenerateQuoteToken(testQuote(req, time.Now().Add(quoteTTL)), secret)
+	expired, _ := GenerateQuoteToken(testQuote(req, time.Now().Add(-time.Minute)), secret)
+	jobToken, _ := GenerateJobToken("job-1", "node-a", time.Hour, secret)
+
+	cases := map[string]struct {
+		token  string
+		secret []byte
+	}{
+		"tampered":     {valid[:len(valid)-1] + "!", secret},
+		"wrong secret": {valid, []byte("other-secret")},
+		"expired":      {expired, secret},
+		"no separator": {"garbage", secret},
+		"job token":    {jobToken, secret},
+	}
+	for name, tc := range cases {
+		if _, err := VerifyQuoteToken(tc.token, tc.secret); !errors.Is(err, ErrInvalidQuote) {
+			t.Errorf("%s: err = %v, want ErrInvalidQuote", name, err)
+		}
+	}
+}
+
+func TestVerifyQuote_MatchesRequest(t *testing.T) {
+	o := &Orchestrator{tokenSecret: []byte("test-secret")}
+	req := testQuoteRequest()
+	token, _ := GenerateQuoteToken(testQuote(req, time.Now().Add(quoteTTL)), o.tokenSecret)
+
+	if pq, err := o.verifyQuote(req); pq != nil || err != nil {
+		t.Errorf("no token: verifyQuote = %v, %v; want nil, nil", pq, err)
+	}
+
+	req.QuoteToken = token
+	if pq, err := o.verifyQuote(req); err != nil || pq == nil {
+		t.Fatalf("matching request: verifyQuote = %v, %v", pq, err)
+	}
+
+	// Fields that do not affect the price may differ from the estimate.
+	req.ContainerImage = "python:3.12-slim"
+	if _, err := o.verifyQuote(req); err != nil {
+		t.Errorf("container image changed: verifyQuote = %v, want nil", err)
+	}
+
+	other := req
+	other.ConsumerID = "consumer-2"
+	if _, err := o.verifyQuote(other); !errors.Is(err, ErrInvalidQuote) {
+		t.Errorf("other consumer: err = %v, want ErrInvalidQuote", err)
+	}
+	bigger := req
+	bigger.CPUCores = 8
+	if _, err := o.verifyQuote(bigger); !errors.Is(err, ErrInvalidQuote) {
+		t.Errorf("larger request: err = %v, want ErrInvalidQuote", err)
+	}
+}
+
+func TestPricingSpecHash_DefaultRuntime(t *testing.T) {
+	req := testQuoteRequest()
+	explicit := req
+	explicit.MaxRuntimeSeconds = req.maxRuntimeSeconds()
+	if pricingSpecHash(req) != pricingSpecHash(explicit) {
+		t.Error("omitted max runtime and the workload ceiling must hash alike")
+	}
+	explicit.MaxRuntimeSeconds = 60
+	if pricingSpecHash(req) == pricingSpecHash(explicit) {
+		t.Error("a shorter max runtime must change the hash")
+	}
+}
+
+func TestPricingSpecHash_Placement(t *testing.T) {
+	req := testQuoteRequest()
+	base := pricingSpecHash(req)
+	for name, p := range map[string]Placement{
+		"required node":          {RequiredNodeID: "6f1c2a9e-3b7d-4c1e-9a55-0d2f8e4b7c10"},
+		"excluded owners":        {ExcludedOwnerIDs: []string{"owner-a"}},
+		"anti-affinity":          {AntiAffinity: AntiAffinityOwner},
+		"required anti-affinity": {AntiAffinity: AntiAffinityOwner, AntiAffinityRequired: true},
+		"node selector":          {NodeSelector: map[string]string{"ssd": ""}},
+		"tolerations":            {Tolerations: []Toleration{{Key: "gpu-only"}}},
+	} {
+		constrained := req
+		constrained.Placement = p
+		if pricingSpecHash(constrained) == base {
+			t.Errorf("%s: must change the hash", name)
+		}
+	}
+
+	preferred := req
+	preferred.Placement = Placement{PreferredNodeIDs: []string{"node-a"}, PreferredOwnerIDs: []string{"owner-a"}}
+	if pricingSpecHash(preferred) != base {
+		t.Error("soft preferences must not change the hash")
+	}
+
+	a, b := req, req
+	a.Placement = Placement{ExcludedOwnerIDs: []string{"owner-a", "owner-b"}, Tolerations: []Toleration{{Key: "x"}, {Key: "y", Value: "1"}}}
+	b.Placement = Placement{ExcludedOwnerIDs: []string{"owner-b", "owner-a"}, Tolerations: []Toleration{{Key: "y", Value: "1"}, {Key: "x"}}}
+	if pricingSpecHash(a) != pricingSpecHash(b) {
+		t.Error("list order must not change the hash")
+	}
+}
+
+func TestQuotedColumns(t *testing.T) {
+	if at, m := quotedColumns(nil, "node-a"); at != nil || m != nil {
+		t.Errorf("no quote: quotedColumns = %v, %v; want nil, nil", at, m)
+	}
+	q := testQuote(testQuoteRequest(), time.Now().Add(quoteTTL))
+	at, m := quotedColumns(&q, "node-b")
+	if at == nil || at.Unix() != q.QuotedAt || m == nil || *m != 1.4 {
+		t.Errorf("quotedColumns(node-b) = %v, %v", at, m)
+	}
+	if _, m := quotedColumns(&PriceQuote{QuotedAt: q.QuotedAt}, ""); m != nil {
+		t.Errorf("empty quote: multiplier = %v, want nil", *m)
+	}
+}
+
+func TestFindMatch_QuotedNodeIDs(t *testing.T) {
+	r := NewNodeRegistry()
+	r.Register(newOnlineNode("node-a", "US", 8, 16384, 100, false))
+	r.Register(newOnlineNode("node-b", "US", 8, 16384, 100, false))
+	r.Register(newOnlineNode("node-unquoted", "US", 8, 16384, 100, false))
+
+	q := testQuote(testQuoteRequest(), time.Now().Add(time.Minute))
+	matches, err := r.FindMatch(MatchRequest{QuotedNodeIDs: q.nodeIDs()})
+	if err != nil {
+		t.Fatalf("FindMatch: %v", err)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("got %d matches, want node-a and node-b", len(matches))
+	}
+	for _, m := range matches {
+		if m.NodeID == "node-unquoted" {
+			t.Error("a node the quote does not price must not match")
+		}
+	}
+}
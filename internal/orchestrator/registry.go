@@ -42,6 +42,13 @@ type NodeEntry struct {
 	OptOutPrinting    bool
 	HasEnabledPrinter bool
 
+	// Labels and Taints, loaded via UpdateLabels right after Register and
+	// refreshed on every heartbeat: the agent-reported ones overlaid by the
+	// owner's (see store.GetNodeLabels). FindMatch requires the job's
+	// NodeSelector of Labels, and that the job tolerate every one of Taints.
+	Labels map[string]string
+	Taints map[string]string
+
 	// Advisory load state, refreshed by handleHeartbeat via UpdateLoad.
 	// Self-reported and spoofable — consumed only by the scheduler's soft
 	// idle-first scoring (never a hard filter). Zero values mean "never
@@ -52,10 +59,58 @@ type NodeEntry struct {
 	CPUUtilPct    float64
 	LoadSampledAt time.Time
 
+	// FreeCapacity is the agent's admission-controller headroom from the same
+	// heartbeat as the load sample. Unlike the load fields it IS a hard filter
+	// in FindMatch while fresh: under-reporting only costs the node work, so
+	// there is nothing to gain by lying. FreeCapacityKnown is false for
+	// agents that predate the report.
+	FreeCapacity      NodeFreeCapacity
+	FreeCapacityKnown bool
+
 	// InFlight counts placements currently assigned to this node (advisory,
-	// clamped >= 0 by AddInFlight). Incremented at placement/rebind time,
-	// decremented on decline and on terminal-for-placement completion.
+	// clamped >= 0 by AddInFlight). Incremented by Reserve at placement/rebind
+	// time, decremented by Release on decline and on terminal-for-placement
+	// completion.
 	InFlight int
+
+	// Reserved is the sum of the resource reservations held by placements on
+	// this node (see Reserve/Release). Filled in by Get and FindMatch from the
+	// registry's reservation table; the value stored by Register is ignored.
+	Reserved Resources
+}
+
+// Resources is a quantity of node capacity held by one placement. GPUs counts
+// whole devices: HardwareProfile only reports GPUPresent, so a node offers
+// one GPU and a GPU job holds it exclusively.
+type Resources struct {
+	CPUCores  int
+	RAMMB     int
+	StorageGB int
+	GPUs      int
+}
+
+func (a Resources) add(b Resources) Resources {
+	return Resources{
+		CPUCores:  a.CPUCores + b.CPUCores,
+		RAMMB:     a.RAMMB + b.RAMMB,
+		StorageGB: a.StorageGB + b.StorageGB,
+		GPUs:      a.GPUs + b.GPUs,
+	}
+}
+
+// Free returns the node's hardware capacity less its reservations. Values may
+// go negative if the hardware shrank under existing placements.
+func (n NodeEntry) Free() Resources {
+	gpus := 0
+	if n.HardwareProfile.GPUPresent {
+		gpus = 1
+	}
+	return Resources{
+		CPUCores:  n.HardwareProfile.CPUCores - n.Reserved.CPUCores,
+		RAMMB:     n.HardwareProfile.RAMMB - n.Reserved.RAMMB,
+		StorageGB: n.HardwareProfile.StorageGB - n.Reserved.StorageGB,
+		GPUs:      gpus - n.Reserved.GPUs,
+	}
 }
 
 // NodeLoadState carries the advisory load fields from a heartbeat into the
@@ -64,6 +119,42 @@ type NodeLoadState struct {
 	OwnerActive bool
 	CPUUtilPct  float64 // 0-100 scale, matching the codebase CPU% convention
 	SampledAt   time.Time
+	// Free is nil when the agent did not report admission headroom.
+	Free *NodeFreeCapacity
+}
+
+// NodeFreeCapacity is the unreserved capacity an agent's admission controller
+// can still hand out under its active resource profile. A negative resource
+// value means that dimension is uncapped (no profile cap, or hardware
+// unknown); Slots is the remaining local job slot budget.
+type NodeFreeCapacity struct {
+	CPUCores  int
+	RAMMB     int
+	StorageGB int
+	Slots     int
+}
+
+// freeCapacityTTL bounds how long a reported NodeFreeCapacity is trusted by
+// FindMatch. Matches the scheduler's load-sample TTL: a node that stops
+// reporting falls back to hardware-only matching rather than being pinned
+// to a stale "full" reading.
+const freeCapacityTTL = 3 * time.Minute
+
+// fits reports whether the free capacity can hold req's resources.
+func (f NodeFreeCapacity) fits(req MatchRequest) bool {
+	if f.Slots <= 0 {
+		return false
+	}
+	if f.CPUCores >= 0 && f.CPUCores < req.CPUCores {
+		return false
+	}
+	if f.RAMMB >= 0 && f.RAMMB < req.RAMMB {
+		return false
+	}
+	if f.StorageGB >= 0 && f.StorageGB < req.StorageGB {
+		return false
+	}
+	return true
 }
 
 // NodeOptOutState carries opt-out flags from the DB into the in-memory
@@ -75,6 +166,13 @@ type NodeOptOutState struct {
 	HasEnabledPrinter bool
 }
 
+// NodeLabelState carries a node's labels and taints from the DB into the
+// in-memory registry via UpdateLabels.
+type NodeLabelState struct {
+	Labels map[string]string
+	Taints map[string]string
+}
+
 // MatchRequest describes the resource requirements for a workload placement.
 type MatchRequest struct {
 	// WorkloadType drives both dispatch and opt-out filtering: candidate nodes
@@ -89,17 +187,41 @@ type MatchRequest struct {
 	GPURequired                  bool
 	StorageGB                    int
 	ExcludedNodeIDs              []string // nodes that have already declined this job
+	ExcludedParticipantIDs       []string // owners already holding a replica of this job (SLA tiers place each replica with a distinct owner)
+	ExcludedRegions              []string // regions the consumer's anti-affinity rules out (Placement.AntiAffinityRequired)
+	RequiredNodeID               string   // empty = any node; otherwise the only node that may match (Placement.RequiredNodeID)
+	QuotedNodeIDs                []string // empty = any node; otherwise only these may match (the nodes a SubmitJobRequest.QuoteToken priced)
 	ExcludeConsumerParticipantID string   // Exclude nodes owned by this participant for ALL workload types (approved operator decision, feat/protocol-integration): routing a job to hardware its own requester owns lets the platform take a share of a transaction the participant could perform unaided. Originally C5 print-only ("compute/storage self-use is legitimate"); that narrower rationale is superseded — the print history is preserved in the C5 commit trail.
+
+	NodeSelector map[string]string // labels a node must carry; an empty value requires only the key (Placement.NodeSelector)
+	Tolerations  []Toleration      // taints the job accepts; a node with any other taint is skipped (Placement.Tolerations)
+}
+
+// Resources returns the reservation a placement matching req holds.
+func (req MatchRequest) Resources() Resources {
+	res := Resources{CPUCores: req.CPUCores, RAMMB: req.RAMMB, StorageGB: req.StorageGB}
+	if req.GPURequired {
+		res.GPUs = 1
+	}
+	return res
 }
 
 // NodeRegistry is a concurrency-safe in-memory store of active nodes.
 type NodeRegistry struct {
 	mu    sync.RWMutex
 	nodes map[string]NodeEntry
+
+	// reservations maps node ID → job ID → the job's reserved resources.
+	// Kept apart from nodes so a re-Register (hardware change, listing
+	// refresh) or an eviction does not drop placements still bound there.
+	reservations map[string]map[string]Resources
 }
 
 func NewNodeRegistry() *NodeRegistry {
-	return &NodeRegistry{nodes: make(map[string]NodeEntry)}
+	return &NodeRegistry{
+		nodes:        make(map[string]NodeEntry),
+		reservations: make(map[string]map[string]Resources),
+	}
 }
 
 // Register adds or replaces a node entry.
@@ -142,6 +264,23 @@ func (r *NodeRegistry) UpdateOptOut(nodeID string, state NodeOptOutState) error
 	return nil
 }
 
+// UpdateLabels overwrites a node's labels and taints. Returns an error if
+// the node is not registered. Callers (typically handleHeartbeat) read them
+// from the DB and forward them here so FindMatch can filter without DB
+// access.
+func (r *NodeRegistry) UpdateLabels(nodeID string, state NodeLabelState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.nodes[nodeID]
+	if !ok {
+		return fmt.Errorf("update labels: node %s not found", nodeID)
+	}
+	entry.Labels = state.Labels
+	entry.Taints = state.Taints
+	r.nodes[nodeID] = entry
+	return nil
+}
+
 // UpdateLoad overwrites a node's advisory load fields. Returns an error if
 // the node is not registered. Callers (typically handleHeartbeat) forward the
 // heartbeat's self-reported load sample here so the scheduler's idle-first
@@ -156,6 +295,11 @@ func (r *NodeRegistry) UpdateLoad(nodeID string, state NodeLoadState) error {
 	entry.OwnerActive = state.OwnerActive
 	entry.CPUUtilPct = state.CPUUtilPct
 	entry.LoadSampledAt = state.SampledAt
+	entry.FreeCapacityKnown = state.Free != nil
+	entry.FreeCapacity = NodeFreeCapacity{}
+	if state.Free != nil {
+		entry.FreeCapacity = *state.Free
+	}
 	r.nodes[nodeID] = entry
 	return nil
 }
@@ -167,6 +311,10 @@ func (r *NodeRegistry) UpdateLoad(nodeID string, state NodeLoadState) error {
 func (r *NodeRegistry) AddInFlight(nodeID string, delta int) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	r.addInFlightLocked(nodeID, delta)
+}
+
+func (r *NodeRegistry) addInFlightLocked(nodeID string, delta int) {
 	entry, ok := r.nodes[nodeID]
 	if !ok {
 		return
@@ -178,6 +326,51 @@ func (r *NodeRegistry) AddInFlight(nodeID string, delta int) {
 	r.nodes[nodeID] = entry
 }
 
+// Reserve records jobID's placement on nodeID holding res, and counts it in
+// the node's InFlight. Re-reserving the same job on the same node replaces the
+// reservation without double counting. A node missing from the registry still
+// gets the reservation (it may re-register while the job is bound to it).
+func (r *NodeRegistry) Reserve(nodeID, jobID string, res Resources) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	jobs, ok := r.reservations[nodeID]
+	if !ok {
+		jobs = make(map[string]Resources)
+		r.reservations[nodeID] = jobs
+	}
+	_, existed := jobs[jobID]
+	jobs[jobID] = res
+	if !existed {
+		r.addInFlightLocked(nodeID, +1)
+	}
+}
+
+// Release drops jobID's reservation on nodeID and decrements InFlight. With
+// no reservation on record (a placement made before an orchestrator restart,
+// or counted via AddInFlight) only the counter is decremented, exactly as
+// AddInFlight(nodeID, -1) — so a release may double-decrement InFlight the
+// way the B4 counter always could, but never double-frees resources.
+func (r *NodeRegistry) Release(nodeID, jobID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if jobs, ok := r.reservations[nodeID]; ok {
+		delete(jobs, jobID)
+		if len(jobs) == 0 {
+			delete(r.reservations, nodeID)
+		}
+	}
+	r.addInFlightLocked(nodeID, -1)
+}
+
+// reservedLocked sums nodeID's reservations. Caller holds r.mu.
+func (r *NodeRegistry) reservedLocked(nodeID string) Resources {
+	var sum Resources
+	for _, res := range r.reservations[nodeID] {
+		sum = sum.add(res)
+	}
+	return sum
+}
+
 // IsOnline reports whether the node is present in the registry with
 // Status "online". Used by the scheduled-staleness reaper to detect jobs
 // bound to nodes that have been evicted or gone offline.
@@ -188,11 +381,15 @@ func (r *NodeRegistry) IsOnline(nodeID string) bool {
 	return ok && entry.Status == "online"
 }
 
-// Get returns a copy of the node's registry entry, if present.
+// Get returns a copy of the node's registry entry, if present, with Reserved
+// filled in.
 func (r *NodeRegistry) Get(nodeID string) (NodeEntry, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	entry, ok := r.nodes[nodeID]
+	if ok {
+		entry.Reserved = r.reservedLocked(nodeID)
+	}
 	return entry, ok
 }
 
@@ -206,7 +403,11 @@ func (r *NodeRegistry) Evict(nodeID string) {
 // FindMatch returns all online nodes that satisfy req.
 // Go map iteration is intentionally random, so candidate order is
 // non-deterministic. Phase 1 Step 4 (Scheduler) scores and ranks this list.
-// CountryConstraint is a hard requirement when non-empty.
+// CountryConstraint is a hard requirement when non-empty, as are
+// RequiredNodeID, the excluded nodes, owners and regions, the NodeSelector
+// and the node's taints. Resource fit is
+// checked against each node's remaining capacity (hardware less Reserved),
+// and returned entries carry Reserved for the scheduler's packing term.
 func (r *NodeRegistry) FindMatch(req MatchRequest) ([]NodeEntry, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -215,10 +416,34 @@ func (r *NodeRegistry) FindMatch(req MatchRequest) ([]NodeEntry, error) {
 	for _, id := range req.ExcludedNodeIDs {
 		excluded[id] = true
 	}
+	excludedOwners := make(map[string]bool, len(req.ExcludedParticipantIDs))
+	for _, id := range req.ExcludedParticipantIDs {
+		excludedOwners[id] = true
+	}
+	excludedRegions := make(map[string]bool, len(req.ExcludedRegions))
+	for _, region := range req.ExcludedRegions {
+		excludedRegions[region] = true
+	}
+	quoted := make(map[string]bool, len(req.QuotedNodeIDs))
+	for _, id := range req.QuotedNodeIDs {
+		quoted[id] = true
+	}
 
 	var candidates []NodeEntry
 	for _, node := range r.nodes {
-		if excluded[node.NodeID] {
+		if req.RequiredNodeID != "" && node.NodeID != req.RequiredNodeID {
+			continue
+		}
+		if len(quoted) > 0 && !quoted[node.NodeID] {
+			continue
+		}
+		if excluded[node.NodeID] || excludedOwners[node.ParticipantID] {
+			continue
+		}
+		if node.Region != "" && excludedRegions[node.Region] {
+			continue
+		}
+		if !node.selectedBy(req.NodeSelector) || !node.toleratedBy(req.Tolerations) {
 			continue
 		}
 		// Same-owner exclusion, all workload types (see the field comment on
@@ -233,16 +458,24 @@ func (r *NodeRegistry) FindMatch(req MatchRequest) ([]NodeEntry, error) {
 		if req.CountryConstraint != "" && node.CountryCode != req.CountryConstraint {
 			continue
 		}
-		if req.GPURequired && !node.HardwareProfile.GPUPresent {
+		node.Reserved = r.reservedLocked(node.NodeID)
+		free := node.Free()
+		if req.GPURequired && free.GPUs < 1 {
+			continue
+		}
+		if free.CPUCores < req.CPUCores {
 			continue
 		}
-		if node.HardwareProfile.CPUCores < req.CPUCores {
+		if free.RAMMB < req.RAMMB {
 			continue
 		}
-		if node.HardwareProfile.RAMMB < req.RAMMB {
+		if free.StorageGB < req.StorageGB {
 			continue
 		}
-		if node.HardwareProfile.StorageGB < req.StorageGB {
+		// The agent's own admission headroom, while fresh: stops placing
+		// onto a node that would only defer and then decline the job.
+		if node.FreeCapacityKnown && time.Since(node.LoadSampledAt) <= freeCapacityTTL &&
+			!node.FreeCapacity.fits(req) {
 			continue
 		}
 		// Opt-out filter. If WorkloadType is set and maps to a known agent
@@ -0,0 +1,201 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// This file holds SLA-tier replication (migration 031). A Reliable or Premium
+// job is a replica group: a parent row the consumer sees, plus one child row
+// per replica. Each child is an ordinary job bound to its own node — polled,
+// declined, rerouted, completed and metered through the unchanged single-job
+// paths — and store.SettleReplicaGroup folds the children's outcomes back into
+// the parent.
+
+// ReplicaPlacement is one replica of a replicated job, as returned in
+// SubmitJobResponse.Replicas.
+type ReplicaPlacement struct {
+	JobID                   string
+	NodeID                  string
+	JobToken                string
+	ProviderStripeAccountID string
+}
+
+// submitReplicated writes the replica group for a job whose scheduler result
+// holds one node per replica: the parent row (no node, never polled) and a
+// scheduled child per node, in one transaction. Print workloads never get
+// here — Validate rejects replication for them, since the confirmation flow is
+// per node and printer. An escrowed group holds quote on the parent; every
+// replica shares its payment status, so none is dispatched until it is paid.
+func (o *Orchestrator) submitReplicated(ctx context.Context, req SubmitJobRequest, match MatchRequest, nodes []NodeEntry, quote int64, pq *PriceQuote) (SubmitJobResponse, error) {
+	// The scheduler picks distinct owners; re-check here so a ScheduleFunc
+	// that does not can never put two replicas in one contributor's hands.
+	owners := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if n.ParticipantID != "" && owners[n.ParticipantID] {
+			return SubmitJobResponse{}, fmt.Errorf("schedule: replicas share owner %s", n.ParticipantID)
+		}
+		owners[n.ParticipantID] = true
+	}
+
+	parentID := uuid.New().String()
+	parentQuotedAt, _ := quotedColumns(pq, "")
+
+	tx, err := o.db.Pool.Begin(ctx)
+	if err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var countryConstraint *string
+	if req.CountryConstraint != "" {
+		countryConstraint = &req.CountryConstraint
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO jobs (
+			id, participant_id, workload_type, status,
+			country_constraint, cpu_cores, ram_mb, storage_gb, gpu_required,
+			container_image, gpu_vram_gb, sla_tier, replica_quorum,
+			output_path, verify, max_runtime_seconds, amount_cents, payment_status,
+			quoted_at
+		) VALUES (
+			$1, $2, $3::workload_type, 'scheduled'::job_status,
+			$4, $5, $6, $7, $8, $9, NULLIF($10, 0), $11, $12,
+			NULLIF($13, ''), $14, $15, $16, NULLIF($17, ''), $18
+		)`,
+		parentID, req.ConsumerID, req.WorkloadType,
+		countryConstraint, req.CPUCores, req.RAMMB, req.StorageGB, req.GPURequired,
+		req.ContainerImage, req.GPUVRAMGB, int(req.tier()), req.quorum(),
+		req.OutputPath, req.Verify, req.maxRuntimeSeconds(), quote, req.paymentStatus(),
+		parentQuotedAt,
+	); err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("insert replica group: %w", err)
+	}
+	if err := storeJobPlacement(ctx, tx, parentID, req.Placement); err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("submit job: %w", err)
+	}
+
+	replicas := make([]ReplicaPlacement, len(nodes))
+	for i, node := range nodes {
+		jobID := uuid.New().String()
+		token, err := GenerateJobToken(jobID, node.NodeID, jobTokenTTL, o.tokenSecret)
+		if err != nil {
+			return SubmitJobResponse{}, fmt.Errorf("generate job token: %w", err)
+		}
+		quotedAt, quotedMultiplier := quotedColumns(pq, node.NodeID)
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO jobs (
+				id, participant_id, node_id, workload_type, status,
+				country_constraint, cpu_cores, ram_mb, storage_gb, gpu_required,
+				container_image, gpu_vram_gb, job_token, parent_job_id, replica_index,
+				output_path, max_runtime_seconds, payment_status,
+				quoted_at, quoted_multiplier
+			) VALUES (
+				$1, $2, $3, $4::workload_type, 'scheduled'::job_status,
+				$5, $6, $7, $8, $9, $10, NULLIF($11, 0), $12, $13, $14,
+				NULLIF($15, ''), $16, NULLIF($17, ''), $18, $19
+			)`,
+			jobID, req.ConsumerID, node.NodeID, req.WorkloadType,
+			countryConstraint, req.CPUCores, req.RAMMB, req.StorageGB, req.GPURequired,
+			req.ContainerImage, req.GPUVRAMGB, token, parentID, i,
+			req.OutputPath, req.maxRuntimeSeconds(), req.paymentStatus(),
+			quotedAt, quotedMultiplier,
+		); err != nil {
+			return SubmitJobResponse{}, fmt.Errorf("insert replica %d: %w", i, err)
+		}
+		if err := insertJobInputs(ctx, tx, jobID, req.ConsumerID, req.Inputs); err != nil {
+			return SubmitJobResponse{}, fmt.Errorf("submit job: replica %d: %w", i, err)
+		}
+		if err := storeJobPlacement(ctx, tx, jobID, req.Placement); err != nil {
+			return SubmitJobResponse{}, fmt.Errorf("submit job: replica %d: %w", i, err)
+		}
+
+		var stripeAccountID string
+		if err := tx.QueryRow(ctx, `
+			SELECT COALESCE(p.stripe_account_id, '')
+			FROM participants p
+			INNER JOIN nodes n ON n.participant_id = p.id
+			WHERE n.id = $1`,
+			node.NodeID,
+		).Scan(&stripeAccountID); err != nil {
+			return SubmitJobResponse{}, fmt.Errorf("fetch provider stripe account: %w", err)
+		}
+		replicas[i] = ReplicaPlacement{
+			JobID:                   jobID,
+			NodeID:                  node.NodeID,
+			JobToken:                token,
+			ProviderStripeAccountID: stripeAccountID,
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return SubmitJobResponse{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	for _, rp := range replicas {
+		o.registry.Reserve(rp.NodeID, rp.JobID, match.Resources())
+	}
+	o.recordPlacement(ctx, req, parentID)
+
+	return SubmitJobResponse{JobID: parentID, Replicas: replicas, QuoteCents: quote}, nil
+}
+
+// replicaSiblings returns the nodes and owners holding the other replicas of
+// jobID's group, for exclusion when the replica is re-placed — a rerouted
+// replica must not land beside a sibling. Both are empty for a job that is
+// not a replica.
+func (o *Orchestrator) replicaSiblings(ctx context.Context, jobID string) (nodeIDs, ownerIDs []string, err error) {
+	rows, err := o.db.Pool.Query(ctx,
+		`SELECT s.node_id::text, n.participant_id::text
+		 FROM jobs c
+		 JOIN jobs s  ON s.parent_job_id = c.parent_job_id AND s.id <> c.id
+		 JOIN nodes n ON n.id = s.node_id
+		 WHERE c.id = $1`,
+		jobID,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read replica siblings of %s: %w", jobID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var nodeID, ownerID string
+		if err := rows.Scan(&nodeID, &ownerID); err != nil {
+			return nil, nil, fmt.Errorf("scan replica sibling: %w", err)
+		}
+		nodeIDs = append(nodeIDs, nodeID)
+		ownerIDs = append(ownerIDs, ownerID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("replica siblings rows: %w", err)
+	}
+	return nodeIDs, ownerIDs, nil
+}
+
+// SettleReplicaGroup runs store.SettleReplicaGroup after a replica of jobID's
+// group changed state and releases the reservations of any replicas it
+// withdrew. Called from every path that starts, completes or fails a job; a
+// no-op for jobs that are not replicas. Errors are logged, never returned: the
+// replica's own transition is already durable, and the group re-settles on
+// its siblings' next transition. registry may be nil when the caller's
+// transition cannot settle the group terminally (a replica starting).
+func SettleReplicaGroup(ctx context.Context, db *store.DB, registry *NodeRegistry, jobID string) {
+	_, withdrawn, err := store.SettleReplicaGroup(ctx, db, jobID)
+	if err != nil {
+		slog.Error("settle replica group", "job_id", jobID, "error", err)
+		return
+	}
+	if registry == nil {
+		return
+	}
+	for _, p := range withdrawn {
+		if p.NodeID != "" {
+			registry.Release(p.NodeID, p.JobID)
+		}
+	}
+}
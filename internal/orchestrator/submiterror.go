@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/sounding"
+)
+
+// SubmitErrorClass says why SubmitJob rejected a request. The values are the
+// wire form of the internal listener's error body (internal/api), which
+// orchclient reconstructs, so the portal can tell a consumer what to change
+// whether the orchestrator runs in-process or not.
+type SubmitErrorClass string
+
+const (
+	// SubmitErrValidation: the request is malformed or inconsistent
+	// (SubmitJobRequest.Validate).
+	SubmitErrValidation SubmitErrorClass = "validation"
+	// SubmitErrImageNotAllowlisted: ContainerImage is not on the workload
+	// allowlist.
+	SubmitErrImageNotAllowlisted SubmitErrorClass = "image_not_allowlisted"
+	// SubmitErrTypeMismatch: the allowlist entry for ContainerImage declares
+	// a different workload type than WorkloadType maps to.
+	SubmitErrTypeMismatch SubmitErrorClass = "type_mismatch"
+	// SubmitErrNoCapacity: no node, or too few nodes for the SLA tier, can
+	// take the job right now. Reason carries the demand-sounding rejection
+	// reason.
+	SubmitErrNoCapacity SubmitErrorClass = "no_capacity"
+	// SubmitErrPaymentRequired: the job cannot be priced as requested — its
+	// quote token is invalid or expired (ErrInvalidQuote).
+	SubmitErrPaymentRequired SubmitErrorClass = "payment_required"
+	// SubmitErrInternal: anything else — the consumer cannot fix it.
+	SubmitErrInternal SubmitErrorClass = "internal"
+)
+
+// ReasonInvalidQuote is the Reason of a SubmitErrPaymentRequired error for a
+// quote token that failed verification.
+const ReasonInvalidQuote = "invalid_quote"
+
+// SubmitError is a classified SubmitJob rejection. Message is written for the
+// consumer and says what to change; Err is the cause, for logs.
+type SubmitError struct {
+	Class SubmitErrorClass
+	// Reason refines Class. For SubmitErrNoCapacity it is the rejection
+	// reason recorded by demand sounding (sounding.ReasonNoCapacity,
+	// ReasonTooBig or ReasonNoMatchingTier); for SubmitErrPaymentRequired,
+	// ReasonInvalidQuote.
+	Reason string
+	// WantedRung is, for SubmitErrNoCapacity, the capacity tier the job fits,
+	// or for ReasonTooBig the coming-soon tier that would take it. Empty when
+	// the ladder names none.
+	WantedRung string
+	Message    string
+	Err        error
+}
+
+func (e *SubmitError) Error() string {
+	if e.Err == nil {
+		return "submit job: " + e.Message
+	}
+	return "submit job: " + e.Err.Error()
+}
+
+func (e *SubmitError) Unwrap() error { return e.Err }
+
+// SubmitErrorOf returns the SubmitError in err's chain, or a SubmitErrInternal
+// one wrapping err when SubmitJob did not classify it.
+func SubmitErrorOf(err error) *SubmitError {
+	var se *SubmitError
+	if errors.As(err, &se) {
+		return se
+	}
+	return &SubmitError{
+		Class:   SubmitErrInternal,
+		Message: "the job could not be submitted; try again later",
+		Err:     err,
+	}
+}
+
+// noCapacityError classifies a FindMatch miss for req by the demand-sounding
+// ladder, exactly as recordRejection records it, and words the advice to
+// match: a job that fits an offered tier should wait or shrink, one larger
+// than every tier must shrink.
+func (o *Orchestrator) noCapacityError(req SubmitJobRequest, err error) *SubmitError {
+	reason, wanted := o.ladder.ClassifyRejection(shapeDims(req))
+	var msg string
+	switch reason {
+	case sounding.ReasonTooBig:
+		msg = fmt.Sprintf("the job is larger than any node offered today (the %s tier is coming soon); request fewer resources", wanted)
+	case sounding.ReasonNoMatchingTier:
+		msg = "the job is larger than any node offered; request fewer resources"
+	default:
+		msg = "no node has room for the job right now; retry later or request fewer resources"
+		if req.CountryConstraint != "" {
+			msg += fmt.Sprintf(", or drop the %s country constraint", req.CountryConstraint)
+		}
+		switch {
+		case req.Placement.RequiredNodeID != "":
+			msg = fmt.Sprintf("node %s cannot take the job right now; retry later or submit it without pinning it to that node", req.Placement.RequiredNodeID)
+		case req.Placement.hard():
+			msg += ", or relax its " + req.Placement.hardTerms()
+		}
+		if req.QuoteToken != "" && req.Placement.RequiredNodeID == "" {
+			msg += "; a price quote covers only the nodes it priced, so a fresh estimate may find others"
+		}
+	}
+	return &SubmitError{
+		Class:      SubmitErrNoCapacity,
+		Reason:     reason,
+		WantedRung: wanted,
+		Message:    msg,
+		Err:        fmt.Errorf("find nodes: %w", err),
+	}
+}
@@ -0,0 +1,121 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/sounding"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
+)
+
+// TestSubmitJob_ErrorClasses: every rejection a consumer can act on comes back
+// as a *SubmitError of the right class, with advice in Message, and the cause
+// still reachable for errors.Is and logs.
+func TestSubmitJob_ErrorClasses(t *testing.T) {
+	orch := New(nil, NewNodeRegistry(), []byte("secret"), nil, writeInstrAllowlist(t), false, 0)
+	orch.AttachDemandSounding(nil, testLadder())
+
+	base := SubmitJobRequest{
+		ConsumerID:     "c1",
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: instrComputeImage,
+		CPUCores:       4,
+		RAMMB:          8192,
+	}
+	tests := []struct {
+		name       string
+		mutate     func(*SubmitJobRequest)
+		class      SubmitErrorClass
+		reason     string
+		wantedRung string
+		message    string
+	}{
+		{
+			name:    "validation",
+			mutate:  func(r *SubmitJobRequest) { r.MaxRuntimeSeconds = -1 },
+			class:   SubmitErrValidation,
+			message: "MaxRuntimeSeconds must not be negative",
+		},
+		{
+			name:    "invalid quote",
+			mutate:  func(r *SubmitJobRequest) { r.QuoteToken = "not-a-quote" },
+			class:   SubmitErrPaymentRequired,
+			reason:  ReasonInvalidQuote,
+			message: "request a new estimate",
+		},
+		{
+			name: "image not allowlisted",
+			mutate: func(r *SubmitJobRequest) {
+				r.ContainerImage = "soholink/other-worker@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+			},
+			class:   SubmitErrImageNotAllowlisted,
+			message: "soholink/other-worker",
+		},
+		{
+			name:    "type mismatch",
+			mutate:  func(r *SubmitJobRequest) { r.WorkloadType = types.MarketplaceObjectStorage },
+			class:   SubmitErrTypeMismatch,
+			message: "allowlisted for compute workloads",
+		},
+		{
+			name:       "no capacity",
+			mutate:     func(r *SubmitJobRequest) { r.CountryConstraint = "DE" },
+			class:      SubmitErrNoCapacity,
+			reason:     sounding.ReasonNoCapacity,
+			wantedRung: "congestus",
+			message:    "drop the DE country constraint",
+		},
+		{
+			name:       "too big",
+			mutate:     func(r *SubmitJobRequest) { r.CPUCores = 64 },
+			class:      SubmitErrNoCapacity,
+			reason:     sounding.ReasonTooBig,
+			wantedRung: "storm",
+			message:    "request fewer resources",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := base
+			tt.mutate(&req)
+			_, err := orch.SubmitJob(context.Background(), req)
+			var se *SubmitError
+			if !errors.As(err, &se) {
+				t.Fatalf("err = %v, want a *SubmitError", err)
+			}
+			if se.Class != tt.class || se.Reason != tt.reason || se.WantedRung != tt.wantedRung {
+				t.Errorf("got %s/%q/%q, want %s/%q/%q",
+					se.Class, se.Reason, se.WantedRung, tt.class, tt.reason, tt.wantedRung)
+			}
+			if !strings.Contains(se.Message, tt.message) {
+				t.Errorf("Message = %q, want it to contain %q", se.Message, tt.message)
+			}
+			if se.Err == nil {
+				t.Error("Err is nil; the cause is lost")
+			}
+		})
+	}
+
+	_, err := orch.SubmitJob(context.Background(), SubmitJobRequest{
+		ConsumerID:     "c1",
+		WorkloadType:   types.MarketplaceBatchCompute,
+		ContainerImage: instrComputeImage,
+		QuoteToken:     "not-a-quote",
+	})
+	if !errors.Is(err, ErrInvalidQuote) {
+		t.Errorf("quote rejection %v does not wrap ErrInvalidQuote", err)
+	}
+}
+
+func TestSubmitErrorOf_Unclassified(t *testing.T) {
+	cause := errors.New("begin transaction: connection refused")
+	se := SubmitErrorOf(cause)
+	if se.Class != SubmitErrInternal || !errors.Is(se, cause) {
+		t.Errorf("SubmitErrorOf(plain error) = %+v, want class internal wrapping the cause", se)
+	}
+	if strings.Contains(se.Message, "connection refused") {
+		t.Errorf("internal Message leaks the cause: %q", se.Message)
+	}
+}
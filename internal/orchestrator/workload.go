@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/agent"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
@@ -20,6 +21,26 @@ var marketplaceToAgent = map[types.MarketplaceWorkloadType]agent.WorkloadType{
 	types.MarketplacePrint3D:          agent.WorkloadPrint3D,
 }
 
+// maxRuntimeCeiling is the platform's limit on a job's runtime, per
+// workload type: the most a consumer may declare, and the limit a job gets
+// when it declares none (migration 037). Long-lived services get a month;
+// batch and inference work a day; a print job a generous print time.
+var maxRuntimeCeiling = map[types.MarketplaceWorkloadType]time.Duration{
+	types.MarketplaceAppHosting:       30 * 24 * time.Hour,
+	types.MarketplaceBatchCompute:     24 * time.Hour,
+	types.MarketplaceAIInference:      24 * time.Hour,
+	types.MarketplaceObjectStorage:    30 * 24 * time.Hour,
+	types.MarketplaceCDNEdge:          30 * 24 * time.Hour,
+	types.MarketplacePrintTraditional: 4 * time.Hour,
+	types.MarketplacePrint3D:          72 * time.Hour,
+}
+
+// MaxRuntimeCeiling returns the platform's runtime ceiling for w, or zero for
+// a type without one — which MustValidateWorkloadMapping rules out at startup.
+func MaxRuntimeCeiling(w types.MarketplaceWorkloadType) time.Duration {
+	return maxRuntimeCeiling[w]
+}
+
 // MarketplaceToAgent translates a MarketplaceWorkloadType to the agent
 // WorkloadType used by OptOutStore.IsResourceEnabled and AllowlistEntry.Type.
 // Returns an error if the marketplace value has no mapping — which should
@@ -33,7 +54,8 @@ func MarketplaceToAgent(w types.MarketplaceWorkloadType) (agent.WorkloadType, er
 }
 
 // MustValidateWorkloadMapping panics if any value returned by
-// AllMarketplaceWorkloadTypes() lacks an entry in marketplaceToAgent.
+// AllMarketplaceWorkloadTypes() lacks an entry in marketplaceToAgent or
+// maxRuntimeCeiling.
 // Call this from an init() or a one-time startup check to catch
 // incomplete mappings at process start rather than at job dispatch time.
 func MustValidateWorkloadMapping() {
@@ -41,5 +63,8 @@ func MustValidateWorkloadMapping() {
 		if _, ok := marketplaceToAgent[w]; !ok {
 			panic(fmt.Sprintf("workload mapping incomplete: no agent type for %q", w))
 		}
+		if _, ok := maxRuntimeCeiling[w]; !ok {
+			panic(fmt.Sprintf("workload mapping incomplete: no runtime ceiling for %q", w))
+		}
 	}
 }
@@ -3,6 +3,7 @@ package orchestrator
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
 )
@@ -60,3 +61,43 @@ func TestMustValidateWorkloadMapping_PanicsOnMissing(t *testing.T) {
 	}()
 	MustValidateWorkloadMapping()
 }
+
+func TestMustValidateWorkloadMapping_PanicsOnMissingCeiling(t *testing.T) {
+	missing := types.MarketplaceCDNEdge
+	saved := maxRuntimeCeiling[missing]
+	delete(maxRuntimeCeiling, missing)
+	t.Cleanup(func() { maxRuntimeCeiling[missing] = saved })
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatal("expected panic for missing runtime ceiling, got none")
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, string(missing)) {
+			t.Errorf("panic message %q does not name the missing value %q", msg, missing)
+		}
+	}()
+	MustValidateWorkloadMapping()
+}
+
+func TestSubmitJobRequest_MaxRuntimeDefaultsToCeiling(t *testing.T) {
+	req := SubmitJobRequest{WorkloadType: types.MarketplacePrintTraditional}
+	if got, want := req.maxRuntimeSeconds(), 4*60*60; got != want {
+		t.Errorf("default maxRuntimeSeconds = %d, want the ceiling %d", got, want)
+	}
+	req.MaxRuntimeSeconds = 600
+	if got := req.maxRuntimeSeconds(); got != 600 {
+		t.Errorf("declared maxRuntimeSeconds = %d, want 600", got)
+	}
+}
+
+func TestSubmitJobRequest_EscrowCapsMaxRuntime(t *testing.T) {
+	req := SubmitJobRequest{WorkloadType: types.MarketplaceAppHosting, Escrow: true}
+	if got, want := req.maxRuntimeSeconds(), int(EscrowMaxRuntime/time.Second); got != want {
+		t.Errorf("escrowed default maxRuntimeSeconds = %d, want EscrowMaxRuntime %d", got, want)
+	}
+	req.WorkloadType = types.MarketplaceBatchCompute
+	if got, want := req.maxRuntimeSeconds(), 24*60*60; got != want {
+		t.Errorf("escrowed batch maxRuntimeSeconds = %d, want its lower ceiling %d", got, want)
+	}
+}
@@ -0,0 +1,140 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+
+	stripe "github.com/stripe/stripe-go/v82"
+)
+
+// MinChargeCents is the smallest amount Stripe will charge in USD. Escrow
+// holds are at least this much; a metered cost below it is not captured.
+const MinChargeCents = 50
+
+// EscrowCharge carries the identifiers of a newly created escrow hold.
+type EscrowCharge struct {
+	PaymentIntentID string
+	ClientSecret    string
+}
+
+// EscrowState is the Stripe-side state of an escrow hold.
+type EscrowState struct {
+	Status       stripe.PaymentIntentStatus
+	ClientSecret string
+}
+
+// Authorized reports whether the consumer has authorized the hold, i.e. the
+// intent is waiting to be captured.
+func (s EscrowState) Authorized() bool {
+	return s.Status == stripe.PaymentIntentStatusRequiresCapture
+}
+
+// CreateEscrowCharge creates a manual-capture PaymentIntent on the platform
+// account holding amountCents for job jobID. Nothing is transferred at
+// charge time: on settlement the metered cost is captured and each run's
+// contributor share is transferred separately (TransferEarnings), so a job
+// rerouted to another node still pays the owner who ran it. The transfer
+// group and metadata tie the intent to the job.
+//
+// The returned ClientSecret is passed to Stripe.js, where the consumer
+// authorizes the hold. Keyed on jobID, so a retried submit cannot create a
+// second hold for the same job.
+func (c *Client) CreateEscrowCharge(ctx context.Context, amountCents int64, jobID string) (EscrowCharge, error) {
+	if amountCents < MinChargeCents {
+		return EscrowCharge{}, fmt.Errorf("create escrow charge: amountCents must be at least %d", MinChargeCents)
+	}
+	if jobID == "" {
+		return EscrowCharge{}, fmt.Errorf("create escrow charge: jobID must be set")
+	}
+
+	params := &stripe.PaymentIntentCreateParams{
+		Amount:        stripe.Int64(amountCents),
+		Currency:      stripe.String("usd"),
+		CaptureMethod: stripe.String(string(stripe.PaymentIntentCaptureMethodManual)),
+		AutomaticPaymentMethods: &stripe.PaymentIntentCreateAutomaticPaymentMethodsParams{
+			Enabled: stripe.Bool(true),
+		},
+		Description:   stripe.String("SoHoLINK job " + jobID),
+		TransferGroup: stripe.String("job:" + jobID),
+		Metadata:      map[string]string{"job_id": jobID},
+	}
+	params.SetIdempotencyKey("escrow:" + jobID)
+
+	pi, err := c.sc.V1PaymentIntents.Create(ctx, params)
+	if err != nil {
+		return EscrowCharge{}, fmt.Errorf("create escrow charge: %w", err)
+	}
+	return EscrowCharge{PaymentIntentID: pi.ID, ClientSecret: pi.ClientSecret}, nil
+}
+
+// RetrieveEscrow returns the current state of the escrow hold paymentIntentID.
+func (c *Client) RetrieveEscrow(ctx context.Context, paymentIntentID string) (EscrowState, error) {
+	pi, err := c.sc.V1PaymentIntents.Retrieve(ctx, paymentIntentID, &stripe.PaymentIntentRetrieveParams{})
+	if err != nil {
+		return EscrowState{}, fmt.Errorf("retrieve escrow: %w", err)
+	}
+	return EscrowState{Status: pi.Status, ClientSecret: pi.ClientSecret}, nil
+}
+
+// CaptureEscrow captures amountCents of the hold paymentIntentID and returns
+// the resulting charge's ID. The rest of the hold is released back to the
+// consumer by Stripe. Keyed on jobID, so a retry returns the original capture.
+func (c *Client) CaptureEscrow(ctx context.Context, paymentIntentID string, amountCents int64, jobID string) (string, error) {
+	if amountCents <= 0 {
+		return "", fmt.Errorf("capture escrow: amountCents must be positive")
+	}
+	params := &stripe.PaymentIntentCaptureParams{
+		AmountToCapture: stripe.Int64(amountCents),
+	}
+	params.SetIdempotencyKey("escrow-capture:" + jobID)
+
+	pi, err := c.sc.V1PaymentIntents.Capture(ctx, paymentIntentID, params)
+	if err != nil {
+		return "", fmt.Errorf("capture escrow: %w", err)
+	}
+	if pi.LatestCharge == nil {
+		return "", fmt.Errorf("capture escrow: intent %s has no charge", paymentIntentID)
+	}
+	return pi.LatestCharge.ID, nil
+}
+
+// ReleaseEscrow cancels the hold paymentIntentID without charging anything.
+// Keyed on jobID, so a retry returns the original cancellation.
+func (c *Client) ReleaseEscrow(ctx context.Context, paymentIntentID, jobID string) error {
+	params := &stripe.PaymentIntentCancelParams{}
+	params.SetIdempotencyKey("escrow-release:" + jobID)
+
+	if _, err := c.sc.V1PaymentIntents.Cancel(ctx, paymentIntentID, params); err != nil {
+		return fmt.Errorf("release escrow: %w", err)
+	}
+	return nil
+}
+
+// TransferEarnings transfers amountCents of the captured charge
+// sourceChargeID to connectedAccountID — one run's contributor share. Using
+// the charge as the source transaction lets the transfer go out before the
+// charge's funds are available in the platform balance. Keyed on the run's
+// jobID, as TriggerPayout is, so a retry cannot transfer twice.
+func (c *Client) TransferEarnings(ctx context.Context, connectedAccountID string, amountCents int64, sourceChargeID, jobID string) (string, error) {
+	if amountCents <= 0 {
+		return "", fmt.Errorf("transfer earnings: amountCents must be positive")
+	}
+	if jobID == "" {
+		return "", fmt.Errorf("transfer earnings: jobID must be set")
+	}
+
+	params := &stripe.TransferCreateParams{
+		Amount:            stripe.Int64(amountCents),
+		Currency:          stripe.String("usd"),
+		Destination:       stripe.String(connectedAccountID),
+		SourceTransaction: stripe.String(sourceChargeID),
+		Metadata:          map[string]string{"job_id": jobID},
+	}
+	params.SetIdempotencyKey("transfer:" + jobID)
+
+	tr, err := c.sc.V1Transfers.Create(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("transfer earnings: %w", err)
+	}
+	return tr.ID, nil
+}
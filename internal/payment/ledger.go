@@ -0,0 +1,482 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	stripe "github.com/stripe/stripe-go/v82"
+)
+
+// PlatformAccount is the Ledger account holding the platform's own balance:
+// captured charges, less what was transferred on to connected accounts and
+// refunded. Unlike a connected account it may go negative, as a Stripe
+// platform balance can.
+const PlatformAccount = "platform"
+
+// ErrInsufficientFunds is returned by Ledger.TriggerPayout when the connected
+// account's balance does not cover the payout.
+var ErrInsufficientFunds = errors.New("payment: insufficient funds")
+
+// LedgerEntry is one money movement recorded by a Ledger. From and To are
+// ledger accounts; an empty account is outside the ledger — the consumer's
+// card for a charge and a refund, the owner's bank for a payout.
+type LedgerEntry struct {
+	ID          string // the charge, refund, transfer or payout that moved the money
+	Kind        string // "charge", "refund", "transfer", "payout" or "payout_failure"
+	From        string
+	To          string
+	AmountCents int64
+	At          time.Time
+}
+
+// ledgerIntent is a Ledger's record of a PaymentIntent and, once captured,
+// its charge.
+type ledgerIntent struct {
+	id               string
+	clientSecret     string
+	status           stripe.PaymentIntentStatus
+	amountCents      int64
+	chargeID         string
+	capturedCents    int64
+	refundedCents    int64
+	transferredCents int64
+}
+
+// ledgerPayout is a Ledger's record of a payout.
+type ledgerPayout struct {
+	accountID   string
+	amountCents int64
+	status      stripe.PayoutStatus
+}
+
+// Ledger is an in-memory PaymentProvider. It follows the Stripe provider's
+// rules — the same validation, idempotency keys and intent statuses — and
+// records every money movement as a LedgerEntry instead of calling out, so
+// billing can run end to end with no network. Connected accounts are ready
+// for payouts as soon as they are created, and destination charges settle
+// immediately; an escrow hold waits for Authorize, which stands in for the
+// consumer confirming it with Stripe.js.
+//
+// State lives for the life of the process. A Ledger is safe for concurrent
+// use.
+type Ledger struct {
+	mu        sync.Mutex
+	seq       int
+	balances  map[string]int64 // PlatformAccount and each connected account
+	intents   map[string]*ledgerIntent
+	charges   map[string]*ledgerIntent // by charge ID
+	transfers map[string]int64         // transfer ID → amount
+	payouts   map[string]*ledgerPayout
+	keys      map[string]string // idempotency key → object ID
+	entries   []LedgerEntry
+}
+
+// NewLedger returns an empty Ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		balances:  map[string]int64{PlatformAccount: 0},
+		intents:   make(map[string]*ledgerIntent),
+		charges:   make(map[string]*ledgerIntent),
+		transfers: make(map[string]int64),
+		payouts:   make(map[string]*ledgerPayout),
+		keys:      make(map[string]string),
+	}
+}
+
+// Balance returns accountID's balance in cents: PlatformAccount or a
+// connected account. An unknown account has a zero balance.
+func (l *Ledger) Balance(accountID string) int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.balances[accountID]
+}
+
+// Entries returns every money movement recorded so far, oldest first.
+func (l *Ledger) Entries() []LedgerEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LedgerEntry(nil), l.entries...)
+}
+
+// Authorize marks the escrow hold paymentIntentID as authorized by the
+// consumer, as confirming it with Stripe.js would. Authorizing an authorized
+// hold is a no-op.
+func (l *Ledger) Authorize(paymentIntentID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pi, ok := l.intents[paymentIntentID]
+	if !ok {
+		return fmt.Errorf("authorize: no such payment intent %s", paymentIntentID)
+	}
+	switch pi.status {
+	case stripe.PaymentIntentStatusRequiresCapture:
+		return nil
+	case stripe.PaymentIntentStatusRequiresPaymentMethod:
+		pi.status = stripe.PaymentIntentStatusRequiresCapture
+		return nil
+	default:
+		return fmt.Errorf("authorize: payment intent %s is %s", paymentIntentID, pi.status)
+	}
+}
+
+// FailPayout marks payoutID failed and returns its amount to the connected
+// account's balance, as a bank rejecting a Stripe payout would. Failing a
+// failed payout is a no-op.
+func (l *Ledger) FailPayout(payoutID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	po, ok := l.payouts[payoutID]
+	if !ok {
+		return fmt.Errorf("fail payout: no such payout %s", payoutID)
+	}
+	if po.status == stripe.PayoutStatusFailed {
+		return nil
+	}
+	po.status = stripe.PayoutStatusFailed
+	l.post("payout_failure", payoutID, "", po.accountID, po.amountCents)
+	return nil
+}
+
+// newID returns a fresh object ID with the given Stripe-style prefix.
+// Callers hold l.mu.
+func (l *Ledger) newID(prefix string) string {
+	l.seq++
+	return fmt.Sprintf("%s_ledger_%d", prefix, l.seq)
+}
+
+// post records a money movement and applies it to the balances. Callers
+// hold l.mu.
+func (l *Ledger) post(kind, id, from, to string, amountCents int64) {
+	if from != "" {
+		l.balances[from] -= amountCents
+	}
+	if to != "" {
+		l.balances[to] += amountCents
+	}
+	l.entries = append(l.entries, LedgerEntry{
+		ID: id, Kind: kind, From: from, To: to, AmountCents: amountCents, At: time.Now(),
+	})
+}
+
+// hasAccount reports whether accountID is a connected account. Callers hold
+// l.mu.
+func (l *Ledger) hasAccount(accountID string) bool {
+	_, ok := l.balances[accountID]
+	return ok && accountID != PlatformAccount
+}
+
+// CreateConnectedAccount creates a connected account, ready for transfers
+// and payouts at once.
+func (l *Ledger) CreateConnectedAccount(_ context.Context, _, _ string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := l.newID("acct")
+	l.balances[id] = 0
+	return id, nil
+}
+
+// CreateOnboardingLink returns returnURL: a Ledger account has nothing left
+// to onboard.
+func (l *Ledger) CreateOnboardingLink(_ context.Context, accountID, _, returnURL string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.hasAccount(accountID) {
+		return "", fmt.Errorf("create onboarding link: no such account %s", accountID)
+	}
+	return returnURL, nil
+}
+
+// CheckOnboardingStatus reports every Ledger account as ready for payouts.
+func (l *Ledger) CheckOnboardingStatus(_ context.Context, accountID string) (OnboardingStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.hasAccount(accountID) {
+		return OnboardingStatus{}, fmt.Errorf("retrieve account: no such account %s", accountID)
+	}
+	return OnboardingStatus{TransfersActive: true}, nil
+}
+
+// CreateDestinationCharge charges amountCents, which settles at once:
+// amountCents less feeCents is transferred on to connectedAccountID.
+func (l *Ledger) CreateDestinationCharge(_ context.Context, amountCents, feeCents int64, connectedAccountID string) (DestinationChargeResult, error) {
+	if amountCents <= 0 {
+		return DestinationChargeResult{}, fmt.Errorf("create destination charge: amountCents must be positive")
+	}
+	if feeCents < 0 {
+		return DestinationChargeResult{}, fmt.Errorf("create destination charge: feeCents must be non-negative")
+	}
+	if feeCents >= amountCents {
+		return DestinationChargeResult{}, fmt.Errorf("create destination charge: feeCents (%d) must be less than amountCents (%d)", feeCents, amountCents)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.hasAccount(connectedAccountID) {
+		return DestinationChargeResult{}, fmt.Errorf("create destination charge: no such account %s", connectedAccountID)
+	}
+	pi := &ledgerIntent{
+		id:            l.newID("pi"),
+		status:        stripe.PaymentIntentStatusSucceeded,
+		amountCents:   amountCents,
+		chargeID:      l.newID("ch"),
+		capturedCents: amountCents,
+	}
+	pi.clientSecret = pi.id + "_secret"
+	pi.transferredCents = amountCents - feeCents
+	l.intents[pi.id] = pi
+	l.charges[pi.chargeID] = pi
+
+	l.post("charge", pi.chargeID, "", PlatformAccount, amountCents)
+	trID := l.newID("tr")
+	l.transfers[trID] = pi.transferredCents
+	l.post("transfer", trID, PlatformAccount, connectedAccountID, pi.transferredCents)
+
+	return DestinationChargeResult{PaymentIntentID: pi.id, ClientSecret: pi.clientSecret}, nil
+}
+
+// CreateRefund refunds amountCents of paymentIntentID's charge from the
+// platform balance. The refunds on a charge may not exceed what it captured.
+func (l *Ledger) CreateRefund(_ context.Context, paymentIntentID string, amountCents int64) error {
+	if amountCents <= 0 {
+		return fmt.Errorf("create refund: amountCents must be positive")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pi, ok := l.intents[paymentIntentID]
+	if !ok {
+		return fmt.Errorf("create refund: no such payment intent %s", paymentIntentID)
+	}
+	if pi.status != stripe.PaymentIntentStatusSucceeded {
+		return fmt.Errorf("create refund: payment intent %s is %s", paymentIntentID, pi.status)
+	}
+	if pi.refundedCents+amountCents > pi.capturedCents {
+		return fmt.Errorf("create refund: %d exceeds the %d left to refund", amountCents, pi.capturedCents-pi.refundedCents)
+	}
+	pi.refundedCents += amountCents
+	l.post("refund", l.newID("re"), PlatformAccount, "", amountCents)
+	return nil
+}
+
+// CreateEscrowCharge creates a hold of amountCents for job jobID, awaiting
+// Authorize. Keyed on jobID as the Stripe provider is, so a retry returns
+// the original hold.
+func (l *Ledger) CreateEscrowCharge(_ context.Context, amountCents int64, jobID string) (EscrowCharge, error) {
+	if amountCents < MinChargeCents {
+		return EscrowCharge{}, fmt.Errorf("create escrow charge: amountCents must be at least %d", MinChargeCents)
+	}
+	if jobID == "" {
+		return EscrowCharge{}, fmt.Errorf("create escrow charge: jobID must be set")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := "escrow:" + jobID
+	if id, ok := l.keys[key]; ok {
+		pi := l.intents[id]
+		return EscrowCharge{PaymentIntentID: pi.id, ClientSecret: pi.clientSecret}, nil
+	}
+	pi := &ledgerIntent{
+		id:          l.newID("pi"),
+		status:      stripe.PaymentIntentStatusRequiresPaymentMethod,
+		amountCents: amountCents,
+	}
+	pi.clientSecret = pi.id + "_secret"
+	l.intents[pi.id] = pi
+	l.keys[key] = pi.id
+	return EscrowCharge{PaymentIntentID: pi.id, ClientSecret: pi.clientSecret}, nil
+}
+
+// RetrieveEscrow returns the current state of the hold paymentIntentID.
+func (l *Ledger) RetrieveEscrow(_ context.Context, paymentIntentID string) (EscrowState, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pi, ok := l.intents[paymentIntentID]
+	if !ok {
+		return EscrowState{}, fmt.Errorf("retrieve escrow: no such payment intent %s", paymentIntentID)
+	}
+	return EscrowState{Status: pi.status, ClientSecret: pi.clientSecret}, nil
+}
+
+// CaptureEscrow captures amountCents of the authorized hold paymentIntentID
+// into the platform balance and returns the charge's ID; the rest of the
+// hold is released. Keyed on jobID, so a retry returns the original capture.
+func (l *Ledger) CaptureEscrow(_ context.Context, paymentIntentID string, amountCents int64, jobID string) (string, error) {
+	if amountCents <= 0 {
+		return "", fmt.Errorf("capture escrow: amountCents must be positive")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := "escrow-capture:" + jobID
+	if id, ok := l.keys[key]; ok {
+		return id, nil
+	}
+	pi, ok := l.intents[paymentIntentID]
+	if !ok {
+		return "", fmt.Errorf("capture escrow: no such payment intent %s", paymentIntentID)
+	}
+	if pi.status != stripe.PaymentIntentStatusRequiresCapture {
+		return "", fmt.Errorf("capture escrow: payment intent %s is %s", paymentIntentID, pi.status)
+	}
+	if amountCents > pi.amountCents {
+		return "", fmt.Errorf("capture escrow: %d exceeds the %d authorized", amountCents, pi.amountCents)
+	}
+	pi.status = stripe.PaymentIntentStatusSucceeded
+	pi.chargeID = l.newID("ch")
+	pi.capturedCents = amountCents
+	l.charges[pi.chargeID] = pi
+	l.keys[key] = pi.chargeID
+
+	l.post("charge", pi.chargeID, "", PlatformAccount, amountCents)
+	return pi.chargeID, nil
+}
+
+// ReleaseEscrow cancels the hold paymentIntentID without charging anything.
+// Releasing a cancelled hold is a no-op.
+func (l *Ledger) ReleaseEscrow(_ context.Context, paymentIntentID, _ string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pi, ok := l.intents[paymentIntentID]
+	if !ok {
+		return fmt.Errorf("release escrow: no such payment intent %s", paymentIntentID)
+	}
+	switch pi.status {
+	case stripe.PaymentIntentStatusCanceled:
+		return nil
+	case stripe.PaymentIntentStatusSucceeded:
+		return fmt.Errorf("release escrow: payment intent %s is already captured", paymentIntentID)
+	}
+	pi.status = stripe.PaymentIntentStatusCanceled
+	return nil
+}
+
+// TransferEarnings transfers amountCents of the captured charge
+// sourceChargeID to connectedAccountID. The transfers out of a charge may
+// not exceed what it captured. Keyed on jobID, so a retry returns the
+// original transfer.
+func (l *Ledger) TransferEarnings(_ context.Context, connectedAccountID string, amountCents int64, sourceChargeID, jobID string) (string, error) {
+	if amountCents <= 0 {
+		return "", fmt.Errorf("transfer earnings: amountCents must be positive")
+	}
+	if jobID == "" {
+		return "", fmt.Errorf("transfer earnings: jobID must be set")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := "transfer:" + jobID
+	if id, ok := l.keys[key]; ok {
+		return id, nil
+	}
+	if !l.hasAccount(connectedAccountID) {
+		return "", fmt.Errorf("transfer earnings: no such account %s", connectedAccountID)
+	}
+	pi, ok := l.charges[sourceChargeID]
+	if !ok {
+		return "", fmt.Errorf("transfer earnings: no such charge %s", sourceChargeID)
+	}
+	if pi.transferredCents+amountCents > pi.capturedCents {
+		return "", fmt.Errorf("transfer earnings: %d exceeds the %d left on charge %s",
+			amountCents, pi.capturedCents-pi.transferredCents, sourceChargeID)
+	}
+	pi.transferredCents += amountCents
+	id := l.newID("tr")
+	l.keys[key] = id
+	l.transfers[id] = amountCents
+
+	l.post("transfer", id, PlatformAccount, connectedAccountID, amountCents)
+	return id, nil
+}
+
+// TriggerPayout pays amountCents out of connectedAccountID's balance,
+// failing with ErrInsufficientFunds when the balance does not cover it.
+// Keyed on idempotencyKey as the Stripe provider is, so a retry returns the
+// original payout.
+func (l *Ledger) TriggerPayout(_ context.Context, connectedAccountID string, amountCents int64, idempotencyKey string) (string, error) {
+	if amountCents <= 0 {
+		return "", fmt.Errorf("trigger payout: amountCents must be positive")
+	}
+	if idempotencyKey == "" {
+		return "", fmt.Errorf("trigger payout: idempotencyKey must be set")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := "payout:" + idempotencyKey
+	if id, ok := l.keys[key]; ok {
+		return id, nil
+	}
+	if !l.hasAccount(connectedAccountID) {
+		return "", fmt.Errorf("trigger payout: no such account %s", connectedAccountID)
+	}
+	if l.balances[connectedAccountID] < amountCents {
+		return "", fmt.Errorf("trigger payout: %w: balance %d, payout %d",
+			ErrInsufficientFunds, l.balances[connectedAccountID], amountCents)
+	}
+	id := l.newID("po")
+	l.keys[key] = id
+	l.payouts[id] = &ledgerPayout{accountID: connectedAccountID, amountCents: amountCents, status: stripe.PayoutStatusPaid}
+
+	l.post("payout", id, connectedAccountID, "", amountCents)
+	return id, nil
+}
+
+// RetrievePayment returns the Ledger's record of paymentIntentID.
+func (l *Ledger) RetrievePayment(_ context.Context, paymentIntentID string) (PaymentRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	pi, ok := l.intents[paymentIntentID]
+	if !ok {
+		return PaymentRecord{}, fmt.Errorf("retrieve payment: no such payment intent %s", paymentIntentID)
+	}
+	return PaymentRecord{
+		Status:        pi.status,
+		AmountCents:   pi.amountCents,
+		CapturedCents: pi.capturedCents,
+		RefundedCents: pi.refundedCents,
+	}, nil
+}
+
+// RetrievePayout returns the Ledger's record of payoutID, which must have
+// been paid out of connectedAccountID.
+func (l *Ledger) RetrievePayout(_ context.Context, connectedAccountID, payoutID string) (PayoutRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	po, ok := l.payouts[payoutID]
+	if !ok || po.accountID != connectedAccountID {
+		return PayoutRecord{}, fmt.Errorf("retrieve payout: no such payout %s on %s", payoutID, connectedAccountID)
+	}
+	return PayoutRecord{Status: po.status, AmountCents: po.amountCents}, nil
+}
+
+// RetrieveTransfer returns the Ledger's record of transferID. Ledger
+// transfers are never reversed.
+func (l *Ledger) RetrieveTransfer(_ context.Context, transferID string) (TransferRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	amount, ok := l.transfers[transferID]
+	if !ok {
+		return TransferRecord{}, fmt.Errorf("retrieve transfer: no such transfer %s", transferID)
+	}
+	return TransferRecord{AmountCents: amount}, nil
+}
@@ -0,0 +1,171 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLedger_EscrowSettlement(t *testing.T) {
+	ctx := context.Background()
+	l := NewLedger()
+
+	acct, err := l.CreateConnectedAccount(ctx, "Node Owner", "owner@test.com")
+	if err != nil {
+		t.Fatalf("CreateConnectedAccount: %v", err)
+	}
+	hold, err := l.CreateEscrowCharge(ctx, 1000, "job-1")
+	if err != nil {
+		t.Fatalf("CreateEscrowCharge: %v", err)
+	}
+	if _, err := l.CaptureEscrow(ctx, hold.PaymentIntentID, 600, "job-1"); err == nil {
+		t.Fatal("CaptureEscrow before Authorize: want error")
+	}
+	if err := l.Authorize(hold.PaymentIntentID); err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if st, _ := l.RetrieveEscrow(ctx, hold.PaymentIntentID); !st.Authorized() {
+		t.Fatalf("RetrieveEscrow status = %s, want authorized", st.Status)
+	}
+
+	chargeID, err := l.CaptureEscrow(ctx, hold.PaymentIntentID, 600, "job-1")
+	if err != nil {
+		t.Fatalf("CaptureEscrow: %v", err)
+	}
+	if _, err := l.TransferEarnings(ctx, acct, 540, chargeID, "job-1"); err != nil {
+		t.Fatalf("TransferEarnings: %v", err)
+	}
+	if _, err := l.TriggerPayout(ctx, acct, 540, "job-1"); err != nil {
+		t.Fatalf("TriggerPayout: %v", err)
+	}
+	if err := l.CreateRefund(ctx, hold.PaymentIntentID, 60); err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+
+	if got := l.Balance(PlatformAccount); got != 0 {
+		t.Errorf("platform balance = %d, want 0 (600 captured - 540 transferred - 60 refunded)", got)
+	}
+	if got := l.Balance(acct); got != 0 {
+		t.Errorf("account balance = %d, want 0 after payout", got)
+	}
+	if got := len(l.Entries()); got != 4 {
+		t.Errorf("entries = %d, want 4 (charge, transfer, payout, refund)", got)
+	}
+}
+
+func TestLedger_Idempotency(t *testing.T) {
+	ctx := context.Background()
+	l := NewLedger()
+
+	acct, _ := l.CreateConnectedAccount(ctx, "Node Owner", "owner@test.com")
+	first, _ := l.CreateEscrowCharge(ctx, 1000, "job-1")
+	again, err := l.CreateEscrowCharge(ctx, 1000, "job-1")
+	if err != nil || again.PaymentIntentID != first.PaymentIntentID {
+		t.Fatalf("retried CreateEscrowCharge = %q, %v; want %q", again.PaymentIntentID, err, first.PaymentIntentID)
+	}
+	_ = l.Authorize(first.PaymentIntentID)
+	chargeID, _ := l.CaptureEscrow(ctx, first.PaymentIntentID, 1000, "job-1")
+	if retry, err := l.CaptureEscrow(ctx, first.PaymentIntentID, 1000, "job-1"); err != nil || retry != chargeID {
+		t.Errorf("retried CaptureEscrow = %q, %v; want %q", retry, err, chargeID)
+	}
+	tr, _ := l.TransferEarnings(ctx, acct, 900, chargeID, "job-1")
+	if retry, err := l.TransferEarnings(ctx, acct, 900, chargeID, "job-1"); err != nil || retry != tr {
+		t.Errorf("retried TransferEarnings = %q, %v; want %q", retry, err, tr)
+	}
+	po, _ := l.TriggerPayout(ctx, acct, 900, "job-1")
+	if retry, err := l.TriggerPayout(ctx, acct, 900, "job-1"); err != nil || retry != po {
+		t.Errorf("retried TriggerPayout = %q, %v; want %q", retry, err, po)
+	}
+	if got := l.Balance(acct); got != 0 {
+		t.Errorf("account balance = %d, want 0 — retries must not move money twice", got)
+	}
+}
+
+func TestLedger_Limits(t *testing.T) {
+	ctx := context.Background()
+	l := NewLedger()
+
+	acct, _ := l.CreateConnectedAccount(ctx, "Node Owner", "owner@test.com")
+	hold, _ := l.CreateEscrowCharge(ctx, 500, "job-1")
+	_ = l.Authorize(hold.PaymentIntentID)
+
+	if _, err := l.CaptureEscrow(ctx, hold.PaymentIntentID, 501, "job-1"); err == nil {
+		t.Error("CaptureEscrow above the hold: want error")
+	}
+	chargeID, _ := l.CaptureEscrow(ctx, hold.PaymentIntentID, 400, "job-1")
+	if err := l.ReleaseEscrow(ctx, hold.PaymentIntentID, "job-1"); err == nil {
+		t.Error("ReleaseEscrow after capture: want error")
+	}
+	if _, err := l.TransferEarnings(ctx, acct, 401, chargeID, "job-1"); err == nil {
+		t.Error("TransferEarnings above the charge: want error")
+	}
+	if err := l.CreateRefund(ctx, hold.PaymentIntentID, 401); err == nil {
+		t.Error("CreateRefund above the charge: want error")
+	}
+	if _, err := l.TriggerPayout(ctx, acct, 1, "job-1"); !errors.Is(err, ErrInsufficientFunds) {
+		t.Errorf("TriggerPayout on an empty account = %v, want ErrInsufficientFunds", err)
+	}
+}
+
+func TestLedger_DestinationCharge(t *testing.T) {
+	ctx := context.Background()
+	l := NewLedger()
+
+	acct, _ := l.CreateConnectedAccount(ctx, "Node Owner", "owner@test.com")
+	if st, err := l.CheckOnboardingStatus(ctx, acct); err != nil || !st.TransfersActive {
+		t.Fatalf("CheckOnboardingStatus = %+v, %v; want transfers active", st, err)
+	}
+	if _, err := l.CreateDestinationCharge(ctx, 1000, 150, "acct_unknown"); err == nil {
+		t.Error("CreateDestinationCharge to an unknown account: want error")
+	}
+	if _, err := l.CreateDestinationCharge(ctx, 1000, 150, acct); err != nil {
+		t.Fatalf("CreateDestinationCharge: %v", err)
+	}
+	if got := l.Balance(acct); got != 850 {
+		t.Errorf("account balance = %d, want 850", got)
+	}
+	if got := l.Balance(PlatformAccount); got != 150 {
+		t.Errorf("platform balance = %d, want 150 (the fee)", got)
+	}
+}
+
+func TestLedger_Retrieve(t *testing.T) {
+	ctx := context.Background()
+	l := NewLedger()
+
+	acct, _ := l.CreateConnectedAccount(ctx, "Node Owner", "owner@test.com")
+	hold, _ := l.CreateEscrowCharge(ctx, 1000, "job-1")
+	_ = l.Authorize(hold.PaymentIntentID)
+	chargeID, _ := l.CaptureEscrow(ctx, hold.PaymentIntentID, 600, "job-1")
+	_ = l.CreateRefund(ctx, hold.PaymentIntentID, 100)
+
+	rec, err := l.RetrievePayment(ctx, hold.PaymentIntentID)
+	if err != nil {
+		t.Fatalf("RetrievePayment: %v", err)
+	}
+	if rec.AmountCents != 1000 || rec.CapturedCents != 600 || rec.RefundedCents != 100 {
+		t.Errorf("RetrievePayment = %+v, want 1000 authorized, 600 captured, 100 refunded", rec)
+	}
+
+	tr, _ := l.TransferEarnings(ctx, acct, 450, chargeID, "job-1")
+	if got, err := l.RetrieveTransfer(ctx, tr); err != nil || got.AmountCents != 450 {
+		t.Errorf("RetrieveTransfer = %+v, %v; want 450", got, err)
+	}
+
+	po, _ := l.TriggerPayout(ctx, acct, 450, "batch-1")
+	if got, err := l.RetrievePayout(ctx, acct, po); err != nil || got.Status != "paid" || got.AmountCents != 450 {
+		t.Errorf("RetrievePayout = %+v, %v; want paid 450", got, err)
+	}
+	if _, err := l.RetrievePayout(ctx, "acct_other", po); err == nil {
+		t.Error("RetrievePayout on another account: want error")
+	}
+	if err := l.FailPayout(po); err != nil {
+		t.Fatalf("FailPayout: %v", err)
+	}
+	if got, _ := l.RetrievePayout(ctx, acct, po); got.Status != "failed" {
+		t.Errorf("status after FailPayout = %s, want failed", got.Status)
+	}
+	if got := l.Balance(acct); got != 450 {
+		t.Errorf("account balance after failed payout = %d, want 450 returned", got)
+	}
+}
@@ -15,8 +15,8 @@ import (
 // account's balance rather than the platform balance.
 //
 // idempotencyKey MUST be stable per logical payout (the caller passes the
-// job id). If the releaser's post-transfer bookkeeping write fails and the
-// job is re-selected on the next tick, replaying the SAME key makes Stripe
+// payout batch id). If the releaser's post-payout bookkeeping write fails and
+// the batch is retried on the next tick, replaying the SAME key makes Stripe
 // return the original payout instead of creating a second one — this is the
 // backstop that closes the double-pay window (audit finding M1). An empty key
 // disables the header and is treated as a caller error.
@@ -34,7 +34,7 @@ func (c *Client) TriggerPayout(ctx context.Context, connectedAccountID string, a
 	}
 	params.SetStripeAccount(connectedAccountID)
 	// Scope the key to payouts so it can never collide with another Stripe
-	// operation that happens to key on the same job id.
+	// operation that happens to key on the same id.
 	params.SetIdempotencyKey("payout:" + idempotencyKey)
 
 	po, err := c.sc.V1Payouts.Create(ctx, params)
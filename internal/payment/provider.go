@@ -0,0 +1,43 @@
+package payment
+
+import "context"
+
+// PaymentProvider is the money-moving surface the portal and the settlement
+// runners depend on: connected accounts for node owners, consumer charges
+// (destination charges and escrow holds), refunds, transfers to connected
+// accounts and payouts from them. Client implements it against Stripe;
+// Ledger implements it in memory for dev and tests.
+//
+// Webhook handling is not part of the interface: HandleV1Event and
+// Client.HandleThinEvent verify Stripe signatures and only make sense with
+// the Stripe provider.
+type PaymentProvider interface {
+	// Connected accounts.
+	CreateConnectedAccount(ctx context.Context, displayName, email string) (string, error)
+	CreateOnboardingLink(ctx context.Context, accountID, refreshURL, returnURL string) (string, error)
+	CheckOnboardingStatus(ctx context.Context, accountID string) (OnboardingStatus, error)
+
+	// Charges and refunds.
+	CreateDestinationCharge(ctx context.Context, amountCents, feeCents int64, connectedAccountID string) (DestinationChargeResult, error)
+	CreateRefund(ctx context.Context, paymentIntentID string, amountCents int64) error
+
+	// Escrow holds and the transfers settled out of them.
+	CreateEscrowCharge(ctx context.Context, amountCents int64, jobID string) (EscrowCharge, error)
+	RetrieveEscrow(ctx context.Context, paymentIntentID string) (EscrowState, error)
+	CaptureEscrow(ctx context.Context, paymentIntentID string, amountCents int64, jobID string) (string, error)
+	ReleaseEscrow(ctx context.Context, paymentIntentID, jobID string) error
+	TransferEarnings(ctx context.Context, connectedAccountID string, amountCents int64, sourceChargeID, jobID string) (string, error)
+
+	// Payouts.
+	TriggerPayout(ctx context.Context, connectedAccountID string, amountCents int64, idempotencyKey string) (string, error)
+
+	// Reconciliation: the provider's own record of each money movement.
+	RetrievePayment(ctx context.Context, paymentIntentID string) (PaymentRecord, error)
+	RetrievePayout(ctx context.Context, connectedAccountID, payoutID string) (PayoutRecord, error)
+	RetrieveTransfer(ctx context.Context, transferID string) (TransferRecord, error)
+}
+
+var (
+	_ PaymentProvider = (*Client)(nil)
+	_ PaymentProvider = (*Ledger)(nil)
+)
@@ -0,0 +1,77 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+
+	stripe "github.com/stripe/stripe-go/v82"
+)
+
+// PaymentRecord is the provider's record of a consumer payment — an escrow
+// hold or a destination charge — for reconciliation against the job it paid.
+type PaymentRecord struct {
+	Status        stripe.PaymentIntentStatus
+	AmountCents   int64 // authorized (a hold) or charged
+	CapturedCents int64 // received; zero until captured
+	RefundedCents int64
+	Disputed      bool // the consumer's bank has opened a chargeback
+}
+
+// PayoutRecord is the provider's record of a payout from a connected
+// account.
+type PayoutRecord struct {
+	Status      stripe.PayoutStatus
+	AmountCents int64
+}
+
+// TransferRecord is the provider's record of a transfer to a connected
+// account.
+type TransferRecord struct {
+	AmountCents   int64
+	ReversedCents int64
+}
+
+// RetrievePayment returns the provider's record of paymentIntentID, with its
+// latest charge expanded for the refunded amount and dispute flag.
+func (c *Client) RetrievePayment(ctx context.Context, paymentIntentID string) (PaymentRecord, error) {
+	params := &stripe.PaymentIntentRetrieveParams{}
+	params.AddExpand("latest_charge")
+
+	pi, err := c.sc.V1PaymentIntents.Retrieve(ctx, paymentIntentID, params)
+	if err != nil {
+		return PaymentRecord{}, fmt.Errorf("retrieve payment: %w", err)
+	}
+	rec := PaymentRecord{
+		Status:        pi.Status,
+		AmountCents:   pi.Amount,
+		CapturedCents: pi.AmountReceived,
+	}
+	if pi.LatestCharge != nil {
+		rec.RefundedCents = pi.LatestCharge.AmountRefunded
+		rec.Disputed = pi.LatestCharge.Disputed
+	}
+	return rec, nil
+}
+
+// RetrievePayout returns the provider's record of payoutID, made from
+// connectedAccountID's balance. The Stripe-Account header is set because a
+// connected account's payouts are only visible on that account.
+func (c *Client) RetrievePayout(ctx context.Context, connectedAccountID, payoutID string) (PayoutRecord, error) {
+	params := &stripe.PayoutRetrieveParams{}
+	params.SetStripeAccount(connectedAccountID)
+
+	po, err := c.sc.V1Payouts.Retrieve(ctx, payoutID, params)
+	if err != nil {
+		return PayoutRecord{}, fmt.Errorf("retrieve payout: %w", err)
+	}
+	return PayoutRecord{Status: po.Status, AmountCents: po.Amount}, nil
+}
+
+// RetrieveTransfer returns the provider's record of transferID.
+func (c *Client) RetrieveTransfer(ctx context.Context, transferID string) (TransferRecord, error) {
+	tr, err := c.sc.V1Transfers.Retrieve(ctx, transferID, &stripe.TransferRetrieveParams{})
+	if err != nil {
+		return TransferRecord{}, fmt.Errorf("retrieve transfer: %w", err)
+	}
+	return TransferRecord{AmountCents: tr.Amount, ReversedCents: tr.AmountReversed}, nil
+}
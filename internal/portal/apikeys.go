@@ -0,0 +1,87 @@
+package portal
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// APIKeysData is the template data for consumer_api_keys.html. NewKey is the
+// key just created, shown this once.
+type APIKeysData struct {
+	Keys            []store.APIKey
+	Scopes          []string
+	NewKey          string
+	NewKeyName      string
+	Error           string
+	Email           string
+	IsAuthenticated bool
+}
+
+// renderAPIKeys renders consumer_api_keys.html with the caller's keys.
+func (ps *PortalServer) renderAPIKeys(w http.ResponseWriter, r *http.Request, data APIKeysData) {
+	claims, _ := ClaimsFromContext(r.Context())
+	keys, err := store.ListAPIKeys(r.Context(), ps.db, claims.UserID)
+	if err != nil {
+		slog.Error("list api keys failed", "participant_id", claims.UserID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	data.Keys = keys
+	data.Scopes = store.APIKeyScopes
+	data.Email = claims.Email
+	data.IsAuthenticated = true
+	ps.renderTemplate(w, "consumer_api_keys.html", data)
+}
+
+// handleAPIKeysPage lists the caller's API keys for the JSON API.
+func (ps *PortalServer) handleAPIKeysPage(w http.ResponseWriter, r *http.Request) {
+	ps.renderAPIKeys(w, r, APIKeysData{})
+}
+
+// handleCreateAPIKey creates an API key from the name and repeated scope form
+// fields and shows it once on the keys page.
+func (ps *PortalServer) handleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	key, secret, err := store.CreateAPIKey(r.Context(), ps.db, claims.UserID, r.FormValue("name"), r.Form["scope"])
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidAPIKey) {
+			ps.renderAPIKeys(w, r, APIKeysData{Error: "Give the key a name and at least one scope."})
+			return
+		}
+		slog.Error("create api key failed", "participant_id", claims.UserID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	ps.renderAPIKeys(w, r, APIKeysData{NewKey: secret, NewKeyName: key.Name})
+}
+
+// handleRevokeAPIKey revokes one of the caller's API keys.
+func (ps *PortalServer) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	keyID := r.PathValue("id")
+	if _, err := uuid.Parse(keyID); err != nil {
+		http.Error(w, "api key not found", http.StatusNotFound)
+		return
+	}
+
+	if err := store.RevokeAPIKey(r.Context(), ps.db, claims.UserID, keyID); err != nil {
+		if errors.Is(err, store.ErrAPIKeyNotFound) {
+			http.Error(w, "api key not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("revoke api key failed", "key_id", keyID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/consumer/api-keys", http.StatusSeeOther)
+}
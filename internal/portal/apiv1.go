@@ -0,0 +1,726 @@
+package portal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/metrics"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// The consumer JSON API (/api/v1) is the portal's consumer surface for
+// scripts: the same submit, status, cancel, logs, artifact and estimate
+// operations as the HTML pages, authenticated with a participant's API key
+// (store.CreateAPIKey) instead of a session cookie. docs/openapi.yaml
+// describes it.
+//
+// Every error is a JSON body {"error": <message>, "code": <code>}: the
+// message is for people and may change, the code is one of the apiCode*
+// constants below and is what clients should branch on.
+
+// API error codes.
+const (
+	apiCodeUnauthorized      = "unauthorized"
+	apiCodeInsufficientScope = "insufficient_scope"
+	apiCodeRateLimited       = "rate_limited"
+	apiCodeInvalidRequest    = "invalid_request"
+	apiCodeJobNotFound       = "job_not_found"
+	apiCodeJobNotCancellable = "job_not_cancellable"
+	apiCodeInputNotFound     = "input_not_found"
+	apiCodeInvalidQuote      = "invalid_quote"
+	apiCodeImageNotAllowed   = "image_not_allowlisted"
+	apiCodeTypeMismatch      = "workload_type_mismatch"
+	apiCodeNoCapacity        = "no_capacity"
+	apiCodeNoEstimate        = "estimate_unavailable"
+	apiCodePaymentFailed     = "payment_failed"
+	apiCodeArtifactNotFound  = "artifact_not_found"
+	apiCodeArtifactExpired   = "artifact_expired"
+	apiCodeUnavailable       = "unavailable"
+	apiCodeInternal          = "internal_error"
+)
+
+// apiMaxBodyBytes bounds a JSON request body.
+const apiMaxBodyBytes = 1 << 20
+
+// API rate limit per key: a burst of apiRateBurst requests, refilled at
+// apiRatePerSecond.
+const (
+	apiRateBurst     = 60
+	apiRatePerSecond = 1.0
+)
+
+// List and log page sizes.
+const (
+	apiDefaultPageSize = 50
+	apiMaxPageSize     = 200
+	apiMaxLogChunks    = 1000
+)
+
+// apiErrorBody is the JSON body of every /api/v1 error response.
+type apiErrorBody struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// apiPlacementErrorBody is the no_capacity error body: Reason is why no node
+// could take the job (no_capacity, too_big or no_matching_tier) and WantedTier
+// the capacity tier it fits, when there is one.
+type apiPlacementErrorBody struct {
+	apiErrorBody
+	Reason     string `json:"reason"`
+	WantedTier string `json:"wanted_tier,omitempty"`
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, msg string) {
+	writeAPIJSON(w, status, apiErrorBody{Error: msg, Code: code})
+}
+
+// decodeAPIJSON decodes a request body into v, rejecting unknown fields so a
+// misspelt field fails loudly instead of being ignored. On failure it writes
+// a 400 and returns false.
+func decodeAPIJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, "invalid JSON body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// requireAPIKey authenticates a /api/v1 request by its
+// "Authorization: Bearer <key>" header, requires the key to carry scope, and
+// spends one of the key's rate-limit tokens. Only an admitted request records
+// the key's use, so a flood of throttled requests causes no database writes.
+// The key's participant is stored in the request context as SessionClaims,
+// so handlers read it with ClaimsFromContext as the HTML handlers do.
+func (ps *PortalServer) requireAPIKey(scope string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || secret == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="soholink"`)
+			writeAPIError(w, http.StatusUnauthorized, apiCodeUnauthorized, "an API key is required")
+			return
+		}
+		key, err := store.AuthenticateAPIKey(r.Context(), ps.db, secret)
+		if err != nil {
+			if errors.Is(err, store.ErrAPIKeyNotFound) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="soholink", error="invalid_token"`)
+				writeAPIError(w, http.StatusUnauthorized, apiCodeUnauthorized, "invalid or revoked API key")
+				return
+			}
+			slog.Error("api key lookup failed", "error", err)
+			writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "database error")
+			return
+		}
+		if !key.HasScope(scope) {
+			writeAPIError(w, http.StatusForbidden, apiCodeInsufficientScope, "this API key lacks the "+scope+" scope")
+			return
+		}
+		if ok, wait := ps.apiLimiter.Allow(key.ID); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeAPIError(w, http.StatusTooManyRequests, apiCodeRateLimited, "rate limit exceeded")
+			return
+		}
+		if err := store.TouchAPIKey(r.Context(), ps.db, key.ID); err != nil {
+			slog.Warn("api key last_used_at update failed", "key_id", key.ID, "error", err)
+		}
+		ctx := context.WithValue(r.Context(), contextKey{}, SessionClaims{UserID: key.ParticipantID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// registerAPIRoutes mounts the consumer JSON API on mux.
+func (ps *PortalServer) registerAPIRoutes(mux *http.ServeMux) {
+	mux.Handle("POST /api/v1/jobs",
+		ps.requireAPIKey(store.ScopeJobsWrite, ps.handleAPISubmitJob))
+	mux.Handle("GET /api/v1/jobs",
+		ps.requireAPIKey(store.ScopeJobsRead, ps.handleAPIListJobs))
+	mux.Handle("GET /api/v1/jobs/{id}",
+		ps.requireAPIKey(store.ScopeJobsRead, ps.handleAPIGetJob))
+	mux.Handle("POST /api/v1/jobs/{id}/cancel",
+		ps.requireAPIKey(store.ScopeJobsWrite, ps.handleAPICancelJob))
+	mux.Handle("GET /api/v1/jobs/{id}/logs",
+		ps.requireAPIKey(store.ScopeJobsRead, ps.handleAPIJobLogs))
+	mux.Handle("GET /api/v1/jobs/{id}/artifacts",
+		ps.requireAPIKey(store.ScopeArtifactsRead, ps.handleAPIJobArtifacts))
+	mux.Handle("POST /api/v1/estimate",
+		ps.requireAPIKey(store.ScopeJobsRead, ps.handleAPIEstimate))
+}
+
+// apiJobID returns the request's {id} path value. Job IDs are UUIDs; any
+// other value answers 404, as an unknown job would.
+func apiJobID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := r.PathValue("id")
+	if _, err := uuid.Parse(id); err != nil {
+		writeAPIError(w, http.StatusNotFound, apiCodeJobNotFound, "job not found")
+		return "", false
+	}
+	return id, true
+}
+
+// apiJobShape is what a job asks for and is priced on, as in the portal's
+// form fields (see jobShape for the defaults). SLATier is the number of
+// replicas, each on a node of a distinct owner; zero means 1.
+type apiJobShape struct {
+	WorkloadType      string `json:"workload_type"`
+	CPUCores          int    `json:"cpu_cores"`
+	RAMMB             int    `json:"ram_mb"`
+	StorageGB         int    `json:"storage_gb"`
+	GPURequired       bool   `json:"gpu_required"`
+	GPUVRAMGB         int    `json:"gpu_vram_gb"`
+	MaxRuntimeSeconds int    `json:"max_runtime_seconds"`
+	SLATier           int    `json:"sla_tier"`
+}
+
+// apiJobInput is one job input: an upload named by its digest, or a URL the
+// agent fetches and checks against the digest.
+type apiJobInput struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	URL    string `json:"url,omitempty"`
+}
+
+// apiPlacement is a job's placement constraints (orchestrator.Placement).
+// The zero value leaves placement to the scheduler.
+type apiPlacement struct {
+	RequiredNodeID       string   `json:"required_node_id"`
+	PreferredNodeIDs     []string `json:"preferred_node_ids"`
+	PreferredOwnerIDs    []string `json:"preferred_owner_ids"`
+	ExcludedOwnerIDs     []string `json:"excluded_owner_ids"`
+	AntiAffinity         string   `json:"anti_affinity"`
+	AntiAffinityRequired bool     `json:"anti_affinity_required"`
+
+	NodeSelector map[string]string `json:"node_selector"`
+	Tolerations  []apiToleration   `json:"tolerations"`
+}
+
+// apiToleration is one node taint a job accepts (orchestrator.Toleration);
+// an empty value accepts any value of the key.
+type apiToleration struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (p apiPlacement) placement() orchestrator.Placement {
+	var tolerations []orchestrator.Toleration
+	for _, t := range p.Tolerations {
+		tolerations = append(tolerations, orchestrator.Toleration{Key: t.Key, Value: t.Value})
+	}
+	return orchestrator.Placement{
+		RequiredNodeID:       p.RequiredNodeID,
+		PreferredNodeIDs:     p.PreferredNodeIDs,
+		PreferredOwnerIDs:    p.PreferredOwnerIDs,
+		ExcludedOwnerIDs:     p.ExcludedOwnerIDs,
+		AntiAffinity:         orchestrator.AntiAffinity(p.AntiAffinity),
+		AntiAffinityRequired: p.AntiAffinityRequired,
+		NodeSelector:         p.NodeSelector,
+		Tolerations:          tolerations,
+	}
+}
+
+// apiSubmitRequest is the body of POST /api/v1/jobs. Quorum is how many of a
+// replicated job's replicas must succeed (zero: 1, or 2 with Verify); Verify
+// has them agree on the hash of the output_path file
+// (orchestrator.SubmitJobRequest.Verify).
+type apiSubmitRequest struct {
+	apiJobShape
+	Quorum         int           `json:"quorum"`
+	Verify         bool          `json:"verify"`
+	ContainerImage string        `json:"container_image"`
+	OutputPath     string        `json:"output_path"`
+	Inputs         []apiJobInput `json:"inputs"`
+	QuoteToken     string        `json:"quote_token"`
+	Placement      apiPlacement  `json:"placement"`
+}
+
+// apiSubmitResponse is the 201 body of POST /api/v1/jobs. For an escrowed
+// job PaymentURL is the page where the consumer authorizes the hold for
+// QuoteCents; the job is not dispatched until they do.
+type apiSubmitResponse struct {
+	JobID      string `json:"job_id"`
+	NodeID     string `json:"node_id,omitempty"`
+	QuoteCents int64  `json:"quote_cents,omitempty"`
+	PaymentURL string `json:"payment_url,omitempty"`
+}
+
+// handleAPISubmitJob submits a job, validated as the portal's form
+// submission is.
+func (ps *PortalServer) handleAPISubmitJob(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+
+	var body apiSubmitRequest
+	if !decodeAPIJSON(w, r, &body) {
+		return
+	}
+	req, err := jobShape(body.apiJobShape)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, err.Error())
+		return
+	}
+	if body.ContainerImage == "" {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, "container_image is required")
+		return
+	}
+	for _, in := range body.Inputs {
+		req.Inputs = append(req.Inputs, orchestrator.JobInput{
+			Name: in.Name, SHA256: strings.ToLower(in.SHA256), URL: in.URL,
+		})
+	}
+	wt := req.WorkloadType
+	req.ConsumerID = claims.UserID
+	req.ContainerImage = body.ContainerImage
+	req.OutputPath = body.OutputPath
+	req.Quorum = body.Quorum
+	req.Verify = body.Verify
+	req.QuoteToken = body.QuoteToken
+	req.Placement = body.Placement.placement()
+	req.Escrow = ps.escrowFor(wt)
+	if err := req.Validate(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, err.Error())
+		return
+	}
+	if err := ps.checkInputsUploaded(r.Context(), claims.UserID, req.Inputs); err != nil {
+		if errors.Is(err, store.ErrInputNotFound) {
+			writeAPIError(w, http.StatusBadRequest, apiCodeInputNotFound, err.Error())
+			return
+		}
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "database error")
+		return
+	}
+
+	resp, err := ps.orch.SubmitJob(r.Context(), req)
+	if err != nil {
+		se := orchestrator.SubmitErrorOf(err)
+		status := submitErrorStatus(se.Class)
+		switch se.Class {
+		case orchestrator.SubmitErrValidation:
+			writeAPIError(w, status, apiCodeInvalidRequest, se.Message)
+		case orchestrator.SubmitErrImageNotAllowlisted:
+			writeAPIError(w, status, apiCodeImageNotAllowed, se.Message)
+		case orchestrator.SubmitErrTypeMismatch:
+			writeAPIError(w, status, apiCodeTypeMismatch, se.Message)
+		case orchestrator.SubmitErrPaymentRequired:
+			writeAPIError(w, status, apiCodeInvalidQuote, se.Message)
+		case orchestrator.SubmitErrNoCapacity:
+			writeAPIJSON(w, status, apiPlacementErrorBody{
+				apiErrorBody: apiErrorBody{Error: se.Message, Code: apiCodeNoCapacity},
+				Reason:       se.Reason,
+				WantedTier:   se.WantedRung,
+			})
+		default:
+			slog.Error("api submit job failed", "consumer_id", claims.UserID, "error", err)
+			writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "failed to submit job")
+		}
+		return
+	}
+
+	metrics.JobsSubmittedTotal.WithLabelValues(string(wt)).Inc()
+	out := apiSubmitResponse{JobID: resp.JobID, NodeID: resp.NodeID}
+	if req.Escrow {
+		if err := ps.escrowSubmitted(r.Context(), resp, claims.UserID); err != nil {
+			writeAPIError(w, http.StatusBadGateway, apiCodePaymentFailed,
+				"payment could not be started; the job was not submitted")
+			return
+		}
+		out.QuoteCents = resp.QuoteCents
+		out.PaymentURL = ps.baseURL + "/consumer/job/" + resp.JobID + "/pay"
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+resp.JobID)
+	writeAPIJSON(w, http.StatusCreated, out)
+}
+
+// apiJob is a job in /api/v1 responses. Bill is set only on
+// GET /api/v1/jobs/{id}, once the job has been metered.
+type apiJob struct {
+	ID                string     `json:"id"`
+	WorkloadType      string     `json:"workload_type"`
+	Status            string     `json:"status"`
+	NodeID            string     `json:"node_id,omitempty"`
+	ContainerImage    string     `json:"container_image,omitempty"`
+	CPUCores          int        `json:"cpu_cores"`
+	RAMMB             int        `json:"ram_mb"`
+	MaxRuntimeSeconds int        `json:"max_runtime_seconds,omitempty"`
+	FailureCause      string     `json:"failure_cause,omitempty"`
+	PaymentStatus     string     `json:"payment_status,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	StartedAt         *time.Time `json:"started_at,omitempty"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+	Bill              *apiBill   `json:"bill,omitempty"`
+}
+
+// apiBill is a metered job's bill: what each resource cost and how the
+// payment was split, under fee declaration FeeSeq (null: the platform
+// default).
+type apiBill struct {
+	UsageSource            string               `json:"usage_source"`
+	ConsumerPaidCents      int64                `json:"consumer_paid_cents"`
+	ContributorEarnedCents int64                `json:"contributor_earned_cents"`
+	PlatformFeeCents       int64                `json:"platform_fee_cents"`
+	FeeSeq                 *uint64              `json:"fee_seq"`
+	Lines                  []store.MeteringLine `json:"lines"`
+}
+
+func newAPIJob(j store.ConsumerJob) apiJob {
+	return apiJob{
+		ID:                j.ID,
+		WorkloadType:      j.WorkloadType,
+		Status:            j.Status,
+		NodeID:            j.NodeID,
+		ContainerImage:    j.ContainerImage,
+		CPUCores:          j.CPUCores,
+		RAMMB:             j.RAMMB,
+		MaxRuntimeSeconds: j.MaxRuntimeSeconds,
+		FailureCause:      j.FailureCause,
+		PaymentStatus:     j.PaymentStatus,
+		CreatedAt:         j.CreatedAt,
+		StartedAt:         j.StartedAt,
+		CompletedAt:       j.CompletedAt,
+	}
+}
+
+// handleAPIGetJob returns one of the caller's jobs, with its bill once
+// metered.
+func (ps *PortalServer) handleAPIGetJob(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID, ok := apiJobID(w, r)
+	if !ok {
+		return
+	}
+
+	j, err := store.GetConsumerJob(r.Context(), ps.db, jobID, claims.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrJobNotFound) {
+			writeAPIError(w, http.StatusNotFound, apiCodeJobNotFound, "job not found")
+			return
+		}
+		slog.Error("api get job failed", "job_id", jobID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "database error")
+		return
+	}
+	out := newAPIJob(j)
+
+	m, err := store.GetJobMetering(r.Context(), ps.db, jobID)
+	switch {
+	case err == nil:
+		out.Bill = &apiBill{
+			UsageSource:            m.UsageSource,
+			ConsumerPaidCents:      m.ConsumerPaidCents,
+			ContributorEarnedCents: m.ContributorEarnedCents,
+			PlatformFeeCents:       m.PlatformFeeCents,
+			FeeSeq:                 m.FeeSeq,
+			Lines:                  m.Breakdown,
+		}
+	case !errors.Is(err, store.ErrMeteringNotFound):
+		slog.Warn("api get job: get job metering", "job_id", jobID, "error", err)
+	}
+	writeAPIJSON(w, http.StatusOK, out)
+}
+
+// apiJobList is the body of GET /api/v1/jobs.
+type apiJobList struct {
+	Jobs []apiJob `json:"jobs"`
+}
+
+// handleAPIListJobs lists the caller's jobs newest first, paged by ?limit
+// (default 50, at most 200) and ?offset, optionally only those in ?status.
+func (ps *PortalServer) handleAPIListJobs(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	q := r.URL.Query()
+
+	limit, offset := apiDefaultPageSize, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > apiMaxPageSize {
+			writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest,
+				"limit must be between 1 and "+strconv.Itoa(apiMaxPageSize))
+			return
+		}
+		limit = n
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	jobs, err := store.ListConsumerJobs(r.Context(), ps.db, claims.UserID, q.Get("status"), limit, offset)
+	if err != nil {
+		slog.Error("api list jobs failed", "consumer_id", claims.UserID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "database error")
+		return
+	}
+	out := apiJobList{Jobs: []apiJob{}}
+	for _, j := range jobs {
+		out.Jobs = append(out.Jobs, newAPIJob(j))
+	}
+	writeAPIJSON(w, http.StatusOK, out)
+}
+
+// apiCancelResponse is the body of POST /api/v1/jobs/{id}/cancel; see
+// refundCancelled for RefundStatus.
+type apiCancelResponse struct {
+	Status       string `json:"status"`
+	PriorStatus  string `json:"prior_status"`
+	ChargedCents int64  `json:"charged_cents"`
+	RefundCents  int64  `json:"refund_cents"`
+	RefundStatus string `json:"refund_status"`
+}
+
+// apiNotCancellableBody is the 409 body of POST /api/v1/jobs/{id}/cancel.
+type apiNotCancellableBody struct {
+	apiErrorBody
+	CurrentStatus string `json:"current_status"`
+}
+
+// handleAPICancelJob cancels one of the caller's jobs and refunds what it
+// was charged beyond what it ran, as the portal's cancel button does.
+func (ps *PortalServer) handleAPICancelJob(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID, ok := apiJobID(w, r)
+	if !ok {
+		return
+	}
+
+	resp, err := ps.orch.CancelJob(r.Context(), jobID, claims.UserID)
+	switch {
+	case errors.Is(err, store.ErrJobNotFound):
+		writeAPIError(w, http.StatusNotFound, apiCodeJobNotFound, "job not found")
+		return
+	case errors.Is(err, store.ErrJobNotCancellable):
+		writeAPIJSON(w, http.StatusConflict, apiNotCancellableBody{
+			apiErrorBody:  apiErrorBody{Error: "job can no longer be cancelled", Code: apiCodeJobNotCancellable},
+			CurrentStatus: resp.PriorStatus,
+		})
+		return
+	case err != nil:
+		slog.Error("api cancel job failed", "job_id", jobID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "failed to cancel job")
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, apiCancelResponse{
+		Status:       "cancelled",
+		PriorStatus:  resp.PriorStatus,
+		ChargedCents: resp.ChargedCents,
+		RefundCents:  resp.RefundCents,
+		RefundStatus: ps.refundCancelled(r.Context(), jobID, resp),
+	})
+}
+
+// apiLogChunk is one chunk of a job's container output.
+type apiLogChunk struct {
+	Seq    int64  `json:"seq"`
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+}
+
+// apiLogPage is the body of GET /api/v1/jobs/{id}/logs. Next is the ?after
+// to pass for the following page. Live is false once the job can produce no
+// more output; a client has read the whole log when Live is false and a
+// page comes back short.
+type apiLogPage struct {
+	JobID     string        `json:"job_id"`
+	Status    string        `json:"status"`
+	Live      bool          `json:"live"`
+	Truncated bool          `json:"truncated"`
+	Chunks    []apiLogChunk `json:"chunks"`
+	Next      int64         `json:"next"`
+}
+
+// handleAPIJobLogs returns a page of a job's container output: the chunks
+// after ?after (default -1, the beginning), at most ?limit (default 200, at
+// most 1000). For a replicated job ?replica=N picks the replica (default 0).
+// It is the polling form of the portal's server-sent log tail.
+func (ps *PortalServer) handleAPIJobLogs(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID, ok := apiJobID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+
+	replica, after, limit := 0, int64(-1), logTailBatch
+	if v := q.Get("replica"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, "replica must be a non-negative integer")
+			return
+		}
+		replica = n
+	}
+	if v := q.Get("after"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < -1 {
+			writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, "after must be a chunk seq, or -1")
+			return
+		}
+		after = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > apiMaxLogChunks {
+			writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest,
+				"limit must be between 1 and "+strconv.Itoa(apiMaxLogChunks))
+			return
+		}
+		limit = n
+	}
+
+	// The status is read before the chunks, as in the log tail: output
+	// stored before a job finished is then never missed by a client that
+	// stops at the first short page with live false.
+	job, err := store.ConsumerLogJob(r.Context(), ps.db, jobID, claims.UserID, replica)
+	if err != nil {
+		if errors.Is(err, store.ErrJobLogNotFound) {
+			writeAPIError(w, http.StatusNotFound, apiCodeJobNotFound, "job not found")
+			return
+		}
+		slog.Error("api log lookup failed", "job_id", jobID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "database error")
+		return
+	}
+	chunks, err := store.JobLogs(r.Context(), ps.db, job.JobID, after, limit)
+	if err != nil {
+		slog.Error("api log read failed", "job_id", job.JobID, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, apiCodeInternal, "database error")
+		return
+	}
+
+	out := apiLogPage{
+		JobID:     jobID,
+		Status:    job.Status,
+		Live:      logLive(job.Status),
+		Truncated: job.Truncated,
+		Chunks:    []apiLogChunk{},
+		Next:      after,
+	}
+	for _, c := range chunks {
+		out.Chunks = append(out.Chunks, apiLogChunk{Seq: c.Seq, Stream: c.Stream, Data: c.Data})
+		out.Next = c.Seq
+	}
+	writeAPIJSON(w, http.StatusOK, out)
+}
+
+// handleAPIJobArtifacts streams a job's output artifact; see
+// handleConsumerJobArtifacts.
+func (ps *PortalServer) handleAPIJobArtifacts(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID, ok := apiJobID(w, r)
+	if !ok {
+		return
+	}
+	ps.serveJobArtifact(w, r, jobID, claims.UserID,
+		func(status int, code, msg string) { writeAPIError(w, status, code, msg) })
+}
+
+// apiEstimateRequest is the body of POST /api/v1/estimate.
+type apiEstimateRequest struct {
+	apiJobShape
+	ExpectedRuntimeSeconds int          `json:"expected_runtime_seconds"`
+	Placement              apiPlacement `json:"placement"`
+}
+
+// apiNodeEstimate is one candidate node's price range for one replica.
+type apiNodeEstimate struct {
+	NodeID          string  `json:"node_id"`
+	CountryCode     string  `json:"country_code"`
+	PriceMultiplier float64 `json:"price_multiplier"`
+	LowCents        int64   `json:"low_cents"`
+	HighCents       int64   `json:"high_cents"`
+}
+
+// apiEstimateResponse is an orchestrator.EstimateJobResponse. QuoteToken,
+// passed as quote_token to POST /api/v1/jobs with the same job shape before
+// ExpiresAt, holds the submission to these prices.
+type apiEstimateResponse struct {
+	Candidates          []apiNodeEstimate `json:"candidates"`
+	Replicas            int               `json:"replicas"`
+	LowCents            int64             `json:"low_cents"`
+	HighCents           int64             `json:"high_cents"`
+	ContributorShareBps int               `json:"contributor_share_bps"`
+	PlatformFeeBps      int               `json:"platform_fee_bps"`
+	FeeSeq              *uint64           `json:"fee_seq"`
+	QuoteToken          string            `json:"quote_token"`
+	ExpiresAt           time.Time         `json:"expires_at"`
+}
+
+// handleAPIEstimate prices a job shape on the nodes that could run it now,
+// as the portal's estimate page does.
+func (ps *PortalServer) handleAPIEstimate(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+
+	var body apiEstimateRequest
+	if !decodeAPIJSON(w, r, &body) {
+		return
+	}
+	req, err := jobShape(body.apiJobShape)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, err.Error())
+		return
+	}
+	if body.ExpectedRuntimeSeconds < 0 {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest,
+			"expected_runtime_seconds must be a whole number of seconds")
+		return
+	}
+	req.ConsumerID = claims.UserID
+	req.Placement = body.Placement.placement()
+	req.Escrow = ps.escrowFor(req.WorkloadType)
+	if err := req.Validate(); err != nil {
+		writeAPIError(w, http.StatusBadRequest, apiCodeInvalidRequest, err.Error())
+		return
+	}
+
+	est, err := ps.orch.EstimateJob(r.Context(), orchestrator.EstimateJobRequest{
+		SubmitJobRequest:       req,
+		ExpectedRuntimeSeconds: body.ExpectedRuntimeSeconds,
+	})
+	if err != nil {
+		slog.Warn("api estimate job failed", "consumer_id", claims.UserID, "error", err)
+		writeAPIError(w, http.StatusServiceUnavailable, apiCodeNoEstimate,
+			"no estimate is available for this job right now")
+		return
+	}
+
+	out := apiEstimateResponse{
+		Candidates:          []apiNodeEstimate{},
+		Replicas:            est.Replicas,
+		LowCents:            est.LowCents,
+		HighCents:           est.HighCents,
+		ContributorShareBps: est.ContributorShareBps,
+		PlatformFeeBps:      est.PlatformFeeBps,
+		FeeSeq:              est.FeeSeq,
+		QuoteToken:          est.QuoteToken,
+		ExpiresAt:           est.ExpiresAt,
+	}
+	for _, c := range est.Candidates {
+		out.Candidates = append(out.Candidates, apiNodeEstimate{
+			NodeID:          c.NodeID,
+			CountryCode:     c.CountryCode,
+			PriceMultiplier: c.PriceMultiplier,
+			LowCents:        c.LowCents,
+			HighCents:       c.HighCents,
+		})
+	}
+	writeAPIJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,411 @@
+package portal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// seedAPIKey creates an API key for participantID carrying scopes and
+// returns the key itself.
+func seedAPIKey(t *testing.T, db *store.DB, participantID string, scopes ...string) string {
+	t.Helper()
+	_, secret, err := store.CreateAPIKey(context.Background(), db, participantID, "test key", scopes)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	return secret
+}
+
+// apiRequest serves one /api/v1 request through the portal mux.
+func apiRequest(ps *PortalServer, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	ps.srv.Handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// apiErrorCode decodes the code of an /api/v1 error response.
+func apiErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body apiErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error body %q: %v", rec.Body.String(), err)
+	}
+	return body.Code
+}
+
+func TestAPIRateLimiter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	rl := NewAPIRateLimiter(2, 0.5)
+	rl.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if ok, _ := rl.Allow("key-a"); !ok {
+			t.Fatalf("request %d within the burst was throttled", i+1)
+		}
+	}
+	ok, wait := rl.Allow("key-a")
+	if ok || wait != 2*time.Second {
+		t.Fatalf("third request: ok=%v wait=%v, want throttled for 2s", ok, wait)
+	}
+	if ok, _ := rl.Allow("key-b"); !ok {
+		t.Error("another key shares key-a's bucket")
+	}
+
+	now = now.Add(2 * time.Second)
+	if ok, _ := rl.Allow("key-a"); !ok {
+		t.Error("bucket did not refill")
+	}
+}
+
+func TestRequireAPIKey_MissingKey(t *testing.T) {
+	ps := &PortalServer{apiLimiter: NewAPIRateLimiter(1, 1)}
+	h := ps.requireAPIKey(store.ScopeJobsRead, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler reached without a key")
+	})
+	for _, header := range []string{"", "Basic dXNlcjpwYXNz", "Bearer "} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/jobs", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized || apiErrorCode(t, rec) != apiCodeUnauthorized {
+			t.Errorf("Authorization %q: got %d %s, want 401 unauthorized", header, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: no WWW-Authenticate challenge", header)
+		}
+	}
+}
+
+func TestAPISubmitJob(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{jobID: "3f1c2d4e-0000-4000-8000-000000000001"}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "apisubmit@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsWrite)
+
+	rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs", key,
+		`{"workload_type":"batch_compute","container_image":"nginx:latest","cpu_cores":4,"quote_token":"quote-token"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if loc := rec.Header().Get("Location"); loc != "/api/v1/jobs/"+stub.jobID {
+		t.Errorf("Location = %q", loc)
+	}
+	var resp apiSubmitResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.JobID != stub.jobID {
+		t.Errorf("response %s (err %v), want job_id %s", rec.Body.String(), err, stub.jobID)
+	}
+	got := stub.lastReq
+	if got.ConsumerID != participantID || got.CPUCores != 4 || got.RAMMB != 4096 ||
+		got.ContainerImage != "nginx:latest" || got.QuoteToken != "quote-token" {
+		t.Errorf("SubmitJob called with %+v", got)
+	}
+
+	rec = apiRequest(ps, http.MethodPost, "/api/v1/jobs", key, `{"container_image":"nginx:latest","cpu":4}`)
+	if rec.Code != http.StatusBadRequest || apiErrorCode(t, rec) != apiCodeInvalidRequest {
+		t.Errorf("unknown field: got %d %s, want 400 invalid_request", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPISubmitJob_Placement(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{jobID: "3f1c2d4e-0000-4000-8000-000000000002"}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "apiplacement@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsWrite)
+	const owner = "bbbbbbbb-bbbb-4bbb-8bbb-bbbbbbbbbbbb"
+
+	rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs", key,
+		`{"container_image":"nginx:latest","placement":{"excluded_owner_ids":["`+owner+`"],"anti_affinity":"region"}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got := stub.lastReq.Placement
+	if len(got.ExcludedOwnerIDs) != 1 || got.ExcludedOwnerIDs[0] != owner ||
+		got.AntiAffinity != orchestrator.AntiAffinityRegion || got.AntiAffinityRequired {
+		t.Errorf("SubmitJob called with Placement %+v", got)
+	}
+
+	rec = apiRequest(ps, http.MethodPost, "/api/v1/jobs", key,
+		`{"container_image":"nginx:latest","placement":{"node_selector":{"ssd":""},"tolerations":[{"key":"office-hours-only"}]}}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("selector: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got = stub.lastReq.Placement
+	if v, ok := got.NodeSelector["ssd"]; !ok || v != "" || len(got.Tolerations) != 1 ||
+		got.Tolerations[0] != (orchestrator.Toleration{Key: "office-hours-only"}) {
+		t.Errorf("SubmitJob called with Placement %+v", got)
+	}
+
+	rec = apiRequest(ps, http.MethodPost, "/api/v1/jobs", key,
+		`{"container_image":"nginx:latest","placement":{"anti_affinity":"rack"}}`)
+	if rec.Code != http.StatusBadRequest || apiErrorCode(t, rec) != apiCodeInvalidRequest {
+		t.Errorf("unknown anti_affinity: got %d %s, want 400 invalid_request", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPISubmitJob_ReliableVerified(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{jobID: "3f1c2d4e-0000-4000-8000-000000000003"}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "apiverify@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsWrite)
+
+	rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs", key,
+		`{"workload_type":"batch_compute","container_image":"nginx:latest","sla_tier":2,"verify":true,`+
+			`"output_path":"/output/result.bin","storage_gb":20,"gpu_required":true,"gpu_vram_gb":8}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got := stub.lastReq
+	if got.SLATier != orchestrator.SLAReliable || !got.Verify || got.Quorum != 0 ||
+		got.OutputPath != "/output/result.bin" || got.StorageGB != 20 || !got.GPURequired || got.GPUVRAMGB != 8 {
+		t.Errorf("SubmitJob called with %+v", got)
+	}
+
+	for name, body := range map[string]string{
+		"tier above premium":   `{"container_image":"nginx:latest","sla_tier":4}`,
+		"quorum above tier":    `{"container_image":"nginx:latest","sla_tier":2,"quorum":3}`,
+		"verify at standard":   `{"workload_type":"batch_compute","container_image":"nginx:latest","verify":true,"output_path":"/output/result.bin"}`,
+		"verify without file":  `{"workload_type":"batch_compute","container_image":"nginx:latest","sla_tier":2,"verify":true}`,
+		"negative storage":     `{"container_image":"nginx:latest","storage_gb":-1}`,
+		"vram without gpu":     `{"container_image":"nginx:latest","gpu_vram_gb":8}`,
+		"replicated print job": `{"workload_type":"print_3d","container_image":"nginx:latest","sla_tier":2}`,
+	} {
+		stub.lastReq = orchestrator.SubmitJobRequest{}
+		rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs", key, body)
+		if rec.Code != http.StatusBadRequest || apiErrorCode(t, rec) != apiCodeInvalidRequest {
+			t.Errorf("%s: got %d %s, want 400 invalid_request", name, rec.Code, rec.Body.String())
+		}
+		if stub.lastReq.ConsumerID != "" {
+			t.Errorf("%s: reached SubmitJob", name)
+		}
+	}
+}
+
+func TestAPISubmitJob_ClassifiedErrors(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "apisubmiterr@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsWrite)
+
+	tests := []struct {
+		err    *orchestrator.SubmitError
+		status int
+		code   string
+	}{
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrImageNotAllowlisted, Message: "choose an allowlisted image"},
+			http.StatusUnprocessableEntity, apiCodeImageNotAllowed},
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrTypeMismatch, Message: "choose a matching workload type"},
+			http.StatusUnprocessableEntity, apiCodeTypeMismatch},
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrPaymentRequired, Reason: orchestrator.ReasonInvalidQuote,
+			Message: "request a new estimate", Err: orchestrator.ErrInvalidQuote}, http.StatusUnprocessableEntity, apiCodeInvalidQuote},
+		{&orchestrator.SubmitError{Class: orchestrator.SubmitErrInternal, Message: "try again later",
+			Err: errors.New("commit transaction: connection reset")}, http.StatusInternalServerError, apiCodeInternal},
+	}
+	for _, tt := range tests {
+		stub.err = tt.err
+		rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs", key, `{"container_image":"nginx:latest"}`)
+		if rec.Code != tt.status || apiErrorCode(t, rec) != tt.code {
+			t.Errorf("%s: got %d %s, want %d %s", tt.err.Class, rec.Code, rec.Body.String(), tt.status, tt.code)
+		}
+	}
+
+	stub.err = &orchestrator.SubmitError{Class: orchestrator.SubmitErrNoCapacity, Reason: "too_big", WantedRung: "storm",
+		Message: "the job is larger than any node offered today", Err: errors.New("find nodes: no available nodes match request")}
+	rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs", key, `{"container_image":"nginx:latest"}`)
+	var body apiPlacementErrorBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", rec.Body.String(), err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || body.Code != apiCodeNoCapacity ||
+		body.Reason != "too_big" || body.WantedTier != "storm" {
+		t.Errorf("no capacity: got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAPIKeyScopeAndRevocation(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServerWithOrch(t, db, &stubOrchestrator{})
+	participantID := seedParticipant(t, db, "apiscope@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsRead)
+
+	rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs", key, `{"container_image":"nginx:latest"}`)
+	if rec.Code != http.StatusForbidden || apiErrorCode(t, rec) != apiCodeInsufficientScope {
+		t.Errorf("read-only key submitting: got %d %s, want 403 insufficient_scope", rec.Code, rec.Body.String())
+	}
+	if rec := apiRequest(ps, http.MethodGet, "/api/v1/jobs", key, ""); rec.Code != http.StatusOK {
+		t.Errorf("read-only key listing: got %d %s, want 200", rec.Code, rec.Body.String())
+	}
+
+	keys, err := store.ListAPIKeys(context.Background(), db, participantID)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("ListAPIKeys: %v, %d keys", err, len(keys))
+	}
+	form := httptest.NewRequest(http.MethodPost, "/consumer/api-keys/"+keys[0].ID+"/revoke", nil)
+	form.SetPathValue("id", keys[0].ID)
+	form = withClaims(form, SessionClaims{UserID: participantID, Email: "apiscope@test.com"})
+	w := httptest.NewRecorder()
+	ps.handleRevokeAPIKey(w, form)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("revoke: got %d %s, want 303", w.Code, w.Body.String())
+	}
+
+	rec = apiRequest(ps, http.MethodGet, "/api/v1/jobs", key, "")
+	if rec.Code != http.StatusUnauthorized || apiErrorCode(t, rec) != apiCodeUnauthorized {
+		t.Errorf("revoked key: got %d %s, want 401 unauthorized", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequireAPIKey_ThrottledRequestNotRecorded(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServerWithOrch(t, db, &stubOrchestrator{})
+	ps.apiLimiter = NewAPIRateLimiter(1, 0.001)
+	participantID := seedParticipant(t, db, "apithrottle@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsRead)
+
+	lastUsed := func() *time.Time {
+		t.Helper()
+		keys, err := store.ListAPIKeys(context.Background(), db, participantID)
+		if err != nil || len(keys) != 1 {
+			t.Fatalf("ListAPIKeys: %v, %d keys", err, len(keys))
+		}
+		return keys[0].LastUsedAt
+	}
+
+	if rec := apiRequest(ps, http.MethodGet, "/api/v1/jobs", key, ""); rec.Code != http.StatusOK {
+		t.Fatalf("first request: got %d %s, want 200", rec.Code, rec.Body.String())
+	}
+	admitted := lastUsed()
+	if admitted == nil {
+		t.Fatal("admitted request did not record last_used_at")
+	}
+	rec := apiRequest(ps, http.MethodGet, "/api/v1/jobs", key, "")
+	if rec.Code != http.StatusTooManyRequests || apiErrorCode(t, rec) != apiCodeRateLimited {
+		t.Fatalf("second request: got %d %s, want 429 rate_limited", rec.Code, rec.Body.String())
+	}
+	if got := lastUsed(); got == nil || !got.Equal(*admitted) {
+		t.Errorf("last_used_at after a throttled request = %v, want %v", got, admitted)
+	}
+}
+
+func TestAPIGetAndListJobs(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServerWithOrch(t, db, &stubOrchestrator{})
+	participantID := seedParticipant(t, db, "apijobs@test.com", "pass1234")
+	otherID := seedParticipant(t, db, "apiother@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsRead)
+
+	var jobID, otherJobID string
+	for owner, id := range map[string]*string{participantID: &jobID, otherID: &otherJobID} {
+		if err := db.Pool.QueryRow(context.Background(),
+			`INSERT INTO jobs (participant_id, workload_type, status, cpu_cores, ram_mb, container_image)
+			 VALUES ($1, 'batch_compute', 'running', 2, 4096, 'nginx:latest') RETURNING id`,
+			owner,
+		).Scan(id); err != nil {
+			t.Fatalf("seed job: %v", err)
+		}
+	}
+
+	rec := apiRequest(ps, http.MethodGet, "/api/v1/jobs/"+jobID, key, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get job: got %d %s", rec.Code, rec.Body.String())
+	}
+	var job apiJob
+	if err := json.Unmarshal(rec.Body.Bytes(), &job); err != nil || job.ID != jobID || job.Status != "running" {
+		t.Errorf("get job = %s (err %v)", rec.Body.String(), err)
+	}
+
+	for _, path := range []string{"/api/v1/jobs/" + otherJobID, "/api/v1/jobs/not-a-uuid"} {
+		rec := apiRequest(ps, http.MethodGet, path, key, "")
+		if rec.Code != http.StatusNotFound || apiErrorCode(t, rec) != apiCodeJobNotFound {
+			t.Errorf("GET %s: got %d %s, want 404 job_not_found", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = apiRequest(ps, http.MethodGet, "/api/v1/jobs?limit=10", key, "")
+	var list apiJobList
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Jobs) != 1 || list.Jobs[0].ID != jobID {
+		t.Errorf("list jobs = %s (err %v), want only the caller's job", rec.Body.String(), err)
+	}
+	if rec := apiRequest(ps, http.MethodGet, "/api/v1/jobs?limit=0", key, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: got %d, want 400", rec.Code)
+	}
+}
+
+func TestAPICancelJob_NotCancellable(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{
+		cancelResp: orchestrator.CancelJobResponse{PriorStatus: "completed"},
+		cancelErr:  store.ErrJobNotCancellable,
+	}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "apicancel@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsWrite)
+
+	jobID := "3f1c2d4e-0000-4000-8000-000000000002"
+	rec := apiRequest(ps, http.MethodPost, "/api/v1/jobs/"+jobID+"/cancel", key, "")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body apiNotCancellableBody
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil ||
+		body.Code != apiCodeJobNotCancellable || body.CurrentStatus != "completed" {
+		t.Errorf("body = %s (err %v)", rec.Body.String(), err)
+	}
+	if stub.lastCancel != [2]string{jobID, participantID} {
+		t.Errorf("CancelJob called with %v", stub.lastCancel)
+	}
+}
+
+func TestAPIEstimate(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{estimateResp: orchestrator.EstimateJobResponse{
+		Candidates: []orchestrator.NodeEstimate{
+			{NodeID: "node-cheap", CountryCode: "US", PriceMultiplier: 1, LowCents: 12, HighCents: 48},
+		},
+		Replicas: 1, LowCents: 12, HighCents: 48,
+		ContributorShareBps: 9000, PlatformFeeBps: 1000,
+		QuoteToken: "quote-token", ExpiresAt: time.Now().Add(15 * time.Minute),
+	}}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "apiestimate@test.com", "pass1234")
+	key := seedAPIKey(t, db, participantID, store.ScopeJobsRead)
+
+	rec := apiRequest(ps, http.MethodPost, "/api/v1/estimate", key,
+		`{"workload_type":"batch_compute","cpu_cores":4,"ram_mb":8192,"sla_tier":2,"expected_runtime_seconds":900}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got := stub.lastEstimate
+	if got.ConsumerID != participantID || got.CPUCores != 4 || got.RAMMB != 8192 ||
+		got.SLATier != orchestrator.SLAReliable || got.ExpectedRuntimeSeconds != 900 {
+		t.Errorf("EstimateJob called with %+v", got)
+	}
+	var est apiEstimateResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &est); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if est.QuoteToken != "quote-token" || est.LowCents != 12 || len(est.Candidates) != 1 || est.Candidates[0].NodeID != "node-cheap" {
+		t.Errorf("estimate = %s", rec.Body.String())
+	}
+}
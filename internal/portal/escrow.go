@@ -0,0 +1,151 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
+)
+
+// JobPayData is the template data for consumer_job_pay.html.
+type JobPayData struct {
+	JobID           string
+	AmountDollars   float64
+	PaymentError    string // why the last authorization attempt failed, if it did
+	PublishableKey  string
+	ClientSecret    string
+	ReturnURL       string
+	Email           string
+	IsAuthenticated bool
+}
+
+// escrowEnabled reports whether submissions are held in escrow: WithEscrow
+// was given and there is a payment client to create the holds.
+func (ps *PortalServer) escrowEnabled() bool {
+	return ps.escrowKey != "" && ps.payment != nil
+}
+
+// escrowFor reports whether a job of workload type wt is held in escrow:
+// escrow is enabled and wt is not a print workload. Estimates ask too, so a
+// quote is priced at the runtime limit an escrowed job gets
+// (orchestrator.EscrowMaxRuntime) and matches the submission.
+func (ps *PortalServer) escrowFor(wt types.MarketplaceWorkloadType) bool {
+	return ps.escrowEnabled() &&
+		wt != types.MarketplacePrintTraditional && wt != types.MarketplacePrint3D
+}
+
+// startEscrow creates the payment hold for an escrowed job just submitted and
+// attaches it to the job. The hold is the quote, raised to Stripe's minimum
+// charge.
+func (ps *PortalServer) startEscrow(ctx context.Context, resp orchestrator.SubmitJobResponse) error {
+	amount := max(resp.QuoteCents, payment.MinChargeCents)
+	charge, err := ps.payment.CreateEscrowCharge(ctx, amount, resp.JobID)
+	if err != nil {
+		return err
+	}
+	attached, err := store.AttachJobPayment(ctx, ps.db, resp.JobID, charge.PaymentIntentID, amount)
+	if err != nil {
+		return err
+	}
+	if !attached {
+		return fmt.Errorf("job %s is not awaiting payment", resp.JobID)
+	}
+	return nil
+}
+
+// handleConsumerJobPay renders the payment page where the consumer authorizes
+// the escrow hold for their job. A job that is not awaiting payment
+// redirects to its status page.
+func (ps *PortalServer) handleConsumerJobPay(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID := r.PathValue("id")
+
+	if !ps.escrowEnabled() {
+		http.Error(w, "payments are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	p, err := store.GetJobPayment(r.Context(), ps.db, jobID, claims.UserID)
+	if errors.Is(err, store.ErrJobNotFound) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("handleConsumerJobPay: read payment", "job_id", jobID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if p.Status != store.PaymentAwaiting || p.PaymentIntentID == "" {
+		http.Redirect(w, r, "/consumer/job/"+jobID, http.StatusSeeOther)
+		return
+	}
+
+	state, err := ps.payment.RetrieveEscrow(r.Context(), p.PaymentIntentID)
+	if err != nil {
+		slog.Error("handleConsumerJobPay: retrieve escrow", "job_id", jobID, "error", err)
+		http.Error(w, "payment provider error", http.StatusBadGateway)
+		return
+	}
+
+	ps.renderTemplate(w, "consumer_job_pay.html", JobPayData{
+		JobID:           jobID,
+		AmountDollars:   float64(p.AmountCents) / 100,
+		PaymentError:    p.PaymentError,
+		PublishableKey:  ps.escrowKey,
+		ClientSecret:    state.ClientSecret,
+		ReturnURL:       ps.baseURL + "/consumer/job/" + jobID + "/payment-return",
+		Email:           claims.Email,
+		IsAuthenticated: true,
+	})
+}
+
+// handleConsumerJobPaymentReturn is where Stripe.js sends the consumer after
+// confirming the hold. It checks the hold with Stripe and, once authorized,
+// releases the job for dispatch — the payment_intent.amount_capturable_updated
+// webhook does the same, whichever lands first. Always ends on the job's
+// status page, or back on the payment page when the hold is not authorized.
+func (ps *PortalServer) handleConsumerJobPaymentReturn(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID := r.PathValue("id")
+
+	if !ps.escrowEnabled() {
+		http.Error(w, "payments are not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	p, err := store.GetJobPayment(r.Context(), ps.db, jobID, claims.UserID)
+	if errors.Is(err, store.ErrJobNotFound) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("handleConsumerJobPaymentReturn: read payment", "job_id", jobID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if p.Status != store.PaymentAwaiting || p.PaymentIntentID == "" {
+		http.Redirect(w, r, "/consumer/job/"+jobID, http.StatusSeeOther)
+		return
+	}
+
+	state, err := ps.payment.RetrieveEscrow(r.Context(), p.PaymentIntentID)
+	if err != nil {
+		slog.Error("handleConsumerJobPaymentReturn: retrieve escrow", "job_id", jobID, "error", err)
+		http.Error(w, "payment provider error", http.StatusBadGateway)
+		return
+	}
+	if !state.Authorized() {
+		http.Redirect(w, r, "/consumer/job/"+jobID+"/pay", http.StatusSeeOther)
+		return
+	}
+	if _, err := store.AuthorizeJobPayment(r.Context(), ps.db, p.PaymentIntentID); err != nil {
+		slog.Error("handleConsumerJobPaymentReturn: authorize", "job_id", jobID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/consumer/job/"+jobID, http.StatusSeeOther)
+}
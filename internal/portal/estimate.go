@@ -0,0 +1,131 @@
+package portal
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
+)
+
+// EstimateData is the template data for consumer_estimate.html. The form
+// fields echo the request so the page can be refined and so each candidate's
+// submit form carries exactly the request the quote was issued for.
+type EstimateData struct {
+	WorkloadType           string
+	CPUCores               int
+	RAMMB                  int
+	MaxRuntimeSeconds      int
+	SLATier                int
+	ExpectedRuntimeSeconds int
+	Estimate               *EstimateView
+	Error                  string
+	Email                  string
+	IsAuthenticated        bool
+}
+
+// EstimateView is an orchestrator.EstimateJobResponse in dollars.
+type EstimateView struct {
+	Candidates     []CandidateEstimateRow
+	Replicas       int
+	LowDollars     float64
+	HighDollars    float64
+	ContributorPct float64
+	PlatformPct    float64
+	FeeSeq         *uint64
+	QuoteToken     string
+	ExpiresAt      time.Time
+}
+
+// CandidateEstimateRow is one candidate node's price range for one replica.
+type CandidateEstimateRow struct {
+	NodeID          string
+	CountryCode     string
+	PriceMultiplier float64
+	LowDollars      float64
+	HighDollars     float64
+}
+
+// handleConsumerEstimate renders the estimate page. Without a workload_type
+// it shows only the form; with one it prices the request on the nodes that
+// could run it now and offers a submit form per candidate carrying the
+// signed quote, honored by the submission until it expires.
+func (ps *PortalServer) handleConsumerEstimate(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	q := r.URL.Query()
+
+	data := EstimateData{
+		WorkloadType:    q.Get("workload_type"),
+		CPUCores:        2,
+		RAMMB:           4096,
+		SLATier:         int(orchestrator.SLAStandard),
+		Email:           claims.Email,
+		IsAuthenticated: true,
+	}
+	if data.WorkloadType == "" {
+		ps.renderTemplate(w, "consumer_estimate.html", data)
+		return
+	}
+
+	req, err := formJobShape(q)
+	if err != nil {
+		data.Error = err.Error()
+		ps.renderTemplate(w, "consumer_estimate.html", data)
+		return
+	}
+	data.CPUCores, data.RAMMB, data.MaxRuntimeSeconds = req.CPUCores, req.RAMMB, req.MaxRuntimeSeconds
+	if req.SLATier != 0 {
+		data.SLATier = int(req.SLATier)
+	}
+	if v := q.Get("expected_runtime_seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			data.Error = "expected_runtime_seconds must be a whole number of seconds"
+			ps.renderTemplate(w, "consumer_estimate.html", data)
+			return
+		}
+		data.ExpectedRuntimeSeconds = n
+	}
+	req.ConsumerID = claims.UserID
+	req.Escrow = ps.escrowFor(req.WorkloadType)
+	if err := req.Validate(); err != nil {
+		data.Error = err.Error()
+		ps.renderTemplate(w, "consumer_estimate.html", data)
+		return
+	}
+
+	est, err := ps.orch.EstimateJob(r.Context(), orchestrator.EstimateJobRequest{
+		SubmitJobRequest:       req,
+		ExpectedRuntimeSeconds: data.ExpectedRuntimeSeconds,
+	})
+	if err != nil {
+		slog.Warn("handleConsumerEstimate: estimate job", "error", err)
+		data.Error = fmt.Sprintf("No estimate is available for this job right now: %s", err)
+		ps.renderTemplate(w, "consumer_estimate.html", data)
+		return
+	}
+
+	view := &EstimateView{
+		Replicas:       est.Replicas,
+		LowDollars:     float64(est.LowCents) / 100.0,
+		HighDollars:    float64(est.HighCents) / 100.0,
+		ContributorPct: float64(est.ContributorShareBps) / 100.0,
+		PlatformPct:    float64(est.PlatformFeeBps) / 100.0,
+		FeeSeq:         est.FeeSeq,
+		QuoteToken:     est.QuoteToken,
+		ExpiresAt:      est.ExpiresAt,
+	}
+	for _, c := range est.Candidates {
+		view.Candidates = append(view.Candidates, CandidateEstimateRow{
+			NodeID:          c.NodeID,
+			CountryCode:     c.CountryCode,
+			PriceMultiplier: c.PriceMultiplier,
+			LowDollars:      float64(c.LowCents) / 100.0,
+			HighDollars:     float64(c.HighCents) / 100.0,
+		})
+	}
+	data.Estimate = view
+	ps.renderTemplate(w, "consumer_estimate.html", data)
+}
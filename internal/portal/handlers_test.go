@@ -4,22 +4,44 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	stripe "github.com/stripe/stripe-go/v82"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
 )
 
 // stubOrchestrator satisfies jobSubmitter for tests. It returns a fake job ID
-// on SubmitJob and records the last request for assertion.
+// on SubmitJob and records the last request for assertion. CancelJob returns
+// cancelResp and cancelErr.
 type stubOrchestrator struct {
 	lastReq orchestrator.SubmitJobRequest
 	jobID   string
 	err     error
+
+	lastCancel [2]string // job ID, consumer ID
+	cancelResp orchestrator.CancelJobResponse
+	cancelErr  error
+
+	lastEstimate orchestrator.EstimateJobRequest
+	estimateResp orchestrator.EstimateJobResponse
+}
+
+func (s *stubOrchestrator) EstimateJob(_ context.Context, req orchestrator.EstimateJobRequest) (orchestrator.EstimateJobResponse, error) {
+	s.lastEstimate = req
+	return s.estimateResp, s.err
+}
+
+func (s *stubOrchestrator) CancelJob(_ context.Context, jobID, consumerID string) (orchestrator.CancelJobResponse, error) {
+	s.lastCancel = [2]string{jobID, consumerID}
+	return s.cancelResp, s.cancelErr
 }
 
 func (s *stubOrchestrator) SubmitJob(_ context.Context, req orchestrator.SubmitJobRequest) (orchestrator.SubmitJobResponse, error) {
@@ -317,6 +339,112 @@ func TestHandleSubmitJob_ValidNode(t *testing.T) {
 	if !strings.HasPrefix(loc, "/consumer/job/") {
 		t.Fatalf("expected redirect to /consumer/job/{id}, got %q", loc)
 	}
+	if stub.lastReq.Placement.RequiredNodeID != nodeID {
+		t.Errorf("job not pinned to the chosen node: Placement = %+v", stub.lastReq.Placement)
+	}
+}
+
+func TestHandleSubmitJob_PlacementRejectionShowsReason(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{err: &orchestrator.SubmitError{
+		Class:   orchestrator.SubmitErrNoCapacity,
+		Reason:  "no_capacity",
+		Message: "no node has room for the job right now; retry later or request fewer resources",
+		Err:     errors.New("find nodes: no available nodes match request"),
+	}}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "jobnocapacity@test.com", "pass1234")
+	nodeID := seedNode(t, db, participantID, "online", "A", "US")
+
+	body := strings.NewReader("node_id=" + nodeID + "&container_image=nginx%3Alatest")
+	r := httptest.NewRequest(http.MethodPost, "/consumer/job", body)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = withClaims(r, SessionClaims{UserID: participantID, Email: "jobnocapacity@test.com"})
+	w := httptest.NewRecorder()
+
+	ps.handleSubmitJob(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "retry later or request fewer resources") {
+		t.Errorf("body does not say what to change: %s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "find nodes") {
+		t.Errorf("body leaks the internal cause: %s", w.Body.String())
+	}
+}
+
+func TestHandleSubmitJob_MaxRuntime(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "jobruntime@test.com", "pass1234")
+	nodeID := seedNode(t, db, participantID, "online", "A", "US")
+
+	submit := func(maxRuntime string) *httptest.ResponseRecorder {
+		body := strings.NewReader("node_id=" + nodeID + "&container_image=nginx%3Alatest" +
+			"&workload_type=batch_compute&max_runtime_seconds=" + maxRuntime)
+		r := httptest.NewRequest(http.MethodPost, "/consumer/job", body)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = withClaims(r, SessionClaims{UserID: participantID, Email: "jobruntime@test.com"})
+		w := httptest.NewRecorder()
+		ps.handleSubmitJob(w, r)
+		return w
+	}
+
+	if w := submit("3600"); w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	if stub.lastReq.MaxRuntimeSeconds != 3600 {
+		t.Errorf("MaxRuntimeSeconds = %d, want 3600", stub.lastReq.MaxRuntimeSeconds)
+	}
+	for _, bad := range []string{"1h", "-5", "999999999"} {
+		if w := submit(bad); w.Code != http.StatusBadRequest {
+			t.Errorf("max_runtime_seconds=%s: expected 400, got %d", bad, w.Code)
+		}
+	}
+}
+
+func TestHandleSubmitJob_SLATier(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "jobtier@test.com", "pass1234")
+	nodeID := seedNode(t, db, participantID, "online", "A", "US")
+
+	submit := func(tier string) *httptest.ResponseRecorder {
+		body := strings.NewReader("node_id=" + nodeID + "&container_image=nginx%3Alatest" +
+			"&workload_type=batch_compute&sla_tier=" + tier)
+		r := httptest.NewRequest(http.MethodPost, "/consumer/job", body)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = withClaims(r, SessionClaims{UserID: participantID, Email: "jobtier@test.com"})
+		w := httptest.NewRecorder()
+		ps.handleSubmitJob(w, r)
+		return w
+	}
+
+	if w := submit("2"); w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	got := stub.lastReq
+	if got.SLATier != orchestrator.SLAReliable {
+		t.Errorf("SLATier = %d, want %d", got.SLATier, orchestrator.SLAReliable)
+	}
+	// Replicas need distinct owners, so the picked node is tried first
+	// rather than required.
+	if got.Placement.RequiredNodeID != "" || len(got.Placement.PreferredNodeIDs) != 1 || got.Placement.PreferredNodeIDs[0] != nodeID {
+		t.Errorf("Placement = %+v, want the chosen node preferred", got.Placement)
+	}
+
+	if w := submit("1"); w.Code != http.StatusSeeOther || stub.lastReq.Placement.RequiredNodeID != nodeID {
+		t.Errorf("standard tier: got %d, Placement %+v; want the chosen node required", w.Code, stub.lastReq.Placement)
+	}
+	for _, bad := range []string{"4", "-1", "reliable"} {
+		if w := submit(bad); w.Code != http.StatusBadRequest {
+			t.Errorf("sla_tier=%s: expected 400, got %d", bad, w.Code)
+		}
+	}
 }
 
 func TestHandleSubmitJob_NodeNotFound(t *testing.T) {
@@ -356,6 +484,65 @@ func TestHandleSubmitJob_NodeOffline(t *testing.T) {
 	}
 }
 
+// ── handleConsumerEstimate ───────────────────────────────────────────────────
+
+func TestHandleConsumerEstimate(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{estimateResp: orchestrator.EstimateJobResponse{
+		Candidates: []orchestrator.NodeEstimate{
+			{NodeID: "node-cheap", CountryCode: "US", PriceMultiplier: 1, LowCents: 12, HighCents: 48},
+		},
+		Replicas: 1, LowCents: 12, HighCents: 48,
+		ContributorShareBps: 9000, PlatformFeeBps: 1000,
+		QuoteToken: "quote-token", ExpiresAt: time.Now().Add(15 * time.Minute),
+	}}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "estimate@test.com", "pass1234")
+
+	r := httptest.NewRequest(http.MethodGet,
+		"/consumer/estimate?workload_type=batch_compute&cpu_cores=4&ram_mb=8192&sla_tier=2&expected_runtime_seconds=900", nil)
+	r = withClaims(r, SessionClaims{UserID: participantID, Email: "estimate@test.com"})
+	w := httptest.NewRecorder()
+	ps.handleConsumerEstimate(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	got := stub.lastEstimate
+	if got.ConsumerID != participantID || got.CPUCores != 4 || got.RAMMB != 8192 ||
+		got.SLATier != orchestrator.SLAReliable || got.ExpectedRuntimeSeconds != 900 {
+		t.Errorf("EstimateJob called with %+v", got)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"$0.12", "$0.48", `value="quote-token"`, `value="node-cheap"`, `name="sla_tier" value="2"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("estimate page missing %q", want)
+		}
+	}
+}
+
+func TestHandleSubmitJob_QuoteToken(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	participantID := seedParticipant(t, db, "jobquote@test.com", "pass1234")
+	nodeID := seedNode(t, db, participantID, "online", "A", "US")
+
+	body := strings.NewReader("node_id=" + nodeID + "&container_image=nginx%3Alatest&quote_token=quote-token")
+	r := httptest.NewRequest(http.MethodPost, "/consumer/job", body)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = withClaims(r, SessionClaims{UserID: participantID, Email: "jobquote@test.com"})
+	w := httptest.NewRecorder()
+	ps.handleSubmitJob(w, r)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	if stub.lastReq.QuoteToken != "quote-token" {
+		t.Errorf("QuoteToken = %q, want quote-token", stub.lastReq.QuoteToken)
+	}
+}
+
 // ── handleDisputeResolve ─────────────────────────────────────────────────────
 
 func TestHandleDisputeResolve_InvalidPct(t *testing.T) {
@@ -627,6 +814,155 @@ func TestHandlePostOptOut_upsertsPrinterEnabledFlags(t *testing.T) {
 	check("PrinterC", false)
 }
 
+func TestHandlePostNodeLabels_setsOwnedNodeOnly(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+
+	pid1 := seedParticipant(t, db, "labels-owner@test.com", "password123")
+	pid2 := seedParticipant(t, db, "labels-intruder@test.com", "password123")
+	nid := seedNode(t, db, pid1, "online", "A", "US")
+
+	post := func(pid, email string, body map[string]any) *httptest.ResponseRecorder {
+		t.Helper()
+		b, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/api/node-labels", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		token, _ := ps.sm.CreateToken(SessionClaims{UserID: pid, Email: email, ExpiresAt: time.Now().Add(24 * time.Hour).Unix()})
+		req.AddCookie(&http.Cookie{Name: "session_token", Value: token})
+		rec := httptest.NewRecorder()
+		ps.srv.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post(pid1, "labels-owner@test.com", map[string]any{
+		"node_id": nid, "labels": "zone=attic,ssd", "taints": "office-hours-only",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Labels string `json:"labels"`
+		Taints string `json:"taints"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Labels != "ssd, zone=attic" || resp.Taints != "office-hours-only" {
+		t.Errorf("response = %+v", resp)
+	}
+
+	if rec := post(pid1, "labels-owner@test.com", map[string]any{"node_id": nid, "labels": "SSD"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid label: status = %d, want 400", rec.Code)
+	}
+	if rec := post(pid2, "labels-intruder@test.com", map[string]any{"node_id": nid, "labels": "pwned"}); rec.Code != http.StatusNotFound {
+		t.Errorf("non-owner: status = %d, want 404", rec.Code)
+	}
+
+	got, err := store.GetNodeLabels(context.Background(), db, nid)
+	if err != nil {
+		t.Fatalf("GetNodeLabels: %v", err)
+	}
+	if len(got.Labels) != 2 || got.Labels["zone"] != "attic" || len(got.Taints) != 1 {
+		t.Errorf("stored labels = %+v, want only the owner's first write", got)
+	}
+}
+
+// ── handleConsumerCancelJob ──────────────────────────────────────────────────
+
+func cancelRequest(ps *PortalServer, jobID, consumerID string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/consumer/job/"+jobID+"/cancel", nil)
+	r.SetPathValue("id", jobID)
+	r = withClaims(r, SessionClaims{UserID: consumerID, Email: "cancel@test.com"})
+	w := httptest.NewRecorder()
+	ps.handleConsumerCancelJob(w, r)
+	return w
+}
+
+func TestHandleConsumerCancelJob_Success(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{cancelResp: orchestrator.CancelJobResponse{
+		JobID: "job-1", PriorStatus: "running", ChargedCents: 120,
+	}}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+
+	w := cancelRequest(ps, "job-1", "consumer-1")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if stub.lastCancel != [2]string{"job-1", "consumer-1"} {
+		t.Errorf("CancelJob called with %v, want [job-1 consumer-1]", stub.lastCancel)
+	}
+	var resp struct {
+		Status       string `json:"status"`
+		PriorStatus  string `json:"prior_status"`
+		ChargedCents int64  `json:"charged_cents"`
+		RefundStatus string `json:"refund_status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "cancelled" || resp.PriorStatus != "running" || resp.ChargedCents != 120 || resp.RefundStatus != "none" {
+		t.Errorf("response = %+v, want cancelled/running/120/none", resp)
+	}
+}
+
+func TestHandleConsumerCancelJob_Escrowed(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{cancelResp: orchestrator.CancelJobResponse{
+		JobID: "job-1", PriorStatus: "running", ChargedCents: 120, RefundCents: 380, Escrowed: true,
+	}}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+
+	w := cancelRequest(ps, "job-1", "consumer-1")
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		RefundCents  int64  `json:"refund_cents"`
+		RefundStatus string `json:"refund_status"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.RefundStatus != "escrow" || resp.RefundCents != 380 {
+		t.Errorf("response = %+v, want escrow/380", resp)
+	}
+}
+
+func TestHandleConsumerCancelJob_NotFound_404(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{cancelErr: store.ErrJobNotFound}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+
+	if w := cancelRequest(ps, "job-1", "consumer-1"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestHandleConsumerCancelJob_WrongStatus_409(t *testing.T) {
+	db := setupTestDB(t)
+	stub := &stubOrchestrator{
+		cancelResp: orchestrator.CancelJobResponse{JobID: "job-1", PriorStatus: "completed"},
+		cancelErr:  store.ErrJobNotCancellable,
+	}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+
+	w := cancelRequest(ps, "job-1", "consumer-1")
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", w.Code)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp["error"] != "wrong_status" || resp["current_status"] != "completed" {
+		t.Errorf("409 body = %v, want wrong_status/completed", resp)
+	}
+}
+
 // ── handleConsumerPickedUp ───────────────────────────────────────────────────
 
 func TestHandleConsumerPickedUp_Success(t *testing.T) {
@@ -1268,3 +1604,527 @@ func TestHandleConsumerContestNoShow_WindowExpired_409(t *testing.T) {
 		t.Errorf("expected no dispute opened after window expiry, got %d", count)
 	}
 }
+
+// ── handleConsumerJobArtifacts ───────────────────────────────────────────────
+
+// seedArtifact stores body as jobID's artifact in st and records it with the
+// given expiry.
+func seedArtifact(t *testing.T, db *store.DB, st artifact.Store, jobID, consumerID string, body []byte, expiresAt time.Time) {
+	t.Helper()
+	key := artifact.Key(jobID)
+	if err := st.Put(context.Background(), key, bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if _, err := db.Pool.Exec(context.Background(),
+		`INSERT INTO job_artifacts (job_id, participant_id, object_key, size_bytes, sha256, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		jobID, consumerID, key, len(body), strings.Repeat("a", 64), expiresAt,
+	); err != nil {
+		t.Fatalf("seed artifact: %v", err)
+	}
+}
+
+func seedCompletedBatchJob(t *testing.T, db *store.DB, consumerID, nodeID string) string {
+	t.Helper()
+	var jobID string
+	if err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO jobs (participant_id, node_id, workload_type, status, completed_at, output_path)
+		 VALUES ($1, $2, 'batch_compute'::workload_type, 'completed'::job_status, NOW(), '/output')
+		 RETURNING id`,
+		consumerID, nodeID,
+	).Scan(&jobID); err != nil {
+		t.Fatalf("seed job: %v", err)
+	}
+	return jobID
+}
+
+func TestHandleConsumerJobArtifacts_Download(t *testing.T) {
+	db := setupTestDB(t)
+	st, _ := artifact.NewFSStore(t.TempDir())
+	ps := newTestPortalServer(t, db)
+	ps.artifacts = st
+	consumerID := seedParticipant(t, db, "artifact_dl@test.com", "pass1234")
+	providerID := seedParticipant(t, db, "artifact_dl_prov@test.com", "pass1234")
+	jobID := seedCompletedBatchJob(t, db, consumerID, seedNode(t, db, providerID, "online", "A", "US"))
+	seedArtifact(t, db, st, jobID, consumerID, []byte("tar bytes"), time.Now().Add(time.Hour))
+
+	r := httptest.NewRequest(http.MethodGet, "/consumer/job/"+jobID+"/artifacts", nil)
+	r.SetPathValue("id", jobID)
+	r = withClaims(r, SessionClaims{UserID: consumerID, Email: "artifact_dl@test.com"})
+	w := httptest.NewRecorder()
+	ps.handleConsumerJobArtifacts(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "tar bytes" {
+		t.Errorf("body = %q, want tar bytes", w.Body.String())
+	}
+	if got := w.Header().Get("X-Artifact-SHA256"); got != strings.Repeat("a", 64) {
+		t.Errorf("X-Artifact-SHA256 = %q", got)
+	}
+}
+
+func TestHandleConsumerJobArtifacts_Expired_410(t *testing.T) {
+	db := setupTestDB(t)
+	st, _ := artifact.NewFSStore(t.TempDir())
+	ps := newTestPortalServer(t, db)
+	ps.artifacts = st
+	consumerID := seedParticipant(t, db, "artifact_exp@test.com", "pass1234")
+	providerID := seedParticipant(t, db, "artifact_exp_prov@test.com", "pass1234")
+	jobID := seedCompletedBatchJob(t, db, consumerID, seedNode(t, db, providerID, "online", "A", "US"))
+	seedArtifact(t, db, st, jobID, consumerID, []byte("tar bytes"), time.Now().Add(-time.Minute))
+
+	r := httptest.NewRequest(http.MethodGet, "/consumer/job/"+jobID+"/artifacts", nil)
+	r.SetPathValue("id", jobID)
+	r = withClaims(r, SessionClaims{UserID: consumerID, Email: "artifact_exp@test.com"})
+	w := httptest.NewRecorder()
+	ps.handleConsumerJobArtifacts(w, r)
+
+	if w.Code != http.StatusGone {
+		t.Fatalf("expected 410, got %d", w.Code)
+	}
+}
+
+func TestHandleConsumerJobArtifacts_WrongConsumer_404(t *testing.T) {
+	db := setupTestDB(t)
+	st, _ := artifact.NewFSStore(t.TempDir())
+	ps := newTestPortalServer(t, db)
+	ps.artifacts = st
+	consumerID := seedParticipant(t, db, "artifact_owner@test.com", "pass1234")
+	otherID := seedParticipant(t, db, "artifact_other@test.com", "pass1234")
+	providerID := seedParticipant(t, db, "artifact_owner_prov@test.com", "pass1234")
+	jobID := seedCompletedBatchJob(t, db, consumerID, seedNode(t, db, providerID, "online", "A", "US"))
+	seedArtifact(t, db, st, jobID, consumerID, []byte("tar bytes"), time.Now().Add(time.Hour))
+
+	r := httptest.NewRequest(http.MethodGet, "/consumer/job/"+jobID+"/artifacts", nil)
+	r.SetPathValue("id", jobID)
+	r = withClaims(r, SessionClaims{UserID: otherID, Email: "artifact_other@test.com"})
+	w := httptest.NewRecorder()
+	ps.handleConsumerJobArtifacts(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+// ── handleConsumerJobLogs ────────────────────────────────────────────────────
+
+func logsStreamRequest(jobID, consumerID, lastEventID string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/consumer/job/"+jobID+"/logs", nil)
+	r.SetPathValue("id", jobID)
+	if lastEventID != "" {
+		r.Header.Set("Last-Event-ID", lastEventID)
+	}
+	return withClaims(r, SessionClaims{UserID: consumerID})
+}
+
+func TestHandleConsumerJobLogs_FinishedJob(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	consumerID := seedParticipant(t, db, "logs_tail@test.com", "pass1234")
+	providerID := seedParticipant(t, db, "logs_tail_prov@test.com", "pass1234")
+	jobID := seedCompletedBatchJob(t, db, consumerID, seedNode(t, db, providerID, "online", "A", "US"))
+	if _, err := store.AppendJobLogs(context.Background(), db, jobID, []store.LogChunk{
+		{Seq: 0, Stream: "stdout", Data: "step 1\n"},
+		{Seq: 1, Stream: "stderr", Data: "warning\n"},
+	}); err != nil {
+		t.Fatalf("AppendJobLogs: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	ps.handleConsumerJobLogs(w, logsStreamRequest(jobID, consumerID, ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"id: 0\nevent: log\ndata: {\"data\":\"step 1\\n\",\"seq\":0,\"stream\":\"stdout\"}\n\n",
+		"id: 1\nevent: log\n",
+		"event: end\ndata: {\"status\":\"completed\",\"truncated\":false}\n\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stream missing %q:\n%s", want, body)
+		}
+	}
+
+	// A reconnect resumes after the last event it saw.
+	w = httptest.NewRecorder()
+	ps.handleConsumerJobLogs(w, logsStreamRequest(jobID, consumerID, "0"))
+	if body := w.Body.String(); strings.Contains(body, "id: 0\n") || !strings.Contains(body, "id: 1\n") {
+		t.Errorf("resumed stream:\n%s", body)
+	}
+}
+
+func TestHandleConsumerJobLogs_WrongConsumer_404(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	consumerID := seedParticipant(t, db, "logs_owner@test.com", "pass1234")
+	otherID := seedParticipant(t, db, "logs_other@test.com", "pass1234")
+	providerID := seedParticipant(t, db, "logs_owner_prov@test.com", "pass1234")
+	jobID := seedCompletedBatchJob(t, db, consumerID, seedNode(t, db, providerID, "online", "A", "US"))
+
+	w := httptest.NewRecorder()
+	ps.handleConsumerJobLogs(w, logsStreamRequest(jobID, otherID, ""))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+// ── handleConsumerUploadInput ────────────────────────────────────────────────
+
+// uploadInput POSTs body to /consumer/inputs as participantID and returns the
+// recorder.
+func uploadInput(t *testing.T, ps *PortalServer, participantID string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/consumer/inputs", bytes.NewReader(body))
+	r = withClaims(r, SessionClaims{UserID: participantID})
+	w := httptest.NewRecorder()
+	ps.handleConsumerUploadInput(w, r)
+	return w
+}
+
+func TestHandleConsumerUploadInput_ContentAddressed(t *testing.T) {
+	db := setupTestDB(t)
+	st, _ := artifact.NewFSStore(t.TempDir())
+	ps := newTestPortalServer(t, db)
+	ps.artifacts, ps.limits = st, artifact.DefaultLimits
+	consumerID := seedParticipant(t, db, "input_up@test.com", "pass1234")
+
+	body := []byte("G28\nG1 X10\n")
+	var first inputUploadResponse
+	for i := 0; i < 2; i++ {
+		w := uploadInput(t, ps, consumerID, body)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("upload %d: expected 201, got %d: %s", i, w.Code, w.Body.String())
+		}
+		var resp inputUploadResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if i == 0 {
+			first = resp
+		} else if resp.SHA256 != first.SHA256 {
+			t.Errorf("re-upload digest %s, want %s", resp.SHA256, first.SHA256)
+		}
+	}
+	if first.SizeBytes != int64(len(body)) {
+		t.Errorf("size_bytes = %d, want %d", first.SizeBytes, len(body))
+	}
+	if _, err := store.LiveInputUpload(context.Background(), db, consumerID, first.SHA256); err != nil {
+		t.Fatalf("LiveInputUpload: %v", err)
+	}
+	used, err := store.ArtifactUsage(context.Background(), db, consumerID)
+	if err != nil || used != int64(len(body)) {
+		t.Errorf("usage = %d (%v), want one copy of %d bytes", used, err, len(body))
+	}
+}
+
+func TestHandleConsumerUploadInput_QuotaExceeded(t *testing.T) {
+	db := setupTestDB(t)
+	st, _ := artifact.NewFSStore(t.TempDir())
+	ps := newTestPortalServer(t, db)
+	ps.artifacts = st
+	ps.limits = artifact.Limits{MaxBytes: 1 << 20, QuotaBytes: 4, TTL: time.Hour}
+	consumerID := seedParticipant(t, db, "input_quota@test.com", "pass1234")
+
+	if w := uploadInput(t, ps, consumerID, []byte("too big")); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", w.Code)
+	}
+}
+
+func TestHandleSubmitJob_Inputs(t *testing.T) {
+	db := setupTestDB(t)
+	st, _ := artifact.NewFSStore(t.TempDir())
+	stub := &stubOrchestrator{}
+	ps := newTestPortalServerWithOrch(t, db, stub)
+	ps.artifacts, ps.limits = st, artifact.DefaultLimits
+	consumerID := seedParticipant(t, db, "input_submit@test.com", "pass1234")
+	nodeID := seedNode(t, db, consumerID, "online", "A", "US")
+
+	w := uploadInput(t, ps, consumerID, []byte("G28\n"))
+	var up inputUploadResponse
+	if err := json.NewDecoder(w.Body).Decode(&up); err != nil {
+		t.Fatalf("decode upload: %v", err)
+	}
+
+	submit := func(sum string) *httptest.ResponseRecorder {
+		body := strings.NewReader("node_id=" + nodeID + "&container_image=nginx%3Alatest" +
+			"&input_name=part.gcode&input_sha256=" + sum)
+		r := httptest.NewRequest(http.MethodPost, "/consumer/job", body)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = withClaims(r, SessionClaims{UserID: consumerID, Email: "input_submit@test.com"})
+		w := httptest.NewRecorder()
+		ps.handleSubmitJob(w, r)
+		return w
+	}
+
+	if w := submit(up.SHA256); w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	want := []orchestrator.JobInput{{Name: "part.gcode", SHA256: up.SHA256}}
+	if got := stub.lastReq.Inputs; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("Inputs = %+v, want %+v", got, want)
+	}
+
+	if w := submit(strings.Repeat("b", 64)); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown upload: expected 400, got %d", w.Code)
+	}
+}
+
+func TestHandleProviderPayoutSchedule_Threshold(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	ownerID := seedParticipant(t, db, "payout-owner@test.com", "pass1234")
+
+	body := strings.NewReader("schedule=threshold&threshold_dollars=25.50")
+	r := httptest.NewRequest(http.MethodPost, "/provider/payouts/schedule", body)
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r = withClaims(r, SessionClaims{UserID: ownerID, Email: "payout-owner@test.com"})
+	w := httptest.NewRecorder()
+
+	ps.handleProviderPayoutSchedule(w, r)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected 303, got %d: %s", w.Code, w.Body.String())
+	}
+	got, err := store.GetPayoutSchedule(context.Background(), db, ownerID)
+	if err != nil {
+		t.Fatalf("GetPayoutSchedule: %v", err)
+	}
+	if got.Schedule != store.PayoutThreshold || got.ThresholdCents != 2550 {
+		t.Errorf("schedule = %+v, want threshold at 2550 cents", got)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/provider/payouts", nil)
+	r = withClaims(r, SessionClaims{UserID: ownerID, Email: "payout-owner@test.com"})
+	w = httptest.NewRecorder()
+	ps.handleProviderPayouts(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("payouts page: expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "25.50") {
+		t.Error("payouts page does not show the threshold")
+	}
+}
+
+func TestHandleProviderPayoutSchedule_Invalid(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	ownerID := seedParticipant(t, db, "payout-invalid@test.com", "pass1234")
+
+	for _, form := range []string{"schedule=hourly", "schedule=threshold&threshold_dollars=0"} {
+		r := httptest.NewRequest(http.MethodPost, "/provider/payouts/schedule", strings.NewReader(form))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r = withClaims(r, SessionClaims{UserID: ownerID, Email: "payout-invalid@test.com"})
+		w := httptest.NewRecorder()
+
+		ps.handleProviderPayoutSchedule(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", form, w.Code)
+		}
+	}
+}
+
+// ── Stripe webhook events ────────────────────────────────────────────────────
+
+// stripeEvent builds a verified webhook event of type typ about obj.
+func stripeEvent(t *testing.T, id, typ string, obj map[string]any) stripe.Event {
+	t.Helper()
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("marshal event object: %v", err)
+	}
+	return stripe.Event{ID: id, Type: stripe.EventType(typ), Data: &stripe.EventData{Raw: raw}}
+}
+
+// deliver hands ev to the webhook dispatcher twice, as Stripe's redelivery
+// would.
+func deliver(t *testing.T, ps *PortalServer, ev stripe.Event) {
+	t.Helper()
+	for range 2 {
+		if err := ps.onStripeEvent(ev); err != nil {
+			t.Fatalf("onStripeEvent(%s): %v", ev.Type, err)
+		}
+	}
+}
+
+// seedEscrowJob inserts an unplaced job of consumerID in status, held in
+// escrow by paymentIntentID for 5000 cents, and returns its UUID.
+func seedEscrowJob(t *testing.T, db *store.DB, consumerID, status, paymentStatus, paymentIntentID string) string {
+	t.Helper()
+	var id string
+	err := db.Pool.QueryRow(context.Background(),
+		`INSERT INTO jobs (participant_id, workload_type, status, amount_cents, payment_status, payment_intent_id)
+		 VALUES ($1, 'batch_compute', $2::job_status, 5000, $3, $4) RETURNING id`,
+		consumerID, status, paymentStatus, paymentIntentID,
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("seedEscrowJob: %v", err)
+	}
+	return id
+}
+
+// jobPaymentStatus returns jobID's payment_status.
+func jobPaymentStatus(t *testing.T, db *store.DB, jobID string) string {
+	t.Helper()
+	var status string
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT payment_status FROM jobs WHERE id = $1`, jobID,
+	).Scan(&status); err != nil {
+		t.Fatalf("read payment_status: %v", err)
+	}
+	return status
+}
+
+func TestOnStripeEvent_PaymentIntentSucceeded(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	consumer := seedParticipant(t, db, "wh_succeeded@test.com", "pw")
+	finished := seedEscrowJob(t, db, consumer, "failed", store.PaymentAuthorized, "pi_wh_finished")
+	running := seedEscrowJob(t, db, consumer, "running", store.PaymentAuthorized, "pi_wh_running")
+
+	for _, pi := range []string{"pi_wh_finished", "pi_wh_running"} {
+		deliver(t, ps, stripeEvent(t, "evt_"+pi, "payment_intent.succeeded", map[string]any{
+			"id": pi, "object": "payment_intent", "status": "succeeded",
+			"amount_received": 1200, "latest_charge": "ch_" + pi,
+		}))
+	}
+
+	var (
+		captured, refund int64
+		chargeID         string
+		settlements      int
+	)
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT captured_cents, charge_id, refund_cents,
+		        (SELECT COUNT(*) FROM ledger_transactions WHERE job_id = $1)
+		 FROM jobs WHERE id = $1`, finished,
+	).Scan(&captured, &chargeID, &refund, &settlements); err != nil {
+		t.Fatalf("read finished job: %v", err)
+	}
+	if got := jobPaymentStatus(t, db, finished); got != store.PaymentCaptured {
+		t.Errorf("finished job: payment_status = %q, want captured", got)
+	}
+	if captured != 1200 || chargeID != "ch_pi_wh_finished" || refund != 3800 {
+		t.Errorf("finished job: captured %d as %q, refund %d; want 1200 as ch_pi_wh_finished, 3800",
+			captured, chargeID, refund)
+	}
+	if settlements != 1 {
+		t.Errorf("finished job: %d ledger transactions after redelivery, want 1", settlements)
+	}
+	// A capture of a job still running is not ours to settle yet.
+	if got := jobPaymentStatus(t, db, running); got != store.PaymentAuthorized {
+		t.Errorf("running job: payment_status = %q, want authorized", got)
+	}
+
+	var logged int
+	if err := db.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM stripe_events WHERE id = 'evt_pi_wh_finished'`,
+	).Scan(&logged); err != nil || logged != 1 {
+		t.Errorf("stripe_events rows = %d, %v; want 1", logged, err)
+	}
+}
+
+func TestOnStripeEvent_PaymentIntentPaymentFailed(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	consumer := seedParticipant(t, db, "wh_failed@test.com", "pw")
+	jobID := seedEscrowJob(t, db, consumer, "scheduled", store.PaymentAwaiting, "pi_wh_declined")
+
+	deliver(t, ps, stripeEvent(t, "evt_wh_declined", "payment_intent.payment_failed", map[string]any{
+		"id": "pi_wh_declined", "object": "payment_intent", "status": "requires_payment_method",
+		"last_payment_error": map[string]any{"code": "card_declined", "message": "Your card was declined."},
+	}))
+
+	p, err := store.GetJobPayment(context.Background(), db, jobID, consumer)
+	if err != nil {
+		t.Fatalf("GetJobPayment: %v", err)
+	}
+	if p.Status != store.PaymentAwaiting || p.PaymentError != "Your card was declined." {
+		t.Errorf("after payment_failed: %+v; want awaiting with the decline", p)
+	}
+
+	if _, err := store.AuthorizeJobPayment(context.Background(), db, "pi_wh_declined"); err != nil {
+		t.Fatalf("AuthorizeJobPayment: %v", err)
+	}
+	if p, _ = store.GetJobPayment(context.Background(), db, jobID, consumer); p.PaymentError != "" {
+		t.Errorf("after authorization: payment_error = %q, want cleared", p.PaymentError)
+	}
+}
+
+func TestOnStripeEvent_PaymentIntentCanceled(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	consumer := seedParticipant(t, db, "wh_canceled@test.com", "pw")
+	unpaid := seedEscrowJob(t, db, consumer, "scheduled", store.PaymentAwaiting, "pi_wh_unpaid")
+	running := seedEscrowJob(t, db, consumer, "running", store.PaymentAuthorized, "pi_wh_lapsed")
+
+	for _, pi := range []string{"pi_wh_unpaid", "pi_wh_lapsed"} {
+		deliver(t, ps, stripeEvent(t, "evt_"+pi, "payment_intent.canceled", map[string]any{
+			"id": pi, "object": "payment_intent", "status": "canceled",
+		}))
+	}
+
+	if got := jobPaymentStatus(t, db, unpaid); got != store.PaymentReleased {
+		t.Errorf("unpaid job: payment_status = %q, want released", got)
+	}
+	if got := jobPaymentStatus(t, db, running); got != store.PaymentAuthorized {
+		t.Errorf("running job: payment_status = %q, want authorized until it can settle", got)
+	}
+}
+
+func TestOnStripeEvent_ChargeRefunded(t *testing.T) {
+	db := setupTestDB(t)
+	ps := newTestPortalServer(t, db)
+	consumer := seedParticipant(t, db, "wh_refunded@test.com", "pw")
+	owe := func(paymentIntentID string) string {
+		t.Helper()
+		var id string
+		if err := db.Pool.QueryRow(context.Background(),
+			`INSERT INTO jobs (participant_id, workload_type, status, amount_cents, payment_intent_id,
+			                   refund_cents, cancelled_at, failure_cause)
+			 VALUES ($1, 'batch_compute', 'failed', 1000, $2, 300, NOW(), $3) RETURNING id`,
+			consumer, paymentIntentID, store.FailureCauseConsumerCancelled,
+		).Scan(&id); err != nil {
+			t.Fatalf("owe refund: %v", err)
+		}
+		return id
+	}
+	full := owe("pi_wh_refund_full")
+	partial := owe("pi_wh_refund_partial")
+
+	for pi, refunded := range map[string]int{"pi_wh_refund_full": 300, "pi_wh_refund_partial": 100} {
+		deliver(t, ps, stripeEvent(t, "evt_"+pi, "charge.refunded", map[string]any{
+			"id": "ch_" + pi, "object": "charge", "payment_intent": pi,
+			"amount": 1000, "amount_refunded": refunded, "refunded": false,
+		}))
+	}
+
+	refunded := func(jobID string) bool {
+		t.Helper()
+		var done bool
+		if err := db.Pool.QueryRow(context.Background(),
+			`SELECT refunded_at IS NOT NULL FROM jobs WHERE id = $1`, jobID,
+		).Scan(&done); err != nil {
+			t.Fatalf("read refunded_at: %v", err)
+		}
+		return done
+	}
+	if !refunded(full) {
+		t.Error("refund covered by the charge: not recorded")
+	}
+	if refunded(partial) {
+		t.Error("refund larger than the charge's refunds: recorded")
+	}
+	owed, err := store.OwedCancelRefunds(context.Background(), db)
+	if err != nil {
+		t.Fatalf("OwedCancelRefunds: %v", err)
+	}
+	for _, o := range owed {
+		if o.JobID == full {
+			t.Error("recorded refund still listed as owed")
+		}
+	}
+}
@@ -61,3 +61,53 @@ func (rl *LoginRateLimiter) RecordFailure(ip string) {
 func (rl *LoginRateLimiter) Reset(ip string) {
 	rl.m.Delete(ip)
 }
+
+// keyBucket is one API key's token bucket.
+type keyBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// APIRateLimiter throttles /api/v1 requests per API key with a token bucket:
+// each key may burst up to burst requests, refilled at perSecond. Unlike
+// LoginRateLimiter it counts every request, not only failures. Safe for
+// concurrent use; process-local, like the login limiter.
+type APIRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*keyBucket
+	burst     float64
+	perSecond float64
+	now       func() time.Time
+}
+
+// NewAPIRateLimiter returns an APIRateLimiter allowing burst requests per key
+// at once and perSecond sustained.
+func NewAPIRateLimiter(burst int, perSecond float64) *APIRateLimiter {
+	return &APIRateLimiter{
+		buckets:   make(map[string]*keyBucket),
+		burst:     float64(burst),
+		perSecond: perSecond,
+		now:       time.Now,
+	}
+}
+
+// Allow spends one of key's tokens. When none is left it returns false and
+// how long until one is.
+func (rl *APIRateLimiter) Allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &keyBucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens = min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.perSecond)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / rl.perSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
@@ -4,15 +4,20 @@ import (
 	"context"
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
@@ -23,6 +28,7 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/metrics"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/orchestrator"
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
@@ -34,6 +40,8 @@ import (
 // Defined as an interface so tests can inject a stub without a real Orchestrator.
 type jobSubmitter interface {
 	SubmitJob(ctx context.Context, req orchestrator.SubmitJobRequest) (orchestrator.SubmitJobResponse, error)
+	CancelJob(ctx context.Context, jobID, consumerID string) (orchestrator.CancelJobResponse, error)
+	EstimateJob(ctx context.Context, req orchestrator.EstimateJobRequest) (orchestrator.EstimateJobResponse, error)
 }
 
 // PortalServer is the SoHoLINK marketplace portal HTTP server. It sits behind
@@ -43,12 +51,19 @@ type PortalServer struct {
 	metricsSrv    *http.Server
 	db            *store.DB
 	sm            *SessionManager
-	payment       *payment.Client
+	payment       payment.PaymentProvider
 	orch          jobSubmitter
 	baseURL       string
 	templatePaths []string
 	limiter       *LoginRateLimiter
+	apiLimiter    *APIRateLimiter
 	webhookSecret string
+	artifacts     artifact.Store
+	limits        artifact.Limits
+
+	// escrowKey is the Stripe publishable key the payment page loads
+	// Stripe.js with; non-empty enables escrow at submission (WithEscrow).
+	escrowKey string
 }
 
 // onboardingData is the template data for provider_onboarding.html.
@@ -94,6 +109,11 @@ type DisputeRow struct {
 	ConsumerEmail     string
 	NodeClass         string
 	CountryCode       string
+	// BilledCents and Breakdown come from the job's metering record so staff
+	// can see which resource line a dispute contests. Zero/empty when the job
+	// was never metered.
+	BilledCents int64
+	Breakdown   []store.MeteringLine
 }
 
 // DisputeQueueData is the template data for dispute_queue.html.
@@ -103,6 +123,34 @@ type DisputeQueueData struct {
 	IsAuthenticated bool
 }
 
+// PayoutBatchRow is one payout batch on provider_payouts.html.
+type PayoutBatchRow struct {
+	ID          string
+	Status      string
+	Dollars     float64
+	PayoutID    string
+	FailureCode string // the bank's reason, when Status is "failed"
+	CreatedAt   time.Time
+	ReleasedAt  *time.Time
+	Lines       []PayoutLineRow
+}
+
+// PayoutLineRow is one job paid in a payout batch.
+type PayoutLineRow struct {
+	JobID   string
+	Dollars float64
+}
+
+// PayoutsData is the template data for provider_payouts.html.
+type PayoutsData struct {
+	Email            string
+	Schedule         string
+	ThresholdDollars float64
+	OwedDollars      float64
+	Batches          []PayoutBatchRow
+	IsAuthenticated  bool
+}
+
 // NodeListing is a single marketplace node entry.
 type NodeListing struct {
 	ID            string
@@ -190,11 +238,34 @@ type JobStatusData struct {
 	Status          string
 	NodeID          string
 	FailureCause    string
+	PaymentStatus   string
 	CreatedAt       time.Time
+	Receipt         *JobReceipt
 	Email           string
 	IsAuthenticated bool
 }
 
+// JobReceipt is a metered job's bill on consumer_job_status.html: what each
+// resource cost and how the payment was split, under which fee declaration.
+type JobReceipt struct {
+	Lines              []ReceiptLine
+	UsageSource        string
+	TotalDollars       float64
+	ContributorDollars float64
+	PlatformDollars    float64
+	// FeeSeq is the fee declaration applied; nil for the platform default.
+	FeeSeq *uint64
+}
+
+// ReceiptLine is one billed resource on a JobReceipt.
+type ReceiptLine struct {
+	ResourceType string
+	Quantity     float64
+	Rate         float64
+	Multiplier   float64
+	Dollars      float64
+}
+
 // JobConfirmData is the template data for contributor_job_confirm.html.
 type JobConfirmData struct {
 	Email                string
@@ -229,6 +300,13 @@ type OptOutNodeRow struct {
 	Version        int
 	SyncStatus     string
 	Printers       []PrinterRow
+
+	// Labels and Taints are the owner-set ones, AgentLabels and AgentTaints
+	// the ones the agent reports, all in types.ParseNodeLabels syntax.
+	Labels      string
+	Taints      string
+	AgentLabels string
+	AgentTaints string
 }
 
 // PrinterRow is one printer attached to a node, with its current enabled state.
@@ -250,6 +328,21 @@ type optOutAPIResponse struct {
 	Printers      []optOutPrinterDTO `json:"printers"`
 }
 
+// telemetrySampleDTO is one point of the per-job CPU/RAM series.
+type telemetrySampleDTO struct {
+	SampledAt     time.Time `json:"sampled_at"`
+	CPUPct        float64   `json:"cpu_pct"`
+	RAMPct        float64   `json:"ram_pct"`
+	BandwidthMbps int       `json:"bandwidth_mbps"`
+}
+
+// jobTelemetryResponse is the JSON shape returned from the consumer and
+// provider job telemetry endpoints.
+type jobTelemetryResponse struct {
+	JobID   string               `json:"job_id"`
+	Samples []telemetrySampleDTO `json:"samples"`
+}
+
 type optOutPrinterDTO struct {
 	PrinterID string `json:"printer_id"`
 	Name      string `json:"printer_name"`
@@ -265,6 +358,14 @@ type optOutPostRequest struct {
 	Printers []optOutPrinterDTO `json:"printers"`
 }
 
+// nodeLabelsPostRequest is the JSON body for POST /api/node-labels. Labels
+// and taints are comma-separated key or key=value entries.
+type nodeLabelsPostRequest struct {
+	NodeID string `json:"node_id"`
+	Labels string `json:"labels"`
+	Taints string `json:"taints"`
+}
+
 // Option customizes a PortalServer at construction. Options are applied before
 // routes are registered so they can influence route wiring (e.g. mounting the
 // public operator console, which re-parents GET / to the operator landing).
@@ -278,6 +379,15 @@ type portalOptions struct {
 	// register its own GET / member index — the operator console's GET /{$} owns
 	// the root per design §11 (member routes stay live under their own paths).
 	operatorRoutes func(mux *http.ServeMux)
+
+	// artifacts, when non-nil, serves GET /consumer/job/{id}/artifacts and
+	// accepts POST /consumer/inputs within limits.
+	artifacts artifact.Store
+	limits    artifact.Limits
+
+	// escrowKey, when non-empty, holds consumers' payment in escrow from
+	// submission (see WithEscrow).
+	escrowKey string
 }
 
 // WithOperatorConsole mounts the public operator console onto the portal mux.
@@ -290,11 +400,28 @@ func WithOperatorConsole(register func(mux *http.ServeMux)) Option {
 	return func(o *portalOptions) { o.operatorRoutes = register }
 }
 
+// WithArtifactStore lets consumers download job output artifacts from st,
+// which must be the store the orchestrator uploads into, and upload job input
+// files into it within limits. Without it both answer 503.
+func WithArtifactStore(st artifact.Store, limits artifact.Limits) Option {
+	return func(o *portalOptions) { o.artifacts, o.limits = st, limits }
+}
+
+// WithEscrow holds each consumer job's payment in escrow: a submitted job is
+// quoted at its maximum cost, the consumer authorizes a hold for it on
+// /consumer/job/{id}/pay — a Stripe.js page loaded with publishableKey — and
+// the job is dispatched only once the hold is authorized. store.RunEscrowSettler
+// captures the metered cost when the job finishes. Print workloads are never
+// escrowed. Requires a payment client.
+func WithEscrow(publishableKey string) Option {
+	return func(o *portalOptions) { o.escrowKey = publishableKey }
+}
+
 // New constructs a PortalServer. It walks templatesDir recursively to collect
 // all .html file paths (not parsed yet — see renderTemplate), registers routes,
 // and builds the http.Server. metricsAddr is the address for the plain HTTP
 // metrics server (e.g. ":9090") — not wrapped with session auth.
-func New(db *store.DB, addr string, privateKey ed25519.PrivateKey, templatesDir string, paymentClient *payment.Client, baseURL string, orch jobSubmitter, metricsAddr string, webhookSecret string, opts ...Option) (*PortalServer, error) {
+func New(db *store.DB, addr string, privateKey ed25519.PrivateKey, templatesDir string, paymentClient payment.PaymentProvider, baseURL string, orch jobSubmitter, metricsAddr string, webhookSecret string, opts ...Option) (*PortalServer, error) {
 	var options portalOptions
 	for _, opt := range opts {
 		opt(&options)
@@ -323,8 +450,12 @@ func New(db *store.DB, addr string, privateKey ed25519.PrivateKey, templatesDir
 		orch:          orch,
 		baseURL:       baseURL,
 		templatePaths: paths,
+		artifacts:     options.artifacts,
+		limits:        options.limits,
+		escrowKey:     options.escrowKey,
 	}
 	ps.limiter = NewLoginRateLimiter(5, 15*time.Minute)
+	ps.apiLimiter = NewAPIRateLimiter(apiRateBurst, apiRatePerSecond)
 	ps.webhookSecret = webhookSecret
 
 	mux := http.NewServeMux()
@@ -366,10 +497,26 @@ func New(db *store.DB, addr string, privateKey ed25519.PrivateKey, templatesDir
 		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerMarketplace)))
 	mux.Handle("POST /consumer/job",
 		RequireAuth(sm, http.HandlerFunc(ps.handleSubmitJob)))
+	mux.Handle("GET /consumer/estimate",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerEstimate)))
 	mux.Handle("GET /consumer/job/{id}",
 		RequireAuth(sm, http.HandlerFunc(ps.handleJobStatus)))
 	mux.Handle("GET /consumer/job/{id}/status-stream",
 		RequireAuth(sm, http.HandlerFunc(ps.handleJobStatusStream)))
+	mux.Handle("GET /consumer/job/{id}/logs",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerJobLogs)))
+	mux.Handle("GET /consumer/job/{id}/telemetry",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerJobTelemetry)))
+	mux.Handle("GET /consumer/job/{id}/artifacts",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerJobArtifacts)))
+	mux.Handle("POST /consumer/inputs",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerUploadInput)))
+	mux.Handle("GET /consumer/job/{id}/pay",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerJobPay)))
+	mux.Handle("GET /consumer/job/{id}/payment-return",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerJobPaymentReturn)))
+	mux.Handle("POST /consumer/job/{id}/cancel",
+		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerCancelJob)))
 	mux.Handle("POST /consumer/job/{id}/picked-up",
 		RequireAuth(sm, http.HandlerFunc(ps.handleConsumerPickedUp)))
 	mux.Handle("POST /consumer/job/{id}/delivered",
@@ -392,6 +539,10 @@ func New(db *store.DB, addr string, privateKey ed25519.PrivateKey, templatesDir
 		RequireAuth(sm, http.HandlerFunc(ps.handleProviderProvision)))
 	mux.Handle("POST /provider/provision/profile",
 		RequireAuth(sm, http.HandlerFunc(ps.handleAddProfile)))
+	mux.Handle("GET /provider/payouts",
+		RequireAuth(sm, http.HandlerFunc(ps.handleProviderPayouts)))
+	mux.Handle("POST /provider/payouts/schedule",
+		RequireAuth(sm, http.HandlerFunc(ps.handleProviderPayoutSchedule)))
 	mux.Handle("POST /node/token",
 		RequireAuth(sm, http.HandlerFunc(ps.handleGenerateNodeToken)))
 	mux.Handle("GET /opt-out",
@@ -400,12 +551,25 @@ func New(db *store.DB, addr string, privateKey ed25519.PrivateKey, templatesDir
 		RequireAuth(sm, http.HandlerFunc(ps.handleGetOptOut)))
 	mux.Handle("POST /api/opt-out",
 		RequireAuth(sm, http.HandlerFunc(ps.handlePostOptOut)))
+	mux.Handle("POST /api/node-labels",
+		RequireAuth(sm, http.HandlerFunc(ps.handlePostNodeLabels)))
 	mux.Handle("GET /provider/job/{id}/confirm",
 		RequireAuth(sm, http.HandlerFunc(ps.handleJobConfirm)))
 	mux.Handle("POST /provider/job/{id}/confirm",
 		RequireAuth(sm, http.HandlerFunc(ps.handleJobConfirmAction)))
 	mux.Handle("POST /provider/job/{id}/no-show",
 		RequireAuth(sm, http.HandlerFunc(ps.handleProviderNoShow)))
+	mux.Handle("GET /provider/job/{id}/telemetry",
+		RequireAuth(sm, http.HandlerFunc(ps.handleProviderJobTelemetry)))
+	mux.Handle("GET /consumer/api-keys",
+		RequireAuth(sm, http.HandlerFunc(ps.handleAPIKeysPage)))
+	mux.Handle("POST /consumer/api-keys",
+		RequireAuth(sm, http.HandlerFunc(ps.handleCreateAPIKey)))
+	mux.Handle("POST /consumer/api-keys/{id}/revoke",
+		RequireAuth(sm, http.HandlerFunc(ps.handleRevokeAPIKey)))
+
+	// Consumer JSON API, authenticated by API key (see apiv1.go).
+	ps.registerAPIRoutes(mux)
 
 	ps.srv = &http.Server{
 		Addr:         addr,
@@ -1057,11 +1221,14 @@ func (ps *PortalServer) handleDisputeQueue(w http.ResponseWriter, r *http.Reques
 		     d.created_at, d.payment_intent_id,
 		     j.id AS job_id,
 		     c.email AS consumer_email,
-		     n.node_class, n.country_code
+		     n.node_class, n.country_code,
+		     COALESCE(jm.consumer_paid_cents, 0),
+		     COALESCE(jm.breakdown, '[]'::jsonb)
 		 FROM disputes d
 		 JOIN jobs j ON j.id = d.job_id
 		 JOIN participants c ON c.id = d.participant_id
 		 JOIN nodes n ON n.id = d.node_id
+		 LEFT JOIN job_metering jm ON jm.job_id = j.id
 		 WHERE d.status IN ('open', 'under_review')
 		 ORDER BY d.created_at ASC`,
 	)
@@ -1074,15 +1241,21 @@ func (ps *PortalServer) handleDisputeQueue(w http.ResponseWriter, r *http.Reques
 	var disputes []DisputeRow
 	for rows.Next() {
 		var d DisputeRow
+		var breakdownJSON []byte
 		if err := rows.Scan(
 			&d.ID, &d.Status, &d.Reason, &d.ConsumerRefundPct,
 			&d.CreatedAt, &d.PaymentIntentID,
 			&d.JobID, &d.ConsumerEmail,
 			&d.NodeClass, &d.CountryCode,
+			&d.BilledCents, &breakdownJSON,
 		); err != nil {
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
+		if err := json.Unmarshal(breakdownJSON, &d.Breakdown); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
 		disputes = append(disputes, d)
 	}
 	if err := rows.Err(); err != nil {
@@ -1124,50 +1297,228 @@ func (ps *PortalServer) handleSubmitJob(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	workloadTypeStr := r.FormValue("workload_type")
-	if workloadTypeStr == "" {
-		workloadTypeStr = string(types.MarketplaceAppHosting)
-	}
-	wt, err := types.ParseMarketplaceWorkloadType(workloadTypeStr)
+	req, err := formJobShape(r.Form)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("invalid workload_type: %s", err), http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	wt := req.WorkloadType
 	containerImage := r.FormValue("container_image")
 	if containerImage == "" {
 		http.Error(w, "container_image is required", http.StatusBadRequest)
 		return
 	}
 
-	cpuCores := 2
-	ramMB := 4096
-	if v := r.FormValue("cpu_cores"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cpuCores = n
-		}
+	inputs, err := formJobInputs(r.Form)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The job runs on the node the consumer picked or not at all. A job of
+	// a higher SLA tier runs its replicas on nodes of distinct owners, so the
+	// picked node is only tried first. output_path (optional) declares
+	// /output, or a file under it, as the job's output; the artifact is then
+	// downloadable from /consumer/job/{id}/artifacts. quote_token (optional)
+	// comes from the estimate page and holds the job to its quoted price.
+	req.ConsumerID = claims.UserID
+	req.ContainerImage = containerImage
+	if req.SLATier > orchestrator.SLAStandard {
+		req.Placement.PreferredNodeIDs = []string{nodeID}
+	} else {
+		req.Placement.RequiredNodeID = nodeID
+	}
+	req.OutputPath = r.FormValue("output_path")
+	req.Inputs = inputs
+	req.QuoteToken = r.FormValue("quote_token")
+	req.Escrow = ps.escrowFor(wt)
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
-	if v := r.FormValue("ram_mb"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			ramMB = n
+	if err := ps.checkInputsUploaded(r.Context(), claims.UserID, inputs); err != nil {
+		if errors.Is(err, store.ErrInputNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
 	}
 
-	resp, err := ps.orch.SubmitJob(r.Context(), orchestrator.SubmitJobRequest{
-		ConsumerID:     claims.UserID,
-		WorkloadType:   wt,
-		ContainerImage: containerImage,
-		CPUCores:       cpuCores,
-		RAMMB:          ramMB,
-	})
+	resp, err := ps.orch.SubmitJob(r.Context(), req)
 	if err != nil {
-		http.Error(w, "failed to submit job", http.StatusInternalServerError)
+		se := orchestrator.SubmitErrorOf(err)
+		if se.Class == orchestrator.SubmitErrInternal {
+			slog.Error("submit job failed", "consumer_id", claims.UserID, "error", err)
+			http.Error(w, "failed to submit job", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, "The job was not submitted: "+se.Message+".", submitErrorStatus(se.Class))
 		return
 	}
 
 	metrics.JobsSubmittedTotal.WithLabelValues(string(wt)).Inc()
+	if req.Escrow {
+		if err := ps.escrowSubmitted(r.Context(), resp, claims.UserID); err != nil {
+			http.Error(w, "payment could not be started; the job was not submitted", http.StatusBadGateway)
+			return
+		}
+		http.Redirect(w, r, "/consumer/job/"+resp.JobID+"/pay", http.StatusSeeOther)
+		return
+	}
 	http.Redirect(w, r, "/consumer/job/"+resp.JobID, http.StatusSeeOther)
 }
 
+// submitErrorStatus is the portal's HTTP status for a SubmitJob rejection of
+// class c. A payment_required rejection is a stale or foreign quote, which the
+// consumer fixes by re-estimating, so it is 422 like the other request
+// problems rather than 402.
+func submitErrorStatus(c orchestrator.SubmitErrorClass) int {
+	switch c {
+	case orchestrator.SubmitErrValidation:
+		return http.StatusBadRequest
+	case orchestrator.SubmitErrImageNotAllowlisted, orchestrator.SubmitErrTypeMismatch,
+		orchestrator.SubmitErrPaymentRequired:
+		return http.StatusUnprocessableEntity
+	case orchestrator.SubmitErrNoCapacity:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// missingInputError names a submission's input that is not a live upload.
+// It unwraps to store.ErrInputNotFound.
+type missingInputError struct{ name string }
+
+func (e missingInputError) Error() string {
+	return fmt.Sprintf("input %q has not been uploaded (or has expired)", e.name)
+}
+
+func (e missingInputError) Unwrap() error { return store.ErrInputNotFound }
+
+// checkInputsUploaded checks that every uploaded input of a submission is
+// one of consumerID's live uploads. SubmitJob enforces this too, but its
+// errors surface as a 500; a missing upload is returned as a
+// missingInputError.
+func (ps *PortalServer) checkInputsUploaded(ctx context.Context, consumerID string, inputs []orchestrator.JobInput) error {
+	for _, in := range inputs {
+		if in.URL != "" {
+			continue
+		}
+		if _, err := store.LiveInputUpload(ctx, ps.db, consumerID, in.SHA256); err != nil {
+			if errors.Is(err, store.ErrInputNotFound) {
+				return missingInputError{name: in.Name}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// escrowSubmitted starts the payment hold for an escrowed job just
+// submitted. When it cannot be started the job cannot be paid for, so it is
+// withdrawn rather than leave its node held until the payment window
+// expires, and the error is returned.
+func (ps *PortalServer) escrowSubmitted(ctx context.Context, resp orchestrator.SubmitJobResponse, consumerID string) error {
+	err := ps.startEscrow(ctx, resp)
+	if err == nil {
+		return nil
+	}
+	slog.Error("start escrow failed", "job_id", resp.JobID, "error", err)
+	if _, cerr := ps.orch.CancelJob(ctx, resp.JobID, consumerID); cerr != nil {
+		slog.Error("withdraw unpayable job failed", "job_id", resp.JobID, "error", cerr)
+	}
+	return err
+}
+
+// formJobShape reads what a submission or an estimate asks for from the
+// workload_type, cpu_cores, ram_mb, max_runtime_seconds and sla_tier form
+// fields. The workload type defaults to app hosting and the resources to
+// 2 vCPU and 4 GB; max_runtime_seconds omitted means the workload type's
+// ceiling, and Validate rejects values above it; sla_tier omitted means a
+// single replica. The estimate page and the submission read the same fields
+// the same way, so a quote matches the job it was asked for.
+func formJobShape(form url.Values) (orchestrator.SubmitJobRequest, error) {
+	var cpuCores, ramMB, maxRuntime, slaTier int
+	if v := form.Get("cpu_cores"); v != "" {
+		cpuCores, _ = strconv.Atoi(v)
+	}
+	if v := form.Get("ram_mb"); v != "" {
+		ramMB, _ = strconv.Atoi(v)
+	}
+	if v := form.Get("max_runtime_seconds"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return orchestrator.SubmitJobRequest{}, errors.New("max_runtime_seconds must be a whole number of seconds")
+		}
+		maxRuntime = n
+	}
+	if v := form.Get("sla_tier"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return orchestrator.SubmitJobRequest{}, errors.New("sla_tier must be 1, 2 or 3")
+		}
+		slaTier = n
+	}
+	return jobShape(apiJobShape{
+		WorkloadType:      form.Get("workload_type"),
+		CPUCores:          cpuCores,
+		RAMMB:             ramMB,
+		MaxRuntimeSeconds: maxRuntime,
+		SLATier:           slaTier,
+	})
+}
+
+// jobShape is formJobShape's request from already-parsed fields, shared with
+// the JSON API so a job shape means the same whichever way it arrives: an
+// empty workload type is app hosting and a non-positive CPUCores or RAMMB
+// takes the default. The remaining fields pass through as given, for
+// Validate to check.
+func jobShape(s apiJobShape) (orchestrator.SubmitJobRequest, error) {
+	workloadType := s.WorkloadType
+	if workloadType == "" {
+		workloadType = string(types.MarketplaceAppHosting)
+	}
+	wt, err := types.ParseMarketplaceWorkloadType(workloadType)
+	if err != nil {
+		return orchestrator.SubmitJobRequest{}, fmt.Errorf("invalid workload_type: %s", err)
+	}
+
+	req := orchestrator.SubmitJobRequest{WorkloadType: wt, CPUCores: 2, RAMMB: 4096}
+	if s.CPUCores > 0 {
+		req.CPUCores = s.CPUCores
+	}
+	if s.RAMMB > 0 {
+		req.RAMMB = s.RAMMB
+	}
+	req.StorageGB = s.StorageGB
+	req.GPURequired = s.GPURequired
+	req.GPUVRAMGB = s.GPUVRAMGB
+	req.MaxRuntimeSeconds = s.MaxRuntimeSeconds
+	req.SLATier = orchestrator.SLATier(s.SLATier)
+	return req, nil
+}
+
+// formJobInputs reads a submission's inputs from the repeated input_name,
+// input_sha256 and input_url form fields, matched by position. input_url may
+// be omitted entirely when every input is an upload.
+func formJobInputs(form url.Values) ([]orchestrator.JobInput, error) {
+	names, sums, urls := form["input_name"], form["input_sha256"], form["input_url"]
+	if len(sums) != len(names) || (len(urls) != 0 && len(urls) != len(names)) {
+		return nil, errors.New("input_name, input_sha256 and input_url must be given once per input")
+	}
+	var inputs []orchestrator.JobInput
+	for i, name := range names {
+		in := orchestrator.JobInput{Name: name, SHA256: strings.ToLower(sums[i])}
+		if len(urls) != 0 {
+			in.URL = urls[i]
+		}
+		inputs = append(inputs, in)
+	}
+	return inputs, nil
+}
+
 func (ps *PortalServer) handleJobStatus(w http.ResponseWriter, r *http.Request) {
 	claims, _ := ClaimsFromContext(r.Context())
 	jobID := r.PathValue("id")
@@ -1178,15 +1529,39 @@ func (ps *PortalServer) handleJobStatus(w http.ResponseWriter, r *http.Request)
 	data.IsAuthenticated = true
 
 	err := ps.db.Pool.QueryRow(r.Context(),
-		`SELECT status, COALESCE(node_id::text, ''), COALESCE(failure_cause, ''), created_at
+		`SELECT status, COALESCE(node_id::text, ''), COALESCE(failure_cause, ''),
+		        COALESCE(payment_status, ''), created_at
 		 FROM jobs WHERE id = $1 AND participant_id = $2`,
 		jobID, claims.UserID,
-	).Scan(&data.Status, &data.NodeID, &data.FailureCause, &data.CreatedAt)
+	).Scan(&data.Status, &data.NodeID, &data.FailureCause, &data.PaymentStatus, &data.CreatedAt)
 	if err != nil {
 		http.Error(w, "job not found", http.StatusNotFound)
 		return
 	}
 
+	m, err := store.GetJobMetering(r.Context(), ps.db, jobID)
+	switch {
+	case err == nil:
+		data.Receipt = &JobReceipt{
+			UsageSource:        m.UsageSource,
+			TotalDollars:       float64(m.ConsumerPaidCents) / 100.0,
+			ContributorDollars: float64(m.ContributorEarnedCents) / 100.0,
+			PlatformDollars:    float64(m.PlatformFeeCents) / 100.0,
+			FeeSeq:             m.FeeSeq,
+		}
+		for _, l := range m.Breakdown {
+			data.Receipt.Lines = append(data.Receipt.Lines, ReceiptLine{
+				ResourceType: l.ResourceType,
+				Quantity:     l.Quantity,
+				Rate:         l.Rate,
+				Multiplier:   l.Multiplier,
+				Dollars:      float64(l.Cents) / 100.0,
+			})
+		}
+	case !errors.Is(err, store.ErrMeteringNotFound):
+		slog.Warn("handleJobStatus: get job metering", "job_id", jobID, "error", err)
+	}
+
 	ps.renderTemplate(w, "consumer_job_status.html", data)
 }
 
@@ -1361,6 +1736,365 @@ func (ps *PortalServer) handleJobStatusStream(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// logTailBatch bounds the chunks one log-tail poll reads.
+const logTailBatch = 200
+
+// handleConsumerJobLogs streams a job's container output as server-sent
+// events: one "log" event per stored chunk, its id the chunk's seq so a
+// reconnecting EventSource resumes via Last-Event-ID. For a replicated job
+// ?replica=N picks the replica (default 0). Once the job is no longer live
+// and every chunk has been sent, an "end" event reports whether the log was
+// truncated and the stream closes.
+func (ps *PortalServer) handleConsumerJobLogs(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID := r.PathValue("id")
+
+	replica := 0
+	if v := r.URL.Query().Get("replica"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "replica must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		replica = n
+	}
+	after := int64(-1)
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		after = n
+	}
+
+	job, err := store.ConsumerLogJob(r.Context(), ps.db, jobID, claims.UserID, replica)
+	if err != nil {
+		if errors.Is(err, store.ErrJobLogNotFound) {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		slog.Error("log tail lookup failed", "job_id", jobID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// A tail outlives the server's 15s write deadline; the client's
+	// disconnect ends it instead.
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	flusher.Flush()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		// Re-read the status before the chunks: output stored before a job
+		// finished is then always sent before "end".
+		if job, err = store.ConsumerLogJob(r.Context(), ps.db, jobID, claims.UserID, replica); err != nil {
+			if r.Context().Err() == nil {
+				fmt.Fprintf(w, "event: error\ndata: {\"error\":\"job not found\"}\n\n")
+				flusher.Flush()
+			}
+			return
+		}
+
+		chunks, err := store.JobLogs(r.Context(), ps.db, job.JobID, after, logTailBatch)
+		if err != nil {
+			if r.Context().Err() == nil {
+				slog.Error("log tail read failed", "job_id", job.JobID, "error", err)
+			}
+			return
+		}
+		for _, c := range chunks {
+			data, _ := json.Marshal(map[string]any{"seq": c.Seq, "stream": c.Stream, "data": c.Data})
+			fmt.Fprintf(w, "id: %d\nevent: log\ndata: %s\n\n", c.Seq, data)
+			after = c.Seq
+		}
+		if len(chunks) == logTailBatch {
+			flusher.Flush()
+			continue
+		}
+		if !logLive(job.Status) {
+			fmt.Fprintf(w, "event: end\ndata: {\"status\":%q,\"truncated\":%t}\n\n", job.Status, job.Truncated)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logLive reports whether a job in status may still produce output: it has
+// not run yet, is running, or is waiting to be rerouted.
+func logLive(status string) bool {
+	switch status {
+	case "pending", "scheduled", "dispatched", "running", "awaiting_confirmation", "declined":
+		return true
+	}
+	return false
+}
+
+// handleConsumerJobTelemetry returns the verified CPU/RAM series for a job
+// the caller submitted. Optional ?since=<RFC 3339> fetches only newer samples
+// so the status page can poll incrementally.
+func (ps *PortalServer) handleConsumerJobTelemetry(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID := r.PathValue("id")
+
+	var exists bool
+	err := ps.db.Pool.QueryRow(r.Context(),
+		`SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1 AND participant_id = $2)`,
+		jobID, claims.UserID,
+	).Scan(&exists)
+	if err != nil || !exists {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	ps.writeJobTelemetry(w, r, jobID)
+}
+
+// artifactDownloadTimeout replaces the server's 15s write deadline for the
+// duration of an artifact download.
+const artifactDownloadTimeout = 30 * time.Minute
+
+// handleConsumerJobArtifacts streams the output artifact (a tar of the job's
+// /output directory) of a job the caller submitted. For a replicated job it
+// is the first completed replica's artifact. 404 when the job produced none,
+// 410 once it has expired; X-Artifact-SHA256 carries the digest the agent
+// reported and the control plane verified.
+func (ps *PortalServer) handleConsumerJobArtifacts(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	ps.serveJobArtifact(w, r, r.PathValue("id"), claims.UserID,
+		func(status int, _, msg string) { http.Error(w, msg, status) })
+}
+
+// serveJobArtifact streams consumerID's artifact for jobID, as described on
+// handleConsumerJobArtifacts. fail writes an error response: the HTML portal
+// and the JSON API answer the same failures in their own formats, so each
+// failure carries the API's error code as well as a status and message.
+func (ps *PortalServer) serveJobArtifact(w http.ResponseWriter, r *http.Request, jobID, consumerID string, fail func(status int, code, msg string)) {
+	if ps.artifacts == nil {
+		fail(http.StatusServiceUnavailable, apiCodeUnavailable, "artifact downloads unavailable")
+		return
+	}
+	a, err := store.ConsumerArtifact(r.Context(), ps.db, jobID, consumerID)
+	if err != nil {
+		if errors.Is(err, store.ErrArtifactNotFound) {
+			fail(http.StatusNotFound, apiCodeArtifactNotFound, "artifact not found")
+			return
+		}
+		slog.Error("artifact lookup failed", "job_id", jobID, "error", err)
+		fail(http.StatusInternalServerError, apiCodeInternal, "database error")
+		return
+	}
+	if a.Expired(time.Now()) {
+		fail(http.StatusGone, apiCodeArtifactExpired, "artifact expired")
+		return
+	}
+
+	body, err := ps.artifacts.Open(r.Context(), a.ObjectKey)
+	if err != nil {
+		slog.Error("artifact open failed", "job_id", jobID, "key", a.ObjectKey, "error", err)
+		if errors.Is(err, artifact.ErrNotFound) {
+			fail(http.StatusNotFound, apiCodeArtifactNotFound, "artifact not found")
+			return
+		}
+		fail(http.StatusBadGateway, apiCodeUnavailable, "artifact store error")
+		return
+	}
+	defer body.Close()
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(artifactDownloadTimeout))
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Header().Set("Content-Length", strconv.FormatInt(a.SizeBytes, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", jobID+"-output.tar"))
+	w.Header().Set("X-Artifact-SHA256", a.SHA256)
+	w.Header().Set("Cache-Control", "private, no-store")
+	if _, err := io.Copy(w, body); err != nil {
+		slog.Warn("artifact download interrupted", "job_id", jobID, "error", err)
+	}
+}
+
+// inputUploadTimeout replaces the server's 15s read deadline for the duration
+// of an input upload.
+const inputUploadTimeout = 30 * time.Minute
+
+// inputUploadResponse is the JSON body returned by POST /consumer/inputs.
+type inputUploadResponse struct {
+	SHA256    string    `json:"sha256"`
+	SizeBytes int64     `json:"size_bytes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// handleConsumerUploadInput accepts a job input file ahead of submission. The
+// body is the raw file with an exact Content-Length; the response carries its
+// SHA-256, which the submission then references in input_sha256. Uploads are
+// content-addressed per consumer: re-uploading the same bytes stores nothing
+// new and only extends the expiry. Bounded by the artifact limits — the
+// single-object size and the consumer quota shared with job artifacts (413).
+func (ps *PortalServer) handleConsumerUploadInput(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+
+	if ps.artifacts == nil {
+		http.Error(w, "input uploads unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	size := r.ContentLength
+	if size < 0 {
+		http.Error(w, "Content-Length required", http.StatusLengthRequired)
+		return
+	}
+	if size > ps.limits.MaxBytes {
+		http.Error(w, "input exceeds maximum size", http.StatusRequestEntityTooLarge)
+		return
+	}
+	used, err := store.ArtifactUsage(r.Context(), ps.db, claims.UserID)
+	if err != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if used+size > ps.limits.QuotaBytes {
+		http.Error(w, "storage quota exceeded", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(inputUploadTimeout))
+
+	// The object key is the digest, so the body is spooled and hashed before
+	// anything is written to the store.
+	spool, err := os.CreateTemp("", "soholink-input-*")
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(spool.Name()) //nolint:errcheck
+	defer spool.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(spool, h), http.MaxBytesReader(w, r.Body, size))
+	if err != nil || n != size {
+		http.Error(w, "incomplete upload", http.StatusBadRequest)
+		return
+	}
+	u := store.InputUpload{
+		ParticipantID: claims.UserID,
+		SHA256:        hex.EncodeToString(h.Sum(nil)),
+		SizeBytes:     size,
+		ExpiresAt:     time.Now().Add(ps.limits.TTL).UTC(),
+	}
+	u.ObjectKey = artifact.InputKey(u.ParticipantID, u.SHA256)
+
+	_, err = store.LiveInputUpload(r.Context(), ps.db, u.ParticipantID, u.SHA256)
+	stored := err == nil
+	if err != nil && !errors.Is(err, store.ErrInputNotFound) {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if !stored {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if err := ps.artifacts.Put(r.Context(), u.ObjectKey, spool, size); err != nil {
+			slog.Error("input upload failed", "participant_id", u.ParticipantID, "error", err)
+			http.Error(w, "artifact store error", http.StatusBadGateway)
+			return
+		}
+	}
+	if err := store.RecordInputUpload(r.Context(), ps.db, u, ps.limits.QuotaBytes); err != nil {
+		if !stored {
+			if derr := ps.artifacts.Delete(r.Context(), u.ObjectKey); derr != nil {
+				slog.Warn("discard rejected input failed", "participant_id", u.ParticipantID, "error", derr)
+			}
+		}
+		if errors.Is(err, store.ErrArtifactQuotaExceeded) {
+			http.Error(w, "storage quota exceeded", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(inputUploadResponse{ //nolint:errcheck
+		SHA256:    u.SHA256,
+		SizeBytes: u.SizeBytes,
+		ExpiresAt: u.ExpiresAt,
+	})
+}
+
+// handleProviderJobTelemetry returns the same series to the contributor whose
+// node ran the job. Ownership is checked through the job's node, mirroring
+// handleJobConfirm.
+func (ps *PortalServer) handleProviderJobTelemetry(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID := r.PathValue("id")
+
+	var exists bool
+	err := ps.db.Pool.QueryRow(r.Context(),
+		`SELECT EXISTS (
+		     SELECT 1 FROM jobs j
+		     JOIN nodes n ON n.id = j.node_id
+		     WHERE j.id = $1 AND n.participant_id = $2
+		 )`,
+		jobID, claims.UserID,
+	).Scan(&exists)
+	if err != nil || !exists {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	ps.writeJobTelemetry(w, r, jobID)
+}
+
+// writeJobTelemetry encodes the job's telemetry series after the caller has
+// established ownership.
+func (ps *PortalServer) writeJobTelemetry(w http.ResponseWriter, r *http.Request, jobID string) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "since must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+
+	samples, err := store.JobTelemetry(r.Context(), ps.db, jobID, since)
+	if err != nil {
+		slog.Error("job telemetry read failed", "job_id", jobID, "error", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := jobTelemetryResponse{JobID: jobID, Samples: make([]telemetrySampleDTO, 0, len(samples))}
+	for _, s := range samples {
+		resp.Samples = append(resp.Samples, telemetrySampleDTO{
+			SampledAt:     s.SampledAt,
+			CPUPct:        s.CPUPct,
+			RAMPct:        s.RAMPct,
+			BandwidthMbps: s.BandwidthMbps,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
 func (ps *PortalServer) handleDisputeResolve(w http.ResponseWriter, r *http.Request) {
 	claims, _ := ClaimsFromContext(r.Context())
 
@@ -1401,18 +2135,26 @@ func (ps *PortalServer) handleDisputeResolve(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if consumerRefundPct > 0 {
-		var amountCents int64
-		err = ps.db.Pool.QueryRow(r.Context(),
-			`SELECT amount_cents FROM jobs WHERE id = $1`,
-			jobID,
-		).Scan(&amountCents)
+	// The refund is a share of what the consumer actually paid for the job:
+	// for an escrowed job, the captured charge, which must have settled
+	// first. A job that carried no charge has nothing to refund.
+	var refundAmount int64
+	if consumerRefundPct > 0 && paymentIntentID != "" {
+		paidCents, settled, err := store.DisputeRefundBasis(r.Context(), ps.db, jobID)
 		if err != nil {
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
 		}
-		refundAmount := amountCents * int64(consumerRefundPct) / 100
+		if !settled {
+			http.Error(w, "the job's payment has not settled yet; try again shortly", http.StatusConflict)
+			return
+		}
+		refundAmount = paidCents * int64(consumerRefundPct) / 100
 		if refundAmount > 0 {
+			if ps.payment == nil {
+				http.Error(w, "payments are not enabled", http.StatusServiceUnavailable)
+				return
+			}
 			if err := ps.payment.CreateRefund(r.Context(), paymentIntentID, refundAmount); err != nil {
 				http.Error(w, "failed to issue refund", http.StatusInternalServerError)
 				return
@@ -1420,14 +2162,8 @@ func (ps *PortalServer) handleDisputeResolve(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
-	_, err = ps.db.Pool.Exec(r.Context(),
-		`UPDATE disputes
-		 SET status = 'resolved', consumer_refund_pct = $1, arbiter_participant_id = $2,
-		     arbiter_notes = 'resolved via terminal', resolved_at = NOW(), updated_at = NOW()
-		 WHERE id = $3`,
-		consumerRefundPct, claims.UserID, disputeID,
-	)
-	if err != nil {
+	if err := store.ResolveDispute(r.Context(), ps.db, disputeID, consumerRefundPct, claims.UserID, refundAmount); err != nil {
+		slog.Error("handleDisputeResolve: record resolution", "dispute_id", disputeID, "refund_cents", refundAmount, "error", err)
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
@@ -1774,6 +2510,87 @@ func (ps *PortalServer) handleAddProfile(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/provider/provision", http.StatusSeeOther)
 }
 
+// handleProviderPayouts shows a node owner what they are owed now, their
+// payout schedule, and their recent payout batches with the jobs each paid.
+func (ps *PortalServer) handleProviderPayouts(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+
+	schedule, err := store.GetPayoutSchedule(r.Context(), ps.db, claims.UserID)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	owed, err := store.ContributorPayable(r.Context(), ps.db, claims.UserID)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	batches, err := store.PayoutHistory(r.Context(), ps.db, claims.UserID, 50)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	rows := make([]PayoutBatchRow, len(batches))
+	for i, b := range batches {
+		rows[i] = PayoutBatchRow{
+			ID:          b.ID,
+			Status:      b.Status,
+			Dollars:     float64(b.AmountCents) / 100.0,
+			PayoutID:    b.PayoutID,
+			FailureCode: b.FailureCode,
+			CreatedAt:   b.CreatedAt,
+			ReleasedAt:  b.ReleasedAt,
+		}
+		for _, l := range b.Lines {
+			rows[i].Lines = append(rows[i].Lines, PayoutLineRow{
+				JobID:   l.JobID,
+				Dollars: float64(l.AmountCents) / 100.0,
+			})
+		}
+	}
+
+	ps.renderTemplate(w, "provider_payouts.html", PayoutsData{
+		Email:            claims.Email,
+		Schedule:         schedule.Schedule,
+		ThresholdDollars: float64(schedule.ThresholdCents) / 100.0,
+		OwedDollars:      float64(owed) / 100.0,
+		Batches:          rows,
+		IsAuthenticated:  true,
+	})
+}
+
+// handleProviderPayoutSchedule sets the node owner's payout schedule. The
+// threshold is entered in dollars and only read for the threshold schedule.
+func (ps *PortalServer) handleProviderPayoutSchedule(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	schedule := store.PayoutSchedule{Schedule: r.FormValue("schedule")}
+	if schedule.Schedule == store.PayoutThreshold {
+		dollars, err := strconv.ParseFloat(r.FormValue("threshold_dollars"), 64)
+		if err != nil || dollars < 1 {
+			http.Error(w, "threshold must be at least $1.00", http.StatusBadRequest)
+			return
+		}
+		schedule.ThresholdCents = int64(math.Round(dollars * 100))
+	}
+
+	err := store.SetPayoutSchedule(r.Context(), ps.db, claims.UserID, schedule)
+	if errors.Is(err, store.ErrInvalidPayoutSchedule) {
+		http.Error(w, "invalid payout schedule", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/provider/payouts", http.StatusSeeOther)
+}
+
 // handleGenerateNodeToken issues a single-use registration token tied to the
 // authenticated participant. The token is displayed once on the dashboard so
 // the installer wizard can copy it during first-run node claim.
@@ -1856,7 +2673,6 @@ func (ps *PortalServer) handleGenerateNodeToken(w http.ResponseWriter, r *http.R
 	ps.renderTemplate(w, "dashboard.html", data)
 }
 
-
 // formatOptOutSyncStatus returns the per-node sync-status string for the UI.
 // Uses last_heartbeat_at vs opt_out_updated_at as a heuristic: if the node
 // has heartbeated since the version was bumped we treat that as confirmation.
@@ -1900,7 +2716,8 @@ func (ps *PortalServer) handleOptOutPage(w http.ResponseWriter, r *http.Request)
 		           ORDER BY np.printer_id
 		         ) FILTER (WHERE np.printer_id IS NOT NULL),
 		         '[]'::jsonb
-		       ) AS printers
+		       ) AS printers,
+		       n.labels, n.taints, n.agent_labels, n.agent_taints
 		FROM nodes n
 		LEFT JOIN node_printers np ON np.node_id = n.id
 		WHERE n.participant_id = $1
@@ -1921,12 +2738,16 @@ func (ps *PortalServer) handleOptOutPage(w http.ResponseWriter, r *http.Request)
 			optOutUpdatedAt time.Time
 			lastHeartbeat   *time.Time
 			printersJSON    []byte
+			labels, taints  map[string]string
+			agentLabels     map[string]string
+			agentTaints     map[string]string
 		)
 		if err := rows.Scan(
 			&row.ID, &row.Hostname,
 			&row.OptOutCompute, &row.OptOutStorage, &row.OptOutPrinting,
 			&row.Version, &optOutUpdatedAt, &lastHeartbeat,
 			&printersJSON,
+			&labels, &taints, &agentLabels, &agentTaints,
 		); err != nil {
 			http.Error(w, "internal error", http.StatusInternalServerError)
 			return
@@ -1946,6 +2767,10 @@ func (ps *PortalServer) handleOptOutPage(w http.ResponseWriter, r *http.Request)
 		}
 
 		row.SyncStatus = formatOptOutSyncStatus(row.Version, optOutUpdatedAt, lastHeartbeat, now)
+		row.Labels = types.FormatNodeLabels(labels)
+		row.Taints = types.FormatNodeLabels(taints)
+		row.AgentLabels = types.FormatNodeLabels(agentLabels)
+		row.AgentTaints = types.FormatNodeLabels(agentTaints)
 		nodeRows = append(nodeRows, row)
 	}
 	if err := rows.Err(); err != nil {
@@ -2104,6 +2929,130 @@ func (ps *PortalServer) handlePostOptOut(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// handlePostNodeLabels replaces the owner-set labels and taints of a single
+// owned node. Returns 400 with the reason when either list does not parse,
+// and 404 when the node does not exist or is not the caller's, as
+// handlePostOptOut does. The orchestrator picks the change up on the node's
+// next heartbeat.
+func (ps *PortalServer) handlePostNodeLabels(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+
+	var body nodeLabelsPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	body.NodeID = strings.TrimSpace(body.NodeID)
+	if body.NodeID == "" {
+		http.Error(w, "node_id required", http.StatusBadRequest)
+		return
+	}
+	labels, err := types.ParseNodeLabels(body.Labels)
+	if err != nil {
+		http.Error(w, "labels: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	taints, err := types.ParseNodeLabels(body.Taints)
+	if err != nil {
+		http.Error(w, "taints: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = store.SetNodeLabels(r.Context(), ps.db, body.NodeID, claims.UserID,
+		store.NodeLabels{Labels: labels, Taints: taints})
+	if errors.Is(err, store.ErrNodeNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		slog.Error("handlePostNodeLabels: set labels", "node_id", body.NodeID, "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"labels":  types.FormatNodeLabels(labels),
+		"taints":  types.FormatNodeLabels(taints),
+	})
+}
+
+// handleConsumerCancelJob cancels a job the caller submitted that has not
+// finished: pending, scheduled, dispatched, awaiting_confirmation, declined
+// or running. The orchestrator releases the job's node and meters any
+// partial run; a running container is stopped by its agent on the next
+// heartbeat. When the job carried a charge, the unused share is refunded
+// here. A refund that fails stays recorded as owed (refund_status
+// "pending"): store.RunCancelRefunder retries it, and staff see it on the
+// governance reconciliation page until it is made. The job is cancelled
+// either way. An
+// escrowed job was never charged: the escrow settler captures what ran and
+// releases the rest of the hold (refund_status "escrow").
+func (ps *PortalServer) handleConsumerCancelJob(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromContext(r.Context())
+	jobID := r.PathValue("id")
+
+	resp, err := ps.orch.CancelJob(r.Context(), jobID, claims.UserID)
+	switch {
+	case errors.Is(err, store.ErrJobNotFound):
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrJobNotCancellable):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"error":          "wrong_status",
+			"current_status": resp.PriorStatus,
+		})
+		return
+	case err != nil:
+		slog.Error("handleConsumerCancelJob: cancel", "job_id", jobID, "error", err)
+		http.Error(w, "failed to cancel job", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":        "cancelled",
+		"prior_status":  resp.PriorStatus,
+		"charged_cents": resp.ChargedCents,
+		"refund_cents":  resp.RefundCents,
+		"refund_status": ps.refundCancelled(r.Context(), jobID, resp),
+	})
+}
+
+// refundCancelled refunds what cancelled job jobID was charged beyond what it
+// ran, and returns the refund's status: "none", "escrow" (the escrow settler
+// releases it with the hold), "refunded", or "pending" when the refund could
+// not be made and is owed.
+func (ps *PortalServer) refundCancelled(ctx context.Context, jobID string, resp orchestrator.CancelJobResponse) string {
+	switch {
+	case resp.Escrowed:
+		// Nothing was charged yet: the escrow settler captures what ran and
+		// releases the rest of the hold.
+		if resp.RefundCents > 0 {
+			return "escrow"
+		}
+	case resp.PaymentIntentID != "" && resp.RefundCents > 0:
+		if ps.payment == nil {
+			slog.Error("refund cancelled job: no payment client; refund owed", "job_id", jobID,
+				"refund_cents", resp.RefundCents)
+			return "pending"
+		}
+		if err := ps.payment.CreateRefund(ctx, resp.PaymentIntentID, resp.RefundCents); err != nil {
+			slog.Error("refund cancelled job failed", "job_id", jobID,
+				"refund_cents", resp.RefundCents, "error", err)
+			return "pending"
+		}
+		if err := store.MarkRefunded(ctx, ps.db, jobID); err != nil {
+			slog.Error("record refund failed", "job_id", jobID, "error", err)
+		}
+		return "refunded"
+	}
+	return "none"
+}
+
 // handleConsumerPickedUp transitions a print job from awaiting_pickup → picked_up.
 // The consumer must own the job (jobs.participant_id == session UserID).
 // Sets picked_up_at = NOW(). C5 print lifecycle.
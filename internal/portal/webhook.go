@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 
 	stripe "github.com/stripe/stripe-go/v82"
 
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
 )
 
 // handleStripeWebhook verifies the Stripe-Signature header and dispatches
@@ -22,12 +24,173 @@ func (ps *PortalServer) handleStripeWebhook(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+// onStripeEvent dispatches a verified event and, once handled, logs it to
+// stripe_events. Stripe redelivers events and sends them out of order with
+// our own calls, so each handler is idempotent; a difference one cannot
+// resolve is left to the reconciler, which reports it.
 func (ps *PortalServer) onStripeEvent(event stripe.Event) error {
+	var err error
 	switch event.Type {
 	case "account.updated":
-		return ps.onAccountUpdated(event)
+		err = ps.onAccountUpdated(event)
+	case "payment_intent.amount_capturable_updated":
+		err = ps.onPaymentIntentCapturable(event)
+	case "payout.paid":
+		err = ps.onPayoutPaid(event)
+	case "payout.failed":
+		err = ps.onPayoutFailed(event)
+	case "charge.dispute.created", "charge.dispute.updated", "charge.dispute.closed":
+		err = ps.onChargeDispute(event)
+	case "payment_intent.succeeded":
+		err = ps.onPaymentIntentSucceeded(event)
+	case "payment_intent.payment_failed":
+		err = ps.onPaymentIntentFailed(event)
+	case "payment_intent.canceled":
+		err = ps.onPaymentIntentCanceled(event)
+	case "charge.refunded":
+		err = ps.onChargeRefunded(event)
+	default:
+		return nil
 	}
-	return nil
+	if err != nil {
+		return err
+	}
+
+	var obj struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(event.Data.Raw, &obj); err != nil {
+		return fmt.Errorf("unmarshal event object: %w", err)
+	}
+	return store.RecordStripeEvent(context.Background(), ps.db, event.ID, string(event.Type), event.Account, obj.ID)
+}
+
+// onPayoutPaid marks the payout batch paid by a payout that reached the
+// owner's bank. Stripe sends payout events from the connected account the
+// payout was made on; payouts that are not ours match no batch.
+func (ps *PortalServer) onPayoutPaid(event stripe.Event) error {
+	var po stripe.Payout
+	if err := json.Unmarshal(event.Data.Raw, &po); err != nil {
+		return fmt.Errorf("unmarshal payout: %w", err)
+	}
+	_, err := store.MarkPayoutBatchPaid(context.Background(), ps.db, po.ID)
+	return err
+}
+
+// onPayoutFailed fails the payout batch whose payout the owner's bank
+// rejected, so its runs are paid again in the owner's next batch.
+func (ps *PortalServer) onPayoutFailed(event stripe.Event) error {
+	var po stripe.Payout
+	if err := json.Unmarshal(event.Data.Raw, &po); err != nil {
+		return fmt.Errorf("unmarshal payout: %w", err)
+	}
+	failed, err := store.FailPayoutBatch(context.Background(), ps.db, po.ID, string(po.FailureCode))
+	if failed {
+		slog.Warn("stripe webhook: payout failed",
+			"payout_id", po.ID,
+			"account", event.Account,
+			"failure_code", po.FailureCode,
+		)
+	}
+	return err
+}
+
+// onChargeDispute records a chargeback against a job's charge, holding the
+// job's earnings from payout until it is won or closed.
+func (ps *PortalServer) onChargeDispute(event stripe.Event) error {
+	var d stripe.Dispute
+	if err := json.Unmarshal(event.Data.Raw, &d); err != nil {
+		return fmt.Errorf("unmarshal dispute: %w", err)
+	}
+	cb := store.Chargeback{
+		ID:          d.ID,
+		AmountCents: d.Amount,
+		Reason:      string(d.Reason),
+		Status:      string(d.Status),
+	}
+	if d.Charge != nil {
+		cb.ChargeID = d.Charge.ID
+	}
+	if d.PaymentIntent != nil {
+		cb.PaymentIntentID = d.PaymentIntent.ID
+	}
+	return store.RecordChargeback(context.Background(), ps.db, cb)
+}
+
+// onPaymentIntentCapturable releases an escrowed job for dispatch once its
+// consumer has authorized the hold. Stripe sends this event when a
+// manual-capture intent reaches requires_capture. Intents that are not job
+// escrows match no job and are ignored.
+func (ps *PortalServer) onPaymentIntentCapturable(event stripe.Event) error {
+	var pi stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+		return fmt.Errorf("unmarshal payment intent: %w", err)
+	}
+	if pi.Status != stripe.PaymentIntentStatusRequiresCapture {
+		return nil
+	}
+	_, err := store.AuthorizeJobPayment(context.Background(), ps.db, pi.ID)
+	return err
+}
+
+// onPaymentIntentSucceeded records the capture of a job's escrow hold. The
+// escrow settler records its own captures; this covers one whose bookkeeping
+// write failed. Intents that are not escrows — destination charges, already
+// recorded when made — match no job and are ignored.
+func (ps *PortalServer) onPaymentIntentSucceeded(event stripe.Event) error {
+	var pi stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+		return fmt.Errorf("unmarshal payment intent: %w", err)
+	}
+	var chargeID string
+	if pi.LatestCharge != nil {
+		chargeID = pi.LatestCharge.ID
+	}
+	_, err := store.CaptureEscrowByIntent(context.Background(), ps.db, pi.ID, pi.AmountReceived, chargeID)
+	return err
+}
+
+// onPaymentIntentFailed keeps the reason a consumer's attempt to authorize a
+// job's hold failed, for the job's payment page. The hold stays awaiting: the
+// consumer may try again.
+func (ps *PortalServer) onPaymentIntentFailed(event stripe.Event) error {
+	var pi stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+		return fmt.Errorf("unmarshal payment intent: %w", err)
+	}
+	msg := "the payment was declined"
+	if pi.LastPaymentError != nil && pi.LastPaymentError.Msg != "" {
+		msg = pi.LastPaymentError.Msg
+	}
+	_, err := store.RecordJobPaymentFailure(context.Background(), ps.db, pi.ID, msg)
+	return err
+}
+
+// onPaymentIntentCanceled records that a job's escrow hold was cancelled —
+// by the escrow settler, from the Stripe dashboard, or by Stripe when an
+// authorization lapses — so the settler does not try to cancel it again.
+func (ps *PortalServer) onPaymentIntentCanceled(event stripe.Event) error {
+	var pi stripe.PaymentIntent
+	if err := json.Unmarshal(event.Data.Raw, &pi); err != nil {
+		return fmt.Errorf("unmarshal payment intent: %w", err)
+	}
+	_, err := store.ReleaseEscrowByIntent(context.Background(), ps.db, pi.ID)
+	return err
+}
+
+// onChargeRefunded records a cancelled job's refund once Stripe reports it
+// made, so the cancel refunder does not retry it. Dispute refunds are
+// recorded with their resolution and match nothing here.
+func (ps *PortalServer) onChargeRefunded(event stripe.Event) error {
+	var ch stripe.Charge
+	if err := json.Unmarshal(event.Data.Raw, &ch); err != nil {
+		return fmt.Errorf("unmarshal charge: %w", err)
+	}
+	if ch.PaymentIntent == nil {
+		return nil
+	}
+	_, err := store.MarkChargeRefunded(context.Background(), ps.db, ch.PaymentIntent.ID, ch.AmountRefunded)
+	return err
 }
 
 // onAccountUpdated syncs a connected account's onboarding status to the
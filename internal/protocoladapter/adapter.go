@@ -246,6 +246,19 @@ func (a *Adapter) SubmitListing(ctx context.Context, l listing.CapabilityListing
 		// Eviction race after Register — advisory state; warn and continue.
 		slog.Warn("protocoladapter: registry opt-out refresh failed", "node_id", nodeID, "err", err)
 	}
+
+	// A protocol listing carries no labels or taints; the node is matched
+	// on the ones stored for it, which its owner sets in the portal.
+	labels, err := store.GetNodeLabels(ctx, a.db, nodeID)
+	if err != nil {
+		return fmt.Errorf("listing from %s: %w", nodeID, err)
+	}
+	if err := a.registry.UpdateLabels(nodeID, orchestrator.NodeLabelState{
+		Labels: labels.Labels,
+		Taints: labels.Taints,
+	}); err != nil {
+		slog.Warn("protocoladapter: registry labels refresh failed", "node_id", nodeID, "err", err)
+	}
 	return nil
 }
 
@@ -353,23 +366,13 @@ func (a *Adapter) Decline(ctx context.Context, d employment.Decline) error {
 		return fmt.Errorf("decline from %s: %w", nodeID, ErrBadSignature)
 	}
 
-	// Guarded status flip FIRST: only a job actually placed on THIS node
-	// (scheduled/dispatched to it) can be declined. Doing the flip before the
-	// decline-row INSERT means an authenticated node cannot spam
-	// job_node_declines rows for arbitrary jobs it was never offered.
-	tag, err := a.db.Pool.Exec(ctx,
-		`UPDATE jobs
-		 SET status      = 'declined'::job_status,
-		     declined_at = NOW(),
-		     updated_at  = NOW()
-		 WHERE id = $1 AND node_id = $2
-		   AND status IN ('scheduled'::job_status, 'dispatched'::job_status)`,
-		d.JobID, nodeID,
-	)
+	// Guarded flip + decline row via the shared store path (the bespoke
+	// POST /jobs/{id}/decline uses the same helper).
+	declined, err := store.DeclineJob(ctx, a.db, d.JobID, nodeID)
 	if err != nil {
-		return fmt.Errorf("decline from %s: update job %s: %w", nodeID, d.JobID, err)
+		return fmt.Errorf("decline from %s: %w", nodeID, err)
 	}
-	if tag.RowsAffected() == 0 {
+	if !declined {
 		// Lost race or the job is not currently placeable on this node — nothing
 		// to flip, and (deliberately) no decline row to record for a job this
 		// node was never offered.
@@ -377,17 +380,7 @@ func (a *Adapter) Decline(ctx context.Context, d employment.Decline) error {
 			"job_id", d.JobID, "node_id", nodeID, "reason", string(d.Reason))
 		return nil
 	}
-
-	// Record the decline so StartDeclineRerouteLoop excludes this node when it
-	// re-places the job.
-	if _, err := a.db.Pool.Exec(ctx,
-		`INSERT INTO job_node_declines (job_id, node_id) VALUES ($1, $2)
-		 ON CONFLICT (job_id, node_id) DO NOTHING`,
-		d.JobID, nodeID,
-	); err != nil {
-		return fmt.Errorf("decline from %s: record decline for job %s: %w", nodeID, d.JobID, err)
-	}
-	a.registry.AddInFlight(nodeID, -1)
+	a.registry.Release(nodeID, d.JobID)
 	return nil
 }
 
@@ -453,7 +446,8 @@ func (a *Adapter) ReportJob(ctx context.Context, r employment.JobReport) error {
 		return fmt.Errorf("report from %s: complete job %s: %w", nodeID, r.JobID, err)
 	}
 	// All statuses CompleteJob produces are terminal for placement.
-	a.registry.AddInFlight(nodeID, -1)
+	a.registry.Release(nodeID, r.JobID)
+	orchestrator.SettleReplicaGroup(ctx, a.db, a.registry, r.JobID)
 	slog.Info("protocoladapter: job report applied",
 		"job_id", r.JobID, "node_id", nodeID, "status", newStatus)
 	return nil
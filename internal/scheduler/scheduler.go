@@ -3,6 +3,7 @@ package scheduler
 import (
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"time"
 
@@ -67,8 +68,68 @@ const (
 	// loadSampleTTL bounds how long a heartbeat load sample counts as fresh:
 	// 3× the 60s heartbeat interval. Older (or absent) samples score 0.0.
 	loadSampleTTL = 3 * 60 * time.Second
+
+	// wPacking weights the reservation-utilization term. Same rank as wIdle:
+	// it orders otherwise-comparable nodes but never overturns locality or
+	// certified class. FindMatch has already guaranteed the job fits.
+	wPacking = 2.0
+
+	// wPreference weights the consumer's soft placement constraints
+	// (preferenceScore). 20.0 exceeds the combined range of every other term
+	// (class 4 + freshness 1 + capacity 1 + locality 6 + idle 2 + packing 2
+	// = 16): what the consumer asked for decides among the nodes that can
+	// take the job, and the platform's terms only order the rest.
+	wPreference = 20.0
 )
 
+// Strategy selects how Schedule weighs a node's reserved utilization.
+type Strategy string
+
+const (
+	// StrategySpread prefers the least-reserved nodes and keeps the
+	// per-in-flight penalty: work fans out across the fleet, leaving
+	// headroom everywhere. The default.
+	StrategySpread Strategy = "spread"
+
+	// StrategyBinpack prefers the most-reserved nodes that still fit,
+	// filling machines before touching idle ones so whole nodes stay free
+	// for large jobs (and idle contributors stay idle). The per-in-flight
+	// penalty is dropped — it would pull the other way.
+	StrategyBinpack Strategy = "binpack"
+)
+
+// ParseStrategy maps a configuration string to a Strategy. The empty string
+// selects StrategySpread.
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "", StrategySpread:
+		return StrategySpread, nil
+	case StrategyBinpack:
+		return StrategyBinpack, nil
+	}
+	return "", fmt.Errorf("scheduler: unknown strategy %q (want %q or %q)", s, StrategySpread, StrategyBinpack)
+}
+
+// utilization returns the node's reserved share of CPU and RAM, averaged over
+// whichever of the two the node reports, clamped to 0–1. A node reporting
+// neither scores 0.
+func utilization(node orchestrator.NodeEntry) float64 {
+	var sum float64
+	var dims int
+	if hw := node.HardwareProfile.CPUCores; hw > 0 {
+		sum += float64(node.Reserved.CPUCores) / float64(hw)
+		dims++
+	}
+	if hw := node.HardwareProfile.RAMMB; hw > 0 {
+		sum += float64(node.Reserved.RAMMB) / float64(hw)
+		dims++
+	}
+	if dims == 0 {
+		return 0
+	}
+	return math.Min(1, math.Max(0, sum/float64(dims)))
+}
+
 // localityScore returns the soft locality tier of a node relative to the
 // requester: 0.6 same region, 0.3 same country, 0 otherwise. Empty values on
 // either side never match — an unknown requester location contributes 0
@@ -85,6 +146,28 @@ func localityScore(node orchestrator.NodeEntry, pctx orchestrator.PlacementConte
 	}
 }
 
+// preferenceScore returns the consumer's soft placement constraints for a
+// node: +1 each for a preferred node and a preferred owner, −1 each for an
+// owner and a region already running the consumer's other jobs
+// (anti-affinity). Empty Region never matches. Hard constraints are
+// FindMatch's; every candidate here may take the job.
+func preferenceScore(node orchestrator.NodeEntry, pctx orchestrator.PlacementContext) float64 {
+	var score float64
+	if slices.Contains(pctx.PreferredNodeIDs, node.NodeID) {
+		score++
+	}
+	if slices.Contains(pctx.PreferredParticipantIDs, node.ParticipantID) {
+		score++
+	}
+	if slices.Contains(pctx.AvoidParticipantIDs, node.ParticipantID) {
+		score--
+	}
+	if node.Region != "" && slices.Contains(pctx.AvoidRegions, node.Region) {
+		score--
+	}
+	return score
+}
+
 // idleScore returns 0–1 from the node's self-reported load sample:
 //
 //   - OwnerActive → 0.0 (the member is using their machine; leave it alone)
@@ -116,14 +199,27 @@ func idleScore(node orchestrator.NodeEntry) float64 {
 // given SLA tier. Returns an error if fewer candidates are available than
 // the tier requires.
 //
+// Schedule uses StrategySpread; ScheduleWith selects a strategy and documents
+// the scoring formula.
+func Schedule(candidates []orchestrator.NodeEntry, tier orchestrator.SLATier, pctx orchestrator.PlacementContext) ([]orchestrator.NodeEntry, error) {
+	return schedule(candidates, tier, pctx, StrategySpread)
+}
+
+// ScheduleWith returns an orchestrator.ScheduleFunc ranking with strategy.
+//
 // Scoring formula: classScore + freshnessScore + capacityScore
-// + wLocality×localityScore + wIdle×idleScore − perInFlightPenalty×InFlight
+// + wLocality×localityScore + wIdle×idleScore + wPacking×packingScore
+// + wPreference×preferenceScore − perInFlightPenalty×InFlight (spread only)
 //
 //   - classScore:     node class ordinal (A=4, B=3, C=2, D=1) — platform reliability cert
 //   - freshnessScore: heartbeat recency, linear decay 1.0→0.0 over 30 minutes
 //   - capacityScore:  CPU cores normalized 0–1 against the candidate pool — breaks ties
 //   - localityScore:  soft tiers — same region 0.6, same country 0.3, else 0
 //   - idleScore:      self-reported idleness 0–1; absent/stale sample scores 0
+//   - packingScore:   reserved CPU/RAM utilization u (0–1) — spread scores
+//     1−u, binpack scores u
+//   - preferenceScore: consumer soft constraints — +1 preferred node, +1
+//     preferred owner, −1 owner and −1 region of the consumer's other jobs
 //   - InFlight:       advisory count of current placements on the node
 //
 // Ties are NOT broken deterministically by NodeID: Go's random map iteration
@@ -132,7 +228,13 @@ func idleScore(node orchestrator.NodeEntry) float64 {
 // Extension points: when a Reputation Engine is available, add a reputation
 // component here. When Marketplace Engine pricing is live, replace or weight
 // the capacityScore with a price-efficiency term.
-func Schedule(candidates []orchestrator.NodeEntry, tier orchestrator.SLATier, pctx orchestrator.PlacementContext) ([]orchestrator.NodeEntry, error) {
+func ScheduleWith(strategy Strategy) orchestrator.ScheduleFunc {
+	return func(candidates []orchestrator.NodeEntry, tier orchestrator.SLATier, pctx orchestrator.PlacementContext) ([]orchestrator.NodeEntry, error) {
+		return schedule(candidates, tier, pctx, strategy)
+	}
+}
+
+func schedule(candidates []orchestrator.NodeEntry, tier orchestrator.SLATier, pctx orchestrator.PlacementContext, strategy Strategy) ([]orchestrator.NodeEntry, error) {
 	if len(candidates) < int(tier) {
 		return nil, fmt.Errorf("schedule: tier requires %d node(s), only %d available", int(tier), len(candidates))
 	}
@@ -152,8 +254,14 @@ func Schedule(candidates []orchestrator.NodeEntry, tier orchestrator.SLATier, pc
 			freshnessScore(node.LastHeartbeat) +
 			capacityScore +
 			wLocality*localityScore(node, pctx) +
-			wIdle*idleScore(node) -
-			perInFlightPenalty*float64(node.InFlight)
+			wIdle*idleScore(node) +
+			wPreference*preferenceScore(node, pctx)
+		if strategy == StrategyBinpack {
+			score += wPacking * utilization(node)
+		} else {
+			score += wPacking*(1-utilization(node)) -
+				perInFlightPenalty*float64(node.InFlight)
+		}
 		scored[i] = CandidateScore{Node: node, Score: score}
 	}
 
@@ -161,9 +269,40 @@ func Schedule(candidates []orchestrator.NodeEntry, tier orchestrator.SLATier, pc
 		return scored[i].Score > scored[j].Score
 	})
 
-	result := make([]orchestrator.NodeEntry, int(tier))
-	for i := range result {
-		result[i] = scored[i].Node
+	return pickReplicas(scored, int(tier))
+}
+
+// pickReplicas takes the n best-scored nodes for an n-replica placement. No
+// two picks share an owner (hard: a replica on the same contributor's second
+// machine adds no independence), and where the pool allows no two share a
+// region — a first pass skips repeated regions, a second relaxes that. An
+// empty ParticipantID or Region never counts as a repeat. For n == 1 this is
+// simply the top-scored node.
+func pickReplicas(scored []CandidateScore, n int) ([]orchestrator.NodeEntry, error) {
+	result := make([]orchestrator.NodeEntry, 0, n)
+	picked := make([]bool, len(scored))
+	owners := make(map[string]bool, n)
+	regions := make(map[string]bool, n)
+	for pass := 0; pass < 2 && len(result) < n; pass++ {
+		for i, c := range scored {
+			if len(result) == n {
+				break
+			}
+			owner, region := c.Node.ParticipantID, c.Node.Region
+			if picked[i] || (owner != "" && owners[owner]) {
+				continue
+			}
+			if pass == 0 && region != "" && regions[region] {
+				continue
+			}
+			picked[i] = true
+			owners[owner] = true
+			regions[region] = true
+			result = append(result, c.Node)
+		}
+	}
+	if len(result) < n {
+		return nil, fmt.Errorf("schedule: tier requires %d node(s) with distinct owners, only %d available", n, len(result))
 	}
 	return result, nil
 }
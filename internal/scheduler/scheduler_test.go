@@ -349,3 +349,154 @@ func TestSchedule_EmptyContextReproducesLegacyOrdering(t *testing.T) {
 		}
 	}
 }
+
+// ── Reservation-aware packing strategies ─────────────────────────────────────
+
+func reservedPair() (busy, idle orchestrator.NodeEntry) {
+	busy = makeNode("A", 8)
+	busy.NodeID = "busy"
+	busy.HardwareProfile.RAMMB = 16384
+	busy.Reserved = orchestrator.Resources{CPUCores: 6, RAMMB: 12288}
+
+	idle = makeNode("A", 8)
+	idle.NodeID = "idle"
+	idle.HardwareProfile.RAMMB = 16384
+	return busy, idle
+}
+
+func TestSchedule_SpreadPrefersLeastReserved(t *testing.T) {
+	busy, idle := reservedPair()
+	result, err := ScheduleWith(StrategySpread)([]orchestrator.NodeEntry{busy, idle}, orchestrator.SLAStandard, orchestrator.PlacementContext{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0].NodeID != "idle" {
+		t.Errorf("spread should rank the unreserved node first, got %q", result[0].NodeID)
+	}
+}
+
+func TestSchedule_BinpackPrefersMostReserved(t *testing.T) {
+	busy, idle := reservedPair()
+	busy.InFlight = 2
+	result, err := ScheduleWith(StrategyBinpack)([]orchestrator.NodeEntry{idle, busy}, orchestrator.SLAStandard, orchestrator.PlacementContext{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0].NodeID != "busy" {
+		t.Errorf("binpack should rank the partly reserved node first, got %q", result[0].NodeID)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	for in, want := range map[string]Strategy{"": StrategySpread, "spread": StrategySpread, "binpack": StrategyBinpack} {
+		got, err := ParseStrategy(in)
+		if err != nil || got != want {
+			t.Errorf("ParseStrategy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseStrategy("roundrobin"); err == nil {
+		t.Error("ParseStrategy(roundrobin): expected error")
+	}
+}
+
+// ── SLA tiers: replica diversity ─────────────────────────────────────────────
+
+func TestSchedule_ReplicasUseDistinctOwners(t *testing.T) {
+	a1 := makeNode("A", 8)
+	a1.NodeID, a1.ParticipantID = "a1", "owner-a"
+	a2 := makeNode("A", 8)
+	a2.NodeID, a2.ParticipantID = "a2", "owner-a"
+	b := makeNode("C", 2)
+	b.NodeID, b.ParticipantID = "b", "owner-b"
+
+	result, err := Schedule([]orchestrator.NodeEntry{a1, a2, b}, orchestrator.SLAReliable, orchestrator.PlacementContext{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0].ParticipantID == result[1].ParticipantID {
+		t.Errorf("replicas share owner %q", result[0].ParticipantID)
+	}
+
+	if _, err := Schedule([]orchestrator.NodeEntry{a1, a2}, orchestrator.SLAReliable, orchestrator.PlacementContext{}); err == nil {
+		t.Error("expected error when candidates cover only one owner")
+	}
+}
+
+func TestSchedule_ReplicasPreferDistinctRegions(t *testing.T) {
+	east1 := makeNode("A", 8)
+	east1.NodeID, east1.ParticipantID, east1.Region = "east-1", "o1", "us-east"
+	east2 := makeNode("A", 8)
+	east2.NodeID, east2.ParticipantID, east2.Region = "east-2", "o2", "us-east"
+	west := makeNode("C", 2)
+	west.NodeID, west.ParticipantID, west.Region = "west", "o3", "us-west"
+
+	result, err := Schedule([]orchestrator.NodeEntry{east1, east2, west}, orchestrator.SLAReliable, orchestrator.PlacementContext{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0].Region == result[1].Region {
+		t.Errorf("replicas share region %q although another region was available", result[0].Region)
+	}
+
+	// With only one region in the pool, distinct owners still suffice.
+	result, err = Schedule([]orchestrator.NodeEntry{east1, east2}, orchestrator.SLAReliable, orchestrator.PlacementContext{})
+	if err != nil || len(result) != 2 {
+		t.Errorf("single-region pool: got %d node(s), err %v; want 2", len(result), err)
+	}
+}
+
+// ── Consumer placement preferences ───────────────────────────────────────────
+
+func TestSchedule_PreferredNodeBeatsEveryPlatformTerm(t *testing.T) {
+	// The best node the platform could pick: class A, local, idle, big.
+	best := makeNode("A", 16)
+	best.NodeID, best.Region = "best", "us-east"
+	best.LoadSampledAt, best.CPUUtilPct = time.Now(), 0
+	preferred := makeNode("D", 2)
+	preferred.NodeID = "preferred"
+	preferred.LastHeartbeat = time.Now().Add(-29 * time.Minute)
+
+	pctx := orchestrator.PlacementContext{RequesterRegion: "us-east", PreferredNodeIDs: []string{"preferred"}}
+	result, err := Schedule([]orchestrator.NodeEntry{best, preferred}, orchestrator.SLAStandard, pctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0].NodeID != "preferred" {
+		t.Errorf("picked %s, want the preferred node", result[0].NodeID)
+	}
+
+	// A preferred owner works the same way.
+	preferred.ParticipantID = "owner-p"
+	pctx = orchestrator.PlacementContext{RequesterRegion: "us-east", PreferredParticipantIDs: []string{"owner-p"}}
+	result, _ = Schedule([]orchestrator.NodeEntry{best, preferred}, orchestrator.SLAStandard, pctx)
+	if result[0].NodeID != "preferred" {
+		t.Errorf("picked %s, want the preferred owner's node", result[0].NodeID)
+	}
+}
+
+func TestSchedule_AntiAffinityRanksLastButStillPlaces(t *testing.T) {
+	busyOwner := makeNode("A", 16)
+	busyOwner.NodeID, busyOwner.ParticipantID = "busy-owner", "owner-running-my-job"
+	busyRegion := makeNode("A", 16)
+	busyRegion.NodeID, busyRegion.ParticipantID, busyRegion.Region = "busy-region", "o2", "us-east"
+	elsewhere := makeNode("D", 2)
+	elsewhere.NodeID, elsewhere.ParticipantID, elsewhere.Region = "elsewhere", "o3", "us-west"
+
+	pctx := orchestrator.PlacementContext{
+		AvoidParticipantIDs: []string{"owner-running-my-job"},
+		AvoidRegions:        []string{"us-east"},
+	}
+	result, err := Schedule([]orchestrator.NodeEntry{busyOwner, busyRegion, elsewhere}, orchestrator.SLAStandard, pctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0].NodeID != "elsewhere" {
+		t.Errorf("picked %s, want the node away from the consumer's other jobs", result[0].NodeID)
+	}
+
+	// Soft: with nowhere else to go, the job still lands.
+	result, err = Schedule([]orchestrator.NodeEntry{busyOwner}, orchestrator.SLAStandard, pctx)
+	if err != nil || result[0].NodeID != "busy-owner" {
+		t.Errorf("only avoided nodes: got %v, err %v; want busy-owner", result, err)
+	}
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// API key scopes (migration 045). A key may carry any non-empty subset.
+const (
+	ScopeJobsRead      = "jobs:read"
+	ScopeJobsWrite     = "jobs:write"
+	ScopeArtifactsRead = "artifacts:read"
+)
+
+// APIKeyScopes lists every scope a key may carry, in display order.
+var APIKeyScopes = []string{ScopeJobsRead, ScopeJobsWrite, ScopeArtifactsRead}
+
+// apiKeyPrefix marks a string as a SoHoLINK API key, so one pasted into the
+// wrong place (or a secret scanner) can recognise it.
+const apiKeyPrefix = "shk_"
+
+// apiKeyDisplayLen is how much of a key is kept in api_keys.prefix.
+const apiKeyDisplayLen = len(apiKeyPrefix) + 8
+
+var (
+	// ErrAPIKeyNotFound is returned when a presented key matches no live key,
+	// or when a participant has no such key to revoke.
+	ErrAPIKeyNotFound = errors.New("store: api key not found")
+	// ErrInvalidAPIKey is returned by CreateAPIKey for an empty name or scope
+	// list, or an unknown scope.
+	ErrInvalidAPIKey = errors.New("store: invalid api key")
+)
+
+// APIKey is one api_keys row. The key itself is never stored; see
+// CreateAPIKey.
+type APIKey struct {
+	ID            string
+	ParticipantID string
+	Name          string
+	Prefix        string
+	Scopes        []string
+	CreatedAt     time.Time
+	LastUsedAt    *time.Time
+	RevokedAt     *time.Time
+}
+
+// HasScope reports whether the key carries scope.
+func (k APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// hashAPIKey is the api_keys.key_hash of key.
+func hashAPIKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// CreateAPIKey issues participantID a new key named name carrying scopes and
+// returns it with the key itself, which is shown to the participant once and
+// cannot be recovered: only its hash is stored.
+func CreateAPIKey(ctx context.Context, db *DB, participantID, name string, scopes []string) (APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(scopes) == 0 {
+		return APIKey{}, "", ErrInvalidAPIKey
+	}
+	for _, s := range scopes {
+		if !slices.Contains(APIKeyScopes, s) {
+			return APIKey{}, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKey, s)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return APIKey{}, "", fmt.Errorf("create api key: random: %w", err)
+	}
+	secret := apiKeyPrefix + hex.EncodeToString(raw)
+
+	k := APIKey{
+		ParticipantID: participantID,
+		Name:          name,
+		Prefix:        secret[:apiKeyDisplayLen],
+		Scopes:        scopes,
+	}
+	if err := db.Pool.QueryRow(ctx,
+		`INSERT INTO api_keys (participant_id, name, prefix, key_hash, scopes)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id::text, created_at`,
+		participantID, k.Name, k.Prefix, hashAPIKey(secret), k.Scopes,
+	).Scan(&k.ID, &k.CreatedAt); err != nil {
+		return APIKey{}, "", fmt.Errorf("create api key: insert: %w", err)
+	}
+	return k, secret, nil
+}
+
+// AuthenticateAPIKey returns the live key matching key, without writing
+// anything: a request the rate limiter then turns away must not cost a
+// database write. Record an admitted request's use with TouchAPIKey.
+// Returns ErrAPIKeyNotFound for a malformed, unknown or revoked key.
+func AuthenticateAPIKey(ctx context.Context, db *DB, key string) (APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) != len(apiKeyPrefix)+64 {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	var k APIKey
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id::text, participant_id::text, name, prefix, scopes,
+		        created_at, last_used_at, revoked_at
+		 FROM api_keys
+		 WHERE key_hash = $1 AND revoked_at IS NULL`,
+		hashAPIKey(key),
+	).Scan(&k.ID, &k.ParticipantID, &k.Name, &k.Prefix, &k.Scopes,
+		&k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return APIKey{}, ErrAPIKeyNotFound
+		}
+		return APIKey{}, fmt.Errorf("authenticate api key: %w", err)
+	}
+	return k, nil
+}
+
+// TouchAPIKey records a use of key keyID as its last_used_at.
+func TouchAPIKey(ctx context.Context, db *DB, keyID string) error {
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, keyID,
+	); err != nil {
+		return fmt.Errorf("touch api key %s: %w", keyID, err)
+	}
+	return nil
+}
+
+// ListAPIKeys returns participantID's keys, revoked ones included, newest
+// first.
+func ListAPIKeys(ctx context.Context, db *DB, participantID string) ([]APIKey, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id::text, participant_id::text, name, prefix, scopes,
+		        created_at, last_used_at, revoked_at
+		 FROM api_keys
+		 WHERE participant_id = $1
+		 ORDER BY created_at DESC`,
+		participantID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: query: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []APIKey
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.ParticipantID, &k.Name, &k.Prefix, &k.Scopes,
+			&k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, fmt.Errorf("list api keys: scan: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys: rows: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes participantID's key keyID; it never authenticates
+// again. Returns ErrAPIKeyNotFound when the participant has no such live key.
+func RevokeAPIKey(ctx context.Context, db *DB, participantID, keyID string) error {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE api_keys SET revoked_at = NOW()
+		 WHERE id = $1 AND participant_id = $2 AND revoked_at IS NULL`,
+		keyID, participantID,
+	)
+	if err != nil {
+		return fmt.Errorf("revoke api key %s: %w", keyID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
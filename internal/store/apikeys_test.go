@@ -0,0 +1,145 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+func TestAPIKeys_CreateAuthenticateRevoke(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	var participantID string
+	if err := db.Pool.QueryRow(ctx,
+		`INSERT INTO participants (email, display_name)
+		 VALUES ('apikeys@test.com', 'API Keys') RETURNING id`,
+	).Scan(&participantID); err != nil {
+		t.Fatalf("seed participant: %v", err)
+	}
+
+	if _, _, err := store.CreateAPIKey(ctx, db, participantID, "ci", []string{"jobs:delete"}); !errors.Is(err, store.ErrInvalidAPIKey) {
+		t.Fatalf("unknown scope: err = %v, want ErrInvalidAPIKey", err)
+	}
+	if _, _, err := store.CreateAPIKey(ctx, db, participantID, " ", []string{store.ScopeJobsRead}); !errors.Is(err, store.ErrInvalidAPIKey) {
+		t.Fatalf("blank name: err = %v, want ErrInvalidAPIKey", err)
+	}
+
+	key, secret, err := store.CreateAPIKey(ctx, db, participantID, "ci", []string{store.ScopeJobsRead, store.ScopeJobsWrite})
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(secret, key.Prefix) || len(secret) != 68 {
+		t.Fatalf("secret %q does not start with prefix %q or has the wrong length", secret, key.Prefix)
+	}
+	var stored int
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM api_keys WHERE key_hash = convert_to($1, 'UTF8')`, secret,
+	).Scan(&stored); err != nil {
+		t.Fatalf("count plaintext: %v", err)
+	}
+	if stored != 0 {
+		t.Fatal("the key is stored in plain text")
+	}
+
+	got, err := store.AuthenticateAPIKey(ctx, db, secret)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey: %v", err)
+	}
+	if got.ID != key.ID || got.ParticipantID != participantID || got.LastUsedAt != nil {
+		t.Errorf("authenticated %+v, want key %s of %s not yet used", got, key.ID, participantID)
+	}
+	if err := store.TouchAPIKey(ctx, db, key.ID); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+	if got, err := store.AuthenticateAPIKey(ctx, db, secret); err != nil || got.LastUsedAt == nil {
+		t.Errorf("after TouchAPIKey: %+v, %v; want last_used_at set", got, err)
+	}
+	if !got.HasScope(store.ScopeJobsWrite) || got.HasScope(store.ScopeArtifactsRead) {
+		t.Errorf("scopes = %v", got.Scopes)
+	}
+	wrong := secret[:len(secret)-1] + "0"
+	if wrong == secret {
+		wrong = secret[:len(secret)-1] + "1"
+	}
+	if _, err := store.AuthenticateAPIKey(ctx, db, wrong); !errors.Is(err, store.ErrAPIKeyNotFound) {
+		t.Errorf("wrong key: err = %v, want ErrAPIKeyNotFound", err)
+	}
+
+	if err := store.RevokeAPIKey(ctx, db, participantID, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if _, err := store.AuthenticateAPIKey(ctx, db, secret); !errors.Is(err, store.ErrAPIKeyNotFound) {
+		t.Errorf("revoked key: err = %v, want ErrAPIKeyNotFound", err)
+	}
+	if err := store.RevokeAPIKey(ctx, db, participantID, key.ID); !errors.Is(err, store.ErrAPIKeyNotFound) {
+		t.Errorf("second revoke: err = %v, want ErrAPIKeyNotFound", err)
+	}
+
+	keys, err := store.ListAPIKeys(ctx, db, participantID)
+	if err != nil {
+		t.Fatalf("ListAPIKeys: %v", err)
+	}
+	if len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("ListAPIKeys = %+v, want the one key, revoked", keys)
+	}
+}
+
+func TestListConsumerJobs_HidesReplicas(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	var consumerID string
+	if err := db.Pool.QueryRow(ctx,
+		`INSERT INTO participants (email, display_name)
+		 VALUES ('consumerjobs@test.com', 'Consumer Jobs') RETURNING id`,
+	).Scan(&consumerID); err != nil {
+		t.Fatalf("seed participant: %v", err)
+	}
+	var parentID string
+	if err := db.Pool.QueryRow(ctx,
+		`INSERT INTO jobs (participant_id, workload_type, status, cpu_cores, ram_mb)
+		 VALUES ($1, 'batch_compute', 'running', 2, 4096) RETURNING id`,
+		consumerID,
+	).Scan(&parentID); err != nil {
+		t.Fatalf("seed parent: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`INSERT INTO jobs (participant_id, workload_type, status, cpu_cores, ram_mb,
+		                   parent_job_id, replica_index)
+		 VALUES ($1, 'batch_compute', 'running', 2, 4096, $2, 0)`,
+		consumerID, parentID,
+	); err != nil {
+		t.Fatalf("seed replica: %v", err)
+	}
+
+	jobs, err := store.ListConsumerJobs(ctx, db, consumerID, "", 50, 0)
+	if err != nil {
+		t.Fatalf("ListConsumerJobs: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != parentID || jobs[0].Status != "running" {
+		t.Fatalf("ListConsumerJobs = %+v, want only the parent", jobs)
+	}
+	if jobs, err := store.ListConsumerJobs(ctx, db, consumerID, "completed", 50, 0); err != nil || len(jobs) != 0 {
+		t.Errorf("status filter: %d jobs, err %v; want none", len(jobs), err)
+	}
+
+	if _, err := store.GetConsumerJob(ctx, db, parentID, consumerID); err != nil {
+		t.Errorf("GetConsumerJob: %v", err)
+	}
+	var otherID string
+	if err := db.Pool.QueryRow(ctx,
+		`INSERT INTO participants (email, display_name)
+		 VALUES ('otherconsumer@test.com', 'Other') RETURNING id`,
+	).Scan(&otherID); err != nil {
+		t.Fatalf("seed other participant: %v", err)
+	}
+	if _, err := store.GetConsumerJob(ctx, db, parentID, otherID); !errors.Is(err, store.ErrJobNotFound) {
+		t.Errorf("another consumer's job: err = %v, want ErrJobNotFound", err)
+	}
+}
@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
+)
+
+// ErrArtifactNotFound is returned by ConsumerArtifact when the job has no
+// recorded artifact the consumer may download.
+var ErrArtifactNotFound = errors.New("store: job artifact not found")
+
+// ErrArtifactQuotaExceeded is returned by RecordArtifact and
+// RecordInputUpload when the object would take the consumer past their
+// artifact quota.
+var ErrArtifactQuotaExceeded = errors.New("store: artifact quota exceeded")
+
+// JobArtifact is one job_artifacts row (migration 033).
+type JobArtifact struct {
+	JobID         string
+	ParticipantID string
+	ObjectKey     string
+	SizeBytes     int64
+	SHA256        string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	// DeletedAt is set once the expiry reaper has removed the object.
+	DeletedAt *time.Time
+}
+
+// Expired reports whether the artifact is no longer downloadable at now.
+func (a JobArtifact) Expired(now time.Time) bool {
+	return a.DeletedAt != nil || !now.Before(a.ExpiresAt)
+}
+
+// ArtifactUsage returns the total size of participantID's live (not yet
+// reaped) artifacts and input uploads (034), which share one quota, for the
+// upload handlers' quota pre-check.
+func ArtifactUsage(ctx context.Context, db *DB, participantID string) (int64, error) {
+	var used int64
+	if err := db.Pool.QueryRow(ctx, storageUsageSQL, participantID, "", "").Scan(&used); err != nil {
+		return 0, fmt.Errorf("artifact usage %s: %w", participantID, err)
+	}
+	return used, nil
+}
+
+// RecordArtifact records an uploaded artifact, replacing any earlier upload
+// for the same job, and returns the object key of the upload it replaced
+// ("" for none, or when it was stored under the same key) for the caller to
+// delete. The consumer's quota is re-checked under a lock on their
+// participants row, so concurrent uploads cannot together exceed quotaBytes;
+// the replaced upload does not count against it. Returns
+// ErrArtifactQuotaExceeded when a.SizeBytes does not fit.
+func RecordArtifact(ctx context.Context, db *DB, a JobArtifact, quotaBytes int64) (string, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("record artifact %s: begin: %w", a.JobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	if _, err := tx.Exec(ctx,
+		`SELECT 1 FROM participants WHERE id = $1 FOR UPDATE`, a.ParticipantID,
+	); err != nil {
+		return "", fmt.Errorf("record artifact %s: lock participant: %w", a.JobID, err)
+	}
+	var used int64
+	if err := tx.QueryRow(ctx, storageUsageSQL, a.ParticipantID, a.JobID, "").Scan(&used); err != nil {
+		return "", fmt.Errorf("record artifact %s: usage: %w", a.JobID, err)
+	}
+	if used+a.SizeBytes > quotaBytes {
+		return "", ErrArtifactQuotaExceeded
+	}
+
+	// A reaped artifact (deleted_at set) has no object left to delete.
+	var replaced string
+	err = tx.QueryRow(ctx,
+		`SELECT object_key FROM job_artifacts
+		 WHERE job_id = $1 AND deleted_at IS NULL AND object_key <> $2
+		 FOR UPDATE`,
+		a.JobID, a.ObjectKey,
+	).Scan(&replaced)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", fmt.Errorf("record artifact %s: read replaced: %w", a.JobID, err)
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO job_artifacts (job_id, participant_id, object_key, size_bytes, sha256, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (job_id) DO UPDATE
+		 SET object_key = EXCLUDED.object_key,
+		     size_bytes = EXCLUDED.size_bytes,
+		     sha256     = EXCLUDED.sha256,
+		     created_at = NOW(),
+		     expires_at = EXCLUDED.expires_at,
+		     deleted_at = NULL`,
+		a.JobID, a.ParticipantID, a.ObjectKey, a.SizeBytes, a.SHA256, a.ExpiresAt,
+	); err != nil {
+		return "", fmt.Errorf("record artifact %s: upsert: %w", a.JobID, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("record artifact %s: commit: %w", a.JobID, err)
+	}
+	return replaced, nil
+}
+
+// ConsumerArtifact returns the artifact participantID may download for jobID.
+// For a replica group parent (031) that is the artifact of the earliest
+// completed replica not flagged suspected_fraud (032). Expired artifacts are
+// returned too — see JobArtifact.Expired. Returns ErrArtifactNotFound when
+// there is none, including when jobID is not the participant's.
+func ConsumerArtifact(ctx context.Context, db *DB, jobID, participantID string) (JobArtifact, error) {
+	var a JobArtifact
+	err := db.Pool.QueryRow(ctx,
+		`SELECT a.job_id::text, a.participant_id::text, a.object_key, a.size_bytes, a.sha256,
+		        a.created_at, a.expires_at, a.deleted_at
+		 FROM jobs j
+		 JOIN job_artifacts a ON a.job_id = j.id
+		 WHERE j.participant_id = $2
+		   AND (j.id = $1
+		        OR (j.parent_job_id = $1
+		            AND j.status = 'completed'::job_status
+		            AND NOT j.suspected_fraud))
+		 ORDER BY j.completed_at NULLS FIRST
+		 LIMIT 1`,
+		jobID, participantID,
+	).Scan(&a.JobID, &a.ParticipantID, &a.ObjectKey, &a.SizeBytes, &a.SHA256,
+		&a.CreatedAt, &a.ExpiresAt, &a.DeletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return JobArtifact{}, ErrArtifactNotFound
+		}
+		return JobArtifact{}, fmt.Errorf("consumer artifact %s: %w", jobID, err)
+	}
+	return a, nil
+}
+
+// artifactReapBatch bounds one reaper pass; the rest wait for the next tick.
+const artifactReapBatch = 100
+
+// RunArtifactReaper runs in a goroutine and, every interval, deletes expired
+// artifacts and input uploads from st and marks their rows deleted. A failed
+// delete leaves the row live for the next pass.
+func RunArtifactReaper(ctx context.Context, db *DB, st artifact.Store, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := reapArtifacts(ctx, db, st); err != nil {
+				slog.Warn("artifact reaper error", "error", err)
+			}
+			if err := reapInputUploads(ctx, db, st); err != nil {
+				slog.Warn("artifact reaper error", "error", err)
+			}
+		}
+	}
+}
+
+func reapArtifacts(ctx context.Context, db *DB, st artifact.Store) error {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT job_id::text, object_key
+		 FROM job_artifacts
+		 WHERE deleted_at IS NULL AND expires_at <= NOW()
+		 ORDER BY expires_at
+		 LIMIT $1`,
+		artifactReapBatch,
+	)
+	if err != nil {
+		return fmt.Errorf("reap artifacts: query: %w", err)
+	}
+	type expired struct{ jobID, key string }
+	var batch []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.jobID, &e.key); err != nil {
+			rows.Close()
+			return fmt.Errorf("reap artifacts: scan: %w", err)
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reap artifacts: rows: %w", err)
+	}
+
+	for _, e := range batch {
+		if err := st.Delete(ctx, e.key); err != nil {
+			slog.Warn("artifact reaper: delete failed", "job_id", e.jobID, "error", err)
+			continue
+		}
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE job_artifacts SET deleted_at = NOW() WHERE job_id = $1`, e.jobID,
+		); err != nil {
+			slog.Warn("artifact reaper: mark deleted failed", "job_id", e.jobID, "error", err)
+		}
+	}
+	return nil
+}
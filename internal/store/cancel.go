@@ -0,0 +1,395 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FailureCauseConsumerCancelled marks a job its consumer cancelled before it
+// finished (migration 036).
+const FailureCauseConsumerCancelled = "consumer_cancelled"
+
+// ErrJobNotFound is returned by CancelJob when the participant has no such
+// job. Replicas are not addressable on their own: a replica group is
+// cancelled through its parent.
+var ErrJobNotFound = errors.New("store: job not found")
+
+// ErrJobNotCancellable is returned by CancelJob for a job that has already
+// finished or reached the print hand-off stages.
+var ErrJobNotCancellable = errors.New("store: job is not cancellable")
+
+// cancellableStatuses are the job_status values a consumer may cancel from:
+// every status before the work is done.
+var cancellableStatuses = []string{
+	"pending", "scheduled", "dispatched", "awaiting_confirmation", "declined", "running",
+}
+
+// CancelledJob is one row CancelJob moved to failed — the job itself, or for a
+// replica group the parent and each unfinished replica.
+type CancelledJob struct {
+	JobID       string
+	NodeID      string // "" when no node was bound
+	PriorStatus string
+	Started     bool // the container ran; the partial run is billable
+}
+
+// HoldsNode reports whether the job held a placement on its node before it
+// was cancelled, i.e. whether the node's registry reservation is still live.
+// A declined job released its node when the decline was recorded.
+func (c CancelledJob) HoldsNode() bool {
+	if c.NodeID == "" {
+		return false
+	}
+	switch c.PriorStatus {
+	case "awaiting_confirmation", "scheduled", "dispatched", "running":
+		return true
+	}
+	return false
+}
+
+// Cancellation is the result of CancelJob.
+type Cancellation struct {
+	JobID       string
+	PriorStatus string
+	Jobs        []CancelledJob
+}
+
+// CancelJob cancels participantID's job jobID: it and, for a replica group,
+// every unfinished replica become failed with FailureCauseConsumerCancelled,
+// completed_at and cancelled_at set. A running container is stopped by its
+// agent on the next heartbeat (see JobsToStop); the caller releases the
+// returned placements and meters the started runs. Returns ErrJobNotFound, or
+// ErrJobNotCancellable with PriorStatus set to the job's current status.
+func CancelJob(ctx context.Context, db *DB, jobID, participantID string) (Cancellation, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return Cancellation{}, fmt.Errorf("cancel job %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	c := Cancellation{JobID: jobID}
+	err = tx.QueryRow(ctx,
+		`SELECT status::text FROM jobs
+		 WHERE id = $1 AND participant_id = $2 AND parent_job_id IS NULL
+		 FOR UPDATE`,
+		jobID, participantID,
+	).Scan(&c.PriorStatus)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Cancellation{}, ErrJobNotFound
+		}
+		return Cancellation{}, fmt.Errorf("cancel job %s: read status: %w", jobID, err)
+	}
+	if !slices.Contains(cancellableStatuses, c.PriorStatus) {
+		return c, ErrJobNotCancellable
+	}
+
+	rows, err := tx.Query(ctx,
+		`WITH prior AS (
+		     SELECT id, status FROM jobs
+		     WHERE (id = $1 OR parent_job_id = $1) AND status::text = ANY($3)
+		     FOR UPDATE
+		 )
+		 UPDATE jobs j
+		 SET status        = 'failed'::job_status,
+		     failure_cause = $2,
+		     cancelled_at  = NOW(),
+		     completed_at  = NOW(),
+		     updated_at    = NOW()
+		 FROM prior
+		 WHERE j.id = prior.id
+		 RETURNING j.id::text, COALESCE(j.node_id::text, ''), prior.status::text,
+		           j.started_at IS NOT NULL`,
+		jobID, FailureCauseConsumerCancelled, cancellableStatuses,
+	)
+	if err != nil {
+		return Cancellation{}, fmt.Errorf("cancel job %s: update: %w", jobID, err)
+	}
+	for rows.Next() {
+		var j CancelledJob
+		if err := rows.Scan(&j.JobID, &j.NodeID, &j.PriorStatus, &j.Started); err != nil {
+			rows.Close()
+			return Cancellation{}, fmt.Errorf("cancel job %s: scan: %w", jobID, err)
+		}
+		c.Jobs = append(c.Jobs, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Cancellation{}, fmt.Errorf("cancel job %s: rows: %w", jobID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Cancellation{}, fmt.Errorf("cancel job %s: commit: %w", jobID, err)
+	}
+	return c, nil
+}
+
+// CancelRefund is the settlement of a cancelled job's charge.
+type CancelRefund struct {
+	ChargedCents    int64  // metered cost of what ran, replicas included
+	PaymentIntentID string // "" when the job carried no charge
+	RefundCents     int64  // unused share of amount_cents owed back
+
+	// Escrowed is set for a job whose payment is held in escrow (migration
+	// 038). Nothing is refunded against PaymentIntentID: the escrow settler
+	// captures ChargedCents and releases the RefundCents remainder.
+	Escrowed bool
+}
+
+// SettleCancelRefund records the refund owed on cancelled job jobID once its
+// partial runs are metered: amount_cents less the metered cost of the job (or
+// its replicas), never below zero. A job with no payment intent or no charge
+// owes nothing and comes back with an empty PaymentIntentID. Idempotent until
+// MarkRefunded: a retry recomputes the same amount.
+//
+// An escrowed job is not charged until it settles, so nothing is recorded:
+// the result reports the hold the settler will release — all of it if the
+// consumer never authorized the payment.
+func SettleCancelRefund(ctx context.Context, db *DB, jobID string) (CancelRefund, error) {
+	var r CancelRefund
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(m.consumer_paid_cents), 0)
+		 FROM job_metering m
+		 JOIN jobs j ON j.id = m.job_id
+		 WHERE j.id = $1 OR j.parent_job_id = $1`,
+		jobID,
+	).Scan(&r.ChargedCents); err != nil {
+		return CancelRefund{}, fmt.Errorf("settle cancel refund %s: sum metering: %w", jobID, err)
+	}
+
+	var (
+		paymentStatus *string
+		amountCents   int64
+	)
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT payment_status, amount_cents FROM jobs WHERE id = $1`,
+		jobID,
+	).Scan(&paymentStatus, &amountCents); err != nil {
+		return CancelRefund{}, fmt.Errorf("settle cancel refund %s: read payment: %w", jobID, err)
+	}
+	if paymentStatus != nil {
+		r.Escrowed = true
+		if *paymentStatus == PaymentAuthorized {
+			r.RefundCents = max(amountCents-r.ChargedCents, 0)
+		}
+		return r, nil
+	}
+
+	err := db.Pool.QueryRow(ctx,
+		`UPDATE jobs
+		 SET refund_cents = GREATEST(amount_cents - $2, 0),
+		     updated_at   = NOW()
+		 WHERE id = $1
+		   AND cancelled_at IS NOT NULL
+		   AND refunded_at IS NULL
+		   AND payment_intent_id IS NOT NULL
+		   AND amount_cents > 0
+		 RETURNING payment_intent_id, refund_cents`,
+		jobID, r.ChargedCents,
+	).Scan(&r.PaymentIntentID, &r.RefundCents)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return CancelRefund{}, fmt.Errorf("settle cancel refund %s: record: %w", jobID, err)
+	}
+	return r, nil
+}
+
+// MarkRefunded records that jobID's refund_cents were refunded, and posts
+// the refund to the ledger.
+func MarkRefunded(ctx context.Context, db *DB, jobID string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("mark refunded %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var (
+		consumerID  string
+		refundCents int64
+	)
+	err = tx.QueryRow(ctx,
+		`UPDATE jobs SET refunded_at = NOW(), updated_at = NOW()
+		 WHERE id = $1 AND refunded_at IS NULL
+		 RETURNING participant_id::text, COALESCE(refund_cents, 0)`,
+		jobID,
+	).Scan(&consumerID, &refundCents)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("mark refunded %s: %w", jobID, err)
+	}
+	if err := postLedger(ctx, tx, ledgerRefund, jobID, jobID, []LedgerEntry{
+		{Account: AccountEscrow, AmountCents: -refundCents},
+		{Account: AccountRefund, ParticipantID: consumerID, AmountCents: refundCents},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("mark refunded %s: commit: %w", jobID, err)
+	}
+	return nil
+}
+
+// MarkChargeRefunded records, through MarkRefunded, the refund owed on the
+// cancelled job charged as paymentIntentID once Stripe reports at least that
+// much of the charge refunded (charge.refunded) — a refund the cancel path or
+// the cancel refunder made but did not get to record. Returns false when no
+// cancelled job owes a refund covered by refundedCents.
+func MarkChargeRefunded(ctx context.Context, db *DB, paymentIntentID string, refundedCents int64) (bool, error) {
+	var jobID string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id::text FROM jobs
+		 WHERE payment_intent_id = $1
+		   AND parent_job_id IS NULL
+		   AND payment_status IS NULL
+		   AND cancelled_at IS NOT NULL
+		   AND refunded_at IS NULL
+		   AND refund_cents > 0
+		   AND refund_cents <= $2`,
+		paymentIntentID, refundedCents,
+	).Scan(&jobID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("mark charge refunded %s: %w", paymentIntentID, err)
+	}
+	return true, MarkRefunded(ctx, db, jobID)
+}
+
+// maxCancelRefundAttempts is how many failed retries of one cancellation
+// refund the cancel refunder makes before leaving it to staff.
+const maxCancelRefundAttempts = 5
+
+// OwedRefund is a cancelled job whose refund_cents are still owed: the
+// refund failed when the job was cancelled, and perhaps on retry since.
+type OwedRefund struct {
+	JobID           string
+	PaymentIntentID string
+	RefundCents     int64
+	Attempts        int    // failed retries
+	LastError       string // "" before the first failed retry
+	CancelledAt     time.Time
+}
+
+// GaveUp reports whether the cancel refunder has stopped retrying o.
+func (o OwedRefund) GaveUp() bool { return o.Attempts >= maxCancelRefundAttempts }
+
+// owedRefundColumns select an OwedRefund from jobs; owedRefundWhere matches
+// the jobs that owe one.
+const (
+	owedRefundColumns = `id::text, payment_intent_id, refund_cents, refund_attempts,
+		COALESCE(refund_error, ''), cancelled_at`
+	owedRefundWhere = `cancelled_at IS NOT NULL
+		AND refunded_at IS NULL
+		AND refund_cents > 0
+		AND payment_status IS NULL
+		AND payment_intent_id IS NOT NULL`
+)
+
+// CancelRefundsToRetry returns up to 100 owed refunds of jobs cancelled
+// before cancelledBefore, those with the fewest failed retries first. The
+// cutoff leaves the refund made while the cancel request is served to finish
+// first.
+func CancelRefundsToRetry(ctx context.Context, db *DB, cancelledBefore time.Time) ([]OwedRefund, error) {
+	return queryOwedRefunds(ctx, db, "cancel refunds to retry",
+		`SELECT `+owedRefundColumns+`
+		 FROM jobs
+		 WHERE `+owedRefundWhere+`
+		   AND cancelled_at < $1
+		 ORDER BY refund_attempts, cancelled_at
+		 LIMIT 100`,
+		cancelledBefore)
+}
+
+// OwedCancelRefunds returns up to 100 owed refunds, the most recently
+// cancelled first, for staff: those still being retried and those the cancel
+// refunder gave up on (Attempts at maxCancelRefundAttempts).
+func OwedCancelRefunds(ctx context.Context, db *DB) ([]OwedRefund, error) {
+	return queryOwedRefunds(ctx, db, "owed cancel refunds",
+		`SELECT `+owedRefundColumns+`
+		 FROM jobs
+		 WHERE `+owedRefundWhere+`
+		 ORDER BY cancelled_at DESC
+		 LIMIT 100`)
+}
+
+func queryOwedRefunds(ctx context.Context, db *DB, what, query string, args ...any) ([]OwedRefund, error) {
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", what, err)
+	}
+	defer rows.Close()
+	var out []OwedRefund
+	for rows.Next() {
+		var o OwedRefund
+		if err := rows.Scan(&o.JobID, &o.PaymentIntentID, &o.RefundCents, &o.Attempts,
+			&o.LastError, &o.CancelledAt); err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", what, err)
+		}
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// RecordCancelRefundFailure counts a failed retry of job jobID's
+// cancellation refund, keeping cause as its refund_error. Returns true when
+// this failure was the last maxCancelRefundAttempts allows.
+func RecordCancelRefundFailure(ctx context.Context, db *DB, jobID string, cause error) (bool, error) {
+	var attempts int
+	err := db.Pool.QueryRow(ctx,
+		`UPDATE jobs
+		 SET refund_attempts = refund_attempts + 1,
+		     refund_error    = $2,
+		     updated_at      = NOW()
+		 WHERE id = $1 AND refunded_at IS NULL
+		 RETURNING refund_attempts`,
+		jobID, cause.Error(),
+	).Scan(&attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("record cancel refund failure %s: %w", jobID, err)
+	}
+	return attempts >= maxCancelRefundAttempts, nil
+}
+
+// JobsToStop returns the jobs among running — the jobs nodeID's agent reports
+// it is running — that the control plane no longer has running or dispatched
+// on that node: cancelled, failed by a reaper, rerouted elsewhere, or unknown.
+// The agent stops their containers.
+func JobsToStop(ctx context.Context, db *DB, nodeID string, running []string) ([]string, error) {
+	if len(running) == 0 {
+		return nil, nil
+	}
+	rows, err := db.Pool.Query(ctx,
+		`SELECT r.id
+		 FROM unnest($2::text[]) AS r(id)
+		 WHERE NOT EXISTS (
+		     SELECT 1 FROM jobs j
+		     WHERE j.id::text = r.id
+		       AND j.node_id = $1
+		       AND j.status IN ('dispatched'::job_status, 'running'::job_status)
+		 )`,
+		nodeID, running,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("jobs to stop on %s: %w", nodeID, err)
+	}
+	defer rows.Close()
+	var stop []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("jobs to stop on %s: scan: %w", nodeID, err)
+		}
+		stop = append(stop, id)
+	}
+	return stop, rows.Err()
+}
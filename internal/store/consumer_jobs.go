@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ConsumerJob is a job as its consumer sees it. Replicas of a replica group
+// (migration 031) are not listed on their own; the group's parent stands for
+// them.
+type ConsumerJob struct {
+	ID                string
+	WorkloadType      string
+	Status            string
+	NodeID            string
+	ContainerImage    string
+	CPUCores          int
+	RAMMB             int
+	MaxRuntimeSeconds int
+	FailureCause      string
+	PaymentStatus     string
+	CreatedAt         time.Time
+	StartedAt         *time.Time
+	CompletedAt       *time.Time
+}
+
+// consumerJobColumns are the columns scanned by scanConsumerJob.
+const consumerJobColumns = `
+	id::text, workload_type::text, status::text, COALESCE(node_id::text, ''),
+	COALESCE(container_image, ''), COALESCE(cpu_cores, 0), COALESCE(ram_mb, 0),
+	COALESCE(max_runtime_seconds, 0), COALESCE(failure_cause, ''),
+	COALESCE(payment_status, ''), created_at, started_at, completed_at`
+
+func scanConsumerJob(row pgx.Row) (ConsumerJob, error) {
+	var j ConsumerJob
+	err := row.Scan(&j.ID, &j.WorkloadType, &j.Status, &j.NodeID,
+		&j.ContainerImage, &j.CPUCores, &j.RAMMB,
+		&j.MaxRuntimeSeconds, &j.FailureCause,
+		&j.PaymentStatus, &j.CreatedAt, &j.StartedAt, &j.CompletedAt)
+	return j, err
+}
+
+// GetConsumerJob returns participantID's job jobID, or ErrJobNotFound.
+func GetConsumerJob(ctx context.Context, db *DB, jobID, participantID string) (ConsumerJob, error) {
+	j, err := scanConsumerJob(db.Pool.QueryRow(ctx,
+		`SELECT `+consumerJobColumns+`
+		 FROM jobs
+		 WHERE id = $1 AND participant_id = $2 AND parent_job_id IS NULL`,
+		jobID, participantID,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ConsumerJob{}, ErrJobNotFound
+		}
+		return ConsumerJob{}, fmt.Errorf("consumer job %s: %w", jobID, err)
+	}
+	return j, nil
+}
+
+// ListConsumerJobs returns up to limit of participantID's jobs, newest first,
+// skipping the first offset. A non-empty status keeps only jobs in it.
+func ListConsumerJobs(ctx context.Context, db *DB, participantID, status string, limit, offset int) ([]ConsumerJob, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+consumerJobColumns+`
+		 FROM jobs
+		 WHERE participant_id = $1 AND parent_job_id IS NULL
+		   AND ($2 = '' OR status::text = $2)
+		 ORDER BY created_at DESC, id
+		 LIMIT $3 OFFSET $4`,
+		participantID, status, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list consumer jobs: query: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []ConsumerJob
+	for rows.Next() {
+		j, err := scanConsumerJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list consumer jobs: scan: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list consumer jobs: rows: %w", err)
+	}
+	return jobs, nil
+}
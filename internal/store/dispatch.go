@@ -20,25 +20,56 @@ import (
 // this to HTTP 409.
 var ErrJobNotRunning = errors.New("store: job is not in running state")
 
-// DispatchedJob is one job claimed by PollScheduledJobs.
+// FailureCauseTimeout marks a job that ran past its max_runtime_seconds
+// (migration 037): its agent stopped the container, or the orchestrator's
+// reaper gave up waiting for the node to report.
+const FailureCauseTimeout = "timeout"
+
+// ErrInvalidResultHash is returned by RecordResultHash for a hash that is not
+// 64 lowercase hex characters (a SHA-256 digest). Callers map this to 400.
+var ErrInvalidResultHash = errors.New("store: result hash is not a hex SHA-256 digest")
+
+// DispatchedJob is one job claimed by PollScheduledJobs. CPUCores, RAMMB and
+// StorageGB are the job's requested resources (zero when the job left them
+// unset); the agent intersects them with the contributor's active profile.
 type DispatchedJob struct {
 	JobID        string
 	JobToken     string
 	Image        string
 	PrinterID    string
 	WorkloadType string
+	CPUCores     int
+	RAMMB        int
+	StorageGB    int
+
+	// OutputPath is the job's declared output artifact inside the container
+	// (empty when none); the agent reports its SHA-256 on completion.
+	OutputPath string
+
+	// MaxRuntimeSeconds is the job's runtime limit (migration 037); the
+	// agent stops the container when it is reached. Zero for jobs submitted
+	// before the limit existed.
+	MaxRuntimeSeconds int
+
+	// Inputs are the files the agent stages read-only under /input before
+	// starting the container (migration 034).
+	Inputs []JobInput
 }
 
 // PollScheduledJobs returns the node's scheduled jobs and atomically flips
 // them scheduled → dispatched (the agent's claim; see B5/TODO 24). Both the
 // SELECT and the UPDATE carry the C5 self-print predicate: print jobs whose
-// consumer owns the polling node are never dispatched to it.
+// consumer owns the polling node are never dispatched to it. An escrowed job
+// is held back until its payment is authorized (migration 038).
 func PollScheduledJobs(ctx context.Context, db *DB, nodeID string) ([]DispatchedJob, error) {
 	rows, err := db.Pool.Query(ctx,
 		`SELECT id, COALESCE(job_token, ''), COALESCE(container_image, ''), COALESCE(printer_id, ''),
-		        workload_type::text
+		        workload_type::text,
+		        COALESCE(cpu_cores, 0), COALESCE(ram_mb, 0), COALESCE(storage_gb, 0),
+		        COALESCE(output_path, ''), COALESCE(max_runtime_seconds, 0)
 		 FROM jobs
 		 WHERE node_id = $1 AND status = 'scheduled'::job_status
+		 AND (payment_status IS NULL OR payment_status = 'authorized')
 		 AND NOT (
 		     workload_type IN ('print_traditional'::workload_type, 'print_3d'::workload_type)
 		     AND participant_id = (SELECT participant_id FROM nodes WHERE id = $1)
@@ -54,7 +85,8 @@ func PollScheduledJobs(ctx context.Context, db *DB, nodeID string) ([]Dispatched
 	var jobIDs []string
 	for rows.Next() {
 		var j DispatchedJob
-		if err := rows.Scan(&j.JobID, &j.JobToken, &j.Image, &j.PrinterID, &j.WorkloadType); err != nil {
+		if err := rows.Scan(&j.JobID, &j.JobToken, &j.Image, &j.PrinterID, &j.WorkloadType,
+			&j.CPUCores, &j.RAMMB, &j.StorageGB, &j.OutputPath, &j.MaxRuntimeSeconds); err != nil {
 			return nil, fmt.Errorf("poll scheduled jobs: scan: %w", err)
 		}
 		jobs = append(jobs, j)
@@ -77,6 +109,14 @@ func PollScheduledJobs(ctx context.Context, db *DB, nodeID string) ([]Dispatched
 		if err != nil {
 			return nil, fmt.Errorf("poll scheduled jobs: dispatch flip: %w", err)
 		}
+
+		inputs, err := jobInputs(ctx, db, jobIDs)
+		if err != nil {
+			return nil, fmt.Errorf("poll scheduled jobs: %w", err)
+		}
+		for i := range jobs {
+			jobs[i].Inputs = inputs[jobs[i].JobID]
+		}
 	}
 	return jobs, nil
 }
@@ -155,6 +195,77 @@ func CompleteJob(ctx context.Context, db *DB, jobID string, exitCode *int, failu
 	return newStatus, nil
 }
 
+// RecordResultHash stores the SHA-256 of a running job's declared output
+// artifact, as reported with its completion. Call before CompleteJob, which
+// settles a verified replica group by comparing these hashes. Returns
+// ErrInvalidResultHash for a malformed digest and ErrJobNotRunning when the
+// job is not in 'running' status.
+func RecordResultHash(ctx context.Context, db *DB, jobID, hash string) error {
+	if !isHexSHA256(hash) {
+		return ErrInvalidResultHash
+	}
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE jobs SET result_hash = $2, updated_at = NOW()
+		 WHERE id = $1 AND status = 'running'::job_status`,
+		jobID, hash,
+	)
+	if err != nil {
+		return fmt.Errorf("record result hash %s: %w", jobID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotRunning
+	}
+	return nil
+}
+
+// isHexSHA256 reports whether s is a lowercase hex SHA-256 digest, the form
+// the jobs.result_hash CHECK accepts.
+func isHexSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// DeclineJob records a node turning down a job placed on it: the guarded
+// status flip to 'declined' (only a job scheduled/dispatched to THIS node
+// matches) followed by the job_node_declines row that makes the reroute
+// worker exclude the node. The flip runs first so an authenticated node
+// cannot spam decline rows for jobs it was never offered. Returns false when
+// the flip matched no row (lost race, or not this node's job); no decline row
+// is written in that case. In-flight accounting stays with the caller.
+func DeclineJob(ctx context.Context, db *DB, jobID, nodeID string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE jobs
+		 SET status      = 'declined'::job_status,
+		     declined_at = NOW(),
+		     updated_at  = NOW()
+		 WHERE id = $1 AND node_id = $2
+		   AND status IN ('scheduled'::job_status, 'dispatched'::job_status)`,
+		jobID, nodeID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("decline job %s: update: %w", jobID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := db.Pool.Exec(ctx,
+		`INSERT INTO job_node_declines (job_id, node_id) VALUES ($1, $2)
+		 ON CONFLICT (job_id, node_id) DO NOTHING`,
+		jobID, nodeID,
+	); err != nil {
+		return false, fmt.Errorf("decline job %s: record decline: %w", jobID, err)
+	}
+	return true, nil
+}
+
 // RecordNodeHeartbeat persists a node liveness signal: refreshes
 // nodes.last_heartbeat_at and appends a node_heartbeat_events row (the uptime
 // scorer's raw input).
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrDisputeNotOpen is returned by ResolveDispute for a dispute that does not
+// exist or is already resolved.
+var ErrDisputeNotOpen = errors.New("store: dispute not found or already resolved")
+
+// ResolveDispute records arbiterID's resolution of dispute disputeID, with
+// consumerRefundPct of the job's charge — refundCents, already refunded by
+// the caller — going back to the consumer. The refund is posted to the
+// ledger in the same transaction, out of the platform fee: the contributor
+// keeps their earnings.
+func ResolveDispute(ctx context.Context, db *DB, disputeID string, consumerRefundPct int, arbiterID string, refundCents int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("resolve dispute %s: begin: %w", disputeID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var jobID, consumerID string
+	err = tx.QueryRow(ctx,
+		`UPDATE disputes d
+		 SET status = 'resolved', consumer_refund_pct = $1, arbiter_participant_id = $2,
+		     arbiter_notes = 'resolved via terminal', resolved_at = NOW(), updated_at = NOW()
+		 FROM jobs j
+		 WHERE d.id = $3 AND j.id = d.job_id
+		   AND d.status IN ('open', 'under_review')
+		 RETURNING j.id::text, j.participant_id::text`,
+		consumerRefundPct, arbiterID, disputeID,
+	).Scan(&jobID, &consumerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDisputeNotOpen
+	}
+	if err != nil {
+		return fmt.Errorf("resolve dispute %s: update: %w", disputeID, err)
+	}
+	if err := postLedger(ctx, tx, ledgerDisputeRefund, disputeID, jobID, []LedgerEntry{
+		{Account: AccountPlatformFee, AmountCents: -refundCents},
+		{Account: AccountRefund, ParticipantID: consumerID, AmountCents: refundCents},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("resolve dispute %s: commit: %w", disputeID, err)
+	}
+	return nil
+}
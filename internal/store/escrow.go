@@ -0,0 +1,528 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Payment statuses of an escrowed job (jobs.payment_status, migrations 038
+// and 048). A job submitted without escrow has none.
+const (
+	PaymentAwaiting   = "awaiting_payment"
+	PaymentAuthorized = "authorized"
+	PaymentCaptured   = "captured"
+	PaymentReleased   = "released"
+	// PaymentSettlementFailed: the escrow settler gave up capturing or
+	// releasing the hold (maxEscrowSettleAttempts). Nothing was captured.
+	PaymentSettlementFailed = "settlement_failed"
+)
+
+// maxEscrowSettleAttempts is how many failed captures or releases of one
+// hold the escrow settler makes before marking it PaymentSettlementFailed:
+// a provider that refused this many times — most often because the
+// authorization lapsed — will not accept the next tick's try either.
+const maxEscrowSettleAttempts = 5
+
+// FailureCausePaymentNotAuthorized marks an escrowed job whose consumer never
+// authorized its payment (migration 038).
+const FailureCausePaymentNotAuthorized = "payment_not_authorized"
+
+// JobPayment is the escrow state of a consumer's job.
+type JobPayment struct {
+	JobID           string
+	Status          string // "" when the job is not escrowed
+	PaymentIntentID string // "" until AttachJobPayment
+	AmountCents     int64  // the quoted maximum held
+	PaymentError    string // why the last authorization attempt failed, if it did
+}
+
+// GetJobPayment returns participantID's job jobID's escrow state, or
+// ErrJobNotFound.
+func GetJobPayment(ctx context.Context, db *DB, jobID, participantID string) (JobPayment, error) {
+	p := JobPayment{JobID: jobID}
+	err := db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(payment_status, ''), COALESCE(payment_intent_id, ''), amount_cents,
+		        COALESCE(payment_error, '')
+		 FROM jobs
+		 WHERE id = $1 AND participant_id = $2 AND parent_job_id IS NULL`,
+		jobID, participantID,
+	).Scan(&p.Status, &p.PaymentIntentID, &p.AmountCents, &p.PaymentError)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return JobPayment{}, ErrJobNotFound
+		}
+		return JobPayment{}, fmt.Errorf("get job payment %s: %w", jobID, err)
+	}
+	return p, nil
+}
+
+// AttachJobPayment records paymentIntentID, holding amountCents, as the
+// escrow of awaiting job jobID and, for a replica group, of each replica, so
+// a dispute opened against a replica copies it. amountCents replaces the
+// quote on the job: the hold may be larger (payment.MinChargeCents), and
+// settlement captures against what is actually held. Returns false when the
+// job is not awaiting payment or already has an intent.
+func AttachJobPayment(ctx context.Context, db *DB, jobID, paymentIntentID string, amountCents int64) (bool, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE jobs
+		 SET payment_intent_id = $2,
+		     amount_cents      = CASE WHEN id = $1 THEN $4 ELSE amount_cents END,
+		     updated_at        = NOW()
+		 WHERE (id = $1 OR parent_job_id = $1)
+		   AND payment_status = $3
+		   AND payment_intent_id IS NULL`,
+		jobID, paymentIntentID, PaymentAwaiting, amountCents,
+	)
+	if err != nil {
+		return false, fmt.Errorf("attach job payment %s: %w", jobID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// AuthorizeJobPayment marks the job escrowed by paymentIntentID — and its
+// replicas — authorized, which makes it dispatchable. Called once Stripe
+// reports the intent requires_capture. A job that already failed (its
+// payment window expired) stays awaiting; the escrow settler releases the
+// hold. Returns false when no awaiting job matched, so repeated
+// notifications are harmless.
+func AuthorizeJobPayment(ctx context.Context, db *DB, paymentIntentID string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE jobs
+		 SET payment_status = $2, payment_error = NULL, updated_at = NOW()
+		 WHERE payment_intent_id = $1
+		   AND payment_status = $3
+		   AND status <> 'failed'::job_status`,
+		paymentIntentID, PaymentAuthorized, PaymentAwaiting,
+	)
+	if err != nil {
+		return false, fmt.Errorf("authorize job payment %s: %w", paymentIntentID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RecordJobPaymentFailure keeps message, Stripe's reason an attempt to
+// authorize the hold paymentIntentID failed, on the awaiting job it escrows
+// for its payment page. The consumer can try again until the payment window
+// closes; AuthorizeJobPayment clears it. Returns false when no awaiting job
+// matched.
+func RecordJobPaymentFailure(ctx context.Context, db *DB, paymentIntentID, message string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE jobs
+		 SET payment_error = $2, updated_at = NOW()
+		 WHERE payment_intent_id = $1
+		   AND parent_job_id IS NULL
+		   AND payment_status = $3`,
+		paymentIntentID, message, PaymentAwaiting,
+	)
+	if err != nil {
+		return false, fmt.Errorf("record job payment failure %s: %w", paymentIntentID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// EscrowSettlement is an escrowed job (a single job, or a replica group's
+// parent) that has finished and whose hold can be settled.
+type EscrowSettlement struct {
+	JobID           string
+	PaymentIntentID string
+	PaymentStatus   string // PaymentAwaiting or PaymentAuthorized
+	AmountCents     int64  // the authorized maximum
+	MeteredCents    int64  // metered cost of the job, replicas included
+}
+
+// CaptureCents is how much of the hold to capture: the metered cost, never
+// more than was authorized. Zero means release the hold instead — nothing
+// was authorized, nothing was metered, or the cost is below minCents (the
+// smallest charge the payment provider accepts), which is waived.
+func (s EscrowSettlement) CaptureCents(minCents int64) int64 {
+	if s.PaymentStatus != PaymentAuthorized || s.MeteredCents < minCents {
+		return 0
+	}
+	return min(s.MeteredCents, s.AmountCents)
+}
+
+// settleableEscrow is the condition on escrowed job j that it has reached a
+// final status with every billable run metered, so its settlement can be
+// posted to the ledger.
+const settleableEscrow = `j.status IN ('completed'::job_status, 'failed'::job_status, 'disputed'::job_status)
+		   AND NOT EXISTS (
+		       SELECT 1 FROM jobs x
+		       LEFT JOIN job_metering m ON m.job_id = x.id
+		       WHERE (x.id = j.id OR x.parent_job_id = j.id)
+		         AND x.node_id IS NOT NULL
+		         AND m.job_id IS NULL
+		         AND (x.status = 'completed'::job_status
+		              OR (x.cancelled_at IS NOT NULL AND x.started_at IS NOT NULL))
+		   )`
+
+// EscrowsToSettle returns up to 100 escrowed jobs that have reached a final
+// status — completed, failed (cancelled, expired, timed out, …) or disputed —
+// with every billable run metered: each completed run, and each cancelled run
+// that started. A failed run that was not cancelled is not billed.
+func EscrowsToSettle(ctx context.Context, db *DB) ([]EscrowSettlement, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT j.id::text, j.payment_intent_id, j.payment_status, j.amount_cents,
+		        COALESCE((SELECT SUM(m.consumer_paid_cents)
+		                  FROM job_metering m
+		                  JOIN jobs x ON x.id = m.job_id
+		                  WHERE x.id = j.id OR x.parent_job_id = j.id), 0)
+		 FROM jobs j
+		 WHERE j.parent_job_id IS NULL
+		   AND j.payment_intent_id IS NOT NULL
+		   AND j.payment_status IN ($1, $2)
+		   AND `+settleableEscrow+`
+		 ORDER BY j.completed_at
+		 LIMIT 100`,
+		PaymentAwaiting, PaymentAuthorized,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("escrows to settle: %w", err)
+	}
+	defer rows.Close()
+	var out []EscrowSettlement
+	for rows.Next() {
+		var s EscrowSettlement
+		if err := rows.Scan(&s.JobID, &s.PaymentIntentID, &s.PaymentStatus, &s.AmountCents, &s.MeteredCents); err != nil {
+			return nil, fmt.Errorf("escrows to settle: scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// MarkEscrowCaptured records that capturedCents of job jobID's hold were
+// captured as chargeID, and posts the settlement to the ledger. The
+// uncaptured remainder went back to the consumer with the capture and is
+// recorded as the job's refund. Replicas follow the parent's status.
+func MarkEscrowCaptured(ctx context.Context, db *DB, jobID string, capturedCents int64, chargeID string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("mark escrow captured %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE jobs
+		 SET payment_status = $2,
+		     captured_cents = CASE WHEN id = $1 THEN $3::bigint END,
+		     charge_id      = CASE WHEN id = $1 THEN $4 END,
+		     refund_cents   = CASE WHEN id = $1 THEN GREATEST(amount_cents - $3, 0) ELSE refund_cents END,
+		     refunded_at    = CASE WHEN id = $1 THEN NOW() ELSE refunded_at END,
+		     updated_at     = NOW()
+		 WHERE (id = $1 OR parent_job_id = $1) AND payment_status = $5`,
+		jobID, PaymentCaptured, capturedCents, chargeID, PaymentAuthorized,
+	)
+	if err != nil {
+		return fmt.Errorf("mark escrow captured %s: %w", jobID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if err := postEscrowSettlement(ctx, tx, jobID, capturedCents); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("mark escrow captured %s: commit: %w", jobID, err)
+	}
+	return nil
+}
+
+// CaptureEscrowByIntent records a capture of the hold paymentIntentID that
+// Stripe reports (payment_intent.succeeded) through MarkEscrowCaptured, for
+// an authorized job ready to settle — most often the settler's own capture,
+// whose bookkeeping write this replays. Returns false when no such job
+// matched: the intent is not an escrow, the capture is already recorded, or
+// the job has not finished, which the reconciler reports.
+func CaptureEscrowByIntent(ctx context.Context, db *DB, paymentIntentID string, capturedCents int64, chargeID string) (bool, error) {
+	jobID, err := escrowByIntent(ctx, db, paymentIntentID,
+		`j.payment_status = $2 AND `+settleableEscrow, PaymentAuthorized)
+	if jobID == "" || err != nil {
+		return false, err
+	}
+	return true, MarkEscrowCaptured(ctx, db, jobID, capturedCents, chargeID)
+}
+
+// ReleaseEscrowByIntent records that the hold paymentIntentID was cancelled
+// (payment_intent.canceled) through MarkEscrowReleased. A hold never
+// authorized is released whatever its job's state: nothing of the job ran,
+// and the orchestrator fails it as unpaid when its payment window closes. An
+// authorized hold is released only once its job is ready to settle. Returns
+// false when no such job matched.
+func ReleaseEscrowByIntent(ctx context.Context, db *DB, paymentIntentID string) (bool, error) {
+	jobID, err := escrowByIntent(ctx, db, paymentIntentID,
+		`(j.payment_status = $2 OR (j.payment_status = $3 AND `+settleableEscrow+`))`,
+		PaymentAwaiting, PaymentAuthorized)
+	if jobID == "" || err != nil {
+		return false, err
+	}
+	return true, MarkEscrowReleased(ctx, db, jobID)
+}
+
+// escrowByIntent returns the id of the job — a replica group's parent — whose
+// hold is paymentIntentID and which meets cond on jobs j, or "". cond's
+// placeholders start at $2.
+func escrowByIntent(ctx context.Context, db *DB, paymentIntentID, cond string, args ...any) (string, error) {
+	var jobID string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT j.id::text FROM jobs j
+		 WHERE j.payment_intent_id = $1 AND j.parent_job_id IS NULL AND `+cond,
+		append([]any{paymentIntentID}, args...)...,
+	).Scan(&jobID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("escrow by intent %s: %w", paymentIntentID, err)
+	}
+	return jobID, nil
+}
+
+// RecordEscrowSettleFailure counts a failed capture or release of job
+// jobID's hold, keeping cause as its settle_error. At maxEscrowSettleAttempts
+// the job — with its replicas — becomes PaymentSettlementFailed, which
+// EscrowsToSettle no longer returns and FailedEscrowSettlements lists for
+// staff. Returns true when this failure was the last.
+func RecordEscrowSettleFailure(ctx context.Context, db *DB, jobID string, cause error) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("record escrow settle failure %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var attempts int
+	err = tx.QueryRow(ctx,
+		`UPDATE jobs
+		 SET settle_attempts = settle_attempts + 1,
+		     settle_error    = $2,
+		     updated_at      = NOW()
+		 WHERE id = $1 AND payment_status IN ($3, $4)
+		 RETURNING settle_attempts`,
+		jobID, cause.Error(), PaymentAuthorized, PaymentAwaiting,
+	).Scan(&attempts)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("record escrow settle failure %s: %w", jobID, err)
+	}
+	gaveUp := attempts >= maxEscrowSettleAttempts
+	if gaveUp {
+		if _, err := tx.Exec(ctx,
+			`UPDATE jobs
+			 SET payment_status = $2, updated_at = NOW()
+			 WHERE (id = $1 OR parent_job_id = $1) AND payment_status IN ($3, $4)`,
+			jobID, PaymentSettlementFailed, PaymentAuthorized, PaymentAwaiting,
+		); err != nil {
+			return false, fmt.Errorf("record escrow settle failure %s: mark failed: %w", jobID, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("record escrow settle failure %s: commit: %w", jobID, err)
+	}
+	return gaveUp, nil
+}
+
+// FailedEscrowSettlement is an escrowed job the settler gave up on.
+type FailedEscrowSettlement struct {
+	JobID           string
+	PaymentIntentID string
+	AmountCents     int64 // the authorized maximum
+	MeteredCents    int64 // metered cost of the job, replicas included
+	Attempts        int
+	LastError       string
+	FailedAt        time.Time
+}
+
+// FailedEscrowSettlements returns up to 100 PaymentSettlementFailed jobs,
+// the most recent first.
+func FailedEscrowSettlements(ctx context.Context, db *DB) ([]FailedEscrowSettlement, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT j.id::text, j.payment_intent_id, j.amount_cents,
+		        COALESCE((SELECT SUM(m.consumer_paid_cents)
+		                  FROM job_metering m
+		                  JOIN jobs x ON x.id = m.job_id
+		                  WHERE x.id = j.id OR x.parent_job_id = j.id), 0),
+		        j.settle_attempts, COALESCE(j.settle_error, ''), j.updated_at
+		 FROM jobs j
+		 WHERE j.parent_job_id IS NULL
+		   AND j.payment_status = $1
+		 ORDER BY j.updated_at DESC
+		 LIMIT 100`,
+		PaymentSettlementFailed,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed escrow settlements: %w", err)
+	}
+	defer rows.Close()
+	var out []FailedEscrowSettlement
+	for rows.Next() {
+		var f FailedEscrowSettlement
+		if err := rows.Scan(&f.JobID, &f.PaymentIntentID, &f.AmountCents, &f.MeteredCents,
+			&f.Attempts, &f.LastError, &f.FailedAt); err != nil {
+			return nil, fmt.Errorf("failed escrow settlements: scan: %w", err)
+		}
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// MarkEscrowReleased records that job jobID's hold was cancelled without a
+// charge, and posts the settlement — any metered cost waived — to the
+// ledger. An authorized hold is recorded as refunded in full.
+func MarkEscrowReleased(ctx context.Context, db *DB, jobID string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("mark escrow released %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE jobs
+		 SET refund_cents   = CASE WHEN id = $1 AND payment_status = $3 THEN amount_cents ELSE refund_cents END,
+		     refunded_at    = CASE WHEN id = $1 AND payment_status = $3 THEN NOW() ELSE refunded_at END,
+		     payment_status = $2,
+		     updated_at     = NOW()
+		 WHERE (id = $1 OR parent_job_id = $1) AND payment_status IN ($3, $4)`,
+		jobID, PaymentReleased, PaymentAuthorized, PaymentAwaiting,
+	)
+	if err != nil {
+		return fmt.Errorf("mark escrow released %s: %w", jobID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if err := postEscrowSettlement(ctx, tx, jobID, 0); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("mark escrow released %s: commit: %w", jobID, err)
+	}
+	return nil
+}
+
+// EscrowTransfer is a metered run of a captured escrow whose contributor
+// share has not yet been transferred to its node owner.
+type EscrowTransfer struct {
+	JobID                   string // the metered run
+	ChargeID                string // the escrow's captured charge
+	ProviderStripeAccountID string
+	ContributorEarnedCents  int64
+	CapturedCents           int64 // captured on the escrow
+	MeteredCents            int64 // metered on the escrow, all runs
+}
+
+// TransferCents is the run's share of the capture owed to its owner: its
+// contributor earnings, scaled down in proportion when the capture was capped
+// below the metered cost.
+func (t EscrowTransfer) TransferCents() int64 {
+	if t.MeteredCents <= t.CapturedCents || t.MeteredCents <= 0 {
+		return t.ContributorEarnedCents
+	}
+	return int64(math.Round(float64(t.ContributorEarnedCents) * float64(t.CapturedCents) / float64(t.MeteredCents)))
+}
+
+// PendingEscrowTransfers returns up to 100 metered runs of captured escrows
+// still owed their contributor transfer. Runs whose owner has no connected
+// account yet, that are flagged suspected_fraud, or that are under an open or
+// under_review dispute wait.
+func PendingEscrowTransfers(ctx context.Context, db *DB) ([]EscrowTransfer, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT x.id::text, g.charge_id, p.stripe_account_id, m.contributor_earned_cents,
+		        g.captured_cents,
+		        (SELECT COALESCE(SUM(mm.consumer_paid_cents), 0)
+		         FROM job_metering mm
+		         JOIN jobs y ON y.id = mm.job_id
+		         WHERE y.id = g.id OR y.parent_job_id = g.id)
+		 FROM jobs g
+		 JOIN jobs x ON x.id = g.id OR x.parent_job_id = g.id
+		 JOIN job_metering m ON m.job_id = x.id
+		 JOIN nodes n ON n.id = x.node_id
+		 JOIN participants p ON p.id = n.participant_id
+		 WHERE g.parent_job_id IS NULL
+		   AND g.payment_status = $1
+		   AND g.charge_id IS NOT NULL
+		   AND m.transfer_id IS NULL
+		   AND m.contributor_earned_cents > 0
+		   AND p.stripe_account_id IS NOT NULL
+		   AND NOT x.suspected_fraud
+		   AND NOT EXISTS (
+		       SELECT 1 FROM disputes d
+		       WHERE d.job_id = x.id AND d.status IN ('open', 'under_review'))
+		 LIMIT 100`,
+		PaymentCaptured,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("pending escrow transfers: %w", err)
+	}
+	defer rows.Close()
+	var out []EscrowTransfer
+	for rows.Next() {
+		var t EscrowTransfer
+		if err := rows.Scan(&t.JobID, &t.ChargeID, &t.ProviderStripeAccountID, &t.ContributorEarnedCents,
+			&t.CapturedCents, &t.MeteredCents); err != nil {
+			return nil, fmt.Errorf("pending escrow transfers: scan: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// MarkEarningsTransferred records transfer transferID of amountCents as
+// metered run jobID's contributor share. EligiblePayouts pays out only runs
+// of escrowed jobs with a transfer recorded.
+func MarkEarningsTransferred(ctx context.Context, db *DB, jobID, transferID string, amountCents int64) error {
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE job_metering
+		 SET transfer_id = $2, transferred_cents = $3
+		 WHERE job_id = $1 AND transfer_id IS NULL`,
+		jobID, transferID, amountCents,
+	); err != nil {
+		return fmt.Errorf("mark earnings transferred %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// DisputeRefundBasis returns what the consumer paid for job jobID — the most
+// a dispute against it can refund. For a job without escrow that is
+// amount_cents. For an escrowed job it is the capture (a replica's own
+// metered cost, within its group's capture); settled is false while the hold
+// is not yet captured or released, and nothing can be refunded yet.
+func DisputeRefundBasis(ctx context.Context, db *DB, jobID string) (cents int64, settled bool, err error) {
+	var (
+		status           *string
+		amount, captured int64
+		replicaPaid      int64
+		isReplica        bool
+	)
+	err = db.Pool.QueryRow(ctx,
+		`SELECT g.payment_status, j.amount_cents, COALESCE(g.captured_cents, 0),
+		        COALESCE(m.consumer_paid_cents, 0), j.parent_job_id IS NOT NULL
+		 FROM jobs j
+		 JOIN jobs g ON g.id = COALESCE(j.parent_job_id, j.id)
+		 LEFT JOIN job_metering m ON m.job_id = j.id
+		 WHERE j.id = $1`,
+		jobID,
+	).Scan(&status, &amount, &captured, &replicaPaid, &isReplica)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, ErrJobNotFound
+		}
+		return 0, false, fmt.Errorf("dispute refund basis %s: %w", jobID, err)
+	}
+	switch {
+	case status == nil:
+		return amount, true, nil
+	case *status == PaymentReleased, *status == PaymentSettlementFailed:
+		return 0, true, nil
+	case *status != PaymentCaptured:
+		return 0, false, nil
+	case isReplica:
+		return min(replicaPaid, captured), true, nil
+	}
+	return captured, true, nil
+}
@@ -0,0 +1,70 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// TestRecordEscrowSettleFailure_GivesUp fails an authorized hold's
+// settlement until the settler gives up, and checks the job leaves the
+// settlement queue for the staff list.
+func TestRecordEscrowSettleFailure_GivesUp(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	jobID := seedMeteringJob(t, db, "escrow_settle_failed@test.com", 1.0)
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE jobs SET payment_status = $2, payment_intent_id = 'pi_lapsed', amount_cents = 5000
+		 WHERE id = $1`,
+		jobID, store.PaymentAuthorized,
+	); err != nil {
+		t.Fatalf("escrow job: %v", err)
+	}
+
+	cause := errors.New("authorization expired")
+	for i := 1; ; i++ {
+		gaveUp, err := store.RecordEscrowSettleFailure(ctx, db, jobID, cause)
+		if err != nil {
+			t.Fatalf("RecordEscrowSettleFailure #%d: %v", i, err)
+		}
+		if gaveUp {
+			if i != 5 {
+				t.Errorf("gave up after %d failures, want 5", i)
+			}
+			break
+		}
+		if i > 5 {
+			t.Fatal("never gave up")
+		}
+	}
+
+	var status string
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT payment_status FROM jobs WHERE id = $1`, jobID,
+	).Scan(&status); err != nil || status != store.PaymentSettlementFailed {
+		t.Errorf("payment_status = %q, %v; want %q", status, err, store.PaymentSettlementFailed)
+	}
+	failed, err := store.FailedEscrowSettlements(ctx, db)
+	if err != nil {
+		t.Fatalf("FailedEscrowSettlements: %v", err)
+	}
+	var listed bool
+	for _, f := range failed {
+		if f.JobID == jobID {
+			listed = f.Attempts == 5 && f.LastError == cause.Error() && f.PaymentIntentID == "pi_lapsed"
+		}
+	}
+	if !listed {
+		t.Errorf("job %s not listed as failed with 5 attempts: %+v", jobID, failed)
+	}
+
+	// Settled for good: a further failure changes nothing.
+	if gaveUp, err := store.RecordEscrowSettleFailure(ctx, db, jobID, cause); err != nil || gaveUp {
+		t.Errorf("after giving up: RecordEscrowSettleFailure = %v, %v; want false, nil", gaveUp, err)
+	}
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+)
+
+// RunEscrowSettler runs in a goroutine and settles escrowed jobs every
+// interval: each finished job's hold is captured at its metered cost (see
+// EscrowSettlement.CaptureCents) or released, then each captured run's
+// contributor share is transferred to its owner's connected account. Stripe
+// calls are keyed on the job id, so a retry after a failed bookkeeping write
+// replays the original operation instead of repeating it. Errors
+// per-candidate are logged and skipped; a hold the provider refuses to
+// settle maxEscrowSettleAttempts times is left to staff
+// (RecordEscrowSettleFailure).
+func RunEscrowSettler(ctx context.Context, db *DB, pc payment.PaymentProvider, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			settleEscrows(ctx, db, pc)
+			transferEscrowEarnings(ctx, db, pc)
+		}
+	}
+}
+
+// settleEscrows captures or releases the hold of each finished escrowed job.
+func settleEscrows(ctx context.Context, db *DB, pc payment.PaymentProvider) {
+	settlements, err := EscrowsToSettle(ctx, db)
+	if err != nil {
+		slog.Warn("escrow settler: EscrowsToSettle error", "error", err)
+		return
+	}
+	for _, s := range settlements {
+		capture := s.CaptureCents(payment.MinChargeCents)
+		if capture == 0 {
+			if err := pc.ReleaseEscrow(ctx, s.PaymentIntentID, s.JobID); err != nil {
+				slog.Warn("escrow settler: ReleaseEscrow failed", "job_id", s.JobID, "error", err)
+				recordSettleFailure(ctx, db, s, err)
+				continue
+			}
+			if err := MarkEscrowReleased(ctx, db, s.JobID); err != nil {
+				slog.Warn("escrow settler: failed to mark released", "job_id", s.JobID, "error", err)
+			}
+			continue
+		}
+
+		chargeID, err := pc.CaptureEscrow(ctx, s.PaymentIntentID, capture, s.JobID)
+		if err != nil {
+			slog.Warn("escrow settler: CaptureEscrow failed",
+				"job_id", s.JobID, "capture_cents", capture, "error", err)
+			recordSettleFailure(ctx, db, s, err)
+			continue
+		}
+		if err := MarkEscrowCaptured(ctx, db, s.JobID, capture, chargeID); err != nil {
+			slog.Warn("escrow settler: failed to mark captured", "job_id", s.JobID, "error", err)
+			continue
+		}
+		slog.Info("escrow captured", "job_id", s.JobID,
+			"captured_cents", capture, "released_cents", s.AmountCents-capture)
+	}
+}
+
+// recordSettleFailure counts a refused capture or release of s's hold and
+// says so loudly when the settler gives up on it.
+func recordSettleFailure(ctx context.Context, db *DB, s EscrowSettlement, cause error) {
+	gaveUp, err := RecordEscrowSettleFailure(ctx, db, s.JobID, cause)
+	if err != nil {
+		slog.Warn("escrow settler: failed to record settle failure", "job_id", s.JobID, "error", err)
+		return
+	}
+	if gaveUp {
+		slog.Error("escrow settler: giving up on hold; listed on /admin/reconciliation",
+			"job_id", s.JobID, "payment_intent_id", s.PaymentIntentID,
+			"metered_cents", s.MeteredCents, "error", cause)
+	}
+}
+
+// transferEscrowEarnings moves each captured run's contributor share to its
+// owner's connected account, from which RunPayoutReleaser pays it out.
+func transferEscrowEarnings(ctx context.Context, db *DB, pc payment.PaymentProvider) {
+	transfers, err := PendingEscrowTransfers(ctx, db)
+	if err != nil {
+		slog.Warn("escrow settler: PendingEscrowTransfers error", "error", err)
+		return
+	}
+	for _, t := range transfers {
+		amount := t.TransferCents()
+		if amount <= 0 {
+			// Scaled down to nothing: record it so the run is not
+			// selected again. EligiblePayouts skips a zero transfer.
+			if err := MarkEarningsTransferred(ctx, db, t.JobID, "", 0); err != nil {
+				slog.Warn("escrow settler: failed to mark transferred", "job_id", t.JobID, "error", err)
+			}
+			continue
+		}
+		transferID, err := pc.TransferEarnings(ctx, t.ProviderStripeAccountID, amount, t.ChargeID, t.JobID)
+		if err != nil {
+			slog.Warn("escrow settler: TransferEarnings failed",
+				"job_id", t.JobID, "stripe_account", t.ProviderStripeAccountID, "error", err)
+			continue
+		}
+		if err := MarkEarningsTransferred(ctx, db, t.JobID, transferID, amount); err != nil {
+			slog.Warn("escrow settler: failed to mark transferred", "job_id", t.JobID, "error", err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package store
+
+import "testing"
+
+func TestEscrowSettlementCaptureCents(t *testing.T) {
+	cases := []struct {
+		name string
+		s    EscrowSettlement
+		want int64
+	}{
+		{"metered within hold", EscrowSettlement{PaymentStatus: PaymentAuthorized, AmountCents: 500, MeteredCents: 320}, 320},
+		{"metered above hold is capped", EscrowSettlement{PaymentStatus: PaymentAuthorized, AmountCents: 500, MeteredCents: 740}, 500},
+		{"below minimum is waived", EscrowSettlement{PaymentStatus: PaymentAuthorized, AmountCents: 500, MeteredCents: 49}, 0},
+		{"nothing metered", EscrowSettlement{PaymentStatus: PaymentAuthorized, AmountCents: 500}, 0},
+		{"never authorized", EscrowSettlement{PaymentStatus: PaymentAwaiting, AmountCents: 500, MeteredCents: 320}, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.s.CaptureCents(50); got != tc.want {
+				t.Errorf("CaptureCents = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEscrowTransferTransferCents(t *testing.T) {
+	cases := []struct {
+		name string
+		t    EscrowTransfer
+		want int64
+	}{
+		{"full capture", EscrowTransfer{ContributorEarnedCents: 270, CapturedCents: 300, MeteredCents: 300}, 270},
+		{"capped capture scales down", EscrowTransfer{ContributorEarnedCents: 270, CapturedCents: 150, MeteredCents: 300}, 135},
+		{"rounds to nearest cent", EscrowTransfer{ContributorEarnedCents: 100, CapturedCents: 200, MeteredCents: 300}, 67},
+		{"nothing metered", EscrowTransfer{ContributorEarnedCents: 0, CapturedCents: 0, MeteredCents: 0}, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.t.TransferCents(); got != tc.want {
+				t.Errorf("TransferCents = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
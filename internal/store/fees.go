@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FeeTerms is how a consumer's payment is split between the node owner and
+// the platform, in basis points.
+type FeeTerms struct {
+	ContributorShareBps int
+	PlatformFeeBps      int
+
+	// Seq is the fee declaration the terms come from (migration 024); nil
+	// when none has taken effect yet, and the terms are resource_pricing's
+	// contributor_share. A coordinator's first declaration may carry Seq 0.
+	Seq *uint64
+}
+
+// contributorCents is the node owner's share of paidCents under t, rounded
+// to the nearest cent; the platform fee is the remainder.
+func (t FeeTerms) contributorCents(paidCents int64) int64 {
+	return int64(math.Round(float64(paidCents) * float64(t.ContributorShareBps) / 10000.0))
+}
+
+// ActiveFeeTerms returns the fee terms in effect at at: the highest-Seq fee
+// declaration whose EffectiveAt has passed. fee_declarations holds only this
+// coordinator's declarations.
+func ActiveFeeTerms(ctx context.Context, db *DB, at time.Time) (FeeTerms, error) {
+	var (
+		t   FeeTerms
+		seq int64
+	)
+	err := db.Pool.QueryRow(ctx,
+		`SELECT contributor_share_bps, platform_fee_bps, seq
+		 FROM fee_declarations
+		 WHERE effective_at <= $1
+		 ORDER BY seq DESC
+		 LIMIT 1`,
+		at,
+	).Scan(&t.ContributorShareBps, &t.PlatformFeeBps, &seq)
+	if err == nil {
+		s := uint64(seq)
+		t.Seq = &s
+		return t, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return FeeTerms{}, fmt.Errorf("active fee terms: %w", err)
+	}
+
+	_, share, err := ratesAt(ctx, db, at)
+	if err != nil {
+		return FeeTerms{}, fmt.Errorf("active fee terms: read rates: %w", err)
+	}
+	t.ContributorShareBps = int(math.Round(share * 10000))
+	t.PlatformFeeBps = 10000 - t.ContributorShareBps
+	return t, nil
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/artifact"
+)
+
+// ErrInputNotFound is returned when a consumer's upload, or a job's input, is
+// not recorded — or, for an upload, has expired.
+var ErrInputNotFound = errors.New("store: job input not found")
+
+// JobInput is one job_inputs row (migration 034): a file staged read-only
+// into the container as /input/<Name>. Exactly one of SizeBytes (an upload)
+// and URL (fetched by the agent) is meaningful; SHA256 pins either.
+type JobInput struct {
+	Name      string
+	SHA256    string
+	SizeBytes int64
+	URL       string
+}
+
+// InputUpload is one input_uploads row: a file a consumer uploaded ahead of
+// submission, addressed by its digest.
+type InputUpload struct {
+	ParticipantID string
+	SHA256        string
+	ObjectKey     string
+	SizeBytes     int64
+	ExpiresAt     time.Time
+}
+
+// storageUsageSQL sums a consumer's live artifacts and input uploads, which
+// share one quota. $2 and $3 exclude the artifact (job ID) or upload (digest)
+// being replaced; pass "" to exclude nothing.
+const storageUsageSQL = `
+	SELECT ((SELECT COALESCE(SUM(size_bytes), 0)
+	         FROM job_artifacts
+	         WHERE participant_id = $1 AND deleted_at IS NULL AND job_id::text <> $2)
+	      + (SELECT COALESCE(SUM(size_bytes), 0)
+	         FROM input_uploads
+	         WHERE participant_id = $1 AND deleted_at IS NULL AND sha256 <> $3))::bigint`
+
+// RecordInputUpload records an uploaded input, refreshing the expiry of an
+// earlier upload of the same bytes. Like RecordArtifact, the quota is
+// re-checked under a lock on the consumer's participants row; returns
+// ErrArtifactQuotaExceeded when u.SizeBytes does not fit.
+func RecordInputUpload(ctx context.Context, db *DB, u InputUpload, quotaBytes int64) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("record input upload %s: begin: %w", u.SHA256, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	if _, err := tx.Exec(ctx,
+		`SELECT 1 FROM participants WHERE id = $1 FOR UPDATE`, u.ParticipantID,
+	); err != nil {
+		return fmt.Errorf("record input upload %s: lock participant: %w", u.SHA256, err)
+	}
+	var used int64
+	if err := tx.QueryRow(ctx, storageUsageSQL, u.ParticipantID, "", u.SHA256).Scan(&used); err != nil {
+		return fmt.Errorf("record input upload %s: usage: %w", u.SHA256, err)
+	}
+	if used+u.SizeBytes > quotaBytes {
+		return ErrArtifactQuotaExceeded
+	}
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO input_uploads (participant_id, sha256, object_key, size_bytes, expires_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (participant_id, sha256) DO UPDATE
+		 SET object_key = EXCLUDED.object_key,
+		     size_bytes = EXCLUDED.size_bytes,
+		     created_at = NOW(),
+		     expires_at = EXCLUDED.expires_at,
+		     deleted_at = NULL`,
+		u.ParticipantID, u.SHA256, u.ObjectKey, u.SizeBytes, u.ExpiresAt,
+	); err != nil {
+		return fmt.Errorf("record input upload %s: upsert: %w", u.SHA256, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("record input upload %s: commit: %w", u.SHA256, err)
+	}
+	return nil
+}
+
+// LiveInputUpload returns participantID's unexpired upload of the file with
+// digest sha256, or ErrInputNotFound.
+func LiveInputUpload(ctx context.Context, db *DB, participantID, sha256 string) (InputUpload, error) {
+	var u InputUpload
+	err := db.Pool.QueryRow(ctx,
+		`SELECT participant_id::text, sha256, object_key, size_bytes, expires_at
+		 FROM input_uploads
+		 WHERE participant_id = $1 AND sha256 = $2
+		   AND deleted_at IS NULL AND expires_at > NOW()`,
+		participantID, sha256,
+	).Scan(&u.ParticipantID, &u.SHA256, &u.ObjectKey, &u.SizeBytes, &u.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return InputUpload{}, ErrInputNotFound
+		}
+		return InputUpload{}, fmt.Errorf("input upload %s: %w", sha256, err)
+	}
+	return u, nil
+}
+
+// JobInputs returns jobID's inputs ordered by name.
+func JobInputs(ctx context.Context, db *DB, jobID string) ([]JobInput, error) {
+	inputs, err := jobInputs(ctx, db, []string{jobID})
+	if err != nil {
+		return nil, err
+	}
+	return inputs[jobID], nil
+}
+
+// jobInputs returns the inputs of each of jobIDs, keyed by job ID.
+func jobInputs(ctx context.Context, db *DB, jobIDs []string) (map[string][]JobInput, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT job_id::text, name, sha256, COALESCE(size_bytes, 0), COALESCE(source_url, '')
+		 FROM job_inputs
+		 WHERE job_id = ANY($1)
+		 ORDER BY job_id, name`,
+		jobIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("job inputs: query: %w", err)
+	}
+	defer rows.Close()
+
+	inputs := map[string][]JobInput{}
+	for rows.Next() {
+		var (
+			jobID string
+			in    JobInput
+		)
+		if err := rows.Scan(&jobID, &in.Name, &in.SHA256, &in.SizeBytes, &in.URL); err != nil {
+			return nil, fmt.Errorf("job inputs: scan: %w", err)
+		}
+		inputs[jobID] = append(inputs[jobID], in)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("job inputs: rows: %w", err)
+	}
+	return inputs, nil
+}
+
+// JobInputUpload returns the upload backing jobID's input name, for the node
+// fetching it. Returns ErrInputNotFound when the job has no such uploaded
+// input or the upload has been reaped.
+func JobInputUpload(ctx context.Context, db *DB, jobID, name string) (InputUpload, error) {
+	var u InputUpload
+	err := db.Pool.QueryRow(ctx,
+		`SELECT u.participant_id::text, u.sha256, u.object_key, u.size_bytes, u.expires_at
+		 FROM job_inputs i
+		 JOIN jobs j          ON j.id = i.job_id
+		 JOIN input_uploads u ON u.participant_id = j.participant_id AND u.sha256 = i.sha256
+		 WHERE i.job_id = $1 AND i.name = $2
+		   AND i.source_url IS NULL AND u.deleted_at IS NULL`,
+		jobID, name,
+	).Scan(&u.ParticipantID, &u.SHA256, &u.ObjectKey, &u.SizeBytes, &u.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return InputUpload{}, ErrInputNotFound
+		}
+		return InputUpload{}, fmt.Errorf("job input %s/%s: %w", jobID, name, err)
+	}
+	return u, nil
+}
+
+// reapInputUploads deletes expired input uploads from st and marks their rows
+// deleted, like reapArtifacts. An upload still referenced by a job that has
+// not finished is kept: its node may not have fetched it yet, and a declined
+// job's next node will need it again.
+func reapInputUploads(ctx context.Context, db *DB, st artifact.Store) error {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT u.participant_id::text, u.sha256, u.object_key
+		 FROM input_uploads u
+		 WHERE u.deleted_at IS NULL AND u.expires_at <= NOW()
+		   AND NOT EXISTS (
+		       SELECT 1 FROM job_inputs i
+		       JOIN jobs j ON j.id = i.job_id
+		       WHERE j.participant_id = u.participant_id AND i.sha256 = u.sha256
+		         AND i.source_url IS NULL
+		         AND j.status IN ('pending'::job_status, 'scheduled'::job_status,
+		                          'dispatched'::job_status, 'awaiting_confirmation'::job_status,
+		                          'declined'::job_status, 'running'::job_status)
+		   )
+		 ORDER BY u.expires_at
+		 LIMIT $1`,
+		artifactReapBatch,
+	)
+	if err != nil {
+		return fmt.Errorf("reap input uploads: query: %w", err)
+	}
+	type expired struct{ participantID, sha256, key string }
+	var batch []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.participantID, &e.sha256, &e.key); err != nil {
+			rows.Close()
+			return fmt.Errorf("reap input uploads: scan: %w", err)
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reap input uploads: rows: %w", err)
+	}
+
+	for _, e := range batch {
+		if err := st.Delete(ctx, e.key); err != nil {
+			slog.Warn("artifact reaper: delete input failed", "participant_id", e.participantID, "sha256", e.sha256, "error", err)
+			continue
+		}
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE input_uploads SET deleted_at = NOW() WHERE participant_id = $1 AND sha256 = $2`,
+			e.participantID, e.sha256,
+		); err != nil {
+			slog.Warn("artifact reaper: mark input deleted failed", "participant_id", e.participantID, "sha256", e.sha256, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Ledger accounts (migration 039). An entry's amount is money moving into
+// the account; an account's balance is what it holds. The per-participant
+// accounts are keyed by the consumer or node owner as well.
+const (
+	AccountConsumerWallet     = "consumer_wallet"     // per consumer: minus what they have paid in
+	AccountEscrow             = "escrow"              // consumer money held, not yet earned
+	AccountPlatformFee        = "platform_fee"        // fee revenue
+	AccountContributorPayable = "contributor_payable" // per node owner: owed to them now
+	AccountPayout             = "payout"              // per node owner: paid out
+	AccountRefund             = "refund"              // per consumer: refunded
+)
+
+// Ledger transaction kinds. Each is posted once per ref (a job, or a dispute
+// for ledgerDisputeRefund).
+const (
+	ledgerMetering         = "metering"
+	ledgerEscrowSettlement = "escrow_settlement"
+	ledgerRefund           = "refund"
+	ledgerDisputeRefund    = "dispute_refund"
+	ledgerPayout           = "payout"
+	ledgerPayoutReversal   = "payout_reversal"
+)
+
+// LedgerEntry is one leg of a ledger transaction. ParticipantID is "" for the
+// escrow and platform_fee accounts.
+type LedgerEntry struct {
+	Account       string
+	ParticipantID string
+	AmountCents   int64
+}
+
+// postLedger records a ledger transaction of kind for ref inside tx, so it
+// commits or rolls back with the write it mirrors. Zero legs are dropped; the
+// rest must sum to zero, which the database checks again at commit.
+// Idempotent: a transaction already posted for (kind, ref) is left as is.
+func postLedger(ctx context.Context, tx pgx.Tx, kind, ref, jobID string, entries []LedgerEntry) error {
+	var sum int64
+	for _, e := range entries {
+		sum += e.AmountCents
+	}
+	if sum != 0 {
+		return fmt.Errorf("post ledger %s %s: legs sum to %d", kind, ref, sum)
+	}
+
+	var txnID string
+	err := tx.QueryRow(ctx,
+		`INSERT INTO ledger_transactions (kind, ref, job_id)
+		 VALUES ($1, $2, NULLIF($3, '')::uuid)
+		 ON CONFLICT (kind, ref) DO NOTHING
+		 RETURNING id`,
+		kind, ref, jobID,
+	).Scan(&txnID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("post ledger %s %s: insert transaction: %w", kind, ref, err)
+	}
+
+	for _, e := range entries {
+		if e.AmountCents == 0 {
+			continue
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO ledger_entries (transaction_id, account, participant_id, amount_cents)
+			 VALUES ($1, $2, NULLIF($3, '')::uuid, $4)`,
+			txnID, e.Account, e.ParticipantID, e.AmountCents,
+		); err != nil {
+			return fmt.Errorf("post ledger %s %s: insert %s entry: %w", kind, ref, e.Account, err)
+		}
+	}
+	return nil
+}
+
+// meteringEntries are the legs of a run's metering: its cost leaves escrow
+// as the platform fee and its owner's earnings. chargeCents, the up-front
+// charge of a job without escrow, is recognized alongside.
+func meteringEntries(consumerID, ownerID string, paidCents, earnedCents, feeCents, chargeCents int64) []LedgerEntry {
+	return []LedgerEntry{
+		{Account: AccountConsumerWallet, ParticipantID: consumerID, AmountCents: -chargeCents},
+		{Account: AccountEscrow, AmountCents: chargeCents - paidCents},
+		{Account: AccountPlatformFee, AmountCents: feeCents},
+		{Account: AccountContributorPayable, ParticipantID: ownerID, AmountCents: earnedCents},
+	}
+}
+
+// meteredRun is one metered run of an escrow, for its settlement.
+type meteredRun struct {
+	ownerID     string
+	paidCents   int64
+	earnedCents int64
+}
+
+// settlementEntries are the legs of an escrow's settlement: capturedCents
+// moves from the consumer into escrow, and whatever of the metered cost was
+// not captured — a capped capture, or all of it when the hold was released —
+// is written back out of escrow from each owner's earnings (by the amount
+// EscrowTransfer.TransferCents withholds) and the platform fee.
+func settlementEntries(consumerID string, runs []meteredRun, capturedCents int64) []LedgerEntry {
+	var metered int64
+	for _, r := range runs {
+		metered += r.paidCents
+	}
+	entries := []LedgerEntry{
+		{Account: AccountConsumerWallet, ParticipantID: consumerID, AmountCents: -capturedCents},
+		{Account: AccountEscrow, AmountCents: capturedCents},
+	}
+	shortfall := metered - capturedCents
+	if shortfall <= 0 {
+		return entries
+	}
+
+	entries = append(entries, LedgerEntry{Account: AccountEscrow, AmountCents: shortfall})
+	feeShortfall := shortfall
+	for _, r := range runs {
+		t := EscrowTransfer{ContributorEarnedCents: r.earnedCents, CapturedCents: capturedCents, MeteredCents: metered}
+		withheld := r.earnedCents - t.TransferCents()
+		feeShortfall -= withheld
+		entries = append(entries, LedgerEntry{Account: AccountContributorPayable, ParticipantID: r.ownerID, AmountCents: -withheld})
+	}
+	return append(entries, LedgerEntry{Account: AccountPlatformFee, AmountCents: -feeShortfall})
+}
+
+// postEscrowSettlement posts the settlement of escrowed job jobID, captured
+// at capturedCents (zero for a release), inside tx.
+func postEscrowSettlement(ctx context.Context, tx pgx.Tx, jobID string, capturedCents int64) error {
+	var consumerID string
+	if err := tx.QueryRow(ctx,
+		`SELECT participant_id::text FROM jobs WHERE id = $1`, jobID,
+	).Scan(&consumerID); err != nil {
+		return fmt.Errorf("post escrow settlement %s: read consumer: %w", jobID, err)
+	}
+
+	rows, err := tx.Query(ctx,
+		`SELECT n.participant_id::text, m.consumer_paid_cents, m.contributor_earned_cents
+		 FROM jobs x
+		 JOIN job_metering m ON m.job_id = x.id
+		 JOIN nodes n ON n.id = x.node_id
+		 WHERE x.id = $1 OR x.parent_job_id = $1`,
+		jobID,
+	)
+	if err != nil {
+		return fmt.Errorf("post escrow settlement %s: read metering: %w", jobID, err)
+	}
+	var runs []meteredRun
+	for rows.Next() {
+		var r meteredRun
+		if err := rows.Scan(&r.ownerID, &r.paidCents, &r.earnedCents); err != nil {
+			rows.Close()
+			return fmt.Errorf("post escrow settlement %s: scan metering: %w", jobID, err)
+		}
+		runs = append(runs, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("post escrow settlement %s: rows: %w", jobID, err)
+	}
+
+	return postLedger(ctx, tx, ledgerEscrowSettlement, jobID, jobID,
+		settlementEntries(consumerID, runs, capturedCents))
+}
+
+// LedgerBalance returns the balance of account, for participantID on the
+// per-participant accounts ("" for escrow and platform_fee).
+func LedgerBalance(ctx context.Context, db *DB, account, participantID string) (int64, error) {
+	var balance int64
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount_cents), 0)
+		 FROM ledger_entries
+		 WHERE account = $1 AND participant_id IS NOT DISTINCT FROM NULLIF($2, '')::uuid`,
+		account, participantID,
+	).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("ledger balance %s %s: %w", account, participantID, err)
+	}
+	return balance, nil
+}
+
+// ContributorPayable returns what the coordinator owes node owner
+// participantID right now: their earnings less payouts and escrow
+// shortfalls.
+func ContributorPayable(ctx context.Context, db *DB, participantID string) (int64, error) {
+	return LedgerBalance(ctx, db, AccountContributorPayable, participantID)
+}
+
+// AccountBalance is one participant's balance on a ledger account.
+type AccountBalance struct {
+	ParticipantID string
+	BalanceCents  int64
+}
+
+// LedgerBalances returns every non-zero participant balance on account,
+// largest first — e.g. everything owed to contributors, from
+// contributor_payable.
+func LedgerBalances(ctx context.Context, db *DB, account string) ([]AccountBalance, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT COALESCE(participant_id::text, ''), SUM(amount_cents) AS balance
+		 FROM ledger_entries
+		 WHERE account = $1
+		 GROUP BY participant_id
+		 HAVING SUM(amount_cents) <> 0
+		 ORDER BY balance DESC`,
+		account,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ledger balances %s: %w", account, err)
+	}
+	defer rows.Close()
+	var out []AccountBalance
+	for rows.Next() {
+		var b AccountBalance
+		if err := rows.Scan(&b.ParticipantID, &b.BalanceCents); err != nil {
+			return nil, fmt.Errorf("ledger balances %s: scan: %w", account, err)
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// LedgerViolation is one broken ledger invariant found by CheckLedger.
+type LedgerViolation struct {
+	Check  string // which invariant
+	Ref    string // the ledger transaction, job or participant concerned
+	Detail string
+}
+
+// ledgerChecks are CheckLedger's invariants: each query returns (ref, detail)
+// for every violation.
+var ledgerChecks = []struct {
+	name  string
+	query string
+}{
+	{"unbalanced_transaction", `
+		SELECT transaction_id::text, 'legs sum to ' || SUM(amount_cents)
+		FROM ledger_entries
+		GROUP BY transaction_id
+		HAVING SUM(amount_cents) <> 0`},
+	{"unposted_metering", `
+		SELECT m.job_id::text, 'metered ' || m.consumer_paid_cents || ' cents with no metering transaction'
+		FROM job_metering m
+		WHERE NOT EXISTS (SELECT 1 FROM ledger_transactions t
+		                  WHERE t.kind = 'metering' AND t.ref = m.job_id::text)`},
+	{"unposted_payout", `
+		SELECT m.job_id::text, 'payout released with no payout transaction'
+		FROM job_metering m
+		WHERE m.payout_released_at IS NOT NULL
+		  AND m.payout_batch_id IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM ledger_transactions t
+		                  WHERE t.kind = 'payout' AND t.ref = m.job_id::text)
+		UNION ALL
+		SELECT b.id::text, 'payout batch ' || b.status || ' with no payout transaction'
+		FROM payout_batches b
+		WHERE b.status <> 'pending'
+		  AND NOT EXISTS (SELECT 1 FROM ledger_transactions t
+		                  WHERE t.kind = 'payout' AND t.ref = b.id::text)`},
+	{"unposted_payout_reversal", `
+		SELECT b.id::text, 'payout batch failed with no reversal transaction'
+		FROM payout_batches b
+		WHERE b.status = 'failed'
+		  AND NOT EXISTS (SELECT 1 FROM ledger_transactions t
+		                  WHERE t.kind = 'payout_reversal' AND t.ref = b.id::text)`},
+	{"payout_mismatch", `
+		SELECT m.job_id::text, 'paid out ' || COALESCE(m.transferred_cents, m.contributor_earned_cents) ||
+		       ', ledger records ' || SUM(e.amount_cents)
+		FROM job_metering m
+		JOIN ledger_transactions t ON t.kind = 'payout' AND t.ref = m.job_id::text
+		JOIN ledger_entries e ON e.transaction_id = t.id AND e.account = 'payout'
+		GROUP BY m.job_id, m.transferred_cents, m.contributor_earned_cents
+		HAVING SUM(e.amount_cents) <> COALESCE(m.transferred_cents, m.contributor_earned_cents)
+		UNION ALL
+		SELECT b.id::text, 'paid out ' || b.amount_cents || ', ledger records ' || SUM(e.amount_cents)
+		FROM payout_batches b
+		JOIN ledger_transactions t ON t.kind = 'payout' AND t.ref = b.id::text
+		JOIN ledger_entries e ON e.transaction_id = t.id AND e.account = 'payout'
+		GROUP BY b.id, b.amount_cents
+		HAVING SUM(e.amount_cents) <> b.amount_cents`},
+	{"batch_mismatch", `
+		SELECT b.id::text, 'batch of ' || b.amount_cents || ', its runs total ' ||
+		       COALESCE(SUM(COALESCE(m.transferred_cents, m.contributor_earned_cents)), 0)
+		FROM payout_batches b
+		LEFT JOIN job_metering m ON m.payout_batch_id = b.id
+		WHERE b.status <> 'failed'
+		GROUP BY b.id, b.amount_cents
+		HAVING COALESCE(SUM(COALESCE(m.transferred_cents, m.contributor_earned_cents)), 0) <> b.amount_cents`},
+	{"unposted_escrow_settlement", `
+		SELECT j.id::text, 'escrow ' || j.payment_status || ' with no settlement transaction'
+		FROM jobs j
+		WHERE j.parent_job_id IS NULL
+		  AND j.payment_status IN ('captured', 'released')
+		  AND NOT EXISTS (SELECT 1 FROM ledger_transactions t
+		                  WHERE t.kind = 'escrow_settlement' AND t.ref = j.id::text)`},
+	{"negative_balance", `
+		SELECT participant_id::text, account || ' balance ' || SUM(amount_cents)
+		FROM ledger_entries
+		WHERE account IN ('payout', 'refund')
+		GROUP BY account, participant_id
+		HAVING SUM(amount_cents) < 0`},
+}
+
+// CheckLedger verifies the ledger's invariants: every transaction balances,
+// every metering, released payout and settled escrow has been posted, each
+// posted payout matches what was paid, each failed payout is reversed, each
+// payout batch matches its runs, and nothing has been paid out or refunded
+// in the negative. Returns every violation found; none is a clean ledger.
+func CheckLedger(ctx context.Context, db *DB) ([]LedgerViolation, error) {
+	var out []LedgerViolation
+	for _, c := range ledgerChecks {
+		rows, err := db.Pool.Query(ctx, c.query)
+		if err != nil {
+			return nil, fmt.Errorf("check ledger %s: %w", c.name, err)
+		}
+		for rows.Next() {
+			v := LedgerViolation{Check: c.name}
+			if err := rows.Scan(&v.Ref, &v.Detail); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("check ledger %s: scan: %w", c.name, err)
+			}
+			out = append(out, v)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("check ledger %s: rows: %w", c.name, err)
+		}
+	}
+	return out, nil
+}
+
+// RunLedgerChecker runs in a goroutine and runs CheckLedger every interval,
+// logging each violation.
+func RunLedgerChecker(ctx context.Context, db *DB, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			violations, err := CheckLedger(ctx, db)
+			if err != nil {
+				slog.Warn("ledger checker: CheckLedger error", "error", err)
+				continue
+			}
+			for _, v := range violations {
+				slog.Error("ledger invariant violated", "check", v.Check, "ref", v.Ref, "detail", v.Detail)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+func TestLedger_MeteringAndPayout(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	jobID := seedMeteringJob(t, db, "ledger_payout@test.com", 2.0)
+	var ownerID string
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT n.participant_id FROM jobs j JOIN nodes n ON n.id = j.node_id WHERE j.id = $1`, jobID,
+	).Scan(&ownerID); err != nil {
+		t.Fatalf("read owner: %v", err)
+	}
+
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	// Idempotent: a second metering posts nothing more.
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering (again): %v", err)
+	}
+	var earned int64
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT contributor_earned_cents FROM job_metering WHERE job_id = $1`, jobID,
+	).Scan(&earned); err != nil {
+		t.Fatalf("read metering: %v", err)
+	}
+
+	payable, err := store.ContributorPayable(ctx, db, ownerID)
+	if err != nil {
+		t.Fatalf("ContributorPayable: %v", err)
+	}
+	if payable != earned {
+		t.Errorf("payable after metering = %d, want %d", payable, earned)
+	}
+
+	// Make the run eligible: charged, past the dispute window, and the owner
+	// onboarded.
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE jobs SET amount_cents = $2, completed_at = NOW() - INTERVAL '25 hours' WHERE id = $1`,
+		jobID, earned+1,
+	); err != nil {
+		t.Fatalf("age job: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE participants SET stripe_account_id = 'acct_ledger_payout' WHERE id = $1`, ownerID,
+	); err != nil {
+		t.Fatalf("set stripe account: %v", err)
+	}
+
+	if _, err := store.BatchPayouts(ctx, db, time.Now()); err != nil {
+		t.Fatalf("BatchPayouts: %v", err)
+	}
+	history, err := store.PayoutHistory(ctx, db, ownerID, 10)
+	if err != nil {
+		t.Fatalf("PayoutHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].AmountCents != earned || len(history[0].Lines) != 1 ||
+		history[0].Lines[0].JobID != jobID {
+		t.Fatalf("PayoutHistory = %+v, want one batch of %d paying job %s", history, earned, jobID)
+	}
+	batch := history[0]
+	if batch.Status != store.PayoutBatchPending {
+		t.Errorf("batch status = %q, want %q", batch.Status, store.PayoutBatchPending)
+	}
+	// The batched run is not batched again.
+	if _, err := store.BatchPayouts(ctx, db, time.Now().Add(48*time.Hour)); err != nil {
+		t.Fatalf("BatchPayouts (again): %v", err)
+	}
+	if again, _ := store.PayoutHistory(ctx, db, ownerID, 10); len(again) != 1 {
+		t.Errorf("batches after a second BatchPayouts = %d, want 1", len(again))
+	}
+
+	if err := store.MarkPayoutBatchReleased(ctx, db, batch.ID, "po_ledger"); err != nil {
+		t.Fatalf("MarkPayoutBatchReleased: %v", err)
+	}
+	if err := store.MarkPayoutBatchReleased(ctx, db, batch.ID, "po_ledger"); err != nil {
+		t.Fatalf("MarkPayoutBatchReleased (again): %v", err)
+	}
+	if payable, _ := store.ContributorPayable(ctx, db, ownerID); payable != 0 {
+		t.Errorf("payable after payout = %d, want 0", payable)
+	}
+	if paid, _ := store.LedgerBalance(ctx, db, store.AccountPayout, ownerID); paid != earned {
+		t.Errorf("paid out = %d, want %d", paid, earned)
+	}
+
+	violations, err := store.CheckLedger(ctx, db)
+	if err != nil {
+		t.Fatalf("CheckLedger: %v", err)
+	}
+	for _, v := range violations {
+		if v.Ref == jobID || v.Ref == ownerID || v.Ref == batch.ID {
+			t.Errorf("ledger violation for this job: %+v", v)
+		}
+	}
+}
+
+func TestLedger_AppendOnly(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	jobID := seedMeteringJob(t, db, "ledger_append@test.com", 1.0)
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE ledger_entries SET amount_cents = amount_cents + 1
+		 WHERE transaction_id IN (SELECT id FROM ledger_transactions WHERE ref = $1)`, jobID,
+	); err == nil {
+		t.Error("UPDATE of ledger_entries succeeded, want the append-only trigger to reject it")
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`DELETE FROM ledger_transactions WHERE ref = $1`, jobID,
+	); err == nil {
+		t.Error("DELETE of ledger_transactions succeeded, want the append-only trigger to reject it")
+	}
+}
@@ -0,0 +1,76 @@
+package store
+
+import "testing"
+
+func ledgerSum(entries []LedgerEntry) int64 {
+	var sum int64
+	for _, e := range entries {
+		sum += e.AmountCents
+	}
+	return sum
+}
+
+func accountTotal(entries []LedgerEntry, account, participantID string) int64 {
+	var sum int64
+	for _, e := range entries {
+		if e.Account == account && e.ParticipantID == participantID {
+			sum += e.AmountCents
+		}
+	}
+	return sum
+}
+
+func TestMeteringEntries(t *testing.T) {
+	entries := meteringEntries("consumer", "owner", 1000, 850, 150, 1200)
+	if sum := ledgerSum(entries); sum != 0 {
+		t.Fatalf("legs sum to %d, want 0", sum)
+	}
+	if got := accountTotal(entries, AccountEscrow, ""); got != 200 {
+		t.Errorf("escrow = %d, want 200 (1200 charged - 1000 metered)", got)
+	}
+	if got := accountTotal(entries, AccountContributorPayable, "owner"); got != 850 {
+		t.Errorf("contributor payable = %d, want 850", got)
+	}
+}
+
+func TestSettlementEntries(t *testing.T) {
+	runs := []meteredRun{
+		{ownerID: "a", paidCents: 600, earnedCents: 510},
+		{ownerID: "b", paidCents: 400, earnedCents: 340},
+	}
+	cases := []struct {
+		name         string
+		captured     int64
+		wantEscrow   int64 // net escrow movement
+		wantPayableA int64
+		wantPayableB int64
+		wantFee      int64
+	}{
+		{"full capture", 1000, 1000, 0, 0, 0},
+		{"capped capture", 500, 1000, -255, -170, -75},
+		{"waived", 0, 1000, -510, -340, -150},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries := settlementEntries("consumer", runs, tc.captured)
+			if sum := ledgerSum(entries); sum != 0 {
+				t.Fatalf("legs sum to %d, want 0", sum)
+			}
+			if got := accountTotal(entries, AccountConsumerWallet, "consumer"); got != -tc.captured {
+				t.Errorf("consumer wallet = %d, want %d", got, -tc.captured)
+			}
+			if got := accountTotal(entries, AccountEscrow, ""); got != tc.wantEscrow {
+				t.Errorf("escrow = %d, want %d", got, tc.wantEscrow)
+			}
+			if got := accountTotal(entries, AccountContributorPayable, "a"); got != tc.wantPayableA {
+				t.Errorf("payable a = %d, want %d", got, tc.wantPayableA)
+			}
+			if got := accountTotal(entries, AccountContributorPayable, "b"); got != tc.wantPayableB {
+				t.Errorf("payable b = %d, want %d", got, tc.wantPayableB)
+			}
+			if got := accountTotal(entries, AccountPlatformFee, ""); got != tc.wantFee {
+				t.Errorf("platform fee = %d, want %d", got, tc.wantFee)
+			}
+		})
+	}
+}
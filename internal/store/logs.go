@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// JobLogCapBytes caps the container output stored per job (migration 035).
+// The agent applies the same cap before shipping (agent.MaxLogBytes).
+const JobLogCapBytes = 1 << 20
+
+// ErrJobLogNotFound is returned by ConsumerLogJob when the consumer has no
+// such job (or replica).
+var ErrJobLogNotFound = errors.New("store: job not found for logs")
+
+// LogChunk is one job_log_chunks row: a run of redacted output from one of
+// the container's streams.
+type LogChunk struct {
+	Seq    int64
+	Stream string
+	Data   string
+}
+
+// AppendJobLogs stores chunks for jobID, skipping any seq already stored (a
+// retried batch). Output past JobLogCapBytes is dropped and the job marked
+// log_truncated; the chunk crossing the cap is cut short. The job row is
+// locked so concurrent batches cannot together overrun the cap. Returns
+// whether the job's log is truncated.
+func AppendJobLogs(ctx context.Context, db *DB, jobID string, chunks []LogChunk) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("append job logs %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var (
+		used      int64
+		truncated bool
+	)
+	if err := tx.QueryRow(ctx,
+		`SELECT log_bytes, log_truncated FROM jobs WHERE id = $1 FOR UPDATE`, jobID,
+	).Scan(&used, &truncated); err != nil {
+		return false, fmt.Errorf("append job logs %s: lock job: %w", jobID, err)
+	}
+
+	for _, c := range chunks {
+		room := JobLogCapBytes - used
+		if room <= 0 {
+			truncated = true
+			break
+		}
+		// TEXT holds neither NUL nor invalid UTF-8.
+		data := strings.ToValidUTF8(strings.ReplaceAll(c.Data, "\x00", ""), "\uFFFD")
+		if int64(len(data)) > room {
+			data = truncateUTF8(data, int(room))
+			truncated = true
+		}
+		ct, err := tx.Exec(ctx,
+			`INSERT INTO job_log_chunks (job_id, seq, stream, data)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (job_id, seq) DO NOTHING`,
+			jobID, c.Seq, c.Stream, data,
+		)
+		if err != nil {
+			return false, fmt.Errorf("append job logs %s: insert seq %d: %w", jobID, c.Seq, err)
+		}
+		if ct.RowsAffected() == 1 {
+			used += int64(len(data))
+		}
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE jobs SET log_bytes = $2, log_truncated = $3 WHERE id = $1`,
+		jobID, used, truncated,
+	); err != nil {
+		return false, fmt.Errorf("append job logs %s: update usage: %w", jobID, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("append job logs %s: commit: %w", jobID, err)
+	}
+	return truncated, nil
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// JobLogs returns up to limit of jobID's chunks with seq above afterSeq, in
+// order. Pass -1 to start from the beginning.
+func JobLogs(ctx context.Context, db *DB, jobID string, afterSeq int64, limit int) ([]LogChunk, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT seq, stream, data
+		 FROM job_log_chunks
+		 WHERE job_id = $1 AND seq > $2
+		 ORDER BY seq
+		 LIMIT $3`,
+		jobID, afterSeq, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("job logs %s: query: %w", jobID, err)
+	}
+	defer rows.Close()
+
+	var chunks []LogChunk
+	for rows.Next() {
+		var c LogChunk
+		if err := rows.Scan(&c.Seq, &c.Stream, &c.Data); err != nil {
+			return nil, fmt.Errorf("job logs %s: scan: %w", jobID, err)
+		}
+		chunks = append(chunks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("job logs %s: rows: %w", jobID, err)
+	}
+	return chunks, nil
+}
+
+// LogJob is the job whose logs a consumer is reading, with the state the
+// log tail needs.
+type LogJob struct {
+	JobID     string
+	Status    string
+	Truncated bool
+}
+
+// ConsumerLogJob resolves the job whose logs participantID may read for
+// jobID. For a replica group parent (031), which runs no container itself,
+// that is the replica numbered replica; otherwise replica is ignored.
+// Returns ErrJobLogNotFound when there is no such job of the participant's.
+func ConsumerLogJob(ctx context.Context, db *DB, jobID, participantID string, replica int) (LogJob, error) {
+	var j LogJob
+	err := db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(c.id, p.id)::text,
+		        COALESCE(c.status, p.status)::text,
+		        COALESCE(c.log_truncated, p.log_truncated)
+		 FROM jobs p
+		 LEFT JOIN jobs c ON c.parent_job_id = p.id AND c.replica_index = $3
+		 WHERE p.id = $1 AND p.participant_id = $2
+		   AND (c.id IS NOT NULL
+		        OR NOT EXISTS (SELECT 1 FROM jobs s WHERE s.parent_job_id = p.id))`,
+		jobID, participantID, replica,
+	).Scan(&j.JobID, &j.Status, &j.Truncated)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return LogJob{}, ErrJobLogNotFound
+		}
+		return LogJob{}, fmt.Errorf("consumer log job %s: %w", jobID, err)
+	}
+	return j, nil
+}
@@ -2,48 +2,215 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 )
 
+// ErrMeteringNotFound is returned by GetJobMetering when the job has no
+// job_metering row (not completed, or metering not yet computed).
+var ErrMeteringNotFound = errors.New("store: job metering not found")
+
+// meteringMaxSampleGap is the widest interval between two consecutive
+// telemetry samples that still counts as measured. The agent emits every 30s;
+// a longer gap means samples were lost, and that stretch is billed at the
+// job's requested resources instead of being interpolated.
+const meteringMaxSampleGap = 90 * time.Second
+
+// Usage sources recorded on job_metering.usage_source (migration 030).
+const (
+	UsageSourceRequested = "requested"
+	UsageSourceTelemetry = "telemetry"
+)
+
+// MeteringLine is one billed resource on a job_metering record. Lines are
+// persisted as the breakdown JSONB and their Cents sum exactly to
+// consumer_paid_cents.
+type MeteringLine struct {
+	ResourceType string  `json:"resource_type"`
+	Quantity     float64 `json:"quantity"`
+	Rate         float64 `json:"rate"`
+	Multiplier   float64 `json:"multiplier"`
+	Cents        int64   `json:"cents"`
+}
+
+// JobMetering is a job_metering row with its decoded breakdown.
+type JobMetering struct {
+	JobID                  string
+	CPUCoreHours           float64
+	RAMGBHours             float64
+	StorageGBMonths        float64
+	GPUVRAMGBHours         float64
+	EgressGB               float64
+	UsageSource            string
+	ConsumerPaidCents      int64
+	ContributorEarnedCents int64
+	PlatformFeeCents       int64
+	Breakdown              []MeteringLine
+	ComputedAt             time.Time
+
+	// FeeSeq is the fee declaration the split was made under (migration
+	// 043); nil for resource_pricing's contributor_share.
+	FeeSeq *uint64
+}
+
+// meteredUsage is the billable quantity of each resource for one job run.
+type meteredUsage struct {
+	cpuCoreHours    float64
+	ramGBHours      float64
+	storageGBMonths float64
+	gpuVRAMGBHours  float64
+	egressGB        float64
+	source          string
+}
+
+// usageBasis carries what integrateUsage needs besides the samples: the
+// requested reservation (the billing ceiling) and the node totals that turn
+// host-wide utilisation percentages into absolute cores / GB.
+type usageBasis struct {
+	startedAt, completedAt time.Time
+	reqCores               float64
+	reqRAMGB               float64
+	reqStorageGB           float64
+	reqVRAMGB              float64
+	nodeCores              float64
+	nodeRAMGB              float64
+	// nodeBandwidthMbps is the node's registered link speed, the ceiling on
+	// the bandwidth its telemetry may bill; 0 bills no egress.
+	nodeBandwidthMbps int
+}
+
+// ResourceRequest is the resources a job reserves on its node, as submitted.
+// Zero CPUCores, RAMMB or StorageGB fall back to the node (see
+// nodePricing.basis); GPUVRAMGB counts only with GPURequired.
+type ResourceRequest struct {
+	CPUCores    int
+	RAMMB       int
+	StorageGB   int
+	GPURequired bool
+	GPUVRAMGB   int
+}
+
+// nodePricing is what a node contributes to pricing a job placed on it: its
+// hardware totals and its default resource profile's fallbacks and price
+// multiplier.
+type nodePricing struct {
+	cpuEnabled      bool
+	ramPct          int
+	storageGB       int
+	priceMultiplier float64
+	hwCores         int
+	hwRAMMB         int64
+	hwBandwidthMbps int
+}
+
+// basis returns the usage basis (without the run window) for req on the
+// node: the request, with the node's hardware and default profile standing
+// in for columns a legacy job left unset.
+func (n nodePricing) basis(req ResourceRequest) usageBasis {
+	b := usageBasis{
+		nodeCores: float64(n.hwCores),
+		nodeRAMGB: float64(n.hwRAMMB) / 1024.0,
+
+		nodeBandwidthMbps: n.hwBandwidthMbps,
+	}
+	if n.cpuEnabled {
+		b.reqCores = float64(req.CPUCores)
+		if req.CPUCores <= 0 {
+			b.reqCores = b.nodeCores
+		}
+	}
+	b.reqRAMGB = float64(req.RAMMB) / 1024.0
+	if req.RAMMB <= 0 {
+		b.reqRAMGB = b.nodeRAMGB * float64(n.ramPct) / 100.0
+	}
+	b.reqStorageGB = float64(req.StorageGB)
+	if req.StorageGB <= 0 {
+		b.reqStorageGB = float64(n.storageGB)
+	}
+	if req.GPURequired {
+		b.reqVRAMGB = float64(req.GPUVRAMGB)
+	}
+	return b
+}
+
 // ComputeMetering calculates resource consumption and earnings for a completed
 // job and writes a record to job_metering. It is idempotent — calling it twice
 // for the same job is safe. Returns nil if the job is not found or not yet
 // completed (started_at / completed_at not set).
+//
+// Quantities come from the job's own request (jobs.cpu_cores / ram_mb /
+// storage_gb / gpu_vram_gb), falling back to the node's hardware and default
+// resource profile only for columns a legacy job left unset. Where verified
+// job_telemetry samples cover the run, measured CPU and RAM replace the
+// request for the covered stretch — capped at the request, so they can only
+// lower a bill. Reported bandwidth is integrated into egress_gb. Only a
+// version 2 telemetry signature covers it (agent.VerifyTelemetry; a version 1
+// sample is accepted only with zero bandwidth), and each sample is clamped to
+// [0, the node's registered bandwidth], so a node with none registered bills
+// no egress. handleTelemetry rejects samples outside that range.
+//
+// A job submitted with a price quote (migration 042) is priced at the rates
+// in effect when it was quoted, and at most its quoted multiplier.
+//
+// The consumer's payment is split under the fee declaration in effect when
+// the job started (ActiveFeeTerms), never one published later, and its Seq is
+// recorded as job_metering.fee_seq (migration 043).
+//
+// The metering is posted to the ledger in the same transaction (migration
+// 039), recognizing the up-front charge of a job without escrow with it.
 func ComputeMetering(ctx context.Context, db *DB, jobID string) error {
 	var (
 		startedAt, completedAt time.Time
-		cpuEnabled             bool
-		ramPct, storageGB      int
-		cpuCores               int
-		ramMB                  int64
-		priceMultiplier        float64
+		consumerID, ownerID    string
+		chargeCents            int64
+		quotedAt               *time.Time
+		req                    ResourceRequest
+		n                      nodePricing
 	)
 
 	err := db.Pool.QueryRow(ctx, `
 		SELECT j.started_at, j.completed_at,
+		       j.participant_id::text, n.participant_id::text,
+		       CASE WHEN j.payment_status IS NULL AND j.payment_intent_id IS NOT NULL
+		            THEN j.amount_cents ELSE 0 END,
 		       COALESCE(rp.cpu_enabled, true),
 		       COALESCE(rp.ram_pct, 100),
 		       COALESCE(rp.storage_gb, 0),
-		       COALESCE(rp.price_multiplier, 1.0),
-		       hw.cpu_cores, hw.ram_mb
+		       LEAST(COALESCE(rp.price_multiplier, 1.0), j.quoted_multiplier),
+		       j.quoted_at,
+		       COALESCE(j.cpu_cores, 0), COALESCE(j.ram_mb, 0),
+		       COALESCE(j.storage_gb, 0), COALESCE(j.gpu_vram_gb, 0),
+		       j.gpu_required,
+		       hw.cpu_cores, hw.ram_mb, hw.bandwidth_mbps
 		FROM jobs j
 		JOIN nodes n ON n.id = j.node_id
 		LEFT JOIN resource_profiles rp ON rp.node_id = n.id AND rp.is_default = TRUE
 		CROSS JOIN LATERAL (
-		    SELECT (n.hardware_profile->>'CPUCores')::int    AS cpu_cores,
-		           (n.hardware_profile->>'RAMMB')::bigint    AS ram_mb
+		    -- Claim writes snake_case keys; register/legacy rows use Go field
+		    -- names. Accept either.
+		    SELECT COALESCE((n.hardware_profile->>'cpu_cores')::int,
+		                    (n.hardware_profile->>'CPUCores')::int, 0)    AS cpu_cores,
+		           COALESCE((n.hardware_profile->>'ram_mb')::bigint,
+		                    (n.hardware_profile->>'RAMMB')::bigint, 0)    AS ram_mb,
+		           COALESCE((n.hardware_profile->>'bandwidth_mbps')::int,
+		                    (n.hardware_profile->>'BandwidthMbps')::int, 0) AS bandwidth_mbps
 		) hw
 		WHERE j.id = $1
 		  AND j.started_at IS NOT NULL
 		  AND j.completed_at IS NOT NULL`,
 		jobID,
 	).Scan(&startedAt, &completedAt,
-		&cpuEnabled, &ramPct, &storageGB,
-		&priceMultiplier, &cpuCores, &ramMB)
+		&consumerID, &ownerID, &chargeCents,
+		&n.cpuEnabled, &n.ramPct, &n.storageGB,
+		&n.priceMultiplier, &quotedAt,
+		&req.CPUCores, &req.RAMMB, &req.StorageGB, &req.GPUVRAMGB,
+		&req.GPURequired,
+		&n.hwCores, &n.hwRAMMB, &n.hwBandwidthMbps)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil
@@ -51,23 +218,87 @@ func ComputeMetering(ctx context.Context, db *DB, jobID string) error {
 		return err
 	}
 
-	durationHours := completedAt.Sub(startedAt).Hours()
+	b := n.basis(req)
+	b.startedAt, b.completedAt = startedAt, completedAt
+
+	samples, err := JobTelemetry(ctx, db, jobID, time.Time{})
+	if err != nil {
+		return err
+	}
+	usage := integrateUsage(b, samples)
 
-	var cpuCoreHours float64
-	if cpuEnabled {
-		cpuCoreHours = float64(cpuCores) * durationHours
+	pricedAt := time.Now()
+	if quotedAt != nil {
+		pricedAt = *quotedAt
+	}
+	rates, _, err := ratesAt(ctx, db, pricedAt)
+	if err != nil {
+		return err
+	}
+	terms, err := ActiveFeeTerms(ctx, db, startedAt)
+	if err != nil {
+		return fmt.Errorf("compute metering %s: %w", jobID, err)
+	}
+	breakdown, consumerPaidCents := priceUsage(usage, rates, n.priceMultiplier)
+	breakdownJSON, err := json.Marshal(breakdown)
+	if err != nil {
+		return fmt.Errorf("compute metering %s: marshal breakdown: %w", jobID, err)
+	}
+
+	contributorEarnedCents := terms.contributorCents(consumerPaidCents)
+	platformFeeCents := consumerPaidCents - contributorEarnedCents
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("compute metering %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO job_metering
+		    (job_id, cpu_core_hours, ram_gb_hours, storage_gb_months,
+		     gpu_vram_gb_hours, egress_gb, usage_source, breakdown,
+		     consumer_paid_cents, contributor_earned_cents, platform_fee_cents,
+		     fee_seq)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (job_id) DO NOTHING`,
+		jobID, usage.cpuCoreHours, usage.ramGBHours, usage.storageGBMonths,
+		usage.gpuVRAMGBHours, usage.egressGB, usage.source, breakdownJSON,
+		consumerPaidCents, contributorEarnedCents, platformFeeCents,
+		terms.Seq,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if err := postLedger(ctx, tx, ledgerMetering, jobID, jobID,
+		meteringEntries(consumerID, ownerID, consumerPaidCents, contributorEarnedCents, platformFeeCents, chargeCents),
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("compute metering %s: commit: %w", jobID, err)
 	}
-	ramGBHours := float64(ramMB) / 1024.0 * float64(ramPct) / 100.0 * durationHours
-	storageGBMonths := float64(storageGB) / 730.0 * durationHours
+	return nil
+}
 
-	// Fetch current platform rates.
+// ratesAt returns the platform's rate for each resource type and the
+// contributor share of what the consumer pays in effect at at, from
+// resource_pricing. The share only applies before the first fee declaration
+// takes effect; see ActiveFeeTerms.
+func ratesAt(ctx context.Context, db *DB, at time.Time) (map[string]float64, float64, error) {
 	rateRows, err := db.Pool.Query(ctx, `
 		SELECT resource_type, base_rate, contributor_share
 		FROM resource_pricing
-		WHERE (effective_until IS NULL OR effective_until > NOW())
-		ORDER BY resource_type, effective_from DESC`)
+		WHERE effective_from <= $1
+		  AND (effective_until IS NULL OR effective_until > $1)
+		ORDER BY resource_type, effective_from DESC`,
+		at,
+	)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 	defer rateRows.Close()
 
@@ -77,7 +308,7 @@ func ComputeMetering(ctx context.Context, db *DB, jobID string) error {
 		var rt string
 		var rate, share float64
 		if err := rateRows.Scan(&rt, &rate, &share); err != nil {
-			return err
+			return nil, 0, err
 		}
 		if _, seen := rates[rt]; !seen {
 			rates[rt] = rate
@@ -87,25 +318,142 @@ func ComputeMetering(ctx context.Context, db *DB, jobID string) error {
 		}
 	}
 	if err := rateRows.Err(); err != nil {
-		return err
+		return nil, 0, err
 	}
+	return rates, contributorShare, nil
+}
 
-	totalCost := (cpuCoreHours*rates["cpu_core_hr"] +
-		ramGBHours*rates["ram_gb_hr"] +
-		storageGBMonths*rates["storage_gb_mo"]) * priceMultiplier
+// priceUsage prices each non-zero quantity of usage at rates × multiplier,
+// rounding each line to the cent, and returns the lines with their total.
+func priceUsage(usage meteredUsage, rates map[string]float64, multiplier float64) ([]MeteringLine, int64) {
+	quantities := []struct {
+		resourceType string
+		quantity     float64
+	}{
+		{"cpu_core_hr", usage.cpuCoreHours},
+		{"ram_gb_hr", usage.ramGBHours},
+		{"storage_gb_mo", usage.storageGBMonths},
+		{"gpu_vram_gb_hr", usage.gpuVRAMGBHours},
+		{"egress_gb", usage.egressGB},
+	}
+	lines := make([]MeteringLine, 0, len(quantities))
+	var total int64
+	for _, q := range quantities {
+		if q.quantity <= 0 {
+			continue
+		}
+		line := MeteringLine{
+			ResourceType: q.resourceType,
+			Quantity:     q.quantity,
+			Rate:         rates[q.resourceType],
+			Multiplier:   multiplier,
+		}
+		line.Cents = int64(math.Round(line.Quantity * line.Rate * line.Multiplier * 100))
+		total += line.Cents
+		lines = append(lines, line)
+	}
+	return lines, total
+}
 
-	consumerPaidCents := int64(math.Round(totalCost * 100))
-	contributorEarnedCents := int64(math.Round(float64(consumerPaidCents) * contributorShare))
-	platformFeeCents := consumerPaidCents - contributorEarnedCents
+// integrateUsage turns a run's wall-clock window and its telemetry samples into
+// billable quantities. Each pair of consecutive samples no more than
+// meteringMaxSampleGap apart is a measured interval: CPU and RAM are the
+// trapezoid of the two samples' utilisation (host-wide percent × node total,
+// capped at the request) and egress is the trapezoid of bandwidth, each
+// sample clamped to [0, the node's registered bandwidth]. All time
+// not inside a measured interval is billed at the request. Storage and GPU
+// VRAM are reservations and always bill the request for the full run.
+func integrateUsage(b usageBasis, samples []TelemetrySample) meteredUsage {
+	duration := b.completedAt.Sub(b.startedAt)
+	if duration < 0 {
+		duration = 0
+	}
+	u := meteredUsage{
+		storageGBMonths: b.reqStorageGB / 730.0 * duration.Hours(),
+		gpuVRAMGBHours:  b.reqVRAMGB * duration.Hours(),
+		source:          UsageSourceRequested,
+	}
 
-	_, err = db.Pool.Exec(ctx, `
-		INSERT INTO job_metering
-		    (job_id, cpu_core_hours, ram_gb_hours, storage_gb_months,
-		     consumer_paid_cents, contributor_earned_cents, platform_fee_cents)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		ON CONFLICT (job_id) DO NOTHING`,
-		jobID, cpuCoreHours, ramGBHours, storageGBMonths,
-		consumerPaidCents, contributorEarnedCents, platformFeeCents,
+	cpuBase, ramBase := b.nodeCores, b.nodeRAMGB
+	if cpuBase <= 0 {
+		cpuBase = b.reqCores
+	}
+	if ramBase <= 0 {
+		ramBase = b.reqRAMGB
+	}
+	measuredCPU := func(s TelemetrySample) float64 { return math.Min(b.reqCores, s.CPUPct/100*cpuBase) }
+	measuredRAM := func(s TelemetrySample) float64 { return math.Min(b.reqRAMGB, s.RAMPct/100*ramBase) }
+	measuredMbps := func(s TelemetrySample) float64 {
+		return float64(max(0, min(s.BandwidthMbps, b.nodeBandwidthMbps)))
+	}
+
+	var covered time.Duration
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		// Clamp to the billed window; samples outside it contribute nothing.
+		from, to := prev.SampledAt, cur.SampledAt
+		if from.Before(b.startedAt) {
+			from = b.startedAt
+		}
+		if to.After(b.completedAt) {
+			to = b.completedAt
+		}
+		gap := cur.SampledAt.Sub(prev.SampledAt)
+		if !to.After(from) || gap > meteringMaxSampleGap {
+			continue
+		}
+		span := to.Sub(from)
+		covered += span
+		u.cpuCoreHours += (measuredCPU(prev) + measuredCPU(cur)) / 2 * span.Hours()
+		u.ramGBHours += (measuredRAM(prev) + measuredRAM(cur)) / 2 * span.Hours()
+		// Mbps × seconds → megabits; ÷ 8 → megabytes; ÷ 1000 → GB.
+		u.egressGB += (measuredMbps(prev) + measuredMbps(cur)) / 2 * span.Seconds() / 8 / 1000
+	}
+
+	uncovered := duration - covered
+	if uncovered < 0 {
+		uncovered = 0
+	}
+	u.cpuCoreHours += b.reqCores * uncovered.Hours()
+	u.ramGBHours += b.reqRAMGB * uncovered.Hours()
+	if covered > 0 {
+		u.source = UsageSourceTelemetry
+	}
+	return u
+}
+
+// GetJobMetering returns the job's metering record, including the
+// per-resource breakdown disputes and receipts reference.
+func GetJobMetering(ctx context.Context, db *DB, jobID string) (JobMetering, error) {
+	m := JobMetering{JobID: jobID}
+	var (
+		breakdownJSON []byte
+		feeSeq        *int64
 	)
-	return err
+	err := db.Pool.QueryRow(ctx, `
+		SELECT cpu_core_hours::float8, ram_gb_hours::float8, storage_gb_months::float8,
+		       gpu_vram_gb_hours::float8, egress_gb::float8, usage_source,
+		       consumer_paid_cents, contributor_earned_cents, platform_fee_cents,
+		       fee_seq, breakdown, computed_at
+		FROM job_metering
+		WHERE job_id = $1`,
+		jobID,
+	).Scan(&m.CPUCoreHours, &m.RAMGBHours, &m.StorageGBMonths,
+		&m.GPUVRAMGBHours, &m.EgressGB, &m.UsageSource,
+		&m.ConsumerPaidCents, &m.ContributorEarnedCents, &m.PlatformFeeCents,
+		&feeSeq, &breakdownJSON, &m.ComputedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return JobMetering{}, fmt.Errorf("get job metering %s: %w", jobID, ErrMeteringNotFound)
+		}
+		return JobMetering{}, fmt.Errorf("get job metering %s: %w", jobID, err)
+	}
+	if err := json.Unmarshal(breakdownJSON, &m.Breakdown); err != nil {
+		return JobMetering{}, fmt.Errorf("get job metering %s: decode breakdown: %w", jobID, err)
+	}
+	if feeSeq != nil {
+		s := uint64(*feeSeq)
+		m.FeeSeq = &s
+	}
+	return m, nil
 }
@@ -4,6 +4,7 @@ package store_test
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
@@ -30,7 +31,7 @@ func seedMeteringJob(t *testing.T, db *store.DB, email string, durationHours flo
 	err = db.Pool.QueryRow(ctx,
 		`INSERT INTO nodes (participant_id, hostname, status, node_class, country_code,
 		  hardware_profile, uptime_pct)
-		 VALUES ($1, $2, 'online', 'A', 'US', '{"CPUCores":4,"RAMMB":8192}', 100.0)
+		 VALUES ($1, $2, 'online', 'A', 'US', '{"CPUCores":4,"RAMMB":8192,"BandwidthMbps":100}', 100.0)
 		 RETURNING id`,
 		participantID, "meter-host-"+email,
 	).Scan(&nodeID)
@@ -183,3 +184,205 @@ func TestComputeMetering_PendingJob(t *testing.T) {
 		t.Errorf("expected no job_metering row for pending job, got %d", count)
 	}
 }
+
+func TestComputeMetering_BillsRequestedNotWholeNode(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	// 2-core / 4 GB job on a 4-core / 8 GB node, 2 hours, no telemetry.
+	jobID := seedMeteringJob(t, db, "meter_requested@test.com", 2.0)
+
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	m, err := store.GetJobMetering(ctx, db, jobID)
+	if err != nil {
+		t.Fatalf("GetJobMetering: %v", err)
+	}
+	if m.UsageSource != store.UsageSourceRequested {
+		t.Errorf("usage_source: want %q, got %q", store.UsageSourceRequested, m.UsageSource)
+	}
+	if m.CPUCoreHours < 3.99 || m.CPUCoreHours > 4.01 {
+		t.Errorf("cpu_core_hours: want ~4 (2 cores × 2h), got %v", m.CPUCoreHours)
+	}
+	if m.RAMGBHours < 7.99 || m.RAMGBHours > 8.01 {
+		t.Errorf("ram_gb_hours: want ~8 (4 GB × 2h), got %v", m.RAMGBHours)
+	}
+
+	var sum int64
+	for _, l := range m.Breakdown {
+		sum += l.Cents
+	}
+	if sum != m.ConsumerPaidCents {
+		t.Errorf("breakdown sums to %d, consumer_paid_cents is %d", sum, m.ConsumerPaidCents)
+	}
+}
+
+func TestComputeMetering_TelemetryLowersCPUAndBillsEgress(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	jobID := seedMeteringJob(t, db, "meter_telemetry@test.com", 1.0)
+
+	var startedAt, completedAt time.Time
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT started_at, completed_at FROM jobs WHERE id = $1`, jobID,
+	).Scan(&startedAt, &completedAt); err != nil {
+		t.Fatalf("read job window: %v", err)
+	}
+
+	// One sample every 30s across the whole hour at 25% host CPU (1 of the
+	// node's 4 cores, under the 2-core request) and 80 Mbps egress.
+	for ts := startedAt.Truncate(time.Second); !ts.After(completedAt); ts = ts.Add(30 * time.Second) {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO job_telemetry (job_id, node_id, sampled_at, cpu_pct, ram_pct, bandwidth_mbps)
+			 SELECT id, node_id, $2, 25, 50, 80 FROM jobs WHERE id = $1`,
+			jobID, ts,
+		); err != nil {
+			t.Fatalf("seed telemetry: %v", err)
+		}
+	}
+
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	m, err := store.GetJobMetering(ctx, db, jobID)
+	if err != nil {
+		t.Fatalf("GetJobMetering: %v", err)
+	}
+	if m.UsageSource != store.UsageSourceTelemetry {
+		t.Errorf("usage_source: want %q, got %q", store.UsageSourceTelemetry, m.UsageSource)
+	}
+	if m.CPUCoreHours < 0.95 || m.CPUCoreHours > 1.05 {
+		t.Errorf("cpu_core_hours: want ~1 (measured 1 core × 1h), got %v", m.CPUCoreHours)
+	}
+	// 80 Mbps for ~3600s = 36 GB.
+	if m.EgressGB < 35 || m.EgressGB > 37 {
+		t.Errorf("egress_gb: want ~36, got %v", m.EgressGB)
+	}
+	var sawEgress bool
+	for _, l := range m.Breakdown {
+		if l.ResourceType == "egress_gb" && l.Cents > 0 {
+			sawEgress = true
+		}
+	}
+	if !sawEgress {
+		t.Errorf("expected a non-zero egress_gb line in breakdown, got %+v", m.Breakdown)
+	}
+}
+
+// TestComputeMetering_ClampsEgressToNodeBandwidth verifies unsigned
+// bandwidth above the node's registered 100 Mbps bills as 100 Mbps.
+func TestComputeMetering_ClampsEgressToNodeBandwidth(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	jobID := seedMeteringJob(t, db, "meter_egress_clamp@test.com", 1.0)
+
+	var startedAt, completedAt time.Time
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT started_at, completed_at FROM jobs WHERE id = $1`, jobID,
+	).Scan(&startedAt, &completedAt); err != nil {
+		t.Fatalf("read job window: %v", err)
+	}
+	for ts := startedAt.Truncate(time.Second); !ts.After(completedAt); ts = ts.Add(30 * time.Second) {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO job_telemetry (job_id, node_id, sampled_at, cpu_pct, ram_pct, bandwidth_mbps)
+			 SELECT id, node_id, $2, 25, 50, 100000 FROM jobs WHERE id = $1`,
+			jobID, ts,
+		); err != nil {
+			t.Fatalf("seed telemetry: %v", err)
+		}
+	}
+
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	m, err := store.GetJobMetering(ctx, db, jobID)
+	if err != nil {
+		t.Fatalf("GetJobMetering: %v", err)
+	}
+	// Clamped to 100 Mbps for ~3600s = 45 GB.
+	if m.EgressGB < 44 || m.EgressGB > 46 {
+		t.Errorf("egress_gb: want ~45 (clamped to the node's 100 Mbps), got %v", m.EgressGB)
+	}
+}
+
+func TestComputeMetering_BillsGPUVRAM(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	jobID := seedMeteringJob(t, db, "meter_gpu@test.com", 2.0)
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE jobs SET gpu_required = TRUE, gpu_vram_gb = 8 WHERE id = $1`, jobID,
+	); err != nil {
+		t.Fatalf("set gpu request: %v", err)
+	}
+
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	m, err := store.GetJobMetering(ctx, db, jobID)
+	if err != nil {
+		t.Fatalf("GetJobMetering: %v", err)
+	}
+	if m.GPUVRAMGBHours < 15.99 || m.GPUVRAMGBHours > 16.01 {
+		t.Errorf("gpu_vram_gb_hours: want ~16 (8 GB × 2h), got %v", m.GPUVRAMGBHours)
+	}
+}
+
+func TestComputeMetering_AppliesFeeDeclarationAtStart(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	jobID := seedMeteringJob(t, db, "meter_fees@test.com", 2.0)
+	var startedAt time.Time
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT started_at FROM jobs WHERE id = $1`, jobID,
+	).Scan(&startedAt); err != nil {
+		t.Fatalf("read started_at: %v", err)
+	}
+
+	// Seq 0 took effect before the job started; seq 1, published with a
+	// higher fee, took effect mid-run and must not apply to it.
+	t.Cleanup(func() {
+		db.Pool.Exec(context.Background(), //nolint:errcheck
+			`DELETE FROM fee_declarations WHERE coordinator_id = 'meter-fees-test'`)
+	})
+	for _, d := range []struct {
+		seq, shareBps int
+		effectiveAt   time.Time
+	}{
+		{0, 8000, startedAt.Add(-time.Hour)},
+		{1, 5000, startedAt.Add(time.Minute)},
+	} {
+		if _, err := db.Pool.Exec(ctx,
+			`INSERT INTO fee_declarations
+			    (coordinator_id, contributor_share_bps, platform_fee_bps, effective_at, seq, signature)
+			 VALUES ('meter-fees-test', $1, 10000 - $1, $2, $3, '\x00')`,
+			d.shareBps, d.effectiveAt, d.seq,
+		); err != nil {
+			t.Fatalf("seed fee declaration %d: %v", d.seq, err)
+		}
+	}
+
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	m, err := store.GetJobMetering(ctx, db, jobID)
+	if err != nil {
+		t.Fatalf("GetJobMetering: %v", err)
+	}
+	if m.FeeSeq == nil || *m.FeeSeq != 0 {
+		t.Fatalf("FeeSeq = %v, want seq 0 (in effect at start)", m.FeeSeq)
+	}
+	want := int64(math.Round(float64(m.ConsumerPaidCents) * 0.8))
+	if m.ContributorEarnedCents != want {
+		t.Errorf("contributor_earned_cents = %d, want %d (80%% of %d)",
+			m.ContributorEarnedCents, want, m.ConsumerPaidCents)
+	}
+	if m.ContributorEarnedCents+m.PlatformFeeCents != m.ConsumerPaidCents {
+		t.Errorf("split %d + %d does not sum to %d",
+			m.ContributorEarnedCents, m.PlatformFeeCents, m.ConsumerPaidCents)
+	}
+}
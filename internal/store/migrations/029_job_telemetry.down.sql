@@ -0,0 +1,7 @@
+-- 029_job_telemetry.down.sql
+-- Reverses 029_job_telemetry.up.sql. The retention policy is removed first so
+-- no orphaned TimescaleDB background job outlives the hypertable.
+
+SELECT remove_retention_policy('job_telemetry', if_exists => TRUE);
+DROP TABLE IF EXISTS job_telemetry;
+ALTER TABLE nodes DROP COLUMN IF EXISTS telemetry_secret;
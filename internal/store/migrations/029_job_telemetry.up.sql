@@ -0,0 +1,39 @@
+-- 029_job_telemetry.up.sql
+-- Persisted, verified per-job telemetry. Until now POST /jobs/{id}/telemetry
+-- only logged the agent's TelemetryPayload and never checked its HMAC.
+--
+-- Two changes:
+--
+-- 1. nodes.telemetry_secret (nullable BYTEA) — the 32-byte per-node HMAC key
+--    handleClaimNode issues to the agent as token_secret. Stored raw, not
+--    hashed: verifying an HMAC requires the key itself. Nodes claimed before
+--    this migration carry NULL; their telemetry is rejected (403) until the
+--    node is re-claimed, because an unverifiable sample must never reach the
+--    billing-adjacent series.
+--
+-- 2. job_telemetry hypertable — one row per ACCEPTED sample. Unlike the 025
+--    demand-sounding sinks, these rows are evidence (metering and disputes read
+--    them), so job_id carries a real foreign key and the insert is synchronous
+--    on the request path: a sample the agent believes was delivered must be on
+--    disk. PRIMARY KEY (job_id, sampled_at) includes the partitioning column as
+--    TimescaleDB requires, and doubles as the exact-duplicate replay guard; the
+--    strictly-increasing check lives in the guarded INSERT in store.
+--
+-- Retention: raw samples are kept 90 days, comfortably past the dispute window.
+-- add_retention_policy schedules a TimescaleDB background job; the extension is
+-- already enabled by 025.
+
+ALTER TABLE nodes ADD COLUMN telemetry_secret BYTEA;
+
+CREATE TABLE job_telemetry (
+    job_id         UUID             NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    node_id        UUID             NOT NULL,              -- jobs.node_id at sample time (no FK: reroutes reassign)
+    sampled_at     TIMESTAMPTZ      NOT NULL,              -- agent-signed timestamp, seconds precision
+    cpu_pct        DOUBLE PRECISION NOT NULL CHECK (cpu_pct BETWEEN 0 AND 100),
+    ram_pct        DOUBLE PRECISION NOT NULL CHECK (ram_pct BETWEEN 0 AND 100),
+    bandwidth_mbps INTEGER          NOT NULL DEFAULT 0,
+    received_at    TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, sampled_at)
+);
+SELECT create_hypertable('job_telemetry', 'sampled_at', if_not_exists => TRUE);
+SELECT add_retention_policy('job_telemetry', INTERVAL '90 days', if_not_exists => TRUE);
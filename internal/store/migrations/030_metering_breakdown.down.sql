@@ -0,0 +1,10 @@
+-- 030_metering_breakdown.down.sql
+-- Reverses 030_metering_breakdown.up.sql.
+
+ALTER TABLE job_metering
+    DROP COLUMN IF EXISTS breakdown,
+    DROP COLUMN IF EXISTS usage_source,
+    DROP COLUMN IF EXISTS egress_gb,
+    DROP COLUMN IF EXISTS gpu_vram_gb_hours;
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS gpu_vram_gb;
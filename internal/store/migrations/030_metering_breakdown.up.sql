@@ -0,0 +1,29 @@
+-- 030_metering_breakdown.up.sql
+-- Usage-based metering. ComputeMetering previously billed the node's whole
+-- hardware_profile (a 1-core job on a 16-core box paid for 16 core-hours) and
+-- never charged gpu_vram_gb_hr or egress_gb even though both exist in the
+-- resource_type enum and have seeded rates.
+--
+-- 1. jobs.gpu_vram_gb (nullable) — the GPU memory a job requests, the billing
+--    quantity for gpu_vram_gb_hr. hardware_profile carries no VRAM figure, so
+--    the request is the only source. NULL/0 bills no GPU time.
+--
+-- 2. job_metering gains the two new quantities, the source the quantities were
+--    derived from, and a per-resource breakdown (resource_type, quantity, rate,
+--    multiplier, cents) that sums exactly to consumer_paid_cents, so a dispute
+--    can cite the line it contests. usage_source is TEXT + CHECK (no enum, no
+--    ALTER TYPE hazard):
+--      'hardware'  — legacy rows computed from the whole-node profile (default
+--                    for every row that predates this migration)
+--      'requested' — the job's requested cpu/ram/storage/vram × wall-clock
+--      'telemetry' — verified job_telemetry samples (029) covered at least
+--                    part of the run; uncovered time falls back to requested
+
+ALTER TABLE jobs ADD COLUMN gpu_vram_gb INTEGER CHECK (gpu_vram_gb IS NULL OR gpu_vram_gb >= 0);
+
+ALTER TABLE job_metering
+    ADD COLUMN gpu_vram_gb_hours NUMERIC(12,4) NOT NULL DEFAULT 0,
+    ADD COLUMN egress_gb         NUMERIC(14,6) NOT NULL DEFAULT 0,
+    ADD COLUMN usage_source      TEXT          NOT NULL DEFAULT 'hardware'
+        CHECK (usage_source IN ('hardware', 'requested', 'telemetry')),
+    ADD COLUMN breakdown         JSONB         NOT NULL DEFAULT '[]'::jsonb;
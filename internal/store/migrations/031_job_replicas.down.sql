@@ -0,0 +1,14 @@
+-- 031_job_replicas.down.sql
+-- Reverses 031_job_replicas.up.sql. Replica child rows are kept: each ran on
+-- a real node and may carry job_metering / payout history, so they survive as
+-- standalone jobs.
+
+DROP INDEX IF EXISTS uq_jobs_parent_replica;
+
+ALTER TABLE jobs
+    DROP CONSTRAINT IF EXISTS jobs_replica_index_parent_check,
+    DROP CONSTRAINT IF EXISTS jobs_replica_quorum_check,
+    DROP COLUMN IF EXISTS replica_quorum,
+    DROP COLUMN IF EXISTS sla_tier,
+    DROP COLUMN IF EXISTS replica_index,
+    DROP COLUMN IF EXISTS parent_job_id;
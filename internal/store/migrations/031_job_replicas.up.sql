@@ -0,0 +1,33 @@
+-- 031_job_replicas.up.sql
+-- SLA tiers. SubmitJob always placed a single node even though SLATier
+-- (Standard/Reliable/Premium) and the scheduler's top-N selection existed.
+-- A Reliable (2) or Premium (3) job is now a replica group:
+--
+--   parent row  — the consumer-facing job. node_id stays NULL (it is never
+--                 polled, rerouted or metered); status follows its replicas:
+--                 scheduled → running on the first replica start →
+--                 completed once replica_quorum replicas succeed, or failed
+--                 once too many replicas failed for the quorum to be reached.
+--   child rows  — one per replica, each an ordinary job bound to its own node
+--                 (distinct nodes and distinct owners) with its own job_token,
+--                 lifecycle and job_metering row. Billing is per replica: the
+--                 consumer pays for every replica that ran, and each
+--                 contributor earns for its own.
+--
+-- When the parent settles, replicas that have not started are withdrawn
+-- (failed, failure_cause 'replica_superseded'); replicas already running run
+-- to completion and are billed.
+--
+-- sla_tier / replica_quorum live on the parent; children and Standard jobs
+-- keep the defaults (1, 1). replica_index numbers the children 0..tier-1.
+
+ALTER TABLE jobs
+    ADD COLUMN parent_job_id  UUID     REFERENCES jobs(id) ON DELETE CASCADE,
+    ADD COLUMN replica_index  SMALLINT CHECK (replica_index IS NULL OR replica_index >= 0),
+    ADD COLUMN sla_tier       SMALLINT NOT NULL DEFAULT 1 CHECK (sla_tier BETWEEN 1 AND 3),
+    ADD COLUMN replica_quorum SMALLINT NOT NULL DEFAULT 1,
+    ADD CONSTRAINT jobs_replica_quorum_check CHECK (replica_quorum BETWEEN 1 AND sla_tier),
+    ADD CONSTRAINT jobs_replica_index_parent_check CHECK ((parent_job_id IS NULL) = (replica_index IS NULL));
+
+CREATE UNIQUE INDEX uq_jobs_parent_replica ON jobs(parent_job_id, replica_index)
+    WHERE parent_job_id IS NOT NULL;
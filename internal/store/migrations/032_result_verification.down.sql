@@ -0,0 +1,10 @@
+-- 032_result_verification.down.sql
+-- Reverses 032_result_verification.up.sql. Disputes opened by verification
+-- are ordinary disputes rows and are kept.
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS suspected_fraud,
+    DROP COLUMN IF EXISTS verification,
+    DROP COLUMN IF EXISTS result_hash,
+    DROP COLUMN IF EXISTS verify,
+    DROP COLUMN IF EXISTS output_path;
@@ -0,0 +1,31 @@
+-- 032_result_verification.up.sql
+-- Redundant execution with result-hash verification. /jobs/{id}/complete took
+-- the agent's exit code on trust; nothing checked that a node actually ran the
+-- workload. A batch_compute job may now opt into verification: it runs as a
+-- replica group (031) with a quorum of at least two, every replica's agent
+-- reports the SHA-256 of the job's declared output artifact, and the group is
+-- settled by comparing the hashes.
+--
+-- 1. jobs.output_path (nullable) — the declared artifact, an absolute path
+--    under /output inside the container. Set on the parent and every replica.
+--
+-- 2. jobs.verify (parent only) — verification requested at submit.
+--
+-- 3. jobs.result_hash (replicas, nullable) — lowercase hex SHA-256 reported
+--    with the replica's completion. NULL when the agent reported none.
+--
+-- 4. jobs.verification (parent only, nullable) — the settlement outcome,
+--    TEXT + CHECK (no enum, no ALTER TYPE hazard):
+--      'matched'    — the completed replicas agreed on one hash
+--      'mismatched' — they did not; the parent is 'disputed'
+--
+-- 5. jobs.suspected_fraud (replicas) — set on every replica whose hash lost
+--    the comparison (both of a 2-way split). Each suspect also gets an open
+--    dispute, and EligiblePayouts withholds suspect payouts outright.
+
+ALTER TABLE jobs
+    ADD COLUMN output_path     TEXT,
+    ADD COLUMN verify          BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN result_hash     TEXT CHECK (result_hash IS NULL OR result_hash ~ '^[0-9a-f]{64}$'),
+    ADD COLUMN verification    TEXT CHECK (verification IS NULL OR verification IN ('matched', 'mismatched')),
+    ADD COLUMN suspected_fraud BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- 033_job_artifacts.down.sql
+-- Reverses 033_job_artifacts.up.sql. Stored objects are not removed.
+
+DROP TABLE IF EXISTS job_artifacts;
@@ -0,0 +1,27 @@
+-- 033_job_artifacts.up.sql
+-- Job output artifacts. A job that declares an output (jobs.output_path, 032)
+-- gets a writable /output mount; after the container exits the agent tars the
+-- directory, hashes the tar and uploads it to PUT /jobs/{id}/artifacts. The
+-- bytes live in the artifact store (filesystem or S3-compatible, see
+-- internal/artifact); this table records what was stored and for how long.
+--
+-- One row per job (a re-upload replaces it). participant_id is the consumer,
+-- denormalized from jobs so the per-consumer size quota sums one index.
+-- deleted_at is set by the expiry reaper once the object is removed; expired
+-- rows are kept so the portal can answer 410 Gone instead of 404.
+
+CREATE TABLE job_artifacts (
+    job_id         UUID        PRIMARY KEY REFERENCES jobs(id) ON DELETE CASCADE,
+    participant_id UUID        NOT NULL REFERENCES participants(id),
+    object_key     TEXT        NOT NULL,
+    size_bytes     BIGINT      NOT NULL CHECK (size_bytes >= 0),
+    sha256         TEXT        NOT NULL CHECK (sha256 ~ '^[0-9a-f]{64}$'),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    deleted_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_job_artifacts_participant_live
+    ON job_artifacts (participant_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_job_artifacts_expiry
+    ON job_artifacts (expires_at) WHERE deleted_at IS NULL;
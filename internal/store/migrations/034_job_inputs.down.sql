@@ -0,0 +1,5 @@
+-- 034_job_inputs.down.sql
+-- Reverses 034_job_inputs.up.sql. Stored objects are not removed.
+
+DROP TABLE IF EXISTS job_inputs;
+DROP TABLE IF EXISTS input_uploads;
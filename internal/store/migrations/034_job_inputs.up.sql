@@ -0,0 +1,45 @@
+-- 034_job_inputs.up.sql
+-- Job input staging. A consumer attaches input files to a job at submission —
+-- a document or G-code for a print job, a dataset for a batch job. Each input
+-- is named (its file name under /input in the container) and pinned by
+-- SHA-256; before ContainerStart the agent fetches every input, verifies the
+-- digest and bind-mounts the set read-only at /input.
+--
+-- An input's bytes come from one of two places:
+--
+--   - an upload: the consumer PUTs the file to the portal ahead of submission
+--     (POST /consumer/inputs) and the job references it by digest alone. The
+--     bytes live in the artifact store under artifact.InputKey; input_uploads
+--     records them, content-addressed per consumer, so resubmitting a job
+--     with the same file uploads nothing new.
+--   - a URL: an https URL the agent downloads itself; the consumer supplies
+--     the digest it must match.
+--
+-- input_uploads share the consumer's artifact quota and TTL (033). The expiry
+-- reaper skips uploads still referenced by a job that has not started.
+
+CREATE TABLE input_uploads (
+    participant_id UUID        NOT NULL REFERENCES participants(id),
+    sha256         TEXT        NOT NULL CHECK (sha256 ~ '^[0-9a-f]{64}$'),
+    object_key     TEXT        NOT NULL,
+    size_bytes     BIGINT      NOT NULL CHECK (size_bytes >= 0),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at     TIMESTAMPTZ NOT NULL,
+    deleted_at     TIMESTAMPTZ,
+    PRIMARY KEY (participant_id, sha256)
+);
+
+CREATE INDEX idx_input_uploads_expiry
+    ON input_uploads (expires_at) WHERE deleted_at IS NULL;
+
+-- One row per input of a job. size_bytes is known for uploads and NULL for
+-- URL inputs; source_url is NULL for uploads.
+CREATE TABLE job_inputs (
+    job_id     UUID   NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    name       TEXT   NOT NULL CHECK (name ~ '^[A-Za-z0-9._-]{1,128}$' AND name NOT IN ('.', '..')),
+    sha256     TEXT   NOT NULL CHECK (sha256 ~ '^[0-9a-f]{64}$'),
+    size_bytes BIGINT CHECK (size_bytes >= 0),
+    source_url TEXT   CHECK (source_url LIKE 'https://%'),
+    PRIMARY KEY (job_id, name),
+    CHECK ((source_url IS NULL) = (size_bytes IS NOT NULL))
+);
@@ -0,0 +1,8 @@
+-- 035_job_logs.down.sql
+-- Reverses 035_job_logs.up.sql.
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS log_truncated,
+    DROP COLUMN IF EXISTS log_bytes;
+
+DROP TABLE IF EXISTS job_log_chunks;
@@ -0,0 +1,24 @@
+-- 035_job_logs.up.sql
+-- Consumer-visible container logs. While a job's container runs, the agent
+-- follows its stdout/stderr, redacts known secrets and likely credentials,
+-- and ships the output in chunks to POST /jobs/{id}/logs. The portal serves
+-- them to the consumer as a server-sent event tail
+-- (GET /consumer/job/{id}/logs) next to status-stream.
+--
+-- seq is assigned by the agent, so a retried batch is deduplicated by the
+-- primary key. jobs.log_bytes counts stored bytes against the per-job cap;
+-- once it is reached further output is dropped and log_truncated is set.
+-- Chunks are removed with their job.
+
+CREATE TABLE job_log_chunks (
+    job_id      UUID        NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    seq         BIGINT      NOT NULL CHECK (seq >= 0),
+    stream      TEXT        NOT NULL CHECK (stream IN ('stdout', 'stderr')),
+    data        TEXT        NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, seq)
+);
+
+ALTER TABLE jobs
+    ADD COLUMN log_bytes     BIGINT  NOT NULL DEFAULT 0,
+    ADD COLUMN log_truncated BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+-- 036_job_cancellation.down.sql
+-- Reverses 036_job_cancellation.up.sql.
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS refunded_at,
+    DROP COLUMN IF EXISTS refund_cents,
+    DROP COLUMN IF EXISTS cancelled_at;
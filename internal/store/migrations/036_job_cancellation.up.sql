@@ -0,0 +1,19 @@
+-- 036_job_cancellation.up.sql
+-- Consumer cancellation of a job that has not finished.
+--
+-- No enum change: a cancelled job is 'failed' with failure_cause
+-- 'consumer_cancelled' (the replica_superseded precedent, migration 031), so
+-- the ALTER TYPE … ADD VALUE same-transaction hazard is not triggered and
+-- every reader that treats 'failed' as terminal already handles it.
+--
+--   cancelled_at  — when the consumer cancelled. NULL for every other job.
+--   refund_cents  — the unused share of the job's charge owed back to the
+--                   consumer: amount_cents less the metered cost of what ran.
+--                   NULL when the job carried no charge (no payment intent).
+--   refunded_at   — when that refund was issued. refund_cents set with
+--                   refunded_at NULL is a refund still owed.
+
+ALTER TABLE jobs
+    ADD COLUMN cancelled_at TIMESTAMPTZ,
+    ADD COLUMN refund_cents BIGINT CHECK (refund_cents >= 0),
+    ADD COLUMN refunded_at  TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- 037_job_max_runtime.down.sql
+-- Reverses 037_job_max_runtime.up.sql.
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS max_runtime_seconds;
@@ -0,0 +1,19 @@
+-- 037_job_max_runtime.up.sql
+-- Maximum runtime for a job. A container could previously run forever: the
+-- agent waited on it with no timeout and nothing on the control plane noticed
+-- a run that never reported /complete.
+--
+-- jobs.max_runtime_seconds — the wall-clock limit on the job's run, from its
+-- start. The consumer may declare it at submit; otherwise it is the platform
+-- ceiling for the workload type. Set on every job submitted from this
+-- migration on (replica group parents and children alike); NULL on older
+-- rows, which are never reaped.
+--
+-- The agent stops a container that reaches the limit and completes the job
+-- as failed with failure_cause 'timeout'. A node that never reports back is
+-- covered by the orchestrator's reaper, which fails a running job once
+-- started_at + max_runtime_seconds + a grace period has passed — with the
+-- same cause, so no enum change (the 036 precedent).
+
+ALTER TABLE jobs
+    ADD COLUMN max_runtime_seconds INTEGER CHECK (max_runtime_seconds > 0);
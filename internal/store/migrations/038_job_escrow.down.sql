@@ -0,0 +1,13 @@
+-- 038_job_escrow.down.sql
+-- Reverses 038_job_escrow.up.sql.
+
+ALTER TABLE job_metering
+    DROP COLUMN IF EXISTS transferred_cents,
+    DROP COLUMN IF EXISTS transfer_id;
+
+DROP INDEX IF EXISTS idx_jobs_payment_status;
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS charge_id,
+    DROP COLUMN IF EXISTS captured_cents,
+    DROP COLUMN IF EXISTS payment_status;
@@ -0,0 +1,47 @@
+-- 038_job_escrow.up.sql
+-- Escrow-in: the consumer's payment is held from submission and settled
+-- against metering (GOAL 2 of 026, previously deferred).
+--
+-- At submit the job is quoted at its maximum — requested resources for its
+-- full max_runtime_seconds at current rates and the node's multiplier — and a
+-- manual-capture PaymentIntent for that quote is created on the platform
+-- account. jobs.amount_cents holds the quote and jobs.payment_intent_id the
+-- intent; a replica group's children carry the parent's intent so disputes
+-- opened against a replica have a charge to refund. When the job settles, the
+-- metered cost (capped at the quote) is captured, the uncaptured remainder is
+-- released back to the consumer, and each metered run's contributor share is
+-- transferred to its node owner's connected account, from which the payout
+-- releaser pays out after the dispute window.
+--
+--   jobs.payment_status — NULL for a job submitted without escrow (every row
+--       before this migration). Otherwise:
+--         awaiting_payment  intent created, not yet authorized; never polled
+--         authorized        funds held; the job may be dispatched
+--         captured          metered cost captured
+--         released          the hold was cancelled and nothing was charged
+--       TEXT + CHECK rather than an enum, so no ALTER TYPE … ADD VALUE.
+--   jobs.captured_cents — what was captured, on the escrowed job (a replica
+--       group's parent). refund_cents / refunded_at (036) record the released
+--       remainder.
+--   jobs.charge_id — the captured charge, the source_transaction of the
+--       contributor transfers.
+--   job_metering.transfer_id / transferred_cents — the transfer that moved the
+--       run's contributor share to its owner. Equal to contributor_earned_cents
+--       unless the metered cost exceeded the quote and the capture was capped.
+--
+-- A job left awaiting_payment is failed with failure_cause
+-- 'payment_not_authorized' by the orchestrator's reaper (no enum change; the
+-- 036 precedent).
+
+ALTER TABLE jobs
+    ADD COLUMN payment_status TEXT CHECK (payment_status IN
+        ('awaiting_payment', 'authorized', 'captured', 'released')),
+    ADD COLUMN captured_cents BIGINT CHECK (captured_cents >= 0),
+    ADD COLUMN charge_id      TEXT;
+
+CREATE INDEX idx_jobs_payment_status ON jobs (payment_status)
+    WHERE payment_status IN ('awaiting_payment', 'authorized', 'captured');
+
+ALTER TABLE job_metering
+    ADD COLUMN transfer_id       TEXT,
+    ADD COLUMN transferred_cents BIGINT CHECK (transferred_cents >= 0);
@@ -0,0 +1,222 @@
+-- 039_ledger.up.sql
+-- Append-only double-entry ledger of every money movement.
+--
+-- Money state was spread across jobs.amount_cents, the job_metering cents
+-- columns, payout_released_at and Stripe. The ledger records each movement
+-- once, in the same database transaction as the row it mirrors, so a balance
+-- is a SUM instead of a reconstruction from joins.
+--
+--   ledger_transactions — one per event: kind, plus ref (the job, or the
+--       dispute for a dispute refund). UNIQUE (kind, ref) makes posting
+--       idempotent: a retried write finds its event already recorded.
+--   ledger_entries — the legs of a transaction. Every transaction's
+--       amount_cents sum to zero, checked at commit by a deferred constraint
+--       trigger. An amount is money moving INTO the account; an account's
+--       balance is what it holds.
+--
+-- Accounts (participant_id set on the per-participant ones):
+--   consumer_wallet[c]      the consumer's side of every payment: minus what
+--                           they have paid in.
+--   escrow                  consumer money the coordinator holds that is not
+--                           yet earned — negative while metered cost has not
+--                           been collected (an escrow not yet captured).
+--   platform_fee            the platform's fee revenue, less dispute refunds.
+--   contributor_payable[o]  what the coordinator owes node owner o now.
+--   payout[o]               paid out to o.
+--   refund[c]               refunded to c.
+--
+-- Kinds and their legs:
+--   metering         escrow → platform_fee + contributor_payable[o]; a job
+--                    without escrow also recognizes its charge,
+--                    consumer_wallet[c] → escrow.
+--   escrow_settlement  consumer_wallet[c] → escrow for the capture; a capture
+--                    capped below the metered cost, or waived, writes the
+--                    shortfall back from contributor_payable and platform_fee.
+--   refund           escrow → refund[c] (a cancelled job's unused charge).
+--   dispute_refund   platform_fee → refund[c].
+--   payout           contributor_payable[o] → payout[o].
+--
+-- job_id and participant_id carry no foreign keys: the ledger is history and
+-- must survive the rows it describes. Both tables reject UPDATE and DELETE.
+--
+-- Existing metering, payouts, cancellation refunds and escrow settlements
+-- (shortfalls included) are backfilled. Dispute refunds before this
+-- migration are not: their amounts were never recorded.
+
+CREATE TABLE ledger_transactions (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind       TEXT NOT NULL CHECK (kind IN
+        ('metering', 'escrow_settlement', 'refund', 'dispute_refund', 'payout')),
+    ref        TEXT NOT NULL,
+    job_id     UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, ref)
+);
+
+CREATE INDEX idx_ledger_transactions_job ON ledger_transactions(job_id);
+
+CREATE TABLE ledger_entries (
+    id             BIGSERIAL PRIMARY KEY,
+    transaction_id UUID NOT NULL REFERENCES ledger_transactions(id),
+    account        TEXT NOT NULL CHECK (account IN
+        ('consumer_wallet', 'escrow', 'platform_fee', 'contributor_payable', 'payout', 'refund')),
+    participant_id UUID,
+    amount_cents   BIGINT NOT NULL CHECK (amount_cents <> 0),
+    CHECK ((account IN ('escrow', 'platform_fee')) = (participant_id IS NULL))
+);
+
+CREATE INDEX idx_ledger_entries_transaction ON ledger_entries(transaction_id);
+CREATE INDEX idx_ledger_entries_account ON ledger_entries(account, participant_id);
+
+CREATE FUNCTION ledger_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger is append-only: % on % rejected', TG_OP, TG_TABLE_NAME;
+END
+$$;
+
+CREATE TRIGGER ledger_transactions_append_only
+    BEFORE UPDATE OR DELETE ON ledger_transactions
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+CREATE TRIGGER ledger_entries_append_only
+    BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+CREATE FUNCTION ledger_check_balanced() RETURNS trigger LANGUAGE plpgsql AS $$
+DECLARE
+    total BIGINT;
+BEGIN
+    SELECT SUM(amount_cents) INTO total
+    FROM ledger_entries WHERE transaction_id = NEW.transaction_id;
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'ledger transaction % does not balance: legs sum to %',
+            NEW.transaction_id, total;
+    END IF;
+    RETURN NULL;
+END
+$$;
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced
+    AFTER INSERT ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_check_balanced();
+
+-- Backfill. Transactions first, then their legs; the deferred trigger checks
+-- each transaction balances when the migration commits.
+
+INSERT INTO ledger_transactions (kind, ref, job_id, created_at)
+SELECT 'metering', m.job_id::text, m.job_id, m.computed_at
+FROM job_metering m;
+
+INSERT INTO ledger_entries (transaction_id, account, participant_id, amount_cents)
+SELECT t.id, leg.account, leg.participant_id, leg.amount_cents
+FROM job_metering m
+JOIN jobs j ON j.id = m.job_id
+JOIN nodes n ON n.id = j.node_id
+JOIN ledger_transactions t ON t.kind = 'metering' AND t.ref = m.job_id::text
+CROSS JOIN LATERAL (VALUES
+    ('escrow', NULL::uuid, -m.consumer_paid_cents),
+    ('platform_fee', NULL::uuid, m.platform_fee_cents),
+    ('contributor_payable', n.participant_id, m.contributor_earned_cents),
+    ('consumer_wallet', j.participant_id,
+        CASE WHEN j.payment_status IS NULL AND j.payment_intent_id IS NOT NULL
+             THEN -j.amount_cents ELSE 0 END),
+    ('escrow', NULL::uuid,
+        CASE WHEN j.payment_status IS NULL AND j.payment_intent_id IS NOT NULL
+             THEN j.amount_cents ELSE 0 END)
+) AS leg(account, participant_id, amount_cents)
+WHERE leg.amount_cents <> 0;
+
+INSERT INTO ledger_transactions (kind, ref, job_id, created_at)
+SELECT 'payout', m.job_id::text, m.job_id, m.payout_released_at
+FROM job_metering m
+WHERE m.payout_released_at IS NOT NULL;
+
+INSERT INTO ledger_entries (transaction_id, account, participant_id, amount_cents)
+SELECT t.id, leg.account, n.participant_id, leg.amount_cents
+FROM job_metering m
+JOIN jobs j ON j.id = m.job_id
+JOIN nodes n ON n.id = j.node_id
+JOIN ledger_transactions t ON t.kind = 'payout' AND t.ref = m.job_id::text
+CROSS JOIN LATERAL (VALUES
+    ('contributor_payable', -COALESCE(m.transferred_cents, m.contributor_earned_cents)),
+    ('payout', COALESCE(m.transferred_cents, m.contributor_earned_cents))
+) AS leg(account, amount_cents)
+WHERE m.payout_released_at IS NOT NULL
+  AND leg.amount_cents <> 0;
+
+INSERT INTO ledger_transactions (kind, ref, job_id, created_at)
+SELECT 'refund', j.id::text, j.id, j.refunded_at
+FROM jobs j
+WHERE j.payment_status IS NULL
+  AND j.refunded_at IS NOT NULL;
+
+INSERT INTO ledger_entries (transaction_id, account, participant_id, amount_cents)
+SELECT t.id, leg.account, leg.participant_id, leg.amount_cents
+FROM jobs j
+JOIN ledger_transactions t ON t.kind = 'refund' AND t.ref = j.id::text
+CROSS JOIN LATERAL (VALUES
+    ('escrow', NULL::uuid, -j.refund_cents),
+    ('refund', j.participant_id, j.refund_cents)
+) AS leg(account, participant_id, amount_cents)
+WHERE j.payment_status IS NULL
+  AND j.refunded_at IS NOT NULL
+  AND leg.amount_cents <> 0;
+
+INSERT INTO ledger_transactions (kind, ref, job_id, created_at)
+SELECT 'escrow_settlement', j.id::text, j.id, j.updated_at
+FROM jobs j
+WHERE j.parent_job_id IS NULL
+  AND j.payment_status IN ('captured', 'released');
+
+INSERT INTO ledger_entries (transaction_id, account, participant_id, amount_cents)
+SELECT t.id, leg.account, leg.participant_id, leg.amount_cents
+FROM jobs j
+JOIN ledger_transactions t ON t.kind = 'escrow_settlement' AND t.ref = j.id::text
+CROSS JOIN LATERAL (VALUES
+    ('consumer_wallet', j.participant_id, -j.captured_cents),
+    ('escrow', NULL::uuid, j.captured_cents)
+) AS leg(account, participant_id, amount_cents)
+WHERE j.parent_job_id IS NULL
+  AND j.payment_status = 'captured'
+  AND leg.amount_cents <> 0;
+
+-- A capture below the metered cost — capped at the hold, or waived by a
+-- release — writes the shortfall back out of escrow as settlementEntries
+-- does: each run's owner gives up the earnings its transfer withholds
+-- (EscrowTransfer.TransferCents) and the platform fee the rest.
+WITH settled AS (
+    SELECT j.id, COALESCE(j.captured_cents, 0) AS captured,
+           (SELECT COALESCE(SUM(m.consumer_paid_cents), 0)
+            FROM job_metering m
+            JOIN jobs x ON x.id = m.job_id
+            WHERE x.id = j.id OR x.parent_job_id = j.id) AS metered
+    FROM jobs j
+    WHERE j.parent_job_id IS NULL
+      AND j.payment_status IN ('captured', 'released')
+), withheld AS (
+    SELECT s.id, n.participant_id AS owner_id,
+           m.contributor_earned_cents
+             - ROUND(m.contributor_earned_cents::numeric * s.captured / s.metered)::bigint AS cents
+    FROM settled s
+    JOIN jobs x ON x.id = s.id OR x.parent_job_id = s.id
+    JOIN job_metering m ON m.job_id = x.id
+    JOIN nodes n ON n.id = x.node_id
+    WHERE s.metered > s.captured
+)
+INSERT INTO ledger_entries (transaction_id, account, participant_id, amount_cents)
+SELECT t.id, leg.account, leg.participant_id, leg.amount_cents
+FROM settled s
+JOIN ledger_transactions t ON t.kind = 'escrow_settlement' AND t.ref = s.id::text
+CROSS JOIN LATERAL (
+    SELECT 'escrow' AS account, NULL::uuid AS participant_id, s.metered - s.captured AS amount_cents
+    UNION ALL
+    SELECT 'contributor_payable', w.owner_id, -w.cents
+    FROM withheld w WHERE w.id = s.id
+    UNION ALL
+    SELECT 'platform_fee', NULL,
+           (COALESCE((SELECT SUM(w.cents) FROM withheld w WHERE w.id = s.id), 0)
+            - (s.metered - s.captured))::bigint
+) AS leg
+WHERE s.metered > s.captured
+  AND leg.amount_cents <> 0;
@@ -0,0 +1,12 @@
+-- Reverses 040_payout_batches.up.sql.
+
+DROP INDEX IF EXISTS idx_job_metering_payout_batch;
+
+ALTER TABLE job_metering
+    DROP COLUMN IF EXISTS payout_batch_id;
+
+DROP TABLE IF EXISTS payout_batches;
+
+ALTER TABLE participants
+    DROP COLUMN IF EXISTS payout_threshold_cents,
+    DROP COLUMN IF EXISTS payout_schedule;
@@ -0,0 +1,45 @@
+-- 040_payout_batches.up.sql
+-- Batched contributor payouts.
+--
+-- The payout releaser paid out every metered run on its own, so an active
+-- node's owner received dozens of tiny payouts a day. Runs eligible for payout
+-- (EligiblePayouts) now accumulate per owner and are paid out together, one
+-- Stripe payout per batch, on the owner's schedule:
+--
+--   participants.payout_schedule — 'daily' or 'weekly': at most one batch per
+--       day / week, of whatever is eligible. 'threshold': a batch once the
+--       eligible total reaches payout_threshold_cents.
+--   payout_batches — one payout to one connected account. 'pending' from
+--       creation until the payout is created, then 'released' with its
+--       payout_id. The batch id is the payout's idempotency key, so a pending
+--       batch retried after a failure cannot pay twice.
+--   job_metering.payout_batch_id — the batch a run was paid in; its
+--       payout_released_at is set when the batch is released.
+--
+-- The ledger's payout transactions (039) are posted per batch from now on,
+-- ref = the batch id; runs paid before this migration keep theirs per job.
+
+ALTER TABLE participants
+    ADD COLUMN payout_schedule TEXT NOT NULL DEFAULT 'daily'
+        CHECK (payout_schedule IN ('daily', 'weekly', 'threshold')),
+    ADD COLUMN payout_threshold_cents BIGINT NOT NULL DEFAULT 5000
+        CHECK (payout_threshold_cents > 0);
+
+CREATE TABLE payout_batches (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    participant_id    UUID NOT NULL REFERENCES participants(id) ON DELETE CASCADE,
+    stripe_account_id TEXT NOT NULL,
+    amount_cents      BIGINT NOT NULL CHECK (amount_cents > 0),
+    status            TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'released')),
+    payout_id         TEXT,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    released_at       TIMESTAMPTZ
+);
+
+CREATE INDEX idx_payout_batches_participant ON payout_batches(participant_id, created_at DESC);
+CREATE INDEX idx_payout_batches_pending ON payout_batches(created_at) WHERE status = 'pending';
+
+ALTER TABLE job_metering
+    ADD COLUMN payout_batch_id UUID REFERENCES payout_batches(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_job_metering_payout_batch ON job_metering(payout_batch_id);
@@ -0,0 +1,23 @@
+-- Reverses 041_stripe_reconciliation.up.sql. Fails once a payout has been
+-- reversed or a batch paid or failed: the ledger is append-only, and those
+-- rows no longer fit the old constraints.
+
+DROP TABLE IF EXISTS reconciliation_discrepancies;
+DROP TABLE IF EXISTS reconciliation_runs;
+
+ALTER TABLE ledger_transactions
+    DROP CONSTRAINT ledger_transactions_kind_check,
+    ADD CONSTRAINT ledger_transactions_kind_check CHECK (kind IN
+        ('metering', 'escrow_settlement', 'refund', 'dispute_refund', 'payout'));
+
+DROP INDEX IF EXISTS idx_payout_batches_payout;
+
+ALTER TABLE payout_batches
+    DROP COLUMN IF EXISTS settled_at,
+    DROP COLUMN IF EXISTS failure_code,
+    DROP CONSTRAINT payout_batches_status_check,
+    ADD CONSTRAINT payout_batches_status_check
+        CHECK (status IN ('pending', 'released'));
+
+DROP TABLE IF EXISTS stripe_disputes;
+DROP TABLE IF EXISTS stripe_events;
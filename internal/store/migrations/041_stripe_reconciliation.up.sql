@@ -0,0 +1,79 @@
+-- 041_stripe_reconciliation.up.sql
+-- Stripe webhook ingestion and reconciliation.
+--
+-- Only account.updated and the escrow authorization were ingested, so a
+-- refund made in the Stripe dashboard, a payout the owner's bank bounced or
+-- a chargeback never reached our records. This migration stores what the
+-- webhooks now report, and the findings of the periodic reconciliation run
+-- that compares Stripe's objects with ours:
+--
+--   stripe_events — every webhook event processed: a log for the console
+--       (when did Stripe last reach us?) and a record of what was ingested.
+--   stripe_disputes — chargebacks (charge.dispute.*), matched to the job by
+--       payment intent. A job with a chargeback not won is not paid out.
+--   payout_batches — 'paid' once Stripe reports the payout arrived, 'failed'
+--       with failure_code when it bounced. A failed batch's amount goes back
+--       to contributor_payable (ledger kind 'payout_reversal', ref = the
+--       batch) and its runs are released for the next batch.
+--   reconciliation_runs / reconciliation_discrepancies — each run, and every
+--       difference it found between Stripe and our records.
+
+CREATE TABLE stripe_events (
+    id          TEXT PRIMARY KEY,
+    type        TEXT NOT NULL,
+    account     TEXT,
+    object_id   TEXT NOT NULL,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stripe_events_received ON stripe_events(received_at DESC);
+
+CREATE TABLE stripe_disputes (
+    id                TEXT PRIMARY KEY,
+    charge_id         TEXT NOT NULL,
+    payment_intent_id TEXT,
+    job_id            UUID REFERENCES jobs(id) ON DELETE SET NULL,
+    amount_cents      BIGINT NOT NULL,
+    reason            TEXT NOT NULL,
+    status            TEXT NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stripe_disputes_job ON stripe_disputes(job_id);
+
+ALTER TABLE payout_batches
+    DROP CONSTRAINT payout_batches_status_check,
+    ADD CONSTRAINT payout_batches_status_check
+        CHECK (status IN ('pending', 'released', 'paid', 'failed')),
+    ADD COLUMN failure_code TEXT,
+    ADD COLUMN settled_at   TIMESTAMPTZ;
+
+CREATE INDEX idx_payout_batches_payout ON payout_batches(payout_id);
+
+ALTER TABLE ledger_transactions
+    DROP CONSTRAINT ledger_transactions_kind_check,
+    ADD CONSTRAINT ledger_transactions_kind_check CHECK (kind IN
+        ('metering', 'escrow_settlement', 'refund', 'dispute_refund', 'payout', 'payout_reversal'));
+
+CREATE TABLE reconciliation_runs (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    started_at      TIMESTAMPTZ NOT NULL,
+    finished_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    objects_checked INT NOT NULL
+);
+
+CREATE INDEX idx_reconciliation_runs_finished ON reconciliation_runs(finished_at DESC);
+
+CREATE TABLE reconciliation_discrepancies (
+    id          BIGSERIAL PRIMARY KEY,
+    run_id      UUID NOT NULL REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+    object_type TEXT NOT NULL,
+    object_id   TEXT NOT NULL,
+    ref         TEXT NOT NULL,
+    check_name  TEXT NOT NULL,
+    ours        TEXT NOT NULL,
+    stripe      TEXT NOT NULL
+);
+
+CREATE INDEX idx_reconciliation_discrepancies_run ON reconciliation_discrepancies(run_id);
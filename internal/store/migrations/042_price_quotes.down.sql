@@ -0,0 +1,5 @@
+-- Reverses 042_price_quotes.up.sql.
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS quoted_multiplier,
+    DROP COLUMN IF EXISTS quoted_at;
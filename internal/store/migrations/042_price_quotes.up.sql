@@ -0,0 +1,24 @@
+-- 042_price_quotes.up.sql
+-- Signed price quotes, honored at submission.
+--
+-- A consumer can ask for an estimate before submitting: the orchestrator
+-- prices the request on each candidate node from the expected runtime up to
+-- the max runtime, and returns an HMAC-signed quote token valid for a short
+-- time. A job submitted with a valid token is priced as quoted:
+--
+--   jobs.quoted_at — when the quote was issued. Metering (and the escrow
+--       quote) use the resource_pricing rates in effect at this time rather
+--       than at metering time. NULL for a job submitted without a quote.
+--   jobs.quoted_multiplier — the node's price multiplier as quoted, a
+--       CEILING: metering uses the lower of it and the node's multiplier when
+--       metered, so an owner raising their price after the quote does not
+--       raise it for this job. A node placed on that was not in the quote is
+--       capped at the dearest quoted multiplier. A rerouted job keeps it.
+--       NULL for a job submitted without a quote, and on a replica group's
+--       parent, which is never metered.
+--
+-- The quote itself is not stored: the token carries what it commits to.
+
+ALTER TABLE jobs
+    ADD COLUMN quoted_at         TIMESTAMPTZ,
+    ADD COLUMN quoted_multiplier NUMERIC(4,3) CHECK (quoted_multiplier > 0);
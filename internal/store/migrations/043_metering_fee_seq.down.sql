@@ -0,0 +1,3 @@
+-- Reverses 043_metering_fee_seq.up.sql.
+
+ALTER TABLE job_metering DROP COLUMN IF EXISTS fee_seq;
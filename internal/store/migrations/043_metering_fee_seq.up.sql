@@ -0,0 +1,16 @@
+-- 043_metering_fee_seq.up.sql
+-- Record on each metering row which fee declaration split the payment.
+--
+-- Metering took the split from resource_pricing.contributor_share, ignoring
+-- the signed declarations governance publishes (migration 024). It now
+-- applies the declaration in effect when the job started — the highest seq
+-- whose effective_at had passed — so a declaration published after a job was
+-- offered never changes that job's terms. fee_seq names it; NULL means no
+-- declaration was in effect and contributor_share applied, as for every row
+-- metered before this migration. A coordinator's first declaration may have
+-- seq 0, so 0 is not a stand-in for "none".
+--
+-- No foreign key: a declaration is identified by (coordinator_id, seq), and
+-- fee_declarations holds only this coordinator's.
+
+ALTER TABLE job_metering ADD COLUMN fee_seq BIGINT CHECK (fee_seq >= 0);
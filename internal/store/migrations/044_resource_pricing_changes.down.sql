@@ -0,0 +1,5 @@
+-- Reverses 044_resource_pricing_changes.up.sql. Rates scheduled or retired
+-- through governance stay in resource_pricing.
+
+DROP TABLE IF EXISTS resource_pricing_changes;
+DROP FUNCTION IF EXISTS resource_pricing_changes_append_only();
@@ -0,0 +1,50 @@
+-- 044_resource_pricing_changes.up.sql
+-- Audit trail for resource_pricing changes made on the :8090 governance
+-- console.
+--
+-- resource_pricing was only ever seeded (migration 004). Governance now
+-- schedules a rate for a resource_type from an effective_from — closing the
+-- rate it replaces at that instant with effective_until — and retires a rate
+-- by setting effective_until. A scheduled rate retired before it takes effect
+-- is cancelled: effective_until = effective_from, an empty range no read
+-- matches, and the rate it had closed is reopened. Rows are never deleted.
+--
+-- Each change is one row here:
+--   action         'schedule', 'retire' or 'cancel'.
+--   pricing_id     the resource_pricing row scheduled, retired or cancelled.
+--   replaced_id    for 'schedule', the rate closed at effective_from; for
+--                  'cancel', the rate reopened. NULL when there was none.
+--   changed_by     who made the change, as entered on the console. The
+--                  governance surface is reached over an SSH tunnel and has
+--                  no login of its own, so this is the admin's own account of
+--                  who they are.
+--
+-- The table rejects UPDATE and DELETE, like the ledger (migration 039).
+-- Overlap between a resource_type's rates is checked in the application
+-- under a per-type advisory lock; see store.SchedulePricing.
+
+CREATE TABLE resource_pricing_changes (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action          TEXT NOT NULL CHECK (action IN ('schedule', 'retire', 'cancel')),
+    pricing_id      UUID NOT NULL REFERENCES resource_pricing(id),
+    replaced_id     UUID REFERENCES resource_pricing(id),
+    resource_type   resource_type NOT NULL,
+    base_rate       NUMERIC(10,6) NOT NULL,
+    effective_from  TIMESTAMPTZ NOT NULL,
+    effective_until TIMESTAMPTZ,
+    changed_by      TEXT NOT NULL CHECK (changed_by <> ''),
+    reason          TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_resource_pricing_changes_created ON resource_pricing_changes(created_at DESC);
+
+CREATE FUNCTION resource_pricing_changes_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'resource_pricing_changes is append-only: % rejected', TG_OP;
+END
+$$;
+
+CREATE TRIGGER resource_pricing_changes_append_only
+    BEFORE UPDATE OR DELETE ON resource_pricing_changes
+    FOR EACH ROW EXECUTE FUNCTION resource_pricing_changes_append_only();
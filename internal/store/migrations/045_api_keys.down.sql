@@ -0,0 +1,3 @@
+-- Reverses 045_api_keys.up.sql. Every API key stops authenticating.
+
+DROP TABLE IF EXISTS api_keys;
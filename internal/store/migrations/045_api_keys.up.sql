@@ -0,0 +1,35 @@
+-- 045_api_keys.up.sql
+-- API keys for the consumer JSON API (/api/v1).
+--
+-- The HTML portal authenticates with a session cookie; scripts authenticate
+-- with a key a participant creates on /consumer/api-keys and sends as
+-- "Authorization: Bearer shk_<64 hex>". The key itself is shown once, at
+-- creation, and never stored: key_hash is its SHA-256. A key carries 256 bits
+-- from crypto/rand, so an unsalted fast hash is enough to make a leaked table
+-- useless and lets a request find its key by an index lookup, which a
+-- bcrypt-style hash would not.
+--
+--   prefix      the key's first characters, so the participant can tell keys
+--               apart on the page. Not secret and not unique.
+--   scopes      what the key may do: jobs:read (get, list, logs, estimate),
+--               jobs:write (submit, cancel) and artifacts:read (download
+--               output). At least one.
+--   revoked_at  set when the participant revokes the key; a revoked key never
+--               authenticates again. Rows are kept so the page can list them.
+
+CREATE TABLE api_keys (
+    id             UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+    participant_id UUID        NOT NULL REFERENCES participants(id),
+    name           TEXT        NOT NULL CHECK (name <> ''),
+    prefix         TEXT        NOT NULL,
+    key_hash       BYTEA       NOT NULL UNIQUE CHECK (octet_length(key_hash) = 32),
+    scopes         TEXT[]      NOT NULL CHECK (
+                       cardinality(scopes) > 0
+                       AND scopes <@ ARRAY['jobs:read', 'jobs:write', 'artifacts:read']
+                   ),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at   TIMESTAMPTZ,
+    revoked_at     TIMESTAMPTZ
+);
+
+CREATE INDEX idx_api_keys_participant ON api_keys(participant_id, created_at DESC);
@@ -0,0 +1,10 @@
+-- Reverses 046_job_placement.up.sql. Jobs lose their placement constraints;
+-- reroutes place them anywhere.
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS anti_affinity_required,
+    DROP COLUMN IF EXISTS anti_affinity,
+    DROP COLUMN IF EXISTS excluded_owner_ids,
+    DROP COLUMN IF EXISTS preferred_owner_ids,
+    DROP COLUMN IF EXISTS preferred_node_ids,
+    DROP COLUMN IF EXISTS required_node_id;
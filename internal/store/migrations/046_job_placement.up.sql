@@ -0,0 +1,29 @@
+-- 046_job_placement.up.sql
+-- Consumer placement constraints on jobs.
+--
+-- A consumer can pin a job to one node, prefer nodes or owners, exclude
+-- owners, and keep their own jobs apart (anti-affinity). The constraints are
+-- stored on the job so a declined job's reroute and a stale job's reschedule
+-- place it under the same terms as its submission:
+--
+--   required_node_id        the only node the job may run on. A pinned job
+--                           whose node declines it is failed, not moved.
+--   preferred_node_ids,     ranked first among the nodes that can take the
+--   preferred_owner_ids     job; never a filter.
+--   excluded_owner_ids      contributors whose nodes never get the job.
+--   anti_affinity           'owner' or 'region': avoid the owners (and, for
+--                           'region', the regions) of the nodes running the
+--                           consumer's other active jobs. NULL: none.
+--   anti_affinity_required  TRUE makes anti_affinity a filter instead of a
+--                           ranking penalty.
+--
+-- Every column defaults to "no constraint", so existing jobs are unchanged.
+
+ALTER TABLE jobs
+    ADD COLUMN required_node_id       UUID    REFERENCES nodes(id) ON DELETE SET NULL,
+    ADD COLUMN preferred_node_ids     UUID[]  NOT NULL DEFAULT '{}',
+    ADD COLUMN preferred_owner_ids    UUID[]  NOT NULL DEFAULT '{}',
+    ADD COLUMN excluded_owner_ids     UUID[]  NOT NULL DEFAULT '{}',
+    ADD COLUMN anti_affinity          TEXT    CHECK (anti_affinity IN ('owner', 'region')),
+    ADD COLUMN anti_affinity_required BOOLEAN NOT NULL DEFAULT FALSE
+        CHECK (NOT anti_affinity_required OR anti_affinity IS NOT NULL);
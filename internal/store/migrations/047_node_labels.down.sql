@@ -0,0 +1,12 @@
+-- Reverses 047_node_labels.up.sql. Nodes lose their labels and taints; jobs
+-- lose their node selectors and tolerations.
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS tolerations,
+    DROP COLUMN IF EXISTS node_selector;
+
+ALTER TABLE nodes
+    DROP COLUMN IF EXISTS agent_taints,
+    DROP COLUMN IF EXISTS agent_labels,
+    DROP COLUMN IF EXISTS taints,
+    DROP COLUMN IF EXISTS labels;
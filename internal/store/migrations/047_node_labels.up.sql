@@ -0,0 +1,32 @@
+-- 047_node_labels.up.sql
+-- Free-form node labels and taints, and the job constraints that target them.
+--
+-- Labels describe a node ("ssd", "ups-backed", "zone=attic"); a consumer's
+-- node selector requires them. Taints reserve a node: a job is placed there
+-- only if it tolerates every one of its taints ("office-hours-only"). Both
+-- are JSONB objects of key to value, where a bare tag has the value "".
+--
+--   labels, taints              set by the node's owner in the portal.
+--   agent_labels, agent_taints  reported by the agent from AGENT_LABELS and
+--                               AGENT_TAINTS; replaced on each heartbeat.
+--
+-- The orchestrator matches against the two merged, the owner's value winning
+-- for a key set in both.
+--
+-- On jobs:
+--
+--   node_selector  labels a node must carry; an empty value requires only
+--                  the key.
+--   tolerations    taints the job accepts, 'key' (any value) or 'key=value'.
+--
+-- Every column defaults to empty, so existing nodes and jobs are unchanged.
+
+ALTER TABLE nodes
+    ADD COLUMN labels       JSONB NOT NULL DEFAULT '{}' CHECK (jsonb_typeof(labels) = 'object'),
+    ADD COLUMN taints       JSONB NOT NULL DEFAULT '{}' CHECK (jsonb_typeof(taints) = 'object'),
+    ADD COLUMN agent_labels JSONB NOT NULL DEFAULT '{}' CHECK (jsonb_typeof(agent_labels) = 'object'),
+    ADD COLUMN agent_taints JSONB NOT NULL DEFAULT '{}' CHECK (jsonb_typeof(agent_taints) = 'object');
+
+ALTER TABLE jobs
+    ADD COLUMN node_selector JSONB  NOT NULL DEFAULT '{}' CHECK (jsonb_typeof(node_selector) = 'object'),
+    ADD COLUMN tolerations   TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,11 @@
+-- Reverses 048_escrow_settle_failures.up.sql. Fails while a job is
+-- settlement_failed: resolve those first.
+
+DROP INDEX IF EXISTS idx_jobs_settlement_failed;
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS settle_error,
+    DROP COLUMN IF EXISTS settle_attempts,
+    DROP CONSTRAINT jobs_payment_status_check,
+    ADD CONSTRAINT jobs_payment_status_check CHECK (payment_status IN
+        ('awaiting_payment', 'authorized', 'captured', 'released'));
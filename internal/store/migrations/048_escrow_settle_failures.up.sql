@@ -0,0 +1,26 @@
+-- 048_escrow_settle_failures.up.sql
+-- Escrow holds whose settlement keeps failing.
+--
+-- A card authorization lapses about 7 days after it is made; after that
+-- Stripe refuses the capture, and the escrow settler retried it on every
+-- tick forever while the job showed 'authorized'. Escrowed jobs are now
+-- limited to a runtime that settles well inside the authorization (see
+-- orchestrator.EscrowMaxRuntime), and the settler gives up after a bounded
+-- number of failed captures or releases:
+--
+--   jobs.settle_attempts / settle_error — failed settlement attempts on an
+--       escrowed job (a replica group's parent) and the last error.
+--   jobs.payment_status 'settlement_failed' — the settler gave up. Nothing
+--       was captured; the job, with its replicas, is no longer selected for
+--       settlement and is listed on the governance console's reconciliation
+--       page for staff to collect or write off.
+
+ALTER TABLE jobs
+    DROP CONSTRAINT jobs_payment_status_check,
+    ADD CONSTRAINT jobs_payment_status_check CHECK (payment_status IN
+        ('awaiting_payment', 'authorized', 'captured', 'released', 'settlement_failed')),
+    ADD COLUMN settle_attempts INT NOT NULL DEFAULT 0 CHECK (settle_attempts >= 0),
+    ADD COLUMN settle_error    TEXT;
+
+CREATE INDEX idx_jobs_settlement_failed ON jobs (updated_at)
+    WHERE payment_status = 'settlement_failed';
@@ -0,0 +1,7 @@
+-- Reverses 049_cancel_refund_retries.up.sql.
+
+DROP INDEX IF EXISTS idx_jobs_refund_owed;
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS refund_error,
+    DROP COLUMN IF EXISTS refund_attempts;
@@ -0,0 +1,20 @@
+-- 049_cancel_refund_retries.up.sql
+-- Retries of cancellation refunds that failed.
+--
+-- A cancelled job's unused charge is refunded while the consumer's cancel
+-- request is served; a refund Stripe refused stayed owed (refund_cents set,
+-- refunded_at NULL, migration 036) with nothing to retry it. The cancel
+-- refunder (store.RunCancelRefunder) now retries it, and gives up after a
+-- bounded number of failures:
+--
+--   jobs.refund_attempts / refund_error — failed retries of the job's
+--       cancellation refund and the last error. Every refund still owed is
+--       listed on the governance console's reconciliation page, so staff
+--       see the ones the refunder gave up on.
+
+ALTER TABLE jobs
+    ADD COLUMN refund_attempts INT NOT NULL DEFAULT 0 CHECK (refund_attempts >= 0),
+    ADD COLUMN refund_error    TEXT;
+
+CREATE INDEX idx_jobs_refund_owed ON jobs (cancelled_at)
+    WHERE refunded_at IS NULL AND refund_cents > 0 AND payment_status IS NULL;
@@ -0,0 +1,4 @@
+-- Reverses 050_payment_errors.up.sql.
+
+ALTER TABLE jobs
+    DROP COLUMN IF EXISTS payment_error;
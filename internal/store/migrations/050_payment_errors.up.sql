@@ -0,0 +1,14 @@
+-- 050_payment_errors.up.sql
+-- Failed authorizations of an escrow hold.
+--
+-- A consumer's attempt to authorize a job's hold can fail after Stripe.js
+-- has left the payment page (a declined 3-D Secure challenge, a bank that
+-- answers late); Stripe reports it only through the
+-- payment_intent.payment_failed webhook, which was logged and dropped:
+--
+--   jobs.payment_error — Stripe's reason the last authorization attempt on
+--       an awaiting job's hold failed, shown on its payment page. Cleared
+--       when the hold is authorized.
+
+ALTER TABLE jobs
+    ADD COLUMN payment_error TEXT;
@@ -46,3 +46,71 @@ func UpdateNodeCapabilities(ctx context.Context, db *DB, nodeID, nodeClass strin
 	}
 	return row, nil
 }
+
+// NodeLabels are a node's labels and taints (migration 047), each a map of
+// key to value where a bare tag has the value "".
+type NodeLabels struct {
+	Labels map[string]string
+	Taints map[string]string
+}
+
+// SetNodeLabels replaces the owner-set labels and taints of nodeID. Returns
+// ErrNodeNotFound when the node does not exist or ownerID does not own it —
+// deliberately indistinguishable, as on the portal's opt-out endpoints.
+func SetNodeLabels(ctx context.Context, db *DB, nodeID, ownerID string, l NodeLabels) error {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE nodes
+		 SET labels = $3::jsonb, taints = $4::jsonb, updated_at = NOW()
+		 WHERE id::text = $1 AND participant_id::text = $2`,
+		nodeID, ownerID, orEmptyLabels(l.Labels), orEmptyLabels(l.Taints),
+	)
+	if err != nil {
+		return fmt.Errorf("set node labels %s: %w", nodeID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("set node labels %s: %w", nodeID, ErrNodeNotFound)
+	}
+	return nil
+}
+
+// ReportNodeLabels replaces the agent-reported labels and taints of nodeID.
+// The row is only written when they changed, so an agent re-reporting the
+// same labels on every heartbeat costs no write.
+func ReportNodeLabels(ctx context.Context, db *DB, nodeID string, l NodeLabels) error {
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE nodes
+		 SET agent_labels = $2::jsonb, agent_taints = $3::jsonb, updated_at = NOW()
+		 WHERE id = $1
+		   AND (agent_labels <> $2::jsonb OR agent_taints <> $3::jsonb)`,
+		nodeID, orEmptyLabels(l.Labels), orEmptyLabels(l.Taints),
+	); err != nil {
+		return fmt.Errorf("report node labels %s: %w", nodeID, err)
+	}
+	return nil
+}
+
+// GetNodeLabels returns the labels and taints placement matches nodeID
+// against: the agent-reported ones overlaid by the owner's.
+func GetNodeLabels(ctx context.Context, db *DB, nodeID string) (NodeLabels, error) {
+	var l NodeLabels
+	err := db.Pool.QueryRow(ctx,
+		`SELECT agent_labels || labels, agent_taints || taints FROM nodes WHERE id = $1`,
+		nodeID,
+	).Scan(&l.Labels, &l.Taints)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return NodeLabels{}, fmt.Errorf("get node labels %s: %w", nodeID, ErrNodeNotFound)
+		}
+		return NodeLabels{}, fmt.Errorf("get node labels %s: %w", nodeID, err)
+	}
+	return l, nil
+}
+
+// orEmptyLabels returns m, or an empty map for nil, which pgx would send as
+// JSON null.
+func orEmptyLabels(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
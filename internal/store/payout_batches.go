@@ -0,0 +1,403 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Payout schedules (participants.payout_schedule, migration 040).
+const (
+	PayoutDaily     = "daily"
+	PayoutWeekly    = "weekly"
+	PayoutThreshold = "threshold"
+)
+
+// Payout batch statuses (payout_batches.status). A batch is released once
+// its payout is created, then paid or failed as Stripe reports (migration
+// 041).
+const (
+	PayoutBatchPending  = "pending"
+	PayoutBatchReleased = "released"
+	PayoutBatchPaid     = "paid"
+	PayoutBatchFailed   = "failed"
+)
+
+// ErrInvalidPayoutSchedule is returned by SetPayoutSchedule for an unknown
+// schedule or a non-positive threshold.
+var ErrInvalidPayoutSchedule = errors.New("store: invalid payout schedule")
+
+// PayoutSchedule is when a node owner's eligible runs are paid out.
+type PayoutSchedule struct {
+	Schedule       string // PayoutDaily, PayoutWeekly or PayoutThreshold
+	ThresholdCents int64  // the batch size a PayoutThreshold schedule waits for
+}
+
+// due reports whether an owner on this schedule is due a batch of
+// pendingCents at now, their last batch having been created at lastBatch
+// (zero for none).
+func (s PayoutSchedule) due(pendingCents int64, lastBatch, now time.Time) bool {
+	if pendingCents <= 0 {
+		return false
+	}
+	switch s.Schedule {
+	case PayoutWeekly:
+		return now.Sub(lastBatch) >= 7*24*time.Hour
+	case PayoutThreshold:
+		return pendingCents >= s.ThresholdCents
+	default:
+		return now.Sub(lastBatch) >= 24*time.Hour
+	}
+}
+
+// GetPayoutSchedule returns participantID's payout schedule.
+func GetPayoutSchedule(ctx context.Context, db *DB, participantID string) (PayoutSchedule, error) {
+	var s PayoutSchedule
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT payout_schedule, payout_threshold_cents FROM participants WHERE id = $1`,
+		participantID,
+	).Scan(&s.Schedule, &s.ThresholdCents); err != nil {
+		return PayoutSchedule{}, fmt.Errorf("get payout schedule %s: %w", participantID, err)
+	}
+	return s, nil
+}
+
+// SetPayoutSchedule sets participantID's payout schedule. A PayoutDaily or
+// PayoutWeekly schedule keeps the stored threshold.
+func SetPayoutSchedule(ctx context.Context, db *DB, participantID string, s PayoutSchedule) error {
+	if !slices.Contains([]string{PayoutDaily, PayoutWeekly, PayoutThreshold}, s.Schedule) ||
+		(s.Schedule == PayoutThreshold && s.ThresholdCents <= 0) {
+		return ErrInvalidPayoutSchedule
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE participants
+		 SET payout_schedule        = $2,
+		     payout_threshold_cents = CASE WHEN $2 = 'threshold' THEN $3 ELSE payout_threshold_cents END,
+		     updated_at             = NOW()
+		 WHERE id = $1`,
+		participantID, s.Schedule, s.ThresholdCents,
+	); err != nil {
+		return fmt.Errorf("set payout schedule %s: %w", participantID, err)
+	}
+	return nil
+}
+
+// PayoutBatch is one payout of a node owner's eligible runs to their
+// connected account.
+type PayoutBatch struct {
+	ID              string
+	ParticipantID   string
+	StripeAccountID string
+	AmountCents     int64
+	Status          string // PayoutBatchPending, …Released, …Paid or …Failed
+	PayoutID        string // "" until released
+	FailureCode     string // why the payout failed; "" unless PayoutBatchFailed
+	CreatedAt       time.Time
+	ReleasedAt      *time.Time
+	Lines           []PayoutLine // filled by PayoutHistory
+}
+
+// PayoutLine is one run paid in a batch.
+type PayoutLine struct {
+	JobID       string
+	AmountCents int64
+	CompletedAt *time.Time
+}
+
+// BatchPayouts groups the runs EligiblePayouts returns by node owner and
+// creates a pending batch for each owner whose schedule is due at now.
+// Returns the number of batches created.
+func BatchPayouts(ctx context.Context, db *DB, now time.Time) (int, error) {
+	candidates, err := EligiblePayouts(ctx, db)
+	if err != nil {
+		return 0, fmt.Errorf("batch payouts: %w", err)
+	}
+	byOwner := make(map[string][]PayoutCandidate)
+	var owners []string
+	for _, c := range candidates {
+		if _, ok := byOwner[c.ProviderID]; !ok {
+			owners = append(owners, c.ProviderID)
+		}
+		byOwner[c.ProviderID] = append(byOwner[c.ProviderID], c)
+	}
+
+	created := 0
+	for _, owner := range owners {
+		runs := byOwner[owner]
+		var pending int64
+		for _, c := range runs {
+			pending += c.ContributorEarnedCents
+		}
+
+		var (
+			s         PayoutSchedule
+			lastBatch *time.Time
+		)
+		if err := db.Pool.QueryRow(ctx,
+			`SELECT p.payout_schedule, p.payout_threshold_cents,
+			        (SELECT MAX(created_at) FROM payout_batches
+			         WHERE participant_id = p.id AND status <> 'failed')
+			 FROM participants p WHERE p.id = $1`,
+			owner,
+		).Scan(&s.Schedule, &s.ThresholdCents, &lastBatch); err != nil {
+			return created, fmt.Errorf("batch payouts: read schedule %s: %w", owner, err)
+		}
+		var last time.Time
+		if lastBatch != nil {
+			last = *lastBatch
+		}
+		if !s.due(pending, last, now) {
+			continue
+		}
+		if err := createPayoutBatch(ctx, db, runs, pending); err != nil {
+			return created, err
+		}
+		created++
+	}
+	return created, nil
+}
+
+// createPayoutBatch creates a pending batch of amountCents paying runs, all
+// of one owner, and links the runs to it.
+func createPayoutBatch(ctx context.Context, db *DB, runs []PayoutCandidate, amountCents int64) error {
+	owner := runs[0].ProviderID
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("create payout batch %s: begin: %w", owner, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var batchID string
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO payout_batches (participant_id, stripe_account_id, amount_cents)
+		 VALUES ($1, $2, $3) RETURNING id`,
+		owner, runs[0].ProviderStripeAccountID, amountCents,
+	).Scan(&batchID); err != nil {
+		return fmt.Errorf("create payout batch %s: insert: %w", owner, err)
+	}
+
+	jobIDs := make([]string, len(runs))
+	for i, c := range runs {
+		jobIDs[i] = c.JobID
+	}
+	tag, err := tx.Exec(ctx,
+		`UPDATE job_metering SET payout_batch_id = $1
+		 WHERE job_id = ANY($2) AND payout_batch_id IS NULL AND payout_released_at IS NULL`,
+		batchID, jobIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("create payout batch %s: link runs: %w", owner, err)
+	}
+	if tag.RowsAffected() != int64(len(runs)) {
+		return fmt.Errorf("create payout batch %s: linked %d of %d runs", owner, tag.RowsAffected(), len(runs))
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("create payout batch %s: commit: %w", owner, err)
+	}
+	return nil
+}
+
+// PendingPayoutBatches returns the batches not yet released, oldest first:
+// new ones, and any whose payout or bookkeeping failed on an earlier tick.
+func PendingPayoutBatches(ctx context.Context, db *DB) ([]PayoutBatch, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id::text, participant_id::text, stripe_account_id, amount_cents, status, created_at
+		 FROM payout_batches
+		 WHERE status = $1
+		 ORDER BY created_at`,
+		PayoutBatchPending,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("pending payout batches: %w", err)
+	}
+	defer rows.Close()
+	var out []PayoutBatch
+	for rows.Next() {
+		var b PayoutBatch
+		if err := rows.Scan(&b.ID, &b.ParticipantID, &b.StripeAccountID, &b.AmountCents, &b.Status, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("pending payout batches: scan: %w", err)
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// MarkPayoutBatchReleased records that batch batchID was paid out as
+// payoutID: the batch is released, each of its runs gets payout_released_at,
+// and the payout is posted to the ledger. A batch already released is left
+// as is.
+func MarkPayoutBatchReleased(ctx context.Context, db *DB, batchID, payoutID string) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("mark payout batch released %s: begin: %w", batchID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var (
+		ownerID     string
+		amountCents int64
+	)
+	err = tx.QueryRow(ctx,
+		`UPDATE payout_batches
+		 SET status = $2, payout_id = $3, released_at = NOW()
+		 WHERE id = $1 AND status = $4
+		 RETURNING participant_id::text, amount_cents`,
+		batchID, PayoutBatchReleased, payoutID, PayoutBatchPending,
+	).Scan(&ownerID, &amountCents)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("mark payout batch released %s: %w", batchID, err)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE job_metering SET payout_released_at = NOW()
+		 WHERE payout_batch_id = $1 AND payout_released_at IS NULL`,
+		batchID,
+	); err != nil {
+		return fmt.Errorf("mark payout batch released %s: mark runs: %w", batchID, err)
+	}
+	if err := postLedger(ctx, tx, ledgerPayout, batchID, "", []LedgerEntry{
+		{Account: AccountContributorPayable, ParticipantID: ownerID, AmountCents: -amountCents},
+		{Account: AccountPayout, ParticipantID: ownerID, AmountCents: amountCents},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("mark payout batch released %s: commit: %w", batchID, err)
+	}
+	return nil
+}
+
+// MarkPayoutBatchPaid records that the payout payoutID arrived in the
+// owner's bank account (Stripe's payout.paid). Returns false when no
+// released batch was paid by it.
+func MarkPayoutBatchPaid(ctx context.Context, db *DB, payoutID string) (bool, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE payout_batches SET status = $2, settled_at = NOW()
+		 WHERE payout_id = $1 AND status = $3`,
+		payoutID, PayoutBatchPaid, PayoutBatchReleased,
+	)
+	if err != nil {
+		return false, fmt.Errorf("mark payout batch paid %s: %w", payoutID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// FailPayoutBatch records that the payout payoutID failed with failureCode
+// (Stripe's payout.failed): Stripe has returned the money to the connected
+// account. The batch is failed, its payout reversed in the ledger, and its
+// runs released to be paid in the owner's next batch. Returns false when no
+// released or paid batch was paid by it, so a repeated event is harmless.
+func FailPayoutBatch(ctx context.Context, db *DB, payoutID, failureCode string) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("fail payout batch %s: begin: %w", payoutID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var (
+		batchID     string
+		ownerID     string
+		amountCents int64
+	)
+	err = tx.QueryRow(ctx,
+		`UPDATE payout_batches
+		 SET status = $2, failure_code = NULLIF($3, ''), settled_at = NOW()
+		 WHERE payout_id = $1 AND status IN ($4, $5)
+		 RETURNING id::text, participant_id::text, amount_cents`,
+		payoutID, PayoutBatchFailed, failureCode, PayoutBatchReleased, PayoutBatchPaid,
+	).Scan(&batchID, &ownerID, &amountCents)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("fail payout batch %s: %w", payoutID, err)
+	}
+	if _, err := tx.Exec(ctx,
+		`UPDATE job_metering SET payout_batch_id = NULL, payout_released_at = NULL
+		 WHERE payout_batch_id = $1`,
+		batchID,
+	); err != nil {
+		return false, fmt.Errorf("fail payout batch %s: release runs: %w", payoutID, err)
+	}
+	if err := postLedger(ctx, tx, ledgerPayoutReversal, batchID, "", []LedgerEntry{
+		{Account: AccountPayout, ParticipantID: ownerID, AmountCents: -amountCents},
+		{Account: AccountContributorPayable, ParticipantID: ownerID, AmountCents: amountCents},
+	}); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("fail payout batch %s: commit: %w", payoutID, err)
+	}
+	return true, nil
+}
+
+// PayoutHistory returns participantID's most recent payout batches, newest
+// first, up to limit, each with its runs as line items.
+func PayoutHistory(ctx context.Context, db *DB, participantID string, limit int) ([]PayoutBatch, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id::text, participant_id::text, stripe_account_id, amount_cents, status,
+		        COALESCE(payout_id, ''), COALESCE(failure_code, ''), created_at, released_at
+		 FROM payout_batches
+		 WHERE participant_id = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2`,
+		participantID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("payout history %s: %w", participantID, err)
+	}
+	var out []PayoutBatch
+	for rows.Next() {
+		var b PayoutBatch
+		if err := rows.Scan(&b.ID, &b.ParticipantID, &b.StripeAccountID, &b.AmountCents, &b.Status,
+			&b.PayoutID, &b.FailureCode, &b.CreatedAt, &b.ReleasedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("payout history %s: scan: %w", participantID, err)
+		}
+		out = append(out, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("payout history %s: rows: %w", participantID, err)
+	}
+
+	for i := range out {
+		lines, err := payoutLines(ctx, db, out[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		out[i].Lines = lines
+	}
+	return out, nil
+}
+
+// payoutLines returns the runs paid in batch batchID.
+func payoutLines(ctx context.Context, db *DB, batchID string) ([]PayoutLine, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT m.job_id::text, COALESCE(m.transferred_cents, m.contributor_earned_cents), j.completed_at
+		 FROM job_metering m
+		 JOIN jobs j ON j.id = m.job_id
+		 WHERE m.payout_batch_id = $1
+		 ORDER BY j.completed_at`,
+		batchID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("payout lines %s: %w", batchID, err)
+	}
+	defer rows.Close()
+	var out []PayoutLine
+	for rows.Next() {
+		var l PayoutLine
+		if err := rows.Scan(&l.JobID, &l.AmountCents, &l.CompletedAt); err != nil {
+			return nil, fmt.Errorf("payout lines %s: scan: %w", batchID, err)
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPayoutScheduleDue(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	daily := PayoutSchedule{Schedule: PayoutDaily, ThresholdCents: 5000}
+	weekly := PayoutSchedule{Schedule: PayoutWeekly, ThresholdCents: 5000}
+	threshold := PayoutSchedule{Schedule: PayoutThreshold, ThresholdCents: 5000}
+
+	cases := []struct {
+		name      string
+		s         PayoutSchedule
+		pending   int64
+		lastBatch time.Time
+		want      bool
+	}{
+		{"daily, never paid", daily, 120, time.Time{}, true},
+		{"daily, paid a day ago", daily, 120, now.Add(-24 * time.Hour), true},
+		{"daily, paid this morning", daily, 120, now.Add(-3 * time.Hour), false},
+		{"daily, nothing pending", daily, 0, time.Time{}, false},
+		{"weekly, paid six days ago", weekly, 9000, now.Add(-6 * 24 * time.Hour), false},
+		{"weekly, paid a week ago", weekly, 120, now.Add(-7 * 24 * time.Hour), true},
+		{"threshold, below", threshold, 4999, time.Time{}, false},
+		{"threshold, reached", threshold, 5000, now.Add(-time.Hour), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.s.due(tc.pending, tc.lastBatch, now); got != tc.want {
+				t.Errorf("due = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
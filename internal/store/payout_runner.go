@@ -8,11 +8,13 @@ import (
 	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
 )
 
-// RunPayoutReleaser runs in a goroutine and releases payouts for eligible
-// jobs every interval. It calls EligiblePayouts, then for each candidate
-// calls pc.TriggerPayout. Errors per-candidate are logged and skipped —
-// a failed payout does not stop processing other candidates.
-func RunPayoutReleaser(ctx context.Context, db *DB, pc *payment.Client, interval time.Duration) error {
+// RunPayoutReleaser runs in a goroutine and releases payouts every interval.
+// Each tick it batches eligible runs per node owner whose payout schedule is
+// due (BatchPayouts), then pays every pending batch with one pc.TriggerPayout
+// to the owner's connected account. Errors per-batch are logged and skipped —
+// a failed payout does not stop processing other batches, and the batch stays
+// pending for the next tick.
+func RunPayoutReleaser(ctx context.Context, db *DB, pc payment.PaymentProvider, interval time.Duration) error {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -21,37 +23,42 @@ func RunPayoutReleaser(ctx context.Context, db *DB, pc *payment.Client, interval
 		case <-ctx.Done():
 			return nil
 		case <-ticker.C:
-			candidates, err := EligiblePayouts(ctx, db)
-			if err != nil {
-				slog.Warn("payout releaser: EligiblePayouts error", "error", err)
-				continue
-			}
-
-			for _, c := range candidates {
-				// Key the payout on the job id: a retry after a failed
-				// bookkeeping write below reuses this key, so Stripe dedupes
-				// instead of paying twice (audit finding M1).
-				_, err := pc.TriggerPayout(ctx, c.ProviderStripeAccountID, c.ContributorEarnedCents, c.JobID)
-				if err != nil {
-					slog.Warn("payout releaser: TriggerPayout failed",
-						"job_id", c.JobID,
-						"stripe_account", c.ProviderStripeAccountID,
-						"error", err,
-					)
-					continue
-				}
-
-				_, dbErr := db.Pool.Exec(ctx,
-					`UPDATE job_metering SET payout_released_at = NOW() WHERE job_id = $1`,
-					c.JobID,
-				)
-				if dbErr != nil {
-					slog.Warn("payout releaser: failed to mark payout_released_at",
-						"job_id", c.JobID,
-						"error", dbErr,
-					)
-				}
-			}
+			releasePayouts(ctx, db, pc, time.Now())
+		}
+	}
+}
+
+// releasePayouts is one RunPayoutReleaser tick at now.
+func releasePayouts(ctx context.Context, db *DB, pc payment.PaymentProvider, now time.Time) {
+	if _, err := BatchPayouts(ctx, db, now); err != nil {
+		slog.Warn("payout releaser: BatchPayouts error", "error", err)
+	}
+
+	batches, err := PendingPayoutBatches(ctx, db)
+	if err != nil {
+		slog.Warn("payout releaser: PendingPayoutBatches error", "error", err)
+		return
+	}
+
+	for _, b := range batches {
+		// Key the payout on the batch id: a retry after a failed bookkeeping
+		// write below reuses this key, so Stripe dedupes instead of paying
+		// twice (audit finding M1).
+		payoutID, err := pc.TriggerPayout(ctx, b.StripeAccountID, b.AmountCents, b.ID)
+		if err != nil {
+			slog.Warn("payout releaser: TriggerPayout failed",
+				"batch_id", b.ID,
+				"stripe_account", b.StripeAccountID,
+				"error", err,
+			)
+			continue
+		}
+
+		if dbErr := MarkPayoutBatchReleased(ctx, db, b.ID, payoutID); dbErr != nil {
+			slog.Warn("payout releaser: failed to mark batch released",
+				"batch_id", b.ID,
+				"error", dbErr,
+			)
 		}
 	}
 }
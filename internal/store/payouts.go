@@ -5,29 +5,45 @@ import "context"
 // PayoutCandidate holds the identifiers needed to release a payout to a provider.
 type PayoutCandidate struct {
 	JobID                   string
+	ProviderID              string // the node owner's participant id
 	ProviderStripeAccountID string
 	ContributorEarnedCents  int64
 }
 
 // EligiblePayouts returns jobs that are ready for payout release:
-// completed more than 24 hours ago, no open or under_review dispute,
-// and the provider has a stripe_account_id set.
+// completed (or cancelled by the consumer mid-run, for the metered partial
+// run) more than 24 hours ago, no open or under_review dispute and no
+// chargeback still open or lost (migration 041), not flagged
+// suspected_fraud by result verification, and the provider has a
+// stripe_account_id set. The contributor's share must already be in the
+// connected account: for an escrowed job (migration 038) its escrow
+// transfer, paid out at the transferred amount; otherwise a charge on the
+// job (amount_cents > 0). A run already in a payout batch (migration 040) is
+// not returned.
 func EligiblePayouts(ctx context.Context, db *DB) ([]PayoutCandidate, error) {
 	rows, err := db.Pool.Query(ctx, `
-		SELECT j.id, p.stripe_account_id, jm.contributor_earned_cents
+		SELECT j.id, p.id, p.stripe_account_id,
+		       COALESCE(jm.transferred_cents, jm.contributor_earned_cents)
 		FROM jobs j
 		JOIN nodes n ON n.id = j.node_id
 		JOIN participants p ON p.id = n.participant_id
 		JOIN job_metering jm ON jm.job_id = j.id
 		LEFT JOIN disputes d ON d.job_id = j.id
 		    AND d.status IN ('open', 'under_review')
-		WHERE j.status = 'completed'
+		WHERE (j.status = 'completed'
+		       OR (j.status = 'failed' AND j.failure_cause = 'consumer_cancelled'))
 		  AND j.completed_at < NOW() - INTERVAL '24 hours'
-		  AND j.amount_cents > 0
+		  AND CASE WHEN j.payment_status IS NULL THEN j.amount_cents > 0
+		           ELSE jm.transfer_id IS NOT NULL END
 		  AND p.stripe_account_id IS NOT NULL
 		  AND d.id IS NULL
-		  AND jm.contributor_earned_cents > 0
-		  AND jm.payout_released_at IS NULL`)
+		  AND NOT EXISTS (SELECT 1 FROM stripe_disputes sd
+		                  WHERE sd.job_id IN (j.id, j.parent_job_id)
+		                    AND sd.status NOT IN ('won', 'warning_closed'))
+		  AND NOT j.suspected_fraud
+		  AND COALESCE(jm.transferred_cents, jm.contributor_earned_cents) > 0
+		  AND jm.payout_released_at IS NULL
+		  AND jm.payout_batch_id IS NULL`)
 	if err != nil {
 		return nil, err
 	}
@@ -36,7 +52,7 @@ func EligiblePayouts(ctx context.Context, db *DB) ([]PayoutCandidate, error) {
 	var candidates []PayoutCandidate
 	for rows.Next() {
 		var c PayoutCandidate
-		if err := rows.Scan(&c.JobID, &c.ProviderStripeAccountID, &c.ContributorEarnedCents); err != nil {
+		if err := rows.Scan(&c.JobID, &c.ProviderID, &c.ProviderStripeAccountID, &c.ContributorEarnedCents); err != nil {
 			return nil, err
 		}
 		candidates = append(candidates, c)
@@ -0,0 +1,434 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// This file manages effective-dated resource_pricing rates from the :8090
+// governance console (migration 044). A rate covers [EffectiveFrom,
+// EffectiveUntil); ratesAt reads the one covering an instant. Rates are only
+// ever scheduled from now on and retired from now on, so a change never
+// reprices a job already metered or quoted.
+
+// PricingResourceTypes are the resource_type enum values, in display order.
+var PricingResourceTypes = []string{"cpu_core_hr", "ram_gb_hr", "gpu_vram_gb_hr", "storage_gb_mo", "egress_gb"}
+
+// pricingPreviewWindow is how far back PreviewPricing reprices metered jobs.
+const pricingPreviewWindow = 30 * 24 * time.Hour
+
+// Errors from SchedulePricing and RetirePricing.
+var (
+	ErrUnknownResourceType = errors.New("store: unknown resource type")
+	ErrPricingNotFound     = errors.New("store: pricing rate not found")
+	// ErrPricingRetroactive: the change would take effect in the past.
+	ErrPricingRetroactive = errors.New("store: pricing change would take effect in the past")
+	// ErrPricingOverlap: a rate for the resource type already starts at or
+	// after the new rate's effective_from.
+	ErrPricingOverlap = errors.New("store: pricing rate overlaps a scheduled rate")
+	// ErrPricingRetired: the rate already ends by the requested instant.
+	ErrPricingRetired = errors.New("store: pricing rate already retired")
+	// ErrPricingHasSuccessor: a later rate is scheduled after this one; retire
+	// that first.
+	ErrPricingHasSuccessor = errors.New("store: pricing rate has a later rate scheduled")
+)
+
+// PricingRate is one resource_pricing row.
+type PricingRate struct {
+	ID               string
+	ResourceType     string
+	BaseRate         float64
+	ContributorShare float64
+	ReliabilityFloor float64
+	EffectiveFrom    time.Time
+	EffectiveUntil   *time.Time
+	CreatedAt        time.Time
+}
+
+// Cancelled reports whether the rate was retired before it took effect.
+func (p PricingRate) Cancelled() bool {
+	return p.EffectiveUntil != nil && !p.EffectiveUntil.After(p.EffectiveFrom)
+}
+
+// Status is the rate's state at now: "scheduled", "active", "retired" or
+// "cancelled".
+func (p PricingRate) Status(now time.Time) string {
+	switch {
+	case p.Cancelled():
+		return "cancelled"
+	case p.EffectiveFrom.After(now):
+		return "scheduled"
+	case p.EffectiveUntil != nil && !p.EffectiveUntil.After(now):
+		return "retired"
+	default:
+		return "active"
+	}
+}
+
+// PricingChange is one resource_pricing_changes audit row.
+type PricingChange struct {
+	ID             string
+	Action         string // "schedule", "retire" or "cancel"
+	PricingID      string
+	ReplacedID     string // "" when none
+	ResourceType   string
+	BaseRate       float64
+	EffectiveFrom  time.Time
+	EffectiveUntil *time.Time
+	ChangedBy      string
+	Reason         string
+	CreatedAt      time.Time
+}
+
+// NewPricingRate is a rate to schedule. A nil ContributorShare carries the
+// replaced rate's forward. A zero EffectiveFrom means now.
+type NewPricingRate struct {
+	ResourceType     string
+	BaseRate         float64
+	ContributorShare *float64
+	EffectiveFrom    time.Time
+	ChangedBy        string
+	Reason           string
+}
+
+// PricingImpact is what a rate for ResourceType would have charged the jobs
+// metered since Since, against what they were charged. Each breakdown line is
+// repriced at the proposed rate with its own quantity and multiplier, rounded
+// to the cent as metering does.
+type PricingImpact struct {
+	ResourceType  string
+	Since         time.Time
+	CurrentRate   float64 // the rate in effect now; 0 when none
+	ProposedRate  float64
+	Jobs          int
+	CurrentCents  int64
+	ProposedCents int64
+}
+
+const pricingColumns = `id::text, resource_type::text, base_rate::float8, contributor_share::float8,
+	reliability_floor::float8, effective_from, effective_until, created_at`
+
+func scanPricingRate(row pgx.Row) (PricingRate, error) {
+	var p PricingRate
+	err := row.Scan(&p.ID, &p.ResourceType, &p.BaseRate, &p.ContributorShare,
+		&p.ReliabilityFloor, &p.EffectiveFrom, &p.EffectiveUntil, &p.CreatedAt)
+	return p, err
+}
+
+// ListPricing returns every resource_pricing rate, by resource type and then
+// effective_from.
+func ListPricing(ctx context.Context, db *DB) ([]PricingRate, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+pricingColumns+`
+		 FROM resource_pricing
+		 ORDER BY resource_type, effective_from, created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("list pricing: %w", err)
+	}
+	defer rows.Close()
+
+	var rates []PricingRate
+	for rows.Next() {
+		p, err := scanPricingRate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list pricing: scan: %w", err)
+		}
+		rates = append(rates, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list pricing: %w", err)
+	}
+	return rates, nil
+}
+
+// PricingHistory returns the most recent limit pricing changes, newest first.
+func PricingHistory(ctx context.Context, db *DB, limit int) ([]PricingChange, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id::text, action, pricing_id::text, COALESCE(replaced_id::text, ''),
+		        resource_type::text, base_rate::float8, effective_from, effective_until,
+		        changed_by, reason, created_at
+		 FROM resource_pricing_changes
+		 ORDER BY created_at DESC
+		 LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("pricing history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []PricingChange
+	for rows.Next() {
+		var c PricingChange
+		if err := rows.Scan(&c.ID, &c.Action, &c.PricingID, &c.ReplacedID,
+			&c.ResourceType, &c.BaseRate, &c.EffectiveFrom, &c.EffectiveUntil,
+			&c.ChangedBy, &c.Reason, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("pricing history: scan: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("pricing history: %w", err)
+	}
+	return changes, nil
+}
+
+// lockPricing serializes pricing changes to one resource type for the rest of
+// tx and returns its rates, oldest first. Row locks alone would not stop two
+// concurrent schedules from each inserting a rate the other's overlap check
+// cannot see, so the lock is a transaction-scoped advisory lock on the type.
+func lockPricing(ctx context.Context, tx pgx.Tx, resourceType string) ([]PricingRate, error) {
+	if _, err := tx.Exec(ctx,
+		`SELECT pg_advisory_xact_lock(hashtextextended('resource_pricing:' || $1, 0))`,
+		resourceType,
+	); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx,
+		`SELECT `+pricingColumns+`
+		 FROM resource_pricing
+		 WHERE resource_type = $1::resource_type
+		 ORDER BY effective_from, created_at`,
+		resourceType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []PricingRate
+	for rows.Next() {
+		p, err := scanPricingRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, p)
+	}
+	return rates, rows.Err()
+}
+
+// SchedulePricing adds a rate for r.ResourceType from r.EffectiveFrom on,
+// open-ended. The rate in effect at that instant — or, failing that, the
+// latest rate before it — is closed there. A rate already starting at or
+// after EffectiveFrom is ErrPricingOverlap: retire it first. EffectiveFrom
+// in the past is ErrPricingRetroactive. The change is recorded in
+// resource_pricing_changes in the same transaction.
+func SchedulePricing(ctx context.Context, db *DB, r NewPricingRate) (PricingRate, error) {
+	if !slices.Contains(PricingResourceTypes, r.ResourceType) {
+		return PricingRate{}, fmt.Errorf("schedule pricing %q: %w", r.ResourceType, ErrUnknownResourceType)
+	}
+	now := time.Now()
+	if r.EffectiveFrom.IsZero() {
+		r.EffectiveFrom = now
+	}
+	if r.EffectiveFrom.Before(now) {
+		return PricingRate{}, fmt.Errorf("schedule pricing %s: %w", r.ResourceType, ErrPricingRetroactive)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return PricingRate{}, fmt.Errorf("schedule pricing %s: begin: %w", r.ResourceType, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	rates, err := lockPricing(ctx, tx, r.ResourceType)
+	if err != nil {
+		return PricingRate{}, fmt.Errorf("schedule pricing %s: lock: %w", r.ResourceType, err)
+	}
+	var replaced *PricingRate
+	for i := range rates {
+		p := &rates[i]
+		if p.Cancelled() {
+			continue
+		}
+		if !p.EffectiveFrom.Before(r.EffectiveFrom) {
+			return PricingRate{}, fmt.Errorf("schedule pricing %s: rate %s from %s: %w",
+				r.ResourceType, p.ID, p.EffectiveFrom.UTC().Format(time.RFC3339), ErrPricingOverlap)
+		}
+		replaced = p
+	}
+
+	share, floor := 0.65, 0.9 // migration 004's column defaults
+	if replaced != nil {
+		share, floor = replaced.ContributorShare, replaced.ReliabilityFloor
+		if replaced.EffectiveUntil == nil || replaced.EffectiveUntil.After(r.EffectiveFrom) {
+			if _, err := tx.Exec(ctx,
+				`UPDATE resource_pricing SET effective_until = $2 WHERE id = $1`,
+				replaced.ID, r.EffectiveFrom,
+			); err != nil {
+				return PricingRate{}, fmt.Errorf("schedule pricing %s: close replaced rate: %w", r.ResourceType, err)
+			}
+		}
+	}
+	if r.ContributorShare != nil {
+		share = *r.ContributorShare
+	}
+
+	p, err := scanPricingRate(tx.QueryRow(ctx,
+		`INSERT INTO resource_pricing
+		    (resource_type, base_rate, contributor_share, reliability_floor, effective_from)
+		 VALUES ($1::resource_type, $2, $3, $4, $5)
+		 RETURNING `+pricingColumns,
+		r.ResourceType, r.BaseRate, share, floor, r.EffectiveFrom,
+	))
+	if err != nil {
+		return PricingRate{}, fmt.Errorf("schedule pricing %s: insert: %w", r.ResourceType, err)
+	}
+	var replacedID *string
+	if replaced != nil {
+		replacedID = &replaced.ID
+	}
+	if err := recordPricingChange(ctx, tx, "schedule", p, replacedID, r.ChangedBy, r.Reason); err != nil {
+		return PricingRate{}, fmt.Errorf("schedule pricing %s: %w", r.ResourceType, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return PricingRate{}, fmt.Errorf("schedule pricing %s: commit: %w", r.ResourceType, err)
+	}
+	return p, nil
+}
+
+// RetirePricing ends rate id at at (zero: now), leaving its resource type
+// unpriced from then until a new rate is scheduled. A rate retired before it
+// takes effect is cancelled instead, and the rate it had closed is reopened.
+// Only the latest rate of a type can be retired (ErrPricingHasSuccessor); at
+// in the past is ErrPricingRetroactive, and a rate already ending by at is
+// ErrPricingRetired.
+func RetirePricing(ctx context.Context, db *DB, id string, at time.Time, changedBy, reason string) (PricingRate, error) {
+	now := time.Now()
+	if at.IsZero() {
+		at = now
+	}
+	if at.Before(now) {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: %w", id, ErrPricingRetroactive)
+	}
+
+	var resourceType string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT resource_type::text FROM resource_pricing WHERE id::text = $1`, id,
+	).Scan(&resourceType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: %w", id, ErrPricingNotFound)
+	}
+	if err != nil {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: %w", id, err)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: begin: %w", id, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	rates, err := lockPricing(ctx, tx, resourceType)
+	if err != nil {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: lock: %w", id, err)
+	}
+	i := slices.IndexFunc(rates, func(p PricingRate) bool { return p.ID == id })
+	if i < 0 {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: %w", id, ErrPricingNotFound)
+	}
+	p := rates[i]
+	if p.EffectiveUntil != nil && !p.EffectiveUntil.After(at) {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: %w", id, ErrPricingRetired)
+	}
+	for _, later := range rates[i+1:] {
+		if !later.Cancelled() {
+			return PricingRate{}, fmt.Errorf("retire pricing %s: rate %s: %w", id, later.ID, ErrPricingHasSuccessor)
+		}
+	}
+
+	action, until := "retire", at
+	var reopened *string
+	if !at.After(p.EffectiveFrom) {
+		action, until = "cancel", p.EffectiveFrom
+		// The rate this one replaced was closed at its effective_from by
+		// SchedulePricing; give it back what this one would have covered.
+		for j := i - 1; j >= 0; j-- {
+			prev := rates[j]
+			if prev.Cancelled() {
+				continue
+			}
+			if prev.EffectiveUntil != nil && prev.EffectiveUntil.Equal(p.EffectiveFrom) {
+				if _, err := tx.Exec(ctx,
+					`UPDATE resource_pricing SET effective_until = $2 WHERE id = $1`,
+					prev.ID, p.EffectiveUntil,
+				); err != nil {
+					return PricingRate{}, fmt.Errorf("retire pricing %s: reopen replaced rate: %w", id, err)
+				}
+				reopened = &prev.ID
+			}
+			break
+		}
+	}
+
+	p, err = scanPricingRate(tx.QueryRow(ctx,
+		`UPDATE resource_pricing SET effective_until = $2 WHERE id = $1
+		 RETURNING `+pricingColumns,
+		p.ID, until,
+	))
+	if err != nil {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: update: %w", id, err)
+	}
+	if err := recordPricingChange(ctx, tx, action, p, reopened, changedBy, reason); err != nil {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: %w", id, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return PricingRate{}, fmt.Errorf("retire pricing %s: commit: %w", id, err)
+	}
+	return p, nil
+}
+
+func recordPricingChange(ctx context.Context, tx pgx.Tx, action string, p PricingRate, replacedID *string, changedBy, reason string) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO resource_pricing_changes
+		    (action, pricing_id, replaced_id, resource_type, base_rate,
+		     effective_from, effective_until, changed_by, reason)
+		 VALUES ($1, $2, $3, $4::resource_type, $5, $6, $7, $8, $9)`,
+		action, p.ID, replacedID, p.ResourceType, p.BaseRate,
+		p.EffectiveFrom, p.EffectiveUntil, changedBy, reason,
+	)
+	if err != nil {
+		return fmt.Errorf("record pricing change: %w", err)
+	}
+	return nil
+}
+
+// PreviewPricing reprices the last 30 days of metered jobs' resourceType
+// lines at rate; 0 previews retiring the type's rate.
+func PreviewPricing(ctx context.Context, db *DB, resourceType string, rate float64) (PricingImpact, error) {
+	if !slices.Contains(PricingResourceTypes, resourceType) {
+		return PricingImpact{}, fmt.Errorf("preview pricing %q: %w", resourceType, ErrUnknownResourceType)
+	}
+	now := time.Now()
+	impact := PricingImpact{
+		ResourceType: resourceType,
+		Since:        now.Add(-pricingPreviewWindow),
+		ProposedRate: rate,
+	}
+	rates, _, err := ratesAt(ctx, db, now)
+	if err != nil {
+		return PricingImpact{}, fmt.Errorf("preview pricing %s: read rates: %w", resourceType, err)
+	}
+	impact.CurrentRate = rates[resourceType]
+
+	err = db.Pool.QueryRow(ctx,
+		`SELECT COUNT(DISTINCT m.job_id),
+		        COALESCE(SUM((l->>'cents')::bigint), 0),
+		        COALESCE(SUM(ROUND((l->>'quantity')::numeric * $3::numeric
+		                           * (l->>'multiplier')::numeric * 100)), 0)::bigint
+		 FROM job_metering m
+		 CROSS JOIN LATERAL jsonb_array_elements(m.breakdown) l
+		 WHERE m.computed_at >= $2
+		   AND l->>'resource_type' = $1`,
+		resourceType, impact.Since, rate,
+	).Scan(&impact.Jobs, &impact.CurrentCents, &impact.ProposedCents)
+	if err != nil {
+		return PricingImpact{}, fmt.Errorf("preview pricing %s: %w", resourceType, err)
+	}
+	return impact, nil
+}
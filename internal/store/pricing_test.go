@@ -0,0 +1,114 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+func TestPricing_ScheduleRetireCancel(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	// Far-future instants keep the rates other tests meter at unchanged.
+	// TRUNCATE bypasses the audit table's append-only triggers.
+	t.Cleanup(func() {
+		for _, q := range []string{
+			`TRUNCATE resource_pricing_changes`,
+			`DELETE FROM resource_pricing
+			 WHERE resource_type = 'gpu_vram_gb_hr' AND effective_from >= '2100-01-01'`,
+			`UPDATE resource_pricing SET effective_until = NULL
+			 WHERE resource_type = 'gpu_vram_gb_hr' AND effective_until >= '2100-01-01'`,
+		} {
+			db.Pool.Exec(context.Background(), q) //nolint:errcheck
+		}
+	})
+
+	rates, err := store.ListPricing(ctx, db)
+	if err != nil {
+		t.Fatalf("ListPricing: %v", err)
+	}
+	var current store.PricingRate
+	for _, p := range rates {
+		if p.ResourceType == "gpu_vram_gb_hr" && p.Status(time.Now()) == "active" {
+			current = p
+		}
+	}
+	if current.ID == "" {
+		t.Fatal("no active gpu_vram_gb_hr rate seeded")
+	}
+
+	from := time.Date(2101, 1, 1, 0, 0, 0, 0, time.UTC)
+	next, err := store.SchedulePricing(ctx, db, store.NewPricingRate{
+		ResourceType: "gpu_vram_gb_hr", BaseRate: 0.5, EffectiveFrom: from, ChangedBy: "alice",
+	})
+	if err != nil {
+		t.Fatalf("SchedulePricing: %v", err)
+	}
+	if next.ContributorShare != current.ContributorShare {
+		t.Errorf("contributor share = %v, want %v carried forward", next.ContributorShare, current.ContributorShare)
+	}
+
+	_, err = store.SchedulePricing(ctx, db, store.NewPricingRate{
+		ResourceType: "gpu_vram_gb_hr", BaseRate: 0.6, EffectiveFrom: from.AddDate(-1, 0, 0), ChangedBy: "alice",
+	})
+	if !errors.Is(err, store.ErrPricingOverlap) {
+		t.Errorf("schedule before a scheduled rate: err = %v, want ErrPricingOverlap", err)
+	}
+	_, err = store.SchedulePricing(ctx, db, store.NewPricingRate{
+		ResourceType: "gpu_vram_gb_hr", BaseRate: 0.6, EffectiveFrom: time.Now().Add(-time.Hour), ChangedBy: "alice",
+	})
+	if !errors.Is(err, store.ErrPricingRetroactive) {
+		t.Errorf("schedule in the past: err = %v, want ErrPricingRetroactive", err)
+	}
+	if _, err := store.RetirePricing(ctx, db, current.ID, time.Time{}, "bob", ""); !errors.Is(err, store.ErrPricingHasSuccessor) {
+		t.Errorf("retire a replaced rate: err = %v, want ErrPricingHasSuccessor", err)
+	}
+
+	cancelled, err := store.RetirePricing(ctx, db, next.ID, time.Time{}, "bob", "changed our mind")
+	if err != nil {
+		t.Fatalf("RetirePricing: %v", err)
+	}
+	if got := cancelled.Status(time.Now()); got != "cancelled" {
+		t.Errorf("retired scheduled rate status = %q, want cancelled", got)
+	}
+	rates, _ = store.ListPricing(ctx, db)
+	for _, p := range rates {
+		if p.ID == current.ID && p.EffectiveUntil != nil {
+			t.Errorf("replaced rate not reopened: effective_until = %v", p.EffectiveUntil)
+		}
+	}
+
+	if _, err := store.SchedulePricing(ctx, db, store.NewPricingRate{
+		ResourceType: "gpu_vram_gb_hr", BaseRate: 0.7, EffectiveFrom: from, ChangedBy: "carol",
+	}); err != nil {
+		t.Errorf("schedule over a cancelled rate: %v", err)
+	}
+
+	history, err := store.PricingHistory(ctx, db, 10)
+	if err != nil {
+		t.Fatalf("PricingHistory: %v", err)
+	}
+	var actions []string
+	for _, c := range history {
+		actions = append(actions, c.Action+":"+c.ChangedBy)
+	}
+	want := []string{"schedule:carol", "cancel:bob", "schedule:alice"}
+	if len(actions) != len(want) {
+		t.Fatalf("history = %v, want %v", actions, want)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Errorf("history = %v, want %v", actions, want)
+			break
+		}
+	}
+	if history[1].ReplacedID != current.ID {
+		t.Errorf("cancel reopened %q, want %q", history[1].ReplacedID, current.ID)
+	}
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Quote is the most a job can cost on a node: its requested resources held
+// for its whole maximum runtime, priced as ComputeMetering would price them.
+// Egress is not quoted — it is metered only from telemetry.
+type Quote struct {
+	NodeID     string
+	Lines      []MeteringLine
+	TotalCents int64
+}
+
+// PricePin holds a job to a signed price quote (migration 042): the rates in
+// effect at QuotedAt, and at most Multiplier. The zero PricePin prices at the
+// current rates and the node's own multiplier.
+type PricePin struct {
+	QuotedAt   time.Time
+	Multiplier float64 // a ceiling; zero means none
+}
+
+// rates returns the rates the pin prices at.
+func (p PricePin) rates(ctx context.Context, db *DB) (map[string]float64, error) {
+	at := p.QuotedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	rates, _, err := ratesAt(ctx, db, at)
+	return rates, err
+}
+
+// multiplier returns the multiplier the pin allows for a node whose own is m.
+func (p PricePin) multiplier(m float64) float64 {
+	if p.Multiplier > 0 && p.Multiplier < m {
+		return p.Multiplier
+	}
+	return m
+}
+
+// QuoteJob prices req on nodeID for maxRuntime at the rates and the node's
+// default price multiplier, as pinned by pin. Metering never bills more than
+// the request for the time run, so a job stopped at its max runtime is
+// metered at most this (less any egress).
+func QuoteJob(ctx context.Context, db *DB, nodeID string, req ResourceRequest, maxRuntime time.Duration, pin PricePin) (Quote, error) {
+	var n nodePricing
+	err := db.Pool.QueryRow(ctx, `
+		SELECT COALESCE(rp.cpu_enabled, true),
+		       COALESCE(rp.ram_pct, 100),
+		       COALESCE(rp.storage_gb, 0),
+		       COALESCE(rp.price_multiplier, 1.0),
+		       COALESCE((n.hardware_profile->>'cpu_cores')::int,
+		                (n.hardware_profile->>'CPUCores')::int, 0),
+		       COALESCE((n.hardware_profile->>'ram_mb')::bigint,
+		                (n.hardware_profile->>'RAMMB')::bigint, 0)
+		FROM nodes n
+		LEFT JOIN resource_profiles rp ON rp.node_id = n.id AND rp.is_default = TRUE
+		WHERE n.id = $1`,
+		nodeID,
+	).Scan(&n.cpuEnabled, &n.ramPct, &n.storageGB, &n.priceMultiplier, &n.hwCores, &n.hwRAMMB)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Quote{}, fmt.Errorf("quote job on %s: %w", nodeID, ErrNodeNotFound)
+		}
+		return Quote{}, fmt.Errorf("quote job on %s: read node: %w", nodeID, err)
+	}
+	n.priceMultiplier = pin.multiplier(n.priceMultiplier)
+
+	rates, err := pin.rates(ctx, db)
+	if err != nil {
+		return Quote{}, fmt.Errorf("quote job on %s: read rates: %w", nodeID, err)
+	}
+	q := Quote{NodeID: nodeID}
+	q.Lines, q.TotalCents = priceQuote(n, req, maxRuntime, rates)
+	return q, nil
+}
+
+// priceQuote prices req held on the node for maxRuntime: integrateUsage with
+// no telemetry over a window of that length bills exactly the request.
+func priceQuote(n nodePricing, req ResourceRequest, maxRuntime time.Duration, rates map[string]float64) ([]MeteringLine, int64) {
+	b := n.basis(req)
+	b.completedAt = b.startedAt.Add(maxRuntime)
+	return priceUsage(integrateUsage(b, nil), rates, n.priceMultiplier)
+}
+
+// NodeEstimate is the price range of a job on one node: LowCents for its
+// expected runtime, HighCents for its maximum runtime — the most it can be
+// metered, as QuoteJob. Lines are the HighCents lines.
+type NodeEstimate struct {
+	NodeID          string
+	PriceMultiplier float64
+	LowCents        int64
+	HighCents       int64
+	Lines           []MeteringLine
+}
+
+// EstimateJob prices req on each of nodeIDs at the rates in effect at at and
+// each node's default price multiplier, cheapest first. Nodes that no longer
+// exist are left out.
+func EstimateJob(ctx context.Context, db *DB, nodeIDs []string, req ResourceRequest, expected, maxRuntime time.Duration, at time.Time) ([]NodeEstimate, error) {
+	rows, err := db.Pool.Query(ctx, `
+		SELECT n.id::text,
+		       COALESCE(rp.cpu_enabled, true),
+		       COALESCE(rp.ram_pct, 100),
+		       COALESCE(rp.storage_gb, 0),
+		       COALESCE(rp.price_multiplier, 1.0),
+		       COALESCE((n.hardware_profile->>'cpu_cores')::int,
+		                (n.hardware_profile->>'CPUCores')::int, 0),
+		       COALESCE((n.hardware_profile->>'ram_mb')::bigint,
+		                (n.hardware_profile->>'RAMMB')::bigint, 0)
+		FROM nodes n
+		LEFT JOIN resource_profiles rp ON rp.node_id = n.id AND rp.is_default = TRUE
+		WHERE n.id::text = ANY($1)`,
+		nodeIDs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("estimate job: read nodes: %w", err)
+	}
+	defer rows.Close()
+	type node struct {
+		id string
+		nodePricing
+	}
+	var nodes []node
+	for rows.Next() {
+		var n node
+		if err := rows.Scan(&n.id, &n.cpuEnabled, &n.ramPct, &n.storageGB, &n.priceMultiplier,
+			&n.hwCores, &n.hwRAMMB); err != nil {
+			return nil, fmt.Errorf("estimate job: scan node: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("estimate job: read nodes: %w", err)
+	}
+
+	rates, _, err := ratesAt(ctx, db, at)
+	if err != nil {
+		return nil, fmt.Errorf("estimate job: read rates: %w", err)
+	}
+	out := make([]NodeEstimate, len(nodes))
+	for i, n := range nodes {
+		_, low := priceQuote(n.nodePricing, req, expected, rates)
+		lines, high := priceQuote(n.nodePricing, req, maxRuntime, rates)
+		out[i] = NodeEstimate{
+			NodeID:          n.id,
+			PriceMultiplier: n.priceMultiplier,
+			LowCents:        low,
+			HighCents:       high,
+			Lines:           lines,
+		}
+	}
+	slices.SortFunc(out, func(a, b NodeEstimate) int {
+		return cmp.Or(cmp.Compare(a.HighCents, b.HighCents), cmp.Compare(a.LowCents, b.LowCents))
+	})
+	return out, nil
+}
@@ -0,0 +1,414 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+)
+
+// reconcileLookback bounds each reconciliation run to money movements from
+// the last 30 days, so a run's provider calls stay proportional to recent
+// activity rather than all history.
+const reconcileLookback = 30 * 24 * time.Hour
+
+// ErrNoReconciliation is returned by LatestReconciliation before the first
+// reconciliation run.
+var ErrNoReconciliation = errors.New("store: no reconciliation run")
+
+// Discrepancy is one difference between the payment provider's record of a
+// money movement and ours, found by Reconcile.
+type Discrepancy struct {
+	ObjectType string // "payment_intent", "transfer" or "payout"
+	ObjectID   string // the provider's id
+	Ref        string // our job, or payout batch
+	Check      string // what differs, e.g. "captured", "status"
+	Ours       string
+	Provider   string
+}
+
+// ReconciliationReport is one reconciliation run and what it found.
+type ReconciliationReport struct {
+	ID             string
+	StartedAt      time.Time
+	FinishedAt     time.Time
+	ObjectsChecked int
+	Discrepancies  []Discrepancy
+}
+
+// ourPayment is our record of a job's consumer payment: an escrow hold
+// (PaymentStatus set) or a destination charge (PaymentStatus "").
+type ourPayment struct {
+	JobID           string
+	PaymentIntentID string
+	PaymentStatus   string
+	AmountCents     int64
+	CapturedCents   int64 // escrow only
+	RefundedCents   int64 // the job's refund ledger entries
+	Chargeback      bool
+}
+
+// expectedIntentStatuses are the Stripe PaymentIntent statuses consistent
+// with each of our escrow payment statuses; a destination charge ("") must
+// have succeeded.
+var expectedIntentStatuses = map[string][]string{
+	PaymentAwaiting:   {"requires_payment_method", "requires_confirmation", "requires_action", "processing"},
+	PaymentAuthorized: {"requires_capture"},
+	PaymentCaptured:   {"succeeded"},
+	PaymentReleased:   {"canceled"},
+	"":                {"succeeded"},
+
+	PaymentSettlementFailed: {"requires_capture", "canceled"},
+}
+
+// comparePayment returns the differences between our record of a payment
+// and the provider's.
+func comparePayment(ours ourPayment, theirs payment.PaymentRecord) []Discrepancy {
+	d := func(check, o, p string) Discrepancy {
+		return Discrepancy{
+			ObjectType: "payment_intent", ObjectID: ours.PaymentIntentID, Ref: ours.JobID,
+			Check: check, Ours: o, Provider: p,
+		}
+	}
+	var out []Discrepancy
+
+	if !slices.Contains(expectedIntentStatuses[ours.PaymentStatus], string(theirs.Status)) {
+		ourStatus := ours.PaymentStatus
+		if ourStatus == "" {
+			ourStatus = "charged"
+		}
+		out = append(out, d("status", ourStatus, string(theirs.Status)))
+	}
+
+	captured := ours.CapturedCents
+	if ours.PaymentStatus == "" {
+		captured = ours.AmountCents
+	} else if ours.PaymentStatus != PaymentCaptured {
+		captured = 0
+	}
+	if captured != theirs.CapturedCents {
+		out = append(out, d("captured", cents(captured), cents(theirs.CapturedCents)))
+	}
+	if ours.RefundedCents != theirs.RefundedCents {
+		out = append(out, d("refunded", cents(ours.RefundedCents), cents(theirs.RefundedCents)))
+	}
+	if theirs.Disputed && !ours.Chargeback {
+		out = append(out, d("chargeback", "none recorded", "disputed"))
+	}
+	return out
+}
+
+// ourTransfer is our record of a run's escrow transfer.
+type ourTransfer struct {
+	JobID       string
+	TransferID  string
+	AmountCents int64
+}
+
+// compareTransfer returns the difference, if any, between what we recorded
+// transferring and what the provider says remains transferred.
+func compareTransfer(ours ourTransfer, theirs payment.TransferRecord) []Discrepancy {
+	if net := theirs.AmountCents - theirs.ReversedCents; net != ours.AmountCents {
+		return []Discrepancy{{
+			ObjectType: "transfer", ObjectID: ours.TransferID, Ref: ours.JobID,
+			Check: "amount", Ours: cents(ours.AmountCents), Provider: cents(net),
+		}}
+	}
+	return nil
+}
+
+// ourPayout is our record of a released payout batch.
+type ourPayout struct {
+	BatchID         string
+	StripeAccountID string
+	PayoutID        string
+	AmountCents     int64
+	Status          string // PayoutBatchReleased, …Paid or …Failed
+}
+
+// comparePayout returns the differences between a payout batch and the
+// provider's payout. A released batch may still be in flight; paid and
+// failed must match exactly.
+func comparePayout(ours ourPayout, theirs payment.PayoutRecord) []Discrepancy {
+	d := func(check, o, p string) Discrepancy {
+		return Discrepancy{
+			ObjectType: "payout", ObjectID: ours.PayoutID, Ref: ours.BatchID,
+			Check: check, Ours: o, Provider: p,
+		}
+	}
+	var out []Discrepancy
+	if ours.AmountCents != theirs.AmountCents {
+		out = append(out, d("amount", cents(ours.AmountCents), cents(theirs.AmountCents)))
+	}
+	failed := theirs.Status == "failed" || theirs.Status == "canceled"
+	var statusOK bool
+	switch ours.Status {
+	case PayoutBatchReleased:
+		statusOK = !failed
+	case PayoutBatchPaid:
+		statusOK = theirs.Status == "paid"
+	case PayoutBatchFailed:
+		statusOK = failed
+	}
+	if !statusOK {
+		out = append(out, d("status", ours.Status, string(theirs.Status)))
+	}
+	return out
+}
+
+// cents formats an amount for a Discrepancy.
+func cents(c int64) string {
+	return strconv.FormatInt(c, 10) + "¢"
+}
+
+// Reconcile compares the provider's record of every payment, escrow transfer
+// and payout from the last 30 days with ours, and stores the run and its
+// discrepancies. An object the provider cannot return is itself a
+// discrepancy ("retrieve").
+func Reconcile(ctx context.Context, db *DB, pc payment.PaymentProvider) (ReconciliationReport, error) {
+	report := ReconciliationReport{StartedAt: time.Now()}
+	since := report.StartedAt.Add(-reconcileLookback)
+
+	payments, err := paymentsToReconcile(ctx, db, since)
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+	transfers, err := transfersToReconcile(ctx, db, since)
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+	payouts, err := payoutsToReconcile(ctx, db, since)
+	if err != nil {
+		return ReconciliationReport{}, err
+	}
+
+	retrieveFailed := func(objectType, objectID, ref string, err error) Discrepancy {
+		return Discrepancy{
+			ObjectType: objectType, ObjectID: objectID, Ref: ref,
+			Check: "retrieve", Ours: "exists", Provider: err.Error(),
+		}
+	}
+	for _, p := range payments {
+		rec, err := pc.RetrievePayment(ctx, p.PaymentIntentID)
+		if err != nil {
+			report.Discrepancies = append(report.Discrepancies, retrieveFailed("payment_intent", p.PaymentIntentID, p.JobID, err))
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, comparePayment(p, rec)...)
+	}
+	for _, t := range transfers {
+		rec, err := pc.RetrieveTransfer(ctx, t.TransferID)
+		if err != nil {
+			report.Discrepancies = append(report.Discrepancies, retrieveFailed("transfer", t.TransferID, t.JobID, err))
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, compareTransfer(t, rec)...)
+	}
+	for _, p := range payouts {
+		rec, err := pc.RetrievePayout(ctx, p.StripeAccountID, p.PayoutID)
+		if err != nil {
+			report.Discrepancies = append(report.Discrepancies, retrieveFailed("payout", p.PayoutID, p.BatchID, err))
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, comparePayout(p, rec)...)
+	}
+	report.ObjectsChecked = len(payments) + len(transfers) + len(payouts)
+
+	if err := saveReconciliation(ctx, db, &report); err != nil {
+		return ReconciliationReport{}, err
+	}
+	return report, nil
+}
+
+// paymentsToReconcile returns our record of every job payment touched since
+// since. A replica group is paid on its parent.
+func paymentsToReconcile(ctx context.Context, db *DB, since time.Time) ([]ourPayment, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT j.id::text, j.payment_intent_id, COALESCE(j.payment_status, ''),
+		        j.amount_cents, COALESCE(j.captured_cents, 0),
+		        COALESCE((SELECT SUM(e.amount_cents)
+		                  FROM ledger_transactions t
+		                  JOIN ledger_entries e ON e.transaction_id = t.id
+		                  WHERE t.job_id = j.id AND e.account = 'refund'), 0),
+		        EXISTS (SELECT 1 FROM stripe_disputes sd WHERE sd.job_id = j.id)
+		 FROM jobs j
+		 WHERE j.parent_job_id IS NULL
+		   AND j.payment_intent_id IS NOT NULL
+		   AND j.payment_intent_id <> ''
+		   AND j.updated_at > $1`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("payments to reconcile: %w", err)
+	}
+	defer rows.Close()
+	var out []ourPayment
+	for rows.Next() {
+		var p ourPayment
+		if err := rows.Scan(&p.JobID, &p.PaymentIntentID, &p.PaymentStatus,
+			&p.AmountCents, &p.CapturedCents, &p.RefundedCents, &p.Chargeback); err != nil {
+			return nil, fmt.Errorf("payments to reconcile: scan: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// transfersToReconcile returns every escrow transfer recorded on a run
+// touched since since.
+func transfersToReconcile(ctx context.Context, db *DB, since time.Time) ([]ourTransfer, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT m.job_id::text, m.transfer_id, m.transferred_cents
+		 FROM job_metering m
+		 JOIN jobs j ON j.id = m.job_id
+		 WHERE m.transfer_id IS NOT NULL
+		   AND m.transfer_id <> ''
+		   AND j.updated_at > $1`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("transfers to reconcile: %w", err)
+	}
+	defer rows.Close()
+	var out []ourTransfer
+	for rows.Next() {
+		var t ourTransfer
+		if err := rows.Scan(&t.JobID, &t.TransferID, &t.AmountCents); err != nil {
+			return nil, fmt.Errorf("transfers to reconcile: scan: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// payoutsToReconcile returns every payout batch released since since.
+func payoutsToReconcile(ctx context.Context, db *DB, since time.Time) ([]ourPayout, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id::text, stripe_account_id, payout_id, amount_cents, status
+		 FROM payout_batches
+		 WHERE payout_id IS NOT NULL
+		   AND released_at > $1`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("payouts to reconcile: %w", err)
+	}
+	defer rows.Close()
+	var out []ourPayout
+	for rows.Next() {
+		var p ourPayout
+		if err := rows.Scan(&p.BatchID, &p.StripeAccountID, &p.PayoutID, &p.AmountCents, &p.Status); err != nil {
+			return nil, fmt.Errorf("payouts to reconcile: scan: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// saveReconciliation stores report and its discrepancies, setting its ID
+// and FinishedAt.
+func saveReconciliation(ctx context.Context, db *DB, report *ReconciliationReport) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("save reconciliation: begin: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO reconciliation_runs (started_at, objects_checked)
+		 VALUES ($1, $2) RETURNING id::text, finished_at`,
+		report.StartedAt, report.ObjectsChecked,
+	).Scan(&report.ID, &report.FinishedAt); err != nil {
+		return fmt.Errorf("save reconciliation: insert run: %w", err)
+	}
+	for _, d := range report.Discrepancies {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO reconciliation_discrepancies
+			   (run_id, object_type, object_id, ref, check_name, ours, stripe)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			report.ID, d.ObjectType, d.ObjectID, d.Ref, d.Check, d.Ours, d.Provider,
+		); err != nil {
+			return fmt.Errorf("save reconciliation: insert discrepancy: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("save reconciliation: commit: %w", err)
+	}
+	return nil
+}
+
+// LatestReconciliation returns the most recent reconciliation run with its
+// discrepancies, or ErrNoReconciliation before the first.
+func LatestReconciliation(ctx context.Context, db *DB) (ReconciliationReport, error) {
+	var r ReconciliationReport
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id::text, started_at, finished_at, objects_checked
+		 FROM reconciliation_runs
+		 ORDER BY finished_at DESC
+		 LIMIT 1`,
+	).Scan(&r.ID, &r.StartedAt, &r.FinishedAt, &r.ObjectsChecked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ReconciliationReport{}, ErrNoReconciliation
+	}
+	if err != nil {
+		return ReconciliationReport{}, fmt.Errorf("latest reconciliation: %w", err)
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT object_type, object_id, ref, check_name, ours, stripe
+		 FROM reconciliation_discrepancies
+		 WHERE run_id = $1
+		 ORDER BY id`,
+		r.ID,
+	)
+	if err != nil {
+		return ReconciliationReport{}, fmt.Errorf("latest reconciliation %s: %w", r.ID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d Discrepancy
+		if err := rows.Scan(&d.ObjectType, &d.ObjectID, &d.Ref, &d.Check, &d.Ours, &d.Provider); err != nil {
+			return ReconciliationReport{}, fmt.Errorf("latest reconciliation %s: scan: %w", r.ID, err)
+		}
+		r.Discrepancies = append(r.Discrepancies, d)
+	}
+	return r, rows.Err()
+}
+
+// RunReconciler runs in a goroutine and reconciles against pc every
+// interval, logging each discrepancy at warn level; the governance console
+// shows the latest run. A failed run is logged and retried next tick.
+func RunReconciler(ctx context.Context, db *DB, pc payment.PaymentProvider, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			report, err := Reconcile(ctx, db, pc)
+			if err != nil {
+				slog.Warn("reconciler: Reconcile error", "error", err)
+				continue
+			}
+			for _, d := range report.Discrepancies {
+				slog.Warn("reconciler: discrepancy",
+					"object_type", d.ObjectType,
+					"object_id", d.ObjectID,
+					"ref", d.Ref,
+					"check", d.Check,
+					"ours", d.Ours,
+					"stripe", d.Provider,
+				)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// TestReconcile_FailedPayout pays a batch through the in-memory ledger (the
+// Stripe stand-in), has the bank reject it, and checks reconciliation flags
+// the batch until the payout.failed webhook's FailPayoutBatch catches up.
+func TestReconcile_FailedPayout(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+	pc := payment.NewLedger()
+
+	jobID := seedMeteringJob(t, db, "reconcile_payout@test.com", 2.0)
+	var ownerID string
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT n.participant_id FROM jobs j JOIN nodes n ON n.id = j.node_id WHERE j.id = $1`, jobID,
+	).Scan(&ownerID); err != nil {
+		t.Fatalf("read owner: %v", err)
+	}
+	if err := store.ComputeMetering(ctx, db, jobID); err != nil {
+		t.Fatalf("ComputeMetering: %v", err)
+	}
+	var earned int64
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT contributor_earned_cents FROM job_metering WHERE job_id = $1`, jobID,
+	).Scan(&earned); err != nil {
+		t.Fatalf("read metering: %v", err)
+	}
+
+	acct, _ := pc.CreateConnectedAccount(ctx, "Node Owner", "reconcile_payout@test.com")
+	if _, err := pc.CreateDestinationCharge(ctx, earned, 0, acct); err != nil {
+		t.Fatalf("fund account: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE jobs SET amount_cents = $2, completed_at = NOW() - INTERVAL '25 hours' WHERE id = $1`,
+		jobID, earned+1,
+	); err != nil {
+		t.Fatalf("age job: %v", err)
+	}
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE participants SET stripe_account_id = $2 WHERE id = $1`, ownerID, acct,
+	); err != nil {
+		t.Fatalf("set stripe account: %v", err)
+	}
+
+	if _, err := store.BatchPayouts(ctx, db, time.Now()); err != nil {
+		t.Fatalf("BatchPayouts: %v", err)
+	}
+	history, err := store.PayoutHistory(ctx, db, ownerID, 10)
+	if err != nil || len(history) != 1 {
+		t.Fatalf("PayoutHistory = %+v, %v; want one batch", history, err)
+	}
+	batch := history[0]
+	payoutID, err := pc.TriggerPayout(ctx, acct, batch.AmountCents, batch.ID)
+	if err != nil {
+		t.Fatalf("TriggerPayout: %v", err)
+	}
+	if err := store.MarkPayoutBatchReleased(ctx, db, batch.ID, payoutID); err != nil {
+		t.Fatalf("MarkPayoutBatchReleased: %v", err)
+	}
+
+	payoutDiscrepancies := func() []store.Discrepancy {
+		t.Helper()
+		report, err := store.Reconcile(ctx, db, pc)
+		if err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		var out []store.Discrepancy
+		for _, d := range report.Discrepancies {
+			if d.ObjectID == payoutID {
+				out = append(out, d)
+			}
+		}
+		return out
+	}
+	if got := payoutDiscrepancies(); len(got) != 0 {
+		t.Fatalf("discrepancies for a released payout = %+v, want none", got)
+	}
+
+	if err := pc.FailPayout(payoutID); err != nil {
+		t.Fatalf("FailPayout: %v", err)
+	}
+	got := payoutDiscrepancies()
+	if len(got) != 1 || got[0].Check != "status" || got[0].Ref != batch.ID {
+		t.Fatalf("discrepancies after the bank rejects the payout = %+v, want one status mismatch", got)
+	}
+	latest, err := store.LatestReconciliation(ctx, db)
+	if err != nil {
+		t.Fatalf("LatestReconciliation: %v", err)
+	}
+	found := false
+	for _, d := range latest.Discrepancies {
+		found = found || d.ObjectID == payoutID
+	}
+	if !found {
+		t.Errorf("LatestReconciliation does not carry the payout discrepancy")
+	}
+
+	// The payout.failed webhook reverses the payout and frees the run.
+	if failed, err := store.FailPayoutBatch(ctx, db, payoutID, "account_closed"); err != nil || !failed {
+		t.Fatalf("FailPayoutBatch = %v, %v; want true", failed, err)
+	}
+	if failed, err := store.FailPayoutBatch(ctx, db, payoutID, "account_closed"); err != nil || failed {
+		t.Errorf("FailPayoutBatch (again) = %v, %v; want false", failed, err)
+	}
+	if got := payoutDiscrepancies(); len(got) != 0 {
+		t.Errorf("discrepancies after FailPayoutBatch = %+v, want none", got)
+	}
+	if payable, _ := store.ContributorPayable(ctx, db, ownerID); payable != earned {
+		t.Errorf("payable after the failed payout = %d, want %d back", payable, earned)
+	}
+	violations, err := store.CheckLedger(ctx, db)
+	if err != nil {
+		t.Fatalf("CheckLedger: %v", err)
+	}
+	for _, v := range violations {
+		if v.Ref == jobID || v.Ref == ownerID || v.Ref == batch.ID {
+			t.Errorf("ledger violation for this job: %+v", v)
+		}
+	}
+
+	// The run is batched again.
+	if _, err := store.BatchPayouts(ctx, db, time.Now()); err != nil {
+		t.Fatalf("BatchPayouts (after failure): %v", err)
+	}
+	history, _ = store.PayoutHistory(ctx, db, ownerID, 10)
+	if len(history) != 2 || history[0].Status != store.PayoutBatchPending || history[0].AmountCents != earned {
+		t.Errorf("PayoutHistory after re-batching = %+v, want a new pending batch of %d", history, earned)
+	}
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+)
+
+func checks(ds []Discrepancy) []string {
+	out := make([]string, len(ds))
+	for i, d := range ds {
+		out[i] = d.Check
+	}
+	return out
+}
+
+func TestComparePayment(t *testing.T) {
+	cases := []struct {
+		name   string
+		ours   ourPayment
+		theirs payment.PaymentRecord
+		want   []string
+	}{
+		{"captured escrow matches",
+			ourPayment{PaymentStatus: PaymentCaptured, AmountCents: 1000, CapturedCents: 600},
+			payment.PaymentRecord{Status: "succeeded", AmountCents: 1000, CapturedCents: 600}, nil},
+		{"authorized hold matches",
+			ourPayment{PaymentStatus: PaymentAuthorized, AmountCents: 1000},
+			payment.PaymentRecord{Status: "requires_capture", AmountCents: 1000}, nil},
+		{"hold lapsed at Stripe",
+			ourPayment{PaymentStatus: PaymentAuthorized, AmountCents: 1000},
+			payment.PaymentRecord{Status: "canceled", AmountCents: 1000}, []string{"status"}},
+		{"refund made outside the portal",
+			ourPayment{PaymentStatus: PaymentCaptured, AmountCents: 1000, CapturedCents: 600},
+			payment.PaymentRecord{Status: "succeeded", CapturedCents: 600, RefundedCents: 200}, []string{"refunded"}},
+		{"destination charge never paid",
+			ourPayment{AmountCents: 800},
+			payment.PaymentRecord{Status: "requires_payment_method", AmountCents: 800}, []string{"status", "captured"}},
+		{"unrecorded chargeback",
+			ourPayment{AmountCents: 800},
+			payment.PaymentRecord{Status: "succeeded", CapturedCents: 800, Disputed: true}, []string{"chargeback"}},
+		{"recorded chargeback",
+			ourPayment{AmountCents: 800, Chargeback: true},
+			payment.PaymentRecord{Status: "succeeded", CapturedCents: 800, Disputed: true}, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := checks(comparePayment(tc.ours, tc.theirs))
+			if len(got) != len(tc.want) {
+				t.Fatalf("discrepancies = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("discrepancies = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestComparePayout(t *testing.T) {
+	cases := []struct {
+		name   string
+		ours   ourPayout
+		theirs payment.PayoutRecord
+		want   int
+	}{
+		{"released, in transit", ourPayout{AmountCents: 5000, Status: PayoutBatchReleased}, payment.PayoutRecord{Status: "in_transit", AmountCents: 5000}, 0},
+		{"released, failure missed", ourPayout{AmountCents: 5000, Status: PayoutBatchReleased}, payment.PayoutRecord{Status: "failed", AmountCents: 5000}, 1},
+		{"paid, still pending at Stripe", ourPayout{AmountCents: 5000, Status: PayoutBatchPaid}, payment.PayoutRecord{Status: "pending", AmountCents: 5000}, 1},
+		{"failed matches", ourPayout{AmountCents: 5000, Status: PayoutBatchFailed}, payment.PayoutRecord{Status: "failed", AmountCents: 5000}, 0},
+		{"amount differs", ourPayout{AmountCents: 5000, Status: PayoutBatchPaid}, payment.PayoutRecord{Status: "paid", AmountCents: 4000}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := comparePayout(tc.ours, tc.theirs); len(got) != tc.want {
+				t.Errorf("discrepancies = %+v, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCompareTransfer(t *testing.T) {
+	ours := ourTransfer{JobID: "job", TransferID: "tr_1", AmountCents: 450}
+	if got := compareTransfer(ours, payment.TransferRecord{AmountCents: 450}); len(got) != 0 {
+		t.Errorf("matching transfer: discrepancies = %+v", got)
+	}
+	if got := compareTransfer(ours, payment.TransferRecord{AmountCents: 450, ReversedCents: 450}); len(got) != 1 {
+		t.Errorf("reversed transfer: discrepancies = %+v, want 1", got)
+	}
+}
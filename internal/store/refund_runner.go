@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+)
+
+// cancelRefundGrace is how long after a cancellation the cancel refunder
+// leaves the job alone: the refund made while the cancel request is served
+// has finished by then, one way or the other.
+const cancelRefundGrace = 10 * time.Minute
+
+// RunCancelRefunder runs in a goroutine and retries, every interval, the
+// cancellation refunds that failed when their jobs were cancelled (the
+// portal answered refund_status "pending"). Before refunding it asks the
+// provider what was already refunded on the payment, so a refund that went
+// through but was never recorded is recorded rather than repeated. Errors
+// per-refund are logged and counted; after maxCancelRefundAttempts failures
+// the refund is left to staff (OwedCancelRefunds), and the refunder only
+// records it once staff have refunded the payment themselves.
+func RunCancelRefunder(ctx context.Context, db *DB, pc payment.PaymentProvider, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			retryCancelRefunds(ctx, db, pc, time.Now())
+		}
+	}
+}
+
+// retryCancelRefunds is one RunCancelRefunder tick at now.
+func retryCancelRefunds(ctx context.Context, db *DB, pc payment.PaymentProvider, now time.Time) {
+	owed, err := CancelRefundsToRetry(ctx, db, now.Add(-cancelRefundGrace))
+	if err != nil {
+		slog.Warn("cancel refunder: CancelRefundsToRetry error", "error", err)
+		return
+	}
+	for _, o := range owed {
+		rec, err := pc.RetrievePayment(ctx, o.PaymentIntentID)
+		if err != nil {
+			slog.Warn("cancel refunder: RetrievePayment failed", "job_id", o.JobID, "error", err)
+			if !o.GaveUp() {
+				recordRefundFailure(ctx, db, o, err)
+			}
+			continue
+		}
+		if rec.RefundedCents < o.RefundCents {
+			if o.GaveUp() {
+				continue
+			}
+			if err := pc.CreateRefund(ctx, o.PaymentIntentID, o.RefundCents); err != nil {
+				slog.Warn("cancel refunder: CreateRefund failed",
+					"job_id", o.JobID, "refund_cents", o.RefundCents, "error", err)
+				recordRefundFailure(ctx, db, o, err)
+				continue
+			}
+		}
+		if err := MarkRefunded(ctx, db, o.JobID); err != nil {
+			slog.Warn("cancel refunder: failed to mark refunded", "job_id", o.JobID, "error", err)
+			continue
+		}
+		slog.Info("cancel refund retried", "job_id", o.JobID, "refund_cents", o.RefundCents)
+	}
+}
+
+// recordRefundFailure counts a failed retry of o and says so loudly when
+// the refunder gives up on it.
+func recordRefundFailure(ctx context.Context, db *DB, o OwedRefund, cause error) {
+	gaveUp, err := RecordCancelRefundFailure(ctx, db, o.JobID, cause)
+	if err != nil {
+		slog.Warn("cancel refunder: failed to record refund failure", "job_id", o.JobID, "error", err)
+		return
+	}
+	if gaveUp {
+		slog.Error("cancel refunder: giving up on refund; listed on /admin/reconciliation",
+			"job_id", o.JobID, "payment_intent_id", o.PaymentIntentID,
+			"refund_cents", o.RefundCents, "error", cause)
+	}
+}
@@ -0,0 +1,97 @@
+//go:build integration
+
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/payment"
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/store"
+)
+
+// TestRunCancelRefunder retries two owed cancellation refunds against the
+// ledger provider: one it can make, and one on a payment the provider does
+// not know, which it gives up on and leaves listed for staff.
+func TestRunCancelRefunder(t *testing.T) {
+	db := connectTestDB(t)
+	ctx := context.Background()
+
+	pc := payment.NewLedger()
+	acct, err := pc.CreateConnectedAccount(ctx, "refund node", "refund_node@test.com")
+	if err != nil {
+		t.Fatalf("CreateConnectedAccount: %v", err)
+	}
+	charge, err := pc.CreateDestinationCharge(ctx, 1000, 150, acct)
+	if err != nil {
+		t.Fatalf("CreateDestinationCharge: %v", err)
+	}
+
+	owe := func(email, paymentIntentID string) string {
+		t.Helper()
+		jobID := seedMeteringJob(t, db, email, 1.0)
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE jobs SET payment_intent_id = $2, amount_cents = 1000, refund_cents = 300,
+			        cancelled_at = NOW() - INTERVAL '1 hour', status = 'failed'::job_status,
+			        failure_cause = $3
+			 WHERE id = $1`,
+			jobID, paymentIntentID, store.FailureCauseConsumerCancelled,
+		); err != nil {
+			t.Fatalf("owe refund: %v", err)
+		}
+		return jobID
+	}
+	refundable := owe("refund_retry@test.com", charge.PaymentIntentID)
+	unknown := owe("refund_unknown@test.com", "pi_unknown")
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	go func() { _ = store.RunCancelRefunder(runCtx, db, pc, 10*time.Millisecond) }()
+
+	owedFor := func(jobID string) (store.OwedRefund, bool) {
+		owed, err := store.OwedCancelRefunds(ctx, db)
+		if err != nil {
+			t.Fatalf("OwedCancelRefunds: %v", err)
+		}
+		for _, o := range owed {
+			if o.JobID == jobID {
+				return o, true
+			}
+		}
+		return store.OwedRefund{}, false
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, stillOwed := owedFor(refundable)
+		o, _ := owedFor(unknown)
+		if !stillOwed && o.GaveUp() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("refunder did not settle: refundable owed %v, unknown %+v", stillOwed, o)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	stop()
+
+	rec, err := pc.RetrievePayment(ctx, charge.PaymentIntentID)
+	if err != nil || rec.RefundedCents != 300 {
+		t.Errorf("refunded on the payment = %d, %v; want 300 exactly once", rec.RefundedCents, err)
+	}
+	var consumerID string
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT participant_id::text FROM jobs WHERE id = $1`, refundable,
+	).Scan(&consumerID); err != nil {
+		t.Fatalf("read consumer: %v", err)
+	}
+	if got, _ := store.LedgerBalance(ctx, db, store.AccountRefund, consumerID); got != 300 {
+		t.Errorf("ledger refund = %d, want 300", got)
+	}
+
+	o, listed := owedFor(unknown)
+	if !listed || o.Attempts != 5 || o.LastError == "" {
+		t.Errorf("unknown payment's refund = %+v (listed %v), want listed after 5 failed retries", o, listed)
+	}
+}
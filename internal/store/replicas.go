@@ -0,0 +1,274 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FailureCauseReplicaSuperseded marks a replica withdrawn before it started
+// because its replica group had already settled (migration 031).
+const FailureCauseReplicaSuperseded = "replica_superseded"
+
+// ReplicaPlacement is a replica withdrawn by SettleReplicaGroup while still
+// bound to a node. The caller releases NodeID's registry reservation.
+type ReplicaPlacement struct {
+	JobID  string
+	NodeID string
+}
+
+// SettleReplicaGroup re-evaluates the replica group jobID belongs to after one
+// of its replicas changed state. It is a no-op (empty status, nil error) for a
+// job that is not a replica, and for a group whose parent already settled.
+//
+//   - replica_quorum replicas completed → parent completed
+//   - too many replicas failed for the quorum to be reached → parent failed
+//   - otherwise, any replica started → parent running
+//
+// For a verified group (jobs.verify, migration 032) reaching the quorum runs
+// the result-hash comparison instead of completing outright: agreement
+// completes the parent; disagreement makes it 'disputed', flags the losing
+// replicas suspected_fraud and opens a dispute on each.
+//
+// On any settlement, replicas that have not started are withdrawn
+// (failed, FailureCauseReplicaSuperseded); those still bound to a node
+// (scheduled/dispatched) are returned so the caller can release their
+// reservations. Replicas already running are left to finish. The parent row
+// is locked for the duration, so concurrent completions of sibling replicas
+// settle the group exactly once. Returns the parent's resulting status.
+func SettleReplicaGroup(ctx context.Context, db *DB, jobID string) (string, []ReplicaPlacement, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("settle replica group for %s: begin: %w", jobID, err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck // rollback on non-commit paths is intentional
+
+	var (
+		parentID, parentStatus string
+		tier, quorum           int
+		verify                 bool
+	)
+	err = tx.QueryRow(ctx,
+		`SELECT p.id::text, p.status::text, p.sla_tier, p.replica_quorum, p.verify
+		 FROM jobs c
+		 JOIN jobs p ON p.id = c.parent_job_id
+		 WHERE c.id = $1
+		 FOR UPDATE OF p`,
+		jobID,
+	).Scan(&parentID, &parentStatus, &tier, &quorum, &verify)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, nil
+		}
+		return "", nil, fmt.Errorf("settle replica group for %s: read parent: %w", jobID, err)
+	}
+	if settled(parentStatus) {
+		return parentStatus, nil, nil
+	}
+
+	var succeeded, failed, started int
+	if err := tx.QueryRow(ctx,
+		`SELECT COUNT(*) FILTER (WHERE status = 'completed'::job_status),
+		        COUNT(*) FILTER (WHERE status = 'failed'::job_status),
+		        COUNT(*) FILTER (WHERE started_at IS NOT NULL)
+		 FROM jobs WHERE parent_job_id = $1`,
+		parentID,
+	).Scan(&succeeded, &failed, &started); err != nil {
+		return "", nil, fmt.Errorf("settle replica group %s: count replicas: %w", parentID, err)
+	}
+
+	newStatus := parentStatus
+	switch {
+	case succeeded >= quorum:
+		newStatus = "completed"
+	case tier-failed < quorum:
+		newStatus = "failed"
+	case started > 0:
+		newStatus = "running"
+	}
+	if newStatus == parentStatus {
+		return parentStatus, nil, tx.Commit(ctx)
+	}
+
+	var verification *string
+	if verify && newStatus == "completed" {
+		outcome, err := verifyReplicaResults(ctx, tx, parentID)
+		if err != nil {
+			return "", nil, err
+		}
+		verification = &outcome
+		if outcome == VerificationMismatched {
+			newStatus = "disputed"
+		}
+	}
+
+	// started_at is the earliest replica start, so the parent's wall-clock
+	// span covers the whole group; completed_at only on settlement.
+	if _, err := tx.Exec(ctx,
+		`UPDATE jobs
+		 SET status       = $2::job_status,
+		     started_at   = COALESCE(started_at,
+		                      (SELECT MIN(started_at) FROM jobs WHERE parent_job_id = $1)),
+		     completed_at = CASE WHEN $2 IN ('completed', 'failed', 'disputed') THEN NOW() END,
+		     verification = COALESCE($3, verification),
+		     updated_at   = NOW()
+		 WHERE id = $1`,
+		parentID, newStatus, verification,
+	); err != nil {
+		return "", nil, fmt.Errorf("settle replica group %s: update parent: %w", parentID, err)
+	}
+
+	var withdrawn []ReplicaPlacement
+	if settled(newStatus) {
+		rows, err := tx.Query(ctx,
+			`UPDATE jobs
+			 SET status        = 'failed'::job_status,
+			     failure_cause = $2,
+			     completed_at  = NOW(),
+			     updated_at    = NOW()
+			 WHERE parent_job_id = $1
+			   AND status IN ('scheduled'::job_status, 'dispatched'::job_status)
+			 RETURNING id::text, COALESCE(node_id::text, '')`,
+			parentID, FailureCauseReplicaSuperseded,
+		)
+		if err != nil {
+			return "", nil, fmt.Errorf("settle replica group %s: withdraw replicas: %w", parentID, err)
+		}
+		for rows.Next() {
+			var p ReplicaPlacement
+			if err := rows.Scan(&p.JobID, &p.NodeID); err != nil {
+				rows.Close()
+				return "", nil, fmt.Errorf("settle replica group %s: scan withdrawn: %w", parentID, err)
+			}
+			withdrawn = append(withdrawn, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return "", nil, fmt.Errorf("settle replica group %s: withdrawn rows: %w", parentID, err)
+		}
+
+		// A declined replica already released its node; withdraw it without
+		// reporting it, so the reroute worker's guarded UPDATE becomes a no-op.
+		if _, err := tx.Exec(ctx,
+			`UPDATE jobs
+			 SET status        = 'failed'::job_status,
+			     failure_cause = $2,
+			     completed_at  = NOW(),
+			     updated_at    = NOW()
+			 WHERE parent_job_id = $1 AND status = 'declined'::job_status`,
+			parentID, FailureCauseReplicaSuperseded,
+		); err != nil {
+			return "", nil, fmt.Errorf("settle replica group %s: withdraw declined replicas: %w", parentID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", nil, fmt.Errorf("settle replica group %s: commit: %w", parentID, err)
+	}
+	return newStatus, withdrawn, nil
+}
+
+// Verification outcomes recorded on a verified group's parent
+// (jobs.verification, migration 032).
+const (
+	VerificationMatched    = "matched"
+	VerificationMismatched = "mismatched"
+)
+
+// settled reports whether a replica group parent has reached a final status.
+func settled(status string) bool {
+	return status == "completed" || status == "failed" || status == "disputed"
+}
+
+// verifyReplicaResults compares the result hashes of a verified group's
+// completed replicas (see judgeResultHashes). On a mismatch it flags each
+// suspect suspected_fraud and opens a dispute against it, within tx.
+func verifyReplicaResults(ctx context.Context, tx pgx.Tx, parentID string) (string, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT id::text, COALESCE(result_hash, '')
+		 FROM jobs
+		 WHERE parent_job_id = $1 AND status = 'completed'::job_status`,
+		parentID,
+	)
+	if err != nil {
+		return "", fmt.Errorf("verify replica group %s: read hashes: %w", parentID, err)
+	}
+	hashes := map[string]string{}
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return "", fmt.Errorf("verify replica group %s: scan hash: %w", parentID, err)
+		}
+		hashes[id] = hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("verify replica group %s: hash rows: %w", parentID, err)
+	}
+
+	suspects := judgeResultHashes(hashes)
+	if len(suspects) == 0 {
+		return VerificationMatched, nil
+	}
+
+	if _, err := tx.Exec(ctx,
+		`UPDATE jobs SET suspected_fraud = TRUE, updated_at = NOW() WHERE id = ANY($1)`,
+		suspects,
+	); err != nil {
+		return "", fmt.Errorf("verify replica group %s: flag suspects: %w", parentID, err)
+	}
+	// One active dispute per job (026); a suspect already under dispute keeps
+	// its existing one.
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO disputes (job_id, node_id, participant_id, payment_intent_id, reason, status)
+		 SELECT id, node_id, participant_id, COALESCE(payment_intent_id, ''),
+		        'result hash mismatch (suspected fraud)', 'open'::dispute_status
+		 FROM jobs
+		 WHERE id = ANY($1) AND node_id IS NOT NULL
+		 ON CONFLICT (job_id) WHERE status IN ('open', 'under_review') DO NOTHING`,
+		suspects,
+	); err != nil {
+		return "", fmt.Errorf("verify replica group %s: open disputes: %w", parentID, err)
+	}
+	return VerificationMismatched, nil
+}
+
+// judgeResultHashes returns the replicas (by job ID) whose result hash is not
+// the agreed one, sorted. The agreed hash is the one reported by a strict
+// plurality of at least two replicas; a missing hash ("") never agrees. With
+// no agreed hash — a 1–1 split, or every replica different — every replica
+// is a suspect: the platform cannot tell which node lied. An empty result
+// means the replicas matched.
+func judgeResultHashes(hashes map[string]string) []string {
+	counts := map[string]int{}
+	for _, h := range hashes {
+		if h != "" {
+			counts[h]++
+		}
+	}
+	agreed, best, tied := "", 0, false
+	for h, n := range counts {
+		switch {
+		case n > best:
+			agreed, best, tied = h, n, false
+		case n == best:
+			tied = true
+		}
+	}
+	if best < 2 || tied {
+		agreed = ""
+	}
+
+	var suspects []string
+	for id, h := range hashes {
+		if agreed == "" || h != agreed {
+			suspects = append(suspects, id)
+		}
+	}
+	sort.Strings(suspects)
+	return suspects
+}
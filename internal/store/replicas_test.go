@@ -0,0 +1,30 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJudgeResultHashes(t *testing.T) {
+	a, b, c := strings.Repeat("a", 64), strings.Repeat("b", 64), strings.Repeat("c", 64)
+	cases := []struct {
+		name   string
+		hashes map[string]string
+		want   []string
+	}{
+		{"all agree", map[string]string{"r1": a, "r2": a}, nil},
+		{"majority of three", map[string]string{"r1": a, "r2": b, "r3": a}, []string{"r2"}},
+		{"two-way split", map[string]string{"r1": a, "r2": b}, []string{"r1", "r2"}},
+		{"all differ", map[string]string{"r1": a, "r2": b, "r3": c}, []string{"r1", "r2", "r3"}},
+		{"missing hash loses", map[string]string{"r1": a, "r2": a, "r3": ""}, []string{"r3"}},
+		{"missing hashes never agree", map[string]string{"r1": "", "r2": ""}, []string{"r1", "r2"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := judgeResultHashes(tc.hashes); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("judgeResultHashes = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
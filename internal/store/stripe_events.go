@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RecordStripeEvent logs a processed Stripe webhook event: its id, type, the
+// connected account it came from ("" for the platform) and the id of the
+// object it describes. Stripe redelivers events; a repeat is ignored.
+func RecordStripeEvent(ctx context.Context, db *DB, eventID, eventType, account, objectID string) error {
+	if _, err := db.Pool.Exec(ctx,
+		`INSERT INTO stripe_events (id, type, account, object_id)
+		 VALUES ($1, $2, NULLIF($3, ''), $4)
+		 ON CONFLICT (id) DO NOTHING`,
+		eventID, eventType, account, objectID,
+	); err != nil {
+		return fmt.Errorf("record stripe event %s: %w", eventID, err)
+	}
+	return nil
+}
+
+// LastStripeEvent returns when the most recent Stripe webhook event was
+// processed, and false when none ever was.
+func LastStripeEvent(ctx context.Context, db *DB) (time.Time, bool, error) {
+	var at time.Time
+	err := db.Pool.QueryRow(ctx,
+		`SELECT received_at FROM stripe_events ORDER BY received_at DESC LIMIT 1`,
+	).Scan(&at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("last stripe event: %w", err)
+	}
+	return at, true, nil
+}
+
+// Chargeback is a dispute the consumer's bank opened with Stripe against a
+// job's charge — unlike a Dispute, which the consumer files with us. A job
+// with a chargeback neither won nor closed as an inquiry ("warning_closed")
+// is held from payout.
+type Chargeback struct {
+	ID              string // Stripe's dispute id
+	ChargeID        string
+	PaymentIntentID string
+	AmountCents     int64
+	Reason          string
+	Status          string // Stripe's dispute status, e.g. "needs_response", "won", "lost"
+}
+
+// RecordChargeback stores the latest state of chargeback cb, matched to the
+// job whose payment intent or charge it disputes.
+func RecordChargeback(ctx context.Context, db *DB, cb Chargeback) error {
+	if _, err := db.Pool.Exec(ctx,
+		`INSERT INTO stripe_disputes
+		   (id, charge_id, payment_intent_id, job_id, amount_cents, reason, status)
+		 VALUES ($1, $2, NULLIF($3, ''),
+		         (SELECT id FROM jobs
+		          WHERE parent_job_id IS NULL
+		            AND ((payment_intent_id = NULLIF($3, '')) OR charge_id = $2)
+		          LIMIT 1),
+		         $4, $5, $6)
+		 ON CONFLICT (id) DO UPDATE
+		 SET amount_cents = EXCLUDED.amount_cents,
+		     reason       = EXCLUDED.reason,
+		     status       = EXCLUDED.status,
+		     job_id       = COALESCE(stripe_disputes.job_id, EXCLUDED.job_id),
+		     updated_at   = NOW()`,
+		cb.ID, cb.ChargeID, cb.PaymentIntentID, cb.AmountCents, cb.Reason, cb.Status,
+	); err != nil {
+		return fmt.Errorf("record chargeback %s: %w", cb.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNoTelemetrySecret is returned by NodeTelemetrySecret when the node row
+// exists but carries no telemetry_secret — it was claimed before secrets were
+// persisted (migration 029). Callers map this to HTTP 403: the sample cannot
+// be verified, so it is never stored.
+var ErrNoTelemetrySecret = errors.New("store: node has no telemetry secret")
+
+// ErrTelemetryReplay is returned by InsertTelemetrySample when the sample's
+// timestamp is not strictly newer than the last accepted sample for the job —
+// a replayed or out-of-order delivery. Callers map this to HTTP 409.
+var ErrTelemetryReplay = errors.New("store: telemetry sample not newer than last accepted sample")
+
+// TelemetrySample is one verified job_telemetry row. SampledAt is the
+// agent-signed timestamp truncated to whole seconds — the precision the HMAC
+// canonical form covers.
+type TelemetrySample struct {
+	SampledAt     time.Time
+	CPUPct        float64
+	RAMPct        float64
+	BandwidthMbps int
+}
+
+// NodeTelemetrySecret returns the per-node HMAC key issued at claim time.
+func NodeTelemetrySecret(ctx context.Context, db *DB, nodeID string) ([]byte, error) {
+	var secret []byte
+	err := db.Pool.QueryRow(ctx,
+		`SELECT telemetry_secret FROM nodes WHERE id = $1`, nodeID,
+	).Scan(&secret)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("node telemetry secret %s: %w", nodeID, ErrNodeNotFound)
+		}
+		return nil, fmt.Errorf("node telemetry secret %s: %w", nodeID, err)
+	}
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("node telemetry secret %s: %w", nodeID, ErrNoTelemetrySecret)
+	}
+	return secret, nil
+}
+
+// InsertTelemetrySample appends a verified sample for a running job. The
+// INSERT is guarded so that it lands only when the job is 'running' and no
+// sample at or after s.SampledAt already exists — the per-job monotonicity
+// check and the write are one statement, so two concurrent deliveries cannot
+// both pass. When the guard matches nothing, a follow-up read distinguishes
+// ErrJobNotRunning from ErrTelemetryReplay.
+func InsertTelemetrySample(ctx context.Context, db *DB, jobID string, s TelemetrySample) error {
+	sampledAt := s.SampledAt.UTC().Truncate(time.Second)
+	tag, err := db.Pool.Exec(ctx,
+		`INSERT INTO job_telemetry (job_id, node_id, sampled_at, cpu_pct, ram_pct, bandwidth_mbps)
+		 SELECT j.id, j.node_id, $2, $3, $4, $5
+		 FROM jobs j
+		 WHERE j.id = $1
+		   AND j.status = 'running'::job_status
+		   AND NOT EXISTS (
+		       SELECT 1 FROM job_telemetry t
+		       WHERE t.job_id = $1 AND t.sampled_at >= $2
+		   )
+		 ON CONFLICT (job_id, sampled_at) DO NOTHING`,
+		jobID, sampledAt, s.CPUPct, s.RAMPct, s.BandwidthMbps,
+	)
+	if err != nil {
+		return fmt.Errorf("insert telemetry sample %s: %w", jobID, err)
+	}
+	if tag.RowsAffected() == 1 {
+		return nil
+	}
+
+	var status string
+	if err := db.Pool.QueryRow(ctx,
+		`SELECT status::text FROM jobs WHERE id = $1`, jobID,
+	).Scan(&status); err != nil {
+		return fmt.Errorf("insert telemetry sample %s: read status: %w", jobID, err)
+	}
+	if status != "running" {
+		return fmt.Errorf("insert telemetry sample %s: %w", jobID, ErrJobNotRunning)
+	}
+	return fmt.Errorf("insert telemetry sample %s: %w", jobID, ErrTelemetryReplay)
+}
+
+// JobTelemetry returns a job's accepted samples in ascending time order.
+// since, when non-zero, restricts the series to samples strictly after it so
+// pollers can fetch incrementally.
+func JobTelemetry(ctx context.Context, db *DB, jobID string, since time.Time) ([]TelemetrySample, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT sampled_at, cpu_pct, ram_pct, bandwidth_mbps
+		 FROM job_telemetry
+		 WHERE job_id = $1 AND sampled_at > $2
+		 ORDER BY sampled_at ASC`,
+		jobID, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("job telemetry %s: query: %w", jobID, err)
+	}
+	defer rows.Close()
+
+	samples := []TelemetrySample{}
+	for rows.Next() {
+		var s TelemetrySample
+		if err := rows.Scan(&s.SampledAt, &s.CPUPct, &s.RAMPct, &s.BandwidthMbps); err != nil {
+			return nil, fmt.Errorf("job telemetry %s: scan: %w", jobID, err)
+		}
+		samples = append(samples, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("job telemetry %s: rows: %w", jobID, err)
+	}
+	return samples, nil
+}
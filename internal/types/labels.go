@@ -0,0 +1,96 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MaxNodeLabels bounds the labels (or taints) on one node, and the selector
+// terms or tolerations on one job.
+const MaxNodeLabels = 32
+
+// maxLabelLen bounds a label key and a label value.
+const maxLabelLen = 63
+
+// ValidateNodeLabel checks one node label or taint, key=value. Keys are
+// lowercase letters, digits and "-_./", starting and ending with a letter or
+// digit ("ssd", "ups-backed", "example.org/rack"). Values are letters, digits
+// and "-_." and may be empty: a bare tag like "ssd" is the label ssd="".
+func ValidateNodeLabel(key, value string) error {
+	if key == "" || len(key) > maxLabelLen {
+		return fmt.Errorf("label key %q must be 1-%d characters", key, maxLabelLen)
+	}
+	for i, c := range key {
+		alnum := c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+		if !alnum && (i == 0 || i == len(key)-1 || !strings.ContainsRune("-_./", c)) {
+			return fmt.Errorf("label key %q: use lowercase letters, digits and -_./, starting and ending with a letter or digit", key)
+		}
+	}
+	if len(value) > maxLabelLen {
+		return fmt.Errorf("label %s: value must be at most %d characters", key, maxLabelLen)
+	}
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.", c)) {
+			return fmt.Errorf("label %s: value %q may use only letters, digits and -_.", key, value)
+		}
+	}
+	return nil
+}
+
+// ValidateNodeLabels checks every entry of labels and their number.
+func ValidateNodeLabels(labels map[string]string) error {
+	if len(labels) > MaxNodeLabels {
+		return fmt.Errorf("at most %d labels", MaxNodeLabels)
+	}
+	for _, k := range sortedKeys(labels) {
+		if err := ValidateNodeLabel(k, labels[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseNodeLabels parses a comma- or whitespace-separated list of key=value
+// and bare key entries ("ssd, zone=attic") as written in the portal and in
+// AGENT_LABELS / AGENT_TAINTS. An empty s is no labels. A key given twice is
+// an error rather than last-wins, so a typo cannot silently drop a label.
+func ParseNodeLabels(s string) (map[string]string, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	labels := make(map[string]string, len(fields))
+	for _, f := range fields {
+		k, v, _ := strings.Cut(f, "=")
+		if _, dup := labels[k]; dup {
+			return nil, fmt.Errorf("label %s given twice", k)
+		}
+		labels[k] = v
+	}
+	if err := ValidateNodeLabels(labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// FormatNodeLabels renders labels in ParseNodeLabels syntax, sorted by key.
+func FormatNodeLabels(labels map[string]string) string {
+	parts := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		if v := labels[k]; v != "" {
+			parts = append(parts, k+"="+v)
+		} else {
+			parts = append(parts, k)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
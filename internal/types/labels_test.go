@@ -0,0 +1,67 @@
+package types_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NetworkTheoryAppliedResearchInstitute/soholink/internal/types"
+)
+
+func TestParseNodeLabels_RoundTrips(t *testing.T) {
+	labels, err := types.ParseNodeLabels(" ups-backed,zone=attic\nssd  example.org/rack=R2 ")
+	if err != nil {
+		t.Fatalf("ParseNodeLabels: %v", err)
+	}
+	want := map[string]string{"ups-backed": "", "zone": "attic", "ssd": "", "example.org/rack": "R2"}
+	if len(labels) != len(want) {
+		t.Fatalf("labels = %v, want %v", labels, want)
+	}
+	for k, v := range want {
+		if got, ok := labels[k]; !ok || got != v {
+			t.Errorf("labels[%q] = %q (present %v), want %q", k, got, ok, v)
+		}
+	}
+	formatted := types.FormatNodeLabels(labels)
+	if formatted != "example.org/rack=R2, ssd, ups-backed, zone=attic" {
+		t.Errorf("FormatNodeLabels = %q", formatted)
+	}
+	again, err := types.ParseNodeLabels(formatted)
+	if err != nil || types.FormatNodeLabels(again) != formatted {
+		t.Errorf("re-parse of %q = %v, %v", formatted, again, err)
+	}
+
+	if labels, err := types.ParseNodeLabels(""); err != nil || len(labels) != 0 {
+		t.Errorf("ParseNodeLabels(\"\") = %v, %v; want no labels", labels, err)
+	}
+}
+
+func TestParseNodeLabels_Rejects(t *testing.T) {
+	cases := []struct {
+		in          string
+		errContains string
+	}{
+		{"SSD", "lowercase"},
+		{"-ssd", "starting and ending"},
+		{"ssd-", "starting and ending"},
+		{"=attic", "1-63"},
+		{"zone=at!tic", "value"},
+		{"zone=a/b", "value"},
+		{"zone=attic, zone=cellar", "given twice"},
+		{strings.Repeat("k", 64), "1-63"},
+		{"zone=" + strings.Repeat("v", 64), "at most"},
+	}
+	for _, tc := range cases {
+		_, err := types.ParseNodeLabels(tc.in)
+		if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+			t.Errorf("ParseNodeLabels(%q) error = %v, want one containing %q", tc.in, err, tc.errContains)
+		}
+	}
+
+	many := make(map[string]string, types.MaxNodeLabels+1)
+	for i := 0; i <= types.MaxNodeLabels; i++ {
+		many[strings.Repeat("k", i+1)] = ""
+	}
+	if err := types.ValidateNodeLabels(many); err == nil {
+		t.Error("ValidateNodeLabels accepted too many labels")
+	}
+}
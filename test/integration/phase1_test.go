@@ -180,6 +180,189 @@ func TestPhase1EndToEnd(t *testing.T) {
 			t.Logf("CreateDestinationCharge: got expected Stripe error: %v", err)
 		}
 	})
+
+	// The same charge through the in-memory ledger provider runs with no
+	// network and no Stripe key, so it always runs.
+	t.Run("ledger_charge", func(t *testing.T) {
+		var pc payment.PaymentProvider = payment.NewLedger()
+		acct, err := pc.CreateConnectedAccount(ctx, "phase1 node", "phase1@test.com")
+		if err != nil {
+			t.Fatalf("CreateConnectedAccount: %v", err)
+		}
+		if _, err := pc.CreateDestinationCharge(ctx, 1000, 150, acct); err != nil {
+			t.Fatalf("CreateDestinationCharge: %v", err)
+		}
+		if _, err := pc.TriggerPayout(ctx, acct, 850, resp.JobID); err != nil {
+			t.Fatalf("TriggerPayout: %v", err)
+		}
+	})
+
+	// The job's whole billing path against the ledger provider: an escrow
+	// hold, metering, the escrow settler's capture and transfer, and the
+	// payout releaser, then the provider's balances against the database
+	// ledger's.
+	t.Run("ledger_billing", func(t *testing.T) {
+		pc := payment.NewLedger()
+		acct, err := pc.CreateConnectedAccount(ctx, "phase1 node", "provider-phase1@test.internal")
+		if err != nil {
+			t.Fatalf("CreateConnectedAccount: %v", err)
+		}
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE participants SET stripe_account_id = $2 WHERE id = $1`, providerID, acct,
+		); err != nil {
+			t.Fatalf("set stripe account: %v", err)
+		}
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE nodes SET hardware_profile = '{"CPUCores":4,"RAMMB":8192,"StorageGB":100}' WHERE id = $1`, nodeID,
+		); err != nil {
+			t.Fatalf("set hardware profile: %v", err)
+		}
+
+		// Escrow the job and authorize its hold, as the pay page would.
+		const holdCents = 1000
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE jobs SET payment_status = $2 WHERE id = $1`, resp.JobID, store.PaymentAwaiting,
+		); err != nil {
+			t.Fatalf("escrow job: %v", err)
+		}
+		hold, err := pc.CreateEscrowCharge(ctx, holdCents, resp.JobID)
+		if err != nil {
+			t.Fatalf("CreateEscrowCharge: %v", err)
+		}
+		if ok, err := store.AttachJobPayment(ctx, db, resp.JobID, hold.PaymentIntentID, holdCents); err != nil || !ok {
+			t.Fatalf("AttachJobPayment = %v, %v; want true", ok, err)
+		}
+		if err := pc.Authorize(hold.PaymentIntentID); err != nil {
+			t.Fatalf("Authorize: %v", err)
+		}
+		if ok, err := store.AuthorizeJobPayment(ctx, db, hold.PaymentIntentID); err != nil || !ok {
+			t.Fatalf("AuthorizeJobPayment = %v, %v; want true", ok, err)
+		}
+
+		// A day's run that finished past the dispute window, so its payout
+		// is due at once.
+		if _, err := db.Pool.Exec(ctx,
+			`UPDATE jobs SET status = 'completed'::job_status,
+			        started_at = NOW() - INTERVAL '50 hours', completed_at = NOW() - INTERVAL '26 hours'
+			 WHERE id = $1`, resp.JobID,
+		); err != nil {
+			t.Fatalf("complete job: %v", err)
+		}
+		if err := store.ComputeMetering(ctx, db, resp.JobID); err != nil {
+			t.Fatalf("ComputeMetering: %v", err)
+		}
+		var paid, earned, fee int64
+		if err := db.Pool.QueryRow(ctx,
+			`SELECT consumer_paid_cents, contributor_earned_cents, platform_fee_cents
+			 FROM job_metering WHERE job_id = $1`, resp.JobID,
+		).Scan(&paid, &earned, &fee); err != nil {
+			t.Fatalf("read metering: %v", err)
+		}
+		if paid < payment.MinChargeCents || paid > holdCents || earned <= 0 || earned+fee != paid {
+			t.Fatalf("metering paid=%d earned=%d fee=%d; want %d <= paid <= %d split into earned and fee",
+				paid, earned, fee, payment.MinChargeCents, holdCents)
+		}
+
+		runCtx, stopRunners := context.WithCancel(ctx)
+		defer stopRunners()
+		go func() { _ = store.RunEscrowSettler(runCtx, db, pc, 10*time.Millisecond) }()
+		waitFor(t, "escrow transfer", func() bool {
+			var transferred *int64
+			err := db.Pool.QueryRow(ctx,
+				`SELECT transferred_cents FROM job_metering WHERE job_id = $1`, resp.JobID,
+			).Scan(&transferred)
+			return err == nil && transferred != nil
+		})
+
+		var paymentStatus string
+		var captured, transferred int64
+		if err := db.Pool.QueryRow(ctx,
+			`SELECT j.payment_status, j.captured_cents, m.transferred_cents
+			 FROM jobs j JOIN job_metering m ON m.job_id = j.id WHERE j.id = $1`, resp.JobID,
+		).Scan(&paymentStatus, &captured, &transferred); err != nil {
+			t.Fatalf("read settlement: %v", err)
+		}
+		if paymentStatus != store.PaymentCaptured || captured != paid || transferred != earned {
+			t.Errorf("settlement status=%q captured=%d transferred=%d; want %q, %d, %d",
+				paymentStatus, captured, transferred, store.PaymentCaptured, paid, earned)
+		}
+		if got := pc.Balance(acct); got != earned {
+			t.Errorf("connected account after transfer = %d, want %d", got, earned)
+		}
+
+		go func() { _ = store.RunPayoutReleaser(runCtx, db, pc, 10*time.Millisecond) }()
+		waitFor(t, "payout release", func() bool {
+			history, err := store.PayoutHistory(ctx, db, providerID, 10)
+			return err == nil && len(history) == 1 && history[0].Status == store.PayoutBatchReleased
+		})
+		stopRunners()
+
+		history, err := store.PayoutHistory(ctx, db, providerID, 10)
+		if err != nil {
+			t.Fatalf("PayoutHistory: %v", err)
+		}
+		if history[0].AmountCents != earned || len(history[0].Lines) != 1 || history[0].Lines[0].JobID != resp.JobID {
+			t.Errorf("payout batch = %+v, want %d paying job %s", history[0], earned, resp.JobID)
+		}
+
+		// The provider moved the capture in, the earnings through the
+		// connected account and out to the owner, and kept the fee.
+		if got := pc.Balance(acct); got != 0 {
+			t.Errorf("connected account after payout = %d, want 0", got)
+		}
+		if got := pc.Balance(payment.PlatformAccount); got != fee {
+			t.Errorf("platform balance = %d, want the %d fee", got, fee)
+		}
+		var paidOut int64
+		for _, e := range pc.Entries() {
+			if e.Kind == "payout" && e.From == acct {
+				paidOut += e.AmountCents
+			}
+		}
+		if paidOut != earned {
+			t.Errorf("provider paid out %d, want %d", paidOut, earned)
+		}
+
+		// The database ledger agrees.
+		ledgerWant := []struct {
+			account, participantID string
+			want                   int64
+		}{
+			{store.AccountConsumerWallet, consumerID, -paid},
+			{store.AccountContributorPayable, providerID, 0},
+			{store.AccountPayout, providerID, earned},
+		}
+		for _, lw := range ledgerWant {
+			got, err := store.LedgerBalance(ctx, db, lw.account, lw.participantID)
+			if err != nil {
+				t.Fatalf("LedgerBalance(%s): %v", lw.account, err)
+			}
+			if got != lw.want {
+				t.Errorf("ledger %s balance = %d, want %d", lw.account, got, lw.want)
+			}
+		}
+		violations, err := store.CheckLedger(ctx, db)
+		if err != nil {
+			t.Fatalf("CheckLedger: %v", err)
+		}
+		for _, v := range violations {
+			if v.Ref == resp.JobID || v.Ref == providerID || v.Ref == consumerID || v.Ref == history[0].ID {
+				t.Errorf("ledger violation for this job: %+v", v)
+			}
+		}
+	})
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
 }
 
 func writeTestAllowlist(t *testing.T, name, digest string, entryType agent.WorkloadType) string {